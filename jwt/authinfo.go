@@ -0,0 +1,126 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// AuthInfoKey is the gRPC metadata key and Kafka header key carrying encoded AuthInfo.
+const AuthInfoKey = "x-auth-info"
+
+type authInfoCtxKey struct{}
+
+// AuthInfo is normalized summary of request authentication, which the middleware puts into request context.
+// It gives authorization code the same view of the caller regardless of the transport, see EncodeAuthInfo.
+type AuthInfo struct {
+	Subject string `json:"sub,omitempty"`
+	Issuer  string `json:"iss,omitempty"`
+	// RealmRoles are roles of "realm_access.roles" claim.
+	RealmRoles []string `json:"realmRoles,omitempty"`
+	// ResourceRoles are roles of "resource_access.<resource>.roles" claims keyed by resource, e.g. client id.
+	ResourceRoles map[string][]string `json:"resourceRoles,omitempty"`
+	// Scopes are scopes of "scope" claim (space separated string) and "scp" claim (list of strings).
+	Scopes []string `json:"scopes,omitempty"`
+	// Token is the raw bearer token, empty for development bypass. It's not encoded by EncodeAuthInfo.
+	Token string `json:"-"`
+}
+
+// HasRealmRole reports whether role is one of realm roles.
+func (a AuthInfo) HasRealmRole(role string) bool {
+	return contains(a.RealmRoles, role)
+}
+
+// HasResourceRole reports whether role is one of roles of given resource.
+func (a AuthInfo) HasResourceRole(resource, role string) bool {
+	return contains(a.ResourceRoles[resource], role)
+}
+
+// HasScope reports whether scope was granted.
+func (a AuthInfo) HasScope(scope string) bool {
+	return contains(a.Scopes, scope)
+}
+
+// ContextWithAuthInfo returns copy of ctx with given AuthInfo, e.g. decoded from Kafka message headers.
+func ContextWithAuthInfo(ctx context.Context, info AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoCtxKey{}, info)
+}
+
+// AuthInfoFromContext returns AuthInfo stored in ctx by the middleware or ContextWithAuthInfo.
+func AuthInfoFromContext(ctx context.Context) (AuthInfo, bool) {
+	info, ok := ctx.Value(authInfoCtxKey{}).(AuthInfo)
+	return info, ok
+}
+
+// EncodeAuthInfo encodes AuthInfo into a metadata value without the raw token, e.g. to pass it on with gRPC
+// metadata or Kafka headers under AuthInfoKey:
+//
+//	md.Set(jwt.AuthInfoKey, value)
+//	kafka.SetHeader(msg, jwt.AuthInfoKey, value)
+//
+// The value is not signed, so it must only be accepted from trusted services.
+func EncodeAuthInfo(info AuthInfo) (string, error) {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeAuthInfo decodes AuthInfo encoded with EncodeAuthInfo.
+func DecodeAuthInfo(value string) (AuthInfo, error) {
+	var info AuthInfo
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return info, fmt.Errorf("failed to decode auth info: %w", err)
+	}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return info, fmt.Errorf("failed to decode auth info: %w", err)
+	}
+	return info, nil
+}
+
+// newAuthInfo returns AuthInfo of given bearer token and its JSON payload.
+func newAuthInfo(bearer, tokenJSON []byte) AuthInfo {
+	info := AuthInfo{
+		Subject: gjson.GetBytes(tokenJSON, "sub").String(),
+		Issuer:  gjson.GetBytes(tokenJSON, "iss").String(),
+		Token:   string(bearer),
+	}
+	for _, role := range gjson.GetBytes(tokenJSON, "realm_access.roles").Array() {
+		info.RealmRoles = append(info.RealmRoles, role.String())
+	}
+	gjson.GetBytes(tokenJSON, "resource_access").ForEach(func(resource, access gjson.Result) bool {
+		roles := access.Get("roles").Array()
+		if len(roles) == 0 {
+			return true
+		}
+		if info.ResourceRoles == nil {
+			info.ResourceRoles = map[string][]string{}
+		}
+		for _, role := range roles {
+			info.ResourceRoles[resource.String()] = append(info.ResourceRoles[resource.String()], role.String())
+		}
+		return true
+	})
+	info.Scopes = strings.Fields(gjson.GetBytes(tokenJSON, "scope").String())
+	for _, scope := range gjson.GetBytes(tokenJSON, "scp").Array() {
+		if !contains(info.Scopes, scope.String()) {
+			info.Scopes = append(info.Scopes, scope.String())
+		}
+	}
+	return info
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthInfo(t *testing.T) {
+	m, err := NewMiddleware()
+	require.NoError(t, err)
+
+	var info AuthInfo
+	var ok bool
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, ok = AuthInfoFromContext(r.Context())
+	}))
+
+	payload := `{
+		"sub": "user",
+		"iss": "https://idp/realms/neo",
+		"realm_access": {"roles": ["admin", "user"]},
+		"resource_access": {"orders": {"roles": ["read"]}, "billing": {}},
+		"scope": "openid profile",
+		"scp": ["profile", "email"]
+	}`
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", bearerWithPayload(payload))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.True(t, ok)
+	assert.Equal(t, AuthInfo{
+		Subject:       "user",
+		Issuer:        "https://idp/realms/neo",
+		RealmRoles:    []string{"admin", "user"},
+		ResourceRoles: map[string][]string{"orders": {"read"}},
+		Scopes:        []string{"openid", "profile", "email"},
+		Token:         r.Header.Get("Authorization")[len("Bearer "):],
+	}, info)
+	assert.True(t, info.HasRealmRole("admin"))
+	assert.True(t, info.HasResourceRole("orders", "read"))
+	assert.False(t, info.HasResourceRole("billing", "read"))
+	assert.True(t, info.HasScope("email"))
+	assert.False(t, info.HasScope("admin"))
+}
+
+func TestEncodeAuthInfo(t *testing.T) {
+	info := AuthInfo{
+		Subject:       "user",
+		RealmRoles:    []string{"admin"},
+		ResourceRoles: map[string][]string{"orders": {"read"}},
+		Scopes:        []string{"openid"},
+		Token:         "secret",
+	}
+	value, err := EncodeAuthInfo(info)
+	require.NoError(t, err)
+
+	decoded, err := DecodeAuthInfo(value)
+	require.NoError(t, err)
+	info.Token = ""
+	assert.Equal(t, info, decoded, "everything but the raw token should be encoded")
+
+	_, err = DecodeAuthInfo("not base64!")
+	assert.Error(t, err)
+	_, err = DecodeAuthInfo("bm90IGpzb24")
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// ErrNoClientCertificate is returned when token is bound to a certificate, but the request has no client certificate.
+	ErrNoClientCertificate = errors.New("certificate-bound token requires client certificate")
+	// ErrCertificateMismatch is returned when token is bound to another certificate than the client certificate
+	// of the request, or it is not bound to a certificate although binding is required.
+	ErrCertificateMismatch = errors.New("token is not bound to the client certificate")
+)
+
+// certificateBinding validates cnf claim of tokens against client certificate of requests.
+type certificateBinding struct {
+	required    bool
+	certificate func(r *http.Request) *x509.Certificate
+}
+
+// WithCertificateBinding makes the middleware validate certificate-bound tokens (RFC 8705): SHA-256 thumbprint
+// of the client certificate must match "x5t#S256" member of "cnf" claim, otherwise the token is rejected with
+// ErrNoClientCertificate or ErrCertificateMismatch. With required true, tokens without the claim are rejected too,
+// otherwise they are accepted, so that deployments can move to bound tokens gradually.
+//
+// Certificate is taken from the TLS connection of the request, unless certificate function is given, e.g.
+// CertificateFromHeader when TLS is terminated by a proxy.
+func WithCertificateBinding(required bool, certificate func(r *http.Request) *x509.Certificate) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if certificate == nil {
+			certificate = tlsClientCertificate
+		}
+		c.certificateBinding = &certificateBinding{required: required, certificate: certificate}
+		return c, nil
+	}
+}
+
+// CertificateFromHeader returns function taking client certificate from given request header, which contains
+// URL encoded PEM certificate, e.g. $ssl_client_escaped_cert of nginx. The header must be set by a trusted proxy,
+// which removes it from incoming requests.
+func CertificateFromHeader(header string) func(r *http.Request) *x509.Certificate {
+	return func(r *http.Request) *x509.Certificate {
+		value, err := url.QueryUnescape(r.Header.Get(header))
+		if err != nil {
+			return nil
+		}
+		block, _ := pem.Decode([]byte(value))
+		if block == nil {
+			return nil
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		return certificate
+	}
+}
+
+// CertificateThumbprint returns base64url encoded SHA-256 thumbprint of certificate used in "x5t#S256" confirmation.
+func CertificateThumbprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func tlsClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+func (b *certificateBinding) check(r *http.Request, tokenJSON []byte) error {
+	confirmation := gjson.GetBytes(tokenJSON, `cnf.x5t\#S256`)
+	if !confirmation.Exists() {
+		if b.required {
+			return ErrCertificateMismatch
+		}
+		return nil
+	}
+
+	certificate := b.certificate(r)
+	if certificate == nil {
+		return ErrNoClientCertificate
+	}
+	if subtle.ConstantTimeCompare([]byte(confirmation.String()), []byte(CertificateThumbprint(certificate))) != 1 {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
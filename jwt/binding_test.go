@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClientCertificate(t *testing.T, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certificate
+}
+
+func TestCertificateBinding(t *testing.T) {
+	client := newClientCertificate(t, "client")
+	other := newClientCertificate(t, "other")
+	bound := fmt.Sprintf(`{"sub":"user","cnf":{"x5t#S256":%q}}`, CertificateThumbprint(client))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+	serve := func(m Middleware, payload string, certificate *x509.Certificate) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", bearerWithPayload(payload))
+		if certificate != nil {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+		}
+		w := httptest.NewRecorder()
+		m.Handler(ok).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("optional", func(t *testing.T) {
+		m, err := NewMiddleware(WithCertificateBinding(false, nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, serve(m, bound, client))
+		assert.Equal(t, http.StatusUnauthorized, serve(m, bound, other))
+		assert.Equal(t, http.StatusUnauthorized, serve(m, bound, nil))
+		assert.Equal(t, http.StatusOK, serve(m, `{"sub":"user"}`, nil), "unbound token should be accepted")
+	})
+
+	t.Run("required", func(t *testing.T) {
+		m, err := NewMiddleware(WithCertificateBinding(true, nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, serve(m, bound, client))
+		assert.Equal(t, http.StatusUnauthorized, serve(m, `{"sub":"user"}`, client))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		b := certificateBinding{required: true, certificate: tlsClientCertificate}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		assert.ErrorIs(t, b.check(r, []byte(bound)), ErrNoClientCertificate)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}
+		assert.ErrorIs(t, b.check(r, []byte(bound)), ErrCertificateMismatch)
+		assert.Equal(t, "certificate_mismatch", rejectReason(ErrCertificateMismatch))
+	})
+}
+
+func TestCertificateFromHeader(t *testing.T) {
+	client := newClientCertificate(t, "client")
+	escaped := url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: client.Raw})))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Nil(t, CertificateFromHeader("X-Client-Cert")(r))
+	r.Header.Set("X-Client-Cert", "garbage")
+	assert.Nil(t, CertificateFromHeader("X-Client-Cert")(r))
+	r.Header.Set("X-Client-Cert", escaped)
+	certificate := CertificateFromHeader("X-Client-Cert")(r)
+	require.NotNil(t, certificate)
+	assert.Equal(t, client.Raw, certificate.Raw)
+}
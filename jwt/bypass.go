@@ -0,0 +1,30 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+)
+
+// DevelopmentBypassEnv is the environment variable, which must be set to true to enable WithDevelopmentBypass.
+const DevelopmentBypassEnv = "JWT_DEVELOPMENT_BYPASS"
+
+// WithDevelopmentBypass makes the middleware treat requests without Authorization header as if they had
+// a valid token with given JSON claims, e.g. `{"sub":"developer","realm_access":{"roles":["admin"]}}`,
+// so that protected endpoints can be used in local development without a running identity provider.
+// Requests with a token are processed as usual.
+//
+// The option has no effect unless JWT_DEVELOPMENT_BYPASS environment variable is set to true, so it can't
+// be enabled accidentally in production by the code alone. Never set the variable in production.
+func WithDevelopmentBypass(claimsJSON string) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if !json.Valid([]byte(claimsJSON)) {
+			return c, errors.New("development bypass claims are not valid json")
+		}
+		if enabled, _ := strconv.ParseBool(os.Getenv(DevelopmentBypassEnv)); enabled {
+			c.developmentClaims = []byte(claimsJSON)
+		}
+		return c, nil
+	}
+}
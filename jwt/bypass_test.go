@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevelopmentBypass(t *testing.T) {
+	claims := `{"sub":"developer","roles":["admin"]}`
+	subKey := contextKey{"sub"}
+	handler := func(m Middleware) http.Handler {
+		return m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.Context().Value(subKey).(string)))
+		}))
+	}
+	options := []func(conf) (conf, error){
+		WithDevelopmentBypass(claims),
+		WithRequiredRoles("roles", "admin"),
+		WithClaimsToExtract(map[string]interface{}{"sub": subKey}),
+	}
+
+	t.Run("disabled without env", func(t *testing.T) {
+		m, err := NewMiddleware(options...)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		handler(m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("enabled with env", func(t *testing.T) {
+		t.Setenv(DevelopmentBypassEnv, "true")
+		m, err := NewMiddleware(options...)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		handler(m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "developer", w.Body.String())
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", bearerWithPayload(`{"sub":"user","roles":["user"]}`))
+		w = httptest.NewRecorder()
+		handler(m).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code, "requests with token should be processed as usual")
+	})
+
+	t.Run("invalid claims", func(t *testing.T) {
+		_, err := NewMiddleware(WithDevelopmentBypass(`{"sub":`))
+		assert.Error(t, err)
+	})
+}
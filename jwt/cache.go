@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+const (
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+var cacheCounter = metrics.RegisterCounterVec("token_cache_total", "jwt",
+	"Total number of token cache lookups by result.", "result")
+
+// WithTokenCache enables LRU cache of decoded and verified tokens, so that repeated requests with the same
+// bearer token skip decoding and signature verification. Cache holds at most size tokens keyed by token hash,
+// each for ttl, but never past the token exp claim. Scopes and claims are still checked on every request.
+func WithTokenCache(size int, ttl time.Duration) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if size <= 0 || ttl <= 0 {
+			return c, errors.New("token cache size and ttl must be positive")
+		}
+		c.cache = newTokenCache(size, ttl)
+		return c, nil
+	}
+}
+
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	payload []byte
+	expires time.Time
+}
+
+type tokenCache struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+	now     func() time.Time
+	lookups metrics.CounterVec
+}
+
+func newTokenCache(size int, ttl time.Duration) *tokenCache {
+	return &tokenCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[[sha256.Size]byte]*list.Element, size),
+		lru:     list.New(),
+		now:     time.Now,
+		lookups: cacheCounter,
+	}
+}
+
+// get returns decoded payload of given bearer token, if it's cached and not expired.
+func (c *tokenCache) get(bearer []byte) ([]byte, bool) {
+	key := sha256.Sum256(bearer)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.lookups.GetCustomCounter(cacheMiss).Inc()
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		c.lookups.GetCustomCounter(cacheMiss).Inc()
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	c.lookups.GetCustomCounter(cacheHit).Inc()
+	return entry.payload, true
+}
+
+// add caches decoded payload of given bearer token. Tokens already expired are not cached.
+func (c *tokenCache) add(bearer, payload []byte) {
+	now := c.now()
+	expires := now.Add(c.ttl)
+	if exp := gjson.GetBytes(payload, "exp"); exp.Exists() {
+		if tokenExpires := time.Unix(exp.Int(), 0); tokenExpires.Before(expires) {
+			expires = tokenExpires
+		}
+	}
+	if !now.Before(expires) {
+		return
+	}
+
+	key := sha256.Sum256(bearer)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).expires = expires
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, payload: payload, expires: expires})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bearerWithExp(exp int64) string {
+	payload := fmt.Sprintf(`{"exp": %d, "sub": "user"}`, exp)
+	return "ignored." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".ignored"
+}
+
+func TestTokenCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newTokenCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.add([]byte("a"), []byte(`{"exp": 1030}`))
+	cache.add([]byte("b"), []byte(`{}`))
+	cache.add([]byte("expired"), []byte(`{"exp": 999}`))
+
+	payload, ok := cache.get([]byte("a"))
+	require.True(t, ok)
+	assert.Equal(t, `{"exp": 1030}`, string(payload))
+	_, ok = cache.get([]byte("expired"))
+	assert.False(t, ok, "expired token should not be cached")
+
+	cache.add([]byte("c"), []byte(`{}`))
+	_, ok = cache.get([]byte("b"))
+	assert.False(t, ok, "least recently used token should be evicted")
+	_, ok = cache.get([]byte("a"))
+	assert.True(t, ok)
+
+	now = now.Add(30 * time.Second)
+	_, ok = cache.get([]byte("a"))
+	assert.False(t, ok, "token should expire at its exp claim")
+	_, ok = cache.get([]byte("c"))
+	assert.True(t, ok)
+
+	now = now.Add(30 * time.Second)
+	_, ok = cache.get([]byte("c"))
+	assert.False(t, ok, "token should expire after ttl")
+}
+
+func TestMiddlewareWithTokenCache(t *testing.T) {
+	_, err := NewMiddleware(WithTokenCache(0, time.Minute))
+	assert.Error(t, err)
+
+	mw, err := NewMiddleware(
+		WithTokenCache(10, time.Minute),
+		WithClaimsToExtract(map[string]interface{}{"sub": otherKey}),
+	)
+	require.NoError(t, err)
+
+	var subjects []interface{}
+	handler := mw.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		subjects = append(subjects, r.Context().Value(otherKey))
+	}))
+
+	before := scrapeTokenMetrics(t)
+	bearer := bearerWithExp(time.Now().Add(time.Hour).Unix())
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, []interface{}{"user", "user", "user"}, subjects)
+
+	after := scrapeTokenMetrics(t)
+	for result, expected := range map[string]float64{cacheMiss: 1, cacheHit: 2} {
+		v, ok := after.Value("com_metrics_jwt_token_cache_total", map[string]string{"result": result})
+		require.True(t, ok, result)
+		prev, _ := before.Value("com_metrics_jwt_token_cache_total", map[string]string{"result": result})
+		assert.Equal(t, expected, v-prev, result)
+	}
+}
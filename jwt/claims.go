@@ -0,0 +1,68 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/tidwall/gjson"
+)
+
+// ErrClaimsNotValid is returned when token claims can't be unmarshalled into the type given with WithClaimsInto.
+var ErrClaimsNotValid = errors.New("token claims do not match expected type")
+
+// typedClaims unmarshals token claims into values of a type and puts them into request context.
+type typedClaims struct {
+	path    string
+	typ     reflect.Type
+	pointer bool
+	key     interface{}
+}
+
+// WithClaimsInto makes the middleware unmarshal token payload into a new value of the type of prototype for every
+// request and put it into request context with ctxKey, e.g. WithClaimsInto(MyClaims{}, claimsKey) stores MyClaims
+// and WithClaimsInto(&MyClaims{}, claimsKey) stores *MyClaims. Fields are unmarshalled with encoding/json, so
+// the struct can use json tags for claim names. Tokens, which don't match the type, are rejected with ErrClaimsNotValid.
+func WithClaimsInto(prototype interface{}, ctxKey interface{}) func(conf) (conf, error) {
+	return WithClaimsAtPathInto("", prototype, ctxKey)
+}
+
+// WithClaimsAtPathInto is like WithClaimsInto, but unmarshals the claim at given json path, parsable by
+// github.com/tidwall/gjson library, e.g. "resource_access.my-client". Missing claim is rejected with ErrClaimNotExists.
+func WithClaimsAtPathInto(path string, prototype interface{}, ctxKey interface{}) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if prototype == nil || ctxKey == nil {
+			return c, errors.New("prototype and context key are required")
+		}
+		claims := typedClaims{path: path, typ: reflect.TypeOf(prototype), key: ctxKey}
+		if claims.typ.Kind() == reflect.Ptr {
+			claims.typ = claims.typ.Elem()
+			claims.pointer = true
+		}
+		// Append to a copy, so that derived configurations don't share the slice.
+		c.typedClaims = append(append([]typedClaims{}, c.typedClaims...), claims)
+		return c, nil
+	}
+}
+
+// value returns claims of tokenJSON unmarshalled into a new value.
+func (t typedClaims) value(tokenJSON []byte) (interface{}, error) {
+	raw := tokenJSON
+	if t.path != "" {
+		claim := gjson.GetBytes(tokenJSON, t.path)
+		if !claim.Exists() {
+			return nil, ErrClaimNotExists
+		}
+		raw = []byte(claim.Raw)
+	}
+
+	value := reflect.New(t.typ)
+	if err := json.Unmarshal(raw, value.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrClaimsNotValid, err)
+	}
+	if !t.pointer {
+		value = value.Elem()
+	}
+	return value.Interface(), nil
+}
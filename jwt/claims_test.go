@@ -0,0 +1,103 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClaims struct {
+	Subject string `json:"sub"`
+	Access  struct {
+		Roles []string `json:"roles"`
+	} `json:"realm_access"`
+}
+
+type clientAccess struct {
+	Roles []string `json:"roles"`
+}
+
+type claimsKey string
+
+func TestClaimsInto(t *testing.T) {
+	const payload = `{"sub":"user","realm_access":{"roles":["admin","viewer"]},"resource_access":{"client":{"roles":["reader"]}}}`
+
+	m, err := NewMiddleware(
+		WithClaimsInto(testClaims{}, claimsKey("claims")),
+		WithClaimsInto(&testClaims{}, claimsKey("pointer")),
+		WithClaimsAtPathInto("resource_access.client", clientAccess{}, claimsKey("client")),
+	)
+	require.NoError(t, err)
+
+	var claims testClaims
+	var pointer *testClaims
+	var access clientAccess
+	h := m.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		claims = r.Context().Value(claimsKey("claims")).(testClaims)
+		pointer = r.Context().Value(claimsKey("pointer")).(*testClaims)
+		access = r.Context().Value(claimsKey("client")).(clientAccess)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", bearerWithPayload(payload))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user", claims.Subject)
+	assert.Equal(t, []string{"admin", "viewer"}, claims.Access.Roles)
+	assert.Equal(t, claims, *pointer)
+	assert.Equal(t, []string{"reader"}, access.Roles)
+}
+
+func TestClaimsIntoRejectsInvalidClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  func(conf) (conf, error)
+		payload string
+		err     error
+	}{
+		{
+			name:    "type mismatch",
+			option:  WithClaimsInto(testClaims{}, claimsKey("claims")),
+			payload: `{"sub":1}`,
+			err:     ErrClaimsNotValid,
+		},
+		{
+			name:    "missing path",
+			option:  WithClaimsAtPathInto("resource_access.client", clientAccess{}, claimsKey("client")),
+			payload: `{"sub":"user"}`,
+			err:     ErrClaimNotExists,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handlerErr error
+			m, err := NewMiddleware(test.option, WithErrorHandler(func(_ http.ResponseWriter, _ *http.Request, err error) {
+				handlerErr = err
+			}))
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", bearerWithPayload(test.payload))
+			m.Handler(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+				t.Error("handler must not be called")
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.True(t, errors.Is(handlerErr, test.err), handlerErr)
+		})
+	}
+}
+
+func TestClaimsIntoRequiresPrototypeAndKey(t *testing.T) {
+	_, err := NewMiddleware(WithClaimsInto(nil, claimsKey("claims")))
+	assert.Error(t, err)
+
+	_, err = NewMiddleware(WithClaimsInto(testClaims{}, nil))
+	assert.Error(t, err)
+}
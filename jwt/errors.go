@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrInsufficientScope is returned when token does not contain all scopes required with WithRequiredScopes.
+var ErrInsufficientScope = errors.New("token does not have required scope")
+
+const problemContentType = "application/problem+json"
+
+// problem is an RFC 7807 problem details response body.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// WithRealm sets realm reported in WWW-Authenticate header of the default error handler.
+func WithRealm(realm string) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		c.realm = realm
+		return c, nil
+	}
+}
+
+// WithProblemJSON makes the default error handler write application/problem+json response body
+// instead of plain text error message.
+func WithProblemJSON(problemJSON bool) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		c.problemJSON = problemJSON
+		return c, nil
+	}
+}
+
+// WithRequiredScopes makes the middleware reject tokens which do not contain all given scopes
+// in "scope" claim (space separated string) or "scp" claim (list of strings) with ErrInsufficientScope.
+func WithRequiredScopes(scopes ...string) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		c.requiredScopes = scopes
+		return c, nil
+	}
+}
+
+// writeError is the default error handler. It responds according to RFC 6750:
+// 401 with WWW-Authenticate challenge for missing or invalid token and 403 for insufficient scope.
+// 503 is responded when revocation list is not available.
+func (c conf) writeError(w http.ResponseWriter, _ *http.Request, err error) {
+	status := http.StatusUnauthorized
+	challenge := []string{}
+	if c.realm != "" {
+		challenge = append(challenge, fmt.Sprintf("realm=%q", c.realm))
+	}
+
+	switch {
+	case errors.Is(err, ErrNoAuthHeader), errors.Is(err, ErrNoBearerToken):
+		// No error code, when request does not contain bearer token at all.
+	case errors.Is(err, ErrInsufficientScope):
+		status = http.StatusForbidden
+		challenge = append(challenge, `error="insufficient_scope"`, fmt.Sprintf("scope=%q", strings.Join(c.requiredScopes, " ")))
+	case errors.Is(err, ErrInsufficientRole):
+		status = http.StatusForbidden
+		challenge = append(challenge, `error="insufficient_scope"`)
+	case errors.Is(err, ErrRevocationUnavailable):
+		// Token may be valid, but it can't be accepted until revocation list is available again.
+		status = http.StatusServiceUnavailable
+	default:
+		challenge = append(challenge, `error="invalid_token"`, fmt.Sprintf("error_description=%q", err.Error()))
+	}
+
+	if len(challenge) == 0 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	} else {
+		w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(challenge, ", "))
+	}
+
+	if !c.problemJSON {
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	})
+}
@@ -0,0 +1,103 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultErrorHandler(t *testing.T) {
+	scopedToken := "Bearer ignored." + base64.RawURLEncoding.EncodeToString([]byte(`{"scope": "read write"}`)) + ".ignored"
+	listScopedToken := "Bearer ignored." + base64.RawURLEncoding.EncodeToString([]byte(`{"scp": ["read", "admin"]}`)) + ".ignored"
+
+	tests := []struct {
+		name                    string
+		options                 []func(conf) (conf, error)
+		authHeader              string
+		expectedStatusCode      int
+		expectedWWWAuthenticate string
+	}{
+		{
+			name:                    "missing token",
+			expectedStatusCode:      http.StatusUnauthorized,
+			expectedWWWAuthenticate: "Bearer",
+		},
+		{
+			name:                    "missing token with realm",
+			options:                 []func(conf) (conf, error){WithRealm("example")},
+			authHeader:              "Basic dXNlcjpwYXNz",
+			expectedStatusCode:      http.StatusUnauthorized,
+			expectedWWWAuthenticate: `Bearer realm="example"`,
+		},
+		{
+			name:                    "invalid token",
+			authHeader:              "Bearer invalid_jwt",
+			expectedStatusCode:      http.StatusUnauthorized,
+			expectedWWWAuthenticate: `Bearer error="invalid_token", error_description="failed to decode a bearer token"`,
+		},
+		{
+			name:                    "insufficient scope",
+			options:                 []func(conf) (conf, error){WithRequiredScopes("read", "admin")},
+			authHeader:              scopedToken,
+			expectedStatusCode:      http.StatusForbidden,
+			expectedWWWAuthenticate: `Bearer error="insufficient_scope", scope="read admin"`,
+		},
+		{
+			name:               "sufficient scope",
+			options:            []func(conf) (conf, error){WithRequiredScopes("read", "write")},
+			authHeader:         scopedToken,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "sufficient scope in scp claim",
+			options:            []func(conf) (conf, error){WithRequiredScopes("admin")},
+			authHeader:         listScopedToken,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			mw, err := NewMiddleware(test.options...)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.authHeader != "" {
+				r.Header.Set("Authorization", test.authHeader)
+			}
+			w := httptest.NewRecorder()
+			mw.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, r)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedWWWAuthenticate, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	mw, err := NewMiddleware(WithProblemJSON(true))
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer invalid_jwt")
+	w := httptest.NewRecorder()
+	mw.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	body := problem{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, problem{
+		Type:   "about:blank",
+		Title:  "Unauthorized",
+		Status: http.StatusUnauthorized,
+		Detail: ErrDecodingBearer.Error(),
+	}, body)
+}
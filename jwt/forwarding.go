@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// ClaimsSignatureHeader carries expiry and HMAC-SHA256 signature of the headers forwarded with
+	// WithClaimForwarding in form "<expiry unix seconds>.<base64url signature>".
+	ClaimsSignatureHeader = "X-Claims-Signature"
+	// claimsSignatureTTL limits validity of forwarded claims of tokens without exp claim or with later expiry.
+	claimsSignatureTTL = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidClaimsSignature is returned by VerifyForwardedClaims when forwarded claim headers are not signed
+	// with the shared key.
+	ErrInvalidClaimsSignature = errors.New("forwarded claims signature is not valid")
+	// ErrExpiredClaimsSignature is returned by VerifyForwardedClaims when signature of forwarded claims is expired.
+	ErrExpiredClaimsSignature = errors.New("forwarded claims signature is expired")
+)
+
+type claimForwarding struct {
+	// claim paths keyed by canonical header names
+	claims  map[string]string
+	headers []string
+	key     []byte
+}
+
+// WithClaimForwarding sets verified claims of the token to request headers, so that services proxying the request
+// can forward them to upstream services. Keys in the map are claim paths parsable by github.com/tidwall/gjson library
+// and values are header names, e.g. {"sub": "X-User-Id"}. Claims missing from the token are forwarded as empty.
+//
+// Incoming values of the headers are always removed, so clients can't spoof them. Headers are signed with
+// HMAC-SHA256 using given key in ClaimsSignatureHeader, which upstream services check with VerifyForwardedClaims.
+// Signature expires with the token, but at most after five minutes, so captured headers can't be replayed later.
+func WithClaimForwarding(claims map[string]string, key []byte) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if len(key) == 0 {
+			return c, errors.New("claim forwarding key must not be empty")
+		}
+
+		f := &claimForwarding{claims: make(map[string]string, len(claims)), key: key}
+		for path, header := range claims {
+			header = http.CanonicalHeaderKey(header)
+			f.claims[header] = path
+			f.headers = append(f.headers, header)
+		}
+		sort.Strings(f.headers)
+		c.claimForwarding = f
+		return c, nil
+	}
+}
+
+// removeHeaders removes incoming values of forwarded headers.
+func (f *claimForwarding) removeHeaders(h http.Header) {
+	if f == nil {
+		return
+	}
+	for _, header := range f.headers {
+		h.Del(header)
+	}
+	h.Del(ClaimsSignatureHeader)
+}
+
+// setHeaders sets claims of given token payload and their signature to headers.
+func (f *claimForwarding) setHeaders(h http.Header, tokenJSON []byte) {
+	if f == nil {
+		return
+	}
+	for _, header := range f.headers {
+		h.Set(header, gjson.GetBytes(tokenJSON, f.claims[header]).String())
+	}
+
+	expiresAt := time.Now().Add(claimsSignatureTTL).Unix()
+	if exp := gjson.GetBytes(tokenJSON, "exp"); exp.Exists() && exp.Int() < expiresAt {
+		expiresAt = exp.Int()
+	}
+	expiry := strconv.FormatInt(expiresAt, 10)
+	h.Set(ClaimsSignatureHeader, expiry+"."+signHeaders(h, f.headers, f.key, expiry))
+}
+
+// VerifyForwardedClaims checks that given headers of the request were forwarded by a service configured with
+// WithClaimForwarding using the same key and that the signature is not expired. Header names must match
+// the ones given to WithClaimForwarding.
+func VerifyForwardedClaims(r *http.Request, key []byte, headers ...string) error {
+	canonical := make([]string, 0, len(headers))
+	for _, header := range headers {
+		canonical = append(canonical, http.CanonicalHeaderKey(header))
+	}
+	sort.Strings(canonical)
+
+	expiry, signature, ok := strings.Cut(r.Header.Get(ClaimsSignatureHeader), ".")
+	if !ok {
+		return ErrInvalidClaimsSignature
+	}
+	if !hmac.Equal([]byte(signHeaders(r.Header, canonical, key, expiry)), []byte(signature)) {
+		return ErrInvalidClaimsSignature
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return ErrInvalidClaimsSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrExpiredClaimsSignature
+	}
+	return nil
+}
+
+// signHeaders returns base64 encoded HMAC-SHA256 of expiry and given headers, which must be sorted canonical names.
+func signHeaders(h http.Header, headers []string, key []byte, expiry string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(expiry))
+	mac.Write([]byte{'\n'})
+	for _, header := range headers {
+		mac.Write([]byte(header))
+		mac.Write([]byte{':'})
+		mac.Write([]byte(h.Get(header)))
+		mac.Write([]byte{'\n'})
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
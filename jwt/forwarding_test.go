@@ -0,0 +1,92 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimForwarding(t *testing.T) {
+	key := []byte("shared-secret")
+
+	_, err := NewMiddleware(WithClaimForwarding(map[string]string{"sub": "X-User-Id"}, nil))
+	assert.Error(t, err)
+
+	mw, err := NewMiddleware(
+		WithClaimForwarding(map[string]string{"sub": "x-user-id", "tenant": "X-Tenant"}, key),
+		WithIgnoreErrors(true),
+	)
+	require.NoError(t, err)
+
+	var forwarded http.Header
+	handler := mw.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+bearerWithExp(time.Now().Add(time.Hour).Unix()))
+	r.Header.Set("X-User-Id", "spoofed")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "user", forwarded.Get("X-User-Id"))
+	assert.Equal(t, "", forwarded.Get("X-Tenant"))
+	assert.NotEmpty(t, forwarded.Get(ClaimsSignatureHeader))
+
+	upstream := httptest.NewRequest(http.MethodGet, "/", nil)
+	upstream.Header = forwarded
+	assert.NoError(t, VerifyForwardedClaims(upstream, key, "X-User-Id", "x-tenant"))
+	assert.ErrorIs(t, VerifyForwardedClaims(upstream, []byte("other"), "X-User-Id", "X-Tenant"), ErrInvalidClaimsSignature)
+
+	upstream.Header.Set("X-User-Id", "admin")
+	assert.ErrorIs(t, VerifyForwardedClaims(upstream, key, "X-User-Id", "X-Tenant"), ErrInvalidClaimsSignature)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-Id", "spoofed")
+	r.Header.Set(ClaimsSignatureHeader, "spoofed")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Empty(t, forwarded.Get("X-User-Id"), "spoofed header should be removed when token is missing")
+	assert.Empty(t, forwarded.Get(ClaimsSignatureHeader))
+}
+
+func TestClaimForwardingExpiry(t *testing.T) {
+	key := []byte("shared-secret")
+	mw, err := NewMiddleware(WithClaimForwarding(map[string]string{"sub": "X-User-Id"}, key), WithIgnoreErrors(true))
+	require.NoError(t, err)
+
+	var forwarded http.Header
+	handler := mw.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+	}))
+
+	exp := time.Now().Add(time.Minute).Unix()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+bearerWithExp(exp))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, strings.HasPrefix(forwarded.Get(ClaimsSignatureHeader), strconv.FormatInt(exp, 10)+"."),
+		"signature should expire with the token")
+
+	r.Header.Set("Authorization", "Bearer "+bearerWithExp(time.Now().Add(time.Hour).Unix()))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	expiry, _, _ := strings.Cut(forwarded.Get(ClaimsSignatureHeader), ".")
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, expiresAt, time.Now().Add(claimsSignatureTTL).Unix())
+
+	upstream := httptest.NewRequest(http.MethodGet, "/", nil)
+	upstream.Header.Set("X-User-Id", "user")
+	stale := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	upstream.Header.Set(ClaimsSignatureHeader, stale+"."+signHeaders(upstream.Header, []string{"X-User-Id"}, key, stale))
+	assert.ErrorIs(t, VerifyForwardedClaims(upstream, key, "X-User-Id"), ErrExpiredClaimsSignature)
+
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	_, signature, _ := strings.Cut(upstream.Header.Get(ClaimsSignatureHeader), ".")
+	upstream.Header.Set(ClaimsSignatureHeader, future+"."+signature)
+	assert.ErrorIs(t, VerifyForwardedClaims(upstream, key, "X-User-Id"), ErrInvalidClaimsSignature,
+		"expiry can't be changed without the key")
+}
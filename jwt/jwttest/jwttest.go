@@ -0,0 +1,202 @@
+// Package jwttest provides signed tokens for tests of services protected with jwt.Middleware.
+//
+// Issuer mints tokens signed with its own key pair, so protected endpoints can be tested without a running
+// identity provider, e.g. Keycloak. Configure the middleware with jwt.WithVerifier(issuer.Verifier()) or
+// jwt.WithCertificatePem(issuer.CertificatePEM()) and jwt.WithSignatureVerification().
+package jwttest
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/jwt"
+)
+
+// Claims are token claims. Standard time claims exp, iat and nbf are added by Issuer unless set.
+type Claims map[string]interface{}
+
+// WithRealmRoles returns copy of claims with given realm roles in realm_access.roles claim.
+func (c Claims) WithRealmRoles(roles ...string) Claims {
+	return c.with("realm_access", map[string]interface{}{"roles": roles})
+}
+
+// WithResourceRoles returns copy of claims with given roles of the resource in resource_access.<resource>.roles
+// claim. Roles of other resources are kept.
+func (c Claims) WithResourceRoles(resource string, roles ...string) Claims {
+	access := map[string]interface{}{}
+	if existing, ok := c["resource_access"].(map[string]interface{}); ok {
+		for k, v := range existing {
+			access[k] = v
+		}
+	}
+	access[resource] = map[string]interface{}{"roles": roles}
+	return c.with("resource_access", access)
+}
+
+// WithScopes returns copy of claims with given scopes in space separated scope claim.
+func (c Claims) WithScopes(scopes ...string) Claims {
+	return c.with("scope", strings.Join(scopes, " "))
+}
+
+func (c Claims) with(key string, value interface{}) Claims {
+	claims := make(Claims, len(c)+1)
+	for k, v := range c {
+		claims[k] = v
+	}
+	claims[key] = value
+	return claims
+}
+
+// Opt is a functional option type for Issuer.
+type Opt func(*Issuer) error
+
+// WithAlgorithm sets signature algorithm of the tokens, jwt.AlgRS256 by default. RSA algorithms use
+// a generated 2048 bit RSA key and jwt.AlgEdDSA uses a generated Ed25519 key.
+func WithAlgorithm(alg string) Opt {
+	return func(i *Issuer) error {
+		switch alg {
+		case jwt.AlgRS256, jwt.AlgRS384, jwt.AlgRS512, jwt.AlgPS256, jwt.AlgPS384, jwt.AlgPS512, jwt.AlgEdDSA:
+			i.alg = alg
+			return nil
+		default:
+			return fmt.Errorf("unsupported algorithm %q", alg)
+		}
+	}
+}
+
+// WithKey sets key used for signing instead of a generated one, *rsa.PrivateKey or ed25519.PrivateKey
+// matching the algorithm.
+func WithKey(key crypto.Signer) Opt {
+	return func(i *Issuer) error {
+		switch key.(type) {
+		case *rsa.PrivateKey, ed25519.PrivateKey:
+			i.key = key
+			return nil
+		default:
+			return fmt.Errorf("unsupported key type %T", key)
+		}
+	}
+}
+
+// WithExpiry sets lifetime of the tokens, one hour by default. Negative expiry mints expired tokens.
+func WithExpiry(expiry time.Duration) Opt {
+	return func(i *Issuer) error {
+		i.expiry = expiry
+		return nil
+	}
+}
+
+// Issuer mints signed tokens for tests.
+type Issuer struct {
+	alg    string
+	key    crypto.Signer
+	expiry time.Duration
+}
+
+var hashes = map[string]crypto.Hash{
+	jwt.AlgRS256: crypto.SHA256,
+	jwt.AlgRS384: crypto.SHA384,
+	jwt.AlgRS512: crypto.SHA512,
+	jwt.AlgPS256: crypto.SHA256,
+	jwt.AlgPS384: crypto.SHA384,
+	jwt.AlgPS512: crypto.SHA512,
+}
+
+// NewIssuer returns Issuer with given options, the test fails if an option is not valid.
+func NewIssuer(t testing.TB, opts ...Opt) *Issuer {
+	i := &Issuer{alg: jwt.AlgRS256, expiry: time.Hour}
+	for _, opt := range opts {
+		require.NoError(t, opt(i), "failed to apply option for jwttest.Issuer")
+	}
+
+	if i.key == nil {
+		var err error
+		if i.alg == jwt.AlgEdDSA {
+			_, i.key, err = ed25519.GenerateKey(rand.Reader)
+		} else {
+			i.key, err = rsa.GenerateKey(rand.Reader, 2048)
+		}
+		require.NoError(t, err, "failed to generate key")
+	}
+	_, isEd25519 := i.key.(ed25519.PrivateKey)
+	require.Equal(t, i.alg == jwt.AlgEdDSA, isEd25519, "key type %T doesn't match algorithm %s", i.key, i.alg)
+	return i
+}
+
+// Algorithm returns signature algorithm of the tokens, e.g. for jwt.WithAllowedAlgorithms.
+func (i *Issuer) Algorithm() string {
+	return i.alg
+}
+
+// Verifier returns verifier of the tokens for jwt.WithVerifier.
+func (i *Issuer) Verifier() jwt.Verifier {
+	verifier, err := jwt.NewKeyVerifier(i.key.Public())
+	if err != nil {
+		panic(err) // key type is validated in NewIssuer
+	}
+	return verifier
+}
+
+// CertificatePEM returns self-signed certificate of the issuer key for jwt.WithCertificatePem.
+func (i *Issuer) CertificatePEM(t testing.TB) string {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jwttest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, i.key.Public(), i.key)
+	require.NoError(t, err, "failed to create certificate")
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// Token returns token with given claims signed by the issuer.
+func (i *Issuer) Token(t testing.TB, claims Claims) string {
+	now := time.Now()
+	payload := Claims{"iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(i.expiry).Unix()}
+	for k, v := range claims {
+		payload[k] = v
+	}
+	header, err := json.Marshal(map[string]string{"alg": i.alg, "typ": "JWT"})
+	require.NoError(t, err)
+	body, err := json.Marshal(payload)
+	require.NoError(t, err, "failed to marshal claims")
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	signature, err := i.sign([]byte(signingInput))
+	require.NoError(t, err, "failed to sign token")
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Authorize sets Authorization header of the request with bearer token having given claims.
+func (i *Issuer) Authorize(t testing.TB, r *http.Request, claims Claims) {
+	r.Header.Set("Authorization", "Bearer "+i.Token(t, claims))
+}
+
+func (i *Issuer) sign(signingInput []byte) ([]byte, error) {
+	if i.alg == jwt.AlgEdDSA {
+		return i.key.Sign(rand.Reader, signingInput, crypto.Hash(0))
+	}
+	hash := hashes[i.alg]
+	h := hash.New()
+	h.Write(signingInput)
+	if i.alg[0] == 'P' {
+		return i.key.Sign(rand.Reader, h.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+	}
+	return i.key.Sign(rand.Reader, h.Sum(nil), hash)
+}
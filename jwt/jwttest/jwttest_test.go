@@ -0,0 +1,56 @@
+package jwttest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/jwt"
+	"github.com/phanitejak/kptgolib/jwt/jwttest"
+)
+
+func TestIssuer(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	claims := jwttest.Claims{"sub": "user"}.WithRealmRoles("admin").WithResourceRoles("service", "read").WithScopes("openid", "profile")
+
+	for _, alg := range []string{jwt.AlgRS256, jwt.AlgPS256, jwt.AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			issuer := jwttest.NewIssuer(t, jwttest.WithAlgorithm(alg))
+			m, err := jwt.NewMiddleware(
+				jwt.WithCertificatePem(issuer.CertificatePEM(t)),
+				jwt.WithSignatureVerification(),
+				jwt.WithAllowedAlgorithms(issuer.Algorithm()),
+				jwt.WithRequiredRoles("realm_access.roles", "admin"),
+				jwt.WithRequiredScopes("profile"),
+			)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			issuer.Authorize(t, r, claims)
+			w := httptest.NewRecorder()
+			m.Handler(ok).ServeHTTP(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			r = httptest.NewRequest(http.MethodGet, "/", nil)
+			jwttest.NewIssuer(t, jwttest.WithAlgorithm(alg)).Authorize(t, r, claims)
+			w = httptest.NewRecorder()
+			m.Handler(ok).ServeHTTP(w, r)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "token of another issuer should be rejected")
+		})
+	}
+}
+
+func TestIssuerExpiry(t *testing.T) {
+	issuer := jwttest.NewIssuer(t, jwttest.WithExpiry(-time.Minute))
+	expiry, err := jwt.TokenExpiry(issuer.Token(t, nil))
+	require.NoError(t, err)
+	assert.True(t, expiry.Before(time.Now()))
+
+	expiry, err = jwt.TokenExpiry(issuer.Token(t, jwttest.Claims{"exp": 42}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), expiry.Unix())
+}
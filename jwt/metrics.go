@@ -0,0 +1,69 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+const (
+	resultAccepted = "accepted"
+	resultRejected = "rejected"
+)
+
+var (
+	tokensCounter = metrics.RegisterCounterVec("tokens_total", "jwt",
+		"Total number of tokens processed by the middleware.", "result", "reason")
+	verificationDuration = metrics.RegisterSummary("token_verification_milliseconds", "jwt",
+		"Time spent on processing a token in milliseconds.")
+)
+
+// observeToken records outcome of processing a single token.
+func observeToken(start time.Time, err error) {
+	verificationDuration.ObserveDuration(start)
+	if err != nil {
+		tokensCounter.GetCustomCounter(resultRejected, rejectReason(err)).Inc()
+		return
+	}
+	tokensCounter.GetCustomCounter(resultAccepted, "").Inc()
+}
+
+func rejectReason(err error) string {
+	var corruptInputErr base64.CorruptInputError
+	switch {
+	case errors.Is(err, ErrNoAuthHeader):
+		return "no_auth_header"
+	case errors.Is(err, ErrNoBearerToken):
+		return "no_bearer_token"
+	case errors.Is(err, ErrDecodingBearer), errors.As(err, &corruptInputErr):
+		return "malformed_token"
+	case errors.Is(err, ErrNotValidJSON):
+		return "invalid_json"
+	case errors.Is(err, ErrClaimNotExists):
+		return "missing_claim"
+	case errors.Is(err, ErrInsufficientScope):
+		return "insufficient_scope"
+	case errors.Is(err, ErrInsufficientRole):
+		return "insufficient_role"
+	case errors.Is(err, ErrTokenRevoked):
+		return "revoked"
+	case errors.Is(err, ErrRevocationUnavailable):
+		return "revocation_unavailable"
+	case errors.Is(err, ErrNoClientCertificate):
+		return "no_client_certificate"
+	case errors.Is(err, ErrCertificateMismatch):
+		return "certificate_mismatch"
+	case errors.Is(err, ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, ErrTokenNotYetValid):
+		return "not_yet_valid"
+	case errors.Is(err, ErrAlgorithmNotAllowed):
+		return "algorithm_not_allowed"
+	case errors.Is(err, ErrInvalidSignature):
+		return "invalid_signature"
+	default:
+		return "other"
+	}
+}
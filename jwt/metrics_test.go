@@ -0,0 +1,69 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareMetrics(t *testing.T) {
+	mw, err := NewMiddleware()
+	require.NoError(t, err)
+
+	before := scrapeTokenMetrics(t)
+
+	handler := mw.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for _, authHeader := range []string{
+		"",
+		"Basic abc",
+		"Bearer invalid_jwt",
+		"Bearer ignored." + base64.RawURLEncoding.EncodeToString([]byte(jwtPayloadJSON)) + ".ignored",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authHeader != "" {
+			r.Header.Set("Authorization", authHeader)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	after := scrapeTokenMetrics(t)
+
+	for _, labels := range []map[string]string{
+		{"result": "rejected", "reason": "no_auth_header"},
+		{"result": "rejected", "reason": "no_bearer_token"},
+		{"result": "rejected", "reason": "malformed_token"},
+		{"result": "accepted", "reason": ""},
+	} {
+		v, ok := after.Value("com_metrics_jwt_tokens_total", labels)
+		require.True(t, ok, labels)
+		prev, _ := before.Value("com_metrics_jwt_tokens_total", labels)
+		assert.Equal(t, float64(1), v-prev, labels)
+	}
+	assert.Contains(t, after, "com_metrics_jwt_token_verification_milliseconds")
+}
+
+func TestRejectReason(t *testing.T) {
+	assert.Equal(t, "invalid_json", rejectReason(ErrNotValidJSON))
+	assert.Equal(t, "missing_claim", rejectReason(ErrClaimNotExists))
+	assert.Equal(t, "malformed_token", rejectReason(base64.CorruptInputError(1)))
+	assert.Equal(t, "invalid_signature", rejectReason(ErrInvalidSignature))
+	assert.Equal(t, "invalid_signature", rejectReason(fmt.Errorf("%w: crypto/rsa: verification error", ErrInvalidSignature)))
+	assert.Equal(t, "algorithm_not_allowed", rejectReason(fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, "HS256")))
+	assert.Equal(t, "other", rejectReason(errors.New("some error")))
+}
+
+func scrapeTokenMetrics(t *testing.T) metrics.MetricFamilies {
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+	return families
+}
@@ -4,16 +4,14 @@ package jwt
 
 import (
 	"bytes"
-	"context"
-	"crypto"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
-	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/tidwall/gjson"
@@ -51,10 +49,23 @@ type conf struct {
 
 	// error handling function
 	// will be called when token processing fails
+	// defaults to conf.writeError
 	errorHandle func(w http.ResponseWriter, r *http.Request, err error)
 
-	// Trusted public key to verify JWT signature
-	publicKey *rsa.PublicKey
+	// realm reported in WWW-Authenticate header by the default error handler
+	realm string
+
+	// set to true, if the default error handler should write application/problem+json body
+	problemJSON bool
+
+	// scopes, which must all be present in the token
+	requiredScopes []string
+
+	// Verifier of JWT signatures
+	verifier Verifier
+
+	// Signature algorithms accepted by signature verification, RS256 if nil
+	allowedAlgorithms map[string]bool
 
 	// Flag to verify key signature
 	signatureVerificationIsEnabled bool
@@ -62,6 +73,30 @@ type conf struct {
 	// Context Key to store extracted bearer token in the request context
 	// If tokenContextKey is nil - token will not be stored in the request context
 	tokenContextKey interface{}
+
+	// cache of decoded and verified tokens, nil if caching is disabled
+	cache *tokenCache
+
+	// claims forwarded as request headers, nil if forwarding is disabled
+	claimForwarding *claimForwarding
+
+	// roles, one of which must be present in the token, empty if roles are not required
+	requiredRoles requiredRoles
+
+	// set when errorHandle is the default error handler
+	defaultErrorHandle bool
+
+	// claims used for requests without Authorization header, nil unless development bypass is enabled
+	developmentClaims []byte
+
+	// revocation list check, nil if revocation is not checked
+	revocation *revocationCheck
+
+	// validation of certificate-bound tokens, nil if binding is not validated
+	certificateBinding *certificateBinding
+
+	// claims unmarshalled into typed values and put into request context
+	typedClaims []typedClaims
 }
 
 func WithClaimsToExtract(claimsToExtract map[string]interface{}) func(conf) (conf, error) {
@@ -71,8 +106,8 @@ func WithClaimsToExtract(claimsToExtract map[string]interface{}) func(conf) (con
 	}
 }
 
-// A trusted certificate to verify JWT signature
-// If present, token signature will be automatically verified.
+// A trusted certificate to verify JWT signature, with RSA or Ed25519 public key.
+// Signature is verified once verification is enabled with WithSignatureVerification.
 func WithCertificatePem(certificatePem string) func(conf) (conf, error) {
 	return func(c conf) (conf, error) {
 		block, _ := pem.Decode([]byte(certificatePem))
@@ -85,12 +120,12 @@ func WithCertificatePem(certificatePem string) func(conf) (conf, error) {
 			return c, err
 		}
 
-		publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
-		if !ok {
-			return c, errors.New("only rsa keys are supported")
+		verifier, err := NewKeyVerifier(certificate.PublicKey)
+		if err != nil {
+			return c, err
 		}
 
-		c.publicKey = publicKey
+		c.verifier = verifier
 		return c, nil
 	}
 }
@@ -125,20 +160,20 @@ func WithStoredTokenInContext(tokenContextKey interface{}) func(conf) (conf, err
 
 func NewMiddleware(options ...func(conf) (conf, error)) (Middleware, error) {
 	c := conf{
-		claimsToExtract:        map[string]interface{}{},
-		requireToken:           true,
-		ignoreErrors:           false,
-		ignoreNotExistingClaim: false,
-		errorHandle: func(w http.ResponseWriter, r *http.Request, err error) {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = fmt.Fprint(w, err)
-		},
-		publicKey: nil,
-		// TODO: Add support for signature verification - use some library, write more tests and enable this flag
+		claimsToExtract:                map[string]interface{}{},
+		requireToken:                   true,
+		ignoreErrors:                   false,
+		ignoreNotExistingClaim:         false,
+		errorHandle:                    nil,
+		verifier:                       nil,
 		signatureVerificationIsEnabled: false,
 		tokenContextKey:                nil,
 	}
 
+	return newMiddleware(c, options)
+}
+
+func newMiddleware(c conf, options []func(conf) (conf, error)) (Middleware, error) {
 	for _, option := range options {
 		cTemp, err := option(c)
 		if err != nil {
@@ -147,6 +182,11 @@ func NewMiddleware(options ...func(conf) (conf, error)) (Middleware, error) {
 		c = cTemp
 	}
 
+	if c.errorHandle == nil {
+		c.errorHandle = c.writeError
+		c.defaultErrorHandle = true
+	}
+
 	return Middleware{c: c}, nil
 }
 
@@ -176,76 +216,111 @@ func (m Middleware) Handle(h httprouter.Handle) httprouter.Handle {
 	}
 }
 
-func (m Middleware) processToken(_ http.ResponseWriter, r *http.Request) error {
+func (m Middleware) processToken(_ http.ResponseWriter, r *http.Request) (err error) {
+	m.c.claimForwarding.removeHeaders(r.Header)
 	if !m.c.requireToken {
 		return nil
 	}
 
+	start := time.Now()
+	defer func() { observeToken(start, err) }()
+
+	bearer, tokenJSONBytes, err := m.tokenFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if m.c.certificateBinding != nil && bearer != nil {
+		if err := m.c.certificateBinding.check(r, tokenJSONBytes); err != nil {
+			return err
+		}
+	}
+
+	claims, err := m.claims(r.Context(), bearer, tokenJSONBytes)
+	if err != nil {
+		return err
+	}
+
+	m.c.claimForwarding.setHeaders(r.Header, tokenJSONBytes)
+
+	*r = *r.WithContext(claims.Context(r.Context()))
+
+	return nil
+}
+
+// tokenFromRequest returns bearer token from Authorization header of the request and its verified JSON payload.
+// With development bypass, requests without Authorization header get development claims and nil bearer.
+func (m Middleware) tokenFromRequest(r *http.Request) (bearer []byte, payload []byte, err error) {
 	authHeader := []byte(r.Header.Get("Authorization"))
 	if len(authHeader) == 0 {
-		return ErrNoAuthHeader
+		if m.c.developmentClaims != nil {
+			return nil, m.c.developmentClaims, nil
+		}
+		return nil, nil, ErrNoAuthHeader
 	}
 
 	if !bytes.HasPrefix(authHeader, []byte("bearer ")) && !bytes.HasPrefix(authHeader, []byte("Bearer ")) {
-		return ErrNoBearerToken
+		return nil, nil, ErrNoBearerToken
+	}
+
+	bearer = bytes.TrimSpace(authHeader[6:])
+	payload, err = m.decodeToken(bearer)
+	return bearer, payload, err
+}
+
+// decodeToken returns verified JSON payload of given bearer token, from cache if enabled.
+func (m Middleware) decodeToken(bearer []byte) ([]byte, error) {
+	if m.c.cache != nil {
+		if payload, ok := m.c.cache.get(bearer); ok {
+			return payload, nil
+		}
 	}
 
-	bearer := bytes.TrimSpace(authHeader[6:])
 	parts := bytes.Split(bearer, []byte{'.'})
 	if len(parts) != numberOfJWTParts {
-		return ErrDecodingBearer
+		return nil, ErrDecodingBearer
 	}
 
 	tokenJSONBytes := make([]byte, base64.RawURLEncoding.DecodedLen(len(parts[1])))
 	n, err := base64.RawURLEncoding.Decode(tokenJSONBytes, parts[1])
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tokenJSONBytes = tokenJSONBytes[:n]
 
 	if !json.Valid(tokenJSONBytes) {
-		return ErrNotValidJSON
+		return nil, ErrNotValidJSON
 	}
 
 	if m.c.signatureVerificationIsEnabled {
-		if err := validateTokenSignature(bearer[:len(parts[0])+len(parts[1])+1], parts[2], m.c.publicKey); err != nil {
-			return err
+		if err := m.c.verifySignature(bearer, parts); err != nil {
+			return nil, err
 		}
 	}
 
-	for path, key := range m.c.claimsToExtract {
-		claim := gjson.GetBytes(tokenJSONBytes, path)
-
-		if !claim.Exists() && !m.c.ignoreNotExistingClaim {
-			return ErrClaimNotExists
-		}
-
-		newR := r.WithContext(context.WithValue(r.Context(), key, claim.Value()))
-		*r = *newR
+	if m.c.cache != nil {
+		m.c.cache.add(bearer, tokenJSONBytes)
 	}
-
-	if m.c.tokenContextKey != nil {
-		newR := r.WithContext(context.WithValue(r.Context(), m.c.tokenContextKey, string(bearer)))
-		*r = *newR
-	}
-
-	return nil
+	return tokenJSONBytes, nil
 }
 
-func validateTokenSignature(signedToken, signature []byte, key *rsa.PublicKey) error {
-	// TODO: use some library to verify all kinds of signatures
-	h := crypto.SHA256.New()
-	_, err := h.Write(signedToken)
-	if err != nil {
-		return err
+func hasScopes(tokenJSON []byte, required []string) bool {
+	if len(required) == 0 {
+		return true
 	}
 
-	sigBytes := make([]byte, base64.RawURLEncoding.DecodedLen(len(signature)))
-	n, err := base64.RawURLEncoding.Decode(sigBytes, signature)
-	if err != nil {
-		return err
+	granted := map[string]bool{}
+	for _, scope := range strings.Fields(gjson.GetBytes(tokenJSON, "scope").String()) {
+		granted[scope] = true
+	}
+	for _, scope := range gjson.GetBytes(tokenJSON, "scp").Array() {
+		granted[scope.String()] = true
 	}
-	sigBytes = sigBytes[:n]
 
-	return rsa.VerifyPKCS1v15(key, crypto.SHA256, h.Sum(nil), sigBytes)
+	for _, scope := range required {
+		if !granted[scope] {
+			return false
+		}
+	}
+	return true
 }
@@ -92,7 +92,7 @@ func TestMiddleware(t *testing.T) {
 			givenKey:                   nil,
 			authHeader:                 "",
 			expectedResponseBody:       "no Authorization header found in request",
-			expectedResponseStatusCode: http.StatusBadRequest,
+			expectedResponseStatusCode: http.StatusUnauthorized,
 			assertValue: func(t *testing.T, value interface{}) {
 				assert.Nil(t, value)
 			},
@@ -104,7 +104,7 @@ func TestMiddleware(t *testing.T) {
 			givenKey:                   nil,
 			authHeader:                 "Bearer invalid_jwt",
 			expectedResponseBody:       "failed to decode a bearer token",
-			expectedResponseStatusCode: http.StatusBadRequest,
+			expectedResponseStatusCode: http.StatusUnauthorized,
 			assertValue: func(t *testing.T, value interface{}) {
 				assert.Nil(t, value)
 			},
@@ -116,7 +116,7 @@ func TestMiddleware(t *testing.T) {
 			givenKey:                   nil,
 			authHeader:                 "Bearer ignored_header_value..ignored_signature",
 			expectedResponseBody:       "token is not a valid json",
-			expectedResponseStatusCode: http.StatusBadRequest,
+			expectedResponseStatusCode: http.StatusUnauthorized,
 			assertValue: func(t *testing.T, value interface{}) {
 				assert.Nil(t, value)
 			},
@@ -187,7 +187,7 @@ func TestMiddleware(t *testing.T) {
 			givenKey:                   "some_other_claim",
 			authHeader:                 "Bearer ignored." + base64.RawURLEncoding.EncodeToString([]byte(`{"some_claim": "some_value"}`)) + ".ignored",
 			expectedResponseBody:       `expected claim does not exist in the token`,
-			expectedResponseStatusCode: http.StatusBadRequest,
+			expectedResponseStatusCode: http.StatusUnauthorized,
 			assertValue: func(t *testing.T, value interface{}) {
 				assert.Nil(t, value)
 			},
@@ -0,0 +1,182 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+var (
+	// ErrTokenRevoked is returned when token ID or session ID of the token is in the revocation list.
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRevocationUnavailable is returned when revocation list can't be checked and fail-closed policy is used.
+	ErrRevocationUnavailable = errors.New("token revocation list is not available")
+)
+
+var (
+	revocationCacheCounter = metrics.RegisterCounterVec("revocation_cache_total", "jwt",
+		"Total number of revocation list cache lookups by result.", "result")
+	revocationFailures = metrics.RegisterCounterVec("revocation_check_failures_total", "jwt",
+		"Total number of failed revocation list checks by policy.", "policy")
+)
+
+// RevocationList tells whether tokens have been revoked, e.g. on logout or forced revocation of a user's sessions.
+type RevocationList interface {
+	// IsRevoked reports whether token with given ID (jti claim) or session with given ID (sid claim) has been
+	// revoked. Either ID may be empty when the token doesn't have the claim.
+	IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error)
+}
+
+// RevocationListFunc is a function implementing RevocationList. It can be used to check revocation list kept
+// in a shared store, e.g. with go-redis, where revoked IDs are stored with expiry of the longest token lifetime:
+//
+//	jwt.RevocationListFunc(func(ctx context.Context, tokenID, sessionID string) (bool, error) {
+//		n, err := rdb.Exists(ctx, "revoked:jti:"+tokenID, "revoked:sid:"+sessionID).Result()
+//		return n > 0, err
+//	})
+type RevocationListFunc func(ctx context.Context, tokenID, sessionID string) (bool, error)
+
+// IsRevoked calls f.
+func (f RevocationListFunc) IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error) {
+	return f(ctx, tokenID, sessionID)
+}
+
+// HTTPRevocationList checks revocation list with GET request to url with jti and sid query parameters.
+// The service must respond with 200 and JSON body {"revoked": true|false}.
+type HTTPRevocationList struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPRevocationList returns HTTPRevocationList for given url. Client with 2 second timeout is used if client is nil.
+func NewHTTPRevocationList(url string, client *http.Client) *HTTPRevocationList {
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Second}
+	}
+	return &HTTPRevocationList{url: url, client: client}
+}
+
+// IsRevoked asks the revocation service whether token or session has been revoked.
+func (l *HTTPRevocationList) IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error) {
+	u, err := url.Parse(l.url)
+	if err != nil {
+		return false, err
+	}
+	query := u.Query()
+	query.Set("jti", tokenID)
+	query.Set("sid", sessionID)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d from revocation list", resp.StatusCode)
+	}
+	var body struct {
+		Revoked *bool `json:"revoked"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("failed to decode revocation list response: %w", err)
+	}
+	if body.Revoked == nil {
+		return false, errors.New("revocation list response has no revoked field")
+	}
+	return *body.Revoked, nil
+}
+
+// revocationCheck checks tokens against revocation list with optional caching.
+type revocationCheck struct {
+	list     RevocationList
+	failOpen bool
+	cache    *tokenCache
+}
+
+// WithRevocationList makes the middleware reject tokens, which jti or sid claim is in the revocation list, with
+// ErrTokenRevoked. Tokens without those claims can't be revoked. If the list can't be checked, tokens are
+// accepted when failOpen is true and rejected with ErrRevocationUnavailable otherwise, which the default error
+// handler responds with 503. Use WithRevocationCache to avoid checking the list on every request.
+func WithRevocationList(list RevocationList, failOpen bool) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if list == nil {
+			return c, errors.New("revocation list must not be nil")
+		}
+		c.revocation = &revocationCheck{list: list, failOpen: failOpen}
+		return c, nil
+	}
+}
+
+// WithRevocationCache caches results of revocation list checks, at most size results each for ttl.
+// Revoked tokens are accepted until cached result expires, so ttl should be short, e.g. some seconds.
+// Must be given after WithRevocationList.
+func WithRevocationCache(size int, ttl time.Duration) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if c.revocation == nil {
+			return c, errors.New("revocation cache requires revocation list")
+		}
+		if size <= 0 || ttl <= 0 {
+			return c, errors.New("revocation cache size and ttl must be positive")
+		}
+		cache := newTokenCache(size, ttl)
+		cache.lookups = revocationCacheCounter
+		check := *c.revocation
+		check.cache = cache
+		c.revocation = &check
+		return c, nil
+	}
+}
+
+// check returns error if token with given payload has been revoked.
+func (r *revocationCheck) check(ctx context.Context, tokenJSON []byte) error {
+	tokenID := gjson.GetBytes(tokenJSON, "jti").String()
+	sessionID := gjson.GetBytes(tokenJSON, "sid").String()
+	if tokenID == "" && sessionID == "" {
+		return nil
+	}
+
+	key := []byte(tokenID + "\x00" + sessionID)
+	if r.cache != nil {
+		if cached, ok := r.cache.get(key); ok {
+			return revokedError(cached[0] == 1)
+		}
+	}
+
+	revoked, err := r.list.IsRevoked(ctx, tokenID, sessionID)
+	if err != nil {
+		if r.failOpen {
+			revocationFailures.GetCustomCounter("fail_open").Inc()
+			return nil
+		}
+		revocationFailures.GetCustomCounter("fail_closed").Inc()
+		return fmt.Errorf("%w: %s", ErrRevocationUnavailable, err)
+	}
+	if r.cache != nil {
+		result := []byte{0}
+		if revoked {
+			result[0] = 1
+		}
+		r.cache.add(key, result)
+	}
+	return revokedError(revoked)
+}
+
+func revokedError(revoked bool) error {
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
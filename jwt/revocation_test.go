@@ -0,0 +1,107 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationList(t *testing.T) {
+	revoked := map[string]bool{"revoked-jti": true, "revoked-sid": true}
+	calls := 0
+	list := RevocationListFunc(func(_ context.Context, tokenID, sessionID string) (bool, error) {
+		calls++
+		if tokenID == "broken" {
+			return false, errors.New("list not available")
+		}
+		return revoked[tokenID] || revoked[sessionID], nil
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+	serve := func(m Middleware, payload string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", bearerWithPayload(payload))
+		w := httptest.NewRecorder()
+		m.Handler(ok).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("fail closed", func(t *testing.T) {
+		m, err := NewMiddleware(WithRevocationList(list, false))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, serve(m, `{"sub":"user"}`), "token without ids can't be revoked")
+		assert.Equal(t, http.StatusOK, serve(m, `{"jti":"valid","sid":"valid"}`))
+		assert.Equal(t, http.StatusUnauthorized, serve(m, `{"jti":"revoked-jti"}`))
+		assert.Equal(t, http.StatusUnauthorized, serve(m, `{"jti":"valid","sid":"revoked-sid"}`))
+		assert.Equal(t, http.StatusServiceUnavailable, serve(m, `{"jti":"broken"}`))
+	})
+
+	t.Run("fail open", func(t *testing.T) {
+		m, err := NewMiddleware(WithRevocationList(list, true))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, serve(m, `{"jti":"broken"}`))
+		assert.Equal(t, http.StatusUnauthorized, serve(m, `{"jti":"revoked-jti"}`))
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		m, err := NewMiddleware(WithRevocationList(list, false), WithRevocationCache(10, time.Minute))
+		require.NoError(t, err)
+
+		calls = 0
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, serve(m, `{"jti":"valid"}`))
+			assert.Equal(t, http.StatusUnauthorized, serve(m, `{"jti":"revoked-jti"}`))
+			assert.Equal(t, http.StatusServiceUnavailable, serve(m, `{"jti":"broken"}`))
+		}
+		assert.Equal(t, 5, calls, "only successful checks should be cached")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewMiddleware(WithRevocationList(nil, false))
+		assert.Error(t, err)
+		_, err = NewMiddleware(WithRevocationCache(10, time.Minute))
+		assert.Error(t, err)
+		_, err = NewMiddleware(WithRevocationList(list, false), WithRevocationCache(0, time.Minute))
+		assert.Error(t, err)
+	})
+}
+
+func TestHTTPRevocationList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("jti") {
+		case "revoked":
+			_, _ = w.Write([]byte(`{"revoked":true}`))
+		case "valid":
+			_, _ = w.Write([]byte(`{"revoked":false}`))
+		case "invalid":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	list := NewHTTPRevocationList(server.URL+"/revocations", nil)
+	ctx := context.Background()
+
+	isRevoked, err := list.IsRevoked(ctx, "revoked", "")
+	require.NoError(t, err)
+	assert.True(t, isRevoked)
+
+	isRevoked, err = list.IsRevoked(ctx, "valid", "session")
+	require.NoError(t, err)
+	assert.False(t, isRevoked)
+
+	_, err = list.IsRevoked(ctx, "invalid", "")
+	assert.Error(t, err)
+
+	_, err = list.IsRevoked(ctx, "error", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/tidwall/gjson"
+)
+
+// ErrInsufficientRole is returned when token does not contain any of the roles required with WithRequiredRoles.
+var ErrInsufficientRole = errors.New("token does not have required role")
+
+type requiredRoles struct {
+	claim string
+	roles []string
+}
+
+// WithRequiredRoles makes the middleware reject tokens which do not contain at least one of given roles
+// in the claim at given json path with ErrInsufficientRole. Claim can be a list of strings
+// or a space separated string, e.g. "realm_access.roles".
+func WithRequiredRoles(claim string, roles ...string) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if claim == "" || len(roles) == 0 {
+			return c, errors.New("claim and at least one role are required")
+		}
+		c.requiredRoles = requiredRoles{claim: claim, roles: roles}
+		return c, nil
+	}
+}
+
+func (r requiredRoles) granted(tokenJSON []byte) bool {
+	if len(r.roles) == 0 {
+		return true
+	}
+
+	claim := gjson.GetBytes(tokenJSON, r.claim)
+	granted := map[string]bool{}
+	if claim.IsArray() {
+		for _, role := range claim.Array() {
+			granted[role.String()] = true
+		}
+	} else {
+		for _, role := range strings.Fields(claim.String()) {
+			granted[role] = true
+		}
+	}
+
+	for _, role := range r.roles {
+		if granted[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// With returns copy of the middleware with given options applied on top of its configuration.
+// Derived middlewares share the verification backend, i.e. trusted key and token cache,
+// so that route groups can have different requirements without verifying the same token twice.
+// Default error handler follows the derived configuration, e.g. realm set with WithRealm.
+func (m Middleware) With(options ...func(conf) (conf, error)) (Middleware, error) {
+	c := m.c
+	if c.defaultErrorHandle {
+		c.errorHandle = nil
+		c.defaultErrorHandle = false
+	}
+	return newMiddleware(c, options)
+}
+
+// RouteGroup registers httprouter handles under a path prefix, protected by a middleware
+// derived with Middleware.With.
+type RouteGroup struct {
+	router *httprouter.Router
+	prefix string
+	m      Middleware
+}
+
+// Group returns RouteGroup of router for given path prefix and options:
+//
+//	public, err := m.Group(router, "/public", jwt.WithRequiredToken(false))
+//	admin, err := m.Group(router, "/admin", jwt.WithRequiredRoles("roles", "admin"))
+//	admin.Handle(http.MethodDelete, "/users/:id", deleteUser)
+func (m Middleware) Group(router *httprouter.Router, prefix string, options ...func(conf) (conf, error)) (*RouteGroup, error) {
+	derived, err := m.With(options...)
+	if err != nil {
+		return nil, err
+	}
+	return &RouteGroup{router: router, prefix: strings.TrimSuffix(prefix, "/"), m: derived}, nil
+}
+
+// Handle registers handle for given method and path relative to the group prefix.
+func (g *RouteGroup) Handle(method, path string, handle httprouter.Handle) {
+	g.router.Handle(method, g.prefix+path, g.m.Handle(handle))
+}
+
+// Handler registers handler for given method and path relative to the group prefix.
+func (g *RouteGroup) Handler(method, path string, handler http.Handler) {
+	g.router.Handler(method, g.prefix+path, g.m.Handler(handler))
+}
+
+// Route mounts chi sub-router for given pattern, protected by a middleware derived with Middleware.With:
+//
+//	err := m.Route(r, "/admin", func(r chi.Router) {
+//		r.Delete("/users/{id}", deleteUser)
+//	}, jwt.WithRequiredRoles("roles", "admin"))
+func (m Middleware) Route(r chi.Router, pattern string, fn func(r chi.Router), options ...func(conf) (conf, error)) error {
+	derived, err := m.With(options...)
+	if err != nil {
+		return err
+	}
+	r.Route(pattern, func(r chi.Router) {
+		r.Use(derived.Handler)
+		fn(r)
+	})
+	return nil
+}
@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bearerWithPayload(payload string) string {
+	return "Bearer ignored." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".ignored"
+}
+
+func TestRouteGroups(t *testing.T) {
+	base, err := NewMiddleware(WithRealm("service"), WithTokenCache(10, time.Minute))
+	require.NoError(t, err)
+
+	router := httprouter.New()
+	public, err := base.Group(router, "/public/", WithRequiredToken(false))
+	require.NoError(t, err)
+	admin, err := base.Group(router, "/admin", WithRequiredRoles("roles", "admin"), WithRealm("admin"))
+	require.NoError(t, err)
+	_, err = base.Group(router, "/invalid", WithRequiredRoles("roles"))
+	assert.Error(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	public.Handler(http.MethodGet, "/info", ok)
+	admin.Handle(http.MethodGet, "/users/:id", func(w http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+		assert.Equal(t, "1", p.ByName("id"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{"public without token", "/public/info", "", http.StatusOK, ""},
+		{"admin without token", "/admin/users/1", "", http.StatusUnauthorized, `Bearer realm="admin"`},
+		{"admin without role", "/admin/users/1", bearerWithPayload(`{"roles": ["user"]}`), http.StatusForbidden,
+			`Bearer realm="admin", error="insufficient_scope"`},
+		{"admin with role list", "/admin/users/1", bearerWithPayload(`{"roles": ["user", "admin"]}`), http.StatusOK, ""},
+		{"admin with role string", "/admin/users/1", bearerWithPayload(`{"roles": "user admin"}`), http.StatusOK, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, tc.challenge, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+
+	assert.Same(t, base.c.cache, admin.m.c.cache, "derived middleware should share token cache")
+}
+
+func TestChiRoute(t *testing.T) {
+	base, err := NewMiddleware()
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	require.NoError(t, base.Route(r, "/public", func(r chi.Router) {
+		r.Get("/info", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	}, WithRequiredToken(false)))
+	require.NoError(t, base.Route(r, "/private", func(r chi.Router) {
+		r.Get("/info", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	}))
+
+	for path, status := range map[string]int{"/public/info": http.StatusOK, "/private/info": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, status, w.Code, path)
+	}
+}
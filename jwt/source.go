@@ -0,0 +1,267 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+// DefaultServiceAccountTokenPath is the path of Kubernetes service account token mounted to pods.
+// Projected tokens with custom audience are mounted to a path given in the pod spec.
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+
+const (
+	defaultRefreshAhead = 0.2
+	defaultRefreshRetry = 10 * time.Second
+)
+
+// Results of token refresh reported by token_refresh_total metric.
+const (
+	refreshSuccess = "success"
+	refreshFailure = "failure"
+)
+
+var (
+	refreshCounter = metrics.RegisterCounterVec("token_refresh_total", "jwt",
+		"Total number of token refreshes by source and result: success or failure.", "source", "result")
+	_ = metrics.RegisterGaugeVecFunc("token_age_seconds", "jwt",
+		"Age of the token currently used by a refreshing token source in seconds.", tokenAges, "source")
+
+	// runningSources holds sources with Run in progress by name, for token age metric.
+	runningSources sync.Map
+)
+
+// TokenSource provides tokens for outgoing requests.
+type TokenSource interface {
+	// Token returns a token and its expiry time.
+	Token(ctx context.Context) (token string, expiry time.Time, err error)
+}
+
+// TokenSourceFunc is a function implementing TokenSource, e.g. a client credentials request to the IdP.
+type TokenSourceFunc func(ctx context.Context) (string, time.Time, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, time.Time, error) {
+	return f(ctx)
+}
+
+// FileTokenSource reads token from a file on every call, e.g. Kubernetes projected service account token
+// rotated by kubelet, which can be used for Vault style JWT login flows. Expiry is read from the exp claim.
+func FileTokenSource(path string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		token := string(bytes.TrimSpace(b))
+		expiry, err := TokenExpiry(token)
+		return token, expiry, err
+	})
+}
+
+// TokenExpiry returns time of the exp claim of given token without verifying it.
+func TokenExpiry(token string) (time.Time, error) {
+	parts := bytes.Split([]byte(token), []byte("."))
+	if len(parts) != 3 {
+		return time.Time{}, ErrDecodingBearer
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(string(parts[1]))
+	if err != nil {
+		return time.Time{}, err
+	}
+	exp := gjson.GetBytes(payload, "exp")
+	if !exp.Exists() {
+		return time.Time{}, fmt.Errorf("%w: exp", ErrClaimNotExists)
+	}
+	return time.Unix(exp.Int(), 0), nil
+}
+
+// RefreshingTokenSource caches token of another source and refreshes it in background before it expires,
+// so that callers never wait for the IdP as long as Run is running.
+type RefreshingTokenSource struct {
+	name         string
+	source       TokenSource
+	refreshAhead float64
+	retry        time.Duration
+	onRefresh    func(token string, expiry time.Time, err error)
+
+	lock     sync.RWMutex
+	token    string
+	expiry   time.Time
+	obtained time.Time
+}
+
+// RefreshOpt configures RefreshingTokenSource.
+type RefreshOpt func(*RefreshingTokenSource) error
+
+// WithRefreshAhead sets fraction of token lifetime, which is left when token is refreshed, 0.2 by default.
+func WithRefreshAhead(fraction float64) RefreshOpt {
+	return func(s *RefreshingTokenSource) error {
+		if fraction <= 0 || fraction >= 1 {
+			return errors.New("refresh ahead fraction must be between 0 and 1")
+		}
+		s.refreshAhead = fraction
+		return nil
+	}
+}
+
+// WithRefreshRetry sets interval of retries after a failed refresh, 10 seconds by default.
+func WithRefreshRetry(interval time.Duration) RefreshOpt {
+	return func(s *RefreshingTokenSource) error {
+		if interval <= 0 {
+			return errors.New("refresh retry interval must be positive")
+		}
+		s.retry = interval
+		return nil
+	}
+}
+
+// WithRefreshCallback sets function called after every refresh attempt, e.g. to log failures.
+// Token is empty when err is not nil.
+func WithRefreshCallback(onRefresh func(token string, expiry time.Time, err error)) RefreshOpt {
+	return func(s *RefreshingTokenSource) error {
+		s.onRefresh = onRefresh
+		return nil
+	}
+}
+
+// NewRefreshingTokenSource returns RefreshingTokenSource wrapping source. Name is used as source label of
+// jwt token_refresh_total and token_age_seconds metrics.
+func NewRefreshingTokenSource(name string, source TokenSource, opts ...RefreshOpt) (*RefreshingTokenSource, error) {
+	s := &RefreshingTokenSource{
+		name:         name,
+		source:       source,
+		refreshAhead: defaultRefreshAhead,
+		retry:        defaultRefreshRetry,
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Token returns cached token, if it has not expired. Otherwise token is refreshed synchronously.
+func (s *RefreshingTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	s.lock.RLock()
+	token, expiry := s.token, s.expiry
+	s.lock.RUnlock()
+	if token != "" && time.Now().Before(expiry) {
+		return token, expiry, nil
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return "", time.Time{}, err
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.token, s.expiry, nil
+}
+
+// Run refreshes token when refresh ahead fraction of its lifetime is left until ctx is done.
+// Failed refreshes are retried after the retry interval.
+func (s *RefreshingTokenSource) Run(ctx context.Context) error {
+	runningSources.Store(s.name, s)
+	defer runningSources.Delete(s.name)
+
+	for {
+		wait := s.retry
+		if err := s.refresh(ctx); err == nil {
+			wait = s.untilRefresh()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Transport returns RoundTripper setting Authorization header with bearer token of the source.
+// If base is nil, http.DefaultTransport is used.
+func (s *RefreshingTokenSource) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		token, _, err := s.Token(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "Bearer "+token)
+		return base.RoundTrip(r)
+	})
+}
+
+func (s *RefreshingTokenSource) refresh(ctx context.Context) error {
+	token, expiry, err := s.source.Token(ctx)
+	if err == nil && token == "" {
+		err = errors.New("token source returned empty token")
+	}
+	if s.onRefresh != nil {
+		s.onRefresh(token, expiry, err)
+	}
+	if err != nil {
+		refreshCounter.GetCustomCounter(s.name, refreshFailure).Inc()
+		return err
+	}
+	refreshCounter.GetCustomCounter(s.name, refreshSuccess).Inc()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.token, s.expiry, s.obtained = token, expiry, time.Now()
+	return nil
+}
+
+// untilRefresh returns time left until refresh ahead fraction of current token lifetime is left.
+func (s *RefreshingTokenSource) untilRefresh() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	lifetime := s.expiry.Sub(s.obtained)
+	wait := time.Until(s.obtained.Add(time.Duration(float64(lifetime) * (1 - s.refreshAhead))))
+	if wait <= 0 {
+		return s.retry
+	}
+	return wait
+}
+
+func (s *RefreshingTokenSource) age() (time.Duration, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.obtained.IsZero() {
+		return 0, false
+	}
+	return time.Since(s.obtained), true
+}
+
+func tokenAges() []metrics.LabeledValue {
+	var values []metrics.LabeledValue
+	runningSources.Range(func(name, source interface{}) bool {
+		if age, ok := source.(*RefreshingTokenSource).age(); ok {
+			values = append(values, metrics.LabeledValue{LabelValues: []string{name.(string)}, Value: age.Seconds()})
+		}
+		return true
+	})
+	return values
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
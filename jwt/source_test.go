@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshingTokenSource(t *testing.T) {
+	var calls atomic.Int32
+	source := TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		n := calls.Add(1)
+		return fmt.Sprintf("token-%d", n), time.Now().Add(100 * time.Millisecond), nil
+	})
+
+	var refreshed atomic.Int32
+	s, err := NewRefreshingTokenSource("test", source, WithRefreshAhead(0.5),
+		WithRefreshCallback(func(token string, expiry time.Time, err error) { refreshed.Add(1) }))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return calls.Load() >= 3 }, time.Second, 5*time.Millisecond,
+		"token should be refreshed before it expires")
+	token, expiry, err := s.Token(context.Background())
+	require.NoError(t, err)
+	assert.Regexp(t, `^token-\d+$`, token)
+	assert.True(t, expiry.After(time.Now()))
+
+	ages := tokenAges()
+	require.Len(t, ages, 1)
+	assert.Equal(t, []string{"test"}, ages[0].LabelValues)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, calls.Load(), refreshed.Load())
+	assert.Empty(t, tokenAges(), "stopped source should not be reported")
+}
+
+func TestRefreshingTokenSourceFailure(t *testing.T) {
+	fail := false
+	source := TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		if fail {
+			return "", time.Time{}, errors.New("idp unavailable")
+		}
+		return "token", time.Now().Add(time.Hour), nil
+	})
+	s, err := NewRefreshingTokenSource("failing", source)
+	require.NoError(t, err)
+
+	before := scrapeTokenMetrics(t)
+	token, _, err := s.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token", token)
+
+	fail = true
+	assert.Error(t, s.refresh(context.Background()))
+
+	after := scrapeTokenMetrics(t)
+	for _, result := range []string{"success", "failure"} {
+		labels := map[string]string{"source": "failing", "result": result}
+		v, ok := after.Value("com_metrics_jwt_token_refresh_total", labels)
+		require.True(t, ok, labels)
+		prev, _ := before.Value("com_metrics_jwt_token_refresh_total", labels)
+		assert.Equal(t, float64(1), v-prev, labels)
+	}
+	token, _, err = s.Token(context.Background())
+	require.NoError(t, err, "valid cached token should be used when refresh fails")
+	assert.Equal(t, "token", token)
+
+	s.expiry = time.Now()
+	_, _, err = s.Token(context.Background())
+	assert.Error(t, err, "expired token should not be used")
+}
+
+func TestRefreshingTokenSourceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	s, err := NewRefreshingTokenSource("transport", TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		return "token", time.Now().Add(time.Hour), nil
+	}))
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: s.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestFileTokenSource(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := "header." + base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp": %d}`, exp.Unix()))) + ".signature"
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte(token+"\n"), 0o600))
+
+	got, expiry, err := FileTokenSource(path).Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, token, got)
+	assert.True(t, exp.Equal(expiry))
+
+	_, _, err = FileTokenSource(filepath.Join(t.TempDir(), "missing")).Token(context.Background())
+	assert.Error(t, err)
+	_, err = TokenExpiry("not-a-token")
+	assert.ErrorIs(t, err, ErrDecodingBearer)
+}
+
+func TestRefreshOptionsInvalid(t *testing.T) {
+	_, err := NewRefreshingTokenSource("invalid", nil, WithRefreshAhead(1))
+	assert.Error(t, err)
+	_, err = NewRefreshingTokenSource("invalid", nil, WithRefreshRetry(0))
+	assert.Error(t, err)
+}
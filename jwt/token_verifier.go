@@ -0,0 +1,159 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// ErrTokenExpired is returned by TokenVerifier when token exp claim is in the past.
+	ErrTokenExpired = errors.New("token is expired")
+	// ErrTokenNotYetValid is returned by TokenVerifier when token nbf claim is in the future.
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+)
+
+// Claims are verified claims of a token returned by TokenVerifier.
+type Claims struct {
+	AuthInfo
+	// JSON is the decoded token payload.
+	JSON json.RawMessage
+	// values are extracted claims and the token keyed by context keys in the order they are put into context.
+	values []claimValue
+}
+
+type claimValue struct {
+	key   interface{}
+	value interface{}
+}
+
+// Value returns claim extracted with WithClaimsToExtract, WithClaimsInto or WithClaimsAtPathInto, or the token
+// stored with WithStoredTokenInContext, by its context key.
+func (c Claims) Value(ctxKey interface{}) (interface{}, bool) {
+	for _, v := range c.values {
+		if v.key == ctxKey {
+			return v.value, true
+		}
+	}
+	return nil, false
+}
+
+// Context returns ctx with extracted claims, stored token and AuthInfo, like the middleware puts them into
+// request context, so that the same authorization code can be used for HTTP requests and messages.
+func (c Claims) Context(ctx context.Context) context.Context {
+	for _, v := range c.values {
+		ctx = context.WithValue(ctx, v.key, v.value)
+	}
+	return ContextWithAuthInfo(ctx, c.AuthInfo)
+}
+
+// TokenVerifier verifies bearer tokens outside of HTTP requests, e.g. tokens in Kafka message headers.
+// It uses the same options, token cache and metrics as the middleware.
+type TokenVerifier struct {
+	m Middleware
+}
+
+// NewTokenVerifier returns TokenVerifier configured with middleware options. Signature verification must be
+// enabled with WithVerifier or WithCertificatePem and WithSignatureVerification. Options handling HTTP requests,
+// e.g. WithErrorHandler, WithIgnoreErrors and WithClaimForwarding, have no effect. WithCertificateBinding and
+// WithDevelopmentBypass can't be used, as there is no client certificate or missing Authorization header.
+func NewTokenVerifier(options ...func(conf) (conf, error)) (*TokenVerifier, error) {
+	m, err := NewMiddleware(options...)
+	if err != nil {
+		return nil, err
+	}
+	if !m.c.signatureVerificationIsEnabled || m.c.verifier == nil {
+		return nil, errors.New("token verifier requires signature verification with a configured verifier")
+	}
+	if m.c.certificateBinding != nil {
+		return nil, errors.New("certificate binding can't be validated without request")
+	}
+	if m.c.developmentClaims != nil {
+		return nil, errors.New("development bypass can't be used without request")
+	}
+	return &TokenVerifier{m: m}, nil
+}
+
+// Verify verifies signature, exp and nbf claims of token with optional "Bearer " prefix and returns its claims.
+func (v *TokenVerifier) Verify(token string) (Claims, error) {
+	return v.VerifyContext(context.Background(), token)
+}
+
+// VerifyContext is like Verify, but ctx is used for checking revocation list.
+func (v *TokenVerifier) VerifyContext(ctx context.Context, token string) (claims Claims, err error) {
+	start := time.Now()
+	defer func() { observeToken(start, err) }()
+
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return Claims{}, ErrNoBearerToken
+	}
+
+	bearer := []byte(token)
+	payload, err := v.m.decodeToken(bearer)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := validAt(payload, time.Now()); err != nil {
+		return Claims{}, err
+	}
+	return v.m.claims(ctx, bearer, payload)
+}
+
+// validAt checks optional exp and nbf claims of token payload against now.
+func validAt(payload []byte, now time.Time) error {
+	if exp := gjson.GetBytes(payload, "exp"); exp.Exists() && !now.Before(time.Unix(exp.Int(), 0)) {
+		return ErrTokenExpired
+	}
+	if nbf := gjson.GetBytes(payload, "nbf"); nbf.Exists() && now.Before(time.Unix(nbf.Int(), 0)) {
+		return ErrTokenNotYetValid
+	}
+	return nil
+}
+
+// claims checks revocation, scopes and roles of verified token and extracts its claims.
+func (m Middleware) claims(ctx context.Context, bearer, tokenJSON []byte) (Claims, error) {
+	if m.c.revocation != nil && bearer != nil {
+		if err := m.c.revocation.check(ctx, tokenJSON); err != nil {
+			return Claims{}, err
+		}
+	}
+
+	if !hasScopes(tokenJSON, m.c.requiredScopes) {
+		return Claims{}, ErrInsufficientScope
+	}
+
+	if !m.c.requiredRoles.granted(tokenJSON) {
+		return Claims{}, ErrInsufficientRole
+	}
+
+	claims := Claims{AuthInfo: newAuthInfo(bearer, tokenJSON), JSON: tokenJSON}
+	for path, key := range m.c.claimsToExtract {
+		claim := gjson.GetBytes(tokenJSON, path)
+
+		if !claim.Exists() && !m.c.ignoreNotExistingClaim {
+			return Claims{}, ErrClaimNotExists
+		}
+		claims.values = append(claims.values, claimValue{key: key, value: claim.Value()})
+	}
+
+	for _, typed := range m.c.typedClaims {
+		value, err := typed.value(tokenJSON)
+		if err != nil {
+			return Claims{}, err
+		}
+		claims.values = append(claims.values, claimValue{key: typed.key, value: value})
+	}
+
+	if m.c.tokenContextKey != nil && bearer != nil {
+		claims.values = append(claims.values, claimValue{key: m.c.tokenContextKey, value: string(bearer)})
+	}
+	return claims, nil
+}
@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenVerifier(t *testing.T) {
+	rsaVerifier, err := NewKeyVerifier(&testRSAKey.PublicKey)
+	require.NoError(t, err)
+
+	v, err := NewTokenVerifier(
+		WithVerifier(rsaVerifier),
+		WithClaimsToExtract(map[string]interface{}{"sub": claimsKey("sub")}),
+		WithClaimsInto(testClaims{}, claimsKey("claims")),
+		WithStoredTokenInContext(claimsKey("token")),
+		WithRequiredScopes("read"),
+	)
+	require.NoError(t, err)
+
+	token := signToken(t, AlgRS256, testRSAKey, `{"sub":"user","scope":"read","realm_access":{"roles":["admin"]}}`)
+	claims, err := v.Verify("Bearer " + token)
+	require.NoError(t, err)
+
+	assert.Equal(t, "user", claims.Subject)
+	assert.True(t, claims.HasRealmRole("admin"))
+	assert.JSONEq(t, `{"sub":"user","scope":"read","realm_access":{"roles":["admin"]}}`, string(claims.JSON))
+	sub, ok := claims.Value(claimsKey("sub"))
+	assert.True(t, ok)
+	assert.Equal(t, "user", sub)
+	_, ok = claims.Value(claimsKey("missing"))
+	assert.False(t, ok)
+
+	ctx := claims.Context(context.Background())
+	assert.Equal(t, "user", ctx.Value(claimsKey("claims")).(testClaims).Subject)
+	assert.Equal(t, token, ctx.Value(claimsKey("token")))
+	info, ok := AuthInfoFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, token, info.Token)
+
+	_, err = v.Verify(token)
+	assert.NoError(t, err, "token without bearer prefix")
+}
+
+func TestTokenVerifierRejects(t *testing.T) {
+	rsaVerifier, err := NewKeyVerifier(&testRSAKey.PublicKey)
+	require.NoError(t, err)
+	v, err := NewTokenVerifier(WithVerifier(rsaVerifier), WithRequiredScopes("write"))
+	require.NoError(t, err)
+
+	before := scrapeTokenMetrics(t)
+
+	_, err = v.Verify("")
+	assert.ErrorIs(t, err, ErrNoBearerToken)
+	_, err = v.Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrDecodingBearer)
+	token := signToken(t, AlgRS256, testRSAKey, `{"sub":"user","scope":"read"}`)
+	_, err = v.Verify(token)
+	assert.ErrorIs(t, err, ErrInsufficientScope)
+	_, err = v.Verify(token[:len(token)-4] + "AAAA")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	after := scrapeTokenMetrics(t)
+	labels := map[string]string{"result": "rejected", "reason": "insufficient_scope"}
+	value, ok := after.Value("com_metrics_jwt_tokens_total", labels)
+	require.True(t, ok)
+	prev, _ := before.Value("com_metrics_jwt_tokens_total", labels)
+	assert.Equal(t, float64(1), value-prev)
+}
+
+func TestTokenVerifierTimeClaims(t *testing.T) {
+	rsaVerifier, err := NewKeyVerifier(&testRSAKey.PublicKey)
+	require.NoError(t, err)
+	v, err := NewTokenVerifier(WithVerifier(rsaVerifier))
+	require.NoError(t, err)
+
+	now := time.Now().Unix()
+	tests := []struct {
+		name    string
+		payload string
+		err     error
+	}{
+		{name: "valid", payload: fmt.Sprintf(`{"sub":"user","nbf":%d,"exp":%d}`, now-60, now+60)},
+		{name: "expired", payload: fmt.Sprintf(`{"sub":"user","exp":%d}`, now-60), err: ErrTokenExpired},
+		{name: "not yet valid", payload: fmt.Sprintf(`{"sub":"user","nbf":%d}`, now+60), err: ErrTokenNotYetValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(signToken(t, AlgRS256, testRSAKey, tt.payload))
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTokenVerifierRequiresSignatureVerification(t *testing.T) {
+	_, err := NewTokenVerifier()
+	assert.Error(t, err)
+	_, err = NewTokenVerifier(WithSignatureVerification())
+	assert.Error(t, err)
+}
+
+func TestTokenVerifierRequestOnlyOptions(t *testing.T) {
+	rsaVerifier, err := NewKeyVerifier(&testRSAKey.PublicKey)
+	require.NoError(t, err)
+	_, err = NewTokenVerifier(WithVerifier(rsaVerifier), WithCertificateBinding(true, nil))
+	assert.Error(t, err)
+	_, err = NewTokenVerifier(WithVerifier(rsaVerifier), WithErrorHandler(func(http.ResponseWriter, *http.Request, error) {}))
+	assert.NoError(t, err)
+}
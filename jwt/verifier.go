@@ -0,0 +1,164 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/tidwall/gjson"
+
+	// Register hash functions used by RSA algorithms.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Signature algorithms supported by NewKeyVerifier.
+const (
+	AlgRS256 = "RS256"
+	AlgRS384 = "RS384"
+	AlgRS512 = "RS512"
+	AlgPS256 = "PS256"
+	AlgPS384 = "PS384"
+	AlgPS512 = "PS512"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	// ErrAlgorithmNotAllowed is returned when token is signed with algorithm not allowed with WithAllowedAlgorithms.
+	ErrAlgorithmNotAllowed = errors.New("token signature algorithm is not allowed")
+	// ErrInvalidSignature is returned when token signature is not valid.
+	ErrInvalidSignature = errors.New("token signature is not valid")
+)
+
+// Verifier verifies token signatures, it can be implemented e.g. by a hardware-backed or FIPS crypto provider.
+// Verifier is called only for algorithms allowed with WithAllowedAlgorithms.
+type Verifier interface {
+	// Verify returns error if signature of signingInput, i.e. encoded header and payload separated by a dot,
+	// is not valid for given algorithm.
+	Verify(alg string, signingInput, signature []byte) error
+}
+
+// VerifierFunc is a function implementing Verifier.
+type VerifierFunc func(alg string, signingInput, signature []byte) error
+
+// Verify calls f.
+func (f VerifierFunc) Verify(alg string, signingInput, signature []byte) error {
+	return f(alg, signingInput, signature)
+}
+
+var rsaHashes = map[string]crypto.Hash{
+	AlgRS256: crypto.SHA256,
+	AlgRS384: crypto.SHA384,
+	AlgRS512: crypto.SHA512,
+	AlgPS256: crypto.SHA256,
+	AlgPS384: crypto.SHA384,
+	AlgPS512: crypto.SHA512,
+}
+
+// NewKeyVerifier returns Verifier using standard library crypto with given public key. RSA keys verify
+// RS256, RS384, RS512, PS256, PS384 and PS512 signatures and Ed25519 keys verify EdDSA signatures.
+func NewKeyVerifier(key crypto.PublicKey) (Verifier, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return VerifierFunc(func(alg string, signingInput, signature []byte) error {
+			hash, ok := rsaHashes[alg]
+			if !ok {
+				return fmt.Errorf("%w: %s can't be verified with RSA key", ErrAlgorithmNotAllowed, alg)
+			}
+			h := hash.New()
+			h.Write(signingInput)
+			var err error
+			if alg[0] == 'P' {
+				err = rsa.VerifyPSS(k, hash, h.Sum(nil), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			} else {
+				err = rsa.VerifyPKCS1v15(k, hash, h.Sum(nil), signature)
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+			}
+			return nil
+		}), nil
+	case ed25519.PublicKey:
+		return VerifierFunc(func(alg string, signingInput, signature []byte) error {
+			if alg != AlgEdDSA {
+				return fmt.Errorf("%w: %s can't be verified with Ed25519 key", ErrAlgorithmNotAllowed, alg)
+			}
+			if !ed25519.Verify(k, signingInput, signature) {
+				return ErrInvalidSignature
+			}
+			return nil
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// WithVerifier enables signature verification with given verifier. Only RS256 signed tokens are accepted,
+// unless other algorithms are allowed with WithAllowedAlgorithms.
+func WithVerifier(verifier Verifier) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if verifier == nil {
+			return c, errors.New("verifier must not be nil")
+		}
+		c.verifier = verifier
+		c.signatureVerificationIsEnabled = true
+		return c, nil
+	}
+}
+
+// WithSignatureVerification enables signature verification with verifier of the certificate
+// given with WithCertificatePem.
+func WithSignatureVerification() func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		c.signatureVerificationIsEnabled = true
+		return c, nil
+	}
+}
+
+// WithAllowedAlgorithms sets signature algorithms accepted when signature verification is enabled,
+// RS256 by default. Tokens with other algorithms in the header are rejected before the verifier is called,
+// which prevents algorithm confusion attacks. Algorithm "none" is never accepted.
+func WithAllowedAlgorithms(algorithms ...string) func(conf) (conf, error) {
+	return func(c conf) (conf, error) {
+		if len(algorithms) == 0 {
+			return c, errors.New("at least one algorithm must be allowed")
+		}
+		allowed := map[string]bool{}
+		for _, alg := range algorithms {
+			if alg == "" || alg == "none" {
+				return c, fmt.Errorf("algorithm %q can't be allowed", alg)
+			}
+			allowed[alg] = true
+		}
+		c.allowedAlgorithms = allowed
+		return c, nil
+	}
+}
+
+// verifySignature verifies signature of token split into parts with configured verifier.
+func (c conf) verifySignature(token []byte, parts [][]byte) error {
+	header, err := base64.RawURLEncoding.DecodeString(string(parts[0]))
+	if err != nil {
+		return ErrDecodingBearer
+	}
+	alg := gjson.GetBytes(header, "alg").String()
+	allowed := c.allowedAlgorithms
+	if allowed == nil {
+		allowed = map[string]bool{AlgRS256: true}
+	}
+	if !allowed[alg] {
+		return fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, alg)
+	}
+	if c.verifier == nil {
+		return errors.New("signature verification is enabled, but verifier is not configured")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(string(parts[2]))
+	if err != nil {
+		return ErrDecodingBearer
+	}
+	return c.verifier.Verify(alg, token[:len(parts[0])+len(parts[1])+1], signature)
+}
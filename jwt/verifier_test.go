@@ -0,0 +1,152 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testRSAKey, _                = rsa.GenerateKey(rand.Reader, 2048)
+	testEd25519Pub, testEdKey, _ = ed25519.GenerateKey(rand.Reader)
+)
+
+// signToken returns token with given payload signed with given algorithm and key.
+func signToken(t testing.TB, alg string, key crypto.Signer, payload string) string {
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"alg":%q,"typ":"JWT"}`, alg))) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case AlgEdDSA:
+		signature, err = key.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	case AlgPS256:
+		h := crypto.SHA256.New()
+		h.Write([]byte(signingInput))
+		signature, err = key.Sign(rand.Reader, h.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	default:
+		h := crypto.SHA256.New()
+		h.Write([]byte(signingInput))
+		signature, err = key.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+	}
+	require.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestSignatureVerification(t *testing.T) {
+	rsaVerifier, err := NewKeyVerifier(&testRSAKey.PublicKey)
+	require.NoError(t, err)
+	edVerifier, err := NewKeyVerifier(testEd25519Pub)
+	require.NoError(t, err)
+
+	payload := `{"sub":"user"}`
+	rs256 := signToken(t, AlgRS256, testRSAKey, payload)
+	ps256 := signToken(t, AlgPS256, testRSAKey, payload)
+	eddsa := signToken(t, AlgEdDSA, testEdKey, payload)
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload)) + "."
+	tampered := rs256[:len(rs256)-4] + "AAAA"
+
+	tests := []struct {
+		name    string
+		options []func(conf) (conf, error)
+		token   string
+		wantErr error
+	}{
+		{name: "RS256", options: []func(conf) (conf, error){WithVerifier(rsaVerifier)}, token: rs256},
+		{name: "PS256NotAllowedByDefault", options: []func(conf) (conf, error){WithVerifier(rsaVerifier)}, token: ps256, wantErr: ErrAlgorithmNotAllowed},
+		{name: "PS256", options: []func(conf) (conf, error){WithVerifier(rsaVerifier), WithAllowedAlgorithms(AlgPS256)}, token: ps256},
+		{name: "EdDSA", options: []func(conf) (conf, error){WithVerifier(edVerifier), WithAllowedAlgorithms(AlgEdDSA)}, token: eddsa},
+		{name: "None", options: []func(conf) (conf, error){WithVerifier(rsaVerifier)}, token: unsigned, wantErr: ErrAlgorithmNotAllowed},
+		{name: "Tampered", options: []func(conf) (conf, error){WithVerifier(rsaVerifier)}, token: tampered, wantErr: ErrInvalidSignature},
+		{name: "KeyTypeMismatch", options: []func(conf) (conf, error){WithVerifier(rsaVerifier), WithAllowedAlgorithms(AlgEdDSA)},
+			token: eddsa, wantErr: ErrAlgorithmNotAllowed},
+		{name: "WrongKey", options: []func(conf) (conf, error){WithVerifier(edVerifier), WithAllowedAlgorithms(AlgRS256, AlgEdDSA)},
+			token: signToken(t, AlgEdDSA, ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), payload), wantErr: ErrInvalidSignature},
+		{name: "VerificationDisabled", options: []func(conf) (conf, error){WithAllowedAlgorithms(AlgEdDSA)}, token: tampered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := NewMiddleware(tt.options...)
+			require.NoError(t, err)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			err = mw.processToken(nil, r)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomVerifier(t *testing.T) {
+	var calledWith string
+	verifier := VerifierFunc(func(alg string, signingInput, signature []byte) error {
+		calledWith = alg
+		return errors.New("hsm unavailable")
+	})
+	mw, err := NewMiddleware(WithVerifier(verifier), WithAllowedAlgorithms(AlgPS256))
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signToken(t, AlgPS256, testRSAKey, `{}`))
+	assert.EqualError(t, mw.processToken(nil, r), "hsm unavailable")
+	assert.Equal(t, AlgPS256, calledWith)
+}
+
+func TestVerifierOptionErrors(t *testing.T) {
+	_, err := NewMiddleware(WithVerifier(nil))
+	assert.Error(t, err)
+	_, err = NewMiddleware(WithAllowedAlgorithms())
+	assert.Error(t, err)
+	_, err = NewMiddleware(WithAllowedAlgorithms(AlgRS256, "none"))
+	assert.Error(t, err)
+	_, err = NewKeyVerifier("not a key")
+	assert.Error(t, err)
+}
+
+func BenchmarkSignatureVerification(b *testing.B) {
+	rsaVerifier, err := NewKeyVerifier(&testRSAKey.PublicKey)
+	require.NoError(b, err)
+	edVerifier, err := NewKeyVerifier(testEd25519Pub)
+	require.NoError(b, err)
+
+	benchmarks := []struct {
+		alg      string
+		key      crypto.Signer
+		verifier Verifier
+	}{
+		{alg: AlgRS256, key: testRSAKey, verifier: rsaVerifier},
+		{alg: AlgPS256, key: testRSAKey, verifier: rsaVerifier},
+		{alg: AlgEdDSA, key: testEdKey, verifier: edVerifier},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.alg, func(b *testing.B) {
+			mw, err := NewMiddleware(WithVerifier(bm.verifier), WithAllowedAlgorithms(bm.alg))
+			require.NoError(b, err)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+signToken(b, bm.alg, bm.key, jwtPayloadJSON))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := mw.processToken(nil, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package kafka
 
 import (
+	"context"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/kelseyhightower/envconfig"
@@ -9,15 +12,46 @@ import (
 	"github.com/phanitejak/kptgolib/tracing"
 )
 
-// AsyncProducer is a wrapper on the sarama async_producer.
+// AsyncProducer is a wrapper on the sarama async_producer. Tracing headers are injected into sent messages
+// and results of sent messages are read from the success and error channels, which are reported to callbacks
+// set by SetCallbacks. Messages in flight can be limited by SetMaxInFlight and failed messages can be sent again
+// through a bounded queue set by SetRetryQueue. Close waits until all sent messages have a result.
 type AsyncProducer struct {
 	asyncProducer sarama.AsyncProducer
 	log           *tracing.Logger
 	prefix        string
+	payload       payloadMonitor
+	maxChunkSize  int
+	serializers   *SerializerRegistry
+
+	onSuccess    func(msg *sarama.ProducerMessage)
+	onError      func(msg *sarama.ProducerMessage, err error)
+	slots        chan struct{}
+	retries      int
+	retryBackoff time.Duration
+	retryQueue   chan *sarama.ProducerMessage
+	lock         sync.Mutex
+	inFlight     int
+	idle         chan struct{}
+	done         chan struct{}
+}
+
+// asyncMessage is set as metadata of sent messages to keep track of send attempts.
+// Original metadata is restored before messages are passed to callbacks.
+type asyncMessage struct {
+	metadata interface{}
+	attempts int
 }
 
 const defaultPrefix = "default"
 
+var (
+	asyncProducerInFlight = metrics.RegisterGaugeVec("async_producer_in_flight_messages", "kafka",
+		"Number of messages sent by async producer, which don't have a result yet.", "producer")
+	asyncProducerMessages = metrics.RegisterCounterVec("async_producer_messages_total", "kafka",
+		"Total number of results of messages sent by async producer by result: success, error or retried.", "producer", "result")
+)
+
 // NewAsyncProducerFromEnv creates AsyncProducer using broker values from environment and default sarama and metric configurations.
 func NewAsyncProducerFromEnv(logger *tracing.Logger) (*AsyncProducer, error) {
 	return NewAsyncProducerFromEnvWithPrefix(logger, defaultPrefix)
@@ -63,49 +97,235 @@ func NewAsyncProducerFromConfigWithPrefix(logger *tracing.Logger, brokers []stri
 	if err != nil {
 		return nil, err
 	}
+	a := WrapAsyncProducer(logger, asynchProducer, config)
+	a.prefix = prefix
+	return a, nil
+}
+
+// WrapAsyncProducer creates AsyncProducer sending messages with given sarama producer, e.g. a producer created from
+// an existing client. The producer must be created with config.Producer.Return.Successes and config.Producer.Return.Errors
+// set to true and its results must not be read elsewhere. Kafka producer metrics are not registered.
+func WrapAsyncProducer(logger *tracing.Logger, producer sarama.AsyncProducer, config *sarama.Config) *AsyncProducer {
 	a := &AsyncProducer{
-		asyncProducer: asynchProducer,
+		asyncProducer: producer,
 		log:           logger,
-		prefix:        prefix,
+		payload:       newPayloadMonitor(config, logger),
+		done:          make(chan struct{}),
 	}
 	go a.handleProducerResponse()
-	return a, nil
+	return a
 }
 
 func (a *AsyncProducer) handleProducerResponse() {
 	successChan := a.asyncProducer.Successes()
 	errorChan := a.asyncProducer.Errors()
 
+	defer close(a.done)
+
 	// Process successes and errors until closed
-	for successChan != nil && errorChan != nil {
+	for successChan != nil || errorChan != nil {
 		select {
-		case _, ok := <-a.asyncProducer.Successes():
+		case msg, ok := <-successChan:
 			if !ok {
 				successChan = nil
 				continue
 			}
-		case produceErr, ok := <-a.asyncProducer.Errors():
+			if a.complete(msg) {
+				asyncProducerMessages.GetCustomCounter(a.prefix, "success").Inc()
+				if a.onSuccess != nil {
+					a.onSuccess(msg)
+				}
+			}
+		case produceErr, ok := <-errorChan:
 			if !ok {
 				errorChan = nil
 				continue
 			}
 			if produceErr != nil {
-				a.log.Errorf("error in sending message %v", produceErr.Err)
+				a.handleError(produceErr)
 			}
 		}
 	}
 	a.log.Info("Stopped producer response reader")
 }
 
+func (a *AsyncProducer) handleError(produceErr *sarama.ProducerError) {
+	msg := produceErr.Msg
+	if m, ok := msgMetadata(msg); ok && m.attempts <= a.retries && a.retryQueue != nil {
+		select {
+		case a.retryQueue <- msg:
+			asyncProducerMessages.GetCustomCounter(a.prefix, "retried").Inc()
+			return
+		default:
+			a.log.Errorf("retry queue is full, dropping message to topic %s", msg.Topic)
+		}
+	}
+	if !a.complete(msg) {
+		a.log.Errorf("error in sending message %v", produceErr.Err)
+		return
+	}
+	asyncProducerMessages.GetCustomCounter(a.prefix, "error").Inc()
+	if a.onError != nil {
+		a.onError(msg, produceErr.Err)
+		return
+	}
+	a.log.Errorf("error in sending message %v", produceErr.Err)
+}
+
+// complete restores metadata of message sent by SendMessages and releases its in-flight slot.
+// It returns false for messages which were not sent by SendMessages.
+func (a *AsyncProducer) complete(msg *sarama.ProducerMessage) bool {
+	m, ok := msgMetadata(msg)
+	if !ok {
+		return false
+	}
+	msg.Metadata = m.metadata
+	if a.slots != nil {
+		<-a.slots
+	}
+	asyncProducerInFlight.GetCustomGauge(a.prefix).Sub(1)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.inFlight--; a.inFlight == 0 {
+		close(a.idle)
+	}
+	return true
+}
+
+func msgMetadata(msg *sarama.ProducerMessage) (*asyncMessage, bool) {
+	if msg == nil {
+		return nil, false
+	}
+	m, ok := msg.Metadata.(*asyncMessage)
+	return m, ok
+}
+
+func (a *AsyncProducer) sendRetries() {
+	for msg := range a.retryQueue {
+		time.Sleep(a.retryBackoff)
+		if m, ok := msgMetadata(msg); ok {
+			m.attempts++
+		}
+		a.asyncProducer.Input() <- msg
+	}
+}
+
+// SetCallbacks sets functions called with messages, which were sent successfully or failed to be sent after all
+// retries. Callbacks are called from a single goroutine, so they should not block. Metadata of messages is the one
+// set by the sender. Failed messages are logged when onError is nil. Call before sending any messages.
+func (a *AsyncProducer) SetCallbacks(onSuccess func(msg *sarama.ProducerMessage), onError func(msg *sarama.ProducerMessage, err error)) {
+	a.onSuccess, a.onError = onSuccess, onError
+}
+
+// SetMaxInFlight limits number of sent messages (or chunks), which don't have a result yet. SendMessages blocks
+// while the limit is reached, so that a slow or unavailable cluster slows down the sender instead of piling
+// up messages in memory. Call before sending any messages.
+func (a *AsyncProducer) SetMaxInFlight(maxInFlight int) {
+	if maxInFlight > 0 {
+		a.slots = make(chan struct{}, maxInFlight)
+	}
+}
+
+// SetRetryQueue makes messages, which sarama failed to send after its own retries, be sent again up to retries
+// times after backoff. Up to queueSize failed messages wait for retry, messages failing when the queue is full
+// are reported as errors. Call before sending any messages.
+func (a *AsyncProducer) SetRetryQueue(retries int, queueSize int, backoff time.Duration) {
+	if retries <= 0 || queueSize <= 0 || a.retryQueue != nil {
+		return
+	}
+	a.retries, a.retryBackoff = retries, backoff
+	a.retryQueue = make(chan *sarama.ProducerMessage, queueSize)
+	go a.sendRetries()
+}
+
+// SetPayloadSoftLimit sets size in bytes, which produced messages should not exceed, e.g. a fraction of broker's
+// message.max.bytes. Messages exceeding the limit are still sent, but counted in oversized produced messages
+// counter and logged as warnings. Call before sending any messages.
+func (a *AsyncProducer) SetPayloadSoftLimit(limit int) {
+	a.payload.softLimit = limit
+}
+
+// SetChunking enables splitting of message values larger than maxChunkSize bytes into chunks, see ChunkMessage.
+// Consumers of the topic must reassemble chunks with middleware.Reassemble. Messages which can't be chunked
+// are not sent and the error is logged. Call before sending any messages.
+func (a *AsyncProducer) SetChunking(maxChunkSize int) {
+	a.maxChunkSize = maxChunkSize
+}
+
+// SetSerializers enables validation of messages to topics registered in r, see SerializerRegistry.Validate.
+// Invalid messages are not sent and the error is logged. Call before sending any messages.
+func (a *AsyncProducer) SetSerializers(r *SerializerRegistry) {
+	a.serializers = r
+}
+
 // SendMessages send the list of messages.
 func (a *AsyncProducer) SendMessages(msgs ...ProducerMessage) {
+	_ = a.SendMessagesWithContext(context.Background(), msgs...)
+}
+
+// SendMessagesWithContext sends the list of messages like SendMessages, but stops waiting for a free in-flight slot
+// when ctx is done and returns ctx.Err(). Messages after the one waiting for the slot are not sent.
+func (a *AsyncProducer) SendMessagesWithContext(ctx context.Context, msgs ...ProducerMessage) error {
 	for _, msg := range msgs {
-		a.asyncProducer.Input() <- tracing.MessageWithContext(msg.Ctx, msg.Msg)
+		messages := []*sarama.ProducerMessage{tracing.MessageWithContext(msg.Ctx, msg.Msg)}
+		if err := validateMessages(a.serializers, messages); err != nil {
+			a.log.Errorf("error in sending message %v", err)
+			continue
+		}
+		chunks, err := chunkMessages(messages, a.maxChunkSize)
+		if err != nil {
+			a.log.Errorf("error in sending message %v", err)
+			continue
+		}
+		for _, m := range chunks {
+			if a.slots != nil {
+				select {
+				case a.slots <- struct{}{}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			a.payload.observe(m)
+			m.Metadata = &asyncMessage{metadata: m.Metadata, attempts: 1}
+			a.lock.Lock()
+			if a.inFlight++; a.inFlight == 1 {
+				a.idle = make(chan struct{})
+			}
+			a.lock.Unlock()
+			asyncProducerInFlight.GetCustomGauge(a.prefix).Add(1)
+			a.asyncProducer.Input() <- m
+		}
 	}
+	return nil
 }
 
-// Close - Closes the kafka Producer.
+// Flush waits until all sent messages have a result, including retries, or ctx is done.
+func (a *AsyncProducer) Flush(ctx context.Context) error {
+	a.lock.Lock()
+	if a.inFlight == 0 {
+		a.lock.Unlock()
+		return nil
+	}
+	idle := a.idle
+	a.lock.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close - Closes the kafka Producer after all sent messages have a result.
 func (a *AsyncProducer) Close() error {
-	metrics.UnregisterKafkaProducerMetricsPrefix(a.prefix)
-	return a.asyncProducer.Close()
+	_ = a.Flush(context.Background())
+	if a.retryQueue != nil {
+		close(a.retryQueue)
+	}
+	if a.prefix != "" {
+		metrics.UnregisterKafkaProducerMetricsPrefix(a.prefix)
+	}
+	err := a.asyncProducer.Close()
+	<-a.done
+	return err
 }
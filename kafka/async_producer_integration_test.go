@@ -6,21 +6,22 @@ package kafka_test
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/kafkatest"
 	"github.com/phanitejak/kptgolib/logging"
 	"github.com/phanitejak/kptgolib/tracing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-var (
-	defaultBroker = os.Getenv("KAFKA_BROKERS")
-	log           = tracing.NewLogger(logging.NewLogger())
-)
+const asyncProducerTestTopic = "async-producer-test"
+
+var log = tracing.NewLogger(logging.NewLogger())
 
 func TestMain(m *testing.M) {
 	closer, err := tracing.InitGlobalTracer()
@@ -35,13 +36,13 @@ func TestMain(m *testing.M) {
 }
 
 func TestIntegrationNonUniquePrefix(t *testing.T) {
-	broker := "127.0.0.1:9092"
+	brokers := kafkatest.StartBroker(t).Addrs()
 	prefix := "unique_check"
 
 	config := sarama.NewConfig()
-	prod, err := kafka.NewAsyncProducerFromConfigWithPrefix(log, []string{broker}, config, prefix)
+	prod, err := kafka.NewAsyncProducerFromConfigWithPrefix(log, brokers, config, prefix)
 	require.NoError(t, err)
-	_, err = kafka.NewAsyncProducerFromConfigWithPrefix(log, []string{broker}, config, prefix)
+	_, err = kafka.NewAsyncProducerFromConfigWithPrefix(log, brokers, config, prefix)
 	require.Error(t, err)
 	_ = prod.Close()
 }
@@ -56,63 +57,73 @@ func TestIntegrationUnreachableBroker(t *testing.T) {
 }
 
 func TestIntegrationAsyncProducer(t *testing.T) {
-	prod, err := kafka.NewAsyncProducerFromConfigWithPrefix(log, []string{defaultBroker}, sarama.NewConfig(), "async_producer_prefix")
+	broker := startBroker(t)
+	prod, err := kafka.NewAsyncProducerFromConfigWithPrefix(log, broker.Addrs(), sarama.NewConfig(), "async_producer_prefix")
 	require.NoError(t, err)
 	defer func() {
 		_ = prod.Close()
 	}()
-	testAsyncProducerMessageSendReceive(t, prod)
+	testAsyncProducerMessageSendReceive(t, broker, prod)
 }
 
 func TestIntegrationNewAsyncProducerFromEnv(t *testing.T) {
+	broker := startBroker(t)
+	t.Setenv("KAFKA_BROKERS", strings.Join(broker.Addrs(), ","))
+
 	prod, err := kafka.NewAsyncProducerFromEnv(log)
 	require.NoError(t, err)
 	defer func() {
 		_ = prod.Close()
 	}()
-	testAsyncProducerMessageSendReceive(t, prod)
+	testAsyncProducerMessageSendReceive(t, broker, prod)
 }
 
 func TestIntegrationNewDefaultAsyncProducer(t *testing.T) {
-	prod, err := kafka.NewDefaultAsyncProducer(log, []string{defaultBroker})
+	broker := startBroker(t)
+	prod, err := kafka.NewDefaultAsyncProducer(log, broker.Addrs())
 	require.NoError(t, err)
 	defer func() {
 		_ = prod.Close()
 	}()
-	testAsyncProducerMessageSendReceive(t, prod)
+	testAsyncProducerMessageSendReceive(t, broker, prod)
 }
 
 func TestIntegrationNewAsyncProducerFromConfig(t *testing.T) {
-	prod, err := kafka.NewAsyncProducerFromConfig(log, []string{defaultBroker}, sarama.NewConfig())
+	broker := startBroker(t)
+	prod, err := kafka.NewAsyncProducerFromConfig(log, broker.Addrs(), sarama.NewConfig())
 	require.NoError(t, err)
 	defer func() {
 		_ = prod.Close()
 	}()
-	testAsyncProducerMessageSendReceive(t, prod)
+	testAsyncProducerMessageSendReceive(t, broker, prod)
 }
 
 func TestIntegrationNewAsyncProducerFromEnvWithPrefix(t *testing.T) {
+	broker := startBroker(t)
+	t.Setenv("KAFKA_BROKERS", strings.Join(broker.Addrs(), ","))
+
 	prod, err := kafka.NewAsyncProducerFromEnvWithPrefix(log, "producer_from_env")
 	require.NoError(t, err)
 	defer func() {
 		_ = prod.Close()
 	}()
-	testAsyncProducerMessageSendReceive(t, prod)
+	testAsyncProducerMessageSendReceive(t, broker, prod)
 }
 
 func TestIntegrationNewDefaultAsyncProducerWithPrefix(t *testing.T) {
-	prod, err := kafka.NewDefaultAsyncProducerWithPrefix(log, []string{defaultBroker}, "producer_with_prefix")
+	broker := startBroker(t)
+	prod, err := kafka.NewDefaultAsyncProducerWithPrefix(log, broker.Addrs(), "producer_with_prefix")
 	require.NoError(t, err)
 	defer func() {
 		_ = prod.Close()
 	}()
-	testAsyncProducerMessageSendReceive(t, prod)
+	testAsyncProducerMessageSendReceive(t, broker, prod)
 }
 
-func testAsyncProducerMessageSendReceive(t *testing.T, prod *kafka.AsyncProducer) {
+func testAsyncProducerMessageSendReceive(t *testing.T, broker *kafkatest.Broker, prod *kafka.AsyncProducer) {
 	const (
 		msgCount = 5
-		topic    = "async-producer-test"
+		topic    = asyncProducerTestTopic
 		msgValue = "message data"
 	)
 
@@ -126,7 +137,7 @@ func testAsyncProducerMessageSendReceive(t *testing.T, prod *kafka.AsyncProducer
 
 	prod.SendMessages(msgs...)
 
-	conf := kafka.ConsumerConf{Brokers: []string{defaultBroker}, Topics: []string{topic}, Group: "async-producer-test-group"}
+	conf := kafka.ConsumerConf{Brokers: broker.Addrs(), Topics: []string{topic}, Group: "async-producer-test-group"}
 	consumer, err := kafka.NewConcurrentPartitionConsumer(conf, tracing.NewLogger(logging.NewLogger()))
 	require.NoError(t, err)
 	defer consumer.Close()
@@ -153,6 +164,13 @@ func testAsyncProducerMessageSendReceive(t *testing.T, prod *kafka.AsyncProducer
 	}
 }
 
+// startBroker starts broker with empty test topic for async producer tests.
+func startBroker(t *testing.T) *kafkatest.Broker {
+	broker := kafkatest.StartBroker(t)
+	broker.CreateTopic(t, asyncProducerTestTopic, 1)
+	return broker
+}
+
 func getTracingContext(opName string) context.Context {
 	_, ctx := tracing.StartSpanFromContext(context.Background(), opName)
 	return ctx
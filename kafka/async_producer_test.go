@@ -0,0 +1,124 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAsyncProducer answers every message with the result returned by result, blocking while release is not closed.
+type fakeAsyncProducer struct {
+	sarama.AsyncProducer
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+	release   chan struct{}
+	result    func(msg *sarama.ProducerMessage) error
+	stopped   chan struct{}
+}
+
+func newFakeAsyncProducer(result func(msg *sarama.ProducerMessage) error) *fakeAsyncProducer {
+	p := &fakeAsyncProducer{
+		input:     make(chan *sarama.ProducerMessage),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+		release:   make(chan struct{}),
+		result:    result,
+		stopped:   make(chan struct{}),
+	}
+	go func() {
+		defer close(p.stopped)
+		for msg := range p.input {
+			<-p.release
+			if err := p.result(msg); err != nil {
+				p.errors <- &sarama.ProducerError{Msg: msg, Err: err}
+			} else {
+				p.successes <- msg
+			}
+		}
+	}()
+	return p
+}
+
+func (p *fakeAsyncProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *fakeAsyncProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *fakeAsyncProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func (p *fakeAsyncProducer) Close() error {
+	close(p.input)
+	<-p.stopped
+	close(p.successes)
+	close(p.errors)
+	return nil
+}
+
+func newTestMessage(value string, metadata interface{}) kafka.ProducerMessage {
+	return kafka.ProducerMessage{Ctx: context.Background(), Msg: &sarama.ProducerMessage{
+		Topic: "topic", Value: sarama.StringEncoder(value), Metadata: metadata,
+	}}
+}
+
+func TestAsyncProducerCallbacks(t *testing.T) {
+	attempts := map[string]int{}
+	fake := newFakeAsyncProducer(func(msg *sarama.ProducerMessage) error {
+		value, _ := msg.Value.Encode()
+		attempts[string(value)]++
+		switch {
+		case string(value) == "fails" || string(value) == "recovers" && attempts["recovers"] < 3:
+			return errors.New("out of brokers")
+		default:
+			return nil
+		}
+	})
+	close(fake.release)
+
+	var lock sync.Mutex
+	var succeeded, failed []interface{}
+	p := kafka.WrapAsyncProducer(tracing.NewLogger(logging.NewLogger()), fake, sarama.NewConfig())
+	p.SetCallbacks(func(msg *sarama.ProducerMessage) {
+		lock.Lock()
+		defer lock.Unlock()
+		succeeded = append(succeeded, msg.Metadata)
+	}, func(msg *sarama.ProducerMessage, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		failed = append(failed, msg.Metadata)
+		assert.EqualError(t, err, "out of brokers")
+	})
+	p.SetRetryQueue(2, 10, time.Millisecond)
+
+	p.SendMessages(newTestMessage("ok", 1), newTestMessage("recovers", 2), newTestMessage("fails", 3))
+	require.NoError(t, p.Close())
+
+	assert.ElementsMatch(t, []interface{}{1, 2}, succeeded)
+	assert.Equal(t, []interface{}{3}, failed)
+	assert.Equal(t, 3, attempts["recovers"])
+	assert.Equal(t, 3, attempts["fails"])
+}
+
+func TestAsyncProducerMaxInFlight(t *testing.T) {
+	fake := newFakeAsyncProducer(func(*sarama.ProducerMessage) error { return nil })
+	p := kafka.WrapAsyncProducer(tracing.NewLogger(logging.NewLogger()), fake, sarama.NewConfig())
+	p.SetMaxInFlight(1)
+
+	require.NoError(t, p.SendMessagesWithContext(context.Background(), newTestMessage("first", nil)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.SendMessagesWithContext(ctx, newTestMessage("second", nil)), context.DeadlineExceeded)
+	assert.ErrorIs(t, p.Flush(ctx), context.DeadlineExceeded)
+
+	close(fake.release)
+	require.NoError(t, p.Flush(context.Background()))
+	require.NoError(t, p.SendMessagesWithContext(context.Background(), newTestMessage("second", nil)))
+	require.NoError(t, p.Close())
+}
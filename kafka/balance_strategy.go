@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// Partition assignment strategy names accepted by BalanceStrategies and KAFKA_CONSUMER_REBALANCE_STRATEGY.
+const (
+	// RebalanceStrategyRange assigns consecutive partitions of each topic to members, this is the default.
+	RebalanceStrategyRange = sarama.RangeBalanceStrategyName
+	// RebalanceStrategyRoundRobin assigns partitions of all topics to members one by one.
+	RebalanceStrategyRoundRobin = sarama.RoundRobinBalanceStrategyName
+	// RebalanceStrategySticky balances partitions like round-robin but keeps existing assignments
+	// on rebalance whenever possible.
+	RebalanceStrategySticky = sarama.StickyBalanceStrategyName
+	// RebalanceStrategyCooperativeSticky is incremental cooperative rebalancing. It is not supported by
+	// the sarama version in use and is rejected by BalanceStrategies.
+	RebalanceStrategyCooperativeSticky = "cooperative-sticky"
+)
+
+// BalanceStrategies returns sarama balance strategies for given priority-ordered strategy names,
+// which can be set to sarama.Config.Consumer.Group.Rebalance.GroupStrategies.
+// All members of a consumer group must support at least one common strategy.
+func BalanceStrategies(names ...string) ([]sarama.BalanceStrategy, error) {
+	strategies := make([]sarama.BalanceStrategy, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case RebalanceStrategyRange:
+			strategies = append(strategies, sarama.NewBalanceStrategyRange())
+		case RebalanceStrategyRoundRobin:
+			strategies = append(strategies, sarama.NewBalanceStrategyRoundRobin())
+		case RebalanceStrategySticky:
+			strategies = append(strategies, sarama.NewBalanceStrategySticky())
+		case RebalanceStrategyCooperativeSticky:
+			return nil, fmt.Errorf("rebalance strategy '%s' is not supported by sarama client, use '%s' to minimize partition movement",
+				name, RebalanceStrategySticky)
+		default:
+			return nil, fmt.Errorf("invalid rebalance strategy '%s', please specify one of '%s', '%s' or '%s'",
+				name, RebalanceStrategyRange, RebalanceStrategyRoundRobin, RebalanceStrategySticky)
+		}
+	}
+	return strategies, nil
+}
+
+// SetBalanceStrategies sets given priority-ordered strategies to sarama config.
+// Config is left unchanged if no names are given.
+func SetBalanceStrategies(config *sarama.Config, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	strategies, err := BalanceStrategies(names...)
+	if err != nil {
+		return err
+	}
+	config.Consumer.Group.Rebalance.GroupStrategies = strategies
+	return nil
+}
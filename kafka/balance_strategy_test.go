@@ -0,0 +1,39 @@
+package kafka_test
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceStrategies(t *testing.T) {
+	strategies, err := kafka.BalanceStrategies("sticky", " RoundRobin", kafka.RebalanceStrategyRange)
+	require.NoError(t, err)
+	require.Len(t, strategies, 3)
+	assert.Equal(t, sarama.StickyBalanceStrategyName, strategies[0].Name())
+	assert.Equal(t, sarama.RoundRobinBalanceStrategyName, strategies[1].Name())
+	assert.Equal(t, sarama.RangeBalanceStrategyName, strategies[2].Name())
+
+	_, err = kafka.BalanceStrategies(kafka.RebalanceStrategyCooperativeSticky)
+	assert.Error(t, err)
+
+	_, err = kafka.BalanceStrategies("random")
+	assert.Error(t, err)
+}
+
+func TestSetBalanceStrategies(t *testing.T) {
+	config := sarama.NewConfig()
+	defaults := config.Consumer.Group.Rebalance.GroupStrategies
+
+	require.NoError(t, kafka.SetBalanceStrategies(config))
+	assert.Equal(t, defaults, config.Consumer.Group.Rebalance.GroupStrategies)
+
+	require.NoError(t, kafka.SetBalanceStrategies(config, "sticky"))
+	require.Len(t, config.Consumer.Group.Rebalance.GroupStrategies, 1)
+	assert.Equal(t, sarama.StickyBalanceStrategyName, config.Consumer.Group.Rebalance.GroupStrategies[0].Name())
+
+	assert.Error(t, kafka.SetBalanceStrategies(config, "cooperative-sticky"))
+}
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+)
+
+// Header keys of chunks of a message split with ChunkMessage.
+const (
+	// HeaderChunkID is id shared by all chunks of the same message.
+	HeaderChunkID = "chunk-id"
+	// HeaderChunkIndex is zero based index of the chunk.
+	HeaderChunkIndex = "chunk-index"
+	// HeaderChunkCount is total number of chunks of the message.
+	HeaderChunkCount = "chunk-count"
+)
+
+// ChunkMessage splits value of msg into chunks of at most maxChunkSize bytes, e.g. for large documents exceeding
+// broker's message.max.bytes. Each chunk has the key, headers and metadata of msg and chunk headers, so that
+// chunks are produced to the same partition and can be reassembled with middleware.Reassemble.
+// Keep maxChunkSize well below message.max.bytes to leave room for key and headers.
+// Messages not exceeding maxChunkSize are returned as is.
+func ChunkMessage(msg *sarama.ProducerMessage, maxChunkSize int) ([]*sarama.ProducerMessage, error) {
+	if maxChunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", maxChunkSize)
+	}
+	if msg.Value == nil || msg.Value.Length() <= maxChunkSize {
+		return []*sarama.ProducerMessage{msg}, nil
+	}
+	if msg.Key == nil {
+		return nil, errors.New("chunked message must have a key, so that all chunks go to the same partition")
+	}
+	value, err := msg.Value.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message value: %w", err)
+	}
+
+	id := uuid.New().String()
+	count := (len(value) + maxChunkSize - 1) / maxChunkSize
+	chunks := make([]*sarama.ProducerMessage, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * maxChunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		chunk := &sarama.ProducerMessage{
+			Topic:     msg.Topic,
+			Key:       msg.Key,
+			Value:     sarama.ByteEncoder(value[i*maxChunkSize : end]),
+			Headers:   append([]sarama.RecordHeader{}, msg.Headers...),
+			Metadata:  msg.Metadata,
+			Partition: msg.Partition,
+			Timestamp: msg.Timestamp,
+		}
+		SetHeader(chunk, HeaderChunkID, id)
+		SetHeader(chunk, HeaderChunkIndex, strconv.Itoa(i))
+		SetHeader(chunk, HeaderChunkCount, strconv.Itoa(count))
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// chunkMessages splits messages with ChunkMessage, when chunking is enabled with positive maxChunkSize.
+func chunkMessages(msgs []*sarama.ProducerMessage, maxChunkSize int) ([]*sarama.ProducerMessage, error) {
+	if maxChunkSize <= 0 {
+		return msgs, nil
+	}
+	chunked := make([]*sarama.ProducerMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		chunks, err := ChunkMessage(msg, maxChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		chunked = append(chunked, chunks...)
+	}
+	return chunked, nil
+}
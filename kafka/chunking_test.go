@@ -0,0 +1,54 @@
+package kafka_test
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkMessage(t *testing.T) {
+	msg := &sarama.ProducerMessage{
+		Topic:   "documents",
+		Key:     sarama.StringEncoder("doc-1"),
+		Value:   sarama.StringEncoder("0123456789"),
+		Headers: []sarama.RecordHeader{{Key: []byte(kafka.HeaderContentType), Value: []byte("text/plain")}},
+	}
+
+	chunks, err := kafka.ChunkMessage(msg, 4)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	id := headerValue(chunks[0], kafka.HeaderChunkID)
+	assert.NotEmpty(t, id)
+	for i, chunk := range chunks {
+		assert.Equal(t, msg.Topic, chunk.Topic)
+		assert.Equal(t, msg.Key, chunk.Key)
+		assert.Equal(t, "text/plain", headerValue(chunk, kafka.HeaderContentType))
+		assert.Equal(t, id, headerValue(chunk, kafka.HeaderChunkID))
+		assert.Equal(t, []string{"0", "1", "2"}[i], headerValue(chunk, kafka.HeaderChunkIndex))
+		assert.Equal(t, "3", headerValue(chunk, kafka.HeaderChunkCount))
+	}
+	assert.Equal(t, sarama.ByteEncoder("0123"), chunks[0].Value)
+	assert.Equal(t, sarama.ByteEncoder("89"), chunks[2].Value)
+	assert.Len(t, msg.Headers, 1, "original message should not be modified")
+
+	chunks, err = kafka.ChunkMessage(msg, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []*sarama.ProducerMessage{msg}, chunks, "small message should not be chunked")
+
+	_, err = kafka.ChunkMessage(&sarama.ProducerMessage{Value: sarama.StringEncoder("0123456789")}, 4)
+	assert.Error(t, err, "message without key should not be chunked")
+	_, err = kafka.ChunkMessage(msg, 0)
+	assert.Error(t, err)
+}
+
+func headerValue(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -19,8 +20,15 @@ type ConsumerConf struct {
 
 	RetryEnabled        bool `envconfig:"KAFKA_CONSUMER_RETRY_ENABLED" default:"true"`
 	RetryWaitTimeoutSec int  `envconfig:"KAFKA_CONSUMER_RETRY_WAIT_TIMEOUT" default:"1"`
+
+	// RebalanceStrategies is comma separated, priority-ordered list of partition assignment strategies,
+	// see BalanceStrategies. If empty, strategies of the sarama config are used.
+	RebalanceStrategies []string `envconfig:"KAFKA_CONSUMER_REBALANCE_STRATEGY"`
 }
 
+// ErrDrainTimeout is returned by Drain when in-flight messages are not handled within the timeout.
+var ErrDrainTimeout = errors.New("timeout draining in-flight messages")
+
 // HandlerFunc kafka message handler function signature.
 type HandlerFunc func(msg *sarama.ConsumerMessage, mark func(metadata string)) error
 
@@ -45,6 +53,13 @@ type ConcurrentPartitionConsumer struct {
 	groupHandlerMutex *sync.RWMutex
 	groupHandler      ConsumerGroupHandler
 	runSetupMutex     *sync.Mutex
+	session           sarama.ConsumerGroupSession
+	sessionMutex      sync.Mutex
+	drainMutex        sync.Mutex
+	inFlight          int
+	abandoned         int
+	drained           chan struct{}
+	tracker           *GroupTracker
 }
 
 // NewConcurrentPartitionConsumerFromEnv initilize the partition consumer client.
@@ -75,6 +90,10 @@ func NewConcurrentPartitionConsumer(conf ConsumerConf, logger *tracing.Logger) (
 
 // NewConcurrentPartitionConsumerWithConfig initilize the partition consumer client with given sarama config.
 func NewConcurrentPartitionConsumerWithConfig(conf ConsumerConf, config *sarama.Config, logger *tracing.Logger) (*ConcurrentPartitionConsumer, error) {
+	if err := SetBalanceStrategies(config, conf.RebalanceStrategies...); err != nil {
+		return nil, err
+	}
+
 	c := &ConcurrentPartitionConsumer{
 		consumerGroup:     conf.Group,
 		topics:            conf.Topics,
@@ -85,6 +104,7 @@ func NewConcurrentPartitionConsumerWithConfig(conf ConsumerConf, config *sarama.
 		conf:              conf,
 		config:            config,
 		runSetupMutex:     &sync.Mutex{},
+		tracker:           NewGroupTracker(conf.Group),
 	}
 	if err := c.initializeConsumerGroupClient(); err != nil {
 		return nil, err
@@ -197,9 +217,87 @@ func (c *ConcurrentPartitionConsumer) Close() {
 	c.log.Debug("partition consumer closed for %s group", c.conf.Group)
 }
 
+// Drain closes consumer gracefully. It stops fetching new messages, waits up to timeout for messages being
+// handled to complete, commits marked offsets and closes the consumer. Number of fetched messages which were
+// not handled is returned, they are consumed again by the next owner of the partition.
+// If handling doesn't complete within timeout, ErrDrainTimeout is returned and consumer is closed
+// in background once the handlers return.
+func (c *ConcurrentPartitionConsumer) Drain(timeout time.Duration) (abandoned int, err error) {
+	c.drainMutex.Lock()
+	if c.drained != nil {
+		c.drainMutex.Unlock()
+		return 0, errors.New("consumer is already drained")
+	}
+	c.drained = make(chan struct{})
+	if c.inFlight == 0 {
+		close(c.drained)
+	}
+	c.drainMutex.Unlock()
+
+	c.clientMutex.Lock()
+	if c.client != nil {
+		c.client.PauseAll()
+	}
+	c.clientMutex.Unlock()
+
+	select {
+	case <-c.drained:
+	case <-time.After(timeout):
+		c.drainMutex.Lock()
+		abandoned = c.abandoned + c.inFlight
+		c.drainMutex.Unlock()
+		c.log.Errorf("draining %s group timed out after %v, %d messages abandoned", c.conf.Group, timeout, abandoned)
+		go c.Close()
+		return abandoned, ErrDrainTimeout
+	}
+
+	c.sessionMutex.Lock()
+	if c.session != nil {
+		c.session.Commit()
+	}
+	c.sessionMutex.Unlock()
+	c.Close()
+
+	c.drainMutex.Lock()
+	defer c.drainMutex.Unlock()
+	c.log.Infof("drained %s group, %d messages abandoned", c.conf.Group, c.abandoned)
+	return c.abandoned, nil
+}
+
+// startHandling registers message as in-flight, false is returned when consumer is draining
+// and message should not be handled.
+func (c *ConcurrentPartitionConsumer) startHandling() bool {
+	c.drainMutex.Lock()
+	defer c.drainMutex.Unlock()
+	if c.drained != nil {
+		c.abandoned++
+		return false
+	}
+	c.inFlight++
+	return true
+}
+
+func (c *ConcurrentPartitionConsumer) doneHandling() {
+	c.drainMutex.Lock()
+	defer c.drainMutex.Unlock()
+	c.inFlight--
+	if c.drained != nil && c.inFlight == 0 {
+		close(c.drained)
+	}
+}
+
+// GroupInfo returns member ID, generation, assigned partitions and their last consumed offsets of the latest session.
+func (c *ConcurrentPartitionConsumer) GroupInfo() GroupInfo {
+	return c.tracker.GroupInfo()
+}
+
 // Setup Concurrent Partition Consumer initialization callback.
 func (c *ConcurrentPartitionConsumer) Setup(session sarama.ConsumerGroupSession) error {
 	c.log.Infof("setup consumer session, memberId: %s, generationId: %d, claims: %v", session.MemberID(), session.GenerationID(), session.Claims())
+	c.tracker.Setup(session)
+	c.sessionMutex.Lock()
+	c.session = session
+	c.sessionMutex.Unlock()
 	c.groupHandlerMutex.RLock()
 	defer c.groupHandlerMutex.RUnlock()
 	if c.groupHandler != nil {
@@ -211,6 +309,10 @@ func (c *ConcurrentPartitionConsumer) Setup(session sarama.ConsumerGroupSession)
 // Cleanup Concurrent Partition Consumer cleanup callback.
 func (c *ConcurrentPartitionConsumer) Cleanup(session sarama.ConsumerGroupSession) error {
 	c.log.Infof("cleanup consumer session, memberId: %s, generationId: %d, claims: %v", session.MemberID(), session.GenerationID(), session.Claims())
+	c.tracker.Cleanup(session)
+	c.sessionMutex.Lock()
+	c.session = nil
+	c.sessionMutex.Unlock()
 	c.groupHandlerMutex.RLock()
 	defer c.groupHandlerMutex.RUnlock()
 	if c.groupHandler != nil {
@@ -222,12 +324,19 @@ func (c *ConcurrentPartitionConsumer) Cleanup(session sarama.ConsumerGroupSessio
 // ConsumeClaim Concurrent Partition Claim's message cosumer.
 func (c *ConcurrentPartitionConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	c.log.Infof("consumer claim starting, topic: %s, partition: %d, initialOffset: %d", claim.Topic(), claim.Partition(), claim.InitialOffset())
+	c.tracker.Claim(claim)
 
 	for msg := range claim.Messages() {
 		msg := msg
 		mark := func(metadata string) { session.MarkMessage(msg, metadata) }
 
-		if err := c.messageHandler(msg, mark); err != nil {
+		if !c.startHandling() {
+			continue
+		}
+		c.tracker.Consumed(msg)
+		err := c.messageHandler(msg, mark)
+		c.doneHandling()
+		if err != nil {
 			c.cancelContext()
 			return err
 		}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/kafkatest"
 	"github.com/phanitejak/kptgolib/logging"
 	"github.com/phanitejak/kptgolib/tracing"
 	"github.com/stretchr/testify/assert"
@@ -26,11 +27,11 @@ type testMessage struct {
 func TestIntegrationConcurrentConsumption(t *testing.T) {
 	const topic = "partition-concurrency-test"
 	const consumerGroup = "concurrency-test"
-	const kafkaBrokerAddr = "127.0.0.1:9092"
 	const numberOfPartitions = 10
 	const numberOfMessages = 25
 
-	ensureNumberOfPartitions(t, kafkaBrokerAddr, topic, numberOfPartitions)
+	broker := kafkatest.StartBroker(t)
+	broker.CreateTopic(t, topic, numberOfPartitions)
 
 	msgChan := make(chan testMessage, 0)
 	handlerFunc := func(msg *sarama.ConsumerMessage, mark func(string)) error {
@@ -40,7 +41,7 @@ func TestIntegrationConcurrentConsumption(t *testing.T) {
 	}
 
 	consumer, err := kafka.NewConcurrentPartitionConsumer(
-		kafka.ConsumerConf{Brokers: []string{kafkaBrokerAddr}, Topics: []string{topic}, Group: consumerGroup},
+		kafka.ConsumerConf{Brokers: broker.Addrs(), Topics: []string{topic}, Group: consumerGroup},
 		tracing.NewLogger(logging.NewLogger()))
 	require.NoError(t, err)
 	defer consumer.Close()
@@ -51,7 +52,11 @@ func TestIntegrationConcurrentConsumption(t *testing.T) {
 
 	// Produce numberOfMessages messages to the topics and distribute them to partitions in round-robin manner.
 	// Expect that handlerFunc is called in as many goroutines as there are partitions.
-	produceMessages(t, kafkaBrokerAddr, topic, numberOfMessages)
+	values := make([]string, 0, numberOfMessages)
+	for i := 0; i < numberOfMessages; i++ {
+		values = append(values, strconv.Itoa(i))
+	}
+	broker.SendMessages(t, topic, values...)
 	receivedMessages, goroutineIDs := receiveMessages(msgChan, numberOfMessages)
 
 	assert.Equal(t, numberOfMessages, len(receivedMessages), "Received %d messages instead of the expected %d", len(receivedMessages), numberOfMessages)
@@ -70,68 +75,6 @@ func getGoroutineID() uint64 {
 	return n
 }
 
-func ensureNumberOfPartitions(t *testing.T, brokerAddr, topic string, numPartitions int32) {
-	config := sarama.NewConfig()
-	config.Version = sarama.V1_0_0_0
-
-	broker := sarama.NewBroker(brokerAddr)
-	broker.Open(config)
-	defer broker.Close()
-
-	// Try to create a topics with a specified number of partitions
-	createTopic(t, broker, topic, numPartitions)
-
-	// If the topics already exists, the above function call does not have effects.
-	// Try to modify the number of partitions in the existing topics.
-	createPartitions(t, broker, topic, numPartitions)
-}
-
-func createTopic(t *testing.T, broker *sarama.Broker, topic string, numPartitions int32) {
-	_, err := broker.CreateTopics(&sarama.CreateTopicsRequest{
-		TopicDetails: map[string]*sarama.TopicDetail{
-			topic: {
-				NumPartitions:     numPartitions,
-				ReplicationFactor: 1,
-			},
-		},
-		Timeout: 30 * time.Second,
-	})
-	require.NoError(t, err)
-}
-
-func createPartitions(t *testing.T, broker *sarama.Broker, topic string, numPartitions int32) {
-	_, err := broker.CreatePartitions(&sarama.CreatePartitionsRequest{
-		TopicPartitions: map[string]*sarama.TopicPartition{
-			topic: {
-				Count: numPartitions,
-			},
-		},
-		Timeout: 30 * time.Second,
-	})
-	require.NoError(t, err)
-}
-
-func produceMessages(t *testing.T, brokerAddr, topic string, msgCount int) {
-	config := sarama.NewConfig()
-	config.Producer.Flush.MaxMessages = 1
-	config.Producer.Return.Errors = true
-	config.Producer.Return.Successes = true
-	config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
-
-	producer, err := sarama.NewSyncProducer([]string{brokerAddr}, config)
-	require.NoError(t, err)
-	defer producer.Close()
-
-	msgs := make([]*sarama.ProducerMessage, 0, msgCount)
-	for i := 0; i < msgCount; i++ {
-		msgs = append(msgs, &sarama.ProducerMessage{
-			Topic: topic,
-			Value: sarama.StringEncoder(strconv.Itoa(i)),
-		})
-	}
-	require.NoError(t, producer.SendMessages(msgs))
-}
-
 func receiveMessages(msgChan <-chan testMessage, msgCount int) (msgs []testMessage, goroutineIDs map[uint64]bool) {
 	msgs = make([]testMessage, 0)
 	goroutineIDs = make(map[uint64]bool)
@@ -148,5 +91,58 @@ func receiveMessages(msgChan <-chan testMessage, msgCount int) (msgs []testMessa
 			return
 		}
 	}
-	return
+}
+
+func TestIntegrationConcurrentConsumerDrain(t *testing.T) {
+	const topic = "partition-drain-test"
+	const consumerGroup = "drain-test"
+
+	broker := kafkatest.StartBroker(t)
+	broker.CreateTopic(t, topic, 1)
+	broker.SendMessages(t, topic, "0", "1", "2")
+
+	started := make(chan string)
+	release := make(chan struct{})
+	handlerFunc := func(msg *sarama.ConsumerMessage, mark func(string)) error {
+		started <- string(msg.Value)
+		<-release
+		mark("")
+		return nil
+	}
+
+	newConsumer := func() *kafka.ConcurrentPartitionConsumer {
+		consumer, err := kafka.NewConcurrentPartitionConsumer(
+			kafka.ConsumerConf{Brokers: broker.Addrs(), Topics: []string{topic}, Group: consumerGroup},
+			tracing.NewLogger(logging.NewLogger()))
+		require.NoError(t, err)
+		go func() {
+			assert.NoError(t, consumer.Run(handlerFunc))
+		}()
+		return consumer
+	}
+
+	consumer := newConsumer()
+	require.Equal(t, "0", <-started)
+
+	type drainResult struct {
+		abandoned int
+		err       error
+	}
+	result := make(chan drainResult)
+	go func() {
+		abandoned, err := consumer.Drain(10 * time.Second)
+		result <- drainResult{abandoned, err}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	r := <-result
+	require.NoError(t, r.err)
+	assert.LessOrEqual(t, r.abandoned, 2, "only fetched but unhandled messages should be abandoned")
+
+	release = make(chan struct{})
+	close(release)
+	consumer = newConsumer()
+	defer consumer.Close()
+	assert.Equal(t, "1", <-started, "consumption should continue after the drained message")
 }
@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// GroupInfoEndPoint is the default management endpoint for consumer group info, see GroupInfoHandler.
+const GroupInfoEndPoint = "/application/kafka/consumers"
+
+// GroupInfo describes membership of a consumer in its group as seen in the latest session.
+type GroupInfo struct {
+	Group        string `json:"group"`
+	MemberID     string `json:"memberId"`
+	GenerationID int32  `json:"generationId"`
+	// Active is false before the first session and between sessions, e.g. during rebalance.
+	Active      bool            `json:"active"`
+	Assignments []PartitionInfo `json:"assignments"`
+}
+
+// PartitionInfo describes a partition assigned to the consumer.
+// LastConsumedOffset is -1 until a message is consumed from the partition.
+type PartitionInfo struct {
+	Topic               string     `json:"topic"`
+	Partition           int32      `json:"partition"`
+	InitialOffset       int64      `json:"initialOffset"`
+	LastConsumedOffset  int64      `json:"lastConsumedOffset"`
+	LastConsumedAt      *time.Time `json:"lastConsumedAt,omitempty"`
+	HighWaterMarkOffset int64      `json:"highWaterMarkOffset"`
+}
+
+// GroupInfoProvider is implemented by consumers exposing their group membership, e.g. ConcurrentPartitionConsumer.
+type GroupInfoProvider interface {
+	GroupInfo() GroupInfo
+}
+
+// GroupTracker records member ID, generation, assignments and last consumed offsets of a consumer group session.
+// Consumers call Setup, Cleanup, Claim and Consumed from the matching sarama.ConsumerGroupHandler callbacks.
+type GroupTracker struct {
+	lock       sync.RWMutex
+	info       GroupInfo
+	partitions map[topicPartition]*trackedPartition
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type trackedPartition struct {
+	info  PartitionInfo
+	claim sarama.ConsumerGroupClaim
+}
+
+// NewGroupTracker returns tracker of consumer in group.
+func NewGroupTracker(group string) *GroupTracker {
+	return &GroupTracker{
+		info:       GroupInfo{Group: group},
+		partitions: map[topicPartition]*trackedPartition{},
+	}
+}
+
+// Setup records member ID, generation and assignments of a new session.
+func (t *GroupTracker) Setup(session sarama.ConsumerGroupSession) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.info.MemberID = session.MemberID()
+	t.info.GenerationID = session.GenerationID()
+	t.info.Active = true
+	t.partitions = map[topicPartition]*trackedPartition{}
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			t.partitions[topicPartition{topic, partition}] = &trackedPartition{info: PartitionInfo{
+				Topic:              topic,
+				Partition:          partition,
+				InitialOffset:      -1,
+				LastConsumedOffset: -1,
+			}}
+		}
+	}
+}
+
+// Cleanup marks session as ended. Assignments of the ended session are kept until the next Setup.
+func (t *GroupTracker) Cleanup(sarama.ConsumerGroupSession) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.info.Active = false
+}
+
+// Claim records initial offset of a claimed partition, its high water mark is read from claim when info is requested.
+func (t *GroupTracker) Claim(claim sarama.ConsumerGroupClaim) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.partition(claim.Topic(), claim.Partition())
+	p.info.InitialOffset = claim.InitialOffset()
+	p.claim = claim
+}
+
+// Consumed records msg as the last consumed message of its partition.
+func (t *GroupTracker) Consumed(msg *sarama.ConsumerMessage) {
+	now := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.partition(msg.Topic, msg.Partition)
+	p.info.LastConsumedOffset = msg.Offset
+	p.info.LastConsumedAt = &now
+}
+
+// GroupInfo returns recorded info with assignments sorted by topic and partition.
+func (t *GroupTracker) GroupInfo() GroupInfo {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	info := t.info
+	info.Assignments = make([]PartitionInfo, 0, len(t.partitions))
+	for _, p := range t.partitions {
+		partition := p.info
+		if p.claim != nil {
+			partition.HighWaterMarkOffset = p.claim.HighWaterMarkOffset()
+		}
+		info.Assignments = append(info.Assignments, partition)
+	}
+	sort.Slice(info.Assignments, func(i, j int) bool {
+		a, b := info.Assignments[i], info.Assignments[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+	return info
+}
+
+// partition returns tracked partition, adding it if it was not in session claims. Lock must be held.
+func (t *GroupTracker) partition(topic string, partition int32) *trackedPartition {
+	key := topicPartition{topic, partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &trackedPartition{info: PartitionInfo{Topic: topic, Partition: partition, InitialOffset: -1, LastConsumedOffset: -1}}
+		t.partitions[key] = p
+	}
+	return p
+}
+
+// GroupInfoHandler gets handler writing group info of given consumers as JSON array, e.g. for registering
+// to management server with GroupInfoEndPoint for debugging stuck partitions.
+func GroupInfoHandler(consumers ...GroupInfoProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		infos := make([]GroupInfo, 0, len(consumers))
+		for _, c := range consumers {
+			infos = append(infos, c.GroupInfo())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(infos)
+	})
+}
@@ -0,0 +1,72 @@
+package kafka_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/cgmocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupTracker(t *testing.T) {
+	tracker := kafka.NewGroupTracker("my-group")
+	assert.Equal(t, kafka.GroupInfo{Group: "my-group", Assignments: []kafka.PartitionInfo{}}, tracker.GroupInfo())
+
+	session := &cgmocks.ConsumerGroupSession{
+		MemberIDVal:     "member-1",
+		GenerationIDVal: 3,
+		TopicPartitions: map[string][]int32{"orders": {1, 0}, "events": {0}},
+	}
+	tracker.Setup(session)
+	tracker.Claim(&cgmocks.ConsumerGroupClaim{TopicVal: "orders", PartitionVal: 1, InitialOffsetVal: 10, HighWaterMarkVal: 20})
+	tracker.Consumed(&sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 12})
+
+	info := tracker.GroupInfo()
+	assert.Equal(t, "member-1", info.MemberID)
+	assert.Equal(t, int32(3), info.GenerationID)
+	assert.True(t, info.Active)
+	require.Len(t, info.Assignments, 3)
+	assert.Equal(t, kafka.PartitionInfo{Topic: "events", Partition: 0, InitialOffset: -1, LastConsumedOffset: -1}, info.Assignments[0])
+	assert.Equal(t, kafka.PartitionInfo{Topic: "orders", Partition: 0, InitialOffset: -1, LastConsumedOffset: -1}, info.Assignments[1])
+
+	consumed := info.Assignments[2]
+	assert.Equal(t, int64(10), consumed.InitialOffset)
+	assert.Equal(t, int64(12), consumed.LastConsumedOffset)
+	assert.Equal(t, int64(20), consumed.HighWaterMarkOffset)
+	assert.NotNil(t, consumed.LastConsumedAt)
+
+	tracker.Cleanup(session)
+	info = tracker.GroupInfo()
+	assert.False(t, info.Active)
+	assert.Len(t, info.Assignments, 3, "assignments of ended session are kept")
+
+	session.GenerationIDVal = 4
+	session.TopicPartitions = map[string][]int32{"orders": {0}}
+	tracker.Setup(session)
+	info = tracker.GroupInfo()
+	assert.Equal(t, int32(4), info.GenerationID)
+	assert.Equal(t, []kafka.PartitionInfo{{Topic: "orders", Partition: 0, InitialOffset: -1, LastConsumedOffset: -1}}, info.Assignments)
+}
+
+func TestGroupInfoHandler(t *testing.T) {
+	tracker := kafka.NewGroupTracker("my-group")
+	tracker.Setup(&cgmocks.ConsumerGroupSession{
+		MemberIDVal:     "member-1",
+		GenerationIDVal: 1,
+		TopicPartitions: map[string][]int32{"orders": {0}},
+	})
+
+	rec := httptest.NewRecorder()
+	kafka.GroupInfoHandler(tracker).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, kafka.GroupInfoEndPoint, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var infos []kafka.GroupInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &infos))
+	assert.Equal(t, []kafka.GroupInfo{tracker.GroupInfo()}, infos)
+}
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Standard header keys of the message envelope.
+// Trace context headers are written by tracing.MessageWithContext.
+const (
+	HeaderContentType   = "content-type"
+	HeaderSchemaID      = "schema-id"
+	HeaderProducedAt    = "produced-at"
+	HeaderOriginService = "origin-service"
+	// HeaderDeadline is absolute deadline of handling the message in Unix milliseconds.
+	HeaderDeadline = "deadline"
+	// HeaderTTL is time to live of the message in milliseconds, counted from produced-at header or message timestamp.
+	HeaderTTL = "ttl"
+)
+
+// Envelope contains standardized message metadata carried in message headers.
+type Envelope struct {
+	ContentType   string
+	SchemaID      string
+	ProducedAt    time.Time
+	OriginService string
+}
+
+// NewEnvelope creates envelope for given origin service and content type with ProducedAt set to current time.
+func NewEnvelope(originService, contentType string) Envelope {
+	return Envelope{
+		ContentType:   contentType,
+		OriginService: originService,
+		ProducedAt:    time.Now(),
+	}
+}
+
+// Apply writes non-empty envelope fields into message headers, replacing existing values.
+func (e Envelope) Apply(msg *sarama.ProducerMessage) *sarama.ProducerMessage {
+	if e.ContentType != "" {
+		SetHeader(msg, HeaderContentType, e.ContentType)
+	}
+	if e.SchemaID != "" {
+		SetHeader(msg, HeaderSchemaID, e.SchemaID)
+	}
+	if !e.ProducedAt.IsZero() {
+		SetHeader(msg, HeaderProducedAt, strconv.FormatInt(e.ProducedAt.UnixMilli(), 10))
+	}
+	if e.OriginService != "" {
+		SetHeader(msg, HeaderOriginService, e.OriginService)
+	}
+	return msg
+}
+
+// EnvelopeFromMessage reads envelope from consumed message headers.
+// Missing headers are left empty, error is returned only if a header has invalid value.
+func EnvelopeFromMessage(msg *sarama.ConsumerMessage) (Envelope, error) {
+	e := Envelope{}
+	e.ContentType, _ = Header(msg, HeaderContentType)
+	e.SchemaID, _ = Header(msg, HeaderSchemaID)
+	e.OriginService, _ = Header(msg, HeaderOriginService)
+
+	if producedAt, ok := Header(msg, HeaderProducedAt); ok {
+		ms, err := strconv.ParseInt(producedAt, 10, 64)
+		if err != nil {
+			return e, fmt.Errorf("invalid %s header value %q: %w", HeaderProducedAt, producedAt, err)
+		}
+		e.ProducedAt = time.UnixMilli(ms)
+	}
+	return e, nil
+}
+
+// MessageProducedAt returns time when consumed message was produced, read from produced-at header or, if the header
+// is missing or invalid, the message timestamp. False is returned when neither is set.
+func MessageProducedAt(msg *sarama.ConsumerMessage) (time.Time, bool) {
+	if e, err := EnvelopeFromMessage(msg); err == nil && !e.ProducedAt.IsZero() {
+		return e.ProducedAt, true
+	}
+	return msg.Timestamp, !msg.Timestamp.IsZero()
+}
+
+// Header returns value of the first header with given key from consumed message.
+func Header(msg *sarama.ConsumerMessage, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+// SetHeader sets header with given key on produced message, replacing existing values.
+func SetHeader(msg *sarama.ProducerMessage, key, value string) {
+	headers := msg.Headers[:0]
+	for _, h := range msg.Headers {
+		if string(h.Key) != key {
+			headers = append(headers, h)
+		}
+	}
+	msg.Headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// SetDeadline sets deadline header on produced message, e.g. for replies which are useless after the requester
+// has given up waiting.
+func SetDeadline(msg *sarama.ProducerMessage, deadline time.Time) *sarama.ProducerMessage {
+	SetHeader(msg, HeaderDeadline, strconv.FormatInt(deadline.UnixMilli(), 10))
+	return msg
+}
+
+// SetTTL sets time to live header on produced message.
+func SetTTL(msg *sarama.ProducerMessage, ttl time.Duration) *sarama.ProducerMessage {
+	SetHeader(msg, HeaderTTL, strconv.FormatInt(ttl.Milliseconds(), 10))
+	return msg
+}
+
+// MessageDeadline returns deadline of consumed message read from deadline header, or from ttl header added to
+// produced-at header or message timestamp. The earlier one is returned when both headers are set.
+// False is returned when message has no deadline, error is returned only if a header has invalid value.
+func MessageDeadline(msg *sarama.ConsumerMessage) (time.Time, bool, error) {
+	var deadline time.Time
+	if value, ok := Header(msg, HeaderDeadline); ok {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s header value %q: %w", HeaderDeadline, value, err)
+		}
+		deadline = time.UnixMilli(ms)
+	}
+
+	if value, ok := Header(msg, HeaderTTL); ok {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s header value %q: %w", HeaderTTL, value, err)
+		}
+		e, err := EnvelopeFromMessage(msg)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		produced := e.ProducedAt
+		if produced.IsZero() {
+			produced = msg.Timestamp
+		}
+		if expiry := produced.Add(time.Duration(ms) * time.Millisecond); !produced.IsZero() && (deadline.IsZero() || expiry.Before(deadline)) {
+			deadline = expiry
+		}
+	}
+	return deadline, !deadline.IsZero(), nil
+}
@@ -0,0 +1,241 @@
+// Package kafkatest provides Kafka broker and helpers for integration tests.
+//
+// StartBroker starts a single node Kafka broker in a Docker container and removes it when the test
+// finishes. Set KAFKA_TEST_BROKERS to comma separated list of broker addresses to use an existing
+// broker instead, e.g. in CI. Tests are skipped when neither is available.
+//
+// Broker.NewRequestReply returns harness for contract tests of request/reply APIs, which sends requests
+// and awaits replies matched by correlation header.
+package kafkatest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// BrokersEnv is environment variable for addresses of an existing broker.
+	BrokersEnv = "KAFKA_TEST_BROKERS"
+	// ImageEnv is environment variable for overriding the broker container image.
+	ImageEnv = "KAFKA_TEST_IMAGE"
+	// DefaultImage is the broker container image used by default.
+	DefaultImage = "apache/kafka:3.7.0"
+
+	startTimeout = 90 * time.Second
+)
+
+// Broker is a Kafka broker used by tests.
+type Broker struct {
+	addrs []string
+}
+
+// StartBroker returns broker from KAFKA_TEST_BROKERS or starts a new broker container,
+// which is removed on test cleanup. Test is skipped if Docker is not available.
+func StartBroker(t testing.TB) *Broker {
+	t.Helper()
+
+	if brokers := os.Getenv(BrokersEnv); brokers != "" {
+		b := &Broker{addrs: strings.Split(brokers, ",")}
+		b.waitReady(t)
+		return b
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skipf("docker not found and %s not set, skipping test requiring kafka broker", BrokersEnv)
+	}
+
+	port := freePort(t)
+	image := os.Getenv(ImageEnv)
+	if image == "" {
+		image = DefaultImage
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-p", fmt.Sprintf("127.0.0.1:%d:9092", port),
+		"-e", "KAFKA_NODE_ID=1",
+		"-e", "KAFKA_PROCESS_ROLES=broker,controller",
+		"-e", "KAFKA_LISTENERS=PLAINTEXT://:9092,CONTROLLER://:9093",
+		"-e", fmt.Sprintf("KAFKA_ADVERTISED_LISTENERS=PLAINTEXT://127.0.0.1:%d", port),
+		"-e", "KAFKA_LISTENER_SECURITY_PROTOCOL_MAP=CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+		"-e", "KAFKA_CONTROLLER_LISTENER_NAMES=CONTROLLER",
+		"-e", "KAFKA_CONTROLLER_QUORUM_VOTERS=1@localhost:9093",
+		"-e", "KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR=1",
+		"-e", "KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR=1",
+		"-e", "KAFKA_TRANSACTION_STATE_LOG_MIN_ISR=1",
+		"-e", "KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS=0",
+		image,
+	).Output()
+	require.NoError(t, err, "failed to start kafka container")
+
+	containerID := string(bytes.TrimSpace(out))
+	t.Cleanup(func() {
+		_ = exec.Command("docker", "rm", "-f", containerID).Run()
+	})
+
+	b := &Broker{addrs: []string{fmt.Sprintf("127.0.0.1:%d", port)}}
+	b.waitReady(t)
+	return b
+}
+
+// Addrs returns broker addresses.
+func (b *Broker) Addrs() []string {
+	return b.addrs
+}
+
+// CreateTopic creates topic with given number of partitions, which is deleted on test cleanup.
+// Existing topic is deleted first, so that the test starts without messages from earlier runs.
+func (b *Broker) CreateTopic(t testing.TB, name string, partitions int32) {
+	t.Helper()
+
+	admin, err := sarama.NewClusterAdmin(b.addrs, newConfig())
+	require.NoError(t, err)
+	defer admin.Close()
+
+	topics, err := admin.ListTopics()
+	require.NoError(t, err)
+	if _, ok := topics[name]; ok {
+		require.NoError(t, admin.DeleteTopic(name))
+		require.Eventually(t, func() bool {
+			topics, err := admin.ListTopics()
+			_, exists := topics[name]
+			return err == nil && !exists
+		}, startTimeout, 100*time.Millisecond, "topic %s was not deleted", name)
+	}
+
+	require.Eventually(t, func() bool {
+		return admin.CreateTopic(name, &sarama.TopicDetail{NumPartitions: partitions, ReplicationFactor: 1}, false) == nil
+	}, startTimeout, 100*time.Millisecond, "failed to create topic %s", name)
+
+	t.Cleanup(func() {
+		admin, err := sarama.NewClusterAdmin(b.addrs, newConfig())
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		_ = admin.DeleteTopic(name)
+	})
+}
+
+// SendMessages sends given values to topic, distributing them to partitions in round-robin manner.
+func (b *Broker) SendMessages(t testing.TB, topic string, values ...string) {
+	t.Helper()
+
+	config := newConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
+	producer, err := sarama.NewSyncProducer(b.addrs, config)
+	require.NoError(t, err)
+	defer producer.Close()
+
+	msgs := make([]*sarama.ProducerMessage, 0, len(values))
+	for _, value := range values {
+		msgs = append(msgs, &sarama.ProducerMessage{Topic: topic, Value: sarama.StringEncoder(value)})
+	}
+	require.NoError(t, producer.SendMessages(msgs))
+}
+
+// ConsumeAll consumes messages from all partitions of topic from the oldest offset until count
+// messages have been received and returns them. Test fails if messages are not received within timeout.
+func (b *Broker) ConsumeAll(t testing.TB, topic string, count int, timeout time.Duration) []*sarama.ConsumerMessage {
+	t.Helper()
+
+	consumer, err := sarama.NewConsumer(b.addrs, newConfig())
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	partitions, err := consumer.Partitions(topic)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msgs := make(chan *sarama.ConsumerMessage)
+	wg := &sync.WaitGroup{}
+	for _, partition := range partitions {
+		pc, err := consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer pc.Close()
+			for {
+				select {
+				case msg := <-pc.Messages():
+					select {
+					case msgs <- msg:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	received := make([]*sarama.ConsumerMessage, 0, count)
+	for len(received) < count {
+		select {
+		case msg := <-msgs:
+			received = append(received, msg)
+		case <-ctx.Done():
+			require.FailNowf(t, "messages not received in time", "got %d messages, wanted %d", len(received), count)
+		}
+	}
+	return received
+}
+
+func (b *Broker) waitReady(t testing.TB) {
+	t.Helper()
+
+	var lastErr error
+	ready := func() bool {
+		client, err := sarama.NewClient(b.addrs, newConfig())
+		if err != nil {
+			lastErr = err
+			return false
+		}
+		defer client.Close()
+		if _, err = client.Controller(); err != nil {
+			lastErr = err
+			return false
+		}
+		return true
+	}
+
+	deadline := time.Now().Add(startTimeout)
+	for !ready() {
+		if time.Now().After(deadline) {
+			require.FailNowf(t, "kafka broker not ready", "brokers %v: %s", b.addrs, lastErr)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func newConfig() *sarama.Config {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	return config
+}
+
+func freePort(t testing.TB) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,152 @@
+package kafkatest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+)
+
+const (
+	// CorrelationHeader is the default header matching replies to requests.
+	CorrelationHeader = "correlation-id"
+	// ReplyToHeader is the header carrying reply topic of a request.
+	ReplyToHeader = "reply-to"
+)
+
+// RequestReply is a harness for black-box contract tests of request/reply Kafka APIs. It sends requests
+// with a new correlation ID and awaits replies with the same ID consumed from the reply topic.
+type RequestReply struct {
+	producer   sarama.SyncProducer
+	replyTopic string
+	header     string
+
+	lock    sync.Mutex
+	waiting map[string]chan *sarama.ConsumerMessage
+}
+
+// NewRequestReply starts consuming all partitions of replyTopic from the newest offset and returns harness
+// matching replies by CorrelationHeader. Consuming is stopped on test cleanup.
+func (b *Broker) NewRequestReply(t testing.TB, replyTopic string) *RequestReply {
+	t.Helper()
+
+	config := newConfig()
+	config.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer(b.addrs, config)
+	require.NoError(t, err)
+
+	consumer, err := sarama.NewConsumer(b.addrs, newConfig())
+	require.NoError(t, err)
+	partitions, err := consumer.Partitions(replyTopic)
+	require.NoError(t, err)
+
+	rr := &RequestReply{
+		producer:   producer,
+		replyTopic: replyTopic,
+		header:     CorrelationHeader,
+		waiting:    map[string]chan *sarama.ConsumerMessage{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	for _, partition := range partitions {
+		pc, err := consumer.ConsumePartition(replyTopic, partition, sarama.OffsetNewest)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer pc.Close()
+			for {
+				select {
+				case msg := <-pc.Messages():
+					rr.deliver(msg)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+		_ = consumer.Close()
+		_ = producer.Close()
+	})
+	return rr
+}
+
+// SetCorrelationHeader changes header matching replies to requests. Call before sending any requests.
+func (rr *RequestReply) SetCorrelationHeader(header string) {
+	rr.header = header
+}
+
+// Request sends msg with a new correlation ID and reply topic in ReplyToHeader and returns the reply with
+// the same correlation ID. Correlation ID already set in msg headers is kept. Test fails if the reply is not
+// received within timeout.
+func (rr *RequestReply) Request(t testing.TB, msg *sarama.ProducerMessage, timeout time.Duration) *sarama.ConsumerMessage {
+	t.Helper()
+
+	id := headerValue(msg, rr.header)
+	if id == "" {
+		id = uuid.New().String()
+		kafka.SetHeader(msg, rr.header, id)
+	}
+	if headerValue(msg, ReplyToHeader) == "" {
+		kafka.SetHeader(msg, ReplyToHeader, rr.replyTopic)
+	}
+
+	reply := rr.await(id)
+	defer rr.cancel(id)
+	_, _, err := rr.producer.SendMessage(msg)
+	require.NoError(t, err, "failed to send request")
+
+	select {
+	case msg := <-reply:
+		return msg
+	case <-time.After(timeout):
+		require.FailNowf(t, "reply not received in time", "no reply with %s %s on topic %s within %s",
+			rr.header, id, rr.replyTopic, timeout)
+		return nil
+	}
+}
+
+func (rr *RequestReply) await(id string) chan *sarama.ConsumerMessage {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	reply := make(chan *sarama.ConsumerMessage, 1)
+	rr.waiting[id] = reply
+	return reply
+}
+
+func (rr *RequestReply) cancel(id string) {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	delete(rr.waiting, id)
+}
+
+// deliver passes reply to waiting request, replies to other requests are ignored.
+func (rr *RequestReply) deliver(msg *sarama.ConsumerMessage) {
+	id, _ := kafka.Header(msg, rr.header)
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	if reply, ok := rr.waiting[id]; ok {
+		delete(rr.waiting, id)
+		reply <- msg
+	}
+}
+
+func headerValue(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package kafkatest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/kafkatest"
+)
+
+func TestIntegrationRequestReply(t *testing.T) {
+	const requestTopic, replyTopic = "request-reply-requests", "request-reply-replies"
+	broker := kafkatest.StartBroker(t)
+	broker.CreateTopic(t, requestTopic, 1)
+	broker.CreateTopic(t, replyTopic, 2)
+
+	// Service under test echoes requests in upper case to the reply topic.
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer(broker.Addrs(), config)
+	require.NoError(t, err)
+	defer producer.Close()
+	consumer, err := sarama.NewConsumer(broker.Addrs(), sarama.NewConfig())
+	require.NoError(t, err)
+	defer consumer.Close()
+	pc, err := consumer.ConsumePartition(requestTopic, 0, sarama.OffsetOldest)
+	require.NoError(t, err)
+	defer pc.Close()
+	go func() {
+		for msg := range pc.Messages() {
+			id, _ := kafka.Header(msg, kafkatest.CorrelationHeader)
+			replyTo, _ := kafka.Header(msg, kafkatest.ReplyToHeader)
+			reply := &sarama.ProducerMessage{Topic: replyTo, Value: sarama.ByteEncoder(append([]byte("re: "), msg.Value...))}
+			kafka.SetHeader(reply, kafkatest.CorrelationHeader, id)
+			_, _, _ = producer.SendMessage(reply)
+		}
+	}()
+
+	rr := broker.NewRequestReply(t, replyTopic)
+	for _, value := range []string{"first", "second"} {
+		reply := rr.Request(t, &sarama.ProducerMessage{Topic: requestTopic, Value: sarama.StringEncoder(value)}, 30*time.Second)
+		assert.Equal(t, "re: "+value, string(reply.Value))
+	}
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+var checkpointSkippedMessages = metrics.RegisterCounterVec("checkpoint_skipped_messages_total", "kafka",
+	"Total number of messages skipped as already processed according to checkpoint store.", "topic")
+
+// CheckpointStore records offset of the last fully processed message per partition, independent of
+// offsets committed to Kafka. Use one store (or table, key prefix) per consumer group.
+// Any shared storage can be used, e.g. Redis HSET of "topic/partition" fields in a hash per consumer group.
+type CheckpointStore interface {
+	// Load returns checkpointed offset of given partition, ok is false if there is no checkpoint.
+	Load(ctx context.Context, topic string, partition int32) (offset int64, ok bool, err error)
+	// Save records offset of the last processed message of given partition.
+	Save(ctx context.Context, topic string, partition int32, offset int64) error
+}
+
+type checkpointedPartition struct {
+	topic     string
+	partition int32
+}
+
+// Checkpoint will save offset of every message processed by next handler without error to store, and skip
+// messages at or below checkpointed offset, e.g. redelivered after a crash before offsets were committed to Kafka.
+// Skipped messages are marked without calling next handler and counted in checkpoint skipped messages counter.
+// Checkpoints are loaded from store only when a partition is seen first or offsets are not consecutive,
+// e.g. after a rebalance. Error saving the checkpoint is returned as markable error, as the message itself
+// was handled successfully. Use CheckpointReconciler to resume consuming from checkpointed offsets.
+func Checkpoint(store CheckpointStore, next CtxHandlerFunc) CtxHandlerFunc {
+	var lock sync.Mutex
+	checkpoints := map[checkpointedPartition]int64{}
+
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		key := checkpointedPartition{topic: msg.Topic, partition: msg.Partition}
+		lock.Lock()
+		checkpoint, ok := checkpoints[key]
+		lock.Unlock()
+
+		if !ok || msg.Offset != checkpoint+1 {
+			loaded, found, err := store.Load(ctx, msg.Topic, msg.Partition)
+			if err != nil {
+				return fmt.Errorf("failed to load checkpoint of %s/%d: %w", msg.Topic, msg.Partition, err)
+			}
+			checkpoint = sarama.OffsetOldest
+			if found {
+				checkpoint = loaded
+			}
+		}
+		if msg.Offset <= checkpoint {
+			checkpointSkippedMessages.GetCustomCounter(msg.Topic).Inc()
+			mark("")
+			return nil
+		}
+
+		if err := next(ctx, msg, mark); err != nil {
+			return err
+		}
+		lock.Lock()
+		checkpoints[key] = msg.Offset
+		lock.Unlock()
+		if err := store.Save(ctx, msg.Topic, msg.Partition, msg.Offset); err != nil {
+			lock.Lock()
+			delete(checkpoints, key)
+			lock.Unlock()
+			return Markable(fmt.Errorf("failed to save checkpoint of %s/%d: %w", msg.Topic, msg.Partition, err))
+		}
+		return nil
+	}
+}
+
+// CheckpointReconciler moves consumer group offsets of claimed partitions to the message following
+// the checkpointed offset on every session setup, so that consuming resumes exactly where processing stopped,
+// even if offsets committed to Kafka are behind or ahead of the checkpoints. Partitions without checkpoint
+// are consumed from committed offsets. Set it with ConcurrentPartitionConsumer.SetConsumerGroupHandler.
+type CheckpointReconciler struct {
+	store   CheckpointStore
+	log     *tracing.Logger
+	timeout time.Duration
+}
+
+// NewCheckpointReconciler returns CheckpointReconciler loading checkpoints from given store.
+func NewCheckpointReconciler(store CheckpointStore, logger *tracing.Logger) *CheckpointReconciler {
+	return &CheckpointReconciler{store: store, log: logger, timeout: 10 * time.Second}
+}
+
+// Setup resets offsets of claimed partitions to checkpoints.
+func (r *CheckpointReconciler) Setup(session sarama.ConsumerGroupSession) error {
+	ctx, cancel := context.WithTimeout(session.Context(), r.timeout)
+	defer cancel()
+
+	var errs []error
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			offset, ok, err := r.store.Load(ctx, topic, partition)
+			if err != nil {
+				r.log.Errorf("failed to load checkpoint of %s/%d: %s", topic, partition, err)
+				errs = append(errs, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			// ResetOffset only moves offset backwards and MarkOffset only forwards.
+			session.ResetOffset(topic, partition, offset+1, "")
+			session.MarkOffset(topic, partition, offset+1, "")
+			r.log.Infof("resuming %s/%d from checkpointed offset %d", topic, partition, offset+1)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Cleanup does nothing.
+func (r *CheckpointReconciler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// MemoryCheckpointStore is in-memory CheckpointStore. Checkpoints don't survive restart of the process,
+// so it's only useful for testing.
+type MemoryCheckpointStore struct {
+	lock        sync.Mutex
+	checkpoints map[checkpointedPartition]int64
+}
+
+// NewMemoryCheckpointStore returns empty MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[checkpointedPartition]int64{}}
+}
+
+// Load returns checkpointed offset of given partition.
+func (s *MemoryCheckpointStore) Load(_ context.Context, topic string, partition int32) (int64, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	offset, ok := s.checkpoints[checkpointedPartition{topic: topic, partition: partition}]
+	return offset, ok, nil
+}
+
+// Save records offset of the last processed message of given partition.
+func (s *MemoryCheckpointStore) Save(_ context.Context, topic string, partition int32, offset int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.checkpoints[checkpointedPartition{topic: topic, partition: partition}] = offset
+	return nil
+}
+
+// SQLCheckpointStore is CheckpointStore backed by a SQL table:
+//
+//	CREATE TABLE kafka_checkpoints (topic VARCHAR(255) NOT NULL, kafka_partition INT NOT NULL,
+//		kafka_offset BIGINT NOT NULL, PRIMARY KEY (topic, kafka_partition))
+type SQLCheckpointStore struct {
+	db    *sql.DB
+	table string
+	// Placeholder returns n-th (1-based) query placeholder, defaults to PostgreSQL style $n.
+	// Use func(int) string { return "?" } for MySQL and SQLite.
+	Placeholder func(n int) string
+}
+
+// NewSQLCheckpointStore returns SQLCheckpointStore using given table.
+func NewSQLCheckpointStore(db *sql.DB, table string) *SQLCheckpointStore {
+	return &SQLCheckpointStore{
+		db:          db,
+		table:       table,
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	}
+}
+
+// Load returns checkpointed offset of given partition.
+func (s *SQLCheckpointStore) Load(ctx context.Context, topic string, partition int32) (int64, bool, error) {
+	query := fmt.Sprintf("SELECT kafka_offset FROM %s WHERE topic = %s AND kafka_partition = %s",
+		s.table, s.Placeholder(1), s.Placeholder(2))
+	var offset int64
+	err := s.db.QueryRowContext(ctx, query, topic, partition).Scan(&offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return offset, true, nil
+}
+
+// Save records offset of the last processed message of given partition.
+func (s *SQLCheckpointStore) Save(ctx context.Context, topic string, partition int32, offset int64) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE topic = %s AND kafka_partition = %s",
+		s.table, s.Placeholder(1), s.Placeholder(2)), topic, partition); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (topic, kafka_partition, kafka_offset) VALUES (%s, %s, %s)",
+		s.table, s.Placeholder(1), s.Placeholder(2), s.Placeholder(3)), topic, partition, offset); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,118 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka/cgmocks"
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+type failingCheckpointStore struct {
+	*middleware.MemoryCheckpointStore
+	err error
+}
+
+func (s failingCheckpointStore) Save(ctx context.Context, topic string, partition int32, offset int64) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.MemoryCheckpointStore.Save(ctx, topic, partition, offset)
+}
+
+func TestCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := middleware.NewMemoryCheckpointStore()
+	require.NoError(t, store.Save(ctx, "topic", 0, 1))
+
+	var handled []int64
+	fail := false
+	handler := middleware.Checkpoint(store, func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		if fail {
+			return errors.New("handling failed")
+		}
+		handled = append(handled, msg.Offset)
+		mark("")
+		return nil
+	})
+	at := func(partition int32, offset int64) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{Topic: "topic", Partition: partition, Offset: offset}
+	}
+
+	marked := 0
+	mark := func(string) { marked++ }
+
+	for offset := int64(0); offset < 4; offset++ {
+		require.NoError(t, handler(ctx, at(0, offset), mark))
+	}
+	require.NoError(t, handler(ctx, at(1, 0), mark))
+	fail = true
+	assert.Error(t, handler(ctx, at(0, 4), mark))
+	fail = false
+
+	assert.Equal(t, []int64{2, 3, 0}, handled, "messages at or below checkpoint should be skipped")
+	assert.Equal(t, 5, marked, "skipped messages should be marked")
+	offset, ok, err := store.Load(ctx, "topic", 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), offset, "failed message should not be checkpointed")
+
+	require.NoError(t, store.Save(ctx, "topic", 0, 10))
+	require.NoError(t, handler(ctx, at(0, 4), mark))
+	assert.Equal(t, []int64{2, 3, 0, 4}, handled, "consecutive offset should not reload checkpoint")
+	require.NoError(t, handler(ctx, at(0, 3), mark))
+	assert.Equal(t, []int64{2, 3, 0, 4}, handled, "checkpoint should be reloaded after seek")
+}
+
+func TestCheckpointSaveError(t *testing.T) {
+	store := failingCheckpointStore{MemoryCheckpointStore: middleware.NewMemoryCheckpointStore(), err: errors.New("store down")}
+	handler := middleware.MarkIfNoError(middleware.Trace(middleware.Checkpoint(store,
+		func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+			return nil
+		})))
+
+	marked := false
+	err := handler(&sarama.ConsumerMessage{Topic: "topic"}, func(string) { marked = true })
+	assert.NoError(t, err)
+	assert.True(t, marked, "save error should be markable")
+}
+
+type offsetSession struct {
+	cgmocks.ConsumerGroupSession
+	reset, marked map[int32]int64
+}
+
+func (s *offsetSession) ResetOffset(_ string, partition int32, offset int64, _ string) {
+	s.reset[partition] = offset
+}
+
+func (s *offsetSession) MarkOffset(_ string, partition int32, offset int64, _ string) {
+	s.marked[partition] = offset
+}
+
+func TestCheckpointReconciler(t *testing.T) {
+	store := middleware.NewMemoryCheckpointStore()
+	require.NoError(t, store.Save(context.Background(), "topic", 1, 41))
+
+	session := &offsetSession{
+		ConsumerGroupSession: cgmocks.ConsumerGroupSession{
+			Ctx:             context.Background(),
+			TopicPartitions: map[string][]int32{"topic": {0, 1}},
+		},
+		reset:  map[int32]int64{},
+		marked: map[int32]int64{},
+	}
+	reconciler := middleware.NewCheckpointReconciler(store, tracing.NewLogger(loggingtest.NewTestLogger(t)))
+	require.NoError(t, reconciler.Setup(session))
+	require.NoError(t, reconciler.Cleanup(session))
+
+	assert.Equal(t, map[int32]int64{1: 42}, session.reset)
+	assert.Equal(t, map[int32]int64{1: 42}, session.marked)
+}
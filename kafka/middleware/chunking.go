@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+var (
+	reassembledMessages = metrics.RegisterCounterVec("reassembled_messages_total", "kafka",
+		"Total number of messages reassembled from chunks.", "topic")
+	droppedChunkedMessages = metrics.RegisterCounterVec("dropped_chunked_messages_total", "kafka",
+		"Total number of chunked messages dropped before reassembly by reason.", "topic", "reason")
+)
+
+// Reassemble joins chunks of messages split with kafka.ChunkMessage and passes reassembled messages to next handler.
+// Other messages are passed as is. Reassembled message has metadata of the first chunk, offset of the last one,
+// and headers without chunk headers.
+//
+// Incomplete messages are dropped when their first chunk is older than timeout or when chunks of pending messages
+// would take more than maxBytes of memory, starting from the oldest one. Dropped messages are logged and counted
+// in dropped chunked messages counter. Timeouts are checked when messages are received.
+//
+// Offsets are not marked for chunks and marks of other messages are deferred while a message of the same partition
+// is pending, so that chunks are consumed again after restart. Use Reassemble as the outermost handler, e.g. around
+// MarkIfNoError, so that chunks are not marked by other middlewares.
+func Reassemble(logger *tracing.Logger, timeout time.Duration, maxBytes int, next kafka.HandlerFunc) kafka.HandlerFunc {
+	r := &reassembler{
+		log:        logger,
+		timeout:    timeout,
+		maxBytes:   maxBytes,
+		pending:    map[string]*assembly{},
+		partitions: map[topicPartition]*partitionMarks{},
+		now:        time.Now,
+	}
+	return func(msg *sarama.ConsumerMessage, mark func(string)) error {
+		r.expire()
+		tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+		id, isChunk := kafka.Header(msg, kafka.HeaderChunkID)
+		if !isChunk {
+			return next(msg, r.marker(tp, msg.Offset, mark))
+		}
+
+		reassembled, err := r.add(tp, id, msg)
+		if err != nil || reassembled == nil {
+			return err
+		}
+		reassembledMessages.GetCustomCounter(msg.Topic).Inc()
+		return next(reassembled, r.marker(tp, reassembled.Offset, mark))
+	}
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// partitionMarks keeps the latest deferred mark of a partition with pending messages.
+type partitionMarks struct {
+	pending  int
+	latest   int64
+	deferred func()
+}
+
+// assembly is a message being reassembled from chunks.
+type assembly struct {
+	tp       topicPartition
+	started  time.Time
+	first    *sarama.ConsumerMessage
+	chunks   [][]byte
+	received int
+	size     int
+}
+
+type reassembler struct {
+	lock       sync.Mutex
+	log        *tracing.Logger
+	timeout    time.Duration
+	maxBytes   int
+	bytes      int
+	pending    map[string]*assembly
+	partitions map[topicPartition]*partitionMarks
+	now        func() time.Time
+}
+
+// marker returns mark function, which defers marking while partition has pending messages
+// and ignores marks of offsets lower than the latest one.
+func (r *reassembler) marker(tp topicPartition, offset int64, mark func(string)) func(string) {
+	return func(metadata string) {
+		r.lock.Lock()
+		p := r.partition(tp)
+		if offset < p.latest {
+			r.lock.Unlock()
+			return
+		}
+		p.latest = offset
+		if p.pending > 0 {
+			p.deferred = func() { mark(metadata) }
+			r.lock.Unlock()
+			return
+		}
+		r.lock.Unlock()
+		mark(metadata)
+	}
+}
+
+// add adds chunk to its message and returns the message when all chunks were received.
+func (r *reassembler) add(tp topicPartition, id string, msg *sarama.ConsumerMessage) (*sarama.ConsumerMessage, error) {
+	index, count, err := chunkPosition(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var release []func()
+	defer func() {
+		for _, f := range release {
+			f()
+		}
+	}()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := msg.Topic + "/" + id
+	a, ok := r.pending[key]
+	if !ok {
+		a = &assembly{tp: tp, started: r.now(), first: msg, chunks: make([][]byte, count)}
+		r.pending[key] = a
+		r.partition(tp).pending++
+	}
+	if len(a.chunks) != count {
+		release = append(release, r.drop(key, "invalid"))
+		return nil, fmt.Errorf("chunk %d of message %s:%d:%d has count %d, but previous chunks had %d",
+			index, msg.Topic, msg.Partition, msg.Offset, count, len(a.chunks))
+	}
+	if a.chunks[index] != nil {
+		return nil, nil // redelivered chunk
+	}
+
+	if a.size+len(msg.Value) > r.maxBytes {
+		release = append(release, r.drop(key, "too_large"))
+		return nil, nil
+	}
+	for r.bytes+len(msg.Value) > r.maxBytes {
+		release = append(release, r.drop(r.oldest(key), "memory"))
+	}
+
+	a.chunks[index] = msg.Value
+	a.received++
+	a.size += len(msg.Value)
+	r.bytes += len(msg.Value)
+	if a.received < count {
+		return nil, nil
+	}
+
+	release = append(release, r.remove(key))
+	value := make([]byte, 0, a.size)
+	for _, chunk := range a.chunks {
+		value = append(value, chunk...)
+	}
+	reassembled := *a.first
+	reassembled.Value = value
+	reassembled.Offset = msg.Offset
+	reassembled.Headers = make([]*sarama.RecordHeader, 0, len(a.first.Headers))
+	for _, h := range a.first.Headers {
+		if h != nil && !isChunkHeader(string(h.Key)) {
+			reassembled.Headers = append(reassembled.Headers, h)
+		}
+	}
+	return &reassembled, nil
+}
+
+// expire drops pending messages older than timeout.
+func (r *reassembler) expire() {
+	var release []func()
+	r.lock.Lock()
+	for key, a := range r.pending {
+		if r.now().Sub(a.started) > r.timeout {
+			release = append(release, r.drop(key, "timeout"))
+		}
+	}
+	r.lock.Unlock()
+	for _, f := range release {
+		f()
+	}
+}
+
+// oldest returns key of the oldest pending message other than except.
+func (r *reassembler) oldest(except string) string {
+	oldest := ""
+	for key, a := range r.pending {
+		if key != except && (oldest == "" || a.started.Before(r.pending[oldest].started)) {
+			oldest = key
+		}
+	}
+	return oldest
+}
+
+// drop removes pending message with given key, logs and counts it. Returned function must be called without lock.
+func (r *reassembler) drop(key, reason string) func() {
+	a := r.pending[key]
+	droppedChunkedMessages.GetCustomCounter(a.tp.topic, reason).Inc()
+	r.log.Warnf("dropped chunked message %s:%d:%d with %d of %d chunks received: %s",
+		a.first.Topic, a.first.Partition, a.first.Offset, a.received, len(a.chunks), reason)
+	return r.remove(key)
+}
+
+// remove removes pending message with given key. Returned function must be called without lock,
+// it calls deferred mark when partition has no more pending messages.
+func (r *reassembler) remove(key string) func() {
+	a := r.pending[key]
+	delete(r.pending, key)
+	r.bytes -= a.size
+	p := r.partition(a.tp)
+	p.pending--
+	if p.pending > 0 || p.deferred == nil {
+		return func() {}
+	}
+	deferred := p.deferred
+	p.deferred = nil
+	return deferred
+}
+
+func (r *reassembler) partition(tp topicPartition) *partitionMarks {
+	p, ok := r.partitions[tp]
+	if !ok {
+		p = &partitionMarks{latest: -1}
+		r.partitions[tp] = p
+	}
+	return p
+}
+
+func chunkPosition(msg *sarama.ConsumerMessage) (index, count int, err error) {
+	indexValue, _ := kafka.Header(msg, kafka.HeaderChunkIndex)
+	countValue, _ := kafka.Header(msg, kafka.HeaderChunkCount)
+	index, indexErr := strconv.Atoi(indexValue)
+	count, countErr := strconv.Atoi(countValue)
+	if indexErr != nil || countErr != nil || count <= 0 || index < 0 || index >= count {
+		return 0, 0, fmt.Errorf("invalid chunk headers %s=%q and %s=%q in message %s:%d:%d",
+			kafka.HeaderChunkIndex, indexValue, kafka.HeaderChunkCount, countValue, msg.Topic, msg.Partition, msg.Offset)
+	}
+	return index, count, nil
+}
+
+func isChunkHeader(key string) bool {
+	return key == kafka.HeaderChunkID || key == kafka.HeaderChunkIndex || key == kafka.HeaderChunkCount
+}
@@ -0,0 +1,119 @@
+package middleware_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+// chunked returns chunks of value as consumed messages starting from given offset.
+func chunked(t *testing.T, value string, chunkSize int, offset int64) []*sarama.ConsumerMessage {
+	chunks, err := kafka.ChunkMessage(&sarama.ProducerMessage{
+		Topic:   "documents",
+		Key:     sarama.StringEncoder("key"),
+		Value:   sarama.StringEncoder(value),
+		Headers: []sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}},
+	}, chunkSize)
+	require.NoError(t, err)
+	msgs := make([]*sarama.ConsumerMessage, len(chunks))
+	for i, chunk := range chunks {
+		v, err := chunk.Value.Encode()
+		require.NoError(t, err)
+		msg := &sarama.ConsumerMessage{Topic: chunk.Topic, Key: []byte("key"), Value: v, Offset: offset + int64(i)}
+		for j := range chunk.Headers {
+			msg.Headers = append(msg.Headers, &chunk.Headers[j])
+		}
+		msgs[i] = msg
+	}
+	return msgs
+}
+
+func TestReassemble(t *testing.T) {
+	var received []*sarama.ConsumerMessage
+	var marked []int64
+	handler := middleware.Reassemble(tracing.NewLogger(loggingtest.NewTestLogger(t)), time.Minute, 100,
+		middleware.MarkIfNoError(func(msg *sarama.ConsumerMessage, _ func(string)) error {
+			received = append(received, msg)
+			return nil
+		}))
+	send := func(msg *sarama.ConsumerMessage) {
+		require.NoError(t, handler(msg, func(string) { marked = append(marked, msg.Offset) }))
+	}
+
+	first := chunked(t, "first document", 5, 0)
+	second := chunked(t, "second", 4, 3)
+	send(first[0])
+	send(first[1])
+	send(second[0])
+	send(second[1])
+	send(&sarama.ConsumerMessage{Topic: "documents", Value: []byte("plain"), Offset: 5})
+	assert.Empty(t, marked, "marks should be deferred while first message is pending")
+	send(first[1])
+	send(first[2])
+
+	require.Len(t, received, 3)
+	assert.Equal(t, "second", string(received[0].Value))
+	assert.Equal(t, "plain", string(received[1].Value))
+	assert.Equal(t, "first document", string(received[2].Value))
+	assert.Equal(t, int64(2), received[2].Offset)
+	require.Len(t, received[2].Headers, 1, "chunk headers should be removed")
+	assert.Equal(t, "trace", string(received[2].Headers[0].Key))
+	assert.Equal(t, []int64{5}, marked, "latest mark should be applied when no message is pending")
+}
+
+func TestReassembleLimits(t *testing.T) {
+	var received []string
+	handler := func(timeout time.Duration, maxBytes int) kafka.HandlerFunc {
+		received = nil
+		return middleware.Reassemble(tracing.NewLogger(loggingtest.NewTestLogger(t)), timeout, maxBytes,
+			func(msg *sarama.ConsumerMessage, _ func(string)) error {
+				received = append(received, string(msg.Value))
+				return nil
+			})
+	}
+	mark := func(string) {}
+
+	t.Run("timeout", func(t *testing.T) {
+		h := handler(10*time.Millisecond, 100)
+		msgs := chunked(t, "expired", 4, 0)
+		require.NoError(t, h(msgs[0], mark))
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, h(msgs[1], mark))
+		assert.Empty(t, received)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		h := handler(time.Minute, 5)
+		for _, msg := range chunked(t, "too large", 4, 0) {
+			require.NoError(t, h(msg, mark))
+		}
+		assert.Empty(t, received)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		h := handler(time.Minute, 6)
+		evicted := chunked(t, "evicted", 4, 0)
+		kept := chunked(t, "kept", 2, 2)
+		require.NoError(t, h(evicted[0], mark))
+		for _, msg := range kept {
+			require.NoError(t, h(msg, mark))
+		}
+		require.NoError(t, h(evicted[1], mark))
+		assert.Equal(t, []string{"kept"}, received)
+	})
+
+	t.Run("invalid headers", func(t *testing.T) {
+		h := handler(time.Minute, 100)
+		msg := chunked(t, "invalid", 4, 0)[0]
+		msg.Headers = msg.Headers[:len(msg.Headers)-1]
+		assert.Error(t, h(msg, mark))
+	})
+}
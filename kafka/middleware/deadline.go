@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+// ErrDeadlineExceeded is returned by Deadline and DeadlineSkip when message handler does not return in time.
+var ErrDeadlineExceeded = errors.New("message handler deadline exceeded")
+
+var stuckHandlers = metrics.RegisterCounterVec("stuck_handlers_total", "kafka",
+	"Total number of message handlers which exceeded processing deadline.", "topic")
+
+// Deadline will abort handling of a message when next handler does not return within given timeout.
+// Context passed to next handler is cancelled on timeout, the message is logged with trace context,
+// stuck handler counter is incremented and error wrapping ErrDeadlineExceeded is returned,
+// which stops consumption of the partition the same way as any other error.
+// The stuck handler keeps running in background, but its calls to mark are ignored.
+func Deadline(logger *tracing.Logger, timeout time.Duration, next CtxHandlerFunc) CtxHandlerFunc {
+	return deadline(logger, timeout, next, func(err error) error { return err })
+}
+
+// DeadlineSkip works like Deadline, but returns markable error, so the message gets skipped
+// with MarkIfNoError and consumption of the partition continues.
+func DeadlineSkip(logger *tracing.Logger, timeout time.Duration, next CtxHandlerFunc) CtxHandlerFunc {
+	return deadline(logger, timeout, next, Markable)
+}
+
+func deadline(logger *tracing.Logger, timeout time.Duration, next CtxHandlerFunc, wrap func(error) error) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		lock := &sync.Mutex{}
+		abandoned := false
+		guardedMark := func(metadata string) {
+			lock.Lock()
+			defer lock.Unlock()
+			if !abandoned {
+				mark(metadata)
+			}
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- next(ctx, msg, guardedMark)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+		}
+
+		lock.Lock()
+		abandoned = true
+		lock.Unlock()
+
+		// Handler might have returned at the same time as the deadline was reached.
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ctx.Err()
+		}
+
+		stuckHandlers.GetCustomCounter(msg.Topic).Inc()
+		err := fmt.Errorf("%w: message %s:%d:%d not handled within %v",
+			ErrDeadlineExceeded, msg.Topic, msg.Partition, msg.Offset, timeout)
+		logger.For(ctx).Errorf("message handling failed: %s", err)
+		return wrap(err)
+	}
+}
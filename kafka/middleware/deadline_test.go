@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+func TestDeadline(t *testing.T) {
+	log := tracing.NewLogger(logging.NewLogger())
+	msg := &sarama.ConsumerMessage{Topic: "deadline-topic"}
+	release := make(chan struct{})
+	defer close(release)
+
+	stuck := func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		<-release
+		mark("late")
+		return nil
+	}
+	fast := func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline, "context should have deadline")
+		mark("")
+		return errors.New("handler error")
+	}
+
+	t.Run("HandlerReturnsInTime", func(t *testing.T) {
+		marked := 0
+		err := middleware.Deadline(log, time.Second, fast)(context.Background(), msg, func(string) { marked++ })
+		assert.EqualError(t, err, "handler error")
+		assert.Equal(t, 1, marked)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		err := middleware.Deadline(log, 10*time.Millisecond, stuck)(context.Background(), msg, func(string) {
+			t.Error("abandoned handler should not mark")
+		})
+		require.ErrorIs(t, err, middleware.ErrDeadlineExceeded)
+
+		marked := false
+		handler := middleware.MarkIfNoError(func(msg *sarama.ConsumerMessage, mark func(string)) error {
+			return middleware.Deadline(log, 10*time.Millisecond, stuck)(context.Background(), msg, mark)
+		})
+		assert.Error(t, handler(msg, func(string) { marked = true }))
+		assert.False(t, marked, "aborted message should not be marked")
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		marked := false
+		handler := middleware.MarkIfNoError(func(msg *sarama.ConsumerMessage, mark func(string)) error {
+			return middleware.DeadlineSkip(log, 10*time.Millisecond, stuck)(context.Background(), msg, mark)
+		})
+		assert.NoError(t, handler(msg, func(string) { marked = true }))
+		assert.True(t, marked, "skipped message should be marked")
+	})
+
+	t.Run("ParentContextCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := middleware.Deadline(log, time.Second, stuck)(ctx, msg, func(string) {})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, middleware.ErrDeadlineExceeded)
+	})
+}
@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+var duplicateMessages = metrics.RegisterCounterVec("duplicate_messages_total", "kafka",
+	"Total number of messages skipped by deduplication.", "topic")
+
+// DedupStore remembers keys of processed messages for deduplication.
+// Any shared storage with expiring keys can be used, e.g. Redis SET with NX and PX options.
+type DedupStore interface {
+	// Seen reports whether key was stored and has not expired yet.
+	Seen(ctx context.Context, key string) (bool, error)
+	// Store remembers key for given ttl.
+	Store(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// DedupKeyFunc returns deduplication key of a message. Messages with empty key are not deduplicated.
+type DedupKeyFunc func(msg *sarama.ConsumerMessage) string
+
+// Deduplicate will skip messages, which were already processed within ttl according to store.
+// Duplicates are marked without calling next handler and counted in duplicate messages counter.
+// Key of a message is stored after next handler returns without error, so messages redelivered after
+// a failure or rebalance are handled again. Error storing the key is returned as markable error,
+// as the message itself was handled successfully.
+func Deduplicate(store DedupStore, keyFn DedupKeyFunc, ttl time.Duration, next CtxHandlerFunc) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		key := keyFn(msg)
+		if key == "" {
+			return next(ctx, msg, mark)
+		}
+
+		seen, err := store.Seen(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check deduplication key %s: %w", key, err)
+		}
+		if seen {
+			duplicateMessages.GetCustomCounter(msg.Topic).Inc()
+			mark("")
+			return nil
+		}
+
+		if err := next(ctx, msg, mark); err != nil {
+			return err
+		}
+		if err := store.Store(ctx, key, ttl); err != nil {
+			return Markable(fmt.Errorf("failed to store deduplication key %s: %w", key, err))
+		}
+		return nil
+	}
+}
+
+// HeaderKey uses value of given header as deduplication key, e.g. a message id set by the producer.
+func HeaderKey(header string) DedupKeyFunc {
+	return func(msg *sarama.ConsumerMessage) string {
+		for _, h := range msg.Headers {
+			if h != nil && string(h.Key) == header {
+				return string(h.Value)
+			}
+		}
+		return ""
+	}
+}
+
+// MessageKey uses topic and message key as deduplication key.
+func MessageKey(msg *sarama.ConsumerMessage) string {
+	if len(msg.Key) == 0 {
+		return ""
+	}
+	return msg.Topic + "/" + string(msg.Key)
+}
+
+// OffsetKey uses topic, partition and offset as deduplication key, which drops only
+// redeliveries of the same record, e.g. after a rebalance before offsets were committed.
+func OffsetKey(msg *sarama.ConsumerMessage) string {
+	return msg.Topic + "/" + strconv.Itoa(int(msg.Partition)) + "/" + strconv.FormatInt(msg.Offset, 10)
+}
+
+// MemoryDedupStore is in-memory DedupStore, which keeps up to size most recently stored keys.
+// It only deduplicates messages consumed by the same instance.
+type MemoryDedupStore struct {
+	lock    sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryDedupStore returns MemoryDedupStore with given maximum number of keys.
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Seen reports whether key was stored and has not expired yet.
+func (s *MemoryDedupStore) Seen(_ context.Context, key string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if !s.now().Before(e.Value.(*dedupEntry).expires) {
+		s.order.Remove(e)
+		delete(s.entries, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Store remembers key for given ttl, evicting the least recently stored key when store is full.
+func (s *MemoryDedupStore) Store(_ context.Context, key string, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*dedupEntry).expires = s.now().Add(ttl)
+		s.order.MoveToFront(e)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(&dedupEntry{key: key, expires: s.now().Add(ttl)})
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*dedupEntry).key)
+	}
+	return nil
+}
+
+// SQLDedupStore is DedupStore backed by a SQL table, so that deduplication works across instances:
+//
+//	CREATE TABLE kafka_dedup (dedup_key VARCHAR(255) PRIMARY KEY, expires_at BIGINT NOT NULL)
+//
+// Expiration times are stored as unix milliseconds. Expired keys are overwritten when stored again,
+// Purge can be called periodically to delete them.
+type SQLDedupStore struct {
+	db    *sql.DB
+	table string
+	// Placeholder returns n-th (1-based) query placeholder, defaults to PostgreSQL style $n.
+	// Use func(int) string { return "?" } for MySQL and SQLite.
+	Placeholder func(n int) string
+	now         func() time.Time
+}
+
+// NewSQLDedupStore returns SQLDedupStore using given table.
+func NewSQLDedupStore(db *sql.DB, table string) *SQLDedupStore {
+	return &SQLDedupStore{
+		db:          db,
+		table:       table,
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		now:         time.Now,
+	}
+}
+
+// Seen reports whether key was stored and has not expired yet.
+func (s *SQLDedupStore) Seen(ctx context.Context, key string) (bool, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE dedup_key = %s AND expires_at > %s",
+		s.table, s.Placeholder(1), s.Placeholder(2))
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, key, s.now().UnixMilli()).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Store remembers key for given ttl.
+func (s *SQLDedupStore) Store(ctx context.Context, key string, ttl time.Duration) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE dedup_key = %s", s.table, s.Placeholder(1)), key); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (dedup_key, expires_at) VALUES (%s, %s)",
+		s.table, s.Placeholder(1), s.Placeholder(2)), key, s.now().Add(ttl).UnixMilli()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Purge deletes expired keys.
+func (s *SQLDedupStore) Purge(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE expires_at <= %s", s.table, s.Placeholder(1)),
+		s.now().UnixMilli())
+	return err
+}
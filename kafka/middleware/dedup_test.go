@@ -0,0 +1,76 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+)
+
+func TestDeduplicate(t *testing.T) {
+	handled := 0
+	fail := false
+	handler := middleware.Deduplicate(middleware.NewMemoryDedupStore(10), middleware.HeaderKey("message-id"), time.Minute,
+		func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+			if fail {
+				return errors.New("handling failed")
+			}
+			handled++
+			mark("")
+			return nil
+		})
+
+	withID := func(id string) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{Topic: "topic", Headers: []*sarama.RecordHeader{{Key: []byte("message-id"), Value: []byte(id)}}}
+	}
+
+	marked := 0
+	mark := func(string) { marked++ }
+
+	fail = true
+	assert.Error(t, handler(context.Background(), withID("1"), mark))
+	fail = false
+	require.NoError(t, handler(context.Background(), withID("1"), mark))
+	require.NoError(t, handler(context.Background(), withID("1"), mark))
+	require.NoError(t, handler(context.Background(), withID("2"), mark))
+	require.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Topic: "topic"}, mark))
+	require.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Topic: "topic"}, mark))
+
+	assert.Equal(t, 4, handled, "failed and duplicate messages should not be counted, messages without key should not be deduplicated")
+	assert.Equal(t, 5, marked, "duplicate should be marked")
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	store := middleware.NewMemoryDedupStore(2)
+
+	require.NoError(t, store.Store(ctx, "a", time.Minute))
+	require.NoError(t, store.Store(ctx, "b", time.Minute))
+	require.NoError(t, store.Store(ctx, "c", 10*time.Millisecond))
+
+	seen, err := store.Seen(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, seen, "oldest key should be evicted")
+	seen, _ = store.Seen(ctx, "b")
+	assert.True(t, seen)
+	seen, _ = store.Seen(ctx, "c")
+	assert.True(t, seen)
+
+	time.Sleep(20 * time.Millisecond)
+	seen, _ = store.Seen(ctx, "c")
+	assert.False(t, seen, "key should expire after ttl")
+}
+
+func TestDedupKeys(t *testing.T) {
+	msg := &sarama.ConsumerMessage{Topic: "topic", Partition: 1, Offset: 42, Key: []byte("key")}
+	assert.Equal(t, "topic/key", middleware.MessageKey(msg))
+	assert.Equal(t, "topic/1/42", middleware.OffsetKey(msg))
+	assert.Equal(t, "", middleware.HeaderKey("message-id")(msg))
+	assert.Equal(t, "", middleware.MessageKey(&sarama.ConsumerMessage{Topic: "topic"}))
+}
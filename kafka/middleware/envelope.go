@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/kafka"
+)
+
+// ErrInvalidEnvelope is returned by ValidateEnvelope when message headers do not form a valid envelope.
+var ErrInvalidEnvelope = errors.New("invalid message envelope")
+
+type envelopeKey struct{}
+
+// ValidateEnvelope reads kafka.Envelope from message headers and stores it in context passed to next handler.
+// If any of required headers is missing or envelope headers have invalid values, markable error wrapping
+// ErrInvalidEnvelope is returned without calling next handler, so the message gets skipped with MarkIfNoError.
+func ValidateEnvelope(next CtxHandlerFunc, requiredHeaders ...string) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		for _, key := range requiredHeaders {
+			if _, ok := kafka.Header(msg, key); !ok {
+				return Markable(fmt.Errorf("%w: missing %s header", ErrInvalidEnvelope, key))
+			}
+		}
+
+		envelope, err := kafka.EnvelopeFromMessage(msg)
+		if err != nil {
+			return Markable(fmt.Errorf("%w: %s", ErrInvalidEnvelope, err))
+		}
+
+		return next(context.WithValue(ctx, envelopeKey{}, envelope), msg, mark)
+	}
+}
+
+// EnvelopeFromContext returns envelope stored in context by ValidateEnvelope.
+func EnvelopeFromContext(ctx context.Context) (kafka.Envelope, bool) {
+	envelope, ok := ctx.Value(envelopeKey{}).(kafka.Envelope)
+	return envelope, ok
+}
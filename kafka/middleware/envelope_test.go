@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+)
+
+func TestValidateEnvelope(t *testing.T) {
+	producedAt := time.UnixMilli(time.Now().UnixMilli())
+	producerMsg := &sarama.ProducerMessage{Headers: []sarama.RecordHeader{{Key: []byte(kafka.HeaderContentType), Value: []byte("text/plain")}}}
+	envelope := kafka.Envelope{ContentType: "application/json", SchemaID: "1", ProducedAt: producedAt, OriginService: "producer"}
+	envelope.Apply(producerMsg)
+	require.Len(t, producerMsg.Headers, 4)
+
+	tests := []struct {
+		name     string
+		headers  []*sarama.RecordHeader
+		required []string
+		wantErr  bool
+	}{{
+		name:     "ValidEnvelope",
+		headers:  toConsumerHeaders(producerMsg.Headers),
+		required: []string{kafka.HeaderOriginService, kafka.HeaderProducedAt},
+	}, {
+		name:     "MissingRequiredHeader",
+		headers:  toConsumerHeaders(producerMsg.Headers[1:]),
+		required: []string{kafka.HeaderContentType},
+		wantErr:  true,
+	}, {
+		name:    "InvalidProducedAt",
+		headers: []*sarama.RecordHeader{{Key: []byte(kafka.HeaderProducedAt), Value: []byte("yesterday")}},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var got kafka.Envelope
+			called, marked := false, false
+			handler := middleware.ValidateEnvelope(func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+				called = true
+				var ok bool
+				got, ok = middleware.EnvelopeFromContext(ctx)
+				assert.True(t, ok)
+				return nil
+			}, tt.required...)
+
+			err := handler(context.Background(), &sarama.ConsumerMessage{Headers: tt.headers}, func(string) { marked = true })
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, middleware.ErrInvalidEnvelope))
+				assert.False(t, called)
+
+				err = middleware.MarkIfNoError(func(msg *sarama.ConsumerMessage, mark func(string)) error {
+					return handler(context.Background(), msg, mark)
+				})(&sarama.ConsumerMessage{Headers: tt.headers}, func(string) { marked = true })
+				assert.NoError(t, err)
+				assert.True(t, marked)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, called)
+			assert.Equal(t, envelope, got)
+		})
+	}
+}
+
+func TestEnvelopeFromContextMissing(t *testing.T) {
+	_, ok := middleware.EnvelopeFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func toConsumerHeaders(headers []sarama.RecordHeader) []*sarama.RecordHeader {
+	res := make([]*sarama.RecordHeader, len(headers))
+	for i := range headers {
+		res[i] = &headers[i]
+	}
+	return res
+}
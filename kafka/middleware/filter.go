@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+// Predicate reports whether message should be passed to the handler.
+type Predicate func(msg *sarama.ConsumerMessage) bool
+
+var filteredMessages = metrics.RegisterCounterVec("filtered_messages_total", "kafka",
+	"Total number of messages skipped by filter.", "topic")
+
+// Filter will pass only messages matching given predicate to next handler.
+// Other messages are marked and skipped without calling next handler and counted in filtered messages counter.
+func Filter(predicate Predicate, next CtxHandlerFunc) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		if !predicate(msg) {
+			filteredMessages.GetCustomCounter(msg.Topic).Inc()
+			mark("")
+			return nil
+		}
+		return next(ctx, msg, mark)
+	}
+}
+
+// HeaderEquals matches messages having header with given key and value.
+func HeaderEquals(key, value string) Predicate {
+	return func(msg *sarama.ConsumerMessage) bool {
+		for _, h := range msg.Headers {
+			if h != nil && string(h.Key) == key && string(h.Value) == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// KeyHasPrefix matches messages with key starting with given prefix.
+func KeyHasPrefix(prefix string) Predicate {
+	return func(msg *sarama.ConsumerMessage) bool {
+		return bytes.HasPrefix(msg.Key, []byte(prefix))
+	}
+}
+
+// JSONFieldEquals matches messages with JSON value having field in given dot separated path equal to given value.
+// String fields are compared to the value as is and other fields by their JSON representation, e.g. value "2"
+// matches both "2" and 2. Messages with invalid JSON don't match.
+func JSONFieldEquals(path, value string) Predicate {
+	fields := strings.Split(path, ".")
+	return func(msg *sarama.ConsumerMessage) bool {
+		raw := json.RawMessage(msg.Value)
+		for _, field := range fields {
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &object); err != nil {
+				return false
+			}
+			var ok bool
+			if raw, ok = object[field]; !ok {
+				return false
+			}
+		}
+
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s == value
+		}
+		return string(bytes.TrimSpace(raw)) == value
+	}
+}
+
+// Not matches messages not matching given predicate.
+func Not(predicate Predicate) Predicate {
+	return func(msg *sarama.ConsumerMessage) bool {
+		return !predicate(msg)
+	}
+}
+
+// Any matches messages matching any of given predicates.
+func Any(predicates ...Predicate) Predicate {
+	return func(msg *sarama.ConsumerMessage) bool {
+		for _, predicate := range predicates {
+			if predicate(msg) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+)
+
+func TestFilter(t *testing.T) {
+	handled := 0
+	handler := middleware.Filter(middleware.KeyHasPrefix("tenant-a/"), func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		handled++
+		return nil
+	})
+
+	marked := 0
+	mark := func(string) { marked++ }
+	assert.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Key: []byte("tenant-a/1")}, mark))
+	assert.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Key: []byte("tenant-b/1")}, mark))
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, 1, marked, "only skipped message should be marked by filter")
+}
+
+func TestPredicates(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Key:     []byte("tenant-a/1"),
+		Value:   []byte(`{"tenant": {"id": "a"}, "version": 2}`),
+		Headers: []*sarama.RecordHeader{{Key: []byte("tenant"), Value: []byte("a")}},
+	}
+
+	tests := []struct {
+		name      string
+		predicate middleware.Predicate
+		expected  bool
+	}{
+		{"HeaderEquals", middleware.HeaderEquals("tenant", "a"), true},
+		{"HeaderNotEquals", middleware.HeaderEquals("tenant", "b"), false},
+		{"HeaderMissing", middleware.HeaderEquals("type", "a"), false},
+		{"KeyHasPrefix", middleware.KeyHasPrefix("tenant-a/"), true},
+		{"KeyHasNoPrefix", middleware.KeyHasPrefix("tenant-b/"), false},
+		{"JSONNestedString", middleware.JSONFieldEquals("tenant.id", "a"), true},
+		{"JSONNumber", middleware.JSONFieldEquals("version", "2"), true},
+		{"JSONNotEquals", middleware.JSONFieldEquals("tenant.id", "b"), false},
+		{"JSONMissingField", middleware.JSONFieldEquals("tenant.name", "a"), false},
+		{"JSONNotObject", middleware.JSONFieldEquals("version.major", "2"), false},
+		{"Not", middleware.Not(middleware.KeyHasPrefix("tenant-a/")), false},
+		{"Any", middleware.Any(middleware.KeyHasPrefix("tenant-b/"), middleware.HeaderEquals("tenant", "a")), true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.predicate(msg))
+		})
+	}
+
+	assert.False(t, middleware.JSONFieldEquals("tenant", "a")(&sarama.ConsumerMessage{Value: []byte("not json")}))
+}
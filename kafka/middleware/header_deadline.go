@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+// ErrInvalidDeadline is returned by PropagateDeadline when deadline headers have invalid values.
+var ErrInvalidDeadline = errors.New("invalid message deadline")
+
+var expiredMessages = metrics.RegisterCounterVec("expired_messages_total", "kafka",
+	"Total number of messages skipped because their deadline had passed before handling.", "topic")
+
+// PropagateDeadline passes context with deadline of the message, read with kafka.MessageDeadline, to next handler,
+// so that e.g. replies to a request are not processed after the requester has given up waiting.
+// Messages which have already expired are marked and skipped without calling next handler and counted in
+// expired messages counter. Messages without deadline are passed to next handler as they are.
+// If deadline headers have invalid values, markable error wrapping ErrInvalidDeadline is returned.
+func PropagateDeadline(next CtxHandlerFunc) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		deadline, ok, err := kafka.MessageDeadline(msg)
+		if err != nil {
+			return Markable(fmt.Errorf("%w: %s", ErrInvalidDeadline, err))
+		}
+		if !ok {
+			return next(ctx, msg, mark)
+		}
+		if !time.Now().Before(deadline) {
+			expiredMessages.GetCustomCounter(msg.Topic).Inc()
+			mark("")
+			return nil
+		}
+
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		return next(ctx, msg, mark)
+	}
+}
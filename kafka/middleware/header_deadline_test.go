@@ -0,0 +1,92 @@
+package middleware_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+)
+
+func consumed(msg *sarama.ProducerMessage) *sarama.ConsumerMessage {
+	c := &sarama.ConsumerMessage{Topic: msg.Topic, Timestamp: msg.Timestamp}
+	for i := range msg.Headers {
+		c.Headers = append(c.Headers, &msg.Headers[i])
+	}
+	return c
+}
+
+func TestMessageDeadline(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+
+	tests := []struct {
+		name    string
+		msg     *sarama.ProducerMessage
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "NoDeadline", msg: &sarama.ProducerMessage{}},
+		{name: "Deadline", msg: kafka.SetDeadline(&sarama.ProducerMessage{}, now.Add(time.Minute)), want: now.Add(time.Minute)},
+		{name: "TTLFromProducedAt", msg: kafka.SetTTL(kafka.Envelope{ProducedAt: now}.Apply(&sarama.ProducerMessage{}), time.Second),
+			want: now.Add(time.Second)},
+		{name: "TTLFromTimestamp", msg: kafka.SetTTL(&sarama.ProducerMessage{Timestamp: now}, time.Second), want: now.Add(time.Second)},
+		{name: "TTLWithoutTimestamp", msg: kafka.SetTTL(&sarama.ProducerMessage{}, time.Second)},
+		{name: "EarlierWins", msg: kafka.SetTTL(kafka.SetDeadline(&sarama.ProducerMessage{Timestamp: now}, now.Add(time.Minute)), time.Second),
+			want: now.Add(time.Second)},
+		{name: "InvalidDeadline", msg: &sarama.ProducerMessage{Headers: []sarama.RecordHeader{{Key: []byte(kafka.HeaderDeadline), Value: []byte("soon")}}},
+			wantErr: true},
+		{name: "InvalidTTL", msg: &sarama.ProducerMessage{Headers: []sarama.RecordHeader{{Key: []byte(kafka.HeaderTTL), Value: []byte("1s")}}},
+			wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadline, ok, err := kafka.MessageDeadline(consumed(tt.msg))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, !tt.want.IsZero(), ok)
+			assert.True(t, tt.want.Equal(deadline), "expected %s, got %s", tt.want, deadline)
+		})
+	}
+}
+
+func TestPropagateDeadline(t *testing.T) {
+	var handledDeadline time.Time
+	var hasDeadline bool
+	handled := 0
+	handler := middleware.PropagateDeadline(func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		handled++
+		handledDeadline, hasDeadline = ctx.Deadline()
+		return nil
+	})
+	marked := 0
+	mark := func(string) { marked++ }
+
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	require.NoError(t, handler(context.Background(), consumed(kafka.SetDeadline(&sarama.ProducerMessage{}, deadline)), mark))
+	assert.True(t, hasDeadline)
+	assert.True(t, deadline.Equal(handledDeadline))
+
+	require.NoError(t, handler(context.Background(), consumed(&sarama.ProducerMessage{}), mark))
+	assert.False(t, hasDeadline)
+	assert.Equal(t, 2, handled)
+	assert.Equal(t, 0, marked)
+
+	expired := kafka.SetTTL(&sarama.ProducerMessage{Topic: "replies", Timestamp: time.Now().Add(-time.Minute)}, time.Second)
+	require.NoError(t, handler(context.Background(), consumed(expired), mark))
+	assert.Equal(t, 2, handled, "expired message should not be handled")
+	assert.Equal(t, 1, marked, "expired message should be marked")
+
+	invalid := &sarama.ProducerMessage{Headers: []sarama.RecordHeader{{Key: []byte(kafka.HeaderDeadline), Value: []byte(strconv.Quote("x"))}}}
+	err := handler(context.Background(), consumed(invalid), mark)
+	require.ErrorIs(t, err, middleware.ErrInvalidDeadline)
+	assert.Equal(t, 2, handled)
+}
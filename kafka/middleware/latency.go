@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+// EndToEndLatencyBuckets are buckets of end-to-end latency histogram in seconds, from milliseconds up to an hour
+// of pipeline lag.
+var EndToEndLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600}
+
+var endToEndLatency = metrics.RegisterHistogramVec("end_to_end_latency_seconds", "kafka",
+	"Time from producing messages until they are received for handling in seconds by topic and consumer group.",
+	EndToEndLatencyBuckets, "topic", "group")
+
+// EndToEndLatency observes time since the message was produced, read with kafka.MessageProducedAt, in end-to-end
+// latency histogram labeled with topic and given consumer group, and passes the message to next handler.
+// Unlike offset lag, it shows how late messages are handled, including time spent in upstream pipeline stages
+// which forward produced-at header. Messages without produce time are not observed. Latency is observed when
+// the message is received, before next handler is called. Negative latency caused by clock skew between producer and consumer hosts is observed as zero.
+func EndToEndLatency(group string, next CtxHandlerFunc) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		if producedAt, ok := kafka.MessageProducedAt(msg); ok {
+			latency := time.Since(producedAt)
+			if latency < 0 {
+				latency = 0
+			}
+			endToEndLatency.GetCustomHistogram(msg.Topic, group).Observe(latency.Seconds())
+		}
+		return next(ctx, msg, mark)
+	}
+}
@@ -0,0 +1,82 @@
+package middleware_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+)
+
+func latencyHistogram(t *testing.T, topic, group string) (count uint64, sum float64) {
+	gathered, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range gathered {
+		if family.GetName() != "com_metrics_kafka_end_to_end_latency_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			if labels["topic"] == topic && labels["group"] == group {
+				return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+			}
+		}
+	}
+	return 0, 0
+}
+
+func TestEndToEndLatency(t *testing.T) {
+	handled := 0
+	handler := middleware.EndToEndLatency("latency-group", func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		handled++
+		return nil
+	})
+	now := time.Now()
+
+	fromHeader := consumed(kafka.Envelope{ProducedAt: now.Add(-time.Minute)}.Apply(&sarama.ProducerMessage{Topic: "latency-header", Timestamp: now}))
+	require.NoError(t, handler(context.Background(), fromHeader, func(string) {}))
+	count, sum := latencyHistogram(t, "latency-header", "latency-group")
+	assert.Equal(t, uint64(1), count)
+	assert.InDelta(t, 60, sum, 1, "produced-at header should be preferred over timestamp")
+
+	invalidHeader := &sarama.ProducerMessage{Topic: "latency-timestamp", Timestamp: now.Add(-10 * time.Second),
+		Headers: []sarama.RecordHeader{{Key: []byte(kafka.HeaderProducedAt), Value: []byte("yesterday")}}}
+	require.NoError(t, handler(context.Background(), consumed(invalidHeader), func(string) {}))
+	count, sum = latencyHistogram(t, "latency-timestamp", "latency-group")
+	assert.Equal(t, uint64(1), count)
+	assert.InDelta(t, 10, sum, 1, "timestamp should be used when header is invalid")
+
+	skewed := consumed(kafka.Envelope{ProducedAt: now.Add(time.Hour)}.Apply(&sarama.ProducerMessage{Topic: "latency-skew"}))
+	require.NoError(t, handler(context.Background(), skewed, func(string) {}))
+	count, sum = latencyHistogram(t, "latency-skew", "latency-group")
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 0.0, sum)
+
+	require.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Topic: "latency-none"}, func(string) {}))
+	count, _ = latencyHistogram(t, "latency-none", "latency-group")
+	assert.Equal(t, uint64(0), count)
+	assert.Equal(t, 4, handled)
+}
+
+func TestMessageProducedAt(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	msg := &sarama.ConsumerMessage{Timestamp: now.Add(-time.Second), Headers: []*sarama.RecordHeader{
+		{Key: []byte(kafka.HeaderProducedAt), Value: []byte(strconv.FormatInt(now.UnixMilli(), 10))},
+	}}
+	producedAt, ok := kafka.MessageProducedAt(msg)
+	require.True(t, ok)
+	assert.True(t, now.Equal(producedAt))
+
+	_, ok = kafka.MessageProducedAt(&sarama.ConsumerMessage{})
+	assert.False(t, ok)
+}
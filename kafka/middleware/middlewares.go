@@ -168,6 +168,8 @@ type markable struct {
 	error
 }
 
+func (m markable) Unwrap() error { return m.error }
+
 // Markable will return markable error if given error is markable.
 func Markable(err error) error {
 	if err == nil {
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+var (
+	consumedPayloadSize = metrics.RegisterSummaryVec("consumed_payload_size_bytes", "kafka",
+		"Size of consumed message key, value and headers in bytes after decompression.", "topic")
+	oversizedConsumedMessages = metrics.RegisterCounterVec("oversized_consumed_messages_total", "kafka",
+		"Total number of consumed messages exceeding the payload soft limit.", "topic")
+)
+
+// PayloadSize observes size of consumed messages, see kafka.ConsumerMessageSize, in consumed payload size summary.
+// Messages larger than softLimit bytes are counted in oversized consumed messages counter and logged as warnings,
+// to catch producers drifting toward broker's message.max.bytes. Zero softLimit disables the check.
+// Messages are always passed to next handler. Sarama doesn't expose compression codec of consumed batches,
+// so unlike produced payload size summary, consumed one is labeled only by topic.
+func PayloadSize(logger *tracing.Logger, softLimit int, next CtxHandlerFunc) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		size := kafka.ConsumerMessageSize(msg)
+		consumedPayloadSize.GetCustomSummary(msg.Topic).Observe(float64(size))
+		if softLimit > 0 && size > softLimit {
+			oversizedConsumedMessages.GetCustomCounter(msg.Topic).Inc()
+			logger.For(ctx).Warnf("message %s:%d:%d of %d bytes exceeds payload soft limit of %d bytes",
+				msg.Topic, msg.Partition, msg.Offset, size, softLimit)
+		}
+		return next(ctx, msg, mark)
+	}
+}
+
+// ValidatePayload rejects consumed messages which content-type header or value is not valid for the serializer
+// registered for their topic, see kafka.SerializerRegistry. Markable error wrapping kafka.ErrContentTypeMismatch
+// or kafka.ErrInvalidPayload is returned without calling next handler, so the message gets skipped with MarkIfNoError.
+// Messages of topics without serializer are passed to next handler.
+func ValidatePayload(serializers *kafka.SerializerRegistry, next CtxHandlerFunc) CtxHandlerFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		if err := serializers.ValidateConsumed(msg); err != nil {
+			return Markable(err)
+		}
+		return next(ctx, msg, mark)
+	}
+}
@@ -0,0 +1,103 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/middleware"
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+func TestPayloadSize(t *testing.T) {
+	handled := 0
+	handler := middleware.PayloadSize(tracing.NewLogger(logging.NewLogger()), 10, func(ctx context.Context, msg *sarama.ConsumerMessage, mark func(string)) error {
+		handled++
+		return nil
+	})
+
+	require.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Topic: "payload-topic", Value: []byte("small")}, func(string) {}))
+	require.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Topic: "payload-topic", Value: []byte("larger than limit")}, func(string) {}))
+	assert.Equal(t, 2, handled, "oversized message should be handled too")
+
+	gathered, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	families := metrics.MetricFamilies{}
+	for _, family := range gathered {
+		families[family.GetName()] = family
+	}
+	oversized, ok := families.Value("com_metrics_kafka_oversized_consumed_messages_total", map[string]string{"topic": "payload-topic"})
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, oversized)
+
+	require.Contains(t, families, "com_metrics_kafka_consumed_payload_size_bytes")
+	for _, m := range families["com_metrics_kafka_consumed_payload_size_bytes"].GetMetric() {
+		assert.Equal(t, uint64(2), m.GetSummary().GetSampleCount())
+		assert.Equal(t, 22.0, m.GetSummary().GetSampleSum())
+	}
+}
+
+func TestValidatePayload(t *testing.T) {
+	serializers := kafka.NewSerializerRegistry().Register("orders", kafka.JSONSerializer{})
+	tests := []struct {
+		name    string
+		msg     *sarama.ConsumerMessage
+		wantErr error
+	}{{
+		name: "Valid",
+		msg: &sarama.ConsumerMessage{Topic: "orders", Value: []byte(`{"id":"1"}`), Headers: []*sarama.RecordHeader{
+			{Key: []byte(kafka.HeaderContentType), Value: []byte(kafka.ContentTypeJSON)},
+		}},
+	}, {
+		name: "WithoutContentType",
+		msg:  &sarama.ConsumerMessage{Topic: "orders", Value: []byte(`{"id":"1"}`)},
+	}, {
+		name:    "InvalidValue",
+		msg:     &sarama.ConsumerMessage{Topic: "orders", Value: []byte(`{"id":`)},
+		wantErr: kafka.ErrInvalidPayload,
+	}, {
+		name: "ContentTypeMismatch",
+		msg: &sarama.ConsumerMessage{Topic: "orders", Value: []byte(`{}`), Headers: []*sarama.RecordHeader{
+			{Key: []byte(kafka.HeaderContentType), Value: []byte(kafka.ContentTypeProtobuf)},
+		}},
+		wantErr: kafka.ErrContentTypeMismatch,
+	}, {
+		name: "UnregisteredTopic",
+		msg:  &sarama.ConsumerMessage{Topic: "other", Value: []byte(`binary`)},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := middleware.ValidatePayload(serializers, func(context.Context, *sarama.ConsumerMessage, func(string)) error {
+				called = true
+				return nil
+			})
+
+			err := handler(context.Background(), tt.msg, func(string) {})
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				assert.False(t, called)
+
+				marked := false
+				err = middleware.MarkIfNoError(func(msg *sarama.ConsumerMessage, mark func(string)) error {
+					return handler(context.Background(), msg, mark)
+				})(tt.msg, func(string) { marked = true })
+				assert.NoError(t, err)
+				assert.True(t, marked)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, called)
+		})
+	}
+}
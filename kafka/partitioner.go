@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"hash/fnv"
+
+	"github.com/IBM/sarama"
+)
+
+// ConsistentHashPartitioner assigns messages to partitions by consistent hash of the message key,
+// so that growing partition count from n to n+1 moves only 1/(n+1) of the keys, all to the new partition.
+// Default sarama hash partitioner uses hash modulo partition count, which moves almost every key.
+// Messages without key are assigned to random partitions.
+type ConsistentHashPartitioner struct {
+	random sarama.Partitioner
+}
+
+// NewConsistentHashPartitioner is sarama.PartitionerConstructor for ConsistentHashPartitioner:
+//
+//	config.Producer.Partitioner = kafka.NewConsistentHashPartitioner
+func NewConsistentHashPartitioner(topic string) sarama.Partitioner {
+	return &ConsistentHashPartitioner{random: sarama.NewRandomPartitioner(topic)}
+}
+
+// Partition returns partition of the message key.
+func (p *ConsistentHashPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Key == nil {
+		return p.random.Partition(message, numPartitions)
+	}
+	key, err := message.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+	return ConsistentPartition(key, numPartitions), nil
+}
+
+// RequiresConsistency tells sarama that messages with the same key must always go to the same partition.
+func (p *ConsistentHashPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+// ConsistentPartition returns partition of given key among numPartitions partitions,
+// as assigned by ConsistentHashPartitioner.
+func ConsistentPartition(key []byte, numPartitions int32) int32 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return jumpHash(h.Sum64(), numPartitions)
+}
+
+// RemappedKeys returns keys, which ConsistentHashPartitioner assigns to a different partition
+// when partition count changes from oldPartitions to newPartitions, mapped to their new partitions.
+// It can be used to plan migration of keyed state before adding partitions to a topic.
+func RemappedKeys(keys []string, oldPartitions, newPartitions int32) map[string]int32 {
+	remapped := map[string]int32{}
+	for _, key := range keys {
+		if p := ConsistentPartition([]byte(key), newPartitions); p != ConsistentPartition([]byte(key), oldPartitions) {
+			remapped[key] = p
+		}
+	}
+	return remapped
+}
+
+// jumpHash is the jump consistent hash by Lamping and Veach, see https://arxiv.org/abs/1406.2294.
+func jumpHash(key uint64, numBuckets int32) int32 {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int32(b)
+}
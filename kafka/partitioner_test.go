@@ -0,0 +1,52 @@
+package kafka_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsistentHashPartitioner(t *testing.T) {
+	p := kafka.NewConsistentHashPartitioner("topic")
+	assert.True(t, p.RequiresConsistency())
+
+	msg := &sarama.ProducerMessage{Topic: "topic", Key: sarama.StringEncoder("customer-1")}
+	first, err := p.Partition(msg, 12)
+	require.NoError(t, err)
+	assert.Equal(t, kafka.ConsistentPartition([]byte("customer-1"), 12), first)
+	for i := 0; i < 10; i++ {
+		partition, err := p.Partition(msg, 12)
+		require.NoError(t, err)
+		assert.Equal(t, first, partition)
+	}
+
+	partition, err := p.Partition(&sarama.ProducerMessage{Topic: "topic"}, 12)
+	require.NoError(t, err)
+	assert.True(t, partition >= 0 && partition < 12)
+}
+
+func TestConsistentPartitionMovesKeysOnlyToNewPartitions(t *testing.T) {
+	keys := make([]string, 10000)
+	counts := map[int32]int{}
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+		partition := kafka.ConsistentPartition([]byte(keys[i]), 10)
+		require.True(t, partition >= 0 && partition < 10)
+		counts[partition]++
+	}
+	assert.Len(t, counts, 10)
+	for _, count := range counts {
+		assert.InDelta(t, 1000, count, 200, "keys should be evenly distributed")
+	}
+
+	remapped := kafka.RemappedKeys(keys, 10, 12)
+	assert.InDelta(t, len(keys)*2/12, len(remapped), 300)
+	for _, partition := range remapped {
+		assert.True(t, partition >= 10, "keys should only move to new partitions")
+	}
+	assert.Empty(t, kafka.RemappedKeys(keys, 10, 10))
+}
@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+var (
+	producedPayloadSize = metrics.RegisterSummaryVec("produced_payload_size_bytes", "kafka",
+		"Size of produced message key, value and headers in bytes before compression.", "topic", "codec")
+	oversizedProducedMessages = metrics.RegisterCounterVec("oversized_produced_messages_total", "kafka",
+		"Total number of produced messages exceeding the payload soft limit.", "topic")
+)
+
+// ProducerMessageSize returns size of message key, value and headers in bytes.
+func ProducerMessageSize(msg *sarama.ProducerMessage) int {
+	size := 0
+	if msg.Key != nil {
+		size += msg.Key.Length()
+	}
+	if msg.Value != nil {
+		size += msg.Value.Length()
+	}
+	for _, h := range msg.Headers {
+		size += len(h.Key) + len(h.Value)
+	}
+	return size
+}
+
+// ConsumerMessageSize returns size of message key, value and headers in bytes.
+func ConsumerMessageSize(msg *sarama.ConsumerMessage) int {
+	size := len(msg.Key) + len(msg.Value)
+	for _, h := range msg.Headers {
+		if h != nil {
+			size += len(h.Key) + len(h.Value)
+		}
+	}
+	return size
+}
+
+// payloadMonitor observes sizes of produced messages and warns about messages exceeding soft limit.
+type payloadMonitor struct {
+	codec     string
+	softLimit int
+	log       *tracing.Logger
+}
+
+func newPayloadMonitor(config *sarama.Config, logger *tracing.Logger) payloadMonitor {
+	return payloadMonitor{codec: config.Producer.Compression.String(), log: logger}
+}
+
+func (m payloadMonitor) observe(msg *sarama.ProducerMessage) {
+	size := ProducerMessageSize(msg)
+	producedPayloadSize.GetCustomSummary(msg.Topic, m.codec).Observe(float64(size))
+	if m.softLimit > 0 && size > m.softLimit {
+		oversizedProducedMessages.GetCustomCounter(msg.Topic).Inc()
+		if m.log != nil {
+			m.log.Warnf("message of %d bytes to topic %s exceeds payload soft limit of %d bytes", size, msg.Topic, m.softLimit)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package kafka_test
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageSize(t *testing.T) {
+	produced := &sarama.ProducerMessage{
+		Key:     sarama.StringEncoder("key"),
+		Value:   sarama.ByteEncoder("value"),
+		Headers: []sarama.RecordHeader{{Key: []byte("h"), Value: []byte("12")}},
+	}
+	assert.Equal(t, 11, kafka.ProducerMessageSize(produced))
+	assert.Equal(t, 0, kafka.ProducerMessageSize(&sarama.ProducerMessage{}))
+
+	consumed := &sarama.ConsumerMessage{
+		Key:     []byte("key"),
+		Value:   []byte("value"),
+		Headers: []*sarama.RecordHeader{{Key: []byte("h"), Value: []byte("12")}, nil},
+	}
+	assert.Equal(t, 11, kafka.ConsumerMessageSize(consumed))
+}
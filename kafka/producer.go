@@ -16,8 +16,11 @@ type ProducerConf struct {
 // Producer is a wrapper for sarama.SyncProducer which adds tracing and metrics automatically
 // and has only single method for sending messages.
 type Producer struct {
-	client sarama.SyncProducer
-	prefix string
+	client       sarama.SyncProducer
+	prefix       string
+	payload      payloadMonitor
+	maxChunkSize int
+	serializers  *SerializerRegistry
 }
 
 type ProducerMessage struct {
@@ -57,7 +60,7 @@ func NewProducerFromConfigWithPrefix(brokers []string, config *sarama.Config, pr
 		return nil, err
 	}
 
-	return &Producer{client: p, prefix: prefix}, nil
+	return &Producer{client: p, prefix: prefix, payload: newPayloadMonitor(config, nil)}, nil
 }
 
 func NewProducerFromEnv() (*Producer, error) {
@@ -73,11 +76,42 @@ func NewProducerFromEnvWithPrefix(prefix string) (*Producer, error) {
 	return NewDefaultProducerWithPrefix(conf.Brokers, prefix)
 }
 
+// SetPayloadSoftLimit sets size in bytes, which produced messages should not exceed, e.g. a fraction of broker's
+// message.max.bytes. Messages exceeding the limit are still sent, but counted in oversized produced messages
+// counter and logged as warnings with given logger, if it's not nil. Call before sending any messages.
+func (p *Producer) SetPayloadSoftLimit(limit int, logger *tracing.Logger) {
+	p.payload.softLimit, p.payload.log = limit, logger
+}
+
+// SetChunking enables splitting of message values larger than maxChunkSize bytes into chunks, see ChunkMessage.
+// Consumers of the topic must reassemble chunks with middleware.Reassemble. Call before sending any messages.
+func (p *Producer) SetChunking(maxChunkSize int) {
+	p.maxChunkSize = maxChunkSize
+}
+
+// SetSerializers enables validation of messages to topics registered in r, see SerializerRegistry.Validate.
+// Call before sending any messages.
+func (p *Producer) SetSerializers(r *SerializerRegistry) {
+	p.serializers = r
+}
+
+// SendMessages sends messages with tracing headers. If a message is not valid for its topic serializer,
+// error is returned and none of the messages is sent.
 func (p *Producer) SendMessages(msgs ...ProducerMessage) error {
 	messages := make([]*sarama.ProducerMessage, len(msgs))
 	for i, msg := range msgs {
 		messages[i] = tracing.MessageWithContext(msg.Ctx, msg.Msg)
 	}
+	if err := validateMessages(p.serializers, messages); err != nil {
+		return err
+	}
+	messages, err := chunkMessages(messages, p.maxChunkSize)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		p.payload.observe(msg)
+	}
 	return p.client.SendMessages(messages)
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/kafkatest"
 	"github.com/phanitejak/kptgolib/logging"
 	"github.com/phanitejak/kptgolib/tracing"
 	"github.com/stretchr/testify/assert"
@@ -18,15 +19,17 @@ import (
 func TestIntegrationProducer(t *testing.T) {
 	const (
 		msgCount = 5
-		broker   = "127.0.0.1:9092"
 		topic    = "producer-test"
 		msgValue = "some test data"
 	)
+	broker := kafkatest.StartBroker(t)
+	broker.CreateTopic(t, topic, 1)
+
 	closer, err := tracing.InitGlobalTracer()
 	require.NoError(t, err)
 	defer closer.Close()
 
-	prod, err := kafka.NewDefaultProducer([]string{broker})
+	prod, err := kafka.NewDefaultProducer(broker.Addrs())
 	require.NoError(t, err)
 
 	msgs := make([]kafka.ProducerMessage, msgCount)
@@ -42,7 +45,7 @@ func TestIntegrationProducer(t *testing.T) {
 	err = prod.SendMessages(msgs...)
 	require.NoError(t, err)
 
-	conf := kafka.ConsumerConf{Brokers: []string{broker}, Topics: []string{topic}, Group: "producer-test-group"}
+	conf := kafka.ConsumerConf{Brokers: broker.Addrs(), Topics: []string{topic}, Group: "producer-test-group"}
 	consumer, err := kafka.NewConcurrentPartitionConsumer(conf, tracing.NewLogger(logging.NewLogger()))
 	require.NoError(t, err)
 
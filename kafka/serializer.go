@@ -0,0 +1,235 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of message values set in content-type header by SerializerRegistry.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+var (
+	// ErrNoSerializer is returned when no serializer is registered for the topic of a message.
+	ErrNoSerializer = errors.New("no serializer registered for topic")
+	// ErrContentTypeMismatch is returned when content-type header of a message differs from its topic's serializer.
+	ErrContentTypeMismatch = errors.New("content type does not match topic serializer")
+	// ErrInvalidPayload is returned when message value is not valid for its topic's serializer.
+	ErrInvalidPayload = errors.New("invalid message payload")
+)
+
+// Serializer converts message values of a content type.
+type Serializer interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// Validate returns error if data is not a valid serialized value.
+	Validate(data []byte) error
+}
+
+// JSONSerializer serializes values with encoding/json.
+type JSONSerializer struct{}
+
+// ContentType returns application/json.
+func (JSONSerializer) ContentType() string { return ContentTypeJSON }
+
+// Marshal returns JSON encoding of v.
+func (JSONSerializer) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal parses JSON encoded data into v.
+func (JSONSerializer) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// Validate returns error if data is not valid JSON.
+func (JSONSerializer) Validate(data []byte) error {
+	if !json.Valid(data) {
+		return errors.New("value is not valid json")
+	}
+	return nil
+}
+
+// ProtobufSerializer serializes protocol buffer messages of a single type.
+type ProtobufSerializer struct {
+	prototype proto.Message
+}
+
+// NewProtobufSerializer returns serializer validating values as messages of the type of prototype.
+func NewProtobufSerializer(prototype proto.Message) ProtobufSerializer {
+	return ProtobufSerializer{prototype: prototype}
+}
+
+// ContentType returns application/x-protobuf.
+func (ProtobufSerializer) ContentType() string { return ContentTypeProtobuf }
+
+// Marshal returns wire format encoding of v, which must be a proto.Message.
+func (ProtobufSerializer) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal parses wire format data into v, which must be a proto.Message.
+func (ProtobufSerializer) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// Validate returns error if data can't be parsed as message of the prototype type.
+func (s ProtobufSerializer) Validate(data []byte) error {
+	if s.prototype == nil {
+		return nil
+	}
+	return proto.Unmarshal(data, s.prototype.ProtoReflect().New().Interface())
+}
+
+// SerializerRegistry maps topics to serializers, so that payload formats are enforced consistently per topic.
+// Producers set with SetSerializers validate messages to registered topics and set their content-type header,
+// consumers select the serializer with Unmarshal or reject invalid messages with middleware.ValidatePayload.
+//
+// Avro is not implemented by the package, but a Serializer with ContentTypeAvro backed by a schema registry
+// client can be registered like the others.
+type SerializerRegistry struct {
+	lock        sync.RWMutex
+	serializers map[string]Serializer
+}
+
+// NewSerializerRegistry returns empty registry.
+func NewSerializerRegistry() *SerializerRegistry {
+	return &SerializerRegistry{serializers: map[string]Serializer{}}
+}
+
+// Register sets serializer of topic, replacing existing one.
+func (r *SerializerRegistry) Register(topic string, s Serializer) *SerializerRegistry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.serializers[topic] = s
+	return r
+}
+
+// Serializer returns serializer registered for topic.
+func (r *SerializerRegistry) Serializer(topic string) (Serializer, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	s, ok := r.serializers[topic]
+	return s, ok
+}
+
+// NewMessage returns message to topic with value serialized by the topic's serializer and content-type header set.
+func (r *SerializerRegistry) NewMessage(topic string, value interface{}) (*sarama.ProducerMessage, error) {
+	s, ok := r.Serializer(topic)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrNoSerializer, topic)
+	}
+	data, err := s.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+	msg := &sarama.ProducerMessage{Topic: topic, Value: sarama.ByteEncoder(data)}
+	SetHeader(msg, HeaderContentType, s.ContentType())
+	return msg, nil
+}
+
+// Validate checks message to a registered topic: content-type header must match the serializer and the value
+// must be valid. Missing content-type header is set. Messages to other topics are not checked.
+func (r *SerializerRegistry) Validate(msg *sarama.ProducerMessage) error {
+	s, ok := r.Serializer(msg.Topic)
+	if !ok {
+		return nil
+	}
+
+	contentType, ok := producerHeader(msg, HeaderContentType)
+	if err := checkContentType(s, msg.Topic, contentType, ok); err != nil {
+		return err
+	}
+
+	var data []byte
+	if msg.Value != nil {
+		var err error
+		if data, err = msg.Value.Encode(); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+		}
+	}
+	if err := s.Validate(data); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+
+	if !ok {
+		SetHeader(msg, HeaderContentType, s.ContentType())
+	}
+	return nil
+}
+
+// ValidateConsumed checks consumed message like Validate. Message without content-type header is accepted if
+// its value is valid.
+func (r *SerializerRegistry) ValidateConsumed(msg *sarama.ConsumerMessage) error {
+	s, ok := r.Serializer(msg.Topic)
+	if !ok {
+		return nil
+	}
+	contentType, ok := Header(msg, HeaderContentType)
+	if err := checkContentType(s, msg.Topic, contentType, ok); err != nil {
+		return err
+	}
+	if err := s.Validate(msg.Value); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+	return nil
+}
+
+// Unmarshal parses value of consumed message into v with the serializer of its topic.
+func (r *SerializerRegistry) Unmarshal(msg *sarama.ConsumerMessage, v interface{}) error {
+	s, ok := r.Serializer(msg.Topic)
+	if !ok {
+		return fmt.Errorf("%w %s", ErrNoSerializer, msg.Topic)
+	}
+	contentType, ok := Header(msg, HeaderContentType)
+	if err := checkContentType(s, msg.Topic, contentType, ok); err != nil {
+		return err
+	}
+	if err := s.Unmarshal(msg.Value, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+	}
+	return nil
+}
+
+// checkContentType returns error if content type of a message to topic, when present, differs from serializer.
+func checkContentType(s Serializer, topic, contentType string, present bool) error {
+	if present && contentType != s.ContentType() {
+		return fmt.Errorf("%w: %s is %s, message is %s", ErrContentTypeMismatch, topic, s.ContentType(), contentType)
+	}
+	return nil
+}
+
+// validateMessages validates messages with registry, which may be nil.
+func validateMessages(r *SerializerRegistry, msgs []*sarama.ProducerMessage) error {
+	if r == nil {
+		return nil
+	}
+	for _, msg := range msgs {
+		if err := r.Validate(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func producerHeader(msg *sarama.ProducerMessage, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
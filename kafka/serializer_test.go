@@ -0,0 +1,106 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func newTestRegistry() *kafka.SerializerRegistry {
+	return kafka.NewSerializerRegistry().
+		Register("orders", kafka.JSONSerializer{}).
+		Register("timeouts", kafka.NewProtobufSerializer(&durationpb.Duration{}))
+}
+
+func TestSerializerRegistryRoundTrip(t *testing.T) {
+	r := newTestRegistry()
+
+	msg, err := r.NewMessage("orders", order{ID: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, kafka.ContentTypeJSON, headerValue(msg, kafka.HeaderContentType))
+	require.NoError(t, r.Validate(msg))
+
+	var got order
+	require.NoError(t, r.Unmarshal(toConsumerMessage(t, msg), &got))
+	assert.Equal(t, order{ID: "1"}, got)
+
+	msg, err = r.NewMessage("timeouts", durationpb.New(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, kafka.ContentTypeProtobuf, headerValue(msg, kafka.HeaderContentType))
+
+	var timeout durationpb.Duration
+	require.NoError(t, r.Unmarshal(toConsumerMessage(t, msg), &timeout))
+	assert.True(t, proto.Equal(durationpb.New(time.Second), &timeout))
+
+	_, err = r.NewMessage("unknown", order{})
+	assert.True(t, errors.Is(err, kafka.ErrNoSerializer), err)
+}
+
+func TestSerializerRegistryValidate(t *testing.T) {
+	r := newTestRegistry()
+
+	msg := &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder(`{"id":"1"}`)}
+	require.NoError(t, r.Validate(msg))
+	assert.Equal(t, kafka.ContentTypeJSON, headerValue(msg, kafka.HeaderContentType), "missing content type is set")
+
+	msg = &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder(`not json`)}
+	assert.True(t, errors.Is(r.Validate(msg), kafka.ErrInvalidPayload))
+
+	msg = &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder(`{}`)}
+	kafka.SetHeader(msg, kafka.HeaderContentType, kafka.ContentTypeAvro)
+	assert.True(t, errors.Is(r.Validate(msg), kafka.ErrContentTypeMismatch))
+
+	msg = &sarama.ProducerMessage{Topic: "timeouts", Value: sarama.ByteEncoder{0xff}}
+	assert.True(t, errors.Is(r.Validate(msg), kafka.ErrInvalidPayload))
+
+	msg = &sarama.ProducerMessage{Topic: "other", Value: sarama.StringEncoder(`anything`)}
+	assert.NoError(t, r.Validate(msg))
+	assert.Empty(t, msg.Headers)
+}
+
+func TestAsyncProducerSerializers(t *testing.T) {
+	var sent []string
+	fake := newFakeAsyncProducer(func(msg *sarama.ProducerMessage) error {
+		value, _ := msg.Value.Encode()
+		sent = append(sent, string(value))
+		assert.Equal(t, kafka.ContentTypeJSON, headerValue(msg, kafka.HeaderContentType))
+		return nil
+	})
+	close(fake.release)
+
+	p := kafka.WrapAsyncProducer(tracing.NewLogger(logging.NewLogger()), fake, sarama.NewConfig())
+	p.SetSerializers(newTestRegistry())
+	send := func(value string) kafka.ProducerMessage {
+		return kafka.ProducerMessage{Ctx: context.Background(), Msg: &sarama.ProducerMessage{Topic: "orders", Value: sarama.StringEncoder(value)}}
+	}
+
+	p.SendMessages(send(`{"id":"1"}`), send(`invalid`), send(`{"id":"2"}`))
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, []string{`{"id":"1"}`, `{"id":"2"}`}, sent)
+}
+
+func toConsumerMessage(t *testing.T, msg *sarama.ProducerMessage) *sarama.ConsumerMessage {
+	value, err := msg.Value.Encode()
+	require.NoError(t, err)
+	consumed := &sarama.ConsumerMessage{Topic: msg.Topic, Value: value}
+	for _, h := range msg.Headers {
+		h := h
+		consumed.Headers = append(consumed.Headers, &h)
+	}
+	return consumed
+}
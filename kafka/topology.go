@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// ErrTopologyMismatch is returned by ValidateTopology when topics don't exist or differ from the declaration.
+var ErrTopologyMismatch = errors.New("kafka topology does not match the declaration")
+
+const retentionConfig = "retention.ms"
+
+// Topic declares a topic used by the service. Zero values are not validated.
+type Topic struct {
+	Name string
+	// Partitions is the expected number of partitions, also used when the topic is created.
+	Partitions int32
+	// Retention is the expected retention.ms of the topic, also used when the topic is created.
+	Retention time.Duration
+	// ReplicationFactor is used when the topic is created. Zero uses broker default,
+	// which requires sarama.Config.Version 2.4.0 or newer.
+	ReplicationFactor int16
+}
+
+// Topology declares topics the service consumes and produces to.
+type Topology struct {
+	Consumes []Topic
+	Produces []Topic
+}
+
+// TopologyOpt configures ValidateTopology.
+type TopologyOpt func(*topologyConf) error
+
+type topologyConf struct {
+	createMissing bool
+}
+
+// WithCreateMissingTopics creates declared topics, which don't exist, instead of failing.
+func WithCreateMissingTopics() TopologyOpt {
+	return func(c *topologyConf) error {
+		c.createMissing = true
+		return nil
+	}
+}
+
+// ValidateTopology checks that topics of topology exist with declared partition counts and retention,
+// so that a service can fail fast on startup instead of consumers waiting for topics which never appear.
+// All differences are reported in one error wrapping ErrTopologyMismatch.
+func ValidateTopology(admin sarama.ClusterAdmin, topology Topology, opts ...TopologyOpt) error {
+	c := &topologyConf{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return err
+		}
+	}
+
+	topics, err := topology.topics()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metadata, err := admin.DescribeTopics(names)
+	if err != nil {
+		return fmt.Errorf("failed to describe topics: %w", err)
+	}
+
+	var problems []string
+	for _, m := range metadata {
+		topic := topics[m.Name]
+		switch {
+		case errors.Is(m.Err, sarama.ErrUnknownTopicOrPartition) && c.createMissing:
+			if err := createTopic(admin, topic); err != nil {
+				problems = append(problems, err.Error())
+			}
+		case errors.Is(m.Err, sarama.ErrUnknownTopicOrPartition):
+			problems = append(problems, fmt.Sprintf("topic %s does not exist", m.Name))
+		case m.Err != sarama.ErrNoError:
+			problems = append(problems, fmt.Sprintf("topic %s: %s", m.Name, m.Err))
+		default:
+			problems = append(problems, validateTopic(admin, topic, m)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrTopologyMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateTopologyWithBrokers creates cluster admin for given brokers and validates topology with it.
+func ValidateTopologyWithBrokers(brokers []string, config *sarama.Config, topology Topology, opts ...TopologyOpt) error {
+	admin, err := sarama.NewClusterAdmin(brokers, config)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster admin: %w", err)
+	}
+	defer admin.Close()
+	return ValidateTopology(admin, topology, opts...)
+}
+
+// topics returns declared topics by name. Topic declared more than once must have the same expectations.
+func (t Topology) topics() (map[string]Topic, error) {
+	topics := map[string]Topic{}
+	for _, topic := range append(append([]Topic{}, t.Consumes...), t.Produces...) {
+		if topic.Name == "" {
+			return nil, errors.New("topic name must not be empty")
+		}
+		if declared, ok := topics[topic.Name]; ok && declared != topic {
+			return nil, fmt.Errorf("topic %s is declared with different expectations", topic.Name)
+		}
+		topics[topic.Name] = topic
+	}
+	return topics, nil
+}
+
+func validateTopic(admin sarama.ClusterAdmin, topic Topic, m *sarama.TopicMetadata) (problems []string) {
+	if topic.Partitions > 0 && int32(len(m.Partitions)) != topic.Partitions {
+		problems = append(problems, fmt.Sprintf("topic %s has %d partitions, expected %d", topic.Name, len(m.Partitions), topic.Partitions))
+	}
+	if topic.Retention <= 0 {
+		return problems
+	}
+
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{
+		Type:        sarama.TopicResource,
+		Name:        topic.Name,
+		ConfigNames: []string{retentionConfig},
+	})
+	if err != nil {
+		return append(problems, fmt.Sprintf("failed to describe config of topic %s: %s", topic.Name, err))
+	}
+	expected := strconv.FormatInt(topic.Retention.Milliseconds(), 10)
+	for _, entry := range entries {
+		if entry.Name == retentionConfig && entry.Value != expected {
+			problems = append(problems, fmt.Sprintf("topic %s has %s %s, expected %s", topic.Name, retentionConfig, entry.Value, expected))
+		}
+	}
+	return problems
+}
+
+func createTopic(admin sarama.ClusterAdmin, topic Topic) error {
+	if topic.Partitions <= 0 {
+		return fmt.Errorf("topic %s does not exist and can't be created without partitions", topic.Name)
+	}
+	detail := &sarama.TopicDetail{NumPartitions: topic.Partitions, ReplicationFactor: topic.ReplicationFactor}
+	if detail.ReplicationFactor == 0 {
+		detail.ReplicationFactor = -1
+	}
+	if topic.Retention > 0 {
+		retention := strconv.FormatInt(topic.Retention.Milliseconds(), 10)
+		detail.ConfigEntries = map[string]*string{retentionConfig: &retention}
+	}
+	if err := admin.CreateTopic(topic.Name, detail, false); err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("failed to create topic %s: %s", topic.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package kafka_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAdmin struct {
+	sarama.ClusterAdmin
+	partitions map[string]int
+	retention  map[string]string
+	created    map[string]*sarama.TopicDetail
+}
+
+func (a *fakeAdmin) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	metadata := make([]*sarama.TopicMetadata, 0, len(topics))
+	for _, name := range topics {
+		partitions, ok := a.partitions[name]
+		if !ok {
+			metadata = append(metadata, &sarama.TopicMetadata{Name: name, Err: sarama.ErrUnknownTopicOrPartition})
+			continue
+		}
+		metadata = append(metadata, &sarama.TopicMetadata{Name: name, Partitions: make([]*sarama.PartitionMetadata, partitions)})
+	}
+	return metadata, nil
+}
+
+func (a *fakeAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	return []sarama.ConfigEntry{{Name: "retention.ms", Value: a.retention[resource.Name]}}, nil
+}
+
+func (a *fakeAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, _ bool) error {
+	a.created[topic] = detail
+	return nil
+}
+
+func newFakeAdmin() *fakeAdmin {
+	return &fakeAdmin{
+		partitions: map[string]int{"orders": 6, "events": 3},
+		retention:  map[string]string{"orders": "86400000", "events": "3600000"},
+		created:    map[string]*sarama.TopicDetail{},
+	}
+}
+
+func TestValidateTopology(t *testing.T) {
+	topology := kafka.Topology{
+		Consumes: []kafka.Topic{{Name: "orders", Partitions: 6, Retention: 24 * time.Hour}},
+		Produces: []kafka.Topic{{Name: "events", Partitions: 3}, {Name: "orders", Partitions: 6, Retention: 24 * time.Hour}},
+	}
+	assert.NoError(t, kafka.ValidateTopology(newFakeAdmin(), topology))
+}
+
+func TestValidateTopologyMismatch(t *testing.T) {
+	topology := kafka.Topology{
+		Consumes: []kafka.Topic{{Name: "orders", Partitions: 12, Retention: time.Hour}, {Name: "missing"}},
+	}
+	err := kafka.ValidateTopology(newFakeAdmin(), topology)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, kafka.ErrTopologyMismatch))
+	assert.Contains(t, err.Error(), "topic missing does not exist")
+	assert.Contains(t, err.Error(), "topic orders has 6 partitions, expected 12")
+	assert.Contains(t, err.Error(), "topic orders has retention.ms 86400000, expected 3600000")
+}
+
+func TestValidateTopologyCreateMissing(t *testing.T) {
+	admin := newFakeAdmin()
+	topology := kafka.Topology{
+		Produces: []kafka.Topic{{Name: "audit", Partitions: 2, Retention: time.Hour}},
+	}
+	require.NoError(t, kafka.ValidateTopology(admin, topology, kafka.WithCreateMissingTopics()))
+	require.Contains(t, admin.created, "audit")
+	assert.Equal(t, int32(2), admin.created["audit"].NumPartitions)
+	assert.Equal(t, int16(-1), admin.created["audit"].ReplicationFactor)
+	assert.Equal(t, "3600000", *admin.created["audit"].ConfigEntries["retention.ms"])
+
+	err := kafka.ValidateTopology(admin, kafka.Topology{Produces: []kafka.Topic{{Name: "unsized"}}}, kafka.WithCreateMissingTopics())
+	assert.ErrorContains(t, err, "can't be created without partitions")
+}
+
+func TestValidateTopologyInvalidDeclaration(t *testing.T) {
+	err := kafka.ValidateTopology(newFakeAdmin(), kafka.Topology{
+		Consumes: []kafka.Topic{{Name: "orders", Partitions: 6}},
+		Produces: []kafka.Topic{{Name: "orders", Partitions: 3}},
+	})
+	assert.ErrorContains(t, err, "declared with different expectations")
+}
@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	sequenceName      = "seq"
+	hmacName          = "hmac"
+	missingFieldsName = "missing-fields"
+)
+
+// AuditOpt configures audit logger created with NewAuditLogger.
+type AuditOpt func(*auditConfig)
+
+type auditConfig struct {
+	out            io.Writer
+	sequence       bool
+	hmacKey        []byte
+	requiredFields bool
+}
+
+// WithAuditOutput writes audit records to w instead of stderr, e.g. to a file collected separately from service logs.
+func WithAuditOutput(w io.Writer) AuditOpt {
+	return func(c *auditConfig) {
+		c.out = w
+	}
+}
+
+// WithAuditSequence adds seq field with monotonic sequence number starting from 1 to every record,
+// so that removed records can be detected as gaps in the sequence.
+func WithAuditSequence() AuditOpt {
+	return func(c *auditConfig) {
+		c.sequence = true
+	}
+}
+
+// WithAuditHMACChain adds hmac field to every record. HMAC-SHA256 with given key is calculated over
+// the HMAC of the previous record and the sequence number, facility, user, operation, object, result,
+// eventtype, errorcode and message of the record, so that modified, removed or reordered records can be
+// detected with VerifyAuditChain. Implies WithAuditSequence.
+func WithAuditHMACChain(key []byte) AuditOpt {
+	return func(c *auditConfig) {
+		c.sequence = true
+		c.hmacKey = append([]byte{}, key...)
+	}
+}
+
+// WithAuditRequiredFields marks records missing any of mandatory fields user (actor), operation (action),
+// object (resource) and result (outcome) with missing-fields field listing them. Records are logged anyway,
+// as dropping audit records would be worse than logging incomplete ones.
+func WithAuditRequiredFields() AuditOpt {
+	return func(c *auditConfig) {
+		c.requiredFields = true
+	}
+}
+
+type auditChain struct {
+	lock           sync.Mutex
+	sequence       bool
+	key            []byte
+	requiredFields bool
+	seq            uint64
+	prev           string
+}
+
+// apply adds sequence, HMAC and missing fields to fields of record, must be called with lock held.
+func (c *auditChain) apply(fields map[string]interface{}, record AuditRecord) {
+	if c.requiredFields {
+		if missing := missingAuditFields(record); missing != "" {
+			fields[missingFieldsName] = missing
+		}
+	}
+	if !c.sequence {
+		return
+	}
+	c.seq++
+	fields[sequenceName] = c.seq
+	if c.key != nil {
+		c.prev = auditHMAC(c.key, c.prev, c.seq, fields[facilityName].(string), record)
+		fields[hmacName] = c.prev
+	}
+}
+
+func missingAuditFields(record AuditRecord) string {
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"user", record.User},
+		{"operation", record.Operation},
+		{"object", record.Object},
+		{"result", string(record.Result)},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	return strings.Join(missing, ",")
+}
+
+func auditHMAC(key []byte, prev string, seq uint64, facility string, record AuditRecord) string {
+	// JSON array is used as unambiguous encoding of the covered values.
+	covered, _ := json.Marshal([]string{
+		prev, fmt.Sprint(seq), facility, record.User, record.Operation, record.Object,
+		string(record.Result), string(record.EventType), string(record.ErrorCode), record.Msg,
+	})
+	mac := hmac.New(sha256.New, key)
+	mac.Write(covered)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAuditChain verifies JSON formatted audit records written by logger created with WithAuditHMACChain.
+// Records must start from sequence number 1, e.g. rotated files must be concatenated. Error describing
+// the first broken record is returned, if any record was modified, removed or reordered.
+func VerifyAuditChain(key []byte, r io.Reader) error {
+	var prev string
+	var expected uint64 = 1
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry struct {
+			Seq       uint64  `json:"seq"`
+			HMAC      string  `json:"hmac"`
+			Facility  string  `json:"facility"`
+			User      string  `json:"user"`
+			Operation string  `json:"operation"`
+			Object    string  `json:"object"`
+			Result    Results `json:"result"`
+			EventType string  `json:"eventtype"`
+			ErrorCode string  `json:"errorcode"`
+			Message   string  `json:"message"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("line %d: invalid audit record: %w", line, err)
+		}
+		if entry.Seq != expected {
+			return fmt.Errorf("line %d: sequence number %d, expected %d", line, entry.Seq, expected)
+		}
+		record := AuditRecord{
+			User:      entry.User,
+			Operation: entry.Operation,
+			Object:    entry.Object,
+			Result:    entry.Result,
+			EventType: EventTypes(entry.EventType),
+			ErrorCode: ErrorCodes(entry.ErrorCode),
+			Msg:       entry.Message,
+		}
+		mac := auditHMAC(key, prev, entry.Seq, entry.Facility, record)
+		if !hmac.Equal([]byte(mac), []byte(entry.HMAC)) {
+			return fmt.Errorf("line %d: HMAC of record %d does not match", line, entry.Seq)
+		}
+		prev = mac
+		expected++
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,66 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditSequenceAndRequiredFields(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := logging.NewAuditLogger(logging.WithAuditOutput(out), logging.WithAuditSequence(), logging.WithAuditRequiredFields())
+
+	logger.Audit(logging.AuditRecord{User: "admin", Operation: "delete", Object: "user/123", Result: logging.Success, Msg: "user deleted"})
+	logger.Auth(logging.AuditRecord{User: "admin", Result: logging.Failed, Msg: "login failed"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	var first, second map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	assert.Equal(t, 1.0, first["seq"])
+	assert.NotContains(t, first, "missing-fields")
+	assert.NotContains(t, first, "hmac")
+
+	assert.Equal(t, 2.0, second["seq"])
+	assert.Equal(t, "auth", second["facility"])
+	assert.Equal(t, "operation,object", second["missing-fields"])
+}
+
+func TestAuditHMACChain(t *testing.T) {
+	key := []byte("audit-key")
+	out := &bytes.Buffer{}
+	logger := logging.NewAuditLogger(logging.WithAuditOutput(out), logging.WithAuditHMACChain(key))
+
+	logger.Audit(logging.AuditRecord{User: "admin", Operation: "create", Object: "user/1", Result: logging.Success, Msg: "created"})
+	logger.Audit(logging.AuditRecord{User: "admin", Operation: "update", Object: "user/1", Result: logging.Success, Msg: "updated"})
+	logger.Auth(logging.AuditRecord{User: "guest", Operation: "login", Result: logging.Failed, ErrorCode: logging.E_WRONG_CREDENTIALS, Msg: "denied"})
+
+	records := out.String()
+	require.NoError(t, logging.VerifyAuditChain(key, strings.NewReader(records)))
+	lines := strings.SplitAfter(records, "\n")
+
+	tests := []struct {
+		name    string
+		key     []byte
+		records string
+		wantErr string
+	}{
+		{name: "WrongKey", key: []byte("other"), records: records, wantErr: "line 1: HMAC of record 1 does not match"},
+		{name: "Modified", key: key, records: strings.Replace(records, `"guest"`, `"admin"`, 1), wantErr: "line 3: HMAC of record 3 does not match"},
+		{name: "Removed", key: key, records: lines[0] + lines[2], wantErr: "line 2: sequence number 3, expected 2"},
+		{name: "Reordered", key: key, records: lines[1] + lines[0], wantErr: "line 1: sequence number 2, expected 1"},
+		{name: "Truncated", key: key, records: lines[0] + lines[1][:20], wantErr: "line 2: invalid audit record"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, logging.VerifyAuditChain(tt.key, strings.NewReader(tt.records)), tt.wantErr)
+		})
+	}
+}
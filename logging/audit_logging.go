@@ -158,23 +158,34 @@ type AuditLogger interface {
 
 type auditLogger struct {
 	entry *logrus.Entry
+	chain *auditChain
 }
 
 // Audit logs a message at level Info on the standard logger.
 func (l auditLogger) Audit(args AuditRecord) {
-	auditLog := structs.Map(args)
-	auditLog[facilityName] = auditFacility
-	auditLog[logTypeName] = logType
-	auditLog[timeZoneName] = time.Local.String()
-	l.entry.WithFields(auditLog).Info(args.Msg)
+	l.log(auditFacility, args)
 }
 
 func (l auditLogger) Auth(args AuditRecord) {
-	authLog := structs.Map(args)
-	authLog[facilityName] = authFacility
-	authLog[logTypeName] = logType
-	authLog[timeZoneName] = time.Local.String()
-	l.entry.WithFields(authLog).Info(args.Msg)
+	l.log(authFacility, args)
+}
+
+func (l auditLogger) log(facility string, args AuditRecord) {
+	fields := structs.Map(args)
+	fields[facilityName] = facility
+	fields[logTypeName] = logType
+	fields[timeZoneName] = time.Local.String()
+	if l.chain == nil {
+		l.entry.WithFields(fields).Info(args.Msg)
+		return
+	}
+
+	// Records are written while holding the lock, so that sequence numbers and the HMAC chain
+	// follow the order of records in the output.
+	l.chain.lock.Lock()
+	defer l.chain.lock.Unlock()
+	l.chain.apply(fields, args)
+	l.entry.WithFields(fields).Info(args.Msg)
 }
 
 // NewAuditLogger returns a new Logger logging to stderr.
@@ -199,15 +210,27 @@ func (l auditLogger) Auth(args AuditRecord) {
 //
 // Logger will automatically collect metrics (log event counters) for Prometheus.
 // Metrics will be exposed only if you run metrics.ManagementServer in your application.
-func NewAuditLogger() AuditLogger {
+//
+// Options can be used to write records to a separate stream and to make the stream tamper-evident,
+// see WithAuditOutput, WithAuditSequence, WithAuditHMACChain and WithAuditRequiredFields.
+func NewAuditLogger(opts ...AuditOpt) AuditLogger {
+	c := &auditConfig{out: os.Stderr}
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	_, format, _ := parseConfig()
 	level, _ := logrus.ParseLevel("info")
 	l := &logrus.Logger{
-		Out:       os.Stderr,
+		Out:       c.out,
 		Formatter: format,
 		Hooks:     make(logrus.LevelHooks),
 		Level:     level,
 	}
 	l.Hooks.Add(auditHook)
-	return auditLogger{entry: logrus.NewEntry(l)}
+	logger := auditLogger{entry: logrus.NewEntry(l)}
+	if c.sequence || c.hmacKey != nil || c.requiredFields {
+		logger.chain = &auditChain{sequence: c.sequence, key: c.hmacKey, requiredFields: c.requiredFields}
+	}
+	return logger
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"runtime"
-	"runtime/debug"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -56,18 +55,19 @@ type StdLogger interface {
 }
 
 type logger struct {
-	entry *logrus.Entry
-	depth int
+	entry      *logrus.Entry
+	depth      int
+	stackTrace stackTraceMode
 }
 
 // With adds kv pair to log message.
 func (l logger) With(key string, value interface{}) Logger {
-	return logger{entry: l.entry.WithField(key, value)}
+	return logger{entry: l.entry.WithField(key, value), stackTrace: l.stackTrace}
 }
 
 // WithFields adds map as a kv pairs to log message.
 func (l logger) WithFields(fields map[string]interface{}) Logger {
-	return logger{entry: l.entry.WithFields(fields)}
+	return logger{entry: l.entry.WithFields(fields), stackTrace: l.stackTrace}
 }
 
 // Debug logs a message at level Debug on the standard logger.
@@ -102,17 +102,17 @@ func (l logger) Infof(format string, args ...interface{}) {
 
 // Error logs a message at level Error on the standard logger.
 func (l logger) Error(args ...interface{}) {
-	l.sourced(l.depth).WithField("stack_trace", string(debug.Stack())).Error(args...)
+	l.stackTrace.withStackTrace(l.sourced(l.depth), l.depth+1).Error(args...)
 }
 
 // Errorln logs a message at level Error on the standard logger.
 func (l logger) Errorln(args ...interface{}) {
-	l.sourced(l.depth).WithField("stack_trace", string(debug.Stack())).Errorln(args...)
+	l.stackTrace.withStackTrace(l.sourced(l.depth), l.depth+1).Errorln(args...)
 }
 
 // Errorf logs a message at level Error on the standard logger.
 func (l logger) Errorf(format string, args ...interface{}) {
-	l.sourced(l.depth).WithField("stack_trace", string(debug.Stack())).Errorf(format, args...)
+	l.stackTrace.withStackTrace(l.sourced(l.depth), l.depth+1).Errorf(format, args...)
 }
 
 // Print logs a message at level Debug on the standard logger.
@@ -197,10 +197,24 @@ func PrivacyDataFormatter(sensitiveData string) string {
 // with Neo logging standards, configuration can be changed with
 // environment variables as follows:
 //
-//	Variable            | Values
-//	-----------------------------------------------------------
-//	LOGGING_LEVEL       | 'debug', 'info' (default), 'error'
-//	LOGGING_FORMAT      | 'json' (default), 'txt'
+//	Variable                 | Values
+//	----------------------------------------------------------------
+//	LOGGING_LEVEL            | 'debug', 'info' (default), 'error'
+//	LOGGING_FORMAT           | 'json' (default), 'txt'
+//	LOGGING_STACKTRACE       | 'off', 'short', 'full' (default)
+//	LOGGING_MAX_MESSAGE_SIZE | bytes, 0 (default) is unlimited
+//	LOGGING_MAX_FIELD_SIZE   | bytes, 0 (default) is unlimited
+//	LOGGING_SCHEMA_STRICT    | 'true', 'false' (default)
+//	LOGGING_TXT_FOLD         | 'true', 'false' (default)
+//
+// With LOGGING_TXT_FOLD=true every log event of 'txt' format is printed
+// on a single line without colors and stack trace is folded into a list of frames.
+// With 'short' stack trace only the innermost frames starting from
+// the caller of the logger are included.
+// Messages and field values exceeding max sizes are truncated and
+// the event gets "_truncated":true field, see TruncationHook.
+// In strict schema mode fields of types not allowed by the log schema
+// are removed from the event, see SchemaHook.
 //
 // If invalid configuration is given NewLogger will return Logger
 // with default configuration and handle error by logging it.
@@ -210,6 +224,7 @@ func PrivacyDataFormatter(sensitiveData string) string {
 //	message
 //	logger
 //	level
+//	schema_version
 //	stack_trace (only in 'error' level)
 //
 // # Log metrics
@@ -231,6 +246,26 @@ func NewLogger() Logger {
 	if err != nil {
 		neoLogger.Errorf("Error parsing logger config: %s", err)
 	}
+
+	stackTrace, err := parseStackTraceConfig()
+	if err != nil {
+		neoLogger.Errorf("Error parsing logger config: %s", err)
+	}
+	neoLogger.stackTrace = stackTrace
+
+	truncation, err := NewTruncationHookFromEnv()
+	if err != nil {
+		neoLogger.Errorf("Error parsing logger config: %s", err)
+	}
+	if truncation != nil {
+		l.Hooks.Add(truncation)
+	}
+
+	schema, err := NewSchemaHookFromEnv()
+	if err != nil {
+		neoLogger.Errorf("Error parsing logger config: %s", err)
+	}
+	l.Hooks.Add(schema)
 	return neoLogger
 }
 
@@ -265,6 +300,14 @@ func parseConfig() (logLevel logrus.Level, outputFormat logrus.Formatter, err er
 		}
 	case "txt":
 		outputFormat = &logrus.TextFormatter{}
+		fold, foldErr := parseTxtFoldConfig()
+		if foldErr != nil {
+			err = foldErr
+			return
+		}
+		if fold {
+			outputFormat = foldingFormatter{&logrus.TextFormatter{DisableColors: true}}
+		}
 	default:
 		err = fmt.Errorf("Invalid LOGGING_FORMAT '%s' Please specify LOGGING_FORMAT as 'json' or 'txt'", format)
 		return
@@ -50,8 +50,9 @@ func ExampleNewLogger_withFields() {
 
 func TestDefaultFieldsInInfo(t *testing.T) {
 	expectedLogMessage := map[string]string{
-		"level":   "info",
-		"message": "huhuu",
+		"level":          "info",
+		"message":        "huhuu",
+		"schema_version": "1",
 	}
 
 	logger, logOutput := getLogger(t)
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Matcher reports whether log entry with given level and fields should be written to a route.
+type Matcher func(level string, fields map[string]interface{}) bool
+
+// Route directs log entries matching Match to Out.
+type Route struct {
+	Match Matcher
+	Out   io.Writer
+}
+
+// Router writes each log entry to the first route matching the entry and
+// entries matching no route to the fallback writer.
+type Router struct {
+	mu       sync.Mutex
+	routes   []Route
+	fallback io.Writer
+}
+
+// NewRouter returns Router with given fallback writer and routes, which are matched in the given order.
+// Writers are not closed by the router.
+func NewRouter(fallback io.Writer, routes ...Route) *Router {
+	return &Router{routes: routes, fallback: fallback}
+}
+
+// FieldEquals matches entries having field with given key and value.
+func FieldEquals(key string, value interface{}) Matcher {
+	return func(_ string, fields map[string]interface{}) bool {
+		v, ok := fields[key]
+		return ok && v == value
+	}
+}
+
+// LevelIs matches entries logged with any of given levels, e.g. "error".
+func LevelIs(levels ...string) Matcher {
+	return func(level string, _ map[string]interface{}) bool {
+		for _, l := range levels {
+			if l == level {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (r *Router) write(level string, fields map[string]interface{}, b []byte) error {
+	out := r.fallback
+	for _, route := range r.routes {
+		if route.Match(level, fields) {
+			out = route.Out
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := out.Write(b)
+	return err
+}
+
+// NewRoutedLogger returns a new Logger configured like NewLogger, which writes log entries
+// to writers chosen by given router instead of stderr:
+//
+//	audit, _ := os.OpenFile("audit.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+//	log := logging.NewRoutedLogger(logging.NewRouter(os.Stdout,
+//		logging.Route{Match: logging.FieldEquals("type", "audit"), Out: audit},
+//		logging.Route{Match: logging.LevelIs("error"), Out: os.Stderr},
+//	))
+func NewRoutedLogger(router *Router) Logger {
+	l := NewLogger().(logger)
+	l.entry.Logger.AddHook(&routerHook{router: router, formatter: l.entry.Logger.Formatter})
+	l.entry.Logger.SetFormatter(discardFormatter{})
+	l.entry.Logger.SetOutput(io.Discard)
+	return l
+}
+
+// routerHook formats entries with the formatter of the logger and writes them via router.
+type routerHook struct {
+	router    *Router
+	formatter logrus.Formatter
+}
+
+func (h *routerHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *routerHook) Fire(entry *logrus.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	if err := h.router.write(entry.Level.String(), entry.Data, b); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+	}
+	return nil
+}
+
+// discardFormatter skips formatting entries written to discarded logger output.
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
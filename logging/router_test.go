@@ -0,0 +1,45 @@
+package logging_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/logging/testutil"
+)
+
+func TestRoutedLogger(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+	fallback, audit, errors := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	log := logging.NewRoutedLogger(logging.NewRouter(fallback,
+		logging.Route{Match: logging.FieldEquals("type", "audit"), Out: audit},
+		logging.Route{Match: logging.LevelIs("error"), Out: errors},
+	))
+
+	log.With("type", "audit").Error("audit event")
+	log.Error("failure")
+	log.With("tenant", "a").Info("hello")
+
+	assert.Equal(t, "audit event", testutil.UnmarshalLogMessage(t, audit.Bytes())["message"])
+	assert.Equal(t, "failure", testutil.UnmarshalLogMessage(t, errors.Bytes())["message"])
+	msg := testutil.UnmarshalLogMessage(t, fallback.Bytes())
+	assert.Equal(t, "hello", msg["message"])
+	assert.Equal(t, "a", msg["tenant"])
+	assert.Equal(t, "info", msg["level"])
+}
+
+func TestRoutedLoggerByTenant(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+	fallback, tenantA := &bytes.Buffer{}, &bytes.Buffer{}
+	log := logging.NewRoutedLogger(logging.NewRouter(fallback,
+		logging.Route{Match: logging.FieldEquals("tenant", "a"), Out: tenantA},
+	))
+
+	log.With("tenant", "a").Info("for a")
+	log.With("tenant", "b").Info("for b")
+
+	assert.Equal(t, "for a", testutil.UnmarshalLogMessage(t, tenantA.Bytes())["message"])
+	assert.Equal(t, "for b", testutil.UnmarshalLogMessage(t, fallback.Bytes())["message"])
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SchemaVersionFieldKey is the field containing version of the log schema the event complies with.
+	SchemaVersionFieldKey = "schema_version"
+	// SchemaVersion is the version of the agreed log schema produced by the logger. It is increased when
+	// fields are renamed or change meaning, so that parsing pipelines can handle both versions.
+	SchemaVersion = "1"
+	// SchemaViolationsFieldKey is the field listing comma separated keys of fields rejected in strict schema mode.
+	SchemaViolationsFieldKey = "_schema_violations"
+)
+
+var schemaViolations = metrics.RegisterCounterVec("schema_violations_total", "logger",
+	"Total number of log events with fields rejected by strict schema mode.", "level")
+
+// SchemaHook sets schema_version field of log events. In strict mode it also validates field values against
+// the log schema, which allows only strings, booleans, numbers, errors and times. Fields of other types, e.g.
+// structs, maps and slices, are removed from the event, their keys are listed in "_schema_violations" field and
+// the event is counted by com_metrics_logger_schema_violations_total, so that downstream parsing doesn't break.
+type SchemaHook struct {
+	strict bool
+}
+
+// NewSchemaHook returns hook setting schema version, strict enables validation of field types.
+func NewSchemaHook(strict bool) *SchemaHook {
+	return &SchemaHook{strict: strict}
+}
+
+// NewSchemaHookFromEnv returns hook with strict mode configured by LOGGING_SCHEMA_STRICT environment variable.
+func NewSchemaHookFromEnv() (*SchemaHook, error) {
+	value := os.Getenv("LOGGING_SCHEMA_STRICT")
+	if value == "" {
+		return NewSchemaHook(false), nil
+	}
+	strict, err := strconv.ParseBool(value)
+	if err != nil {
+		return NewSchemaHook(false), fmt.Errorf("Invalid LOGGING_SCHEMA_STRICT '%s', please specify LOGGING_SCHEMA_STRICT as 'true' or 'false'", value)
+	}
+	return NewSchemaHook(strict), nil
+}
+
+// Levels returns all log levels.
+func (h *SchemaHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire sets schema version and removes fields violating the schema in strict mode.
+func (h *SchemaHook) Fire(entry *logrus.Entry) error {
+	if h.strict {
+		var rejected []string
+		for key, value := range entry.Data {
+			if !schemaValue(value) {
+				delete(entry.Data, key)
+				rejected = append(rejected, key)
+			}
+		}
+		if len(rejected) > 0 {
+			sort.Strings(rejected)
+			entry.Data[SchemaViolationsFieldKey] = strings.Join(rejected, ",")
+			schemaViolations.GetCustomCounter(entry.Level.String()).Inc()
+		}
+	}
+	entry.Data[SchemaVersionFieldKey] = SchemaVersion
+	return nil
+}
+
+// schemaValue reports whether value is allowed by the log schema.
+func schemaValue(value interface{}) bool {
+	switch value.(type) {
+	case nil, error, time.Time:
+		return true
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package logging_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictSchema(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+	t.Setenv("LOGGING_SCHEMA_STRICT", "true")
+
+	logger, logOutput := getLogger(t)
+	logger.WithFields(map[string]interface{}{
+		"text":     "abc",
+		"count":    3,
+		"duration": time.Second,
+		"payload":  map[string]string{"a": "b"},
+		"items":    []int{1, 2},
+	}).Info("message")
+
+	var logMessage map[string]interface{}
+	require.NoError(t, json.Unmarshal(logOutput().Bytes(), &logMessage))
+	assert.Equal(t, "abc", logMessage["text"])
+	assert.Equal(t, float64(3), logMessage["count"])
+	assert.Equal(t, float64(time.Second), logMessage["duration"])
+	assert.NotContains(t, logMessage, "payload")
+	assert.NotContains(t, logMessage, "items")
+	assert.Equal(t, "items,payload", logMessage[logging.SchemaViolationsFieldKey])
+	assert.Equal(t, logging.SchemaVersion, logMessage[logging.SchemaVersionFieldKey])
+}
+
+func TestSchemaNotStrict(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+
+	logger, logOutput := getLogger(t)
+	logger.With("payload", map[string]string{"a": "b"}).Info("message")
+
+	var logMessage map[string]interface{}
+	require.NoError(t, json.Unmarshal(logOutput().Bytes(), &logMessage))
+	assert.Equal(t, map[string]interface{}{"a": "b"}, logMessage["payload"])
+	assert.NotContains(t, logMessage, logging.SchemaViolationsFieldKey)
+	assert.Equal(t, logging.SchemaVersion, logMessage[logging.SchemaVersionFieldKey])
+}
+
+func TestSchemaInvalidConfig(t *testing.T) {
+	t.Setenv("LOGGING_SCHEMA_STRICT", "maybe")
+	_, logOutput := getLogger(t)
+	assert.Contains(t, logOutput().String(), "Invalid LOGGING_SCHEMA_STRICT 'maybe'")
+}
+
+func TestSchemaHook(t *testing.T) {
+	hook := logging.NewSchemaHook(true)
+	entry := &logrus.Entry{Level: logrus.ErrorLevel, Data: logrus.Fields{
+		"error": errors.New("failed"),
+		"time":  time.Now(),
+		"nil":   nil,
+		"ok":    true,
+		"user":  struct{ Name string }{"name"},
+	}}
+
+	before := schemaViolations(t)
+	require.NoError(t, hook.Fire(entry))
+
+	assert.Equal(t, logrus.Fields{
+		"error":                          entry.Data["error"],
+		"time":                           entry.Data["time"],
+		"nil":                            nil,
+		"ok":                             true,
+		logging.SchemaViolationsFieldKey: "user",
+		logging.SchemaVersionFieldKey:    logging.SchemaVersion,
+	}, entry.Data)
+	assert.Equal(t, before+1, schemaViolations(t))
+}
+
+func schemaViolations(t *testing.T) float64 {
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+	value, _ := families.Value("com_metrics_logger_schema_violations_total", map[string]string{"level": "error"})
+	return value
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	stackTraceFieldKey = "stack_trace"
+	// shortStackTraceFrames is the maximum number of frames included in short stack trace.
+	shortStackTraceFrames = 10
+)
+
+// stackTraceMode controls how stack trace is added to error level log events.
+type stackTraceMode int
+
+const (
+	stackTraceFull stackTraceMode = iota
+	stackTraceShort
+	stackTraceOff
+)
+
+func parseStackTraceConfig() (stackTraceMode, error) {
+	mode := os.Getenv("LOGGING_STACKTRACE")
+	switch strings.ToLower(mode) {
+	case "full", "": // default
+		return stackTraceFull, nil
+	case "short":
+		return stackTraceShort, nil
+	case "off":
+		return stackTraceOff, nil
+	default:
+		return stackTraceFull, fmt.Errorf("Invalid LOGGING_STACKTRACE '%s', please specify LOGGING_STACKTRACE as 'off', 'short' or 'full'", mode)
+	}
+}
+
+// parseTxtFoldConfig reads LOGGING_TXT_FOLD, which enables folding of txt format.
+func parseTxtFoldConfig() (bool, error) {
+	value := os.Getenv("LOGGING_TXT_FOLD")
+	if value == "" {
+		return false, nil
+	}
+	fold, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid LOGGING_TXT_FOLD '%s', please specify LOGGING_TXT_FOLD as 'true' or 'false'", value)
+	}
+	return fold, nil
+}
+
+// withStackTrace adds stack trace field to entry according to the mode.
+// skip is the number of stack frames to skip, with 0 identifying the caller of withStackTrace.
+func (m stackTraceMode) withStackTrace(entry *logrus.Entry, skip int) *logrus.Entry {
+	switch m {
+	case stackTraceOff:
+		return entry
+	case stackTraceShort:
+		return entry.WithField(stackTraceFieldKey, shortStackTrace(skip+1))
+	default:
+		return entry.WithField(stackTraceFieldKey, string(debug.Stack()))
+	}
+}
+
+// shortStackTrace returns stack trace of limited depth starting from the caller identified by skip,
+// formatted like the output of debug.Stack without goroutine header.
+func shortStackTrace(skip int) string {
+	pcs := make([]uintptr, shortStackTraceFrames)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	b := strings.Builder{}
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s()\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// foldingFormatter prints every log event on a single line by folding the
+// multiline stack trace into a list of frames separated by " <- ".
+type foldingFormatter struct {
+	logrus.Formatter
+}
+
+// Format folds stack trace of the entry and formats it with underlying formatter.
+func (f foldingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	stackTrace, ok := entry.Data[stackTraceFieldKey].(string)
+	if !ok {
+		return f.Formatter.Format(entry)
+	}
+
+	folded := *entry
+	folded.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		folded.Data[k] = v
+	}
+	folded.Data[stackTraceFieldKey] = foldStackTrace(stackTrace)
+	return f.Formatter.Format(&folded)
+}
+
+// foldStackTrace converts stack trace in debug.Stack format into single line.
+func foldStackTrace(stackTrace string) string {
+	var frames []string
+	lines := strings.Split(strings.TrimSpace(stackTrace), "\n")
+	for i := 0; i < len(lines); i++ {
+		function := strings.TrimSpace(lines[i])
+		if function == "" || strings.HasPrefix(function, "goroutine ") {
+			continue
+		}
+
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			i++
+			location := strings.TrimSpace(lines[i])
+			if offset := strings.LastIndex(location, " +0x"); offset != -1 {
+				location = location[:offset]
+			}
+			function = fmt.Sprintf("%s %s", function, location)
+		}
+		frames = append(frames, function)
+	}
+	return strings.Join(frames, " <- ")
+}
@@ -0,0 +1,87 @@
+package logging_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/logging/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStackTraceModes(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+
+	t.Run("Off", func(t *testing.T) {
+		t.Setenv("LOGGING_STACKTRACE", "off")
+		logger, logOutput := getLogger(t)
+		logger.Error("error message")
+		logMessage := testutil.UnmarshalLogMessage(t, logOutput().Bytes())
+
+		assertKeyNotInMap(t, "stack_trace", logMessage)
+		assert.Equal(t, "error message", logMessage["message"])
+	})
+
+	t.Run("Short", func(t *testing.T) {
+		t.Setenv("LOGGING_STACKTRACE", "short")
+		logger, logOutput := getLogger(t)
+		logger.With("key", "value").Errorf("error %s", "message")
+		logMessage := testutil.UnmarshalLogMessage(t, logOutput().Bytes())
+
+		stackTrace := logMessage["stack_trace"]
+		assert.True(t, strings.HasPrefix(stackTrace, "github.com/phanitejak/kptgolib/logging_test.TestStackTraceModes"), stackTrace)
+		assert.Contains(t, stackTrace, "stacktrace_test.go")
+		assert.NotContains(t, stackTrace, "runtime/debug.Stack")
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		t.Setenv("LOGGING_STACKTRACE", "full")
+		logger, logOutput := getLogger(t)
+		logger.Error("error message")
+		logMessage := testutil.UnmarshalLogMessage(t, logOutput().Bytes())
+
+		assert.Contains(t, logMessage["stack_trace"], "runtime/debug.Stack")
+		assert.Contains(t, logMessage["stack_trace"], "stacktrace_test.go")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Setenv("LOGGING_STACKTRACE", "invalid")
+		_, logOutput := getLogger(t)
+		assert.Contains(t, logOutput().String(), "Invalid LOGGING_STACKTRACE 'invalid'")
+	})
+}
+
+func TestTxtFormatFoldsStackTrace(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "txt")
+	t.Setenv("LOGGING_STACKTRACE", "full")
+	t.Setenv("LOGGING_TXT_FOLD", "true")
+
+	logger, logOutput := getLogger(t)
+	logger.Error("multi\nline message")
+	output := logOutput().String()
+
+	assert.Equal(t, 1, strings.Count(output, "\n"), output)
+	assert.Contains(t, output, `msg="multi\nline message"`)
+	assert.Contains(t, output, "stack_trace=")
+	assert.Contains(t, output, " <- github.com/phanitejak/kptgolib/logging.logger.Error")
+	assert.NotContains(t, output, "[running]")
+	assert.NotContains(t, output, "+0x")
+}
+
+func TestTxtFormatNotFoldedByDefault(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "txt")
+	t.Setenv("LOGGING_STACKTRACE", "full")
+
+	logger, logOutput := getLogger(t)
+	logger.Error("error message")
+	output := logOutput().String()
+
+	assert.Contains(t, output, "[running]")
+	assert.NotContains(t, output, " <- ")
+}
+
+func TestTxtFoldInvalidConfig(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "txt")
+	t.Setenv("LOGGING_TXT_FOLD", "maybe")
+	_, logOutput := getLogger(t)
+	assert.Contains(t, logOutput().String(), "Invalid LOGGING_TXT_FOLD 'maybe'")
+}
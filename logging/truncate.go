@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// TruncatedFieldKey is the field set to true in log events which message or fields were truncated.
+const TruncatedFieldKey = "_truncated"
+
+var truncatedEvents = metrics.RegisterCounterVec("truncated_events_total", "logger",
+	"Total number of log messages with truncated message or fields.", "level")
+
+// TruncationHook truncates messages and field values of log events exceeding configured sizes in bytes,
+// so that accidentally logged payloads don't blow up the log pipeline. Truncated events get
+// "_truncated":true field and are counted by com_metrics_logger_truncated_events_total.
+//
+// Strings, byte slices, errors and fmt.Stringer values of fields are truncated, values of other types are
+// logged as is. Values are cut at UTF-8 character boundaries.
+type TruncationHook struct {
+	maxMessageSize int
+	maxFieldSize   int
+}
+
+// NewTruncationHook returns hook truncating messages longer than maxMessageSize and field values longer than
+// maxFieldSize bytes. Zero or negative size disables truncation of messages or fields respectively.
+func NewTruncationHook(maxMessageSize, maxFieldSize int) *TruncationHook {
+	return &TruncationHook{maxMessageSize: maxMessageSize, maxFieldSize: maxFieldSize}
+}
+
+// NewTruncationHookFromEnv returns hook configured with LOGGING_MAX_MESSAGE_SIZE and LOGGING_MAX_FIELD_SIZE
+// environment variables, or nil when neither of them is set.
+func NewTruncationHookFromEnv() (*TruncationHook, error) {
+	maxMessageSize, err := parseSizeConfig("LOGGING_MAX_MESSAGE_SIZE")
+	if err != nil {
+		return nil, err
+	}
+	maxFieldSize, err := parseSizeConfig("LOGGING_MAX_FIELD_SIZE")
+	if err != nil {
+		return nil, err
+	}
+	if maxMessageSize == 0 && maxFieldSize == 0 {
+		return nil, nil
+	}
+	return NewTruncationHook(maxMessageSize, maxFieldSize), nil
+}
+
+// Levels returns all log levels.
+func (h *TruncationHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire truncates message and field values of the entry.
+func (h *TruncationHook) Fire(entry *logrus.Entry) error {
+	truncated := false
+	if h.maxMessageSize > 0 && len(entry.Message) > h.maxMessageSize {
+		entry.Message = truncate(entry.Message, h.maxMessageSize)
+		truncated = true
+	}
+	if h.maxFieldSize > 0 {
+		for key, value := range entry.Data {
+			if s, ok := fieldString(value); ok && len(s) > h.maxFieldSize {
+				entry.Data[key] = truncate(s, h.maxFieldSize)
+				truncated = true
+			}
+		}
+	}
+	if truncated {
+		entry.Data[TruncatedFieldKey] = true
+		truncatedEvents.GetCustomCounter(entry.Level.String()).Inc()
+	}
+	return nil
+}
+
+func fieldString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case error:
+		return v.Error(), true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// truncate cuts s to at most size bytes without splitting multi-byte characters.
+func truncate(s string, size int) string {
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
+
+func parseSizeConfig(env string) (int, error) {
+	value := os.Getenv(env)
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("Invalid %s '%s', please specify %s as a non-negative number of bytes", env, value, env)
+	}
+	return size, nil
+}
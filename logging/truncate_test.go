@@ -0,0 +1,81 @@
+package logging_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncation(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+	t.Setenv("LOGGING_STACKTRACE", "off")
+	t.Setenv("LOGGING_MAX_MESSAGE_SIZE", "10")
+	t.Setenv("LOGGING_MAX_FIELD_SIZE", "5")
+
+	logger, logOutput := getLogger(t)
+	logger.WithFields(map[string]interface{}{
+		"payload": strings.Repeat("x", 100),
+		"err":     errors.New("long error"),
+		"short":   "abc",
+		"count":   123456789,
+	}).Info("message longer than ten bytes")
+
+	var logMessage map[string]interface{}
+	require.NoError(t, json.Unmarshal(logOutput().Bytes(), &logMessage))
+	assert.Equal(t, "message lo", logMessage["message"])
+	assert.Equal(t, "xxxxx", logMessage["payload"])
+	assert.Equal(t, "long ", logMessage["err"])
+	assert.Equal(t, "abc", logMessage["short"])
+	assert.Equal(t, float64(123456789), logMessage["count"])
+	assert.Equal(t, true, logMessage["_truncated"])
+}
+
+func TestTruncationNotNeeded(t *testing.T) {
+	t.Setenv("LOGGING_FORMAT", "json")
+	t.Setenv("LOGGING_MAX_MESSAGE_SIZE", "100")
+
+	logger, logOutput := getLogger(t)
+	logger.With("payload", strings.Repeat("x", 200)).Info("message")
+
+	var logMessage map[string]interface{}
+	require.NoError(t, json.Unmarshal(logOutput().Bytes(), &logMessage))
+	assert.Equal(t, "message", logMessage["message"])
+	assert.Len(t, logMessage["payload"], 200)
+	assert.NotContains(t, logMessage, "_truncated")
+}
+
+func TestTruncationInvalidConfig(t *testing.T) {
+	t.Setenv("LOGGING_MAX_FIELD_SIZE", "-1")
+	_, logOutput := getLogger(t)
+	assert.Contains(t, logOutput().String(), "Invalid LOGGING_MAX_FIELD_SIZE '-1'")
+}
+
+func TestTruncationHook(t *testing.T) {
+	hook := logging.NewTruncationHook(4, 0)
+	entry := &logrus.Entry{Level: logrus.ErrorLevel, Message: "äääää", Data: logrus.Fields{"field": "not truncated"}}
+
+	before := truncatedEvents(t)
+	require.NoError(t, hook.Fire(entry))
+
+	assert.Equal(t, "ää", entry.Message)
+	assert.Equal(t, "not truncated", entry.Data["field"])
+	assert.Equal(t, true, entry.Data[logging.TruncatedFieldKey])
+	assert.Equal(t, before+1, truncatedEvents(t))
+}
+
+func truncatedEvents(t *testing.T) float64 {
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+	value, _ := families.Value("com_metrics_logger_truncated_events_total", map[string]string{"level": "error"})
+	return value
+}
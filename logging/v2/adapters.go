@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+)
+
+// depthIncrementer is implemented by loggers which can skip wrapper stack frames when resolving source of a log event.
+type depthIncrementer interface {
+	IncDepth(depth int) Logger
+}
+
+func adapterLogger(log Logger) Logger {
+	if l, ok := log.(depthIncrementer); ok {
+		return l.IncDepth(1)
+	}
+	return log
+}
+
+// SaramaLogger adapts Logger to sarama.StdLogger and retryablehttp.Logger interfaces.
+// Messages are logged at level Debug with trace context of the context given to NewSaramaLogger.
+//
+//	sarama.Logger = logging.NewSaramaLogger(ctx, log.With("component", "sarama"))
+type SaramaLogger struct {
+	ctx context.Context
+	log Logger
+}
+
+// NewSaramaLogger returns SaramaLogger logging with given logger.
+func NewSaramaLogger(ctx context.Context, log Logger) *SaramaLogger {
+	return &SaramaLogger{ctx: ctx, log: adapterLogger(log)}
+}
+
+// Print logs a message at level Debug.
+func (l *SaramaLogger) Print(v ...interface{}) {
+	l.log.Debug(l.ctx, v...)
+}
+
+// Printf logs a message at level Debug.
+func (l *SaramaLogger) Printf(format string, v ...interface{}) {
+	l.log.Debugf(l.ctx, format, v...)
+}
+
+// Println logs a message at level Debug.
+func (l *SaramaLogger) Println(v ...interface{}) {
+	l.log.Debugln(l.ctx, v...)
+}
+
+// LeveledLogger adapts Logger to retryablehttp.LeveledLogger interface, which is also used by
+// other hashicorp libraries. Key value pairs are added to log event as fields and Warn is logged
+// at level Info, as Logger does not have level Warn.
+//
+//	client := retryablehttp.NewClient()
+//	client.Logger = logging.NewLeveledLogger(ctx, log)
+type LeveledLogger struct {
+	ctx context.Context
+	log Logger
+}
+
+// NewLeveledLogger returns LeveledLogger logging with given logger.
+func NewLeveledLogger(ctx context.Context, log Logger) *LeveledLogger {
+	return &LeveledLogger{ctx: ctx, log: adapterLogger(log)}
+}
+
+// Error logs a message with key value pairs at level Error.
+func (l *LeveledLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Error(l.ctx, msg)
+}
+
+// Warn logs a message with key value pairs at level Info.
+func (l *LeveledLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Info(l.ctx, msg)
+}
+
+// Info logs a message with key value pairs at level Info.
+func (l *LeveledLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Info(l.ctx, msg)
+}
+
+// Debug logs a message with key value pairs at level Debug.
+func (l *LeveledLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Debug(l.ctx, msg)
+}
+
+func (l *LeveledLogger) with(keysAndValues []interface{}) Logger {
+	if len(keysAndValues) == 0 {
+		return l.log
+	}
+
+	fields := make(map[string]interface{}, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 == len(keysAndValues) {
+			fields[key] = nil
+			break
+		}
+		fields[key] = keysAndValues[i+1]
+	}
+	return adapterLogger(l.log.WithFields(fields))
+}
@@ -0,0 +1,66 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/v2"
+	"github.com/phanitejak/kptgolib/logging/v2/testutil"
+)
+
+var (
+	_ sarama.StdLogger            = &logging.SaramaLogger{}
+	_ retryablehttp.Logger        = &logging.SaramaLogger{}
+	_ retryablehttp.LeveledLogger = &logging.LeveledLogger{}
+)
+
+func TestSaramaLogger(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "debug")
+	logger, logOutput := getLogger(t)
+
+	l := logging.NewSaramaLogger(context.Background(), logger.With("component", "sarama"))
+	l.Printf("connected to broker %s", "kafka:9092")
+
+	lines := bytes.Split(bytes.TrimSpace(logOutput().Bytes()), []byte("\n"))
+	require.Len(t, lines, 1)
+	msg := testutil.UnmarshalLogMessage(t, lines[0])
+	assert.Equal(t, "connected to broker kafka:9092", msg["message"])
+	assert.Equal(t, "debug", msg["level"])
+	assert.Equal(t, "sarama", msg["component"])
+	assert.Regexp(t, `^adapters_test.go:\d+$`, msg["logger"])
+}
+
+func TestLeveledLogger(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "debug")
+	logger, logOutput := getLogger(t)
+
+	l := logging.NewLeveledLogger(context.Background(), logger)
+	l.Debug("performing request", "method", "GET", "url", "http://vault:8200")
+	l.Warn("retrying request", "attempt", "2")
+	l.Error("request failed", "dangling")
+
+	lines := bytes.Split(bytes.TrimSpace(logOutput().Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	debug := testutil.UnmarshalLogMessage(t, lines[0])
+	assert.Equal(t, "performing request", debug["message"])
+	assert.Equal(t, "debug", debug["level"])
+	assert.Equal(t, "GET", debug["method"])
+	assert.Equal(t, "http://vault:8200", debug["url"])
+	assert.Regexp(t, `^adapters_test.go:\d+$`, debug["logger"])
+
+	warn := testutil.UnmarshalLogMessage(t, lines[1])
+	assert.Equal(t, "info", warn["level"])
+	assert.Equal(t, "2", warn["attempt"])
+
+	errorMsg := testutil.UnmarshalLogMessage(t, lines[2])
+	assert.Equal(t, "error", errorMsg["level"])
+	assert.Contains(t, errorMsg, "dangling")
+	assert.Regexp(t, `^adapters_test.go:\d+$`, errorMsg["logger"])
+}
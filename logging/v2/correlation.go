@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	// CorrelationIDHeader is HTTP header carrying correlation ID of the request.
+	CorrelationIDHeader = "X-Request-ID"
+	// CorrelationIDKey is the log field and tracing baggage key of correlation ID.
+	CorrelationIDKey = "correlation_id"
+)
+
+// validCorrelationID limits accepted incoming IDs, so that arbitrary client input doesn't end up in logs.
+var validCorrelationID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type correlationIDCtxKey struct{}
+
+// ContextWithCorrelationID returns copy of ctx with given correlation ID.
+// Logger adds correlation_id field to every log event logged with such context.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDCtxKey{}, id)
+}
+
+// CorrelationID returns correlation ID stored in ctx or empty string if there is none.
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDCtxKey{}).(string)
+	return id
+}
+
+// NewCorrelationID generates a new random correlation ID.
+func NewCorrelationID() string {
+	return uuid.New().String()
+}
+
+// CorrelationIDMiddleware ensures every request has correlation ID. ID is taken from X-Request-ID header
+// or generated if the header is missing or invalid. ID is stored in request context for logging,
+// added to tracing baggage, so it's propagated to downstream services, and set to the response header.
+// Logs can be correlated by the ID even when trace is not sampled.
+func CorrelationIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationIDHeader)
+		if !validCorrelationID.MatchString(id) {
+			id = NewCorrelationID()
+		}
+
+		ctx := ContextWithCorrelationID(r.Context(), id)
+		if member, err := baggage.NewMember(CorrelationIDKey, id); err == nil {
+			if b, err := baggage.FromContext(ctx).SetMember(member); err == nil {
+				ctx = baggage.ContextWithBaggage(ctx, b)
+			}
+		}
+
+		w.Header().Set(CorrelationIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
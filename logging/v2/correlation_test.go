@@ -0,0 +1,62 @@
+package logging_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/phanitejak/kptgolib/logging/v2"
+	"github.com/phanitejak/kptgolib/logging/v2/testutil"
+)
+
+func TestCorrelationIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "FromHeader", header: "req-123", expected: "req-123"},
+		{name: "Generated"},
+		{name: "InvalidHeader", header: "bad id\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID, baggageID string
+			handler := logging.CorrelationIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = logging.CorrelationID(r.Context())
+				baggageID = baggage.FromContext(r.Context()).Member(logging.CorrelationIDKey).Value()
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.CorrelationIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, ctxID)
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, ctxID)
+			} else {
+				assert.NotEqual(t, tt.header, ctxID)
+			}
+			assert.Equal(t, ctxID, baggageID)
+			assert.Equal(t, ctxID, rec.Header().Get(logging.CorrelationIDHeader))
+		})
+	}
+}
+
+func TestLoggingWithCorrelationID(t *testing.T) {
+	logger, logOutput := getLogger(t)
+
+	logger.Info(logging.ContextWithCorrelationID(context.Background(), "req-123"), "Test")
+
+	msg := testutil.UnmarshalLogMessage(t, logOutput().Bytes())
+	assert.Equal(t, "req-123", msg[logging.CorrelationIDKey])
+}
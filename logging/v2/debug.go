@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	// DebugTokenHeader is HTTP header carrying debug token, which enables debug logging of the request.
+	DebugTokenHeader = "X-Debug-Token"
+	// DebugTokenKey is the tracing baggage key of debug token, which propagates it to downstream services.
+	DebugTokenKey = "debug_token"
+)
+
+var (
+	// ErrInvalidDebugToken is returned by VerifyDebugToken for malformed tokens and tokens with invalid signature.
+	ErrInvalidDebugToken = errors.New("invalid debug token")
+	// ErrExpiredDebugToken is returned by VerifyDebugToken for expired tokens.
+	ErrExpiredDebugToken = errors.New("debug token expired")
+)
+
+type debugCtxKey struct{}
+
+// debugLevelKey is the field carrying level of debug events forwarded by debugForwardHook at enabled level.
+const debugLevelKey = "_debug_level"
+
+// debugLoggers caches debug loggers forwarding to loggers, so elevating a request doesn't allocate a logger.
+var debugLoggers sync.Map
+
+// ContextWithDebug returns copy of ctx, in which events are logged at debug level regardless of LOGGING_LEVEL.
+func ContextWithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugCtxKey{}, true)
+}
+
+// DebugEnabled reports whether debug logging is enabled in ctx by ContextWithDebug.
+func DebugEnabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(debugCtxKey{}).(bool)
+	return enabled
+}
+
+// NewDebugToken returns debug token signed with secret, which is valid until expiresAt.
+// Token has form "<expiry unix seconds>.<base64url HMAC-SHA256 of the expiry>".
+func NewDebugToken(secret []byte, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + signDebugToken(secret, expiry)
+}
+
+// VerifyDebugToken checks signature and expiry of token created by NewDebugToken.
+func VerifyDebugToken(secret []byte, token string, now time.Time) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok || len(secret) == 0 {
+		return ErrInvalidDebugToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(signDebugToken(secret, expiry))) {
+		return ErrInvalidDebugToken
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return ErrInvalidDebugToken
+	}
+	if now.Unix() > expiresAt {
+		return ErrExpiredDebugToken
+	}
+	return nil
+}
+
+func signDebugToken(secret []byte, expiry string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// DebugMiddleware enables debug logging for requests with a valid debug token signed with secret, so that verbose
+// logs of a single request can be collected without changing LOGGING_LEVEL. Token is taken from X-Debug-Token header
+// or debug_token tracing baggage entry and is added to the baggage, so that downstream services sharing the secret
+// log the request at debug level too. Requests with invalid or expired token are served with the configured level.
+// Middleware does nothing when secret is empty.
+func DebugMiddleware(next http.Handler, secret []byte) http.Handler {
+	if len(secret) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		token := r.Header.Get(DebugTokenHeader)
+		if token == "" {
+			token = baggage.FromContext(ctx).Member(DebugTokenKey).Value()
+		}
+		if token == "" || VerifyDebugToken(secret, token, time.Now()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx = ContextWithDebug(ctx)
+		if member, err := baggage.NewMember(DebugTokenKey, token); err == nil {
+			if b, err := baggage.FromContext(ctx).SetMember(member); err == nil {
+				ctx = baggage.ContextWithBaggage(ctx, b)
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// debugEntry returns entry logging every level through a debug logger, which forwards events to the original
+// logger. Output, formatter, hooks and level of the original logger are used at the time of logging.
+func debugEntry(e *logrus.Entry) *logrus.Entry {
+	if e.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return e
+	}
+	debugLogger, ok := debugLoggers.Load(e.Logger)
+	if !ok {
+		hooks := make(logrus.LevelHooks)
+		hooks.Add(debugForwardHook{original: e.Logger})
+		debugLogger, _ = debugLoggers.LoadOrStore(e.Logger, &logrus.Logger{
+			Out: io.Discard, Formatter: discardFormatter{}, Hooks: hooks, Level: logrus.TraceLevel,
+		})
+	}
+	elevated := e.Dup()
+	elevated.Logger = debugLogger.(*logrus.Logger)
+	return elevated
+}
+
+// debugForwardHook logs events of debug logger with the original logger. Events at levels disabled in the original
+// logger are logged at its level and debugLevelHook restores their level before other hooks and formatting.
+type debugForwardHook struct {
+	original *logrus.Logger
+}
+
+func (h debugForwardHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h debugForwardHook) Fire(e *logrus.Entry) error {
+	entry := h.original.WithFields(e.Data).WithTime(e.Time)
+	if e.Context != nil {
+		entry = entry.WithContext(e.Context)
+	}
+	level := e.Level
+	if !h.original.IsLevelEnabled(level) {
+		entry = entry.WithField(debugLevelKey, level)
+		level = h.original.GetLevel()
+	}
+	entry.Log(level, e.Message)
+	return nil
+}
+
+// debugLevelHook restores level of events forwarded by debugForwardHook. It must be the first hook of the logger.
+type debugLevelHook struct{}
+
+func (debugLevelHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (debugLevelHook) Fire(e *logrus.Entry) error {
+	if level, ok := e.Data[debugLevelKey].(logrus.Level); ok {
+		delete(e.Data, debugLevelKey)
+		e.Level = level
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/phanitejak/kptgolib/logging/v2"
+)
+
+var debugSecret = []byte("secret")
+
+func TestDebugToken(t *testing.T) {
+	now := time.Now()
+	token := logging.NewDebugToken(debugSecret, now.Add(time.Minute))
+
+	assert.NoError(t, logging.VerifyDebugToken(debugSecret, token, now))
+	assert.ErrorIs(t, logging.VerifyDebugToken(debugSecret, token, now.Add(2*time.Minute)), logging.ErrExpiredDebugToken)
+	assert.ErrorIs(t, logging.VerifyDebugToken([]byte("other"), token, now), logging.ErrInvalidDebugToken)
+	assert.ErrorIs(t, logging.VerifyDebugToken(nil, token, now), logging.ErrInvalidDebugToken)
+	assert.ErrorIs(t, logging.VerifyDebugToken(debugSecret, "garbage", now), logging.ErrInvalidDebugToken)
+
+	forged := logging.NewDebugToken(debugSecret, now.Add(time.Minute))
+	forged = "9" + forged
+	assert.ErrorIs(t, logging.VerifyDebugToken(debugSecret, forged, now), logging.ErrInvalidDebugToken)
+}
+
+func TestDebugMiddleware(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "info")
+	valid := logging.NewDebugToken(debugSecret, time.Now().Add(time.Minute))
+	expired := logging.NewDebugToken(debugSecret, time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name          string
+		header        string
+		baggage       string
+		expectedDebug bool
+	}{
+		{name: "NoToken"},
+		{name: "Header", header: valid, expectedDebug: true},
+		{name: "Baggage", baggage: valid, expectedDebug: true},
+		{name: "Expired", header: expired},
+		{name: "Invalid", header: "1.invalid"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			log := logging.NewLogger(logging.WithOutput(buf))
+			var propagated string
+			handler := logging.DebugMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Debug(r.Context(), "verbose")
+				log.Info(r.Context(), "info")
+				propagated = baggage.FromContext(r.Context()).Member(logging.DebugTokenKey).Value()
+			}), debugSecret)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.DebugTokenHeader, tt.header)
+			}
+			if tt.baggage != "" {
+				member, err := baggage.NewMember(logging.DebugTokenKey, tt.baggage)
+				require.NoError(t, err)
+				b, err := baggage.New(member)
+				require.NoError(t, err)
+				req = req.WithContext(baggage.ContextWithBaggage(req.Context(), b))
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			messages := logLines(t, buf)
+			if !tt.expectedDebug {
+				require.Len(t, messages, 1)
+				assert.Equal(t, "info", messages[0]["message"])
+				return
+			}
+			require.Len(t, messages, 2)
+			assert.Equal(t, "verbose", messages[0]["message"])
+			assert.Equal(t, "debug", messages[0]["level"])
+			assert.Equal(t, valid, propagated)
+		})
+	}
+}
+
+func TestDebugEnabledContext(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "error")
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf))
+	ctx := logging.ContextWithDebug(context.Background())
+	require.True(t, logging.DebugEnabled(ctx))
+
+	log.Debug(context.Background(), "dropped")
+	log.With("key", "value").Debug(ctx, "logged")
+	log.Info(ctx, "logged too")
+
+	messages := logLines(t, buf)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "logged", messages[0]["message"])
+	assert.Equal(t, "debug", messages[0]["level"])
+	assert.Equal(t, "value", messages[0]["key"])
+	assert.NotContains(t, messages[0], "_debug_level")
+	assert.Equal(t, "logged too", messages[1]["message"])
+	assert.False(t, logging.DebugEnabled(context.Background()))
+}
+
+func TestDebugEnabledContextSharesOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf))
+	ctx := logging.ContextWithDebug(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			log.Debug(ctx, "debug")
+		}()
+		go func() {
+			defer wg.Done()
+			log.Info(context.Background(), "info")
+		}()
+	}
+	wg.Wait()
+
+	levels := map[interface{}]int{}
+	for _, message := range logLines(t, buf) {
+		levels[message["level"]]++
+	}
+	assert.Equal(t, map[interface{}]int{"debug": 10, "info": 10}, levels)
+}
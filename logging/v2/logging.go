@@ -195,20 +195,34 @@ func PrivacyDataFormatter(sensitiveData string) string {
 //	message
 //	logger
 //	level
+//	schema_version
 //	stack_trace (only in 'error' level)
 //
 // # Log metrics
 //
 // Logger will automatically collect metrics (log event counters) for Prometheus.
 // Metrics will be exposed only if you run metrics.ManagementServer in your application.
-func NewLogger() Logger {
+//
+// Options can be used to change output and time source, e.g. to get deterministic output in tests:
+//
+//	log := logging.NewLogger(logging.WithOutput(&buf), logging.WithClock(func() time.Time { return fixed }))
+func NewLogger(opts ...Opt) Logger {
+	o := options{out: os.Stderr}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	level, format, err := parseConfig()
 	l := &logrus.Logger{
-		Out:       os.Stderr,
+		Out:       o.out,
 		Formatter: format,
 		Hooks:     make(logrus.LevelHooks),
 		Level:     level,
 	}
+	l.Hooks.Add(debugLevelHook{})
+	if o.clock != nil {
+		l.Hooks.Add(clockHook{clock: o.clock})
+	}
 	l.Hooks.Add(logging.GetMetricsHook())
 	neoLogger := logger{entry: logrus.NewEntry(l)}
 
@@ -216,6 +230,26 @@ func NewLogger() Logger {
 	if err != nil {
 		neoLogger.Errorf(context.Background(), "Error parsing logger config: %s", err)
 	}
+
+	truncation, err := logging.NewTruncationHookFromEnv()
+	if err != nil {
+		neoLogger.Errorf(context.Background(), "Error parsing logger config: %s", err)
+	}
+	if o.truncation != nil {
+		truncation = o.truncation
+	}
+	if truncation != nil {
+		l.Hooks.Add(truncation)
+	}
+
+	schema, err := logging.NewSchemaHookFromEnv()
+	if err != nil {
+		neoLogger.Errorf(context.Background(), "Error parsing logger config: %s", err)
+	}
+	if o.strictSchema {
+		schema = logging.NewSchemaHook(true)
+	}
+	l.Hooks.Add(schema)
 	return neoLogger
 }
 
@@ -258,6 +292,20 @@ func parseConfig() (logLevel logrus.Level, outputFormat logrus.Formatter, err er
 }
 
 func (l logger) with(context context.Context, isError bool) logger {
+	if id := CorrelationID(context); id != "" {
+		l.entry = l.entry.WithField(CorrelationIDKey, id)
+	}
+	if DebugEnabled(context) {
+		l.entry = debugEntry(l.entry)
+	}
+	if b := tailBufferFrom(context); b != nil {
+		if isError {
+			b.flush()
+		} else {
+			l.entry = b.entry(l.entry)
+		}
+	}
+
 	span := opentracing.SpanFromContext(context)
 	if span == nil {
 		return l
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/phanitejak/kptgolib/logging/v2"
+	"github.com/phanitejak/kptgolib/logging/v2/loggingtest"
 	"github.com/phanitejak/kptgolib/logging/v2/testutil"
 	"github.com/phanitejak/kptgolib/metrics"
 	"github.com/phanitejak/kptgolib/testutils"
@@ -73,8 +74,9 @@ func ExampleNewLogger_withCustomInterfaceFields() {
 
 func TestDefaultFieldsInInfo(t *testing.T) {
 	expectedLogMessage := map[string]string{
-		"level":   "info",
-		"message": "huhuu",
+		"level":          "info",
+		"message":        "huhuu",
+		"schema_version": "1",
 	}
 
 	logger, logOutput := getLogger(t)
@@ -312,6 +314,49 @@ func getLogger(t *testing.T) (logging.Logger, func() *bytes.Buffer) {
 	return logger, logOutput
 }
 
+func TestWithClockAndOutput(t *testing.T) {
+	clock := loggingtest.NewClock(time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC))
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithClock(clock.Now), logging.WithOutput(buf))
+
+	log.Info(context.Background(), "first")
+	clock.Advance(time.Minute)
+	log.With("key", "value").Error(context.Background(), "second")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.Equal(t, "2020-01-02T03:04:05.006Z", testutil.UnmarshalLogMessage(t, lines[0])["timestamp"])
+	assert.Equal(t, "2020-01-02T03:05:05.006Z", testutil.UnmarshalLogMessage(t, lines[1])["timestamp"])
+}
+
+func TestWithTruncation(t *testing.T) {
+	t.Setenv("LOGGING_MAX_MESSAGE_SIZE", "1000")
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf), logging.WithTruncation(5, 3))
+
+	log.With("payload", "abcdef").Info(context.Background(), "long message")
+
+	var logMessage map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logMessage))
+	assert.Equal(t, "long ", logMessage["message"])
+	assert.Equal(t, "abc", logMessage["payload"])
+	assert.Equal(t, true, logMessage["_truncated"])
+}
+
+func TestWithStrictSchema(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf), logging.WithStrictSchema())
+
+	log.With("payload", []string{"a"}).With("id", "abc").Info(context.Background(), "message")
+
+	var logMessage map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logMessage))
+	assert.NotContains(t, logMessage, "payload")
+	assert.Equal(t, "abc", logMessage["id"])
+	assert.Equal(t, "payload", logMessage["_schema_violations"])
+	assert.Equal(t, "1", logMessage["schema_version"])
+}
+
 // --- Traceable logging tests ---
 
 func TestLoggingForBackgroundContextShouldWork(t *testing.T) {
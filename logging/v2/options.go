@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"io"
+	"time"
+
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Opt configures Logger created by NewLogger.
+type Opt func(*options)
+
+type options struct {
+	clock        func() time.Time
+	out          io.Writer
+	truncation   *logging.TruncationHook
+	strictSchema bool
+}
+
+// WithClock sets time source of log event timestamps, e.g. a fixed time for golden-file tests
+// of log output or loggingtest.Clock for testing time dependent behavior without sleeping.
+func WithClock(clock func() time.Time) Opt {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithOutput sets writer of log events instead of stderr.
+func WithOutput(out io.Writer) Opt {
+	return func(o *options) {
+		o.out = out
+	}
+}
+
+// WithTruncation truncates messages longer than maxMessageSize and field values longer than maxFieldSize bytes,
+// overriding LOGGING_MAX_MESSAGE_SIZE and LOGGING_MAX_FIELD_SIZE. Zero disables truncation of messages or fields.
+func WithTruncation(maxMessageSize, maxFieldSize int) Opt {
+	return func(o *options) {
+		o.truncation = logging.NewTruncationHook(maxMessageSize, maxFieldSize)
+	}
+}
+
+// WithStrictSchema removes fields of types not allowed by the log schema from log events like
+// LOGGING_SCHEMA_STRICT=true, see logging.SchemaHook.
+func WithStrictSchema() Opt {
+	return func(o *options) {
+		o.strictSchema = true
+	}
+}
+
+// clockHook sets event time from the clock before the event is formatted.
+type clockHook struct {
+	clock func() time.Time
+}
+
+func (h clockHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h clockHook) Fire(e *logrus.Entry) error {
+	e.Time = h.clock()
+	return nil
+}
@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TailSampledKey is the log field added to events, which were kept by tail sampling and logged on error.
+const TailSampledKey = "tail_sampled"
+
+type tailSamplingCtxKey struct{}
+
+// tailBuffer keeps the newest log events below logger level of one scope, e.g. a request.
+type tailBuffer struct {
+	mu      sync.Mutex
+	size    int
+	events  []tailEvent
+	next    int
+	loggers map[*logrus.Logger]*tailLoggers
+}
+
+type tailEvent struct {
+	flush *logrus.Logger
+	entry *logrus.Entry
+}
+
+// tailLoggers are derived from a logger: capture logs every event into the buffer hook
+// and flush writes buffered events to the output of the logger regardless of level.
+type tailLoggers struct {
+	capture *logrus.Logger
+	flush   *logrus.Logger
+}
+
+// ContextWithTailSampling returns copy of ctx, in which log events below logger level, e.g. debug events
+// when LOGGING_LEVEL is info, are not dropped but kept in memory. Up to size newest such events are logged,
+// marked with tail_sampled field, before the next error logged with the context or when FlushTailSampled is called.
+// This gives context of errors without paying for always-on debug logging.
+func ContextWithTailSampling(ctx context.Context, size int) context.Context {
+	if size <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, tailSamplingCtxKey{}, &tailBuffer{size: size, loggers: map[*logrus.Logger]*tailLoggers{}})
+}
+
+// FlushTailSampled logs events kept by tail sampling in ctx, e.g. when a request fails without logging an error.
+func FlushTailSampled(ctx context.Context) {
+	if b := tailBufferFrom(ctx); b != nil {
+		b.flush()
+	}
+}
+
+// TailSamplingMiddleware enables tail sampling of up to size log events for every request, see
+// ContextWithTailSampling. Kept events are also logged when the response status is 5xx.
+func TailSamplingMiddleware(next http.Handler, size int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ContextWithTailSampling(r.Context(), size)
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		if sw.status >= http.StatusInternalServerError {
+			FlushTailSampled(ctx)
+		}
+	})
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func tailBufferFrom(ctx context.Context) *tailBuffer {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(tailSamplingCtxKey{}).(*tailBuffer)
+	return b
+}
+
+// entry returns entry logging through capture logger, if some levels of entry's logger are disabled.
+func (b *tailBuffer) entry(e *logrus.Entry) *logrus.Entry {
+	if e.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return e
+	}
+	b.mu.Lock()
+	loggers, ok := b.loggers[e.Logger]
+	if !ok {
+		loggers = b.newLoggers(e.Logger)
+		b.loggers[e.Logger] = loggers
+	}
+	b.mu.Unlock()
+
+	captured := e.Dup()
+	captured.Logger = loggers.capture
+	return captured
+}
+
+func (b *tailBuffer) newLoggers(original *logrus.Logger) *tailLoggers {
+	captureHooks := make(logrus.LevelHooks)
+	flushHooks := make(logrus.LevelHooks)
+	for level, hooks := range original.Hooks {
+		for _, hook := range hooks {
+			// Time is set when the event is captured and kept when it is flushed.
+			if _, ok := hook.(clockHook); ok {
+				captureHooks[level] = append(captureHooks[level], hook)
+			} else {
+				flushHooks[level] = append(flushHooks[level], hook)
+			}
+		}
+	}
+	flush := &logrus.Logger{Out: original.Out, Formatter: original.Formatter, Hooks: flushHooks, Level: logrus.TraceLevel}
+	captureHooks.Add(tailCaptureHook{buffer: b, original: original, flush: flush})
+	capture := &logrus.Logger{Out: io.Discard, Formatter: discardFormatter{}, Hooks: captureHooks, Level: logrus.TraceLevel}
+	return &tailLoggers{capture: capture, flush: flush}
+}
+
+func (b *tailBuffer) add(flush *logrus.Logger, e *logrus.Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.events) < b.size {
+		b.events = append(b.events, tailEvent{flush: flush, entry: e})
+		return
+	}
+	b.events[b.next] = tailEvent{flush: flush, entry: e}
+	b.next = (b.next + 1) % b.size
+}
+
+func (b *tailBuffer) flush() {
+	b.mu.Lock()
+	events := make([]tailEvent, 0, len(b.events))
+	events = append(events, b.events[b.next:]...)
+	events = append(events, b.events[:b.next]...)
+	b.events = nil
+	b.next = 0
+	b.mu.Unlock()
+
+	for _, event := range events {
+		event.flush.WithFields(event.entry.Data).WithField(TailSampledKey, "true").
+			WithTime(event.entry.Time).WithContext(event.entry.Context).Log(event.entry.Level, event.entry.Message)
+	}
+}
+
+// tailCaptureHook logs events at enabled levels to the original logger and keeps the others in the buffer.
+type tailCaptureHook struct {
+	buffer   *tailBuffer
+	original *logrus.Logger
+	flush    *logrus.Logger
+}
+
+func (h tailCaptureHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h tailCaptureHook) Fire(e *logrus.Entry) error {
+	if !h.original.IsLevelEnabled(e.Level) {
+		// Dup doesn't copy level and message.
+		kept := e.Dup()
+		kept.Level, kept.Message = e.Level, e.Message
+		h.buffer.add(h.flush, kept)
+		return nil
+	}
+	h.original.WithFields(e.Data).WithTime(e.Time).WithContext(e.Context).Log(e.Level, e.Message)
+	return nil
+}
+
+// discardFormatter formats nothing, as capture logger doesn't write events itself.
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,96 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/v2"
+	"github.com/phanitejak/kptgolib/logging/v2/loggingtest"
+	"github.com/phanitejak/kptgolib/logging/v2/testutil"
+)
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]string {
+	var messages []map[string]string
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if len(line) > 0 {
+			messages = append(messages, testutil.UnmarshalLogMessage(t, line))
+		}
+	}
+	return messages
+}
+
+func TestTailSampling(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "info")
+	clock := loggingtest.NewClock(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf), logging.WithClock(clock.Now))
+	ctx := logging.ContextWithTailSampling(context.Background(), 2)
+
+	log.Debug(ctx, "dropped")
+	clock.Advance(time.Second)
+	log.Debugf(ctx, "kept %d", 1)
+	log.Info(ctx, "logged")
+	clock.Advance(time.Second)
+	log.With("key", "value").Debug(ctx, "kept 2")
+	require.Len(t, logLines(t, buf), 1, "debug events should be kept until error")
+
+	clock.Advance(time.Second)
+	log.Error(ctx, "failed")
+	log.Error(ctx, "failed again")
+
+	messages := logLines(t, buf)
+	require.Len(t, messages, 5)
+	assert.Equal(t, "logged", messages[0]["message"])
+	assert.Equal(t, "", messages[0][logging.TailSampledKey])
+
+	assert.Equal(t, "kept 1", messages[1]["message"])
+	assert.Equal(t, "debug", messages[1]["level"])
+	assert.Equal(t, "true", messages[1][logging.TailSampledKey])
+	assert.Equal(t, "2020-01-02T03:04:06.000Z", messages[1]["timestamp"], "time of the event should be kept")
+	assert.Contains(t, messages[1]["logger"], "tail_sampling_test.go")
+
+	assert.Equal(t, "kept 2", messages[2]["message"])
+	assert.Equal(t, "value", messages[2]["key"])
+	assert.Equal(t, "failed", messages[3]["message"])
+	assert.Equal(t, "failed again", messages[4]["message"], "events should be flushed only once")
+}
+
+func TestTailSamplingWithoutContext(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "info")
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf))
+
+	log.Debug(context.Background(), "dropped")
+	log.Error(context.Background(), "failed")
+	logging.FlushTailSampled(context.Background())
+
+	messages := logLines(t, buf)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "failed", messages[0]["message"])
+}
+
+func TestTailSamplingMiddleware(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "info")
+	buf := &bytes.Buffer{}
+	log := logging.NewLogger(logging.WithOutput(buf))
+	handler := logging.TailSamplingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Debug(r.Context(), r.URL.Path)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), 10)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	messages := logLines(t, buf)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "/fail", messages[0]["message"])
+}
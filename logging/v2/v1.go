@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+
+	v1 "github.com/phanitejak/kptgolib/logging"
+)
+
+// V1Logger adapts Logger to context-less v1 logging.Logger interface, so that packages still using v1 logger
+// can log through v2 logger during migration. Messages are logged with trace context of the context given to
+// NewV1Logger and fields of the logger are kept. Warn is logged at level Info and Print at level Debug,
+// as in v1 logger.
+//
+//	vault.SetLogger(logging.NewV1Logger(context.Background(), log.With("component", "vault")))
+type V1Logger struct {
+	ctx context.Context
+	log Logger
+}
+
+var _ v1.Logger = &V1Logger{}
+
+// NewV1Logger returns V1Logger logging with given logger and context.
+func NewV1Logger(ctx context.Context, log Logger) *V1Logger {
+	return &V1Logger{ctx: ctx, log: adapterLogger(log)}
+}
+
+// With adds kv pair to log message.
+func (l *V1Logger) With(key string, value interface{}) v1.Logger {
+	return &V1Logger{ctx: l.ctx, log: adapterLogger(l.log.With(key, value))}
+}
+
+// WithFields adds map as a kv pairs to log message.
+func (l *V1Logger) WithFields(fields map[string]interface{}) v1.Logger {
+	return &V1Logger{ctx: l.ctx, log: adapterLogger(l.log.WithFields(fields))}
+}
+
+// IncDepth can be used by wrappers to increment stack depth.
+func (l *V1Logger) IncDepth(depth int) v1.Logger {
+	if d, ok := l.log.(depthIncrementer); ok {
+		return &V1Logger{ctx: l.ctx, log: d.IncDepth(depth)}
+	}
+	return l
+}
+
+// Debug logs a message at level Debug.
+func (l *V1Logger) Debug(args ...interface{}) {
+	l.log.Debug(l.ctx, args...)
+}
+
+// Debugln logs a message at level Debug.
+func (l *V1Logger) Debugln(args ...interface{}) {
+	l.log.Debugln(l.ctx, args...)
+}
+
+// Debugf logs a message at level Debug.
+func (l *V1Logger) Debugf(format string, args ...interface{}) {
+	l.log.Debugf(l.ctx, format, args...)
+}
+
+// Info logs a message at level Info.
+func (l *V1Logger) Info(args ...interface{}) {
+	l.log.Info(l.ctx, args...)
+}
+
+// Infoln logs a message at level Info.
+func (l *V1Logger) Infoln(args ...interface{}) {
+	l.log.Infoln(l.ctx, args...)
+}
+
+// Infof logs a message at level Info.
+func (l *V1Logger) Infof(format string, args ...interface{}) {
+	l.log.Infof(l.ctx, format, args...)
+}
+
+// Warn logs a message at level Info.
+func (l *V1Logger) Warn(args ...interface{}) {
+	l.log.Info(l.ctx, args...)
+}
+
+// Warnln logs a message at level Info.
+func (l *V1Logger) Warnln(args ...interface{}) {
+	l.log.Infoln(l.ctx, args...)
+}
+
+// Warnf logs a message at level Info.
+func (l *V1Logger) Warnf(format string, args ...interface{}) {
+	l.log.Infof(l.ctx, format, args...)
+}
+
+// Error logs a message at level Error.
+func (l *V1Logger) Error(args ...interface{}) {
+	l.log.Error(l.ctx, args...)
+}
+
+// Errorln logs a message at level Error.
+func (l *V1Logger) Errorln(args ...interface{}) {
+	l.log.Errorln(l.ctx, args...)
+}
+
+// Errorf logs a message at level Error.
+func (l *V1Logger) Errorf(format string, args ...interface{}) {
+	l.log.Errorf(l.ctx, format, args...)
+}
+
+// Print logs a message at level Debug.
+func (l *V1Logger) Print(args ...interface{}) {
+	l.log.Debug(l.ctx, args...)
+}
+
+// Println logs a message at level Debug.
+func (l *V1Logger) Println(args ...interface{}) {
+	l.log.Debugln(l.ctx, args...)
+}
+
+// Printf logs a message at level Debug.
+func (l *V1Logger) Printf(format string, args ...interface{}) {
+	l.log.Debugf(l.ctx, format, args...)
+}
+
+// Fatal logs a message at level Error and exits.
+func (l *V1Logger) Fatal(args ...interface{}) {
+	l.log.Fatal(l.ctx, args...)
+}
+
+// Fatalln logs a message at level Error and exits.
+func (l *V1Logger) Fatalln(args ...interface{}) {
+	l.log.Fatalln(l.ctx, args...)
+}
+
+// Fatalf logs a message at level Error and exits.
+func (l *V1Logger) Fatalf(format string, args ...interface{}) {
+	l.log.Fatalf(l.ctx, format, args...)
+}
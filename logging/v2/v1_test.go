@@ -0,0 +1,43 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/v2"
+	"github.com/phanitejak/kptgolib/logging/v2/testutil"
+)
+
+func TestV1Logger(t *testing.T) {
+	t.Setenv("LOGGING_LEVEL", "debug")
+	logger, logOutput := getLogger(t)
+
+	l := logging.NewV1Logger(context.Background(), logger.With("component", "vault"))
+	l.With("path", "secret/a").Debugf("reading %s", "secret")
+	l.WithFields(map[string]interface{}{"attempt": "2"}).Warn("retrying")
+	l.Errorln("failed")
+
+	lines := bytes.Split(bytes.TrimSpace(logOutput().Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	debug := testutil.UnmarshalLogMessage(t, lines[0])
+	assert.Equal(t, "reading secret", debug["message"])
+	assert.Equal(t, "debug", debug["level"])
+	assert.Equal(t, "vault", debug["component"])
+	assert.Equal(t, "secret/a", debug["path"])
+	assert.Regexp(t, `^v1_test.go:\d+$`, debug["logger"])
+
+	warn := testutil.UnmarshalLogMessage(t, lines[1])
+	assert.Equal(t, "info", warn["level"])
+	assert.Equal(t, "vault", warn["component"])
+	assert.Equal(t, "2", warn["attempt"])
+	assert.Regexp(t, `^v1_test.go:\d+$`, warn["logger"])
+
+	errorMsg := testutil.UnmarshalLogMessage(t, lines[2])
+	assert.Equal(t, "error", errorMsg["level"])
+	assert.Regexp(t, `^v1_test.go:\d+$`, errorMsg["logger"])
+}
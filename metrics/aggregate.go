@@ -0,0 +1,303 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// AggregateEndPoint is the endpoint of the aggregation server receiving metrics pushed by worker processes.
+	AggregateEndPoint = "/application/aggregate"
+	// WorkerLabel is the label added to aggregated metrics identifying the worker process which pushed them.
+	WorkerLabel = "worker"
+	// unixAddressPrefix is the prefix of Unix socket addresses given to aggregation server and client.
+	unixAddressPrefix = "unix:"
+)
+
+// Aggregator is a collector exposing metrics pushed by worker subprocesses, e.g. forked workers which
+// can't expose metrics themselves. Each worker pushes all its metrics in Prometheus text format and
+// the latest push replaces previously pushed metrics of the same worker. Aggregated metrics are exposed
+// with WorkerLabel added, so workers must use distinct names, e.g. their index or pid.
+type Aggregator struct {
+	mu      sync.Mutex
+	workers map[string]aggregatedWorker
+	expiry  time.Duration
+}
+
+type aggregatedWorker struct {
+	families MetricFamilies
+	pushed   time.Time
+}
+
+// NewAggregator creates a new Aggregator. Metrics of workers, which haven't pushed within expiry,
+// are no longer exposed, so that metrics of exited workers disappear. Zero expiry keeps metrics until
+// the worker removes them.
+func NewAggregator(expiry time.Duration) *Aggregator {
+	return &Aggregator{workers: map[string]aggregatedWorker{}, expiry: expiry}
+}
+
+// ServeHTTP receives metrics pushed by AggregationClient. Metrics given in Prometheus text format with
+// PUT or POST replace previously pushed metrics of the worker given in WorkerLabel query parameter
+// and DELETE removes them.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	worker := r.URL.Query().Get(WorkerLabel)
+	if worker == "" {
+		http.Error(w, fmt.Sprintf("query parameter %s is required", WorkerLabel), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		families, err := ParseMetrics(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for name, family := range families {
+			for _, m := range family.GetMetric() {
+				for _, lp := range m.GetLabel() {
+					if lp.GetName() == WorkerLabel {
+						http.Error(w, fmt.Sprintf("metric %s must not have label %s", name, WorkerLabel), http.StatusBadRequest)
+						return
+					}
+				}
+			}
+		}
+		a.mu.Lock()
+		a.workers[worker] = aggregatedWorker{families: families, pushed: time.Now()}
+		a.mu.Unlock()
+	case http.MethodDelete:
+		a.mu.Lock()
+		delete(a.workers, worker)
+		a.mu.Unlock()
+	default:
+		w.Header().Set("Allow", "PUT, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Describe implements prometheus.Collector. Aggregator is an unchecked collector, because pushed
+// metrics are not known beforehand.
+func (a *Aggregator) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (a *Aggregator) Collect(ch chan<- prometheus.Metric) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for worker, aw := range a.workers {
+		if a.expiry > 0 && time.Since(aw.pushed) > a.expiry {
+			delete(a.workers, worker)
+			continue
+		}
+		for _, family := range aw.families {
+			for _, m := range family.GetMetric() {
+				ch <- aggregatedMetric(family, m, worker)
+			}
+		}
+	}
+}
+
+func aggregatedMetric(family *dto.MetricFamily, m *dto.Metric, worker string) prometheus.Metric {
+	labels := make([]*dto.LabelPair, len(m.GetLabel()))
+	copy(labels, m.GetLabel())
+	sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+	names := make([]string, 0, len(labels)+1)
+	values := make([]string, 0, len(labels)+1)
+	for _, lp := range labels {
+		names = append(names, lp.GetName())
+		values = append(values, lp.GetValue())
+	}
+	names = append(names, WorkerLabel)
+	values = append(values, worker)
+	desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), names, nil)
+
+	var metric prometheus.Metric
+	var err error
+	switch {
+	case m.Counter != nil:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), values...)
+	case m.Gauge != nil:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), values...)
+	case m.Untyped != nil:
+		metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), values...)
+	case m.Summary != nil:
+		s := m.GetSummary()
+		quantiles := make(map[float64]float64, len(s.GetQuantile()))
+		for _, q := range s.GetQuantile() {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+		metric, err = prometheus.NewConstSummary(desc, s.GetSampleCount(), s.GetSampleSum(), quantiles, values...)
+	case m.Histogram != nil:
+		h := m.GetHistogram()
+		buckets := make(map[float64]uint64, len(h.GetBucket()))
+		for _, b := range h.GetBucket() {
+			buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+		}
+		metric, err = prometheus.NewConstHistogram(desc, h.GetSampleCount(), h.GetSampleSum(), buckets, values...)
+	default:
+		err = fmt.Errorf("unsupported type of metric %s", family.GetName())
+	}
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return metric
+}
+
+// AggregationServer receives metrics pushed by worker subprocesses and exposes them in the default
+// registry, i.e. from the metrics endpoint of the parent process.
+type AggregationServer struct {
+	*Aggregator
+	server *http.Server
+	wg     sync.WaitGroup
+}
+
+// StartAggregationServer registers a new Aggregator with given expiry to the default registry and starts
+// HTTP server receiving pushed metrics in AggregateEndPoint. Address is either a Unix socket path
+// prefixed with "unix:", e.g. "unix:/tmp/metrics.sock", or a TCP address, which should be a localhost
+// address, e.g. "127.0.0.1:9091". Server must be closed with Close.
+func StartAggregationServer(address string, expiry time.Duration) (*AggregationServer, error) {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(address, unixAddressPrefix); ok {
+		network, address = "unix", path
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start aggregation server: %w", err)
+	}
+
+	aggregator := NewAggregator(expiry)
+	if err := prometheus.Register(aggregator); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(AggregateEndPoint, aggregator)
+	s := &AggregationServer{Aggregator: aggregator, server: &http.Server{Handler: mux}}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// Close stops the server and removes aggregated metrics. Aggregator is an unchecked collector,
+// which can't be unregistered, so it stays registered without exposing any metrics.
+func (s *AggregationServer) Close() error {
+	err := s.server.Close()
+	s.wg.Wait()
+	s.mu.Lock()
+	s.workers = map[string]aggregatedWorker{}
+	s.mu.Unlock()
+	return err
+}
+
+// AggregationClient pushes metrics of a worker subprocess to AggregationServer of the parent process.
+type AggregationClient struct {
+	client   *http.Client
+	url      string
+	gatherer prometheus.Gatherer
+}
+
+// NewAggregationClient creates client pushing metrics of the default registry to aggregation server in
+// given address, which is given in the same format as to StartAggregationServer. Worker must be unique
+// among the workers of the parent process.
+func NewAggregationClient(address string, worker string) *AggregationClient {
+	client := &http.Client{Timeout: 5 * time.Second}
+	host := address
+	if path, ok := strings.CutPrefix(address, unixAddressPrefix); ok {
+		host = "localhost"
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+	}
+	return &AggregationClient{
+		client:   client,
+		url:      "http://" + host + AggregateEndPoint + "?" + url.Values{WorkerLabel: {worker}}.Encode(),
+		gatherer: Gatherer(),
+	}
+}
+
+// Gatherer sets gatherer, which metrics are pushed instead of the default registry.
+// For convenience, this method returns a pointer to the client itself.
+func (c *AggregationClient) Gatherer(g prometheus.Gatherer) *AggregationClient {
+	c.gatherer = g
+	return c
+}
+
+// Push gathers metrics and pushes them to the aggregation server replacing previously pushed metrics.
+func (c *AggregationClient) Push(ctx context.Context) error {
+	families, err := c.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	enc := expfmt.NewEncoder(buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return fmt.Errorf("failed to encode metrics: %w", err)
+		}
+	}
+	return c.do(ctx, http.MethodPut, buf)
+}
+
+// Remove removes metrics pushed by the worker from the aggregation server, e.g. when the worker exits.
+func (c *AggregationClient) Remove(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, nil)
+}
+
+// Run pushes metrics in given interval until ctx is done. Failed pushes are retried in the next interval,
+// e.g. when the parent process is restarting. Metrics are pushed once more before returning, so that final
+// values of the counters are not lost, and error of the final push is returned.
+func (c *AggregationClient) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			pushCtx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+			defer cancel()
+			return c.Push(pushCtx)
+		case <-ticker.C:
+			_ = c.Push(ctx)
+		}
+	}
+}
+
+func (c *AggregationClient) do(ctx context.Context, method string, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status code %d when pushing metrics: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+func TestAggregationServer(t *testing.T) {
+	address := "unix:" + filepath.Join(t.TempDir(), "metrics.sock")
+	server, err := metrics.StartAggregationServer(address, time.Minute)
+	require.NoError(t, err)
+	defer server.Close()
+
+	for _, worker := range []string{"1", "2"} {
+		registry := prometheus.NewRegistry()
+		jobs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "aggregate_test_jobs_total", Help: "Jobs."}, []string{"queue"})
+		latency := prometheus.NewSummary(prometheus.SummaryOpts{Name: "aggregate_test_latency_seconds", Help: "Latency."})
+		registry.MustRegister(jobs, latency)
+		jobs.WithLabelValues("default").Add(3)
+		latency.Observe(0.5)
+
+		client := metrics.NewAggregationClient(address, worker).Gatherer(registry)
+		require.NoError(t, client.Push(context.Background()))
+	}
+
+	families := gatherFamilies(t)
+	for _, worker := range []string{"1", "2"} {
+		value, ok := families.Value("aggregate_test_jobs_total", map[string]string{"queue": "default", metrics.WorkerLabel: worker})
+		assert.True(t, ok, "worker %s", worker)
+		assert.Equal(t, 3.0, value)
+	}
+	require.Contains(t, families, "aggregate_test_latency_seconds")
+	summaries := families["aggregate_test_latency_seconds"].GetMetric()
+	require.Len(t, summaries, 2)
+	assert.Equal(t, uint64(1), summaries[0].GetSummary().GetSampleCount())
+	assert.Equal(t, 0.5, summaries[0].GetSummary().GetSampleSum())
+
+	client := metrics.NewAggregationClient(address, "1")
+	require.NoError(t, client.Remove(context.Background()))
+	_, ok := gatherFamilies(t).Value("aggregate_test_jobs_total", map[string]string{metrics.WorkerLabel: "1"})
+	assert.False(t, ok)
+
+	require.NoError(t, server.Close())
+	assert.NotContains(t, gatherFamilies(t), "aggregate_test_jobs_total")
+}
+
+func TestAggregatorExpiry(t *testing.T) {
+	aggregator := metrics.NewAggregator(50 * time.Millisecond)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(aggregator)
+
+	r := httptest.NewRequest(http.MethodPut, metrics.AggregateEndPoint+"?worker=1", strings.NewReader("expiring_total 1\n"))
+	w := httptest.NewRecorder()
+	aggregator.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	assert.Len(t, families, 1)
+
+	time.Sleep(100 * time.Millisecond)
+	families, err = registry.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, families)
+}
+
+func TestAggregatorInvalidPush(t *testing.T) {
+	aggregator := metrics.NewAggregator(0)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{name: "MissingWorker", method: http.MethodPut, target: metrics.AggregateEndPoint, body: "a 1\n", want: http.StatusBadRequest},
+		{name: "InvalidFormat", method: http.MethodPut, target: metrics.AggregateEndPoint + "?worker=1", body: "a{ 1\n", want: http.StatusBadRequest},
+		{name: "WorkerLabel", method: http.MethodPut, target: metrics.AggregateEndPoint + "?worker=1", body: "a{worker=\"2\"} 1\n", want: http.StatusBadRequest},
+		{name: "Method", method: http.MethodGet, target: metrics.AggregateEndPoint + "?worker=1", want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			aggregator.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func gatherFamilies(t *testing.T) metrics.MetricFamilies {
+	gathered, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	families := metrics.MetricFamilies{}
+	for _, family := range gathered {
+		families[family.GetName()] = family
+	}
+	return families
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultCgroupRoot is the mount point of cgroup file system read by the default collector.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+type cgroupCollector struct {
+	root                 string
+	gomaxprocsDesc       *prometheus.Desc
+	cpuQuotaDesc         *prometheus.Desc
+	memoryLimitDesc      *prometheus.Desc
+	cpuPeriodsDesc       *prometheus.Desc
+	cpuThrottledDesc     *prometheus.Desc
+	cpuThrottledTimeDesc *prometheus.Desc
+}
+
+// NewCgroupCollector returns collector of container CPU and memory limits read from cgroup v1 or v2 file system
+// mounted at root, so that dashboards reflect container limits rather than node size. Limits are exported only
+// when they are set, i.e. nothing but effective GOMAXPROCS is exported outside of Linux containers.
+// Collector with DefaultCgroupRoot is included in the default collector.
+func NewCgroupCollector(root string) prometheus.Collector {
+	return &cgroupCollector{
+		root:           root,
+		gomaxprocsDesc: prometheus.NewDesc("process_gomaxprocs", "Effective GOMAXPROCS of the current process.", nil, nil),
+		cpuQuotaDesc: prometheus.NewDesc("container_cpu_quota_cores",
+			"CPU quota of the container in cores, exported only when the quota is set.", nil, nil),
+		memoryLimitDesc: prometheus.NewDesc("container_memory_limit_bytes",
+			"Memory limit of the container in bytes, exported only when the limit is set.", nil, nil),
+		cpuPeriodsDesc: prometheus.NewDesc("container_cpu_periods_total",
+			"Number of elapsed CPU quota enforcement periods of the container.", nil, nil),
+		cpuThrottledDesc: prometheus.NewDesc("container_cpu_throttled_periods_total",
+			"Number of CPU quota enforcement periods, in which the container was throttled.", nil, nil),
+		cpuThrottledTimeDesc: prometheus.NewDesc("container_cpu_throttled_seconds_total",
+			"Total time the container was throttled in seconds.", nil, nil),
+	}
+}
+
+// Describe returns all descriptions of the collector.
+func (c *cgroupCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.gomaxprocsDesc
+	ch <- c.cpuQuotaDesc
+	ch <- c.memoryLimitDesc
+	ch <- c.cpuPeriodsDesc
+	ch <- c.cpuThrottledDesc
+	ch <- c.cpuThrottledTimeDesc
+}
+
+// Collect returns the current state of all metrics of the collector.
+func (c *cgroupCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.gomaxprocsDesc, prometheus.GaugeValue, float64(runtime.GOMAXPROCS(0)))
+
+	limits := c.read()
+	if limits.cpuQuota > 0 {
+		ch <- prometheus.MustNewConstMetric(c.cpuQuotaDesc, prometheus.GaugeValue, limits.cpuQuota)
+	}
+	if limits.memoryLimit > 0 {
+		ch <- prometheus.MustNewConstMetric(c.memoryLimitDesc, prometheus.GaugeValue, limits.memoryLimit)
+	}
+	if limits.hasCPUStat {
+		ch <- prometheus.MustNewConstMetric(c.cpuPeriodsDesc, prometheus.CounterValue, limits.periods)
+		ch <- prometheus.MustNewConstMetric(c.cpuThrottledDesc, prometheus.CounterValue, limits.throttledPeriods)
+		ch <- prometheus.MustNewConstMetric(c.cpuThrottledTimeDesc, prometheus.CounterValue, limits.throttledSeconds)
+	}
+}
+
+type cgroupLimits struct {
+	cpuQuota         float64
+	memoryLimit      float64
+	hasCPUStat       bool
+	periods          float64
+	throttledPeriods float64
+	throttledSeconds float64
+}
+
+// read reads limits from cgroup v2 unified hierarchy, or from cgroup v1 cpu and memory controllers.
+func (c *cgroupCollector) read() (limits cgroupLimits) {
+	if cpuMax, err := readCgroupFile(c.root, "cpu.max"); err == nil {
+		// cgroup v2: "$MAX $PERIOD", where $MAX is "max" when quota is not set
+		if fields := strings.Fields(cpuMax); len(fields) == 2 {
+			limits.cpuQuota = quotaCores(fields[0], fields[1])
+		}
+		limits.memoryLimit = parseLimit(readCgroupFileOrEmpty(c.root, "memory.max"))
+		stat, ok := readCPUStat(c.root, "cpu.stat")
+		if ok {
+			limits.hasCPUStat = true
+			limits.periods = stat["nr_periods"]
+			limits.throttledPeriods = stat["nr_throttled"]
+			limits.throttledSeconds = stat["throttled_usec"] / 1e6
+		}
+		return limits
+	}
+
+	limits.cpuQuota = quotaCores(readCgroupFileOrEmpty(c.root, "cpu/cpu.cfs_quota_us"), readCgroupFileOrEmpty(c.root, "cpu/cpu.cfs_period_us"))
+	limits.memoryLimit = parseLimit(readCgroupFileOrEmpty(c.root, "memory/memory.limit_in_bytes"))
+	// cgroup v1 reports unlimited memory as a huge number rounded to page size
+	if limits.memoryLimit >= 1<<62 {
+		limits.memoryLimit = 0
+	}
+	stat, ok := readCPUStat(c.root, "cpu/cpu.stat")
+	if ok {
+		limits.hasCPUStat = true
+		limits.periods = stat["nr_periods"]
+		limits.throttledPeriods = stat["nr_throttled"]
+		limits.throttledSeconds = stat["throttled_time"] / 1e9
+	}
+	return limits
+}
+
+func quotaCores(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+func parseLimit(value string) float64 {
+	limit, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return limit
+}
+
+func readCPUStat(root, name string) (map[string]float64, bool) {
+	f, err := os.Open(filepath.Join(root, name))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	stat := map[string]float64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			stat[fields[0]] = v
+		}
+	}
+	return stat, scanner.Err() == nil
+}
+
+func readCgroupFile(root, name string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readCgroupFileOrEmpty(root, name string) string {
+	value, _ := readCgroupFile(root, name)
+	return value
+}
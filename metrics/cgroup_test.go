@@ -0,0 +1,108 @@
+package metrics_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return root
+}
+
+func gatherCgroup(t *testing.T, root string) map[string]float64 {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(metrics.NewCgroupCollector(root)))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+	for _, f := range families {
+		m := f.GetMetric()[0]
+		if m.GetCounter() != nil {
+			values[f.GetName()] = m.GetCounter().GetValue()
+		} else {
+			values[f.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestCgroupCollector(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected map[string]float64
+	}{
+		{
+			name: "v2",
+			files: map[string]string{
+				"cpu.max":    "150000 100000\n",
+				"memory.max": "536870912\n",
+				"cpu.stat":   "usage_usec 1000\nnr_periods 10\nnr_throttled 4\nthrottled_usec 2500000\n",
+			},
+			expected: map[string]float64{
+				"container_cpu_quota_cores":             1.5,
+				"container_memory_limit_bytes":          536870912,
+				"container_cpu_periods_total":           10,
+				"container_cpu_throttled_periods_total": 4,
+				"container_cpu_throttled_seconds_total": 2.5,
+			},
+		},
+		{
+			name: "v2 unlimited",
+			files: map[string]string{
+				"cpu.max":    "max 100000\n",
+				"memory.max": "max\n",
+			},
+			expected: map[string]float64{},
+		},
+		{
+			name: "v1",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "50000\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"cpu/cpu.stat":                 "nr_periods 7\nnr_throttled 1\nthrottled_time 500000000\n",
+				"memory/memory.limit_in_bytes": "1073741824\n",
+			},
+			expected: map[string]float64{
+				"container_cpu_quota_cores":             0.5,
+				"container_memory_limit_bytes":          1073741824,
+				"container_cpu_periods_total":           7,
+				"container_cpu_throttled_periods_total": 1,
+				"container_cpu_throttled_seconds_total": 0.5,
+			},
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "-1\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			expected: map[string]float64{},
+		},
+		{
+			name:     "no cgroup",
+			expected: map[string]float64{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expected["process_gomaxprocs"] = float64(runtime.GOMAXPROCS(0))
+			assert.Equal(t, tc.expected, gatherCgroup(t, writeCgroupFiles(t, tc.files)))
+		})
+	}
+}
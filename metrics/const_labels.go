@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"os"
+
+	v3 "github.com/phanitejak/kptgolib/metrics/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Environment variables populated from the Kubernetes downward API which are
+// used by PodLabels.
+const (
+	PodNameEnv      = "POD_NAME"
+	PodNamespaceEnv = "POD_NAMESPACE"
+)
+
+// Label names used for pod metadata added by PodLabels.
+const (
+	PodLabel       = "pod"
+	NamespaceLabel = "namespace"
+)
+
+// PodLabels returns pod and namespace labels read from POD_NAME and
+// POD_NAMESPACE environment variables. Variables which are not set are left
+// out from the result.
+func PodLabels() prometheus.Labels {
+	labels := prometheus.Labels{}
+	if name := os.Getenv(PodNameEnv); name != "" {
+		labels[PodLabel] = name
+	}
+	if namespace := os.Getenv(PodNamespaceEnv); namespace != "" {
+		labels[NamespaceLabel] = namespace
+	}
+	return labels
+}
+
+// WithPodLabels returns copy of given const labels extended with PodLabels.
+// Labels given explicitly take precedence over the pod metadata.
+func WithPodLabels(constLabels prometheus.Labels) prometheus.Labels {
+	labels := PodLabels()
+	for k, v := range constLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// RegisterCounterWithConstLabels registers given counter metric with static
+// labels by using given subsystem name and metric description. NEO metrics
+// namespace is added to metric name as prefix.
+func RegisterCounterWithConstLabels(metricName string, subsystem string, desc string, constLabels prometheus.Labels) Counter {
+	if Disabled() {
+		return disabledCounter
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindCounter),
+		v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithConstLabels(constLabels))
+	return &CustomCounter{m.Collector().(prometheus.Counter)}
+}
+
+// RegisterCounterVecWithConstLabels registers given counter vector metric with
+// static labels by using given keys, subsystem name and metric description.
+// NEO metrics namespace is added to metric name as prefix.
+func RegisterCounterVecWithConstLabels(metricName string, subsystem string, desc string, constLabels prometheus.Labels, keys ...string) CounterVec {
+	if Disabled() {
+		return disabledCounter
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindCounter),
+		v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithConstLabels(constLabels), v3.WithLabels(keys...))
+	return &CustomCounterVec{m.Collector().(*prometheus.CounterVec), metricName}
+}
+
+// RegisterGaugeWithConstLabels registers given gauge metric with static
+// labels by using given subsystem name and metric description. NEO metrics
+// namespace is added to metric name as prefix.
+func RegisterGaugeWithConstLabels(metricName string, subsystem string, desc string, constLabels prometheus.Labels) *CustomGauge {
+	if Disabled() {
+		return disabledGauge
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindGauge),
+		v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithConstLabels(constLabels))
+	return &CustomGauge{m.Collector().(prometheus.Gauge)}
+}
+
+// RegisterGaugeVecWithConstLabels registers given gauge vector metric with
+// static labels by using given keys, subsystem name and metric description.
+// NEO metrics namespace is added to metric name as prefix.
+func RegisterGaugeVecWithConstLabels(metricName string, subsystem string, desc string, constLabels prometheus.Labels, keys ...string) *CustomGaugeVec {
+	if Disabled() {
+		return disabledGaugeVec
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindGauge),
+		v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithConstLabels(constLabels), v3.WithLabels(keys...))
+	return &CustomGaugeVec{m.Collector().(*prometheus.GaugeVec), metricName}
+}
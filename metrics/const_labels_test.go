@@ -0,0 +1,79 @@
+package metrics_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleRegisterCounterWithConstLabels() {
+	// Registers new custom counter metric named com_metrics_my_service_foo_counter{component="foo",namespace=<POD_NAMESPACE>,pod=<POD_NAME>}
+	testCounter := metrics.RegisterCounterWithConstLabels("foo_counter", "my_service", "lorem ipsum...",
+		metrics.WithPodLabels(prometheus.Labels{"component": "foo"}))
+
+	testCounter.Inc()
+}
+
+func TestPodLabels(t *testing.T) {
+	t.Setenv(metrics.PodNameEnv, "my-pod-0")
+	t.Setenv(metrics.PodNamespaceEnv, "my-namespace")
+
+	assert.Equal(t, prometheus.Labels{"pod": "my-pod-0", "namespace": "my-namespace"}, metrics.PodLabels())
+	assert.Equal(t, prometheus.Labels{"pod": "overridden", "namespace": "my-namespace", "team": "a"},
+		metrics.WithPodLabels(prometheus.Labels{"pod": "overridden", "team": "a"}))
+}
+
+func TestPodLabelsNotSet(t *testing.T) {
+	t.Setenv(metrics.PodNameEnv, "")
+	t.Setenv(metrics.PodNamespaceEnv, "")
+
+	assert.Empty(t, metrics.PodLabels())
+}
+
+func TestRegisterWithConstLabels(t *testing.T) {
+	constLabels := prometheus.Labels{"component": "comp", "team": "team"}
+
+	t.Run("Counter", func(t *testing.T) {
+		metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+		c := metrics.RegisterCounterWithConstLabels(metric, "test", "test", constLabels)
+		defer c.Unregister()
+		c.Inc()
+
+		body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+		assert.Contains(t, body, fmt.Sprintf(`%s_test_%s{component="comp",team="team"} 1`, metricNamespace, metric))
+	})
+	t.Run("CounterVec", func(t *testing.T) {
+		metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+		c := metrics.RegisterCounterVecWithConstLabels(metric, "test", "test", constLabels, "key1")
+		defer c.Unregister()
+		c.GetCustomCounter("val1").Inc()
+
+		body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+		assert.Contains(t, body, fmt.Sprintf(`%s_test_%s{%s="%s",component="comp",key1="val1",team="team"} 1`,
+			metricNamespace, metric, plainMetricNameKey, metric))
+	})
+	t.Run("Gauge", func(t *testing.T) {
+		metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+		g := metrics.RegisterGaugeWithConstLabels(metric, "test", "test", constLabels)
+		defer g.Unregister()
+		g.Set(3)
+
+		body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+		assert.Contains(t, body, fmt.Sprintf(`%s_test_%s{component="comp",team="team"} 3`, metricNamespace, metric))
+	})
+	t.Run("GaugeVec", func(t *testing.T) {
+		metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+		g := metrics.RegisterGaugeVecWithConstLabels(metric, "test", "test", constLabels, "key1")
+		defer g.Unregister()
+		g.GetCustomGauge("val1").Set(3)
+
+		body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+		assert.Contains(t, body, fmt.Sprintf(`%s_test_%s{%s="%s",component="comp",key1="val1",team="team"} 3`,
+			metricNamespace, metric, plainMetricNameKey, metric))
+	})
+}
@@ -83,27 +83,12 @@ func (ccv *CustomCounterVec) Unregister() bool {
 // and metric description. NEO metrics namespace is added to metric name as
 // prefix.
 func RegisterCounter(metricName string, subsystem string, desc string) Counter {
-	counter := prometheus.NewCounter(prometheus.CounterOpts{
-		Namespace: metricNamespace,
-		Subsystem: subsystem,
-		Name:      metricName,
-		Help:      desc,
-	})
-	prometheus.MustRegister(counter)
-	return &CustomCounter{counter}
+	return RegisterCounterWithConstLabels(metricName, subsystem, desc, nil)
 }
 
 // RegisterCounterVec registers given counter vector metric by using given
 // keys, subsystem name and metric description. NEO metrics namespace is
 // added to metric name as prefix.
 func RegisterCounterVec(metricName string, subsystem string, desc string, keys ...string) CounterVec {
-	finalKeys := append(keys, plainMetricNameKey)
-	counterVec := prometheus.NewCounterVec(prometheus.CounterOpts{
-		Namespace: metricNamespace,
-		Subsystem: subsystem,
-		Name:      metricName,
-		Help:      desc,
-	}, finalKeys)
-	prometheus.MustRegister(counterVec)
-	return &CustomCounterVec{counterVec, metricName}
+	return RegisterCounterVecWithConstLabels(metricName, subsystem, desc, nil, keys...)
 }
@@ -16,6 +16,9 @@ func (cg *CustomGauge) GetCollector() prometheus.Collector {
 
 // GetCollector get the gaugeVec
 func (cgv *CustomGaugeVec) GetCollector() prometheus.Collector {
+	if cgv.gaugeVec == nil {
+		return noopMetric{}
+	}
 	return cgv.gaugeVec
 }
 
@@ -43,6 +46,9 @@ type CustomGaugeVec struct {
 // GetCustomGauge gets custom gauge for given labels. Labels has to be given
 // in the same order than registered.
 func (cgv *CustomGaugeVec) GetCustomGauge(labelValues ...string) *CustomGauge {
+	if cgv.gaugeVec == nil {
+		return disabledGauge
+	}
 	finalLabelValues := append(labelValues, cgv.metricName)
 	return &CustomGauge{cgv.gaugeVec.WithLabelValues(finalLabelValues...)}
 }
@@ -50,17 +56,26 @@ func (cgv *CustomGaugeVec) GetCustomGauge(labelValues ...string) *CustomGauge {
 // DeleteSerie deletes custom gauge for given labels. Labels has to be given
 // in the same order than registered.
 func (cgv *CustomGaugeVec) DeleteSerie(labelValues ...string) bool {
+	if cgv.gaugeVec == nil {
+		return false
+	}
 	finalLabelValues := append(labelValues, cgv.metricName)
 	return cgv.gaugeVec.DeleteLabelValues(finalLabelValues...)
 }
 
 // Reset deletes all metrics in this gauge vector.
 func (cgv *CustomGaugeVec) Reset() {
+	if cgv.gaugeVec == nil {
+		return
+	}
 	cgv.gaugeVec.Reset()
 }
 
 // Unregister unregisters the gaugeVec.
 func (cgv *CustomGaugeVec) Unregister() bool {
+	if cgv.gaugeVec == nil {
+		return false
+	}
 	return prometheus.Unregister(cgv.gaugeVec)
 }
 
@@ -69,14 +84,7 @@ func (cgv *CustomGaugeVec) Unregister() bool {
 // prefix.
 func RegisterGauge(metricName string, subsystem string,
 	desc string) *CustomGauge {
-	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace: metricNamespace,
-		Subsystem: subsystem,
-		Name:      metricName,
-		Help:      desc,
-	})
-	prometheus.MustRegister(gauge)
-	return &CustomGauge{gauge}
+	return RegisterGaugeWithConstLabels(metricName, subsystem, desc, nil)
 }
 
 // RegisterGaugeVec registers given gauge vector metric by using given keys,
@@ -84,13 +92,5 @@ func RegisterGauge(metricName string, subsystem string,
 // metric name as prefix.
 func RegisterGaugeVec(metricName string, subsystem string, desc string,
 	keys ...string) *CustomGaugeVec {
-	finalKeys := append(keys, plainMetricNameKey)
-	gaugeVec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: metricNamespace,
-		Subsystem: subsystem,
-		Name:      metricName,
-		Help:      desc,
-	}, finalKeys)
-	prometheus.MustRegister(gaugeVec)
-	return &CustomGaugeVec{gaugeVec, metricName}
+	return RegisterGaugeVecWithConstLabels(metricName, subsystem, desc, nil, keys...)
 }
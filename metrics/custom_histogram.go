@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	v3 "github.com/phanitejak/kptgolib/metrics/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Default values of NativeHistogramOptions.
+const (
+	DefaultNativeHistogramBucketFactor     = 1.1
+	DefaultNativeHistogramMaxBucketNumber  = 160
+	DefaultNativeHistogramMinResetDuration = time.Hour
+)
+
+// NativeHistogramOptions configures native (sparse) histograms, zero values are replaced with defaults.
+type NativeHistogramOptions struct {
+	// BucketFactor is the upper bound of the growth factor between neighbouring buckets, must be greater than 1.
+	BucketFactor float64
+	// MaxBucketNumber limits the number of buckets of a single histogram.
+	MaxBucketNumber uint32
+	// MinResetDuration is the minimum time between histogram resets when MaxBucketNumber is reached.
+	MinResetDuration time.Duration
+}
+
+var (
+	nativeHistogramMutex   sync.RWMutex
+	nativeHistogramOptions *NativeHistogramOptions
+)
+
+// EnableNativeHistograms makes histograms registered afterwards expose native histograms in addition to
+// classic buckets. Native histograms are only sent when the scraper negotiates protobuf exposition format,
+// other scrapers keep receiving classic buckets.
+func EnableNativeHistograms(opts NativeHistogramOptions) {
+	if opts.BucketFactor <= 1 {
+		opts.BucketFactor = DefaultNativeHistogramBucketFactor
+	}
+	if opts.MaxBucketNumber == 0 {
+		opts.MaxBucketNumber = DefaultNativeHistogramMaxBucketNumber
+	}
+	if opts.MinResetDuration == 0 {
+		opts.MinResetDuration = DefaultNativeHistogramMinResetDuration
+	}
+
+	nativeHistogramMutex.Lock()
+	defer nativeHistogramMutex.Unlock()
+	nativeHistogramOptions = &opts
+}
+
+// DisableNativeHistograms makes histograms registered afterwards expose only classic buckets, which is the default.
+func DisableNativeHistograms() {
+	nativeHistogramMutex.Lock()
+	defer nativeHistogramMutex.Unlock()
+	nativeHistogramOptions = nil
+}
+
+// Histogram is an interface for histogram metrics
+type Histogram interface {
+	GetCollector() prometheus.Collector
+	Observe(f float64)
+	ObserveDuration(startTime time.Time)
+	Unregister() bool
+}
+
+// CustomHistogram is type for business logic specific 1-dimension histogram metrics.
+type CustomHistogram struct {
+	observer  prometheus.Observer
+	collector prometheus.Collector
+	errors    *errorCounter
+}
+
+// GetCollector get the histogram
+func (ch *CustomHistogram) GetCollector() prometheus.Collector {
+	return ch.collector
+}
+
+// Observe observers the given value.
+func (ch *CustomHistogram) Observe(f float64) { ch.observer.Observe(f) }
+
+// ObserveDuration observers the elapsed time since given time in milliseconds.
+func (ch *CustomHistogram) ObserveDuration(startTime time.Time) {
+	ch.observer.Observe(float64(time.Since(startTime)) / float64(time.Millisecond))
+}
+
+// Unregister unregisters the histogram
+func (ch *CustomHistogram) Unregister() bool {
+	ch.errors.unregister()
+	return prometheus.Unregister(ch.collector)
+}
+
+// CustomHistogramVec is type for business logic specific 2-n dimension histogram
+// metrics (1-n custom labels).
+type CustomHistogramVec struct {
+	histogramVec *prometheus.HistogramVec
+	metricName   string
+	errors       *errorCounter
+}
+
+// GetCollector get the histogramVec
+func (chv *CustomHistogramVec) GetCollector() prometheus.Collector {
+	if chv.histogramVec == nil {
+		return noopMetric{}
+	}
+	return chv.histogramVec
+}
+
+// GetCustomHistogram gets custom histogram for given labels. Labels has to be given
+// in the same order than registered.
+func (chv *CustomHistogramVec) GetCustomHistogram(labelValues ...string) Histogram {
+	if chv.histogramVec == nil {
+		return disabledHistogram
+	}
+	finalLabelValues := append(labelValues, chv.metricName)
+	return &CustomHistogram{
+		observer:  chv.histogramVec.WithLabelValues(finalLabelValues...),
+		collector: chv.histogramVec,
+		errors:    chv.errors.with(labelValues...),
+	}
+}
+
+// DeleteSerie deletes custom histogram for given labels. Labels has to be given
+// in the same order than registered.
+func (chv *CustomHistogramVec) DeleteSerie(labelValues ...string) bool {
+	if chv.histogramVec == nil {
+		return false
+	}
+	chv.errors.deleteSeries(labelValues...)
+	finalLabelValues := append(labelValues, chv.metricName)
+	return chv.histogramVec.DeleteLabelValues(finalLabelValues...)
+}
+
+// Reset deletes all metrics in this histogram vector.
+func (chv *CustomHistogramVec) Reset() {
+	if chv.histogramVec == nil {
+		return
+	}
+	chv.errors.reset()
+	chv.histogramVec.Reset()
+}
+
+// Unregister unregisters the histogramVec.
+func (chv *CustomHistogramVec) Unregister() bool {
+	if chv.histogramVec == nil {
+		return false
+	}
+	chv.errors.unregister()
+	return prometheus.Unregister(chv.histogramVec)
+}
+
+// RegisterHistogram registers given histogram metric by using given subsystem name,
+// metric description and classic bucket upper bounds. prometheus.DefBuckets are used
+// when buckets are nil. NEO metrics namespace is added to metric name as prefix.
+func RegisterHistogram(metricName string, subsystem string, desc string, buckets []float64) Histogram {
+	if Disabled() {
+		return disabledHistogram
+	}
+	m := mustRegister(metricName, histogramRegisterOpts(subsystem, desc, buckets)...)
+	return &CustomHistogram{observer: m.Collector().(prometheus.Histogram), collector: m.Collector()}
+}
+
+// RegisterHistogramVec registers given histogram vector metric by using given keys,
+// subsystem name, metric description and classic bucket upper bounds. prometheus.DefBuckets
+// are used when buckets are nil. NEO metrics namespace is added to metric name as prefix.
+func RegisterHistogramVec(metricName string, subsystem string, desc string, buckets []float64, keys ...string) *CustomHistogramVec {
+	if Disabled() {
+		return disabledHistogramVec
+	}
+	m := mustRegister(metricName, append(histogramRegisterOpts(subsystem, desc, buckets), v3.WithLabels(keys...))...)
+	return &CustomHistogramVec{histogramVec: m.Collector().(*prometheus.HistogramVec), metricName: metricName}
+}
+
+func histogramRegisterOpts(subsystem string, desc string, buckets []float64) []v3.Opt {
+	opts := []v3.Opt{v3.WithKind(v3.KindHistogram), v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithBuckets(buckets)}
+
+	nativeHistogramMutex.RLock()
+	defer nativeHistogramMutex.RUnlock()
+	if nativeHistogramOptions != nil {
+		opts = append(opts, v3.WithNativeHistogram(nativeHistogramOptions.BucketFactor,
+			nativeHistogramOptions.MaxBucketNumber, nativeHistogramOptions.MinResetDuration))
+	}
+	return opts
+}
+
+func histogramOpts(metricName string, subsystem string, desc string, buckets []float64) prometheus.HistogramOpts {
+	if len(buckets) == 0 {
+		// Classic buckets are always kept as fallback for scrapers not supporting native histograms.
+		buckets = prometheus.DefBuckets
+	}
+
+	opts := prometheus.HistogramOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+		Buckets:   buckets,
+	}
+
+	nativeHistogramMutex.RLock()
+	defer nativeHistogramMutex.RUnlock()
+	if nativeHistogramOptions != nil {
+		opts.NativeHistogramBucketFactor = nativeHistogramOptions.BucketFactor
+		opts.NativeHistogramMaxBucketNumber = nativeHistogramOptions.MaxBucketNumber
+		opts.NativeHistogramMinResetDuration = nativeHistogramOptions.MinResetDuration
+	}
+	return opts
+}
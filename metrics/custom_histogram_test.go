@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ExampleEnableNativeHistograms() {
+	// Histograms registered after this call expose native histograms to scrapers negotiating protobuf format.
+	metrics.EnableNativeHistograms(metrics.NativeHistogramOptions{})
+	defer metrics.DisableNativeHistograms()
+
+	latency := metrics.RegisterHistogram("request_latency_milliseconds", "my_service", "lorem ipsum...", nil)
+	latency.Observe(42)
+}
+
+func collectHistogram(t *testing.T, c prometheus.Collector) *dto.Histogram {
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	m := &dto.Metric{}
+	require.NoError(t, (<-ch).Write(m))
+	require.NotNil(t, m.Histogram)
+	return m.Histogram
+}
+
+func TestRegisterHistogram(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	h := metrics.RegisterHistogram(metric, "test", "test", []float64{1, 10})
+	defer h.Unregister()
+	h.Observe(5)
+
+	body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+	assert.Contains(t, body, fmt.Sprintf(`%s_test_%s_bucket{le="1"} 0`, metricNamespace, metric))
+	assert.Contains(t, body, fmt.Sprintf(`%s_test_%s_bucket{le="10"} 1`, metricNamespace, metric))
+	assert.Contains(t, body, fmt.Sprintf(`%s_test_%s_count 1`, metricNamespace, metric))
+
+	assert.Nil(t, collectHistogram(t, h.GetCollector()).Schema, "native histogram should be disabled by default")
+}
+
+func TestRegisterHistogramVec(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	h := metrics.RegisterHistogramVec(metric, "test", "test", nil, "key1")
+	defer h.Unregister()
+	h.GetCustomHistogram("val1").Observe(0.3)
+
+	body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+	assert.Contains(t, body, fmt.Sprintf(`%s_test_%s_bucket{%s="%s",key1="val1",le="0.5"} 1`,
+		metricNamespace, metric, plainMetricNameKey, metric))
+	assert.True(t, h.DeleteSerie("val1"))
+}
+
+func TestNativeHistograms(t *testing.T) {
+	metrics.EnableNativeHistograms(metrics.NativeHistogramOptions{})
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	h := metrics.RegisterHistogram(metric, "test", "test", nil)
+	metrics.DisableNativeHistograms()
+	defer h.Unregister()
+	h.Observe(0.3)
+
+	histogram := collectHistogram(t, h.GetCollector())
+	assert.NotNil(t, histogram.Schema, "native histogram should be enabled")
+	assert.Len(t, histogram.Bucket, len(prometheus.DefBuckets), "classic buckets should be kept as fallback")
+
+	body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+	assert.Contains(t, body, fmt.Sprintf(`%s_test_%s_bucket{le="0.5"} 1`, metricNamespace, metric))
+}
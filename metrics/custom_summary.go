@@ -3,6 +3,7 @@ package metrics
 import (
 	"time"
 
+	v3 "github.com/phanitejak/kptgolib/metrics/v3"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -18,6 +19,7 @@ type Summary interface {
 type CustomSummary struct {
 	observer  prometheus.Observer
 	collector prometheus.Collector
+	errors    *errorCounter
 }
 
 // GetCollector get the summary
@@ -27,6 +29,9 @@ func (cs *CustomSummary) GetCollector() prometheus.Collector {
 
 // GetCollector get the summaryVec
 func (csv *CustomSummaryVec) GetCollector() prometheus.Collector {
+	if csv.summaryVec == nil {
+		return noopMetric{}
+	}
 	return csv.summaryVec
 }
 
@@ -40,6 +45,7 @@ func (cs *CustomSummary) ObserveDuration(startTime time.Time) {
 
 // Unregister unregisters the summary
 func (cs *CustomSummary) Unregister() bool {
+	cs.errors.unregister()
 	return prometheus.Unregister(cs.collector)
 }
 
@@ -48,29 +54,49 @@ func (cs *CustomSummary) Unregister() bool {
 type CustomSummaryVec struct {
 	summaryVec *prometheus.SummaryVec
 	metricName string
+	errors     *errorCounter
 }
 
 // GetCustomSummary gets custom summary for given labels. Labels has to be given
 // in the same order than registered.
 func (csv *CustomSummaryVec) GetCustomSummary(labelValues ...string) Summary {
+	if csv.summaryVec == nil {
+		return disabledSummary
+	}
 	finalLabelValues := append(labelValues, csv.metricName)
-	return &CustomSummary{csv.summaryVec.WithLabelValues(finalLabelValues...), csv.summaryVec}
+	return &CustomSummary{
+		observer:  csv.summaryVec.WithLabelValues(finalLabelValues...),
+		collector: csv.summaryVec,
+		errors:    csv.errors.with(labelValues...),
+	}
 }
 
 // DeleteSerie deletes custom summary for given labels. Labels has to be given
 // in the same order than registered.
 func (csv *CustomSummaryVec) DeleteSerie(labelValues ...string) bool {
+	if csv.summaryVec == nil {
+		return false
+	}
+	csv.errors.deleteSeries(labelValues...)
 	finalLabelValues := append(labelValues, csv.metricName)
 	return csv.summaryVec.DeleteLabelValues(finalLabelValues...)
 }
 
 // Reset deletes all metrics in this summary vector.
 func (csv *CustomSummaryVec) Reset() {
+	if csv.summaryVec == nil {
+		return
+	}
+	csv.errors.reset()
 	csv.summaryVec.Reset()
 }
 
 // Unregister unregisters the summaryVec.
 func (csv *CustomSummaryVec) Unregister() bool {
+	if csv.summaryVec == nil {
+		return false
+	}
+	csv.errors.unregister()
 	return prometheus.Unregister(csv.summaryVec)
 }
 
@@ -78,46 +104,33 @@ func (csv *CustomSummaryVec) Unregister() bool {
 // and metric description. NEO metrics namespace is added to metric name as
 // prefix.
 func RegisterSummary(metricName string, subsystem string, desc string) Summary {
-	summary := prometheus.NewSummary(prometheus.SummaryOpts{
-		Namespace: metricNamespace,
-		Subsystem: subsystem,
-		Name:      metricName,
-		Help:      desc,
-	})
-
-	return registerSummaryMetric(summary)
+	if Disabled() {
+		return disabledSummary
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindSummary), v3.WithSubsystem(subsystem), v3.WithHelp(desc))
+	return &CustomSummary{observer: m.Collector().(prometheus.Summary), collector: m.Collector()}
 }
 
 // RegisterSummaryWithObjectives registers given summary metric by using given subsystem name
 // , metric description and the quantile rank. NEO metrics namespace is added to metric name as
 // prefix. It gives option to configure quantities.
 func RegisterSummaryWithObjectives(metricName string, subsystem string, desc string, objectives map[float64]float64) Summary {
-	summary := prometheus.NewSummary(prometheus.SummaryOpts{
-		Namespace:  metricNamespace,
-		Subsystem:  subsystem,
-		Name:       metricName,
-		Help:       desc,
-		Objectives: objectives,
-	})
-	return registerSummaryMetric(summary)
-}
-
-func registerSummaryMetric(summary prometheus.Summary) Summary {
-	prometheus.MustRegister(summary)
-	return &CustomSummary{summary, summary}
+	if Disabled() {
+		return disabledSummary
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindSummary),
+		v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithObjectives(objectives))
+	return &CustomSummary{observer: m.Collector().(prometheus.Summary), collector: m.Collector()}
 }
 
 // RegisterSummaryVec registers given summary vector metric by using given keys,
 // subsystem name and metric description. NEO metrics namespace is added to
 // metric name as prefix.
 func RegisterSummaryVec(metricName string, subsystem string, desc string, keys ...string) *CustomSummaryVec {
-	finalKeys := append(keys, plainMetricNameKey)
-	summaryVec := prometheus.NewSummaryVec(prometheus.SummaryOpts{
-		Namespace: metricNamespace,
-		Subsystem: subsystem,
-		Name:      metricName,
-		Help:      desc,
-	}, finalKeys)
-	prometheus.MustRegister(summaryVec)
-	return &CustomSummaryVec{summaryVec, metricName}
+	if Disabled() {
+		return disabledSummaryVec
+	}
+	m := mustRegister(metricName, v3.WithKind(v3.KindSummary),
+		v3.WithSubsystem(subsystem), v3.WithHelp(desc), v3.WithLabels(keys...))
+	return &CustomSummaryVec{summaryVec: m.Collector().(*prometheus.SummaryVec), metricName: metricName}
 }
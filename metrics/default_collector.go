@@ -11,6 +11,9 @@ type defaultCollector struct {
 	timeZoneDesc    *prometheus.Desc
 	numCpusDesc     *prometheus.Desc
 	numCgoCallsDesc *prometheus.Desc
+	uptimeDesc      *prometheus.Desc
+	shutdownDesc    *prometheus.Desc
+	cgroup          prometheus.Collector
 }
 
 // Describe returns all descriptions of the collector.
@@ -18,6 +21,9 @@ func (c *defaultCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.timeZoneDesc
 	ch <- c.numCpusDesc
 	ch <- c.numCgoCallsDesc
+	ch <- c.uptimeDesc
+	ch <- c.shutdownDesc
+	c.cgroup.Describe(ch)
 }
 
 // Collect returns the current state of all metrics of the collector.
@@ -39,6 +45,21 @@ func (c *defaultCollector) Collect(ch chan<- prometheus.Metric) {
 		prometheus.GaugeValue,
 		float64(runtime.NumCgoCall()),
 	)
+	ch <- prometheus.MustNewConstMetric(
+		c.uptimeDesc,
+		prometheus.GaugeValue,
+		now.Sub(processStartTime).Seconds(),
+	)
+	shutdown := 0.0
+	if ShutdownStarted() {
+		shutdown = 1
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.shutdownDesc,
+		prometheus.GaugeValue,
+		shutdown,
+	)
+	c.cgroup.Collect(ch)
 }
 
 func newDefaultCollector() *defaultCollector {
@@ -46,5 +67,8 @@ func newDefaultCollector() *defaultCollector {
 		timeZoneDesc:    prometheus.NewDesc("timezone_offset_milliseconds", "Timezone offset in milliseconds. Zone name abbreviation is stored in zone_name label.", []string{"zone_name"}, nil),
 		numCpusDesc:     prometheus.NewDesc("process_cpu_count", "Number of logical CPUs usable by the current process.", nil, nil),
 		numCgoCallsDesc: prometheus.NewDesc("process_cgo_calls", "Number of cgo calls made by the current process.", nil, nil),
+		uptimeDesc:      prometheus.NewDesc("process_uptime_seconds", "Time since the process started in seconds.", nil, nil),
+		shutdownDesc:    prometheus.NewDesc("graceful_shutdown_started", "Set to 1 when graceful shutdown of the process has started.", nil, nil),
+		cgroup:          NewCgroupCollector(DefaultCgroupRoot),
 	}
 }
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// A DependencyRule maps outgoing requests to a logical dependency name used
+// as clientName label of HTTP client metrics.
+type DependencyRule struct {
+	// Condition is matched against host (including port) and path of the
+	// request URL, e.g. "credentials.default.svc:8080/api/v1/credentials".
+	Condition *regexp.Regexp
+	// Name is the logical dependency name, e.g. "credentials-service".
+	Name string
+}
+
+var (
+	dependencyMutex sync.RWMutex
+	dependencyRules []DependencyRule
+)
+
+// RegisterDependency registers logical dependency name for outgoing requests
+// matching given pattern. Rules are evaluated in registration order and the
+// first matching one is used. Requests not matching any rule are reported
+// with target hostname. Rules are not registered when metrics are disabled.
+func RegisterDependency(name string, pattern *regexp.Regexp) {
+	if Disabled() {
+		return
+	}
+	dependencyMutex.Lock()
+	defer dependencyMutex.Unlock()
+	dependencyRules = append(dependencyRules, DependencyRule{Condition: pattern, Name: name})
+}
+
+// RegisterDependencyHosts registers logical dependency name for outgoing
+// requests to any of given hostnames.
+func RegisterDependencyHosts(name string, hosts ...string) {
+	for _, host := range hosts {
+		RegisterDependency(name, regexp.MustCompile(`^`+regexp.QuoteMeta(host)+`(:\d+)?(/|$)`))
+	}
+}
+
+// ResetDependencies removes all registered dependency rules.
+func ResetDependencies() {
+	dependencyMutex.Lock()
+	defer dependencyMutex.Unlock()
+	dependencyRules = nil
+}
+
+// dependencyName returns logical dependency name for given request URL,
+// falling back to the sanitized hostname.
+func dependencyName(u *url.URL) string {
+	dependencyMutex.RLock()
+	defer dependencyMutex.RUnlock()
+
+	target := u.Host + u.Path
+	for _, rule := range dependencyRules {
+		if rule.Condition.MatchString(target) {
+			return rule.Name
+		}
+	}
+	return SanitizeLabelValue(u.Hostname())
+}
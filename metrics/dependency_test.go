@@ -0,0 +1,46 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ExampleRegisterDependency() {
+	// Requests to any credentials service instance are reported with clientName="credentials-service"
+	metrics.RegisterDependency("credentials-service", regexp.MustCompile(`^credentials[^/]*/api/`))
+	// Requests to given hosts are reported with clientName="vault"
+	metrics.RegisterDependencyHosts("vault", "vault.default.svc", "10.0.0.12")
+}
+
+func TestRegisterDependency(t *testing.T) {
+	t.Cleanup(metrics.ResetDependencies)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	metrics.RegisterDependency("path-dependency", regexp.MustCompile(`/dependency/path$`))
+	metrics.RegisterDependencyHosts("host-dependency", "localhost")
+
+	client := metrics.NewInstrumentedDefaultHttpClient()
+	for _, u := range []string{
+		srv.URL + "/dependency/path",
+		strings.Replace(srv.URL, targetHost, "localhost", 1) + "/dependency/host",
+		srv.URL + "/dependency/fallback",
+	} {
+		resp, err := client.Get(u)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	body := getMetricResponse(t, testServerURLPrefix+metrics.DefaultEndPoint)
+	assert.Contains(t, body, `http_client_requests_seconds_count{clientName="path-dependency",method="GET",status="200",uri="/dependency/path"} 1`)
+	assert.Contains(t, body, `http_client_requests_seconds_count{clientName="host-dependency",method="GET",status="200",uri="/dependency/host"} 1`)
+	assert.Contains(t, body, `http_client_requests_seconds_count{clientName="127.0.0.1",method="GET",status="200",uri="/dependency/fallback"} 1`)
+}
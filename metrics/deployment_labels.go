@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DeploymentLabelsEnv is the environment variable, which enables deployment labels like EnableDeploymentLabels
+// when set to true.
+const DeploymentLabelsEnv = "METRICS_DEPLOYMENT_LABELS"
+
+// Environment variables describing the deployment, which are used by DeploymentLabels.
+const (
+	DeploymentEnvEnv = "DEPLOYMENT_ENV"
+	RegionEnv        = "REGION"
+	ClusterEnv       = "CLUSTER"
+)
+
+// Label names used for deployment metadata added by DeploymentLabels.
+const (
+	DeploymentEnvLabel = "deployment_env"
+	RegionLabel        = "region"
+	ClusterLabel       = "cluster"
+)
+
+// deploymentLabels holds label pairs added to gathered metrics, nil slice when deployment labels are not enabled.
+var deploymentLabels atomic.Value
+
+func init() {
+	if enabled, _ := strconv.ParseBool(os.Getenv(DeploymentLabelsEnv)); enabled {
+		EnableDeploymentLabels()
+	}
+}
+
+// DeploymentLabels returns deployment_env, region and cluster labels read from DEPLOYMENT_ENV, REGION and CLUSTER
+// environment variables. Variables which are not set are left out from the result.
+func DeploymentLabels() prometheus.Labels {
+	labels := prometheus.Labels{}
+	for label, env := range map[string]string{DeploymentEnvLabel: DeploymentEnvEnv, RegionLabel: RegionEnv, ClusterLabel: ClusterEnv} {
+		if value := os.Getenv(env); value != "" {
+			labels[label] = value
+		}
+	}
+	return labels
+}
+
+// EnableDeploymentLabels adds DeploymentLabels, read when it is called, to all metrics exposed by the handlers,
+// pushers and writers of the package, so that dashboards spanning environments don't depend on relabeling of
+// scrape configurations. Metrics having a label with the same name keep their own value. With
+// METRICS_DEPLOYMENT_LABELS=true deployment labels are enabled already at initialization.
+func EnableDeploymentLabels() {
+	labels := DeploymentLabels()
+	pairs := make([]*dto.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		name, value := name, value
+		pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+	}
+	deploymentLabels.Store(pairs)
+}
+
+// Gatherer returns the default gatherer, which adds deployment labels to gathered metrics when they are enabled.
+// Use it instead of prometheus.DefaultGatherer to expose metrics of the default registry.
+func Gatherer() prometheus.Gatherer {
+	return deploymentLabelsGatherer{prometheus.DefaultGatherer}
+}
+
+type deploymentLabelsGatherer struct {
+	prometheus.Gatherer
+}
+
+func (g deploymentLabelsGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	pairs, _ := deploymentLabels.Load().([]*dto.LabelPair)
+	if len(pairs) == 0 {
+		return families, err
+	}
+
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			m.Label = withDeploymentLabels(m.GetLabel(), pairs)
+		}
+	}
+	return families, err
+}
+
+func withDeploymentLabels(labels []*dto.LabelPair, pairs []*dto.LabelPair) []*dto.LabelPair {
+	existing := make(map[string]bool, len(labels))
+	for _, lp := range labels {
+		existing[lp.GetName()] = true
+	}
+	added := false
+	for _, lp := range pairs {
+		if !existing[lp.GetName()] {
+			labels = append(labels, lp)
+			added = true
+		}
+	}
+	if added {
+		sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+	}
+	return labels
+}
@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentLabelsFromEnv(t *testing.T) {
+	t.Setenv(metrics.DeploymentEnvEnv, "staging")
+	t.Setenv(metrics.RegionEnv, "eu-west-1")
+	t.Setenv(metrics.ClusterEnv, "")
+
+	assert.Equal(t, map[string]string{"deployment_env": "staging", "region": "eu-west-1"}, map[string]string(metrics.DeploymentLabels()))
+}
+
+// TestDeploymentLabels runs TestDeploymentLabelsEnabled in a new process, because deployment labels can't be disabled.
+func TestDeploymentLabels(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDeploymentLabelsEnabled$", "-test.v")
+	cmd.Env = append(os.Environ(), metrics.DeploymentLabelsEnv+"=true", metrics.DeploymentEnvEnv+"=production",
+		metrics.RegionEnv+"=eu-north-1", metrics.ClusterEnv+"=blue")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Contains(t, string(out), "--- PASS: TestDeploymentLabelsEnabled")
+}
+
+func TestDeploymentLabelsEnabled(t *testing.T) {
+	if os.Getenv(metrics.DeploymentLabelsEnv) != "true" {
+		t.Skip("run by TestDeploymentLabels")
+	}
+
+	metrics.RegisterCounter("deployment_labeled_total", "test", "help").Inc()
+	metrics.RegisterCounterVecWithConstLabels("deployment_overridden_total", "test", "help",
+		map[string]string{metrics.RegionLabel: "own"}).GetCustomCounter().Inc()
+
+	server := httptest.NewServer(metrics.GetMetricsHandler())
+	defer server.Close()
+	families, err := metrics.Scrape(server.URL)
+	require.NoError(t, err)
+
+	for _, family := range []string{"com_metrics_test_deployment_labeled_total", "go_goroutines", "http_server_requests_duration_seconds"} {
+		for _, m := range families[family].GetMetric() {
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			assert.Equal(t, "production", labels[metrics.DeploymentEnvLabel], family)
+			assert.Equal(t, "eu-north-1", labels[metrics.RegionLabel], family)
+			assert.Equal(t, "blue", labels[metrics.ClusterLabel], family)
+		}
+	}
+	require.NotEmpty(t, families["com_metrics_test_deployment_labeled_total"].GetMetric())
+
+	value, ok := families.Value("com_metrics_test_deployment_overridden_total", map[string]string{
+		metrics.RegionLabel: "own", metrics.ClusterLabel: "blue",
+	})
+	require.True(t, ok, "metric label should take precedence over deployment label")
+	assert.Equal(t, 1.0, value)
+}
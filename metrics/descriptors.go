@@ -0,0 +1,298 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Error counters are alerted on by GenerateAlertRules when their name contains one of these words.
+var errorCounterWords = []string{"error", "fail", "dropped", "timeout", "rejected"}
+
+// AlertRules is a Prometheus alerting rules file. Rule files are YAML, but JSON is valid YAML too.
+type AlertRules struct {
+	Groups []AlertRuleGroup `json:"groups"`
+}
+
+// AlertRuleGroup is a named group of alerting rules.
+type AlertRuleGroup struct {
+	Name  string      `json:"name"`
+	Rules []AlertRule `json:"rules"`
+}
+
+// AlertRule is a Prometheus alerting rule.
+type AlertRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Dashboard is a Grafana dashboard skeleton, which can be imported as JSON.
+type Dashboard struct {
+	Title         string           `json:"title"`
+	Tags          []string         `json:"tags"`
+	SchemaVersion int              `json:"schemaVersion"`
+	Time          DashboardTime    `json:"time"`
+	Panels        []DashboardPanel `json:"panels"`
+}
+
+// DashboardTime is the default time range of a dashboard.
+type DashboardTime struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DashboardPanel is a Grafana panel. Panels of type row group the panels following them.
+type DashboardPanel struct {
+	ID          int               `json:"id"`
+	Title       string            `json:"title"`
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	GridPos     DashboardGridPos  `json:"gridPos"`
+	Targets     []DashboardTarget `json:"targets,omitempty"`
+}
+
+// DashboardGridPos is position and size of a panel.
+type DashboardGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// DashboardTarget is a Prometheus query of a panel.
+type DashboardTarget struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+}
+
+// GatherMetricFamilies gathers metric families from given gatherer, e.g. prometheus.DefaultGatherer
+// to generate descriptors of metrics registered in the service itself.
+func GatherMetricFamilies(gatherer prometheus.Gatherer) (MetricFamilies, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	mf := MetricFamilies{}
+	for _, family := range families {
+		mf[family.GetName()] = family
+	}
+	return mf, nil
+}
+
+// GenerateAlertRules generates alerting rules skeleton for metrics of given service. Metrics are selected by
+// job label with service name. Alerts are generated for 5xx ratio of http_server_requests_duration_seconds,
+// SLO violation ratio of http_server_slo_requests_total and increase of every counter which name refers to errors,
+// e.g. http_server_timeouts_total or kafka dropped messages. Thresholds are meant to be adjusted.
+func GenerateAlertRules(service string, families MetricFamilies) AlertRules {
+	selector := fmt.Sprintf(`job=%q`, service)
+	labels := map[string]string{"severity": "warning"}
+	var rules []AlertRule
+
+	if _, ok := families[metricHTTPRequestsDurationName]; ok {
+		rules = append(rules, AlertRule{
+			Alert: "HTTPServerErrorRate",
+			Expr: fmt.Sprintf(`sum(rate(%[1]s_count{%[2]s,status=~"5.."}[5m])) / sum(rate(%[1]s_count{%[2]s}[5m])) > 0.05`,
+				metricHTTPRequestsDurationName, selector),
+			For:         "10m",
+			Labels:      labels,
+			Annotations: map[string]string{"summary": "More than 5% of HTTP requests of " + service + " fail with 5xx status."},
+		})
+	}
+	if _, ok := families[metricHTTPSLORequestsName]; ok {
+		rules = append(rules, AlertRule{
+			Alert: "HTTPServerSLOViolations",
+			Expr: fmt.Sprintf(`sum(rate(%s{%s}[1h])) / sum(rate(%s{%s}[1h])) > 0.01`,
+				metricHTTPSLOViolationsName, selector, metricHTTPSLORequestsName, selector),
+			For:         "5m",
+			Labels:      labels,
+			Annotations: map[string]string{"summary": "More than 1% of HTTP requests of " + service + " violate SLO."},
+		})
+	}
+	for _, name := range sortedFamilyNames(families) {
+		family := families[name]
+		if family.GetType() != dto.MetricType_COUNTER || !isErrorCounter(name) {
+			continue
+		}
+		rules = append(rules, AlertRule{
+			Alert:       alertName(name),
+			Expr:        fmt.Sprintf(`sum(increase(%s{%s}[5m])) > 0`, name, selector),
+			For:         "5m",
+			Labels:      labels,
+			Annotations: map[string]string{"summary": family.GetHelp()},
+		})
+	}
+
+	return AlertRules{Groups: []AlertRuleGroup{{Name: service, Rules: rules}}}
+}
+
+// GenerateDashboard generates Grafana dashboard skeleton for metrics of given service with rows for
+// HTTP server metrics, Kafka metrics and other custom metrics. Go runtime and process metrics are left out.
+// Counters are shown as rates, summaries as averages and histograms as 95th percentiles by their labels.
+func GenerateDashboard(service string, families MetricFamilies) Dashboard {
+	d := dashboardBuilder{selector: fmt.Sprintf(`job=%q`, service)}
+
+	if _, ok := families[metricHTTPRequestsDurationName]; ok {
+		d.row("HTTP server")
+		d.panel("Request rate", "", DashboardTarget{
+			Expr:         fmt.Sprintf(`sum by (uri) (rate(%s_count{%s}[5m]))`, metricHTTPRequestsDurationName, d.selector),
+			LegendFormat: "{{uri}}",
+		})
+		d.panel("Error rate", "", DashboardTarget{
+			Expr:         fmt.Sprintf(`sum by (uri) (rate(%s_count{%s,status=~"5.."}[5m]))`, metricHTTPRequestsDurationName, d.selector),
+			LegendFormat: "{{uri}}",
+		})
+		d.panel("Average latency", "", DashboardTarget{
+			Expr: fmt.Sprintf(`sum by (uri) (rate(%[1]s_sum{%[2]s}[5m])) / sum by (uri) (rate(%[1]s_count{%[2]s}[5m]))`,
+				metricHTTPRequestsDurationName, d.selector),
+			LegendFormat: "{{uri}}",
+		})
+	}
+
+	var kafka, custom []string
+	for _, name := range sortedFamilyNames(families) {
+		switch {
+		case name == metricHTTPRequestsDurationName, strings.HasPrefix(name, "go_"),
+			strings.HasPrefix(name, "process_"), strings.HasPrefix(name, "promhttp_"):
+			// Already shown in HTTP server row or left out.
+		case strings.HasPrefix(name, "kafka_"), strings.HasPrefix(name, metricNamespace+"_kafka_"):
+			kafka = append(kafka, name)
+		default:
+			custom = append(custom, name)
+		}
+	}
+	if len(kafka) > 0 {
+		d.row("Kafka")
+		for _, name := range kafka {
+			d.familyPanel(families[name])
+		}
+	}
+	if len(custom) > 0 {
+		d.row("Custom metrics")
+		for _, name := range custom {
+			d.familyPanel(families[name])
+		}
+	}
+
+	return Dashboard{
+		Title:         service,
+		Tags:          []string{service, "generated"},
+		SchemaVersion: 36,
+		Time:          DashboardTime{From: "now-6h", To: "now"},
+		Panels:        d.panels,
+	}
+}
+
+type dashboardBuilder struct {
+	selector string
+	panels   []DashboardPanel
+	column   int
+	y        int
+}
+
+func (d *dashboardBuilder) row(title string) {
+	if d.column > 0 {
+		d.column = 0
+		d.y += 8
+	}
+	d.panels = append(d.panels, DashboardPanel{ID: len(d.panels) + 1, Title: title, Type: "row",
+		GridPos: DashboardGridPos{H: 1, W: 24, Y: d.y}})
+	d.y++
+}
+
+func (d *dashboardBuilder) panel(title, description string, target DashboardTarget) {
+	target.RefID = "A"
+	d.panels = append(d.panels, DashboardPanel{
+		ID:          len(d.panels) + 1,
+		Title:       title,
+		Type:        "timeseries",
+		Description: description,
+		GridPos:     DashboardGridPos{H: 8, W: 12, X: d.column * 12, Y: d.y},
+		Targets:     []DashboardTarget{target},
+	})
+	d.column++
+	if d.column == 2 {
+		d.column = 0
+		d.y += 8
+	}
+}
+
+func (d *dashboardBuilder) familyPanel(family *dto.MetricFamily) {
+	name := family.GetName()
+	labels := familyLabels(family)
+	by := ""
+	legend := ""
+	if len(labels) > 0 {
+		by = " by (" + strings.Join(labels, ", ") + ") "
+		legend = "{{" + strings.Join(labels, "}} {{") + "}}"
+	}
+
+	var expr string
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		expr = fmt.Sprintf(`sum%s(rate(%s{%s}[5m]))`, by, name, d.selector)
+	case dto.MetricType_SUMMARY:
+		expr = fmt.Sprintf(`sum%[1]s(rate(%[2]s_sum{%[3]s}[5m])) / sum%[1]s(rate(%[2]s_count{%[3]s}[5m]))`, by, name, d.selector)
+	case dto.MetricType_HISTOGRAM:
+		expr = fmt.Sprintf(`histogram_quantile(0.95, sum by (%s) (rate(%s_bucket{%s}[5m])))`,
+			strings.Join(append(labels, "le"), ", "), name, d.selector)
+	default:
+		expr = fmt.Sprintf(`sum%s(%s{%s})`, by, name, d.selector)
+	}
+	d.panel(name, family.GetHelp(), DashboardTarget{Expr: expr, LegendFormat: legend})
+}
+
+// familyLabels returns sorted label names of metrics in the family.
+func familyLabels(family *dto.MetricFamily) []string {
+	set := map[string]bool{}
+	for _, m := range family.GetMetric() {
+		for _, l := range m.GetLabel() {
+			set[l.GetName()] = true
+		}
+	}
+	labels := make([]string, 0, len(set))
+	for l := range set {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func sortedFamilyNames(families MetricFamilies) []string {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isErrorCounter(name string) bool {
+	for _, word := range errorCounterWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// alertName converts metric name to CamelCase alert name without namespace and _total suffix,
+// e.g. com_metrics_kafka_dropped_messages_total to KafkaDroppedMessages.
+func alertName(metric string) string {
+	metric = strings.TrimPrefix(metric, metricNamespace+"_")
+	metric = strings.TrimSuffix(metric, "_total")
+	var b strings.Builder
+	for _, part := range strings.Split(metric, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+func TestGenerateDescriptors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "job_duration_seconds", Help: "Job duration."}, []string{"job"})
+	histogram.WithLabelValues("import").Observe(1)
+	queue := prometheus.NewGauge(prometheus.GaugeOpts{Name: "queue_length", Help: "Queue length."})
+	failures := prometheus.NewCounter(prometheus.CounterOpts{Name: "import_failures_total", Help: "Failed imports."})
+	registry.MustRegister(histogram, queue, failures, prometheus.NewGoCollector())
+
+	families, err := metrics.GatherMetricFamilies(registry)
+	require.NoError(t, err)
+
+	rules := metrics.GenerateAlertRules("importer", families)
+	require.Len(t, rules.Groups, 1)
+	require.Len(t, rules.Groups[0].Rules, 1)
+	assert.Equal(t, "ImportFailures", rules.Groups[0].Rules[0].Alert)
+	assert.Equal(t, "Failed imports.", rules.Groups[0].Rules[0].Annotations["summary"])
+
+	dashboard := metrics.GenerateDashboard("importer", families)
+	assert.Equal(t, "importer", dashboard.Title)
+	require.Len(t, dashboard.Panels, 4, "go runtime metrics should be left out")
+	assert.Equal(t, "row", dashboard.Panels[0].Type)
+	assert.Equal(t, `sum(rate(import_failures_total{job="importer"}[5m]))`, dashboard.Panels[1].Targets[0].Expr)
+	assert.Equal(t, `histogram_quantile(0.95, sum by (job, le) (rate(job_duration_seconds_bucket{job="importer"}[5m])))`,
+		dashboard.Panels[2].Targets[0].Expr)
+	assert.Equal(t, `sum(queue_length{job="importer"})`, dashboard.Panels[3].Targets[0].Expr)
+	assert.Equal(t, metrics.DashboardGridPos{H: 8, W: 12, X: 12, Y: 1}, dashboard.Panels[2].GridPos)
+}
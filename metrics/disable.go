@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DisabledEnv is the environment variable, which disables metrics like Disable when set to true.
+const DisabledEnv = "METRICS_DISABLED"
+
+var (
+	disabled = isDisabledByEnv()
+
+	disabledDesc         = prometheus.NewInvalidDesc(errors.New("metrics are disabled"))
+	disabledCounter      = noopCounter{}
+	disabledGauge        = &CustomGauge{noopMetric{}}
+	disabledGaugeVec     = &CustomGaugeVec{}
+	disabledSummary      = &CustomSummary{observer: noopMetric{}, collector: noopMetric{}}
+	disabledSummaryVec   = &CustomSummaryVec{}
+	disabledHistogram    = &CustomHistogram{observer: noopMetric{}, collector: noopMetric{}}
+	disabledHistogramVec = &CustomHistogramVec{}
+	disabledFuncMetric   = &FuncMetric{noopMetric{}}
+)
+
+// Disable makes Register*, TryRegister* and InstrumentHTTPHandler* functions called afterwards return shared
+// no-op metrics and handlers, so that CLIs and batch tools importing service code don't register metrics.
+// Observing no-op metrics doesn't allocate, apart from label values passed to vectors. HTTP client metrics
+// are registered on first use of an instrumented client, which sends requests without instrumentation when
+// metrics are disabled, and RegisterDependency ignores rules. Metrics registered before are not affected,
+// so Disable should be called at the beginning of main. With METRICS_DISABLED=true metrics are disabled
+// already at initialization, so the HTTP server metrics of the package are not registered either.
+// Metrics can't be enabled again.
+func Disable() {
+	disabled.Store(true)
+}
+
+// Disabled reports whether metrics are disabled by Disable or METRICS_DISABLED.
+func Disabled() bool {
+	return disabled.Load()
+}
+
+func isDisabledByEnv() *atomic.Bool {
+	b := &atomic.Bool{}
+	value, _ := strconv.ParseBool(os.Getenv(DisabledEnv))
+	b.Store(value)
+	return b
+}
+
+// noopMetric implements prometheus.Counter, prometheus.Gauge and prometheus.Observer without collecting anything.
+type noopMetric struct{}
+
+func (noopMetric) Desc() *prometheus.Desc           { return disabledDesc }
+func (noopMetric) Write(*dto.Metric) error          { return nil }
+func (noopMetric) Describe(chan<- *prometheus.Desc) {}
+func (noopMetric) Collect(chan<- prometheus.Metric) {}
+func (noopMetric) Set(float64)                      {}
+func (noopMetric) Inc()                             {}
+func (noopMetric) Dec()                             {}
+func (noopMetric) Add(float64)                      {}
+func (noopMetric) Sub(float64)                      {}
+func (noopMetric) SetToCurrentTime()                {}
+func (noopMetric) Observe(float64)                  {}
+
+// noopCounter implements Counter and CounterVec.
+type noopCounter struct{}
+
+func (noopCounter) Add(int64)                          {}
+func (noopCounter) Inc()                               {}
+func (noopCounter) Unregister() bool                   { return false }
+func (noopCounter) GetCollector() prometheus.Collector { return noopMetric{} }
+func (noopCounter) GetCustomCounter(...string) Counter { return disabledCounter }
+func (noopCounter) DeleteSerie(...string) bool         { return false }
+func (noopCounter) Reset()                             {}
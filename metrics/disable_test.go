@@ -0,0 +1,97 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDisabled runs TestDisabledMetrics in a new process, because metrics can't be enabled again.
+func TestDisabled(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDisabledMetrics$", "-test.v")
+	cmd.Env = append(os.Environ(), metrics.DisabledEnv+"=true")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Contains(t, string(out), "--- PASS: TestDisabledMetrics")
+}
+
+func TestDisabledMetrics(t *testing.T) {
+	if os.Getenv(metrics.DisabledEnv) != "true" {
+		t.Skip("run by TestDisabled")
+	}
+	require.True(t, metrics.Disabled())
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		assert.NotContains(t, f.GetName(), "com_metrics_", "metrics should not be registered")
+	}
+
+	counter := metrics.RegisterCounter("disabled_total", "test", "help")
+	counterVec := metrics.RegisterCounterVec("disabled_total", "test", "help", "key")
+	gauge := metrics.RegisterGauge("disabled", "test", "help")
+	gaugeVec := metrics.RegisterGaugeVec("disabled", "test", "help", "key")
+	summary := metrics.RegisterSummaryWithErrors("disabled", "test", "help")
+	summaryVec := metrics.RegisterSummaryVec("disabled", "test", "help", "key")
+	histogram := metrics.RegisterHistogram("disabled", "test", "help", nil)
+	histogramVec := metrics.RegisterHistogramVecWithErrors("disabled", "test", "help", nil, "key")
+	gaugeFunc := metrics.RegisterGaugeFunc("disabled_func", "test", "help", func() float64 { return 1 })
+	tryGauge, err := metrics.TryRegisterGauge("disabled", "test", "help")
+	require.NoError(t, err)
+
+	start := time.Now()
+	allocs := testing.AllocsPerRun(100, func() {
+		counter.Inc()
+		gauge.Set(1)
+		summary.ObserveWithError(1, nil)
+		histogram.Observe(1)
+		tryGauge.Sub(1)
+	})
+	assert.Zero(t, allocs)
+	counterVec.GetCustomCounter("a").Add(2)
+	gaugeVec.GetCustomGauge("a").Add(1)
+	summaryVec.GetCustomSummary("a").ObserveDuration(start)
+	histogramVec.ObserveWithError(1, nil, "a")
+
+	for _, m := range []metrics.CustomMetric{counter, counterVec, gauge, gaugeVec, summary, summaryVec, histogram, histogramVec, gaugeFunc} {
+		require.NoError(t, prometheus.NewRegistry().Register(m.GetCollector()))
+	}
+	assert.False(t, gaugeVec.DeleteSerie("a"))
+	gaugeVec.Reset()
+	assert.False(t, histogramVec.Unregister())
+	assert.False(t, gaugeFunc.Unregister())
+
+	families, err = prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		assert.NotContains(t, f.GetName(), "com_metrics_", "metrics should not be registered")
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := metrics.InstrumentHTTPHandler(next)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	srv := httptest.NewServer(next)
+	defer srv.Close()
+	metrics.RegisterDependency("disabled", regexp.MustCompile(".*"))
+	resp, err := metrics.NewInstrumentedDefaultHttpClient().Get(srv.URL+"/{id}", "1")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	families, err = prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		assert.NotContains(t, f.GetName(), "http_client_", "client metrics should not be registered")
+	}
+}
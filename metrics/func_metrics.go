@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FuncMetric is a metric, which value is evaluated by a function at scrape time.
+type FuncMetric struct {
+	collector prometheus.Collector
+}
+
+// GetCollector get the collector of the metric.
+func (fm *FuncMetric) GetCollector() prometheus.Collector {
+	return fm.collector
+}
+
+// Unregister unregisters the metric.
+func (fm *FuncMetric) Unregister() bool {
+	return prometheus.Unregister(fm.collector)
+}
+
+// LabeledValue is a value of a vector metric with label values given in the same order than registered keys.
+type LabeledValue struct {
+	LabelValues []string
+	Value       float64
+}
+
+// RegisterGaugeFunc registers gauge metric, which value is returned by fn at scrape time, by using
+// given subsystem name and metric description. It can be used for values like queue length or cache size,
+// which would otherwise need a goroutine keeping a gauge in sync. fn must be safe for concurrent use.
+// NEO metrics namespace is added to metric name as prefix.
+func RegisterGaugeFunc(metricName string, subsystem string, desc string, fn func() float64) *FuncMetric {
+	if Disabled() {
+		return disabledFuncMetric
+	}
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}, fn)
+	prometheus.MustRegister(gauge)
+	return &FuncMetric{gauge}
+}
+
+// RegisterCounterFunc registers counter metric, which value is returned by fn at scrape time, by using
+// given subsystem name and metric description. Value returned by fn must never decrease.
+// fn must be safe for concurrent use. NEO metrics namespace is added to metric name as prefix.
+func RegisterCounterFunc(metricName string, subsystem string, desc string, fn func() float64) *FuncMetric {
+	if Disabled() {
+		return disabledFuncMetric
+	}
+	counter := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}, fn)
+	prometheus.MustRegister(counter)
+	return &FuncMetric{counter}
+}
+
+// RegisterGaugeVecFunc registers gauge vector metric, which values are returned by fn at scrape time,
+// by using given keys, subsystem name and metric description. fn must be safe for concurrent use.
+// NEO metrics namespace is added to metric name as prefix.
+func RegisterGaugeVecFunc(metricName string, subsystem string, desc string, fn func() []LabeledValue, keys ...string) *FuncMetric {
+	return registerVecFunc(metricName, subsystem, desc, prometheus.GaugeValue, fn, keys)
+}
+
+// RegisterCounterVecFunc registers counter vector metric, which values are returned by fn at scrape time,
+// by using given keys, subsystem name and metric description. Values returned by fn must never decrease.
+// fn must be safe for concurrent use. NEO metrics namespace is added to metric name as prefix.
+func RegisterCounterVecFunc(metricName string, subsystem string, desc string, fn func() []LabeledValue, keys ...string) *FuncMetric {
+	return registerVecFunc(metricName, subsystem, desc, prometheus.CounterValue, fn, keys)
+}
+
+func registerVecFunc(metricName string, subsystem string, desc string, valueType prometheus.ValueType,
+	fn func() []LabeledValue, keys []string,
+) *FuncMetric {
+	if Disabled() {
+		return disabledFuncMetric
+	}
+	c := &vecFuncCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(metricNamespace, subsystem, metricName), desc,
+			withPlainMetricNameKey(keys), nil),
+		metricName: metricName,
+		valueType:  valueType,
+		fn:         fn,
+	}
+	prometheus.MustRegister(c)
+	return &FuncMetric{c}
+}
+
+// vecFuncCollector collects values of a vector metric returned by fn.
+type vecFuncCollector struct {
+	desc       *prometheus.Desc
+	metricName string
+	valueType  prometheus.ValueType
+	fn         func() []LabeledValue
+}
+
+func (c *vecFuncCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *vecFuncCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range c.fn() {
+		labelValues := append(append([]string{}, v.LabelValues...), c.metricName)
+		m, err := prometheus.NewConstMetric(c.desc, c.valueType, v.Value, labelValues...)
+		if err != nil {
+			m = prometheus.NewInvalidMetric(c.desc, err)
+		}
+		ch <- m
+	}
+}
@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncMetrics(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+
+	var queueLength, processed atomic.Int64
+	gauge := metrics.RegisterGaugeFunc(metric+"_queue_length", "test", "help", func() float64 {
+		return float64(queueLength.Load())
+	})
+	defer gauge.Unregister()
+	counter := metrics.RegisterCounterFunc(metric+"_processed_total", "test", "help", func() float64 {
+		return float64(processed.Load())
+	})
+	defer counter.Unregister()
+	gaugeVec := metrics.RegisterGaugeVecFunc(metric+"_cache_size", "test", "help", func() []metrics.LabeledValue {
+		return []metrics.LabeledValue{
+			{LabelValues: []string{"users"}, Value: 3},
+			{LabelValues: []string{"groups"}, Value: 5},
+		}
+	}, "cache")
+	defer gaugeVec.Unregister()
+
+	queueLength.Store(7)
+	processed.Store(11)
+
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	v, ok := families.Value("com_metrics_test_"+metric+"_queue_length", nil)
+	require.True(t, ok)
+	assert.Equal(t, 7.0, v)
+
+	v, ok = families.Value("com_metrics_test_"+metric+"_processed_total", nil)
+	require.True(t, ok)
+	assert.Equal(t, 11.0, v)
+
+	for cache, expected := range map[string]float64{"users": 3, "groups": 5} {
+		v, ok = families.Value("com_metrics_test_"+metric+"_cache_size",
+			map[string]string{"cache": cache, "_plain_metric_name": metric + "_cache_size"})
+		require.True(t, ok, cache)
+		assert.Equal(t, expected, v, cache)
+	}
+
+	queueLength.Store(1)
+	families, err = metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+	v, _ = families.Value("com_metrics_test_"+metric+"_queue_length", nil)
+	assert.Equal(t, 1.0, v, "value should be evaluated on every scrape")
+}
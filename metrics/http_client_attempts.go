@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	clientMetricHTTPRequestAttemptsName = "http_client_request_attempts_total"
+	// maxAttemptLabel is the highest attempt label value, later attempts are labeled "4+".
+	maxAttemptLabel = 4
+)
+
+var clientAttempts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: clientMetricHTTPRequestAttemptsName,
+		Help: "Total count of http requests by status code, method, URI, host and attempt number: 1, 2, 3 or 4+.",
+	},
+	[]string{"status", "method", "uri", "clientName", "attempt"},
+)
+
+type attemptsKey struct{}
+
+// ContextWithAttempts returns context carrying attempt counter of a request, which retry logic increments
+// with NextAttempt before every attempt. Clients with attempt tracking enabled label requests with the counter,
+// so that retries are visible even when the instrumented transport is wrapped with retry logic.
+func ContextWithAttempts(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptsKey{}, new(int32))
+}
+
+// NextAttempt increments attempt counter of ctx set by ContextWithAttempts and returns the attempt number.
+// Without counter 1 is returned.
+func NextAttempt(ctx context.Context) int {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		return int(atomic.AddInt32(attempts, 1))
+	}
+	return 1
+}
+
+// Attempt returns current attempt number of ctx, 1 if attempts are not counted.
+func Attempt(ctx context.Context) int {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		if attempt := int(atomic.LoadInt32(attempts)); attempt > 0 {
+			return attempt
+		}
+	}
+	return 1
+}
+
+// SetAttemptTracking enables counting requests in http_client_request_attempts_total labeled with attempt number
+// of request context, see ContextWithAttempts. Attempt label is capped to 1, 2, 3 and 4+ to keep cardinality low.
+func (hc *InstrumentedHttpClient) SetAttemptTracking(enabled bool) {
+	hc.trackAttempts = enabled
+}
+
+// DoWithRetry sends req with do, e.g. Do method of an instrumented client, and retries it up to maxAttempts in total
+// after backoff when sending fails or the response status is 429 or 5xx. Attempts are counted in request context,
+// see ContextWithAttempts. Requests with body are retried only if req.GetBody is set. Response of the last attempt
+// is returned, bodies of retried responses are closed.
+func DoWithRetry(req *http.Request, maxAttempts int, backoff time.Duration, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx := ContextWithAttempts(req.Context())
+	req = req.WithContext(ctx)
+	for {
+		attempt := NextAttempt(ctx)
+		resp, err := do(req)
+		if attempt >= maxAttempts || !retryableResponse(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+func retryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func attemptLabel(ctx context.Context) string {
+	if attempt := Attempt(ctx); attempt < maxAttemptLabel {
+		return strconv.Itoa(attempt)
+	}
+	return strconv.Itoa(maxAttemptLabel) + "+"
+}
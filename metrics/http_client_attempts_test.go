@@ -0,0 +1,87 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttempts(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, 1, metrics.Attempt(ctx))
+	assert.Equal(t, 1, metrics.NextAttempt(ctx))
+
+	ctx = metrics.ContextWithAttempts(ctx)
+	assert.Equal(t, 1, metrics.Attempt(ctx))
+	assert.Equal(t, 1, metrics.NextAttempt(ctx))
+	assert.Equal(t, 2, metrics.NextAttempt(ctx))
+	assert.Equal(t, 2, metrics.Attempt(metrics.ContextWithAttempts(ctx)), "existing counter is kept")
+}
+
+func TestDoWithRetryTracksAttempts(t *testing.T) {
+	const path = "/client/attempts/{id}"
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, 4)
+		n, _ := r.Body.Read(body)
+		assert.Equal(t, "ping", string(body[:n]))
+		if atomic.AddInt32(&calls, 1) < 5 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := metrics.NewInstrumentedDefaultHttpClient()
+	client.SetAttemptTracking(true)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/client/attempts/1", strings.NewReader("ping"))
+	require.NoError(t, err)
+
+	resp, err := metrics.DoWithRetry(req, 5, time.Millisecond, func(r *http.Request) (*http.Response, error) {
+		return client.Do(&metrics.HttpRequestTemplate{Request: r, UrlTemplate: path})
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 5, atomic.LoadInt32(&calls))
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		status, attempt string
+		want            float64
+	}{{"503", "1", 1}, {"503", "2", 1}, {"503", "3", 1}, {"503", "4+", 1}, {"200", "4+", 1}} {
+		value, ok := families.Value("http_client_request_attempts_total", map[string]string{"uri": path, "status": tc.status, "attempt": tc.attempt})
+		assert.True(t, ok, tc)
+		assert.Equal(t, tc.want, value, tc)
+	}
+}
+
+func TestDoWithRetryGivesUp(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	resp, err := metrics.DoWithRetry(req, 3, time.Millisecond, http.DefaultClient.Do)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+}
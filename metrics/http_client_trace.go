@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	clientMetricDNSDurationName     = "http_client_dns_seconds"
+	clientMetricConnectDurationName = "http_client_connect_seconds"
+	clientMetricTLSDurationName     = "http_client_tls_handshake_seconds"
+	clientMetricFirstByteName       = "http_client_first_byte_seconds"
+	clientMetricConnectionsName     = "http_client_connections_total"
+)
+
+var (
+	clientDNSDuration = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: clientMetricDNSDurationName,
+			Help: "Total time and count of DNS lookups of http client requests by host in seconds.",
+		},
+		[]string{"clientName"},
+	)
+	clientConnectDuration = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: clientMetricConnectDurationName,
+			Help: "Total time and count of new TCP connections of http client requests by host in seconds.",
+		},
+		[]string{"clientName"},
+	)
+	clientTLSDuration = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: clientMetricTLSDurationName,
+			Help: "Total time and count of TLS handshakes of http client requests by host in seconds.",
+		},
+		[]string{"clientName"},
+	)
+	clientFirstByteDuration = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: clientMetricFirstByteName,
+			Help: "Total time and count from writing http client request to receiving the first response byte " +
+				"by host in seconds.",
+		},
+		[]string{"clientName"},
+	)
+	clientConnections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: clientMetricConnectionsName,
+			Help: "Total number of connections obtained for http client requests by host and whether " +
+				"the connection was reused from the pool.",
+		},
+		[]string{"clientName", "reused"},
+	)
+)
+
+// WithClientTrace returns request, which observes DNS lookup, connection setup, TLS handshake and time to
+// the first response byte in HTTP client metrics, e.g. in a custom http.RoundTripper. Request is returned
+// as is when metrics are disabled.
+func WithClientTrace(req *http.Request) *http.Request {
+	if !clientMetrics() {
+		return req
+	}
+	return withClientTrace(req)
+}
+
+// withClientTrace returns request, which observes connection setup and server time of the request
+// with httptrace, so that client latency can be attributed to them.
+func withClientTrace(req *http.Request) *http.Request {
+	clientName := dependencyName(req.URL)
+
+	var (
+		lock                                    sync.Mutex
+		dnsStart, connectStart, tlsStart, wrote time.Time
+	)
+	since := func(start *time.Time, summary *prometheus.SummaryVec) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !start.IsZero() {
+			summary.WithLabelValues(clientName).Observe(time.Since(*start).Seconds())
+			*start = time.Time{}
+		}
+	}
+	now := func(start *time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		*start = time.Now()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { now(&dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { since(&dnsStart, clientDNSDuration) },
+		ConnectStart: func(string, string) {
+			now(&connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				since(&connectStart, clientConnectDuration)
+			}
+		},
+		TLSHandshakeStart: func() { now(&tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				since(&tlsStart, clientTLSDuration)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			clientConnections.WithLabelValues(clientName, strconv.FormatBool(info.Reused)).Inc()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { now(&wrote) },
+		GotFirstResponseByte: func() { since(&wrote, clientFirstByteDuration) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
@@ -0,0 +1,58 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedHttpClientConnectionMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(serveTestResponse))
+	defer ts.Close()
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+
+	const clientName = "trace-test-dependency"
+	metrics.RegisterDependency(clientName, regexp.MustCompile(regexp.QuoteMeta(ts.Listener.Addr().String())))
+	defer metrics.ResetDependencies()
+
+	client := metrics.NewInstrumentedHttpClient(&http.Client{Transport: &http.Transport{}})
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL + testEndpoint)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	labels := map[string]string{"clientName": clientName}
+	for name, expected := range map[string]uint64{
+		"http_client_connect_seconds":    1,
+		"http_client_first_byte_seconds": 2,
+	} {
+		family := families[name]
+		require.NotNil(t, family, name)
+		var count uint64
+		for _, m := range family.GetMetric() {
+			if m.GetLabel()[0].GetValue() == clientName {
+				count = m.GetSummary().GetSampleCount()
+			}
+		}
+		assert.Equal(t, expected, count, name)
+	}
+
+	for reused, expected := range map[string]float64{"false": 1, "true": 1} {
+		labels["reused"] = reused
+		v, ok := families.Value("http_client_connections_total", labels)
+		require.True(t, ok)
+		assert.Equal(t, expected, v, "reused=%s", reused)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -44,15 +45,31 @@ var (
 	)
 )
 
-//nolint:gochecknoinits
-func init() {
-	prometheus.MustRegister(clientDuration, clientRespSize, clientRequestSize)
+var (
+	registerClientMetricsOnce sync.Once
+	clientMetricsRegistered   bool
+)
+
+// clientMetrics registers HTTP client metrics on first use, unless metrics are disabled,
+// and reports whether they are registered.
+func clientMetrics() bool {
+	registerClientMetricsOnce.Do(func() {
+		if Disabled() {
+			return
+		}
+		prometheus.MustRegister(clientDuration, clientRespSize, clientRequestSize,
+			clientDNSDuration, clientConnectDuration, clientTLSDuration, clientFirstByteDuration, clientConnections,
+			clientAttempts)
+		clientMetricsRegistered = true
+	})
+	return clientMetricsRegistered
 }
 
 // A InstrumentedHttpClient represents standard http.Client with metrics instrumentation capabilities.
 type InstrumentedHttpClient struct {
-	client *http.Client
-	rules  []InstrumentRule
+	client        *http.Client
+	rules         []InstrumentRule
+	trackAttempts bool
 }
 
 // A HttpRequestTemplate represents standard http.Request with URL templating capabilities.
@@ -63,12 +80,14 @@ type HttpRequestTemplate struct {
 
 // NewInstrumentedHttpClient returns given http client with instrumentation capabilities.
 func NewInstrumentedHttpClient(httpClient *http.Client) *InstrumentedHttpClient {
-	return &InstrumentedHttpClient{httpClient, nil}
+	clientMetrics()
+	return &InstrumentedHttpClient{client: httpClient}
 }
 
 // NewInstrumentedDefaultHttpClient returns default http client with instrumentation capabilities.
 func NewInstrumentedDefaultHttpClient() *InstrumentedHttpClient {
-	return &InstrumentedHttpClient{http.DefaultClient, nil}
+	clientMetrics()
+	return &InstrumentedHttpClient{client: http.DefaultClient}
 }
 
 // NewHttpRequestTemplate returns a new HttpRequestTemplate given a method, URL, optional body and urlVariables.
@@ -100,58 +119,68 @@ func (hc *InstrumentedHttpClient) SetRules(rules ...InstrumentRule) {
 }
 
 // Get is a metric instrumentation wrapper for Client.Get with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Get method documentation for details.
 func (hc *InstrumentedHttpClient) Get(urlTemplate string, urlVariables ...string) (resp *http.Response, err error) {
-	now := time.Now()
-	response, error := hc.client.Get(expandURL(urlTemplate, urlVariables))
-	hc.Instrument(response, urlTemplate, now)
-	return response, error
+	req, err := http.NewRequest(http.MethodGet, expandURL(urlTemplate, urlVariables), nil)
+	if err != nil {
+		return nil, err
+	}
+	return hc.do(req, urlTemplate)
 }
 
 // Post is a metric instrumentation wrapper for Client.Post with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Post method documentation for details.
 func (hc *InstrumentedHttpClient) Post(urlTemplate string, contentType string, body io.Reader, urlVariables ...string) (resp *http.Response, err error) {
-	now := time.Now()
-	response, error := hc.client.Post(expandURL(urlTemplate, urlVariables), contentType, body)
-	hc.Instrument(response, urlTemplate, now)
-	return response, error
+	req, err := http.NewRequest(http.MethodPost, expandURL(urlTemplate, urlVariables), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return hc.do(req, urlTemplate)
 }
 
 // Do is a metric instrumentation wrapper for Client.Do with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Do method documentation for details.
 func (hc *InstrumentedHttpClient) Do(req *HttpRequestTemplate) (*http.Response, error) {
-	now := time.Now()
-	response, error := hc.client.Do(req.Request)
-	hc.Instrument(response, req.UrlTemplate, now)
-	return response, error
+	return hc.do(req.Request, req.UrlTemplate)
 }
 
 // PostForm is a metric instrumentation wrapper for Client.PostForm with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.PostForm method documentation for details.
 func (hc *InstrumentedHttpClient) PostForm(urlTemplate string, data url.Values, urlVariables ...string) (resp *http.Response, err error) {
-	now := time.Now()
-	response, error := hc.client.PostForm(expandURL(urlTemplate, urlVariables), data)
-	hc.Instrument(response, urlTemplate, now)
-	return response, error
+	return hc.Post(urlTemplate, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()), urlVariables...)
 }
 
 // Head is a metric instrumentation wrapper for Client.Head with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Head method documentation for details.
 func (hc *InstrumentedHttpClient) Head(urlTemplate string, urlVariables ...string) (resp *http.Response, err error) {
+	req, err := http.NewRequest(http.MethodHead, expandURL(urlTemplate, urlVariables), nil)
+	if err != nil {
+		return nil, err
+	}
+	return hc.do(req, urlTemplate)
+}
+
+// do sends the request with connection setup tracing and instruments the response.
+// Requests are sent without tracing when metrics are disabled.
+func (hc *InstrumentedHttpClient) do(req *http.Request, urlTemplate string) (*http.Response, error) {
+	if !clientMetrics() {
+		return hc.client.Do(req)
+	}
 	now := time.Now()
-	response, error := hc.client.Head(expandURL(urlTemplate, urlVariables))
+	response, err := hc.client.Do(withClientTrace(req))
 	hc.Instrument(response, urlTemplate, now)
-	return response, error
+	return response, err
 }
 
 // Instrument instruments response. Usually this is not needed by the library consumers, just use actual HTTP client operations and instrumentation is happening automatically.
 func (hc *InstrumentedHttpClient) Instrument(response *http.Response, urlTemplate string, start time.Time) {
-	if response != nil {
+	if response != nil && clientMetrics() {
 		url, err := url.Parse(urlTemplate)
 		if err != nil {
 			panic(err)
@@ -159,6 +188,9 @@ func (hc *InstrumentedHttpClient) Instrument(response *http.Response, urlTemplat
 		hc.instrumentDuration(response, url, start)
 		hc.instrumentResponseSize(response, url)
 		hc.instrumentRequestSize(response, url)
+		if hc.trackAttempts {
+			hc.instrumentAttempt(response, url)
+		}
 	}
 }
 
@@ -180,19 +212,23 @@ func expandURL(urlTemplate string, urlVariables []string) string {
 }
 
 func (hc *InstrumentedHttpClient) instrumentDuration(response *http.Response, urlTemplate *url.URL, start time.Time) {
-	clientDuration.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), response.Request.URL.Hostname()).Observe(
+	clientDuration.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), dependencyName(response.Request.URL)).Observe(
 		time.Since(start).Seconds())
 }
 
 func (hc *InstrumentedHttpClient) instrumentResponseSize(response *http.Response, urlTemplate *url.URL) {
 	length := response.ContentLength
 	if length > -1 {
-		clientRespSize.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), response.Request.URL.Hostname()).Observe(
+		clientRespSize.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), dependencyName(response.Request.URL)).Observe(
 			float64(length))
 	}
 }
 
 func (hc *InstrumentedHttpClient) instrumentRequestSize(response *http.Response, urlTemplate *url.URL) {
-	clientRequestSize.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), response.Request.URL.Hostname()).Observe(
+	clientRequestSize.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), dependencyName(response.Request.URL)).Observe(
 		float64(computeApproximateRequestSize(response.Request)))
 }
+
+func (hc *InstrumentedHttpClient) instrumentAttempt(response *http.Response, urlTemplate *url.URL) {
+	clientAttempts.WithLabelValues(strconv.Itoa(response.StatusCode), response.Request.Method, getURIApplyingRules(urlTemplate, hc.rules), dependencyName(response.Request.URL), attemptLabel(response.Request.Context())).Inc()
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// JSONEndPoint is the default endpoint for metrics exposed as JSON.
+const JSONEndPoint = "/application/metrics.json"
+
+// JSONMetricFamily is a metric family in JSON dump.
+type JSONMetricFamily struct {
+	Name    string       `json:"name"`
+	Help    string       `json:"help"`
+	Type    string       `json:"type"`
+	Metrics []JSONMetric `json:"metrics"`
+}
+
+// JSONMetric is a single metric in JSON dump. Value is set for counters, gauges and untyped metrics,
+// Count and Sum for summaries and histograms, Quantiles for summaries and Buckets for histograms.
+// Quantiles and buckets are keyed by the quantile and the upper bound formatted as in the Prometheus text format.
+type JSONMetric struct {
+	Labels    map[string]string  `json:"labels,omitempty"`
+	Value     *float64           `json:"value,omitempty"`
+	Count     *uint64            `json:"count,omitempty"`
+	Sum       *float64           `json:"sum,omitempty"`
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+	Buckets   map[string]uint64  `json:"buckets,omitempty"`
+}
+
+// GetJSONMetricsHandler gets handler exposing all registered metrics as JSON for tools which
+// can't parse the Prometheus text format. NaN and infinite values are omitted, as they can't
+// be represented in JSON.
+func GetJSONMetricsHandler() http.Handler {
+	return JSONMetricsHandler(Gatherer())
+}
+
+// JSONMetricsHandler gets handler exposing metrics from given gatherer as JSON.
+func JSONMetricsHandler(gatherer prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := gatherer.Gather()
+		if err != nil && len(families) == 0 {
+			http.Error(w, "failed to gather metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ToJSON(families))
+	})
+}
+
+// InstrumentWithJSONMetrics instruments given Router with JSON metrics endpoint.
+func InstrumentWithJSONMetrics(mux Router) {
+	mux.Handle(JSONEndPoint, GetJSONMetricsHandler())
+}
+
+// ToJSON converts gathered metric families to JSON dump format.
+func ToJSON(families []*dto.MetricFamily) []JSONMetricFamily {
+	result := make([]JSONMetricFamily, 0, len(families))
+	for _, family := range families {
+		jsonFamily := JSONMetricFamily{
+			Name:    family.GetName(),
+			Help:    family.GetHelp(),
+			Type:    jsonMetricType(family.GetType()),
+			Metrics: make([]JSONMetric, 0, len(family.GetMetric())),
+		}
+		for _, m := range family.GetMetric() {
+			jsonFamily.Metrics = append(jsonFamily.Metrics, toJSONMetric(m))
+		}
+		result = append(result, jsonFamily)
+	}
+	return result
+}
+
+func jsonMetricType(t dto.MetricType) string {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return "counter"
+	case dto.MetricType_GAUGE:
+		return "gauge"
+	case dto.MetricType_SUMMARY:
+		return "summary"
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+func toJSONMetric(m *dto.Metric) JSONMetric {
+	jm := JSONMetric{}
+	if len(m.GetLabel()) > 0 {
+		jm.Labels = make(map[string]string, len(m.GetLabel()))
+		for _, lp := range m.GetLabel() {
+			jm.Labels[lp.GetName()] = lp.GetValue()
+		}
+	}
+
+	switch {
+	case m.Counter != nil:
+		jm.Value = jsonFloat(m.GetCounter().GetValue())
+	case m.Gauge != nil:
+		jm.Value = jsonFloat(m.GetGauge().GetValue())
+	case m.Untyped != nil:
+		jm.Value = jsonFloat(m.GetUntyped().GetValue())
+	case m.Summary != nil:
+		s := m.GetSummary()
+		count := s.GetSampleCount()
+		jm.Count, jm.Sum = &count, jsonFloat(s.GetSampleSum())
+		jm.Quantiles = make(map[string]float64, len(s.GetQuantile()))
+		for _, q := range s.GetQuantile() {
+			if v := jsonFloat(q.GetValue()); v != nil {
+				jm.Quantiles[formatFloat(q.GetQuantile())] = *v
+			}
+		}
+	case m.Histogram != nil:
+		h := m.GetHistogram()
+		count := h.GetSampleCount()
+		jm.Count, jm.Sum = &count, jsonFloat(h.GetSampleSum())
+		jm.Buckets = make(map[string]uint64, len(h.GetBucket())+1)
+		for _, b := range h.GetBucket() {
+			jm.Buckets[formatFloat(b.GetUpperBound())] = b.GetCumulativeCount()
+		}
+		jm.Buckets["+Inf"] = count
+	}
+	return jm
+}
+
+func jsonFloat(f float64) *float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findJSONFamily(families []metrics.JSONMetricFamily, name string) (metrics.JSONMetricFamily, bool) {
+	for _, f := range families {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return metrics.JSONMetricFamily{}, false
+}
+
+func TestJSONMetricsHandler(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	counter := metrics.RegisterCounterVec(metric+"_counter", "test", "counter help", "key1")
+	defer counter.Unregister()
+	counter.GetCustomCounter("val1").Add(3)
+
+	histogram := metrics.RegisterHistogram(metric+"_histogram", "test", "histogram help", []float64{1, 2.5})
+	defer histogram.Unregister()
+	histogram.Observe(2)
+
+	mux := http.NewServeMux()
+	metrics.InstrumentWithJSONMetrics(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, metrics.JSONEndPoint, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var families []metrics.JSONMetricFamily
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &families))
+
+	c, ok := findJSONFamily(families, metricNamespace+"_test_"+metric+"_counter")
+	require.True(t, ok)
+	assert.Equal(t, "counter", c.Type)
+	assert.Equal(t, "counter help", c.Help)
+	require.Len(t, c.Metrics, 1)
+	assert.Equal(t, map[string]string{"key1": "val1", plainMetricNameKey: metric + "_counter"}, c.Metrics[0].Labels)
+	require.NotNil(t, c.Metrics[0].Value)
+	assert.Equal(t, 3.0, *c.Metrics[0].Value)
+
+	h, ok := findJSONFamily(families, metricNamespace+"_test_"+metric+"_histogram")
+	require.True(t, ok)
+	assert.Equal(t, "histogram", h.Type)
+	require.Len(t, h.Metrics, 1)
+	assert.Nil(t, h.Metrics[0].Value)
+	require.NotNil(t, h.Metrics[0].Count)
+	assert.Equal(t, uint64(1), *h.Metrics[0].Count)
+	assert.Equal(t, map[string]uint64{"1": 0, "2.5": 1, "+Inf": 1}, h.Metrics[0].Buckets)
+}
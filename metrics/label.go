@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLabelValueMaxLength is the maximum length of label values in runes used by SanitizeLabelValue.
+const DefaultLabelValueMaxLength = 128
+
+// labelHashLength is the number of hex characters of hash used by HashLabelValue and in truncated values.
+const labelHashLength = 12
+
+// A LabelSanitizer converts arbitrary strings into safe label values.
+type LabelSanitizer struct {
+	// MaxLength is the maximum length of label value in runes, zero disables truncation.
+	MaxLength int
+	// HashTruncated replaces the end of values longer than MaxLength with a hash of the whole value,
+	// so that distinct values sharing a long prefix are not merged into one series.
+	HashTruncated bool
+}
+
+// DefaultLabelSanitizer is used by SanitizeLabelValue and applied to uri and clientName labels of HTTP instrumentation.
+var DefaultLabelSanitizer = LabelSanitizer{MaxLength: DefaultLabelValueMaxLength, HashTruncated: true}
+
+// SanitizeLabelValue sanitizes value with DefaultLabelSanitizer.
+func SanitizeLabelValue(value string) string {
+	return DefaultLabelSanitizer.Sanitize(value)
+}
+
+// Sanitize replaces invalid UTF-8 sequences with U+FFFD, which would otherwise make the prometheus client panic,
+// strips newlines and other control characters and truncates value to MaxLength runes.
+func (s LabelSanitizer) Sanitize(value string) string {
+	if isSafeLabelValue(value, s.MaxLength) {
+		return value
+	}
+
+	original := value
+	value = strings.ToValidUTF8(value, string(utf8.RuneError))
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value)
+
+	if s.MaxLength <= 0 || utf8.RuneCountInString(value) <= s.MaxLength {
+		return value
+	}
+	runes := []rune(value)
+	if !s.HashTruncated || s.MaxLength <= labelHashLength+1 {
+		return string(runes[:s.MaxLength])
+	}
+	return string(runes[:s.MaxLength-labelHashLength-1]) + "~" + HashLabelValue(original)
+}
+
+// HashLabelValue returns short hex encoded SHA-256 hash of value. It can be used for values, which must not be exposed
+// as such, or which are too long to be used as label values, e.g. tokens or full URLs.
+func HashLabelValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:labelHashLength]
+}
+
+// isSafeLabelValue is a fast path for the common case of short printable ASCII values.
+func isSafeLabelValue(value string, maxLength int) bool {
+	if maxLength > 0 && len(value) > maxLength {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < 0x20 || c >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
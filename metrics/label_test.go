@@ -0,0 +1,67 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelSanitizer(t *testing.T) {
+	long := strings.Repeat("a", 20)
+	tests := []struct {
+		name      string
+		sanitizer metrics.LabelSanitizer
+		value     string
+		want      string
+	}{
+		{name: "Unchanged", sanitizer: metrics.LabelSanitizer{MaxLength: 20}, value: "/api/v1/users", want: "/api/v1/users"},
+		{name: "InvalidUTF8", value: "/api/\xff\xfeusers", want: "/api/�users"},
+		{name: "Newlines", value: "line1\r\nline2\ttab", want: "line1line2tab"},
+		{name: "Unicode", sanitizer: metrics.LabelSanitizer{MaxLength: 3}, value: "äöüå", want: "äöü"},
+		{name: "Truncated", sanitizer: metrics.LabelSanitizer{MaxLength: 10}, value: long, want: strings.Repeat("a", 10)},
+		{name: "Hashed", sanitizer: metrics.LabelSanitizer{MaxLength: 16, HashTruncated: true}, value: long,
+			want: "aaa~" + metrics.HashLabelValue(long)},
+		{name: "TooShortForHash", sanitizer: metrics.LabelSanitizer{MaxLength: 5, HashTruncated: true}, value: long, want: "aaaaa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.sanitizer.Sanitize(tt.value)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, utf8.ValidString(got))
+		})
+	}
+
+	a, b := metrics.SanitizeLabelValue(strings.Repeat("x", 200)+"a"), metrics.SanitizeLabelValue(strings.Repeat("x", 200)+"b")
+	assert.NotEqual(t, a, b, "values sharing long prefix should stay distinct")
+	assert.Equal(t, metrics.DefaultLabelValueMaxLength, utf8.RuneCountInString(a))
+}
+
+func TestInstrumentHTTPHandlerSanitizesURI(t *testing.T) {
+	id := uuid.New().String()
+	handler := metrics.InstrumentHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/sanitized/"+id, nil)
+	r.URL.Path += "/\xff\n"
+	require.NotPanics(t, func() { handler.ServeHTTP(httptest.NewRecorder(), r) })
+
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+	var uris []string
+	for _, m := range families["http_server_requests_duration_seconds"].GetMetric() {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "uri" && strings.HasPrefix(l.GetValue(), "/sanitized/"+id) {
+				uris = append(uris, l.GetValue())
+			}
+		}
+	}
+	assert.Equal(t, []string{"/sanitized/" + id + "/�"}, uris)
+}
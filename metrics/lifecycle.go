@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	processStartTime = time.Now()
+	shutdownStarted  uint32
+)
+
+// MarkShutdownStarted sets graceful_shutdown_started gauge to 1, so dashboards can distinguish
+// graceful restarts from crashes. It is called by ManagementServer.Shutdown and can be called by
+// applications when they receive SIGTERM.
+func MarkShutdownStarted() {
+	atomic.StoreUint32(&shutdownStarted, 1)
+}
+
+// ShutdownStarted reports whether MarkShutdownStarted has been called.
+func ShutdownStarted() bool {
+	return atomic.LoadUint32(&shutdownStarted) == 1
+}
+
+// Shutdown marks shutdown started and gracefully shuts down the ManagementServer
+// waiting for active requests to finish until given context is done.
+func (managementServer *ManagementServer) Shutdown(ctx context.Context) error {
+	MarkShutdownStarted()
+	err := managementServer.server.Shutdown(ctx)
+	managementServer.wg.Wait()
+	return err
+}
@@ -0,0 +1,32 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagementServerShutdownMarksShutdownStarted(t *testing.T) {
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+	uptime, ok := families.Value("process_uptime_seconds", nil)
+	require.True(t, ok)
+	assert.Greater(t, uptime, 0.0)
+
+	server := metrics.StartManagementServer("127.0.0.1:0", nil)
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.True(t, metrics.ShutdownStarted())
+
+	families, err = metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+	shutdown, ok := families.Value("graceful_shutdown_started", nil)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, shutdown)
+}
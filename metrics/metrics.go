@@ -68,7 +68,11 @@ var (
 
 //nolint:gochecknoinits
 func init() {
-	prometheus.MustRegister(gauge, obs, obsResponseSize, obsRequestSize, commonMetricsCollector)
+	if Disabled() {
+		return
+	}
+	prometheus.MustRegister(gauge, obs, obsResponseSize, obsRequestSize, timeouts, panics, sloRequests, sloViolations, sloLatency,
+		commonMetricsCollector)
 }
 
 // CustomMetric is a provider for collector.
@@ -96,6 +100,7 @@ type loggingResponseWriter struct {
 // ManagementServer type is for gracefully stop the management server.
 type ManagementServer struct {
 	server *http.Server
+	mux    *http.ServeMux
 	wg     *sync.WaitGroup
 }
 
@@ -115,6 +120,11 @@ func (managementServer *ManagementServer) Close() {
 	managementServer.wg.Wait()
 }
 
+// Handle registers additional management endpoint, e.g. tracing.SelfTestHandler.
+func (managementServer *ManagementServer) Handle(pattern string, handler http.Handler) {
+	managementServer.mux.Handle(pattern, handler)
+}
+
 func (lrw *loggingStatusCodeResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
 	lrw.ResponseWriter.WriteHeader(code)
@@ -148,7 +158,7 @@ func (lrw *loggingResponseWriter) Flush() {
 // GetMetricsHandler gets metric handler in case you want embed metrics endpoint
 // to your existing HTTP server.
 func GetMetricsHandler() http.Handler {
-	return promhttp.Handler()
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(Gatherer(), promhttp.HandlerOpts{}))
 }
 
 // StartManagementServer starts HTTP server for metric endpoint, pprof endpoints
@@ -167,7 +177,8 @@ func StartManagementServer(listenAddress string, healthCheckFunc func(http.Respo
 			Addr:    listenAddress,
 			Handler: InstrumentHTTPHandler(mux),
 		},
-		wg: &sync.WaitGroup{},
+		mux: mux,
+		wg:  &sync.WaitGroup{},
 	}
 	listener, err := net.Listen("tcp", managementServer.server.Addr)
 	if err != nil {
@@ -211,6 +222,9 @@ func InstrumentHTTPHandler(next http.Handler) http.Handler {
 // request/response count, size and times.
 // Applies routings according to the given rules.
 func InstrumentHTTPHandlerWithRules(handler http.Handler, rules []InstrumentRule) http.Handler {
+	if Disabled() {
+		return handler
+	}
 	handler = instrumentHTTPHandlerInFlight(gauge, handler, rules)
 	handler = instrumentHTTPHandlerDuration(obs, handler, rules)
 	handler = instrumentHTTPHandlerResponseSize(obsResponseSize, handler, rules)
@@ -334,7 +348,7 @@ func getURIApplyingRules(url *url.URL, rules []InstrumentRule) string {
 			return rule.URIPath
 		}
 	}
-	return url.Path
+	return SanitizeLabelValue(url.Path)
 }
 
 func computeApproximateRequestSize(r *http.Request) int {
@@ -356,6 +356,10 @@ var (
 		{"timezone_offset_milliseconds", "Test timezone_offset_milliseconds metric expose"},
 		{"process_cpu_count", "Test process_cpu_count metric expose"},
 		{"process_cgo_calls", "Test process_cgo_calls metric expose"},
+		{"process_uptime_seconds", "Test process_uptime_seconds metric expose"},
+		{"process_start_time_seconds", "Test process_start_time_seconds metric expose"},
+		{"graceful_shutdown_started", "Test graceful_shutdown_started metric expose"},
+		{"process_gomaxprocs", "Test process_gomaxprocs metric expose"},
 	}
 
 	SwaggerJSON = json.RawMessage([]byte(`{
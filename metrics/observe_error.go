@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const errorClassKey = "error_class"
+
+// Error classes returned by ErrorClass.
+const (
+	ErrorClassTimeout  = "timeout"
+	ErrorClassCanceled = "canceled"
+	ErrorClassError    = "error"
+)
+
+// ErrorClasser can be implemented by errors to control the error class label of the error counter.
+type ErrorClasser interface {
+	ErrorClass() string
+}
+
+// ErrorClass returns error class label value for given error. Class of the first error in the chain
+// implementing ErrorClasser is used, otherwise timeouts and cancellations are classified as
+// ErrorClassTimeout and ErrorClassCanceled and all other errors as ErrorClassError.
+func ErrorClass(err error) string {
+	var classer ErrorClasser
+	var netErr net.Error
+	switch {
+	case errors.As(err, &classer):
+		return classer.ErrorClass()
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	default:
+		return ErrorClassError
+	}
+}
+
+// errorCounter counts errors observed together with a summary or histogram. Methods are no-op on nil
+// errorCounter, so that metrics registered without error counter can be used the same way.
+type errorCounter struct {
+	counterVec *prometheus.CounterVec
+	// keys are the custom label keys of the vector metric.
+	keys []string
+	// metricName is set for vector metrics, which have plain metric name label.
+	metricName  string
+	labelValues []string
+}
+
+func registerErrorCounter(metricName string, subsystem string, vec bool, keys ...string) *errorCounter {
+	finalKeys := append(append([]string{}, keys...), errorClassKey)
+	c := &errorCounter{keys: keys}
+	if vec {
+		finalKeys = append(finalKeys, plainMetricNameKey)
+		c.metricName = metricName
+	}
+
+	c.counterVec = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName + "_errors_total",
+		Help:      "Total number of errors observed with " + metricName + " by error class.",
+	}, finalKeys)
+	prometheus.MustRegister(c.counterVec)
+	return c
+}
+
+func (c *errorCounter) with(labelValues ...string) *errorCounter {
+	if c == nil {
+		return nil
+	}
+	bound := *c
+	bound.labelValues = labelValues
+	return &bound
+}
+
+func (c *errorCounter) inc(err error, labelValues ...string) {
+	if c == nil || err == nil {
+		return
+	}
+	if labelValues == nil {
+		labelValues = c.labelValues
+	}
+	finalLabelValues := append(append([]string{}, labelValues...), ErrorClass(err))
+	if c.metricName != "" {
+		finalLabelValues = append(finalLabelValues, c.metricName)
+	}
+	c.counterVec.WithLabelValues(finalLabelValues...).Inc()
+}
+
+func (c *errorCounter) deleteSeries(labelValues ...string) {
+	if c == nil || len(labelValues) != len(c.keys) {
+		return
+	}
+	labels := make(prometheus.Labels, len(c.keys))
+	for i, key := range c.keys {
+		labels[key] = labelValues[i]
+	}
+	c.counterVec.DeletePartialMatch(labels)
+}
+
+func (c *errorCounter) reset() {
+	if c != nil {
+		c.counterVec.Reset()
+	}
+}
+
+func (c *errorCounter) unregister() {
+	if c != nil {
+		prometheus.Unregister(c.counterVec)
+	}
+}
+
+// RegisterSummaryWithErrors registers summary like RegisterSummary together with <metricName>_errors_total
+// counter labeled by error class, which is incremented by ObserveWithError when error is not nil.
+func RegisterSummaryWithErrors(metricName string, subsystem string, desc string) *CustomSummary {
+	if Disabled() {
+		return disabledSummary
+	}
+	summary := RegisterSummary(metricName, subsystem, desc).(*CustomSummary)
+	summary.errors = registerErrorCounter(metricName, subsystem, false)
+	return summary
+}
+
+// RegisterSummaryVecWithErrors registers summary vector like RegisterSummaryVec together with
+// <metricName>_errors_total counter labeled by given keys and error class.
+func RegisterSummaryVecWithErrors(metricName string, subsystem string, desc string, keys ...string) *CustomSummaryVec {
+	if Disabled() {
+		return disabledSummaryVec
+	}
+	summaryVec := RegisterSummaryVec(metricName, subsystem, desc, keys...)
+	summaryVec.errors = registerErrorCounter(metricName, subsystem, true, keys...)
+	return summaryVec
+}
+
+// RegisterHistogramWithErrors registers histogram like RegisterHistogram together with <metricName>_errors_total
+// counter labeled by error class, which is incremented by ObserveWithError when error is not nil.
+func RegisterHistogramWithErrors(metricName string, subsystem string, desc string, buckets []float64) *CustomHistogram {
+	if Disabled() {
+		return disabledHistogram
+	}
+	histogram := RegisterHistogram(metricName, subsystem, desc, buckets).(*CustomHistogram)
+	histogram.errors = registerErrorCounter(metricName, subsystem, false)
+	return histogram
+}
+
+// RegisterHistogramVecWithErrors registers histogram vector like RegisterHistogramVec together with
+// <metricName>_errors_total counter labeled by given keys and error class.
+func RegisterHistogramVecWithErrors(metricName string, subsystem string, desc string, buckets []float64, keys ...string) *CustomHistogramVec {
+	if Disabled() {
+		return disabledHistogramVec
+	}
+	histogramVec := RegisterHistogramVec(metricName, subsystem, desc, buckets, keys...)
+	histogramVec.errors = registerErrorCounter(metricName, subsystem, true, keys...)
+	return histogramVec
+}
+
+// ObserveWithError observes the given value and increments error counter if err is not nil.
+// Error counter exists only for summaries registered with RegisterSummaryWithErrors
+// or RegisterSummaryVecWithErrors, otherwise the error is ignored.
+func (cs *CustomSummary) ObserveWithError(f float64, err error) {
+	cs.Observe(f)
+	cs.errors.inc(err)
+}
+
+// ObserveDurationWithError observes the elapsed time since given time in milliseconds
+// and increments error counter if err is not nil.
+func (cs *CustomSummary) ObserveDurationWithError(startTime time.Time, err error) {
+	cs.ObserveDuration(startTime)
+	cs.errors.inc(err)
+}
+
+// ObserveWithError observes the given value for given labels and increments error counter if err is not nil.
+func (csv *CustomSummaryVec) ObserveWithError(f float64, err error, labelValues ...string) {
+	csv.GetCustomSummary(labelValues...).Observe(f)
+	csv.errors.inc(err, labelValues...)
+}
+
+// ObserveWithError observes the given value and increments error counter if err is not nil.
+// Error counter exists only for histograms registered with RegisterHistogramWithErrors
+// or RegisterHistogramVecWithErrors, otherwise the error is ignored.
+func (ch *CustomHistogram) ObserveWithError(f float64, err error) {
+	ch.Observe(f)
+	ch.errors.inc(err)
+}
+
+// ObserveDurationWithError observes the elapsed time since given time in milliseconds
+// and increments error counter if err is not nil.
+func (ch *CustomHistogram) ObserveDurationWithError(startTime time.Time, err error) {
+	ch.ObserveDuration(startTime)
+	ch.errors.inc(err)
+}
+
+// ObserveWithError observes the given value for given labels and increments error counter if err is not nil.
+func (chv *CustomHistogramVec) ObserveWithError(f float64, err error, labelValues ...string) {
+	chv.GetCustomHistogram(labelValues...).Observe(f)
+	chv.errors.inc(err, labelValues...)
+}
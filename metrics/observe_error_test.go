@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type classedError struct{}
+
+func (classedError) Error() string      { return "not found" }
+func (classedError) ErrorClass() string { return "not_found" }
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, metrics.ErrorClassTimeout, metrics.ErrorClass(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+	assert.Equal(t, metrics.ErrorClassCanceled, metrics.ErrorClass(context.Canceled))
+	assert.Equal(t, metrics.ErrorClassError, metrics.ErrorClass(errors.New("failed")))
+	assert.Equal(t, "not_found", metrics.ErrorClass(fmt.Errorf("get: %w", classedError{})))
+}
+
+func TestObserveWithError(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+
+	summary := metrics.RegisterSummaryWithErrors(metric+"_summary", "test", "summary help")
+	defer summary.Unregister()
+	summary.ObserveWithError(1, nil)
+	summary.ObserveWithError(2, context.DeadlineExceeded)
+
+	histogramVec := metrics.RegisterHistogramVecWithErrors(metric+"_histogram", "test", "histogram help", nil, "operation")
+	defer histogramVec.Unregister()
+	histogramVec.ObserveWithError(1, errors.New("failed"), "read")
+	histogramVec.ObserveWithError(1, nil, "write")
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+
+	summaryFamily := families[metricNamespace+"_test_"+metric+"_summary"]
+	require.NotNil(t, summaryFamily)
+	assert.Equal(t, uint64(2), summaryFamily.GetMetric()[0].GetSummary().GetSampleCount())
+	v, ok := families.Value(metricNamespace+"_test_"+metric+"_summary_errors_total", map[string]string{"error_class": "timeout"})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, v)
+
+	errorsName := metricNamespace + "_test_" + metric + "_histogram_errors_total"
+	v, ok = families.Value(errorsName, map[string]string{"operation": "read", "error_class": "error", plainMetricNameKey: metric + "_histogram"})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, v)
+	_, ok = families.Value(errorsName, map[string]string{"operation": "write"})
+	assert.False(t, ok, "successful observation should not create error series")
+
+	histogramVec.DeleteSerie("read")
+	families, err = metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+	_, ok = families.Value(errorsName, map[string]string{"operation": "read"})
+	assert.False(t, ok, "error series should be deleted with histogram series")
+}
+
+func TestObserveWithErrorWithoutErrorCounter(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	summary := metrics.RegisterSummary(metric, "test", "summary help").(*metrics.CustomSummary)
+	defer summary.Unregister()
+
+	assert.NotPanics(t, func() { summary.ObserveWithError(1, errors.New("failed")) })
+}
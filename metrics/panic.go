@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricHTTPPanicsName = "http_server_panics_total"
+
+var panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: metricHTTPPanicsName,
+	Help: "Count of http requests, which handler panicked, by URI.",
+}, []string{"uri"})
+
+// ContextErrorLogger logs errors with request context, e.g. logging/v2 Logger, which adds trace
+// correlation fields and stack trace to error events.
+type ContextErrorLogger interface {
+	Errorf(ctx context.Context, format string, args ...interface{})
+}
+
+// PanicRecoveryHTTPHandler recovers panics of the given handler, responds with 500 Internal Server Error,
+// if the response was not started yet, and counts the panics in http_server_panics_total labeled with
+// the URI built applying given instrument rules. Panics are logged with request context by logger or
+// with the standard logger, if logger is nil. http.ErrAbortHandler is not recovered.
+func PanicRecoveryHTTPHandler(next http.Handler, logger ContextErrorLogger, rules []InstrumentRule) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &panicResponseWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			uri := getURIApplyingRules(r.URL, rules)
+			panics.WithLabelValues(uri).Inc()
+			if logger != nil {
+				logger.Errorf(r.Context(), "panic serving %s %s: %v", r.Method, uri, p)
+			} else {
+				log.Printf("panic serving %s %s: %v\n%s", r.Method, uri, p, debug.Stack())
+			}
+			if !pw.started {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(pw, r)
+	})
+}
+
+// InstrumentHTTPHandlerWithPanicRecovery instruments HTTP handler like InstrumentHTTPHandlerWithRules
+// and recovers its panics like PanicRecoveryHTTPHandler. Recovered requests are recorded with status 500.
+func InstrumentHTTPHandlerWithPanicRecovery(next http.Handler, logger ContextErrorLogger, rules []InstrumentRule) http.Handler {
+	return InstrumentHTTPHandlerWithRules(PanicRecoveryHTTPHandler(next, logger, rules), rules)
+}
+
+// panicResponseWriter tracks whether the response was started, so that 500 is written only when possible.
+type panicResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *panicResponseWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *panicResponseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *panicResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
@@ -0,0 +1,92 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type panicLogger struct {
+	messages []string
+}
+
+func (l *panicLogger) Errorf(_ context.Context, format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestInstrumentHTTPHandlerWithPanicRecovery(t *testing.T) {
+	id := uuid.New().String()
+	panicURI, writtenURI, okURI := "/panic/"+id, "/written/"+id, "/ok/"+id
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(panicURI, func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc(writtenURI, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom after write")
+	})
+	mux.HandleFunc(okURI, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	logger := &panicLogger{}
+	handler := metrics.InstrumentHTTPHandlerWithPanicRecovery(mux, logger, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, panicURI, nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, writtenURI, nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, okURI, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, logger.messages, 2)
+	assert.Contains(t, logger.messages[0], panicURI)
+	assert.Contains(t, logger.messages[0], "boom")
+
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	count, ok := families.Value("http_server_panics_total", map[string]string{"uri": panicURI})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, count)
+	_, ok = families.Value("http_server_panics_total", map[string]string{"uri": okURI})
+	assert.False(t, ok)
+
+	var recorded bool
+	for _, m := range families["http_server_requests_duration_seconds"].GetMetric() {
+		labels := map[string]string{}
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if labels["uri"] == panicURI {
+			assert.Equal(t, "500", labels["status"])
+			recorded = true
+		}
+	}
+	assert.True(t, recorded)
+}
+
+func TestPanicRecoveryHTTPHandlerDoesNotRecoverAbort(t *testing.T) {
+	handler := metrics.PanicRecoveryHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), nil, nil)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"os"
 
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
 )
 
@@ -78,7 +77,7 @@ func (p *Pusher) Add() error {
 //
 // For convenience, this method returns a pointer to the Pusher itself.
 func (p *Pusher) CollectAll() *Pusher {
-	p.pusher.Gatherer(prometheus.DefaultGatherer)
+	p.pusher.Gatherer(Gatherer())
 	return p
 }
 
@@ -0,0 +1,289 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// REMOTE_WRITE_ENDPOINT is remote write endpoint's environment variable name.
+const REMOTE_WRITE_ENDPOINT = "METRICS_REMOTE_WRITE_ENDPOINT"
+
+// RemoteWriter pushes metrics to an endpoint implementing Prometheus remote write protocol 1.0, e.g. Prometheus
+// with remote write receiver enabled, Mimir or VictoriaMetrics, for deployments where metrics can't be scraped.
+// Use NewRemoteWriter to create one, configure it with its methods, and use Write or Run to push.
+type RemoteWriter struct {
+	client          *http.Client
+	url             string
+	gatherer        prometheus.Gatherer
+	externalLabels  map[string]string
+	headers         http.Header
+	bearerTokenFile string
+	onError         func(err error)
+	now             func() time.Time
+}
+
+// NewRemoteWriter creates remote writer pushing metrics of the default registry to given URL, e.g.
+// "https://prometheus.example.com/api/v1/write". If url is empty, METRICS_REMOTE_WRITE_ENDPOINT is used.
+func NewRemoteWriter(url string) (*RemoteWriter, error) {
+	if url == "" {
+		url = os.Getenv(REMOTE_WRITE_ENDPOINT)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("url is not given and env %s is not set or it's empty", REMOTE_WRITE_ENDPOINT)
+	}
+	return &RemoteWriter{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		url:      url,
+		gatherer: Gatherer(),
+		headers:  http.Header{},
+		onError:  func(error) {},
+		now:      time.Now,
+	}, nil
+}
+
+// Gatherer sets gatherer, which metrics are pushed instead of the default registry.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) Gatherer(g prometheus.Gatherer) *RemoteWriter {
+	w.gatherer = g
+	return w
+}
+
+// ExternalLabels sets labels added to every series, e.g. instance and job, which are otherwise added by
+// Prometheus when scraping. Labels of the metrics take precedence.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) ExternalLabels(labels map[string]string) *RemoteWriter {
+	w.externalLabels = labels
+	return w
+}
+
+// Client sets HTTP client used for pushing, e.g. with TLS configuration. Client has 10 second timeout by default.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) Client(client *http.Client) *RemoteWriter {
+	w.client = client
+	return w
+}
+
+// Header sets header sent with every request, e.g. X-Scope-OrgID for multi-tenant receivers.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) Header(key, value string) *RemoteWriter {
+	w.headers.Set(key, value)
+	return w
+}
+
+// BasicAuth sets username and password sent with every request.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) BasicAuth(username, password string) *RemoteWriter {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+	return w.Header("Authorization", req.Header.Get("Authorization"))
+}
+
+// BearerToken sets bearer token sent with every request.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) BearerToken(token string) *RemoteWriter {
+	return w.Header("Authorization", "Bearer "+token)
+}
+
+// BearerTokenFile sets file, which is read on every request for bearer token, so that rotated tokens are used.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) BearerTokenFile(path string) *RemoteWriter {
+	w.bearerTokenFile = path
+	return w
+}
+
+// OnError sets function called with errors of pushes made by Run.
+// For convenience, this method returns a pointer to the writer itself.
+func (w *RemoteWriter) OnError(onError func(err error)) *RemoteWriter {
+	w.onError = onError
+	return w
+}
+
+// Write gathers metrics and pushes them as a single remote write request. Samples are timestamped with
+// current time unless metric has its own timestamp.
+func (w *RemoteWriter) Write(ctx context.Context) error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(families, w.externalLabels, w.now().UnixMilli()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range w.headers {
+		req.Header[key] = values
+	}
+	if w.bearerTokenFile != "" {
+		token, err := os.ReadFile(w.bearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to push metrics: unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Run pushes metrics in given interval until ctx is done. Failed pushes are passed to OnError function and
+// metrics are pushed again in the next interval. Metrics are pushed once more before returning, so that final
+// values of the counters are not lost, and error of the final push is returned.
+func (w *RemoteWriter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			writeCtx, cancel := context.WithTimeout(context.Background(), w.client.Timeout)
+			defer cancel()
+			return w.Write(writeCtx)
+		case <-ticker.C:
+			if err := w.Write(ctx); err != nil {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+// remoteWriteSeries is a sample of a series with labels sorted by name.
+type remoteWriteSeries struct {
+	labels    [][2]string
+	value     float64
+	timestamp int64
+}
+
+// encodeWriteRequest encodes metric families as remote write protobuf message:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(families []*dto.MetricFamily, externalLabels map[string]string, now int64) []byte {
+	var b []byte
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			for _, s := range familySeries(family, m, externalLabels, now) {
+				b = protowire.AppendTag(b, 1, protowire.BytesType)
+				b = protowire.AppendBytes(b, encodeTimeSeries(s))
+			}
+		}
+	}
+	return b
+}
+
+func encodeTimeSeries(s remoteWriteSeries) []byte {
+	var b []byte
+	for _, l := range s.labels {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, l[0])
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, l[1])
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, label)
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendBytes(b, sample)
+}
+
+// familySeries converts metric to series the same way as Prometheus does when scraping, e.g. histogram
+// to _bucket, _sum and _count series.
+func familySeries(family *dto.MetricFamily, m *dto.Metric, externalLabels map[string]string, now int64) []remoteWriteSeries {
+	timestamp := now
+	if m.TimestampMs != nil {
+		timestamp = m.GetTimestampMs()
+	}
+	labels := map[string]string{}
+	for name, value := range externalLabels {
+		labels[name] = value
+	}
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	series := func(suffix string, value float64, extra ...string) remoteWriteSeries {
+		s := remoteWriteSeries{value: value, timestamp: timestamp}
+		s.labels = append(s.labels, [2]string{"__name__", family.GetName() + suffix})
+		for name, value := range labels {
+			s.labels = append(s.labels, [2]string{name, value})
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			s.labels = append(s.labels, [2]string{extra[i], extra[i+1]})
+		}
+		sort.Slice(s.labels, func(i, j int) bool { return s.labels[i][0] < s.labels[j][0] })
+		return s
+	}
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		return []remoteWriteSeries{series("", m.GetCounter().GetValue())}
+	case dto.MetricType_GAUGE:
+		return []remoteWriteSeries{series("", m.GetGauge().GetValue())}
+	case dto.MetricType_UNTYPED:
+		return []remoteWriteSeries{series("", m.GetUntyped().GetValue())}
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		result := []remoteWriteSeries{
+			series("_sum", s.GetSampleSum()),
+			series("_count", float64(s.GetSampleCount())),
+		}
+		for _, q := range s.GetQuantile() {
+			result = append(result, series("", q.GetValue(), "quantile", formatLabelFloat(q.GetQuantile())))
+		}
+		return result
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		h := m.GetHistogram()
+		result := []remoteWriteSeries{
+			series("_sum", h.GetSampleSum()),
+			series("_count", float64(h.GetSampleCount())),
+		}
+		hasInf := false
+		for _, b := range h.GetBucket() {
+			hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+			result = append(result, series("_bucket", float64(b.GetCumulativeCount()), "le", formatLabelFloat(b.GetUpperBound())))
+		}
+		if !hasInf {
+			result = append(result, series("_bucket", float64(h.GetSampleCount()), "le", "+Inf"))
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// formatLabelFloat formats bucket bound or quantile as label value like Prometheus text format.
+func formatLabelFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return formatFloat(f)
+}
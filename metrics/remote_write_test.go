@@ -0,0 +1,135 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+type writtenSample struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest decodes remote write request into samples by series name.
+func decodeWriteRequest(t *testing.T, b []byte) map[string][]writtenSample {
+	samples := map[string][]writtenSample{}
+	forEachField(t, b, func(_ protowire.Number, series []byte) {
+		s := writtenSample{labels: map[string]string{}}
+		forEachField(t, series, func(num protowire.Number, field []byte) {
+			if num == 1 {
+				var label [2]string
+				forEachField(t, field, func(num protowire.Number, value []byte) { label[num-1] = string(value) })
+				s.labels[label[0]] = label[1]
+				return
+			}
+			for len(field) > 0 {
+				num, typ, n := protowire.ConsumeTag(field)
+				require.GreaterOrEqual(t, n, 0)
+				field = field[n:]
+				if num == 1 && typ == protowire.Fixed64Type {
+					value, n := protowire.ConsumeFixed64(field)
+					require.GreaterOrEqual(t, n, 0)
+					s.value, field = math.Float64frombits(value), field[n:]
+					continue
+				}
+				timestamp, n := protowire.ConsumeVarint(field)
+				require.GreaterOrEqual(t, n, 0)
+				s.timestamp, field = int64(timestamp), field[n:]
+			}
+		})
+		samples[s.labels["__name__"]] = append(samples[s.labels["__name__"]], s)
+	})
+	return samples
+}
+
+func forEachField(t *testing.T, b []byte, fn func(num protowire.Number, value []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		require.Equal(t, protowire.BytesType, typ)
+		value, m := protowire.ConsumeBytes(b[n:])
+		require.GreaterOrEqual(t, m, 0)
+		fn(num, value)
+		b = b[n+m:]
+	}
+}
+
+func TestRemoteWriter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "help"}, []string{"code"})
+	counter.WithLabelValues("200").Add(3)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "help", Buckets: []float64{0.1, 1}})
+	histogram.Observe(0.5)
+	registry.MustRegister(counter, histogram)
+
+	received := make(chan map[string][]writtenSample, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		received <- decodeWriteRequest(t, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	writer, err := metrics.NewRemoteWriter(server.URL)
+	require.NoError(t, err)
+	writer.Gatherer(registry).ExternalLabels(map[string]string{"instance": "edge-1"}).Header("X-Scope-OrgID", "tenant")
+	assert.Error(t, writer.Write(context.Background()), "request without credentials should fail")
+
+	writer.BasicAuth("user", "secret")
+	require.NoError(t, writer.Write(context.Background()))
+	samples := <-received
+
+	require.Len(t, samples["requests_total"], 1)
+	s := samples["requests_total"][0]
+	assert.Equal(t, map[string]string{"__name__": "requests_total", "code": "200", "instance": "edge-1"}, s.labels)
+	assert.Equal(t, 3.0, s.value)
+	assert.InDelta(t, time.Now().UnixMilli(), s.timestamp, float64(time.Minute.Milliseconds()))
+
+	buckets := map[string]float64{}
+	for _, b := range samples["latency_seconds_bucket"] {
+		buckets[b.labels["le"]] = b.value
+	}
+	assert.Equal(t, map[string]float64{"0.1": 0, "1": 1, "+Inf": 1}, buckets)
+	assert.Equal(t, 0.5, samples["latency_seconds_sum"][0].value)
+	assert.Equal(t, 1.0, samples["latency_seconds_count"][0].value)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, writer.Run(ctx, time.Hour), "final push should be made when ctx is done")
+	assert.Len(t, received, 1)
+}
+
+func TestRemoteWriterEndpoint(t *testing.T) {
+	t.Setenv(metrics.REMOTE_WRITE_ENDPOINT, "")
+	_, err := metrics.NewRemoteWriter("")
+	assert.Error(t, err)
+
+	t.Setenv(metrics.REMOTE_WRITE_ENDPOINT, "http://localhost:9090/api/v1/write")
+	_, err = metrics.NewRemoteWriter("")
+	assert.NoError(t, err)
+}
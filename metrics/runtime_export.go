@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math"
+	"regexp"
+	rtmetrics "runtime/metrics"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	runtimeSubsystem = "runtime"
+	expvarSubsystem  = "expvar"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// RegisterRuntimeMetrics registers collector exporting given runtime/metrics samples, e.g. "/sched/latencies:seconds"
+// or "/gc/heap/allocs:bytes", which are not exported by the Go collector. Names are converted to Prometheus
+// conventions with NEO metrics namespace and runtime subsystem, e.g. com_metrics_runtime_gc_heap_allocs_bytes_total.
+// Cumulative samples are exported as counters, histograms as histograms with sum approximated from buckets
+// and other samples as gauges. Error is returned for names not supported by the Go runtime.
+func RegisterRuntimeMetrics(names ...string) (*FuncMetric, error) {
+	if Disabled() {
+		return disabledFuncMetric, nil
+	}
+	c, err := newRuntimeCollector(names)
+	if err != nil {
+		return nil, err
+	}
+	if err := prometheus.Register(c); err != nil {
+		return nil, fmt.Errorf("failed to register runtime metrics: %w", err)
+	}
+	return &FuncMetric{c}, nil
+}
+
+// RegisterExpvars registers collector exporting given expvar variables published by third-party libraries as gauges
+// with NEO metrics namespace and expvar subsystem, e.g. variable "cache.hits" is exported as
+// com_metrics_expvar_cache_hits. Numbers and booleans are exported as single value, maps of numbers with the map key
+// in "key" label. Variables, which are not published or have other values, are not exported.
+func RegisterExpvars(names ...string) (*FuncMetric, error) {
+	if Disabled() {
+		return disabledFuncMetric, nil
+	}
+	c := &expvarCollector{names: names}
+	if err := prometheus.Register(c); err != nil {
+		return nil, fmt.Errorf("failed to register expvar metrics: %w", err)
+	}
+	return &FuncMetric{c}, nil
+}
+
+type runtimeMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+// runtimeCollector collects runtime/metrics samples.
+type runtimeCollector struct {
+	samples []rtmetrics.Sample
+	metrics []runtimeMetric
+}
+
+func newRuntimeCollector(names []string) (*runtimeCollector, error) {
+	supported := map[string]rtmetrics.Description{}
+	for _, d := range rtmetrics.All() {
+		supported[d.Name] = d
+	}
+
+	c := &runtimeCollector{}
+	for _, name := range names {
+		d, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("runtime metric %s is not supported", name)
+		}
+		fqName, err := runtimeMetricName(d)
+		if err != nil {
+			return nil, err
+		}
+		valueType := prometheus.GaugeValue
+		if d.Cumulative && d.Kind != rtmetrics.KindFloat64Histogram {
+			valueType = prometheus.CounterValue
+		}
+		c.samples = append(c.samples, rtmetrics.Sample{Name: name})
+		c.metrics = append(c.metrics, runtimeMetric{
+			desc:      prometheus.NewDesc(fqName, d.Description, nil, nil),
+			valueType: valueType,
+		})
+	}
+	return c, nil
+}
+
+// runtimeMetricName converts e.g. "/gc/heap/allocs:bytes" to com_metrics_runtime_gc_heap_allocs_bytes_total.
+func runtimeMetricName(d rtmetrics.Description) (string, error) {
+	path, unit, ok := strings.Cut(d.Name, ":")
+	if !ok {
+		return "", fmt.Errorf("runtime metric %s has no unit", d.Name)
+	}
+	unit = strings.ReplaceAll(unit, "/", "_per_")
+	name := sanitizeMetricName(path + "_" + unit)
+	if d.Cumulative && d.Kind != rtmetrics.KindFloat64Histogram && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return prometheus.BuildFQName(metricNamespace, runtimeSubsystem, name), nil
+}
+
+// Describe returns all descriptions of the collector.
+func (c *runtimeCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect reads all samples at once and returns them as metrics.
+func (c *runtimeCollector) Collect(ch chan<- prometheus.Metric) {
+	samples := make([]rtmetrics.Sample, len(c.samples))
+	copy(samples, c.samples)
+	rtmetrics.Read(samples)
+
+	for i, s := range samples {
+		m := c.metrics[i]
+		switch s.Value.Kind() {
+		case rtmetrics.KindUint64:
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(s.Value.Uint64()))
+		case rtmetrics.KindFloat64:
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, s.Value.Float64())
+		case rtmetrics.KindFloat64Histogram:
+			ch <- runtimeHistogram(m.desc, s.Value.Float64Histogram())
+		}
+	}
+}
+
+// runtimeHistogram converts runtime histogram to Prometheus histogram. Bucket i of runtime histogram counts samples
+// in [Buckets[i], Buckets[i+1]), so its upper bound is used as Prometheus bucket. Sum is approximated with
+// bucket midpoints, or the finite bound for open ended buckets.
+func runtimeHistogram(desc *prometheus.Desc, h *rtmetrics.Float64Histogram) prometheus.Metric {
+	buckets := map[float64]uint64{}
+	var count uint64
+	var sum float64
+	for i, n := range h.Counts {
+		count += n
+		lower, upper := h.Buckets[i], h.Buckets[i+1]
+		switch {
+		case math.IsInf(lower, -1) && math.IsInf(upper, 1):
+		case math.IsInf(lower, -1):
+			sum += upper * float64(n)
+		case math.IsInf(upper, 1):
+			sum += lower * float64(n)
+		default:
+			sum += (lower + upper) / 2 * float64(n)
+		}
+		if !math.IsInf(upper, 1) {
+			buckets[upper] = count
+		}
+	}
+	m, err := prometheus.NewConstHistogram(desc, count, sum, buckets)
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return m
+}
+
+// expvarCollector collects expvar variables. Its metrics depend on variable values, so it is unchecked
+// and doesn't describe them upfront.
+type expvarCollector struct {
+	names []string
+}
+
+// Describe returns no descriptions, which makes the collector unchecked.
+func (c *expvarCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect returns current values of published variables.
+func (c *expvarCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, name := range c.names {
+		v := expvar.Get(name)
+		if v == nil {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(v.String()), &value); err != nil {
+			continue
+		}
+
+		fqName := prometheus.BuildFQName(metricNamespace, expvarSubsystem, sanitizeMetricName(name))
+		help := fmt.Sprintf("Value of expvar variable %s.", name)
+		if f, ok := expvarNumber(value); ok {
+			ch <- prometheus.MustNewConstMetric(prometheus.NewDesc(fqName, help, nil, nil), prometheus.GaugeValue, f)
+			continue
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		desc := prometheus.NewDesc(fqName, help, []string{"key"}, nil)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if f, ok := expvarNumber(values[key]); ok {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, f, key)
+			}
+		}
+	}
+}
+
+func expvarNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// sanitizeMetricName replaces characters not allowed in metric names with underscores.
+func sanitizeMetricName(name string) string {
+	return strings.ToLower(strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_"))
+}
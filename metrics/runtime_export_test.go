@@ -0,0 +1,70 @@
+package metrics_test
+
+import (
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterRuntimeMetrics(t *testing.T) {
+	m, err := metrics.RegisterRuntimeMetrics("/gc/heap/allocs:bytes", "/sched/goroutines:goroutines", "/sched/latencies:seconds")
+	require.NoError(t, err)
+	defer m.Unregister()
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+
+	allocs, ok := families.Value("com_metrics_runtime_gc_heap_allocs_bytes_total", nil)
+	require.True(t, ok)
+	assert.Greater(t, allocs, 0.0)
+	goroutines, ok := families.Value("com_metrics_runtime_sched_goroutines_goroutines", nil)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, goroutines, 1.0)
+
+	latencies, ok := families["com_metrics_runtime_sched_latencies_seconds"]
+	require.True(t, ok)
+	assert.Equal(t, "HISTOGRAM", latencies.GetType().String())
+}
+
+func TestRegisterRuntimeMetricsUnsupported(t *testing.T) {
+	_, err := metrics.RegisterRuntimeMetrics("/not/supported:bytes")
+	assert.EqualError(t, err, "runtime metric /not/supported:bytes is not supported")
+}
+
+func TestRegisterExpvars(t *testing.T) {
+	hits := expvar.NewInt("test.cache-hits")
+	hits.Add(3)
+	pool := expvar.NewMap("test_pool")
+	pool.Add("open", 2)
+	pool.Set("name", &expvar.String{})
+	expvar.Publish("test_enabled", expvar.Func(func() interface{} { return true }))
+
+	m, err := metrics.RegisterExpvars("test.cache-hits", "test_pool", "test_enabled", "test_missing")
+	require.NoError(t, err)
+	defer m.Unregister()
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+
+	value, ok := families.Value("com_metrics_expvar_test_cache_hits", nil)
+	require.True(t, ok)
+	assert.Equal(t, 3.0, value)
+	value, ok = families.Value("com_metrics_expvar_test_pool", map[string]string{"key": "open"})
+	require.True(t, ok)
+	assert.Equal(t, 2.0, value)
+	_, ok = families.Value("com_metrics_expvar_test_pool", map[string]string{"key": "name"})
+	assert.False(t, ok)
+	value, ok = families.Value("com_metrics_expvar_test_enabled", nil)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, value)
+	_, ok = families["com_metrics_expvar_test_missing"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const scrapeAcceptHeader = "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+
+// MetricFamilies contains parsed metric families keyed by the metric name.
+type MetricFamilies map[string]*dto.MetricFamily
+
+// Scrape fetches metrics from given url, e.g. another pod's
+// /application/prometheus endpoint, by using http.DefaultClient and parses
+// them from Prometheus text format.
+func Scrape(url string) (MetricFamilies, error) {
+	return ScrapeWithClient(http.DefaultClient, url)
+}
+
+// ScrapeWithClient fetches metrics from given url by using given client and
+// parses them from Prometheus text format.
+func ScrapeWithClient(client *http.Client, url string) (MetricFamilies, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", scrapeAcceptHeader)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d when scraping %s", resp.StatusCode, url)
+	}
+
+	return ParseMetrics(resp.Body)
+}
+
+// ParseMetrics parses metric families from Prometheus text format.
+func ParseMetrics(r io.Reader) (MetricFamilies, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	return families, nil
+}
+
+// Value returns value of the first counter, gauge or untyped metric with
+// given name having all given labels. Second return value reports whether
+// such metric was found.
+func (mf MetricFamilies) Value(name string, labels map[string]string) (float64, bool) {
+	family, ok := mf[name]
+	if !ok {
+		return 0, false
+	}
+
+	for _, m := range family.GetMetric() {
+		if !hasLabels(m, labels) {
+			continue
+		}
+		switch {
+		case m.Counter != nil:
+			return m.GetCounter().GetValue(), true
+		case m.Gauge != nil:
+			return m.GetGauge().GetValue(), true
+		case m.Untyped != nil:
+			return m.GetUntyped().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, lp := range m.GetLabel() {
+		if v, ok := labels[lp.GetName()]; ok {
+			if v != lp.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
@@ -0,0 +1,82 @@
+package metrics_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ExampleScrape() {
+	families, err := metrics.Scrape("http://my-service:9090" + metrics.DefaultEndPoint)
+	if err != nil {
+		return
+	}
+
+	// Read value of com_metrics_my_service_foo_counter{key1="key1Value"}
+	value, ok := families.Value("com_metrics_my_service_foo_counter", map[string]string{"key1": "key1Value"})
+	fmt.Println(value, ok)
+}
+
+func TestScrape(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	counterVec := metrics.RegisterCounterVec(metric, "test", "test", "key1")
+	defer counterVec.Unregister()
+	counterVec.GetCustomCounter("val1").Add(3)
+	counterVec.GetCustomCounter("val2").Add(5)
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+
+	families, err := metrics.Scrape(srv.URL + metrics.DefaultEndPoint)
+	require.NoError(t, err)
+
+	name := fmt.Sprintf("%s_test_%s", metricNamespace, metric)
+	require.Contains(t, families, name)
+	assert.Equal(t, "test", families[name].GetHelp())
+	assert.Len(t, families[name].GetMetric(), 2)
+
+	value, ok := families.Value(name, map[string]string{"key1": "val2"})
+	assert.True(t, ok)
+	assert.Equal(t, float64(5), value)
+
+	_, ok = families.Value(name, map[string]string{"key1": "val3"})
+	assert.False(t, ok)
+
+	_, ok = families.Value("not_existing", nil)
+	assert.False(t, ok)
+}
+
+func TestScrapeUnexpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := metrics.Scrape(srv.URL)
+	assert.EqualError(t, err, fmt.Sprintf("unexpected status code 404 when scraping %s", srv.URL))
+}
+
+func TestParseMetrics(t *testing.T) {
+	families, err := metrics.ParseMetrics(strings.NewReader(`# TYPE foo gauge
+foo{a="1",b="2"} 7
+foo{a="1",b="3"} 8
+bar 3
+`))
+	require.NoError(t, err)
+
+	value, ok := families.Value("foo", map[string]string{"b": "3"})
+	assert.True(t, ok)
+	assert.Equal(t, float64(8), value)
+
+	value, ok = families.Value("bar", nil)
+	assert.True(t, ok)
+	assert.Equal(t, float64(3), value)
+
+	_, err = metrics.ParseMetrics(strings.NewReader("not valid {"))
+	assert.Error(t, err)
+}
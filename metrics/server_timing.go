@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const serverTimingHeader = "Server-Timing"
+
+var serverTimingName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+type serverTimingKey struct{}
+
+type serverTiming struct {
+	mu       sync.Mutex
+	segments []string
+}
+
+func (t *serverTiming) add(metric string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.segments = append(t.segments, metric)
+}
+
+func (t *serverTiming) header(total time.Duration) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(append(t.segments, formatServerTiming("total", total, "")), ", ")
+}
+
+type serverTimingResponseWriter struct {
+	http.ResponseWriter
+	timing      *serverTiming
+	start       time.Time
+	wroteHeader bool
+}
+
+func (w *serverTimingResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Add(serverTimingHeader, w.timing.header(time.Since(w.start)))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *serverTimingResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *serverTimingResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ServerTimingHTTPHandler adds Server-Timing response header, so that browser developer tools and frontend
+// performance monitoring can see backend timing. The header contains segments added with AddServerTiming or
+// StartServerTiming and "total" segment with duration of the handler until the response header was written.
+// Server-Timing exposes internals of the service, so it should be used only for trusted clients.
+func ServerTimingHTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing := &serverTiming{}
+		stw := &serverTimingResponseWriter{ResponseWriter: w, timing: timing, start: time.Now()}
+		next.ServeHTTP(stw, r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, timing)))
+		if !stw.wroteHeader {
+			stw.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// InstrumentHTTPHandlerWithServerTiming instruments HTTP handler like InstrumentHTTPHandlerWithRules
+// and adds Server-Timing response header like ServerTimingHTTPHandler.
+func InstrumentHTTPHandlerWithServerTiming(next http.Handler, rules []InstrumentRule) http.Handler {
+	return InstrumentHTTPHandlerWithRules(ServerTimingHTTPHandler(next), rules)
+}
+
+// AddServerTiming adds a segment with given name, duration and optional description to Server-Timing header
+// of the response. Name must be a token, e.g. "db" or "cache". Segments added after the response header was
+// written and segments of requests not handled by ServerTimingHTTPHandler are ignored.
+func AddServerTiming(ctx context.Context, name string, d time.Duration, description string) {
+	timing, ok := ctx.Value(serverTimingKey{}).(*serverTiming)
+	if !ok || !serverTimingName.MatchString(name) {
+		return
+	}
+	timing.add(formatServerTiming(name, d, description))
+}
+
+// StartServerTiming starts measuring a segment of Server-Timing header. Returned function adds the segment
+// with duration since start:
+//
+//	defer metrics.StartServerTiming(ctx, "db", "query orders")()
+func StartServerTiming(ctx context.Context, name, description string) func() {
+	start := time.Now()
+	return func() {
+		AddServerTiming(ctx, name, time.Since(start), description)
+	}
+}
+
+func formatServerTiming(name string, d time.Duration, description string) string {
+	metric := name + ";dur=" + strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	if description != "" {
+		metric += fmt.Sprintf(";desc=%q", description)
+	}
+	return metric
+}
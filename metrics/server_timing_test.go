@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerTimingHTTPHandler(t *testing.T) {
+	handler := metrics.ServerTimingHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.AddServerTiming(r.Context(), "cache", 1500*time.Microsecond, "")
+		metrics.AddServerTiming(r.Context(), "invalid name", time.Millisecond, "")
+		stop := metrics.StartServerTiming(r.Context(), "db", `query "orders"`)
+		time.Sleep(5 * time.Millisecond)
+		stop()
+		_, _ = w.Write([]byte("OK"))
+		metrics.AddServerTiming(r.Context(), "late", time.Millisecond, "")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "OK", rec.Body.String())
+	assert.Regexp(t, regexp.MustCompile(`^cache;dur=1.5, db;dur=[0-9.]+;desc="query \\"orders\\"", total;dur=[0-9.]+$`),
+		rec.Header().Get("Server-Timing"))
+}
+
+func TestServerTimingHTTPHandlerWithoutBody(t *testing.T) {
+	handler := metrics.InstrumentHTTPHandlerWithServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Regexp(t, regexp.MustCompile(`^total;dur=[0-9.]+$`), rec.Header().Get("Server-Timing"))
+}
+
+func TestAddServerTimingWithoutHandler(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.NotPanics(t, func() {
+		metrics.AddServerTiming(r.Context(), "db", time.Millisecond, "")
+		metrics.StartServerTiming(r.Context(), "db", "")()
+	})
+}
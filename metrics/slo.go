@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricHTTPSLORequestsName   = "http_server_slo_requests_total"
+	metricHTTPSLOViolationsName = "http_server_slo_violations_total"
+	metricHTTPSLOLatencyName    = "http_server_slo_latency_total"
+
+	// SLOViolationLatency is the reason label value of requests slower than the SLO threshold.
+	SLOViolationLatency = "latency"
+	// SLOViolationError is the reason label value of requests responded with 5xx status.
+	SLOViolationError = "error"
+)
+
+var (
+	sloRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricHTTPSLORequestsName,
+		Help: "Count of http requests covered by a latency SLO by method and URI.",
+	}, []string{"method", "uri"})
+	sloViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricHTTPSLOViolationsName,
+		Help: "Count of http requests violating the SLO by method, URI and reason.",
+	}, []string{"method", "uri", "reason"})
+	sloLatency = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricHTTPSLOLatencyName,
+		Help: "Count of http requests served within le seconds by method and URI.",
+	}, []string{"method", "uri", "le"})
+)
+
+// SLORule combines regexp trigger condition and latency objective for matching requests.
+// Buckets are additional latencies, for which requests served within them are counted,
+// e.g. to alert on several objectives. Threshold is always counted as a bucket.
+type SLORule struct {
+	Condition *regexp.Regexp
+	Threshold time.Duration
+	Buckets   []time.Duration
+}
+
+type sloRoute struct {
+	condition *regexp.Regexp
+	threshold time.Duration
+	buckets   []time.Duration
+	les       []string
+}
+
+// SLOHTTPHandler counts requests against latency objectives, so that burn rate alerts can be written as
+// ratio of http_server_slo_violations_total and http_server_slo_requests_total over several windows.
+// Threshold of the first rule matching the request path is used and defaultThreshold for requests
+// matching no rule, zero threshold excludes requests from SLO. Requests slower than the threshold are
+// counted as violations with reason "latency" and requests responded with 5xx with reason "error".
+// Requests served within each bucket are counted in http_server_slo_latency_total with le label in seconds.
+// Metrics are labeled with the URI built applying given instrument rules.
+func SLOHTTPHandler(next http.Handler, defaultThreshold time.Duration, sloRules []SLORule, rules []InstrumentRule) http.Handler {
+	if Disabled() {
+		return next
+	}
+	routes := make([]sloRoute, 0, len(sloRules))
+	for _, rule := range sloRules {
+		routes = append(routes, newSLORoute(rule.Condition, rule.Threshold, rule.Buckets))
+	}
+	defaultRoute := newSLORoute(nil, defaultThreshold, nil)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := defaultRoute
+		for _, candidate := range routes {
+			if candidate.condition.MatchString(requestPath(r.URL.RawPath, r.URL.Path)) {
+				route = candidate
+				break
+			}
+		}
+		if route.threshold <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		lrw := &loggingStatusCodeResponseWriter{w, http.StatusOK}
+		next.ServeHTTP(lrw, r)
+		route.observe(r.Method, getURIApplyingRules(r.URL, rules), lrw.statusCode, time.Since(now))
+	})
+}
+
+// InstrumentHTTPHandlerWithSLO instruments HTTP handler like InstrumentHTTPHandlerWithRules
+// and counts requests against latency objectives like SLOHTTPHandler.
+func InstrumentHTTPHandlerWithSLO(next http.Handler, defaultThreshold time.Duration, sloRules []SLORule, rules []InstrumentRule) http.Handler {
+	return InstrumentHTTPHandlerWithRules(SLOHTTPHandler(next, defaultThreshold, sloRules, rules), rules)
+}
+
+func newSLORoute(condition *regexp.Regexp, threshold time.Duration, buckets []time.Duration) sloRoute {
+	route := sloRoute{condition: condition, threshold: threshold}
+	if threshold <= 0 {
+		return route
+	}
+	route.buckets = append([]time.Duration{threshold}, buckets...)
+	for _, b := range route.buckets {
+		route.les = append(route.les, strconv.FormatFloat(b.Seconds(), 'g', -1, 64))
+	}
+	return route
+}
+
+func (route sloRoute) observe(method, uri string, statusCode int, latency time.Duration) {
+	sloRequests.WithLabelValues(method, uri).Inc()
+	if statusCode >= http.StatusInternalServerError {
+		sloViolations.WithLabelValues(method, uri, SLOViolationError).Inc()
+	} else if latency > route.threshold {
+		sloViolations.WithLabelValues(method, uri, SLOViolationLatency).Inc()
+	}
+	for i, b := range route.buckets {
+		if latency <= b {
+			sloLatency.WithLabelValues(method, uri, route.les[i]).Inc()
+		}
+	}
+}
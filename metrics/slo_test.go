@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentHTTPHandlerWithSLO(t *testing.T) {
+	id := uuid.New().String()
+	slowURI, fastURI, failingURI, excludedURI := "/slow/"+id, "/fast/"+id, "/failing/"+id, "/excluded/"+id
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(slowURI, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	})
+	mux.HandleFunc(fastURI, func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc(failingURI, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc(excludedURI, func(w http.ResponseWriter, r *http.Request) {})
+
+	sloRules := []metrics.SLORule{
+		{Condition: regexp.MustCompile(`^/slow/`), Threshold: 10 * time.Millisecond, Buckets: []time.Duration{time.Second}},
+		{Condition: regexp.MustCompile(`^/excluded/`)},
+	}
+	handler := metrics.InstrumentHTTPHandlerWithSLO(mux, time.Second, sloRules, nil)
+	for _, uri := range []string{slowURI, fastURI, fastURI, failingURI, excludedURI} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, uri, nil))
+	}
+
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	value := func(name string, labels map[string]string) float64 {
+		labels["method"] = http.MethodGet
+		v, ok := families.Value(name, labels)
+		require.True(t, ok, "%s %v", name, labels)
+		return v
+	}
+
+	assert.Equal(t, 1.0, value("http_server_slo_requests_total", map[string]string{"uri": slowURI}))
+	assert.Equal(t, 1.0, value("http_server_slo_violations_total", map[string]string{"uri": slowURI, "reason": metrics.SLOViolationLatency}))
+	assert.Equal(t, 1.0, value("http_server_slo_latency_total", map[string]string{"uri": slowURI, "le": "1"}))
+	_, ok := families.Value("http_server_slo_latency_total", map[string]string{"uri": slowURI, "le": "0.01"})
+	assert.False(t, ok, "slow request should not be counted within threshold")
+
+	assert.Equal(t, 2.0, value("http_server_slo_requests_total", map[string]string{"uri": fastURI}))
+	assert.Equal(t, 2.0, value("http_server_slo_latency_total", map[string]string{"uri": fastURI, "le": "1"}))
+	_, ok = families.Value("http_server_slo_violations_total", map[string]string{"uri": fastURI})
+	assert.False(t, ok)
+
+	assert.Equal(t, 1.0, value("http_server_slo_violations_total", map[string]string{"uri": failingURI, "reason": metrics.SLOViolationError}))
+
+	_, ok = families.Value("http_server_slo_requests_total", map[string]string{"uri": excludedURI})
+	assert.False(t, ok, "requests with zero threshold should be excluded")
+}
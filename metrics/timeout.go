@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricHTTPTimeoutsName = "http_server_timeouts_total"
+
+var timeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: metricHTTPTimeoutsName,
+	Help: "Count of http requests aborted due to timeout by method and URI.",
+}, []string{"method", "uri"})
+
+// TimeoutRule combines regexp trigger condition and timeout for matching requests.
+type TimeoutRule struct {
+	Condition *regexp.Regexp
+	Timeout   time.Duration
+}
+
+type timeoutRoute struct {
+	condition *regexp.Regexp
+	handler   http.Handler
+	timeout   time.Duration
+}
+
+// TimeoutHTTPHandler bounds time spent in the given handler. Timeout of the first rule matching the
+// request path is used and defaultTimeout for requests matching no rule, zero timeout disables the limit.
+// Timed out requests are responded with 503 Service Unavailable and counted in http_server_timeouts_total
+// labeled with the URI built applying given instrument rules. Handler must stop on request context
+// cancellation, as it keeps running in the background after the timeout.
+func TimeoutHTTPHandler(next http.Handler, defaultTimeout time.Duration, timeoutRules []TimeoutRule, rules []InstrumentRule) http.Handler {
+	routes := make([]timeoutRoute, 0, len(timeoutRules)+1)
+	for _, rule := range timeoutRules {
+		routes = append(routes, newTimeoutRoute(next, rule.Condition, rule.Timeout))
+	}
+	defaultRoute := newTimeoutRoute(next, nil, defaultTimeout)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := defaultRoute
+		for _, candidate := range routes {
+			if candidate.condition.MatchString(requestPath(r.URL.RawPath, r.URL.Path)) {
+				route = candidate
+				break
+			}
+		}
+		if route.timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), route.timeout)
+		defer cancel()
+		lrw := &loggingStatusCodeResponseWriter{w, http.StatusOK}
+		route.handler.ServeHTTP(lrw, r.WithContext(ctx))
+		if lrw.statusCode == http.StatusServiceUnavailable && ctx.Err() == context.DeadlineExceeded {
+			timeouts.WithLabelValues(r.Method, getURIApplyingRules(r.URL, rules)).Inc()
+		}
+	})
+}
+
+// InstrumentHTTPHandlerWithTimeouts instruments HTTP handler like InstrumentHTTPHandlerWithRules
+// and bounds its execution time like TimeoutHTTPHandler. Timed out requests are recorded with status 503.
+func InstrumentHTTPHandlerWithTimeouts(next http.Handler, defaultTimeout time.Duration, timeoutRules []TimeoutRule, rules []InstrumentRule) http.Handler {
+	return InstrumentHTTPHandlerWithRules(TimeoutHTTPHandler(next, defaultTimeout, timeoutRules, rules), rules)
+}
+
+func newTimeoutRoute(next http.Handler, condition *regexp.Regexp, timeout time.Duration) timeoutRoute {
+	route := timeoutRoute{condition: condition, timeout: timeout}
+	if timeout > 0 {
+		route.handler = http.TimeoutHandler(next, timeout, "")
+	}
+	return route
+}
+
+func requestPath(rawPath, path string) string {
+	if rawPath != "" {
+		return rawPath
+	}
+	return path
+}
@@ -0,0 +1,77 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentHTTPHandlerWithTimeouts(t *testing.T) {
+	id := uuid.New().String()
+	slowURI, fastURI := "/slow/"+id, "/fast/"+id
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(slowURI, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	mux.HandleFunc(fastURI, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	timeoutRules := []metrics.TimeoutRule{
+		{Condition: regexp.MustCompile(`^/slow/`), Timeout: 10 * time.Millisecond},
+	}
+	handler := metrics.InstrumentHTTPHandlerWithTimeouts(mux, time.Second, timeoutRules, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, slowURI, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fastURI, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	count, ok := families.Value("http_server_timeouts_total", map[string]string{"method": http.MethodGet, "uri": slowURI})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, count)
+	_, ok = families.Value("http_server_timeouts_total", map[string]string{"uri": fastURI})
+	assert.False(t, ok)
+
+	var recorded bool
+	for _, m := range families["http_server_requests_duration_seconds"].GetMetric() {
+		labels := map[string]string{}
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if labels["uri"] == slowURI {
+			assert.Equal(t, "503", labels["status"])
+			recorded = true
+		}
+	}
+	assert.True(t, recorded, "timed out request should be instrumented")
+}
+
+func TestTimeoutHTTPHandlerWithoutTimeout(t *testing.T) {
+	handler := metrics.TimeoutHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline)
+		w.WriteHeader(http.StatusNoContent)
+	}), 0, nil, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TimestampedValue is a value of a vector metric observed at given time, with label values given in the same
+// order than registered keys. Values with zero timestamp are not exposed, e.g. before the first observation.
+type TimestampedValue struct {
+	LabelValues []string
+	Value       float64
+	Timestamp   time.Time
+}
+
+// RegisterTimestampedGaugeVecFunc registers gauge vector metric, which values and their timestamps are
+// returned by fn at scrape time, by using given keys, subsystem name and metric description.
+// It is meant for values known to be observed at an earlier time, e.g. size of the last successful batch
+// read from the database, which would be misleading when stamped with scrape time.
+//
+// Prometheus drops samples with timestamps older than its head block, i.e. about an hour, and samples of
+// the same series must not go back in time, so timestamps should be recent and must never decrease.
+// Counters are not supported, because their rate can't be computed correctly from backfilled samples.
+// fn must be safe for concurrent use. NEO metrics namespace is added to metric name as prefix.
+func RegisterTimestampedGaugeVecFunc(metricName string, subsystem string, desc string, fn func() []TimestampedValue, keys ...string) *FuncMetric {
+	if Disabled() {
+		return disabledFuncMetric
+	}
+	c := &timestampedFuncCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(metricNamespace, subsystem, metricName), desc,
+			withPlainMetricNameKey(keys), nil),
+		metricName: metricName,
+		fn:         fn,
+	}
+	prometheus.MustRegister(c)
+	return &FuncMetric{c}
+}
+
+// RegisterTimestampedGaugeFunc registers gauge metric like RegisterTimestampedGaugeVecFunc, but without labels.
+func RegisterTimestampedGaugeFunc(metricName string, subsystem string, desc string, fn func() (float64, time.Time)) *FuncMetric {
+	return RegisterTimestampedGaugeVecFunc(metricName, subsystem, desc, func() []TimestampedValue {
+		value, timestamp := fn()
+		return []TimestampedValue{{Value: value, Timestamp: timestamp}}
+	})
+}
+
+// timestampedFuncCollector collects timestamped values of a gauge vector metric returned by fn.
+type timestampedFuncCollector struct {
+	desc       *prometheus.Desc
+	metricName string
+	fn         func() []TimestampedValue
+}
+
+func (c *timestampedFuncCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *timestampedFuncCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range c.fn() {
+		if v.Timestamp.IsZero() {
+			continue
+		}
+		labelValues := append(append([]string{}, v.LabelValues...), c.metricName)
+		m, err := prometheus.NewConstMetric(c.desc, prometheus.GaugeValue, v.Value, labelValues...)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.desc, err)
+			continue
+		}
+		ch <- prometheus.NewMetricWithTimestamp(v.Timestamp, m)
+	}
+}
@@ -0,0 +1,47 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampedMetrics(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+
+	lastBatch := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	gauge := metrics.RegisterTimestampedGaugeFunc(metric+"_last_batch_size", "test", "help", func() (float64, time.Time) {
+		return 42, lastBatch
+	})
+	defer gauge.Unregister()
+	gaugeVec := metrics.RegisterTimestampedGaugeVecFunc(metric+"_batch_size", "test", "help", func() []metrics.TimestampedValue {
+		return []metrics.TimestampedValue{
+			{LabelValues: []string{"orders"}, Value: 3, Timestamp: lastBatch},
+			{LabelValues: []string{"invoices"}, Value: 5},
+		}
+	}, "job")
+	defer gaugeVec.Unregister()
+
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	require.Contains(t, families, "com_metrics_test_"+metric+"_last_batch_size")
+	m := families["com_metrics_test_"+metric+"_last_batch_size"].GetMetric()
+	require.Len(t, m, 1)
+	assert.Equal(t, 42.0, m[0].GetGauge().GetValue())
+	assert.Equal(t, lastBatch.UnixMilli(), m[0].GetTimestampMs())
+
+	v, ok := families.Value("com_metrics_test_"+metric+"_batch_size", map[string]string{"job": "orders"})
+	require.True(t, ok)
+	assert.Equal(t, 3.0, v)
+	_, ok = families.Value("com_metrics_test_"+metric+"_batch_size", map[string]string{"job": "invoices"})
+	assert.False(t, ok, "value without timestamp should not be exposed")
+}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	v3 "github.com/phanitejak/kptgolib/metrics/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterOpt configures TryRegister* functions.
+type RegisterOpt func(*registerConf)
+
+type registerConf struct {
+	reuseExisting bool
+}
+
+// ReuseExisting makes TryRegister* functions return the already registered metric instead of an error,
+// when a metric of the same type with the same name, description and labels is registered already.
+// Library code registering its metrics on first use can then be called multiple times.
+func ReuseExisting() RegisterOpt {
+	return func(c *registerConf) {
+		c.reuseExisting = true
+	}
+}
+
+// TryRegisterCounter registers counter like RegisterCounter, but returns an error instead of panicking
+// if the metric can't be registered, e.g. when a metric with the same name is already registered.
+func TryRegisterCounter(metricName string, subsystem string, desc string, opts ...RegisterOpt) (Counter, error) {
+	if Disabled() {
+		return disabledCounter, nil
+	}
+	c, err := tryRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomCounter{c.(prometheus.Counter)}, nil
+}
+
+// TryRegisterCounterVec registers counter vector like RegisterCounterVec, but returns an error instead of panicking
+// if the metric can't be registered.
+func TryRegisterCounterVec(metricName string, subsystem string, desc string, keys []string, opts ...RegisterOpt) (CounterVec, error) {
+	if Disabled() {
+		return disabledCounter, nil
+	}
+	c, err := tryRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}, withPlainMetricNameKey(keys)), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomCounterVec{c.(*prometheus.CounterVec), metricName}, nil
+}
+
+// TryRegisterGauge registers gauge like RegisterGauge, but returns an error instead of panicking
+// if the metric can't be registered.
+func TryRegisterGauge(metricName string, subsystem string, desc string, opts ...RegisterOpt) (*CustomGauge, error) {
+	if Disabled() {
+		return disabledGauge, nil
+	}
+	c, err := tryRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomGauge{c.(prometheus.Gauge)}, nil
+}
+
+// TryRegisterGaugeVec registers gauge vector like RegisterGaugeVec, but returns an error instead of panicking
+// if the metric can't be registered.
+func TryRegisterGaugeVec(metricName string, subsystem string, desc string, keys []string, opts ...RegisterOpt) (*CustomGaugeVec, error) {
+	if Disabled() {
+		return disabledGaugeVec, nil
+	}
+	c, err := tryRegister(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}, withPlainMetricNameKey(keys)), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomGaugeVec{c.(*prometheus.GaugeVec), metricName}, nil
+}
+
+// TryRegisterSummary registers summary like RegisterSummary, but returns an error instead of panicking
+// if the metric can't be registered.
+func TryRegisterSummary(metricName string, subsystem string, desc string, opts ...RegisterOpt) (*CustomSummary, error) {
+	if Disabled() {
+		return disabledSummary, nil
+	}
+	c, err := tryRegister(prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomSummary{observer: c.(prometheus.Summary), collector: c}, nil
+}
+
+// TryRegisterSummaryVec registers summary vector like RegisterSummaryVec, but returns an error instead of panicking
+// if the metric can't be registered.
+func TryRegisterSummaryVec(metricName string, subsystem string, desc string, keys []string, opts ...RegisterOpt) (*CustomSummaryVec, error) {
+	if Disabled() {
+		return disabledSummaryVec, nil
+	}
+	c, err := tryRegister(prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace: metricNamespace,
+		Subsystem: subsystem,
+		Name:      metricName,
+		Help:      desc,
+	}, withPlainMetricNameKey(keys)), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomSummaryVec{summaryVec: c.(*prometheus.SummaryVec), metricName: metricName}, nil
+}
+
+// TryRegisterHistogram registers histogram like RegisterHistogram, but returns an error instead of panicking
+// if the metric can't be registered. Buckets are not compared when reusing existing histogram.
+func TryRegisterHistogram(metricName string, subsystem string, desc string, buckets []float64, opts ...RegisterOpt) (*CustomHistogram, error) {
+	if Disabled() {
+		return disabledHistogram, nil
+	}
+	c, err := tryRegister(prometheus.NewHistogram(histogramOpts(metricName, subsystem, desc, buckets)), subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomHistogram{observer: c.(prometheus.Histogram), collector: c}, nil
+}
+
+// TryRegisterHistogramVec registers histogram vector like RegisterHistogramVec, but returns an error instead of
+// panicking if the metric can't be registered. Buckets are not compared when reusing existing histogram vector.
+func TryRegisterHistogramVec(metricName string, subsystem string, desc string, buckets []float64, keys []string, opts ...RegisterOpt) (*CustomHistogramVec, error) {
+	if Disabled() {
+		return disabledHistogramVec, nil
+	}
+	c, err := tryRegister(prometheus.NewHistogramVec(histogramOpts(metricName, subsystem, desc, buckets), withPlainMetricNameKey(keys)),
+		subsystem, metricName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomHistogramVec{histogramVec: c.(*prometheus.HistogramVec), metricName: metricName}, nil
+}
+
+// tryRegister registers given collector to default registry. With ReuseExisting option
+// already registered collector of the same type is returned instead of an error.
+func tryRegister(c prometheus.Collector, subsystem string, metricName string, opts []RegisterOpt) (prometheus.Collector, error) {
+	conf := &registerConf{}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	fqName := prometheus.BuildFQName(metricNamespace, subsystem, metricName)
+	err := prometheus.Register(c)
+	if err == nil {
+		return c, nil
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if !errors.As(err, &alreadyRegistered) {
+		return nil, fmt.Errorf("failed to register metric %s: %w", fqName, err)
+	}
+	if !conf.reuseExisting {
+		return nil, fmt.Errorf("metric %s is already registered: %w", fqName, err)
+	}
+	if reflect.TypeOf(alreadyRegistered.ExistingCollector) != reflect.TypeOf(c) {
+		return nil, fmt.Errorf("metric %s is already registered with different type %T", fqName, alreadyRegistered.ExistingCollector)
+	}
+	return alreadyRegistered.ExistingCollector, nil
+}
+
+func withPlainMetricNameKey(keys []string) []string {
+	return append(append([]string{}, keys...), plainMetricNameKey)
+}
+
+// mustRegister registers metric with v3.Register and panics if it fails, like prometheus.MustRegister.
+func mustRegister(metricName string, opts ...v3.Opt) *v3.Metric {
+	m, err := v3.Register(metricName, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,77 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryRegisterReturnsErrorOnDuplicate(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+
+	counter, err := metrics.TryRegisterCounter(metric, "test", "counter help")
+	require.NoError(t, err)
+	defer counter.Unregister()
+
+	_, err = metrics.TryRegisterCounter(metric, "test", "counter help")
+	assert.ErrorContains(t, err, "metric com_metrics_test_"+metric+" is already registered")
+
+	_, err = metrics.TryRegisterCounter(metric, "test", "other help")
+	assert.ErrorContains(t, err, "failed to register metric com_metrics_test_"+metric)
+}
+
+func TestTryRegisterReuseExisting(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+
+	first, err := metrics.TryRegisterCounterVec(metric, "test", "counter help", []string{"key"}, metrics.ReuseExisting())
+	require.NoError(t, err)
+	defer first.Unregister()
+
+	second, err := metrics.TryRegisterCounterVec(metric, "test", "counter help", []string{"key"}, metrics.ReuseExisting())
+	require.NoError(t, err)
+	assert.Same(t, first.GetCollector(), second.GetCollector())
+
+	_, err = metrics.TryRegisterCounterVec(metric, "test", "counter help", []string{"other"}, metrics.ReuseExisting())
+	assert.Error(t, err)
+
+	_, err = metrics.TryRegisterGaugeVec(metric, "test", "counter help", []string{"key"}, metrics.ReuseExisting())
+	assert.ErrorContains(t, err, "already registered with different type")
+}
+
+func TestTryRegisterAllTypes(t *testing.T) {
+	metric := strings.ReplaceAll(uuid.New().String(), "-", "_")
+
+	for i := 0; i < 2; i++ {
+		gauge, err := metrics.TryRegisterGauge(metric+"_gauge", "test", "help", metrics.ReuseExisting())
+		require.NoError(t, err)
+		gauge.Set(1)
+
+		summary, err := metrics.TryRegisterSummary(metric+"_summary", "test", "help", metrics.ReuseExisting())
+		require.NoError(t, err)
+		summary.Observe(1)
+
+		summaryVec, err := metrics.TryRegisterSummaryVec(metric+"_summary_vec", "test", "help", []string{"key"}, metrics.ReuseExisting())
+		require.NoError(t, err)
+		summaryVec.GetCustomSummary("value").Observe(1)
+
+		histogram, err := metrics.TryRegisterHistogram(metric+"_histogram", "test", "help", nil, metrics.ReuseExisting())
+		require.NoError(t, err)
+		histogram.Observe(1)
+
+		histogramVec, err := metrics.TryRegisterHistogramVec(metric+"_histogram_vec", "test", "help", nil, []string{"key"}, metrics.ReuseExisting())
+		require.NoError(t, err)
+		histogramVec.GetCustomHistogram("value").Observe(1)
+
+		if i == 1 {
+			gauge.Unregister()
+			summary.Unregister()
+			summaryVec.Unregister()
+			histogram.Unregister()
+			histogramVec.Unregister()
+		}
+	}
+}
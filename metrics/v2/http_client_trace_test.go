@@ -0,0 +1,54 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	metricsv2 "github.com/phanitejak/kptgolib/metrics/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedTransportConnectionMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	metricsServer := httptest.NewServer(metrics.GetMetricsHandler())
+	defer metricsServer.Close()
+
+	const clientName = "v2-transport-trace-test"
+	metrics.RegisterDependency(clientName, regexp.MustCompile(regexp.QuoteMeta(ts.Listener.Addr().String())))
+	defer metrics.ResetDependencies()
+
+	client := http.Client{Transport: metricsv2.NewInstrumentedTransport(&http.Transport{})}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL + "/trace")
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	families, err := metrics.Scrape(metricsServer.URL)
+	require.NoError(t, err)
+
+	family := families["http_client_first_byte_seconds"]
+	require.NotNil(t, family)
+	var count uint64
+	for _, m := range family.GetMetric() {
+		if m.GetLabel()[0].GetValue() == clientName {
+			count = m.GetSummary().GetSampleCount()
+		}
+	}
+	assert.Equal(t, uint64(2), count)
+
+	for reused, expected := range map[string]float64{"false": 1, "true": 1} {
+		v, ok := families.Value("http_client_connections_total", map[string]string{"clientName": clientName, "reused": reused})
+		require.True(t, ok, "reused=%s", reused)
+		assert.Equal(t, expected, v, "reused=%s", reused)
+	}
+}
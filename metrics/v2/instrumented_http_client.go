@@ -59,6 +59,15 @@ func NewInstrumentedTransportWithRules(rt http.RoundTripper, rules ...metrics.In
 	return &InstrumentedTransport{rt, c}
 }
 
+// NewInstrumentedTransportWithAttempts returns given RoundTripper with instrumentation capabilities based on given rules
+// for URI templating, which also counts requests by attempt number, see metrics.ContextWithAttempts.
+func NewInstrumentedTransportWithAttempts(rt http.RoundTripper, rules ...metrics.InstrumentRule) http.RoundTripper {
+	c := metrics.NewInstrumentedDefaultHttpClient()
+	c.SetRules(rules...)
+	c.SetAttemptTracking(true)
+	return &InstrumentedTransport{rt, c}
+}
+
 // NewInstrumentedDefaultTransport returns given RoundTripper with instrumentation capabilities based on given rules for URI templating.
 func NewInstrumentedDefaultTransport(rules ...metrics.InstrumentRule) http.RoundTripper {
 	c := metrics.NewInstrumentedDefaultHttpClient()
@@ -92,8 +101,13 @@ func NewHTTPRequestFromRequest(request *http.Request, urlVariables ...string) (*
 	return tmpl.Request.WithContext(ctx), nil
 }
 
+// SetAttemptTracking enables counting requests by attempt number, see metrics.InstrumentedHttpClient.SetAttemptTracking.
+func (hc2 *InstrumentedHTTPClient) SetAttemptTracking(enabled bool) {
+	hc2.iClient.SetAttemptTracking(enabled)
+}
+
 // Get is a metric instrumentation wrapper for Client.Get with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Get method documentation for details.
 func (hc2 *InstrumentedHTTPClient) Get(urlTemplate string, urlVariables ...string) (resp *http.Response, err error) {
 	response, error := hc2.iClient.Get(urlTemplate, urlVariables...)
@@ -101,7 +115,7 @@ func (hc2 *InstrumentedHTTPClient) Get(urlTemplate string, urlVariables ...strin
 }
 
 // Post is a metric instrumentation wrapper for Client.Post with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Post method documentation for details.
 func (hc2 *InstrumentedHTTPClient) Post(urlTemplate string, contentType string, body io.Reader, urlVariables ...string) (resp *http.Response, err error) {
 	response, error := hc2.iClient.Post(urlTemplate, contentType, body, urlVariables...)
@@ -109,11 +123,11 @@ func (hc2 *InstrumentedHTTPClient) Post(urlTemplate string, contentType string,
 }
 
 // Do is a metric instrumentation wrapper for Client.Do with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Do method documentation for details.
 func (hc2 *InstrumentedHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	now := time.Now()
-	response, error := hc2.hClient.Do(req)
+	response, error := hc2.hClient.Do(metrics.WithClientTrace(req))
 	keyVal := req.Context().Value(contextKeyURLTemplate)
 	var template string
 	if keyVal == nil {
@@ -126,7 +140,7 @@ func (hc2 *InstrumentedHTTPClient) Do(req *http.Request) (*http.Response, error)
 }
 
 // PostForm is a metric instrumentation wrapper for Client.PostForm with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.PostForm method documentation for details.
 func (hc2 *InstrumentedHTTPClient) PostForm(urlTemplate string, data url.Values, urlVariables ...string) (resp *http.Response, err error) {
 	response, error := hc2.iClient.PostForm(urlTemplate, data, urlVariables...)
@@ -134,7 +148,7 @@ func (hc2 *InstrumentedHTTPClient) PostForm(urlTemplate string, data url.Values,
 }
 
 // Head is a metric instrumentation wrapper for Client.Head with URL template support.
-// Instrumentation exposes metrics for request/response time and sizes.
+// Instrumentation exposes metrics for request/response time and sizes and connection setup.
 // See the Client.Head method documentation for details.
 func (hc2 *InstrumentedHTTPClient) Head(urlTemplate string, urlVariables ...string) (resp *http.Response, err error) {
 	response, error := hc2.iClient.Head(urlTemplate, urlVariables...)
@@ -142,10 +156,10 @@ func (hc2 *InstrumentedHTTPClient) Head(urlTemplate string, urlVariables ...stri
 }
 
 // RoundTrip implements http.RoundTripper. It forwards the request to the
-// next RoundTripper and instruments request.
+// next RoundTripper and instruments request and its connection setup.
 func (it *InstrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	now := time.Now()
-	resp, err := it.rt.RoundTrip(req)
+	resp, err := it.rt.RoundTrip(metrics.WithClientTrace(req))
 	keyVal := req.Context().Value(contextKeyURLTemplate)
 	var template string
 	if keyVal == nil {
@@ -0,0 +1,361 @@
+// Package metrics v3 provides single entry point with functional options for registering
+// application level metrics of the NEO services. Registration errors are returned instead of panicking.
+//
+//	requests, err := metrics.Register("requests_total",
+//		metrics.WithKind(metrics.KindCounter),
+//		metrics.WithSubsystem("orders"),
+//		metrics.WithHelp("Number of handled order requests."),
+//		metrics.WithLabels("method"),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	counter, err := requests.Counter("GET")
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Namespace is added as prefix to all metric names.
+	Namespace = "com_metrics"
+	// PlainMetricNameLabel is added to vector metrics with metric name without namespace and subsystem as value.
+	PlainMetricNameLabel = "_plain_metric_name"
+)
+
+// Kind is the type of a metric.
+type Kind int
+
+// Supported metric kinds.
+const (
+	KindCounter Kind = iota + 1
+	KindGauge
+	KindSummary
+	KindHistogram
+)
+
+// String returns name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	case KindSummary:
+		return "summary"
+	case KindHistogram:
+		return "histogram"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// ErrWrongKind is returned when handle of a different kind than registered is requested from Metric.
+var ErrWrongKind = errors.New("wrong metric kind")
+
+// Opt is a functional option type for Register.
+type Opt func(*options) error
+
+type options struct {
+	kind        Kind
+	subsystem   string
+	help        string
+	vec         bool
+	labels      []string
+	constLabels prometheus.Labels
+	objectives  map[float64]float64
+	buckets     []float64
+	native      bool
+	factor      float64
+	maxBuckets  uint32
+	minReset    time.Duration
+	registerer  prometheus.Registerer
+}
+
+// WithKind sets kind of the metric, it is required.
+func WithKind(kind Kind) Opt {
+	return func(o *options) error {
+		if kind < KindCounter || kind > KindHistogram {
+			return fmt.Errorf("unknown metric kind %s", kind)
+		}
+		o.kind = kind
+		return nil
+	}
+}
+
+// WithSubsystem sets subsystem, which is added to metric name after Namespace.
+func WithSubsystem(subsystem string) Opt {
+	return func(o *options) error {
+		o.subsystem = subsystem
+		return nil
+	}
+}
+
+// WithHelp sets description of the metric.
+func WithHelp(help string) Opt {
+	return func(o *options) error {
+		o.help = help
+		return nil
+	}
+}
+
+// WithLabels makes metric a vector with given label names. Values of the labels are given
+// in the same order to Metric methods returning handles. PlainMetricNameLabel is added to the labels.
+func WithLabels(keys ...string) Opt {
+	return func(o *options) error {
+		o.vec = true
+		o.labels = append(append([]string{}, keys...), PlainMetricNameLabel)
+		return nil
+	}
+}
+
+// WithConstLabels sets labels with static values.
+func WithConstLabels(labels prometheus.Labels) Opt {
+	return func(o *options) error {
+		o.constLabels = labels
+		return nil
+	}
+}
+
+// WithObjectives sets quantile ranks with their absolute errors of a summary.
+func WithObjectives(objectives map[float64]float64) Opt {
+	return func(o *options) error {
+		o.objectives = objectives
+		return nil
+	}
+}
+
+// WithBuckets sets classic bucket upper bounds of a histogram, prometheus.DefBuckets are used by default.
+func WithBuckets(buckets []float64) Opt {
+	return func(o *options) error {
+		o.buckets = buckets
+		return nil
+	}
+}
+
+// WithNativeHistogram makes histogram expose native histogram in addition to classic buckets.
+func WithNativeHistogram(bucketFactor float64, maxBucketNumber uint32, minResetDuration time.Duration) Opt {
+	return func(o *options) error {
+		if bucketFactor <= 1 {
+			return fmt.Errorf("native histogram bucket factor must be greater than 1, got %v", bucketFactor)
+		}
+		o.native = true
+		o.factor = bucketFactor
+		o.maxBuckets = maxBucketNumber
+		o.minReset = minResetDuration
+		return nil
+	}
+}
+
+// WithRegisterer sets registerer used instead of prometheus.DefaultRegisterer.
+func WithRegisterer(r prometheus.Registerer) Opt {
+	return func(o *options) error {
+		o.registerer = r
+		return nil
+	}
+}
+
+// Metric is a registered metric. Handles for observing values are returned by its methods
+// matching the kind of the metric.
+type Metric struct {
+	name       string
+	fqName     string
+	kind       Kind
+	vec        bool
+	collector  prometheus.Collector
+	registerer prometheus.Registerer
+}
+
+// Register creates metric with given name and options and registers it.
+// Error is returned when options are invalid or metric can't be registered,
+// e.g. when metric with the same name is already registered.
+func Register(name string, opts ...Opt) (*Metric, error) {
+	o := &options{registerer: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("invalid option for metric %s: %w", name, err)
+		}
+	}
+
+	fqName := prometheus.BuildFQName(Namespace, o.subsystem, name)
+	if name == "" {
+		return nil, errors.New("metric name is required")
+	}
+	if o.kind == 0 {
+		return nil, fmt.Errorf("kind of metric %s is required", fqName)
+	}
+	if o.objectives != nil && o.kind != KindSummary {
+		return nil, fmt.Errorf("objectives given for %s %s", o.kind, fqName)
+	}
+	if (o.buckets != nil || o.native) && o.kind != KindHistogram {
+		return nil, fmt.Errorf("buckets given for %s %s", o.kind, fqName)
+	}
+
+	collector := newCollector(name, o)
+	if err := o.registerer.Register(collector); err != nil {
+		return nil, fmt.Errorf("failed to register metric %s: %w", fqName, err)
+	}
+	return &Metric{
+		name:       name,
+		fqName:     fqName,
+		kind:       o.kind,
+		vec:        o.vec,
+		collector:  collector,
+		registerer: o.registerer,
+	}, nil
+}
+
+func newCollector(name string, o *options) prometheus.Collector {
+	switch o.kind {
+	case KindCounter:
+		opts := prometheus.CounterOpts{Namespace: Namespace, Subsystem: o.subsystem, Name: name, Help: o.help, ConstLabels: o.constLabels}
+		if o.vec {
+			return prometheus.NewCounterVec(opts, o.labels)
+		}
+		return prometheus.NewCounter(opts)
+	case KindGauge:
+		opts := prometheus.GaugeOpts{Namespace: Namespace, Subsystem: o.subsystem, Name: name, Help: o.help, ConstLabels: o.constLabels}
+		if o.vec {
+			return prometheus.NewGaugeVec(opts, o.labels)
+		}
+		return prometheus.NewGauge(opts)
+	case KindSummary:
+		opts := prometheus.SummaryOpts{
+			Namespace: Namespace, Subsystem: o.subsystem, Name: name, Help: o.help, ConstLabels: o.constLabels,
+			Objectives: o.objectives,
+		}
+		if o.vec {
+			return prometheus.NewSummaryVec(opts, o.labels)
+		}
+		return prometheus.NewSummary(opts)
+	default:
+		opts := prometheus.HistogramOpts{
+			Namespace: Namespace, Subsystem: o.subsystem, Name: name, Help: o.help, ConstLabels: o.constLabels,
+			Buckets: o.buckets,
+		}
+		if len(opts.Buckets) == 0 {
+			opts.Buckets = prometheus.DefBuckets
+		}
+		if o.native {
+			opts.NativeHistogramBucketFactor = o.factor
+			opts.NativeHistogramMaxBucketNumber = o.maxBuckets
+			opts.NativeHistogramMinResetDuration = o.minReset
+		}
+		if o.vec {
+			return prometheus.NewHistogramVec(opts, o.labels)
+		}
+		return prometheus.NewHistogram(opts)
+	}
+}
+
+// Name returns fully qualified name of the metric.
+func (m *Metric) Name() string { return m.fqName }
+
+// Kind returns kind of the metric.
+func (m *Metric) Kind() Kind { return m.kind }
+
+// Collector returns underlying prometheus collector.
+func (m *Metric) Collector() prometheus.Collector { return m.collector }
+
+// Unregister unregisters the metric.
+func (m *Metric) Unregister() bool {
+	return m.registerer.Unregister(m.collector)
+}
+
+// Counter returns counter for given label values of a counter metric.
+func (m *Metric) Counter(labelValues ...string) (prometheus.Counter, error) {
+	if err := m.checkKind(KindCounter); err != nil {
+		return nil, err
+	}
+	if !m.vec {
+		if err := m.checkNoLabels(labelValues); err != nil {
+			return nil, err
+		}
+		return m.collector.(prometheus.Counter), nil
+	}
+	c, err := m.collector.(*prometheus.CounterVec).GetMetricWithLabelValues(m.labelValues(labelValues)...)
+	return c, m.wrap(err)
+}
+
+// Gauge returns gauge for given label values of a gauge metric.
+func (m *Metric) Gauge(labelValues ...string) (prometheus.Gauge, error) {
+	if err := m.checkKind(KindGauge); err != nil {
+		return nil, err
+	}
+	if !m.vec {
+		if err := m.checkNoLabels(labelValues); err != nil {
+			return nil, err
+		}
+		return m.collector.(prometheus.Gauge), nil
+	}
+	g, err := m.collector.(*prometheus.GaugeVec).GetMetricWithLabelValues(m.labelValues(labelValues)...)
+	return g, m.wrap(err)
+}
+
+// Observer returns observer for given label values of a summary or histogram metric.
+func (m *Metric) Observer(labelValues ...string) (prometheus.Observer, error) {
+	if m.kind != KindSummary && m.kind != KindHistogram {
+		return nil, fmt.Errorf("%w: %s is %s, not summary or histogram", ErrWrongKind, m.fqName, m.kind)
+	}
+	if !m.vec {
+		if err := m.checkNoLabels(labelValues); err != nil {
+			return nil, err
+		}
+		return m.collector.(prometheus.Observer), nil
+	}
+	var (
+		o   prometheus.Observer
+		err error
+	)
+	if m.kind == KindSummary {
+		o, err = m.collector.(*prometheus.SummaryVec).GetMetricWithLabelValues(m.labelValues(labelValues)...)
+	} else {
+		o, err = m.collector.(*prometheus.HistogramVec).GetMetricWithLabelValues(m.labelValues(labelValues)...)
+	}
+	return o, m.wrap(err)
+}
+
+// Delete deletes series with given label values of a vector metric.
+func (m *Metric) Delete(labelValues ...string) bool {
+	if vec, ok := m.collector.(interface{ DeleteLabelValues(...string) bool }); ok {
+		return vec.DeleteLabelValues(m.labelValues(labelValues)...)
+	}
+	return false
+}
+
+// Reset deletes all series of a vector metric.
+func (m *Metric) Reset() {
+	if vec, ok := m.collector.(interface{ Reset() }); ok {
+		vec.Reset()
+	}
+}
+
+func (m *Metric) checkKind(kind Kind) error {
+	if m.kind != kind {
+		return fmt.Errorf("%w: %s is %s, not %s", ErrWrongKind, m.fqName, m.kind, kind)
+	}
+	return nil
+}
+
+func (m *Metric) checkNoLabels(labelValues []string) error {
+	if len(labelValues) != 0 {
+		return fmt.Errorf("metric %s has no labels, got %d label values", m.fqName, len(labelValues))
+	}
+	return nil
+}
+
+func (m *Metric) labelValues(labelValues []string) []string {
+	return append(append([]string{}, labelValues...), m.name)
+}
+
+func (m *Metric) wrap(err error) error {
+	if err != nil {
+		return fmt.Errorf("invalid label values for metric %s: %w", m.fqName, err)
+	}
+	return nil
+}
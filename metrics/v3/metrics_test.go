@@ -0,0 +1,119 @@
+package metrics_test
+
+import (
+	"testing"
+
+	metrics "github.com/phanitejak/kptgolib/metrics/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterCounterVec(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := metrics.Register("requests_total",
+		metrics.WithKind(metrics.KindCounter),
+		metrics.WithSubsystem("orders"),
+		metrics.WithHelp("Number of requests."),
+		metrics.WithLabels("method"),
+		metrics.WithRegisterer(reg),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "com_metrics_orders_requests_total", m.Name())
+
+	counter, err := m.Counter("GET")
+	require.NoError(t, err)
+	counter.Add(2)
+
+	_, err = m.Counter()
+	assert.Error(t, err)
+	_, err = m.Gauge("GET")
+	assert.ErrorIs(t, err, metrics.ErrWrongKind)
+
+	family := gather(t, reg, m.Name())
+	require.Len(t, family.GetMetric(), 1)
+	assert.Equal(t, 2.0, family.GetMetric()[0].GetCounter().GetValue())
+	assert.Equal(t, map[string]string{"method": "GET", metrics.PlainMetricNameLabel: "requests_total"},
+		labels(family.GetMetric()[0]))
+
+	_, err = metrics.Register("requests_total", metrics.WithKind(metrics.KindCounter),
+		metrics.WithSubsystem("orders"), metrics.WithRegisterer(reg))
+	assert.Error(t, err, "duplicate registration should fail")
+
+	assert.True(t, m.Delete("GET"))
+	assert.True(t, m.Unregister())
+}
+
+func TestRegisterObservers(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	summary, err := metrics.Register("latency_seconds",
+		metrics.WithKind(metrics.KindSummary),
+		metrics.WithObjectives(map[float64]float64{0.5: 0.05}),
+		metrics.WithConstLabels(prometheus.Labels{"app": "test"}),
+		metrics.WithRegisterer(reg),
+	)
+	require.NoError(t, err)
+	histogram, err := metrics.Register("size_bytes",
+		metrics.WithKind(metrics.KindHistogram),
+		metrics.WithBuckets([]float64{10, 100}),
+		metrics.WithLabels("kind"),
+		metrics.WithRegisterer(reg),
+	)
+	require.NoError(t, err)
+
+	o, err := summary.Observer()
+	require.NoError(t, err)
+	o.Observe(1)
+	o, err = histogram.Observer("a")
+	require.NoError(t, err)
+	o.Observe(50)
+	_, err = histogram.Counter("a")
+	assert.ErrorIs(t, err, metrics.ErrWrongKind)
+
+	s := gather(t, reg, summary.Name()).GetMetric()[0]
+	assert.Equal(t, uint64(1), s.GetSummary().GetSampleCount())
+	assert.Len(t, s.GetSummary().GetQuantile(), 1)
+	assert.Equal(t, map[string]string{"app": "test"}, labels(s))
+
+	h := gather(t, reg, histogram.Name()).GetMetric()[0]
+	require.Len(t, h.GetHistogram().GetBucket(), 2)
+	assert.Equal(t, uint64(0), h.GetHistogram().GetBucket()[0].GetCumulativeCount())
+	assert.Equal(t, uint64(1), h.GetHistogram().GetBucket()[1].GetCumulativeCount())
+}
+
+func TestRegisterInvalidOptions(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	for name, opts := range map[string][]metrics.Opt{
+		"missing kind":          nil,
+		"unknown kind":          {metrics.WithKind(42)},
+		"objectives of counter": {metrics.WithKind(metrics.KindCounter), metrics.WithObjectives(map[float64]float64{0.5: 0.05})},
+		"buckets of gauge":      {metrics.WithKind(metrics.KindGauge), metrics.WithBuckets([]float64{1})},
+		"invalid native factor": {metrics.WithKind(metrics.KindHistogram), metrics.WithNativeHistogram(1, 0, 0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := metrics.Register("invalid", append(opts, metrics.WithRegisterer(reg))...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func gather(t *testing.T, reg *prometheus.Registry, name string) *dto.MetricFamily {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	require.Failf(t, "metric not found", "metric %s", name)
+	return nil
+}
+
+func labels(m *dto.Metric) map[string]string {
+	l := map[string]string{}
+	for _, pair := range m.GetLabel() {
+		l[pair.GetName()] = pair.GetValue()
+	}
+	return l
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Provider can be implemented by a Module which provides dependencies for other modules.
+// Provides returns values which will be injected into modules requiring an interface implemented by them.
+type Provider interface {
+	Provides() []interface{}
+}
+
+// Requirer can be implemented by a Module which depends on other modules.
+// Requires returns pointers to interface typed variables, e.g. []interface{}{&m.sink}, which will be
+// set to values provided by other modules before Init is called.
+type Requirer interface {
+	Requires() []interface{}
+}
+
+// resolveDependencies injects provided values into modules requiring them and returns modules ordered
+// so that every module comes after the modules it depends on. Otherwise the original order is kept.
+func resolveDependencies(mods []Module) ([]Module, error) {
+	deps := make([][]int, len(mods))
+	for i, mod := range mods {
+		requirer, ok := mod.(Requirer)
+		if !ok {
+			continue
+		}
+
+		for _, req := range requirer.Requires() {
+			target := reflect.ValueOf(req)
+			if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Interface {
+				return nil, fmt.Errorf("module %T requires %T, expected non nil pointer to interface", mod, req)
+			}
+
+			provider, value, err := findProvider(mods, i, target.Elem().Type())
+			if err != nil {
+				return nil, err
+			}
+			target.Elem().Set(reflect.ValueOf(value))
+			deps[i] = append(deps[i], provider)
+		}
+	}
+
+	return sortModules(mods, deps)
+}
+
+func findProvider(mods []Module, requirer int, iface reflect.Type) (provider int, value interface{}, err error) {
+	provider = -1
+	for i, mod := range mods {
+		p, ok := mod.(Provider)
+		if !ok || i == requirer {
+			continue
+		}
+
+		for _, v := range p.Provides() {
+			if v == nil || !reflect.TypeOf(v).Implements(iface) {
+				continue
+			}
+			if provider != -1 {
+				return 0, nil, fmt.Errorf("%s required by module %T is provided by multiple modules: %T and %T",
+					iface, mods[requirer], mods[provider], mod)
+			}
+			provider, value = i, v
+		}
+	}
+
+	if provider == -1 {
+		return 0, nil, fmt.Errorf("%s required by module %T is not provided by any module", iface, mods[requirer])
+	}
+	return provider, value, nil
+}
+
+func sortModules(mods []Module, deps [][]int) ([]Module, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(mods))
+	sorted := make([]Module, 0, len(mods))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("cyclic dependency detected for module %T", mods[i])
+		}
+
+		state[i] = visiting
+		for _, dep := range deps[i] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		sorted = append(sorted, mods[i])
+		return nil
+	}
+
+	for i := range mods {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,123 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Greeter interface {
+	Greet() string
+}
+
+type Namer interface {
+	Name() string
+}
+
+type ProviderModule struct {
+	FnModule
+	provides []interface{}
+	requires []interface{}
+}
+
+func (m *ProviderModule) Provides() []interface{} { return m.provides }
+func (m *ProviderModule) Requires() []interface{} { return m.requires }
+
+type greeter struct{}
+
+func (greeter) Greet() string { return "hello" }
+
+type namer struct{}
+
+func (namer) Name() string { return "name" }
+
+func TestDependencyInjection(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	var inits []string
+	var g Greeter
+	consumer := &ProviderModule{requires: []interface{}{&g}}
+	consumer.FnModule = FnModule{
+		initFn: func() error {
+			inits = append(inits, "consumer")
+			require.NotNil(t, g, "dependency should be injected before Init")
+			assert.Equal(t, "hello", g.Greet())
+			return nil
+		},
+		runFn:   func() error { stop(); return nil },
+		closeFn: func() error { return nil },
+	}
+	provider := &ProviderModule{provides: []interface{}{greeter{}, namer{}}}
+	provider.FnModule = FnModule{
+		initFn: func() error {
+			inits = append(inits, "provider")
+			return nil
+		},
+		runFn:   func() error { <-ctx.Done(); return nil },
+		closeFn: func() error { return nil },
+	}
+
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+	exitCode := runner.NewRunner(ctx, log).Run(&App{modules: []runner.Module{consumer, provider}})
+	assert.Equal(t, 0, exitCode, "unexpected exit code")
+	assert.Equal(t, []string{"provider", "consumer"}, inits)
+}
+
+func TestDependencyInjectionFailures(t *testing.T) {
+	var g Greeter
+	var n Namer
+	notInitialized := func() error {
+		t.Error("Init should not be called")
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		modules func() []runner.Module
+	}{{
+		name: "MissingDependency",
+		modules: func() []runner.Module {
+			return []runner.Module{&ProviderModule{FnModule: FnModule{initFn: notInitialized}, requires: []interface{}{&g}}}
+		},
+	}, {
+		name: "AmbiguousDependency",
+		modules: func() []runner.Module {
+			return []runner.Module{
+				&ProviderModule{FnModule: FnModule{initFn: notInitialized}, requires: []interface{}{&g}},
+				&ProviderModule{FnModule: FnModule{initFn: notInitialized}, provides: []interface{}{greeter{}}},
+				&ProviderModule{FnModule: FnModule{initFn: notInitialized}, provides: []interface{}{greeter{}}},
+			}
+		},
+	}, {
+		name: "NotPointerToInterface",
+		modules: func() []runner.Module {
+			return []runner.Module{
+				&ProviderModule{FnModule: FnModule{initFn: notInitialized}, requires: []interface{}{g}},
+			}
+		},
+	}, {
+		name: "CyclicDependency",
+		modules: func() []runner.Module {
+			return []runner.Module{
+				&ProviderModule{FnModule: FnModule{initFn: notInitialized}, requires: []interface{}{&g}, provides: []interface{}{namer{}}},
+				&ProviderModule{FnModule: FnModule{initFn: notInitialized}, requires: []interface{}{&n}, provides: []interface{}{greeter{}}},
+			}
+		},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+			r := runner.NewRunner(context.Background(), log)
+			assert.Equal(t, 1, r.Run(&App{modules: tt.modules()}))
+			r.Ready()
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package electionmod provides leader election module.
+package electionmod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+	uuid "github.com/satori/go.uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var leaderGauge = mustRegisterLeaderGauge()
+
+func mustRegisterLeaderGauge() *metrics.CustomGaugeVec {
+	g, err := metrics.TryRegisterGaugeVec("leader", "election",
+		"Is 1 when this instance holds the leader election lock and 0 otherwise.",
+		[]string{"lock"}, metrics.ReuseExisting())
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Config contains leader election configuration.
+// Environment variables are the same as used by leaderselector package, durations are in seconds.
+type Config struct {
+	KubeconfigPath     string `envconfig:"KUBE_CONFIG_PATH" default:""`
+	LeaseLockNamespace string `envconfig:"K8S_LEASE_LOCK_NAMESPACE" default:"neo"`
+	LeaseDuration      int    `envconfig:"K8S_LEASE_DURATION" default:"60"`
+	RenewDeadline      int    `envconfig:"K8S_LEASE_RENEW_DEADLINE" default:"15"`
+	RetryPeriod        int    `envconfig:"K8S_LEASE_RETY_PERIOD" default:"5"`
+}
+
+// Opt is a functional option type for Elector.
+type Opt func(*Elector) error
+
+// WithLock sets lock used for the election.
+// Any resourcelock.Interface implementation can be used, e.g. one backed by a database or Kafka
+// when Kubernetes API is not available.
+func WithLock(lock resourcelock.Interface) Opt {
+	return func(e *Elector) error {
+		e.lock = lock
+		return nil
+	}
+}
+
+// WithTimings sets lease duration, renew deadline and retry period of the election.
+func WithTimings(leaseDuration, renewDeadline, retryPeriod time.Duration) Opt {
+	return func(e *Elector) error {
+		e.leaseDuration = leaseDuration
+		e.renewDeadline = renewDeadline
+		e.retryPeriod = retryPeriod
+		return nil
+	}
+}
+
+// WithLeaseLockFromEnv reads Config from environment variables and uses Kubernetes Lease with given name as lock.
+// Kubernetes client is configured from KUBE_CONFIG_PATH or in-cluster configuration when it is not set.
+// Validate checks only the configuration and doesn't create the client, so it works outside of the cluster.
+func WithLeaseLockFromEnv(lockName string) Opt {
+	return func(e *Elector) error {
+		conf := Config{}
+		if err := envconfig.Process("", &conf); err != nil {
+			return err
+		}
+		if lockName == "" || conf.LeaseLockNamespace == "" {
+			return errors.New("lease lock name and namespace must not be empty")
+		}
+		e.leaseDuration = time.Duration(conf.LeaseDuration) * time.Second
+		e.renewDeadline = time.Duration(conf.RenewDeadline) * time.Second
+		e.retryPeriod = time.Duration(conf.RetryPeriod) * time.Second
+
+		lock := &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      lockName,
+				Namespace: conf.LeaseLockNamespace,
+			},
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: uuid.NewV4().String(),
+			},
+		}
+		if !e.validating {
+			restConf, err := buildKubeconfig(conf.KubeconfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to build kubernetes config: %w", err)
+			}
+			client, err := kubernetes.NewForConfig(restConf)
+			if err != nil {
+				return fmt.Errorf("failed to create kubernetes client: %w", err)
+			}
+			lock.Client = client.CoordinationV1()
+		}
+		e.lock = lock
+		return nil
+	}
+}
+
+// WithCallbacks sets functions called when this instance starts and stops leading.
+// Context given to onStarted is cancelled when leadership is lost or Close is called.
+// Work started in onStarted must be stopped before onStopped returns,
+// otherwise it may overlap with work of the next leader.
+func WithCallbacks(onStarted func(ctx context.Context), onStopped func()) Opt {
+	return func(e *Elector) error {
+		e.onStarted = onStarted
+		e.onStopped = onStopped
+		return nil
+	}
+}
+
+// Elector runs leader election as module, so that singleton jobs run only on one of the replicas.
+type Elector struct {
+	opts          []Opt
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+	lock          resourcelock.Interface
+	logger        *tracing.Logger
+	onStarted     func(ctx context.Context)
+	onStopped     func()
+	leader        atomic.Bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+	done          chan struct{}
+	closeOnce     sync.Once
+	// validating is set for the copy of Elector created by Validate, so that options don't connect to dependencies.
+	validating bool
+}
+
+// NewElector creates new instance of Elector with given options.
+// Lock must be given with WithLock or WithLeaseLockFromEnv.
+func NewElector(opts ...Opt) *Elector {
+	return &Elector{
+		opts:          opts,
+		leaseDuration: 60 * time.Second,
+		renewDeadline: 15 * time.Second,
+		retryPeriod:   5 * time.Second,
+	}
+}
+
+// Init applies all options.
+func (e *Elector) Init(l *tracing.Logger) error {
+	e.logger = l
+	for _, opt := range e.opts {
+		if err := opt(e); err != nil {
+			return fmt.Errorf("failed to apply option for electionmod.Elector: %w", err)
+		}
+	}
+	if e.lock == nil {
+		return errors.New("lock is not configured for electionmod.Elector")
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	e.done = make(chan struct{})
+	leaderGauge.GetCustomGauge(e.lock.Describe()).Set(0)
+	return nil
+}
+
+// Validate applies all options to a copy of the elector and checks the lock and timings without taking part
+// in the election. Lease duration must be greater than renew deadline, which must be greater than retry period
+// multiplied by leaderelection.JitterFactor.
+func (e *Elector) Validate() error {
+	v := NewElector(e.opts...)
+	v.validating = true
+	for _, opt := range v.opts {
+		if err := opt(v); err != nil {
+			return fmt.Errorf("failed to apply option for electionmod.Elector: %w", err)
+		}
+	}
+	if v.lock == nil {
+		return errors.New("lock is not configured for electionmod.Elector")
+	}
+	if v.leaseDuration <= v.renewDeadline {
+		return fmt.Errorf("lease duration %v must be greater than renew deadline %v", v.leaseDuration, v.renewDeadline)
+	}
+	if v.retryPeriod <= 0 || float64(v.renewDeadline) <= leaderelection.JitterFactor*float64(v.retryPeriod) {
+		return fmt.Errorf("renew deadline %v must be greater than %v times retry period %v",
+			v.renewDeadline, leaderelection.JitterFactor, v.retryPeriod)
+	}
+	return nil
+}
+
+// Provides returns Elector itself, so that modules can require it to check leadership.
+func (e *Elector) Provides() []interface{} {
+	return []interface{}{e}
+}
+
+// IsLeader tells whether this instance currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Identity returns identity of this instance in the election.
+func (e *Elector) Identity() string {
+	return e.lock.Identity()
+}
+
+// Run takes part in the election until Close is called.
+// When leadership is lost, instance becomes a candidate again.
+func (e *Elector) Run() error {
+	defer close(e.done)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            e.lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   e.leaseDuration,
+		RenewDeadline:   e.renewDeadline,
+		RetryPeriod:     e.retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: e.startedLeading,
+			OnStoppedLeading: e.stoppedLeading,
+			OnNewLeader: func(identity string) {
+				if identity != e.lock.Identity() {
+					e.logger.Infof("new leader elected: %s", identity)
+				}
+			},
+		},
+		Name: e.lock.Describe(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	for e.ctx.Err() == nil {
+		elector.Run(e.ctx)
+	}
+	return nil
+}
+
+// Close stops taking part in the election, releasing the lock if it is held, and makes Run to return.
+func (e *Elector) Close() error {
+	e.closeOnce.Do(e.cancel)
+	<-e.done
+	return nil
+}
+
+func (e *Elector) startedLeading(ctx context.Context) {
+	// Callback is started in a goroutine, leadership may be already lost.
+	if ctx.Err() != nil {
+		return
+	}
+	e.logger.Infof("started leading with id: %s", e.lock.Identity())
+	e.leader.Store(true)
+	leaderGauge.GetCustomGauge(e.lock.Describe()).Set(1)
+	if e.onStarted != nil {
+		e.onStarted(ctx)
+	}
+}
+
+func (e *Elector) stoppedLeading() {
+	if !e.leader.Swap(false) {
+		return
+	}
+	e.logger.Infof("stopped leading with id: %s", e.lock.Identity())
+	leaderGauge.GetCustomGauge(e.lock.Describe()).Set(0)
+	if e.onStopped != nil {
+		e.onStopped()
+	}
+}
+
+func buildKubeconfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	return rest.InClusterConfig()
+}
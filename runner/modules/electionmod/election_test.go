@@ -0,0 +1,143 @@
+package electionmod_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/runner/modules/electionmod"
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+func TestElectorElectsSingleLeader(t *testing.T) {
+	store := &memoryStore{}
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	var lock sync.Mutex
+	leading := map[string]bool{}
+	newElector := func(id string) *electionmod.Elector {
+		e := electionmod.NewElector(
+			electionmod.WithLock(&memoryLock{store: store, id: id}),
+			electionmod.WithTimings(time.Second, 500*time.Millisecond, 100*time.Millisecond),
+			electionmod.WithCallbacks(
+				func(context.Context) { lock.Lock(); leading[id] = true; lock.Unlock() },
+				func() { lock.Lock(); leading[id] = false; lock.Unlock() },
+			),
+		)
+		require.NoError(t, e.Init(log))
+		return e
+	}
+	isLeading := func(id string) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return leading[id]
+	}
+
+	first, second := newElector("first"), newElector("second")
+	firstDone := run(t, first)
+	require.Eventually(t, first.IsLeader, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return isLeading("first") }, time.Second, 10*time.Millisecond)
+
+	secondDone := run(t, second)
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, second.IsLeader())
+
+	require.NoError(t, first.Close())
+	<-firstDone
+	assert.False(t, first.IsLeader())
+	assert.False(t, isLeading("first"))
+
+	require.Eventually(t, second.IsLeader, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return isLeading("second") }, time.Second, 10*time.Millisecond)
+	require.NoError(t, second.Close())
+	<-secondDone
+	assert.False(t, isLeading("second"))
+}
+
+func TestElectorRequiresLock(t *testing.T) {
+	e := electionmod.NewElector()
+	assert.Error(t, e.Init(tracing.NewLogger(loggingtest.NewTestLogger(t))))
+}
+
+func TestElectorValidate(t *testing.T) {
+	lock := &memoryLock{store: &memoryStore{}, id: "validated"}
+	assert.NoError(t, electionmod.NewElector(electionmod.WithLock(lock)).Validate())
+	assert.Error(t, electionmod.NewElector().Validate())
+	assert.Error(t, electionmod.NewElector(electionmod.WithLock(lock),
+		electionmod.WithTimings(time.Second, 2*time.Second, 100*time.Millisecond)).Validate())
+	assert.Error(t, electionmod.NewElector(electionmod.WithLock(lock),
+		electionmod.WithTimings(10*time.Second, time.Second, time.Second)).Validate())
+}
+
+func TestElectorValidateLeaseLockFromEnv(t *testing.T) {
+	t.Setenv("KUBE_CONFIG_PATH", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	assert.NoError(t, electionmod.NewElector(electionmod.WithLeaseLockFromEnv("lock")).Validate(),
+		"validation should not require kubernetes client")
+	assert.Error(t, electionmod.NewElector(electionmod.WithLeaseLockFromEnv("")).Validate())
+
+	t.Setenv("K8S_LEASE_DURATION", "not-a-number")
+	assert.Error(t, electionmod.NewElector(electionmod.WithLeaseLockFromEnv("lock")).Validate())
+	t.Setenv("K8S_LEASE_DURATION", "10")
+	assert.Error(t, electionmod.NewElector(electionmod.WithLeaseLockFromEnv("lock")).Validate())
+}
+
+func run(t *testing.T, e *electionmod.Elector) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, e.Run())
+	}()
+	return done
+}
+
+type memoryStore struct {
+	lock   sync.Mutex
+	record *resourcelock.LeaderElectionRecord
+}
+
+type memoryLock struct {
+	store *memoryStore
+	id    string
+}
+
+func (l *memoryLock) Get(context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	l.store.lock.Lock()
+	defer l.store.lock.Unlock()
+	if l.store.record == nil {
+		return nil, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, "test")
+	}
+	record := *l.store.record
+	return &record, []byte(record.HolderIdentity + record.RenewTime.String()), nil
+}
+
+func (l *memoryLock) Create(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.store.lock.Lock()
+	defer l.store.lock.Unlock()
+	if l.store.record != nil {
+		return errors.New("already exists")
+	}
+	l.store.record = &ler
+	return nil
+}
+
+func (l *memoryLock) Update(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.store.lock.Lock()
+	defer l.store.lock.Unlock()
+	l.store.record = &ler
+	return nil
+}
+
+func (l *memoryLock) RecordEvent(string) {}
+
+func (l *memoryLock) Identity() string { return l.id }
+
+func (l *memoryLock) Describe() string { return "memory/test" }
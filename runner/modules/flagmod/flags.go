@@ -0,0 +1,322 @@
+// Package flagmod provides feature flags as module.
+package flagmod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+var (
+	evaluations    = mustRegisterCounterVec("evaluations_total", "Total number of feature flag evaluations by flag and result.", "flag", "result")
+	reloadFailures = mustRegisterCounterVec("reload_failures_total", "Total number of failed feature flag reloads.")
+)
+
+func mustRegisterCounterVec(name, desc string, keys ...string) metrics.CounterVec {
+	c, err := metrics.TryRegisterCounterVec(name, "feature_flags", desc, keys, metrics.ReuseExisting())
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Flag is state of a feature flag. Tenants override Enabled for given tenants.
+type Flag struct {
+	Enabled bool            `json:"enabled"`
+	Tenants map[string]bool `json:"tenants,omitempty"`
+}
+
+// Flags are feature flags by name.
+type Flags map[string]Flag
+
+// Source loads feature flags, e.g. from a file or a remote provider like Unleash.
+type Source interface {
+	Load(ctx context.Context) (Flags, error)
+}
+
+// SourceFunc is a function implementing Source.
+type SourceFunc func(ctx context.Context) (Flags, error)
+
+// Load calls f.
+func (f SourceFunc) Load(ctx context.Context) (Flags, error) {
+	return f(ctx)
+}
+
+// EnvSource loads flags from FEATURE_FLAGS environment variable having comma separated flags in format
+// name=bool or name@tenant=bool for tenant overrides, e.g. "new-ui=true,beta=false,beta@tenant-a=true".
+func EnvSource() Source {
+	return SourceFunc(func(context.Context) (Flags, error) {
+		conf := struct {
+			Flags []string `envconfig:"FEATURE_FLAGS"`
+		}{}
+		if err := envconfig.Process("", &conf); err != nil {
+			return nil, err
+		}
+		return ParseFlags(conf.Flags)
+	})
+}
+
+// ParseFlags parses flags in format name=bool or name@tenant=bool, see EnvSource.
+func ParseFlags(values []string) (Flags, error) {
+	flags := Flags{}
+	for _, value := range values {
+		key, enabledValue, ok := strings.Cut(strings.TrimSpace(value), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid feature flag %s: expected format name=bool or name@tenant=bool", value)
+		}
+		enabled, err := strconv.ParseBool(enabledValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of feature flag %s: %w", value, err)
+		}
+		name, tenant, isOverride := strings.Cut(key, "@")
+		flag := flags[name]
+		if !isOverride {
+			flag.Enabled = enabled
+		} else {
+			if flag.Tenants == nil {
+				flag.Tenants = map[string]bool{}
+			}
+			flag.Tenants[tenant] = enabled
+		}
+		flags[name] = flag
+	}
+	return flags, nil
+}
+
+// FileSource loads flags from JSON file, e.g. a mounted ConfigMap key, which is read again on every reload:
+//
+//	{"new-ui": {"enabled": true}, "beta": {"enabled": false, "tenants": {"tenant-a": true}}}
+func FileSource(path string) Source {
+	return SourceFunc(func(context.Context) (Flags, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return decodeFlags(f, path)
+	})
+}
+
+// HTTPSource loads flags in the same JSON format as FileSource from given URL, e.g. a remote flag provider
+// or a proxy in front of it. http.DefaultClient is used if client is nil.
+func HTTPSource(url string, client *http.Client) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return SourceFunc(func(ctx context.Context) (Flags, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d when loading feature flags from %s", resp.StatusCode, url)
+		}
+		return decodeFlags(resp.Body, url)
+	})
+}
+
+func decodeFlags(r io.Reader, source string) (Flags, error) {
+	flags := Flags{}
+	if err := json.NewDecoder(r).Decode(&flags); err != nil {
+		return nil, fmt.Errorf("failed to decode feature flags from %s: %w", source, err)
+	}
+	return flags, nil
+}
+
+type tenantKey struct{}
+
+// WithTenant returns context with tenant, which overrides of feature flags are used by IsEnabled.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns tenant set with WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// Opt is a functional option type for Flagger.
+type Opt func(*Flagger) error
+
+// WithSources sets sources of feature flags instead of EnvSource. Flags are merged in given order,
+// so that later sources override flags of earlier ones, e.g. FileSource overriding defaults from EnvSource.
+func WithSources(sources ...Source) Opt {
+	return func(f *Flagger) error {
+		if len(sources) == 0 {
+			return errors.New("at least one feature flag source is required")
+		}
+		f.sources = sources
+		return nil
+	}
+}
+
+// WithReloadInterval sets how often flags are reloaded from the sources, 30 seconds by default.
+// Zero interval disables reloading.
+func WithReloadInterval(interval time.Duration) Opt {
+	return func(f *Flagger) error {
+		if interval < 0 {
+			return fmt.Errorf("reload interval must not be negative, got %s", interval)
+		}
+		f.interval = interval
+		return nil
+	}
+}
+
+// Flagger runs as module, which loads feature flags on Init and reloads them until Close is called.
+// Other modules can require it to evaluate flags. It implements runner.Reloader, so that flags are
+// reloaded also by runner.WithReloadOn, e.g. on SIGHUP.
+type Flagger struct {
+	opts     []Opt
+	sources  []Source
+	interval time.Duration
+	logger   *tracing.Logger
+
+	mu    sync.RWMutex
+	flags Flags
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates Flagger with given options, by default flags are loaded from EnvSource.
+func New(opts ...Opt) *Flagger {
+	return &Flagger{
+		opts:     opts,
+		sources:  []Source{EnvSource()},
+		interval: 30 * time.Second,
+	}
+}
+
+// Init applies all options and loads flags.
+func (f *Flagger) Init(l *tracing.Logger) error {
+	f.logger = l
+	for _, opt := range f.opts {
+		if err := opt(f); err != nil {
+			return fmt.Errorf("failed to apply option for flagmod.Flagger: %w", err)
+		}
+	}
+	f.done = make(chan struct{})
+	flags, err := f.load(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+	f.flags = flags
+	return nil
+}
+
+// Provides returns Flagger itself, so that modules can require it to evaluate flags.
+func (f *Flagger) Provides() []interface{} {
+	return []interface{}{f}
+}
+
+// Run reloads flags in the configured interval until Close is called. If reloading fails,
+// previously loaded flags are kept.
+func (f *Flagger) Run() error {
+	if f.interval == 0 {
+		<-f.done
+		return nil
+	}
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.done:
+			return nil
+		case <-ticker.C:
+			if err := f.Reload(); err != nil {
+				f.logger.Errorf("%s, keeping previous flags", err)
+			}
+		}
+	}
+}
+
+// Close makes Run to return.
+func (f *Flagger) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+	return nil
+}
+
+// Reload loads flags from the sources immediately, e.g. when a change is notified by the provider.
+// Previously loaded flags are kept when an error is returned.
+func (f *Flagger) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	flags, err := f.load(ctx)
+	if err != nil {
+		reloadFailures.GetCustomCounter().Inc()
+		return fmt.Errorf("failed to reload feature flags: %w", err)
+	}
+
+	f.mu.Lock()
+	changed := !reflect.DeepEqual(f.flags, flags)
+	f.flags = flags
+	f.mu.Unlock()
+	if changed {
+		f.logger.Infof("feature flags changed: %v", flags)
+	}
+	return nil
+}
+
+var _ runner.Reloader = (*Flagger)(nil)
+
+func (f *Flagger) load(ctx context.Context) (Flags, error) {
+	merged := Flags{}
+	for _, source := range f.sources {
+		flags, err := source.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for name, flag := range flags {
+			merged[name] = flag
+		}
+	}
+	return merged, nil
+}
+
+// IsEnabled tells whether flag is enabled for the tenant in ctx, see WithTenant. Tenant overrides take
+// precedence over the flag state and unknown flags are disabled.
+func (f *Flagger) IsEnabled(ctx context.Context, flag string) bool {
+	f.mu.RLock()
+	state := f.flags[flag]
+	f.mu.RUnlock()
+
+	enabled := state.Enabled
+	if tenant, ok := TenantFromContext(ctx); ok {
+		if override, ok := state.Tenants[tenant]; ok {
+			enabled = override
+		}
+	}
+	evaluations.GetCustomCounter(flag, strconv.FormatBool(enabled)).Inc()
+	return enabled
+}
+
+// Flags returns copy of currently loaded flags.
+func (f *Flagger) Flags() Flags {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	flags := make(Flags, len(f.flags))
+	for name, flag := range f.flags {
+		flags[name] = flag
+	}
+	return flags
+}
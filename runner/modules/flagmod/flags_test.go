@@ -0,0 +1,120 @@
+package flagmod_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/phanitejak/kptgolib/runner/modules/flagmod"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+func TestFlaggerTenantOverrides(t *testing.T) {
+	t.Setenv("FEATURE_FLAGS", "new-ui=true,beta=false,beta@tenant-a=true,new-ui@tenant-b=false")
+	flagger := flagmod.New(flagmod.WithReloadInterval(0))
+	require.NoError(t, flagger.Init(tracing.NewLogger(loggingtest.NewTestLogger(t))))
+
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		flag   string
+		expect bool
+	}{
+		{name: "Enabled", ctx: ctx, flag: "new-ui", expect: true},
+		{name: "Disabled", ctx: ctx, flag: "beta", expect: false},
+		{name: "Unknown", ctx: ctx, flag: "unknown", expect: false},
+		{name: "EnabledForTenant", ctx: flagmod.WithTenant(ctx, "tenant-a"), flag: "beta", expect: true},
+		{name: "DisabledForTenant", ctx: flagmod.WithTenant(ctx, "tenant-b"), flag: "new-ui", expect: false},
+		{name: "OtherTenant", ctx: flagmod.WithTenant(ctx, "tenant-c"), flag: "new-ui", expect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, flagger.IsEnabled(tt.ctx, tt.flag))
+		})
+	}
+}
+
+func TestFlaggerReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"beta": {"enabled": false}}`), 0o600))
+	remote := `{"remote": {"enabled": true}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(remote))
+	}))
+	defer server.Close()
+
+	flagger := flagmod.New(
+		flagmod.WithSources(flagmod.FileSource(path), flagmod.HTTPSource(server.URL, nil)),
+		flagmod.WithReloadInterval(10*time.Millisecond),
+	)
+	require.NoError(t, flagger.Init(tracing.NewLogger(loggingtest.NewTestLogger(t))))
+	assert.Equal(t, flagmod.Flags{"beta": {}, "remote": {Enabled: true}}, flagger.Flags())
+
+	done := make(chan error)
+	go func() { done <- flagger.Run() }()
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"beta": {"enabled": true}}`), 0o600))
+	assert.Eventually(t, func() bool { return flagger.IsEnabled(context.Background(), "beta") }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{invalid`), 0o600))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, flagger.IsEnabled(context.Background(), "beta"), "previous flags should be kept when reload fails")
+
+	require.NoError(t, flagger.Close())
+	require.NoError(t, <-done)
+}
+
+func TestFlaggerReloadOnSignal(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	path := filepath.Join(t.TempDir(), "flags.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"beta": {"enabled": false}}`), 0o600))
+	flagger := flagmod.New(flagmod.WithSources(flagmod.FileSource(path)), flagmod.WithReloadInterval(0))
+	r := runner.NewRunner(ctx, log, runner.WithReloadOn(syscall.SIGHUP))
+
+	exitCode := make(chan int)
+	go func() { exitCode <- r.Run(app{flagger}) }()
+	r.Ready()
+	assert.False(t, flagger.IsEnabled(context.Background(), "beta"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"beta": {"enabled": true}}`), 0o600))
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return flagger.IsEnabled(context.Background(), "beta") }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{invalid`), 0o600))
+	assert.Error(t, r.Reload())
+	assert.True(t, flagger.IsEnabled(context.Background(), "beta"), "previous flags should be kept when reload fails")
+
+	stop()
+	assert.Equal(t, 0, <-exitCode)
+}
+
+type app []runner.Module
+
+func (a app) Name() string             { return "flag-app" }
+func (a app) Modules() []runner.Module { return a }
+
+func TestFlaggerInitErrors(t *testing.T) {
+	logger := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	t.Setenv("FEATURE_FLAGS", "beta=maybe")
+	assert.Error(t, flagmod.New().Init(logger))
+	assert.Error(t, flagmod.New(flagmod.WithSources(flagmod.FileSource(filepath.Join(t.TempDir(), "missing.json")))).Init(logger))
+	assert.Error(t, flagmod.New(flagmod.WithSources()).Init(logger))
+	assert.Error(t, flagmod.New(flagmod.WithReloadInterval(-time.Second)).Init(logger))
+}
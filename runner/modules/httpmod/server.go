@@ -70,6 +70,7 @@ func WithManagementServer() Opt {
 		metrics.InstrumentWithPprof(mux)
 		mux.HandleFunc("/status", func(http.ResponseWriter, *http.Request) {})
 		s.srv.Handler = metrics.InstrumentHTTPHandler(mux)
+		s.management = true
 		return nil
 	}
 }
@@ -86,6 +87,7 @@ func WithMetrics() Opt {
 		metrics.InstrumentWithPprof(mux)
 		mux.HandleFunc("/status", func(http.ResponseWriter, *http.Request) {})
 		s.srv.Handler = metrics.InstrumentHTTPHandler(mux)
+		s.management = true
 		return nil
 	}
 }
@@ -95,6 +97,8 @@ type Server struct {
 	srv  *http.Server
 	ln   net.Listener
 	opts []Opt
+	// management is set when server exposes metrics, so shutdown is reported via metrics.
+	management bool
 }
 
 // NewServer creates new instance of Server with given options.
@@ -106,11 +110,8 @@ func NewServer(opts ...Opt) *Server {
 
 // Init applies all options and calls calls net.Listen with Servers address.
 func (s *Server) Init(_ *tracing.Logger) error {
-	s.srv = &http.Server{}
-	for _, opt := range s.opts {
-		if err := opt(s); err != nil {
-			return fmt.Errorf("failed to applie option for httpmod.Server: %w", err)
-		}
+	if err := s.applyOpts(); err != nil {
+		return err
 	}
 
 	ln, err := net.Listen("tcp", s.srv.Addr)
@@ -122,6 +123,36 @@ func (s *Server) Init(_ *tracing.Logger) error {
 	return nil
 }
 
+// Validate applies all options to a copy of the server and checks its address without listening on it.
+// Empty address is valid, listener is bound to a random port then.
+func (s *Server) Validate() error {
+	v := &Server{opts: s.opts}
+	if err := v.applyOpts(); err != nil {
+		return err
+	}
+	if v.srv.Addr == "" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(v.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid server address %q: %w", v.srv.Addr, err)
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return fmt.Errorf("invalid server address %q: %w", v.srv.Addr, err)
+	}
+	return nil
+}
+
+func (s *Server) applyOpts() error {
+	s.srv = &http.Server{}
+	for _, opt := range s.opts {
+		if err := opt(s); err != nil {
+			return fmt.Errorf("failed to applie option for httpmod.Server: %w", err)
+		}
+	}
+	return nil
+}
+
 // URL return http address of server. It can be used only after Init() is called.
 func (s *Server) URL() string {
 	return "http://" + s.ln.Addr().String()
@@ -137,6 +168,10 @@ func (s *Server) Run() error {
 }
 
 // Close shutsdown server gracefully.
+// For servers created with WithManagementServer or WithMetrics, metrics.MarkShutdownStarted is called first.
 func (s *Server) Close() error {
+	if s.management {
+		metrics.MarkShutdownStarted()
+	}
 	return s.srv.Shutdown(context.Background())
 }
@@ -159,3 +159,13 @@ func StatusHandler(statusCode int) http.Handler {
 		w.WriteHeader(statusCode)
 	})
 }
+
+func TestServerValidate(t *testing.T) {
+	assert.NoError(t, httpmod.NewServer().Validate())
+	assert.NoError(t, httpmod.NewServer(httpmod.WithAddr(":8080"), httpmod.WithManagementServer()).Validate())
+	assert.Error(t, httpmod.NewServer(httpmod.WithAddr("localhost")).Validate())
+	assert.Error(t, httpmod.NewServer(httpmod.WithAddr(":port")).Validate())
+
+	optErr := errors.New("")
+	assert.ErrorIs(t, httpmod.NewServer(func(s *httpmod.Server) error { return optErr }).Validate(), optErr)
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/hashicorp/go-multierror"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/phanitejak/kptgolib/kafka"
 	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/runner"
 	"github.com/phanitejak/kptgolib/tracing"
 )
 
@@ -21,6 +23,8 @@ type ConsumerConfig struct {
 	MetricsPrefix      string   `envconfig:"KAFKA_CONSUMER_METRICS_PREFIX" default:""`
 	Group              string   `envconfig:"KAFKA_CONSUMER_GROUP" required:"true"`
 	PreClaimPartitions bool     `envconfig:"KAFKA_CONSUMER_GROUP_PRE_CLAIM_PARTITIONS" default:"false"`
+	// RebalanceStrategies is priority-ordered list of partition assignment strategies, see kafka.BalanceStrategies.
+	RebalanceStrategies []string `envconfig:"KAFKA_CONSUMER_REBALANCE_STRATEGY"`
 }
 
 // ConsumerOpt for Consumer.
@@ -70,6 +74,25 @@ func WithConsumerMetricsPrefix(prefix string) ConsumerOpt {
 	}
 }
 
+// WithConsumerRebalanceStrategies sets priority-ordered list of partition assignment strategies:
+// "range" (default), "roundrobin" or "sticky". See kafka.BalanceStrategies.
+func WithConsumerRebalanceStrategies(names ...string) ConsumerOpt {
+	return func(c *Consumer) error {
+		c.conf.RebalanceStrategies = names
+		return nil
+	}
+}
+
+// WithConsumerReadinessGate delays consuming messages in Run until dependencies checked by gate are healthy.
+// Run returns error wrapping runner.ErrNotReady if they don't become healthy within the gate timeout.
+// With PreClaimPartitions partitions are claimed in Init, but messages are not forwarded before the gate opens.
+func WithConsumerReadinessGate(gate *runner.ReadinessGate) ConsumerOpt {
+	return func(c *Consumer) error {
+		c.gate = gate
+		return nil
+	}
+}
+
 // PreClaimPartitions will claim partition already when Init() is called but only starts forwarding messages once Run() is called.
 func PreClaimPartitions() ConsumerOpt {
 	return func(c *Consumer) error {
@@ -83,6 +106,7 @@ type Consumer struct {
 	opts       []ConsumerOpt
 	conf       ConsumerConfig
 	saramaConf *sarama.Config
+	gate       *runner.ReadinessGate
 
 	client  sarama.ConsumerGroup
 	handler *handlerWrapper
@@ -106,23 +130,8 @@ func (c *Consumer) Init(l *tracing.Logger) error {
 	c.errCh = make(chan error, 1)
 	c.runFinished = make(chan struct{})
 	c.runOnce = &sync.Once{}
-	c.saramaConf = sarama.NewConfig()
-	c.saramaConf.Version = sarama.V1_0_0_0
-	c.saramaConf.Consumer.Offsets.Initial = sarama.OffsetOldest
-
-	c.handler = &handlerWrapper{
-		log:     l,
-		handler: nil, // Handler has to be set using WithHandler Opt.
-	}
-
-	for _, opt := range c.opts {
-		if err := opt(c); err != nil {
-			return fmt.Errorf("failed to apply option: %w", err)
-		}
-	}
-
-	if c.handler.handler == nil {
-		return fmt.Errorf("message handler was not set")
+	if err := c.configure(l); err != nil {
+		return err
 	}
 
 	prefix := c.conf.Group
@@ -152,10 +161,68 @@ func (c *Consumer) Init(l *tracing.Logger) error {
 	return nil
 }
 
+// Validate applies all options to a copy of the consumer and checks its configuration without connecting to brokers.
+func (c *Consumer) Validate() error {
+	v := &Consumer{opts: c.opts}
+	if err := v.configure(nil); err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	if len(v.conf.Brokers) == 0 {
+		result = multierror.Append(result, errors.New("kafka brokers are not configured"))
+	}
+	if len(v.conf.Topics) == 0 {
+		result = multierror.Append(result, errors.New("consumed topics are not configured"))
+	}
+	if v.conf.Group == "" {
+		result = multierror.Append(result, errors.New("consumer group is not configured"))
+	}
+	if err := v.saramaConf.Validate(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("invalid sarama configuration: %w", err))
+	}
+	return result.ErrorOrNil()
+}
+
+// configure sets default sarama configuration and applies all given options.
+func (c *Consumer) configure(l *tracing.Logger) error {
+	c.saramaConf = sarama.NewConfig()
+	c.saramaConf.Version = sarama.V1_0_0_0
+	c.saramaConf.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	c.handler = &handlerWrapper{
+		log:     l,
+		handler: nil, // Handler has to be set using WithHandler Opt.
+	}
+
+	for _, opt := range c.opts {
+		if err := opt(c); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	if c.handler.handler == nil {
+		return fmt.Errorf("message handler was not set")
+	}
+
+	return kafka.SetBalanceStrategies(c.saramaConf, c.conf.RebalanceStrategies...)
+}
+
 // Run starts consuming messages and returns only if Close is called or consuming messages fails.
 func (c *Consumer) Run() error {
 	defer close(c.runFinished)
 
+	if c.gate != nil {
+		c.handler.log.Info("waiting for dependencies before consuming messages")
+		if err := c.gate.Wait(c.ctx); err != nil {
+			if c.ctx.Err() != nil {
+				return nil // Closed while waiting.
+			}
+			return fmt.Errorf("consumer not started: %w", err)
+		}
+		c.handler.log.Info("dependencies ready")
+	}
+
 	// Signal handler that it can start forwarding messages
 	close(c.handler.ready)
 	go c.consume()
@@ -227,7 +294,11 @@ func (h *handlerWrapper) ConsumeClaim(session sarama.ConsumerGroupSession, claim
 	l.Info("claim received")
 
 	// Start forwarding messages once h.ready is closed.
-	<-h.ready
+	select {
+	case <-h.ready:
+	case <-session.Context().Done():
+		return nil
+	}
 
 	if err := h.handler.ConsumeClaim(session, claim); err != nil {
 		h.cancel()
@@ -12,6 +12,7 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/phanitejak/kptgolib/kafka"
+	"github.com/phanitejak/kptgolib/kafka/kafkatest"
 	"github.com/phanitejak/kptgolib/logging/loggingtest"
 	"github.com/phanitejak/kptgolib/runner/modules/kafkamod"
 	"github.com/phanitejak/kptgolib/tracing"
@@ -32,7 +33,7 @@ func TestIntegrationConsumer(t *testing.T) {
 
 func testIntegrationConsumer(t *testing.T, opt kafkamod.ConsumerOpt) {
 	conf := kafkamod.ConsumerConfig{
-		Brokers: []string{"127.0.0.1:9092"},
+		Brokers: kafkatest.StartBroker(t).Addrs(),
 		Topics:  []string{"kafkamod-test-topic"},
 		Group:   "kafkamod-test-group",
 	}
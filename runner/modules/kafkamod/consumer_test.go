@@ -0,0 +1,31 @@
+package kafkamod_test
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/runner/modules/kafkamod"
+)
+
+func TestConsumerValidate(t *testing.T) {
+	handler := kafkamod.WithConsumerHandler(kafkamod.NoOpHandler{})
+	valid := kafkamod.ConsumerConfig{Brokers: []string{"kafka:9092"}, Topics: []string{"topic"}, Group: "group"}
+
+	assert.NoError(t, kafkamod.NewConsumer(kafkamod.WithConsumerConfig(valid), handler).Validate())
+	assert.EqualError(t, kafkamod.NewConsumer(kafkamod.WithConsumerConfig(valid)).Validate(), "message handler was not set")
+	assert.Error(t, kafkamod.NewConsumer(kafkamod.WithConsumerConfig(valid), handler,
+		kafkamod.WithConsumerRebalanceStrategies("unknown")).Validate())
+
+	err := kafkamod.NewConsumer(kafkamod.WithConsumerConfig(kafkamod.ConsumerConfig{}), handler).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kafka brokers are not configured")
+	assert.Contains(t, err.Error(), "consumed topics are not configured")
+	assert.Contains(t, err.Error(), "consumer group is not configured")
+
+	invalid := sarama.NewConfig()
+	invalid.Consumer.Fetch.Min = 0
+	assert.Error(t, kafkamod.NewConsumer(kafkamod.WithConsumerConfig(valid), handler, kafkamod.WithConsumerSaramaConfig(invalid)).Validate())
+}
@@ -2,6 +2,7 @@ package kafkamod
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/IBM/sarama"
@@ -56,19 +57,36 @@ func WithProducerMetricsPrefix(prefix string) ProducerOpt {
 	}
 }
 
+// WithProducerPartitioner sets partitioner used by the producer, e.g. kafka.NewConsistentHashPartitioner.
+// It takes precedence over the partitioner of sarama configuration given with WithProducerSaramaConfig.
+func WithProducerPartitioner(partitioner sarama.PartitionerConstructor) ProducerOpt {
+	return func(p *Producer) error {
+		p.partitioner = partitioner
+		return nil
+	}
+}
+
 // ProducerConfig allows passing configurations for producer.
 type ProducerConfig struct {
 	Brokers       []string `envconfig:"KAFKA_BROKERS" required:"true"`
 	MetricsPrefix string   `envconfig:"KAFKA_PRODUCER_METRICS_PREFIX" default:"default"`
 }
 
+// MessageSink is implemented by modules capable of producing messages.
+// Producer provides MessageSink for modules requiring it, see runner.Requirer.
+type MessageSink interface {
+	SendMessage(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+	SendMessages(msgs ...ProducerMessage) error
+}
+
 // Producer is a wrapper to use sarama.SyncProducer as module and adds tracing and metrics.
 type Producer struct {
-	client     sarama.SyncProducer
-	conf       ProducerConfig
-	saramaConf *sarama.Config
-	done       chan struct{}
-	opts       []ProducerOpt
+	client      sarama.SyncProducer
+	conf        ProducerConfig
+	saramaConf  *sarama.Config
+	done        chan struct{}
+	opts        []ProducerOpt
+	partitioner sarama.PartitionerConstructor
 }
 
 // NewProducer creates producer with given options.
@@ -81,19 +99,10 @@ func NewProducer(opts ...ProducerOpt) *Producer {
 // Init applies the options, registers metrics and creates new sarama.SyncProducer.
 func (p *Producer) Init(*tracing.Logger) error {
 	p.done = make(chan struct{})
-	p.saramaConf = sarama.NewConfig()
-	p.saramaConf.Version = sarama.V1_0_0_0
-
-	for _, opt := range p.opts {
-		if err := opt(p); err != nil {
-			return fmt.Errorf("failed to apply option: %w", err)
-		}
+	if err := p.configure(); err != nil {
+		return err
 	}
 
-	// These are required to be true for SyncProducer.
-	p.saramaConf.Producer.Return.Successes = true
-	p.saramaConf.Producer.Return.Errors = true
-
 	err := metrics.CrossRegisterKafkaProducerMetricsPrefix(p.saramaConf.MetricRegistry, p.conf.MetricsPrefix)
 	if err != nil {
 		return fmt.Errorf("failed to register producer metrics: %w", err)
@@ -107,6 +116,49 @@ func (p *Producer) Init(*tracing.Logger) error {
 	return nil
 }
 
+// Validate applies all options to a copy of the producer and checks its configuration without connecting to brokers.
+func (p *Producer) Validate() error {
+	v := &Producer{opts: p.opts}
+	if err := v.configure(); err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	if len(v.conf.Brokers) == 0 {
+		result = multierror.Append(result, errors.New("kafka brokers are not configured"))
+	}
+	if err := v.saramaConf.Validate(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("invalid sarama configuration: %w", err))
+	}
+	return result.ErrorOrNil()
+}
+
+// configure sets default sarama configuration and applies all given options.
+func (p *Producer) configure() error {
+	p.saramaConf = sarama.NewConfig()
+	p.saramaConf.Version = sarama.V1_0_0_0
+
+	for _, opt := range p.opts {
+		if err := opt(p); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	if p.partitioner != nil {
+		p.saramaConf.Producer.Partitioner = p.partitioner
+	}
+
+	// These are required to be true for SyncProducer.
+	p.saramaConf.Producer.Return.Successes = true
+	p.saramaConf.Producer.Return.Errors = true
+	return nil
+}
+
+// Provides returns Producer as MessageSink for other modules.
+func (p *Producer) Provides() []interface{} {
+	return []interface{}{MessageSink(p)}
+}
+
 // Run blocks until Close is called.
 func (p *Producer) Run() error {
 	<-p.done
@@ -41,3 +41,15 @@ func TestClientCreationFailure(t *testing.T) {
 	).Init(nil)
 	assert.Error(t, err)
 }
+
+func TestProducerProvidesMessageSink(t *testing.T) {
+	p := kafkamod.NewProducer()
+	provided := p.Provides()
+	assert.Len(t, provided, 1)
+	assert.Implements(t, (*kafkamod.MessageSink)(nil), provided[0])
+}
+
+func TestProducerValidate(t *testing.T) {
+	assert.NoError(t, kafkamod.NewProducer(kafkamod.WithProducerConfig(kafkamod.ProducerConfig{Brokers: []string{"kafka:9092"}})).Validate())
+	assert.Error(t, kafkamod.NewProducer(kafkamod.WithProducerConfig(kafkamod.ProducerConfig{})).Validate())
+}
@@ -2,24 +2,52 @@
 package tracermod
 
 import (
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/phanitejak/kptgolib/tracing"
 )
 
 // GlobalTracer allows running tracer as module.
+//
+// Runner closes modules in reverse order, so GlobalTracer should be the first module of the app,
+// so that spans of other modules are flushed when it's closed.
 type GlobalTracer struct {
-	done   chan struct{}
-	closer io.Closer
+	opts         []Opt
+	closeTimeout time.Duration
+	log          *tracing.Logger
+	done         chan struct{}
+	closer       io.Closer
+}
+
+// Opt is a functional option type for GlobalTracer.
+type Opt func(*GlobalTracer) error
+
+// WithCloseTimeout sets how long Close waits for traces to be flushed, tracing.DefaultCloseTimeout by default.
+func WithCloseTimeout(timeout time.Duration) Opt {
+	return func(t *GlobalTracer) error {
+		if timeout <= 0 {
+			return fmt.Errorf("close timeout must be positive, got %s", timeout)
+		}
+		t.closeTimeout = timeout
+		return nil
+	}
 }
 
 // NewGlobalTracer returns instance of GlobalTracer.
-func NewGlobalTracer() *GlobalTracer {
-	return &GlobalTracer{}
+func NewGlobalTracer(opts ...Opt) *GlobalTracer {
+	return &GlobalTracer{opts: opts, closeTimeout: tracing.DefaultCloseTimeout}
 }
 
 // Init will initialize global tracer.
 func (t *GlobalTracer) Init(l *tracing.Logger) (err error) {
+	for _, opt := range t.opts {
+		if err := opt(t); err != nil {
+			return fmt.Errorf("failed to apply option for tracermod.GlobalTracer: %w", err)
+		}
+	}
+	t.log = l
 	t.done = make(chan struct{})
 	// TODO: Expose tracing option type so that it can be used here.
 	t.closer, err = tracing.InitGlobalTracer(tracing.WithLogger(l))
@@ -32,8 +60,12 @@ func (t *GlobalTracer) Run() error {
 	return nil
 }
 
-// Close will close global tracer, flush the traces and make Run() to return.
+// Close will make Run() to return and flush the traces best-effort, giving up after close timeout
+// so that a stuck collector doesn't block termination. Failing to flush is logged, not returned.
 func (t *GlobalTracer) Close() error {
 	close(t.done)
-	return t.closer.Close()
+	if err := tracing.CloseWithTimeout(t.closer, t.closeTimeout); err != nil {
+		t.log.Warnf("failed to flush traces: %s", err)
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package tracermod_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/phanitejak/kptgolib/logging/loggingtest"
 	"github.com/phanitejak/kptgolib/runner/modules/tracermod"
@@ -27,3 +28,13 @@ func TestNewGlobalTracer(t *testing.T) {
 	require.NoError(t, err)
 	<-done
 }
+
+func TestGlobalTracerCloseTimeout(t *testing.T) {
+	logger := tracing.NewLogger(loggingtest.NewTestLogger(t))
+	assert.Error(t, tracermod.NewGlobalTracer(tracermod.WithCloseTimeout(0)).Init(logger))
+
+	tracer := tracermod.NewGlobalTracer(tracermod.WithCloseTimeout(time.Second))
+	require.NoError(t, tracer.Init(logger))
+	require.NoError(t, tracer.Close())
+	require.NoError(t, tracer.Run(), "Run should return after Close")
+}
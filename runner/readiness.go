@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotReady is returned by ReadinessGate.Wait when dependencies did not become healthy within timeout.
+var ErrNotReady = errors.New("dependencies not ready")
+
+const defaultReadinessInterval = time.Second
+
+// ReadinessCheck is a named health check of a startup dependency, e.g. vault.CheckHealth of a vault client.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ReadinessGate delays start of a module until its dependencies are healthy, e.g. a Kafka consumer
+// should not pull messages it can't process until database and Vault are reachable.
+type ReadinessGate struct {
+	checks   []ReadinessCheck
+	timeout  time.Duration
+	interval time.Duration
+}
+
+// NewReadinessGate returns gate with given checks, which waits at most timeout, zero timeout waits
+// until the context given to Wait is done. Checks are repeated every second until they pass.
+func NewReadinessGate(timeout time.Duration, checks ...ReadinessCheck) *ReadinessGate {
+	return &ReadinessGate{checks: checks, timeout: timeout, interval: defaultReadinessInterval}
+}
+
+// WithInterval sets interval of repeating failed checks.
+func (g *ReadinessGate) WithInterval(interval time.Duration) *ReadinessGate {
+	g.interval = interval
+	return g
+}
+
+// Wait blocks until all checks pass. Checks which have passed once are not repeated and all checks
+// must return when the context given to them is done.
+// Error wrapping ErrNotReady with last errors of failing checks is returned on timeout
+// and context error when ctx is done.
+func (g *ReadinessGate) Wait(ctx context.Context) error {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	pending := g.checks
+	for {
+		failed := map[string]error{}
+		var lock sync.Mutex
+		var wg sync.WaitGroup
+		for _, check := range pending {
+			check := check
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := check.Check(ctx); err != nil {
+					lock.Lock()
+					failed[check.Name] = err
+					lock.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(failed) == 0 {
+			return nil
+		}
+		var stillPending []ReadinessCheck
+		for _, check := range pending {
+			if _, ok := failed[check.Name]; ok {
+				stillPending = append(stillPending, check)
+			}
+		}
+		pending = stillPending
+
+		timer := time.NewTimer(g.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && g.timeout > 0 {
+				return fmt.Errorf("%w after %s: %s", ErrNotReady, g.timeout, readinessReport(failed))
+			}
+			return fmt.Errorf("%w: %s", ctx.Err(), readinessReport(failed))
+		case <-timer.C:
+		}
+	}
+}
+
+func readinessReport(failed map[string]error) string {
+	report := make([]string, 0, len(failed))
+	for name, err := range failed {
+		report = append(report, fmt.Sprintf("%s: %s", name, err))
+	}
+	sort.Strings(report)
+	return strings.Join(report, ", ")
+}
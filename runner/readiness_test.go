@@ -0,0 +1,57 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessGate(t *testing.T) {
+	var dbCalls, vaultCalls int32
+	gate := runner.NewReadinessGate(time.Second,
+		runner.ReadinessCheck{Name: "db", Check: func(context.Context) error {
+			if atomic.AddInt32(&dbCalls, 1) < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		}},
+		runner.ReadinessCheck{Name: "vault", Check: func(context.Context) error {
+			atomic.AddInt32(&vaultCalls, 1)
+			return nil
+		}},
+	).WithInterval(time.Millisecond)
+
+	require.NoError(t, gate.Wait(context.Background()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dbCalls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&vaultCalls), "passed check should not be repeated")
+}
+
+func TestReadinessGateTimeout(t *testing.T) {
+	gate := runner.NewReadinessGate(20*time.Millisecond,
+		runner.ReadinessCheck{Name: "db", Check: func(context.Context) error { return errors.New("connection refused") }},
+		runner.ReadinessCheck{Name: "vault", Check: func(context.Context) error { return nil }},
+	).WithInterval(time.Millisecond)
+
+	err := gate.Wait(context.Background())
+	require.ErrorIs(t, err, runner.ErrNotReady)
+	assert.Contains(t, err.Error(), "db: connection refused")
+	assert.NotContains(t, err.Error(), "vault")
+}
+
+func TestReadinessGateContextCanceled(t *testing.T) {
+	gate := runner.NewReadinessGate(0,
+		runner.ReadinessCheck{Name: "db", Check: func(context.Context) error { return errors.New("connection refused") }},
+	).WithInterval(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := gate.Wait(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, runner.ErrNotReady)
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"sync"
+	"time"
 
+	"github.com/kelseyhightower/envconfig"
 	"github.com/phanitejak/kptgolib/logging"
 	"github.com/phanitejak/kptgolib/tracing"
 )
@@ -15,6 +18,7 @@ var exitFn = os.Exit
 
 // Module abstracts runnable units into life cycle methods like http servers for example.
 // Run() is expected to block until app is finished.
+// Modules can depend on each other by implementing Provider and Requirer interfaces.
 type Module interface {
 	Init(*tracing.Logger) error
 	Run() error
@@ -29,29 +33,49 @@ type App interface {
 
 // AppRunner can be used to run App.
 type AppRunner struct {
-	log   *tracing.Logger
-	ctx   context.Context
-	ready chan struct{}
-}
-
-// RunApp is convenience function to create new Runner with tracing logger, hook into os.Interrupt and start running an App.
+	log         *tracing.Logger
+	ctx         context.Context
+	ready       chan struct{}
+	lock        sync.Mutex
+	timings     []*ModuleTiming
+	mods        []Module
+	signalConf  SignalConfig
+	signalHooks map[os.Signal][]SignalHook
+}
+
+// RunApp is convenience function to create new Runner with tracing logger, hook into StopSignals and start running an App.
+// Pre-stop delay and shutdown deadline are read from RUNNER_PRE_STOP_DELAY and RUNNER_SHUTDOWN_DEADLINE,
+// given options are applied after them, e.g. WithReloadOn(syscall.SIGHUP) to reload modules implementing Reloader.
 // If any of App's life cycle methods returns an error it will be logged and os.Exit(1) will be issued.
-func RunApp(a App) {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+func RunApp(a App, opts ...Opt) {
+	ctx, stop := signal.NotifyContext(context.Background(), StopSignals...)
 	defer stop()
 
 	log := tracing.NewLogger(logging.NewLogger())
-	exitCode := NewRunner(ctx, log).Run(a)
+	conf := SignalConfig{}
+	if err := envconfig.Process("", &conf); err != nil {
+		log.Errorf("failed to read runner configuration for %s: %s", a.Name(), err)
+		exitFn(1)
+		return
+	}
+
+	opts = append([]Opt{WithSignalConfig(conf)}, opts...)
+	exitCode := NewRunner(ctx, log, opts...).Run(a)
 	exitFn(exitCode)
 }
 
 // NewRunner creates runner with given logger and channel for signaling when to stop.
-func NewRunner(ctx context.Context, log *tracing.Logger) *AppRunner {
-	return &AppRunner{
-		log:   log,
-		ctx:   ctx,
-		ready: make(chan struct{}),
+func NewRunner(ctx context.Context, log *tracing.Logger, opts ...Opt) *AppRunner {
+	r := &AppRunner{
+		log:         log,
+		ctx:         ctx,
+		ready:       make(chan struct{}),
+		signalHooks: map[os.Signal][]SignalHook{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // Ready will return once everything is initialized successfully.
@@ -59,42 +83,102 @@ func (r *AppRunner) Ready() {
 	<-r.ready
 }
 
+// Timings returns durations of life cycle methods of initialized modules. Close durations are
+// available after Run has returned.
+func (r *AppRunner) Timings() []ModuleTiming {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	timings := make([]ModuleTiming, 0, len(r.timings))
+	for _, t := range r.timings {
+		timings = append(timings, *t)
+	}
+	return timings
+}
+
 // Run will take care of running app.
+// Durations of Init, Close and run-ready, see RunReadyNotifier, of each module are logged and exposed as metrics.
+// Registered signal hooks are called while modules are running.
 func (r *AppRunner) Run(a App) (exitCode int) {
-	mods := a.Modules()
+	started := time.Now()
+	mods, err := resolveDependencies(a.Modules())
+	if err != nil {
+		r.log.Errorf("failed to resolve module dependencies for %s: %s", a.Name(), err)
+		close(r.ready)
+		return 1
+	}
 	runnables := make([]Runnable, 0, len(mods))
 
 	r.log.Infof("initializing %s", a.Name())
-	for _, mod := range mods {
-		runnables = append(runnables, mod)
-		if err := mod.Init(r.log); err != nil {
+	names := moduleNames(mods)
+	for i, mod := range mods {
+		timing := &ModuleTiming{Module: names[i]}
+		r.lock.Lock()
+		r.timings = append(r.timings, timing)
+		r.lock.Unlock()
+		runnables = append(runnables, &timedModule{Module: mod, app: a.Name(), log: r.log, timing: timing, lock: &r.lock})
+
+		initStarted := time.Now()
+		err := mod.Init(r.log)
+		r.lock.Lock()
+		timing.Init = time.Since(initStarted)
+		r.lock.Unlock()
+		if err != nil {
 			r.log.Errorf("failed to initialize modules for %s: %s", a.Name(), err)
 			close(r.ready)
 			return 1
 		}
 	}
-	r.log.Infof("%s initialized successfully", a.Name())
-
-	ctx, cancel := context.WithCancel(r.ctx)
+	startup := time.Since(started)
+	observeStartup(a.Name(), startup, r.timings)
+	r.log.Infof("%s initialized successfully in %v: %s", a.Name(), startup,
+		timingReport(r.timings, func(t *ModuleTiming) time.Duration { return t.Init }))
+
+	r.lock.Lock()
+	r.mods = mods
+	r.lock.Unlock()
+
+	// Modules are closed only after the pre-stop delay, so their context is not cancelled together with r.ctx.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.ctx))
+	defer cancel()
+	var shutdownStarted time.Time
+	shutdownCh := make(chan struct{})
 	runnables = append(runnables, NewFnRunner(
 		func() error {
-			<-ctx.Done()
-			r.log.Infof("Context cancelled for %s", a.Name())
+			select {
+			case <-r.ctx.Done():
+				r.log.Infof("Context cancelled for %s", a.Name())
+				r.preStop(ctx, a.Name())
+			case <-ctx.Done():
+				r.log.Infof("Context cancelled for %s", a.Name())
+			}
 			return nil
 		},
 		func() error {
+			shutdownStarted = time.Now()
+			close(shutdownCh)
 			cancel()
 			return nil
 		},
 	))
 
+	stopSignals := r.handleSignals()
 	r.log.Infof("running %s", a.Name())
 	close(r.ready)
 
-	if err := Run(ctx, runnables...); err != nil {
+	result := make(chan error, 1)
+	go func() { result <- Run(ctx, runnables...) }()
+	if err := r.wait(result, shutdownCh); err != nil {
 		r.log.Errorf("%s exited with error: %s", a.Name(), err)
 		exitCode = 1
 	}
+	stopSignals()
+
+	shutdown := time.Since(shutdownStarted)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	observeShutdown(a.Name(), shutdown, r.timings)
+	r.log.Infof("%s closed in %v: %s", a.Name(), shutdown,
+		timingReport(r.timings, func(t *ModuleTiming) time.Duration { return t.Close }))
 
 	return exitCode
 }
@@ -38,3 +38,13 @@ func (a *TestApp) Modules() []Module          { return []Module{a} }
 func (a *TestApp) Init(*tracing.Logger) error { return a.err }
 func (a *TestApp) Run() error                 { return a.err }
 func (a *TestApp) Close() error               { return a.err }
+
+func TestValidateApp(t *testing.T) {
+	t.Cleanup(func() { exitFn = os.Exit })
+
+	exitCode := -1
+	exitFn = func(code int) { exitCode = code }
+
+	ValidateApp(&TestApp{})
+	assert.Equal(t, 0, exitCode)
+}
@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ErrShutdownDeadline is returned when modules are not closed within the shutdown deadline.
+var ErrShutdownDeadline = errors.New("shutdown deadline exceeded")
+
+// StopSignals make RunApp shut down the App.
+var StopSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// SignalConfig configures shutdown of AppRunner. RunApp reads it from environment variables.
+type SignalConfig struct {
+	// PreStopDelay keeps modules running after stop signal, so that load balancers stop routing traffic to
+	// the instance before its servers are closed.
+	PreStopDelay time.Duration `envconfig:"RUNNER_PRE_STOP_DELAY" default:"0s"`
+	// ShutdownDeadline limits closing of modules, remaining modules are abandoned after it. Zero waits forever.
+	ShutdownDeadline time.Duration `envconfig:"RUNNER_SHUTDOWN_DEADLINE" default:"0s"`
+}
+
+// Reloader can be implemented by a Module which can reload its configuration, e.g. on SIGHUP, see WithReloadOn.
+type Reloader interface {
+	Reload() error
+}
+
+// SignalHook is called when a signal registered with WithSignalHook is received.
+type SignalHook func(sig os.Signal)
+
+// Opt for AppRunner.
+type Opt func(r *AppRunner)
+
+// WithSignalConfig sets pre-stop delay and shutdown deadline.
+func WithSignalConfig(conf SignalConfig) Opt {
+	return func(r *AppRunner) {
+		r.signalConf = conf
+	}
+}
+
+// WithPreStopDelay keeps modules running for delay after the runner context is cancelled, e.g. by SIGTERM.
+// Shutdown caused by a failing module is not delayed.
+func WithPreStopDelay(delay time.Duration) Opt {
+	return func(r *AppRunner) {
+		r.signalConf.PreStopDelay = delay
+	}
+}
+
+// WithShutdownDeadline makes Run return with exit code 1 when modules are not closed within deadline.
+// Remaining modules are abandoned, their Close keeps running in the background.
+func WithShutdownDeadline(deadline time.Duration) Opt {
+	return func(r *AppRunner) {
+		r.signalConf.ShutdownDeadline = deadline
+	}
+}
+
+// WithSignalHook calls hook for every received signal of sigs while the App is running.
+func WithSignalHook(hook SignalHook, sigs ...os.Signal) Opt {
+	return func(r *AppRunner) {
+		for _, sig := range sigs {
+			r.signalHooks[sig] = append(r.signalHooks[sig], hook)
+		}
+	}
+}
+
+// WithReloadOn calls Reload of modules implementing Reloader when any of sigs is received, see AppRunner.Reload.
+func WithReloadOn(sigs ...os.Signal) Opt {
+	return func(r *AppRunner) {
+		WithSignalHook(func(os.Signal) { _ = r.Reload() }, sigs...)(r)
+	}
+}
+
+// Reload calls Reload of running modules implementing Reloader in dependency order.
+// Errors of all modules are returned together and logged, failing reload doesn't stop the App.
+func (r *AppRunner) Reload() error {
+	r.lock.Lock()
+	mods := r.mods
+	r.lock.Unlock()
+
+	var result *multierror.Error
+	for _, mod := range mods {
+		reloader, ok := mod.(Reloader)
+		if !ok {
+			continue
+		}
+		if err := reloader.Reload(); err != nil {
+			result = multierror.Append(result, fmt.Errorf("module %s: %w", moduleName(mod), err))
+		}
+	}
+
+	if err := result.ErrorOrNil(); err != nil {
+		r.log.Errorf("reloading modules failed: %s", err)
+		return err
+	}
+	r.log.Info("modules reloaded")
+	return nil
+}
+
+// handleSignals dispatches signals with registered hooks until returned stop function is called.
+func (r *AppRunner) handleSignals() (stop func()) {
+	if len(r.signalHooks) == 0 {
+		return func() {}
+	}
+
+	sigs := make([]os.Signal, 0, len(r.signalHooks))
+	for sig := range r.signalHooks {
+		sigs = append(sigs, sig)
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case sig := <-ch:
+				r.log.Infof("received signal %s", sig)
+				for _, hook := range r.signalHooks[sig] {
+					hook(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+		<-stopped
+	}
+}
+
+// preStop waits for the pre-stop delay unless runCtx is done first.
+func (r *AppRunner) preStop(runCtx context.Context, app string) {
+	if r.signalConf.PreStopDelay <= 0 {
+		return
+	}
+	r.log.Infof("delaying shutdown of %s by %v", app, r.signalConf.PreStopDelay)
+	timer := time.NewTimer(r.signalConf.PreStopDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-runCtx.Done():
+	}
+}
+
+// wait returns result of running modules. Once shutdown is started, it waits at most the shutdown deadline.
+func (r *AppRunner) wait(result <-chan error, shutdown <-chan struct{}) error {
+	select {
+	case err := <-result:
+		return err
+	case <-shutdown:
+	}
+	if r.signalConf.ShutdownDeadline <= 0 {
+		return <-result
+	}
+
+	timer := time.NewTimer(r.signalConf.ShutdownDeadline)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w after %v, abandoned modules: %s", ErrShutdownDeadline, r.signalConf.ShutdownDeadline, r.unclosedModules())
+	}
+}
+
+func (r *AppRunner) unclosedModules() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var names []string
+	for _, t := range r.timings {
+		if t.Close == 0 {
+			names = append(names, t.Module)
+		}
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,171 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+func TestRunnerPreStopDelay(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	var stopped, closed time.Time
+	closeCh := make(chan struct{})
+	mod := &FnModule{
+		initFn: func() error { return nil },
+		runFn: func() error {
+			stopped = time.Now()
+			stop()
+			<-closeCh
+			return nil
+		},
+		closeFn: func() error {
+			closed = time.Now()
+			close(closeCh)
+			return nil
+		},
+	}
+
+	r := runner.NewRunner(ctx, log, runner.WithPreStopDelay(50*time.Millisecond))
+	require.Equal(t, 0, r.Run(&App{modules: []runner.Module{mod}}))
+	assert.GreaterOrEqual(t, closed.Sub(stopped), 50*time.Millisecond)
+}
+
+func TestRunnerPreStopDelayIsSkippedOnModuleError(t *testing.T) {
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+	mod := &FnModule{
+		initFn:  func() error { return nil },
+		runFn:   func() error { return errors.New("run error") },
+		closeFn: func() error { return nil },
+	}
+
+	started := time.Now()
+	r := runner.NewRunner(context.Background(), log, runner.WithPreStopDelay(time.Minute))
+	assert.Equal(t, 1, r.Run(&App{modules: []runner.Module{mod}}))
+	assert.Less(t, time.Since(started), time.Minute)
+}
+
+func TestRunnerShutdownDeadline(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	hanging := make(chan struct{})
+	t.Cleanup(func() { close(hanging) })
+	mod := &FnModule{
+		initFn: func() error { return nil },
+		runFn: func() error {
+			stop()
+			<-hanging
+			return nil
+		},
+		closeFn: func() error {
+			<-hanging
+			return nil
+		},
+	}
+
+	r := runner.NewRunner(ctx, log, runner.WithShutdownDeadline(50*time.Millisecond))
+	assert.Equal(t, 1, r.Run(&App{modules: []runner.Module{mod}}))
+}
+
+func TestRunnerSignalHooks(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	hooked := make(chan os.Signal, 1)
+	mod := NewReloadableModule(nil)
+	r := runner.NewRunner(ctx, log,
+		runner.WithReloadOn(syscall.SIGHUP),
+		runner.WithSignalHook(func(sig os.Signal) { hooked <- sig }, syscall.SIGHUP),
+	)
+
+	exitCode := make(chan int)
+	go func() { exitCode <- r.Run(&App{modules: []runner.Module{mod}}) }()
+	r.Ready()
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(syscall.SIGHUP))
+
+	select {
+	case sig := <-hooked:
+		assert.Equal(t, syscall.SIGHUP, sig)
+	case <-time.After(time.Second):
+		t.Fatal("signal hook was not called")
+	}
+	select {
+	case <-mod.reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("module was not reloaded")
+	}
+
+	stop()
+	assert.Equal(t, 0, <-exitCode)
+}
+
+func TestRunnerReload(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	ok := NewReloadableModule(nil)
+	failing := NewReloadableModule(errors.New("reload error"))
+	r := runner.NewRunner(ctx, log)
+
+	exitCode := make(chan int)
+	go func() { exitCode <- r.Run(&App{modules: []runner.Module{ok, failing}}) }()
+	r.Ready()
+
+	err := r.Reload()
+	assert.ErrorContains(t, err, "reload error")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ok.calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failing.calls))
+
+	stop()
+	assert.Equal(t, 0, <-exitCode)
+}
+
+type ReloadableModule struct {
+	calls    int32
+	err      error
+	reloaded chan struct{}
+	done     chan struct{}
+}
+
+func NewReloadableModule(err error) *ReloadableModule {
+	return &ReloadableModule{err: err, reloaded: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+func (m *ReloadableModule) Init(*tracing.Logger) error { return nil }
+
+func (m *ReloadableModule) Run() error {
+	<-m.done
+	return nil
+}
+
+func (m *ReloadableModule) Close() error {
+	close(m.done)
+	return nil
+}
+
+func (m *ReloadableModule) Reload() error {
+	atomic.AddInt32(&m.calls, 1)
+	select {
+	case m.reloaded <- struct{}{}:
+	default:
+	}
+	return m.err
+}
@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+var (
+	moduleInitDuration = metrics.RegisterGaugeVec("module_init_duration_seconds", "runner",
+		"Time spent in Init of a module in seconds.", "app", "module")
+	moduleRunReadyDuration = metrics.RegisterGaugeVec("module_run_ready_duration_seconds", "runner",
+		"Time from start of Run until a module implementing RunReadyNotifier was ready in seconds.", "app", "module")
+	moduleCloseDuration = metrics.RegisterGaugeVec("module_close_duration_seconds", "runner",
+		"Time spent in Close of a module in seconds.", "app", "module")
+	startupDuration = metrics.RegisterGaugeVec("startup_duration_seconds", "runner",
+		"Time from start of the runner until all modules were initialized in seconds.", "app")
+	shutdownDuration = metrics.RegisterGaugeVec("shutdown_duration_seconds", "runner",
+		"Time from start of the shutdown until all modules were closed in seconds.", "app")
+)
+
+// RunReadyNotifier can be implemented by a Module, which becomes ready some time after Run is called,
+// e.g. a consumer waiting for partition assignment. Time until the returned channel is closed is reported
+// as run-ready duration of the module.
+type RunReadyNotifier interface {
+	RunReady() <-chan struct{}
+}
+
+// ModuleTiming contains durations of life cycle methods of a module.
+type ModuleTiming struct {
+	// Module is the type name of the module, e.g. *httpmod.Server. Modules of the same type are numbered
+	// in initialization order from the second one, e.g. *httpmod.Server#2.
+	Module string
+	// Init is time spent in Init.
+	Init time.Duration
+	// RunReady is time from start of Run until the module was ready, zero if the module doesn't implement
+	// RunReadyNotifier or wasn't ready yet.
+	RunReady time.Duration
+	// Close is time spent in Close, zero if module was not closed.
+	Close time.Duration
+}
+
+// timedModule measures run-ready duration and duration of Close of the wrapped module.
+type timedModule struct {
+	Module
+	app    string
+	log    *tracing.Logger
+	timing *ModuleTiming
+	lock   *sync.Mutex
+}
+
+func (m *timedModule) Run() error {
+	notifier, ok := m.Module.(RunReadyNotifier)
+	if !ok {
+		return m.Module.Run()
+	}
+
+	started := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-notifier.RunReady():
+		case <-done:
+			return
+		}
+		ready := time.Since(started)
+		m.lock.Lock()
+		m.timing.RunReady = ready
+		m.lock.Unlock()
+		moduleRunReadyDuration.GetCustomGauge(m.app, m.timing.Module).Set(ready.Seconds())
+		m.log.Infof("module %s of %s ready in %v", m.timing.Module, m.app, ready)
+	}()
+	return m.Module.Run()
+}
+
+func (m *timedModule) Close() error {
+	started := time.Now()
+	defer func() {
+		m.lock.Lock()
+		m.timing.Close = time.Since(started)
+		m.lock.Unlock()
+	}()
+	return m.Module.Close()
+}
+
+func moduleName(mod Module) string {
+	return fmt.Sprintf("%T", mod)
+}
+
+// moduleNames returns type names of modules, which are numbered from the second module of the same type.
+func moduleNames(mods []Module) []string {
+	names := make([]string, 0, len(mods))
+	seen := map[string]int{}
+	for _, mod := range mods {
+		name := moduleName(mod)
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s#%d", name, n)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func observeStartup(app string, total time.Duration, timings []*ModuleTiming) {
+	startupDuration.GetCustomGauge(app).Set(total.Seconds())
+	for _, t := range timings {
+		moduleInitDuration.GetCustomGauge(app, t.Module).Set(t.Init.Seconds())
+	}
+}
+
+func observeShutdown(app string, total time.Duration, timings []*ModuleTiming) {
+	shutdownDuration.GetCustomGauge(app).Set(total.Seconds())
+	for _, t := range timings {
+		moduleCloseDuration.GetCustomGauge(app, t.Module).Set(t.Close.Seconds())
+	}
+}
+
+// timingReport formats given durations of modules as "module=duration" pairs.
+func timingReport(timings []*ModuleTiming, duration func(*ModuleTiming) time.Duration) string {
+	pairs := make([]string, 0, len(timings))
+	for _, t := range timings {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", t.Module, duration(t)))
+	}
+	return strings.Join(pairs, " ")
+}
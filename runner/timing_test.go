@@ -0,0 +1,117 @@
+package runner_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+func TestRunnerTimings(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	mod := &FnModule{
+		initFn: func() error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+		runFn: func() error {
+			stop()
+			return nil
+		},
+		closeFn: func() error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	}
+
+	r := runner.NewRunner(ctx, log)
+	require.Equal(t, 0, r.Run(&App{modules: []runner.Module{mod}}))
+
+	timings := r.Timings()
+	require.Len(t, timings, 1)
+	assert.Equal(t, "*runner_test.FnModule", timings[0].Module)
+	assert.GreaterOrEqual(t, timings[0].Init, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, timings[0].Close, 10*time.Millisecond)
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+
+	labels := map[string]string{"app": "test-app", "module": "*runner_test.FnModule"}
+	initSeconds, ok := families.Value("com_metrics_runner_module_init_duration_seconds", labels)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, initSeconds, 0.02)
+	closeSeconds, ok := families.Value("com_metrics_runner_module_close_duration_seconds", labels)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, closeSeconds, 0.01)
+	_, ok = families.Value("com_metrics_runner_startup_duration_seconds", map[string]string{"app": "test-app"})
+	assert.True(t, ok)
+	_, ok = families.Value("com_metrics_runner_shutdown_duration_seconds", map[string]string{"app": "test-app"})
+	assert.True(t, ok)
+}
+
+func TestRunnerRunReadyTiming(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	log := tracing.NewLogger(loggingtest.NewTestLogger(t))
+
+	mod := &ReadyModule{ready: make(chan struct{}), done: make(chan struct{})}
+	other := &ReadyModule{ready: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(mod.ready)
+		time.Sleep(10 * time.Millisecond)
+		stop()
+	}()
+
+	r := runner.NewRunner(ctx, log)
+	require.Equal(t, 0, r.Run(&App{modules: []runner.Module{mod, other}}))
+
+	timings := r.Timings()
+	require.Len(t, timings, 2)
+	assert.Equal(t, "*runner_test.ReadyModule", timings[0].Module)
+	assert.GreaterOrEqual(t, timings[0].RunReady, 20*time.Millisecond)
+	assert.Equal(t, "*runner_test.ReadyModule#2", timings[1].Module)
+	assert.Zero(t, timings[1].RunReady, "module never became ready")
+
+	srv := httptest.NewServer(metrics.GetMetricsHandler())
+	defer srv.Close()
+	families, err := metrics.Scrape(srv.URL)
+	require.NoError(t, err)
+
+	labels := map[string]string{"app": "test-app", "module": "*runner_test.ReadyModule"}
+	readySeconds, ok := families.Value("com_metrics_runner_module_run_ready_duration_seconds", labels)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, readySeconds, 0.02)
+	_, ok = families.Value("com_metrics_runner_module_init_duration_seconds",
+		map[string]string{"app": "test-app", "module": "*runner_test.ReadyModule#2"})
+	assert.True(t, ok)
+}
+
+type ReadyModule struct {
+	ready chan struct{}
+	done  chan struct{}
+}
+
+func (m *ReadyModule) Init(*tracing.Logger) error { return nil }
+func (m *ReadyModule) RunReady() <-chan struct{}  { return m.ready }
+
+func (m *ReadyModule) Run() error {
+	<-m.done
+	return nil
+}
+
+func (m *ReadyModule) Close() error {
+	close(m.done)
+	return nil
+}
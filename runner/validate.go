@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+// Validator can be implemented by a Module which can check its configuration without side effects,
+// i.e. without opening listeners, connecting to dependencies or starting goroutines.
+type Validator interface {
+	Validate() error
+}
+
+// ValidateApp is convenience function to validate App with tracing logger, e.g. when service binary is started
+// with --validate flag in CI smoke checks. It will issue os.Exit(1) if validation fails, os.Exit(0) otherwise.
+func ValidateApp(a App) {
+	exitCode := 0
+	if err := NewRunner(context.Background(), tracing.NewLogger(logging.NewLogger())).Validate(a); err != nil {
+		exitCode = 1
+	}
+	exitFn(exitCode)
+}
+
+// Validate checks App without running it: module dependencies are resolved and Validate is called for modules
+// implementing Validator, other modules are skipped. Modules are not initialized, so no listeners or consumers
+// are started. Errors of all modules are returned together and logged.
+func (r *AppRunner) Validate(a App) error {
+	mods, err := resolveDependencies(a.Modules())
+	if err != nil {
+		err = fmt.Errorf("failed to resolve module dependencies: %w", err)
+		r.log.Errorf("validation of %s failed: %s", a.Name(), err)
+		return err
+	}
+
+	var result *multierror.Error
+	for _, mod := range mods {
+		validator, ok := mod.(Validator)
+		if !ok {
+			r.log.Debugf("module %s of %s does not support validation", moduleName(mod), a.Name())
+			continue
+		}
+		if err := validator.Validate(); err != nil {
+			result = multierror.Append(result, fmt.Errorf("module %s: %w", moduleName(mod), err))
+		}
+	}
+
+	if err := result.ErrorOrNil(); err != nil {
+		r.log.Errorf("validation of %s failed: %s", a.Name(), err)
+		return err
+	}
+	r.log.Infof("%s validated successfully", a.Name())
+	return nil
+}
@@ -0,0 +1,54 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/logging/loggingtest"
+	"github.com/phanitejak/kptgolib/runner"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+type validatedModule struct {
+	Module
+	validateErr    error
+	validateCalled int
+}
+
+func (m *validatedModule) Validate() error {
+	m.validateCalled++
+	return m.validateErr
+}
+
+type validatedApp struct {
+	modules []runner.Module
+}
+
+func (a validatedApp) Name() string             { return "validated-app" }
+func (a validatedApp) Modules() []runner.Module { return a.modules }
+
+func TestAppRunnerValidate(t *testing.T) {
+	valid := &validatedModule{}
+	first := &validatedModule{validateErr: errors.New("missing address")}
+	second := &validatedModule{validateErr: errors.New("missing brokers")}
+	plain := &Module{}
+
+	r := runner.NewRunner(context.Background(), tracing.NewLogger(loggingtest.NewTestLogger(t)))
+	err := r.Validate(validatedApp{modules: []runner.Module{valid, first, plain, second}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing address")
+	assert.Contains(t, err.Error(), "missing brokers")
+
+	for _, m := range []*validatedModule{valid, first, second} {
+		assert.Equal(t, 1, m.validateCalled)
+		assert.Zero(t, m.initCalled, "modules should not be initialized")
+		assert.Zero(t, m.runCalled, "modules should not be run")
+	}
+	assert.Zero(t, plain.initCalled)
+
+	assert.NoError(t, r.Validate(validatedApp{modules: []runner.Module{valid, plain}}))
+}
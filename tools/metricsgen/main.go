@@ -0,0 +1,79 @@
+// Command metricsgen generates Prometheus alerting rules and Grafana dashboard skeletons from metrics
+// exposed by a running service, so that observability assets can be kept in sync with the code.
+//
+// Usage:
+//
+//	metricsgen [flags] <metrics url>
+//
+// Metrics are scraped from given url, e.g. http://localhost:9876/application/prometheus. Alerting rules
+// are written to file given with -alerts and dashboard to file given with -dashboard, "-" means stdout.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "metricsgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("metricsgen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	service := flags.String("service", "", "service name used as job label in queries and as dashboard title")
+	alerts := flags.String("alerts", "-", "alerting rules output file, - for stdout, empty to skip")
+	dashboard := flags.String("dashboard", "", "dashboard output file, - for stdout, empty to skip")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: metricsgen [flags] <metrics url>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("metrics url is required")
+	}
+	if *service == "" {
+		return fmt.Errorf("service name is required, set -service")
+	}
+
+	families, err := metrics.Scrape(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *alerts != "" {
+		if err := write(*alerts, stdout, metrics.GenerateAlertRules(*service, families)); err != nil {
+			return err
+		}
+	}
+	if *dashboard != "" {
+		if err := write(*dashboard, stdout, metrics.GenerateDashboard(*service, families)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes v as indented JSON to file or to stdout if file is "-".
+func write(file string, stdout io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if file == "-" {
+		_, err = stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(file, b, 0o644)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+const exposedMetrics = `# HELP http_server_requests_duration_seconds Total time and count of http requests.
+# TYPE http_server_requests_duration_seconds summary
+http_server_requests_duration_seconds_sum{method="GET",status="200",uri="/api"} 1
+http_server_requests_duration_seconds_count{method="GET",status="200",uri="/api"} 10
+# HELP com_metrics_kafka_dropped_chunked_messages_total Total number of dropped chunked messages.
+# TYPE com_metrics_kafka_dropped_chunked_messages_total counter
+com_metrics_kafka_dropped_chunked_messages_total{reason="timeout",topic="orders"} 0
+# HELP orders_created_total Total number of created orders.
+# TYPE orders_created_total counter
+orders_created_total 3
+`
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(exposedMetrics))
+	}))
+	defer server.Close()
+
+	dashboardFile := filepath.Join(t.TempDir(), "dashboard.json")
+	stdout := &bytes.Buffer{}
+	require.NoError(t, run([]string{"-service", "orders", "-dashboard", dashboardFile, server.URL}, stdout, &bytes.Buffer{}))
+
+	var rules metrics.AlertRules
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rules))
+	require.Len(t, rules.Groups, 1)
+	var alerts []string
+	for _, rule := range rules.Groups[0].Rules {
+		alerts = append(alerts, rule.Alert)
+	}
+	assert.Equal(t, []string{"HTTPServerErrorRate", "KafkaDroppedChunkedMessages"}, alerts)
+	assert.Equal(t, `sum(increase(com_metrics_kafka_dropped_chunked_messages_total{job="orders"}[5m])) > 0`,
+		rules.Groups[0].Rules[1].Expr)
+
+	b, err := os.ReadFile(dashboardFile)
+	require.NoError(t, err)
+	var dashboard metrics.Dashboard
+	require.NoError(t, json.Unmarshal(b, &dashboard))
+	var titles []string
+	for _, panel := range dashboard.Panels {
+		titles = append(titles, panel.Title)
+	}
+	assert.Equal(t, []string{"HTTP server", "Request rate", "Error rate", "Average latency",
+		"Kafka", "com_metrics_kafka_dropped_chunked_messages_total", "Custom metrics", "orders_created_total"}, titles)
+	assert.Equal(t, `sum by (reason, topic) (rate(com_metrics_kafka_dropped_chunked_messages_total{job="orders"}[5m]))`,
+		dashboard.Panels[5].Targets[0].Expr)
+	assert.Equal(t, `sum(rate(orders_created_total{job="orders"}[5m]))`, dashboard.Panels[7].Targets[0].Expr)
+}
+
+func TestRunErrors(t *testing.T) {
+	assert.Error(t, run([]string{"-service", "orders"}, &bytes.Buffer{}, &bytes.Buffer{}))
+	assert.Error(t, run([]string{"http://localhost:0"}, &bytes.Buffer{}, &bytes.Buffer{}))
+	assert.Error(t, run([]string{"-service", "orders", "http://127.0.0.1:0"}, &bytes.Buffer{}, &bytes.Buffer{}))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/phanitejak/kptgolib/vault"
+)
+
+const (
+	kvAuto = "auto"
+	kvV1   = "1"
+	kvV2   = "2"
+)
+
+// kv translates logical paths, e.g. secret/my-service/db, to KV version 2 API paths,
+// e.g. secret/data/my-service/db, for mounts of KV version 2.
+type kv struct {
+	client vault.Client
+	// mounts holds mount paths with trailing slash, longest first.
+	mounts []mount
+}
+
+type mount struct {
+	path string
+	v2   bool
+}
+
+// newKV returns kv for given KV version. With auto version KV version 2 mounts are detected from
+// sys/mounts. If the token is not allowed to list mounts, all paths are treated as KV version 1.
+func newKV(client vault.Client, version, mountPath string) (*kv, error) {
+	k := &kv{client: client}
+	switch version {
+	case kvV1:
+	case kvV2:
+		if mountPath == "" {
+			return nil, fmt.Errorf("mount is required with KV version 2")
+		}
+		k.mounts = []mount{{path: strings.Trim(mountPath, "/") + "/", v2: true}}
+	case kvAuto:
+		mounts, err := client.ListMounts()
+		if err != nil {
+			return k, fmt.Errorf("failed to detect KV version 2 mounts, using KV version 1: %w", err)
+		}
+		for path, m := range mounts {
+			if m != nil {
+				k.mounts = append(k.mounts, mount{path: path, v2: m.Type == "kv" && m.Options["version"] == kvV2})
+			}
+		}
+		// Longest mount first, so that nested mounts match before their parents.
+		sort.Slice(k.mounts, func(i, j int) bool { return len(k.mounts[i].path) > len(k.mounts[j].path) })
+	default:
+		return nil, fmt.Errorf("unknown KV version %s, expected auto, 1 or 2", version)
+	}
+	return k, nil
+}
+
+// path returns API path of logical path, prefix is "data" or "metadata".
+func (k *kv) path(path, prefix string) (string, bool) {
+	path = strings.Trim(path, "/")
+	for _, m := range k.mounts {
+		rest, ok := strings.CutPrefix(path+"/", m.path)
+		if !ok {
+			continue
+		}
+		if !m.v2 {
+			break
+		}
+		return strings.TrimSuffix(m.path+prefix+"/"+rest, "/"), true
+	}
+	return path, false
+}
+
+func (k *kv) read(path string) (interface{}, error) {
+	apiPath, v2 := k.path(path, "data")
+	secret, err := k.client.Read(apiPath)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("secret %s not found", path)
+	}
+	if v2 {
+		data, _ := secret.Data["data"].(map[string]interface{})
+		return data, nil
+	}
+	return secret.Data, nil
+}
+
+func (k *kv) write(path string, data map[string]interface{}) (interface{}, error) {
+	apiPath, v2 := k.path(path, "data")
+	if v2 {
+		data = map[string]interface{}{"data": data}
+	}
+	secret, err := k.client.Write(apiPath, data)
+	if err != nil {
+		return nil, err
+	}
+	return secretData(secret), nil
+}
+
+func (k *kv) list(path string) (interface{}, error) {
+	apiPath, _ := k.path(path, "metadata")
+	secret, err := k.client.List(apiPath)
+	if err != nil {
+		return nil, err
+	}
+	keys := []interface{}{}
+	if secret != nil {
+		if list, ok := secret.Data["keys"].([]interface{}); ok {
+			keys = list
+		}
+	}
+	return keys, nil
+}
+
+// delete deletes secret, for KV version 2 only the latest version is deleted.
+func (k *kv) delete(path string) (interface{}, error) {
+	apiPath, _ := k.path(path, "data")
+	secret, err := k.client.Delete(apiPath)
+	if err != nil {
+		return nil, err
+	}
+	return secretData(secret), nil
+}
+
+func secretData(secret *api.Secret) map[string]interface{} {
+	if secret == nil {
+		return map[string]interface{}{}
+	}
+	return secret.Data
+}
@@ -0,0 +1,139 @@
+// Command vaultctl reads, writes, lists and deletes Vault secrets using vault.Client, so that in-cluster
+// permissions can be debugged with the same authentication code path as services use.
+//
+// Usage:
+//
+//	vaultctl [flags] read <path>
+//	vaultctl [flags] write <path> key=value...
+//	vaultctl [flags] list <path>
+//	vaultctl [flags] delete <path>
+//
+// Flags default to environment variables VAULT_ADDR, VAULT_ROLE, VAULT_TOKEN, VAULT_TOKEN_FILE,
+// VAULT_AUTH_PATH and VAULT_JWT_PATH. Results are written to stdout as JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/phanitejak/kptgolib/vault"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr, nil); err != nil {
+		fmt.Fprintln(os.Stderr, "vaultctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes command given in args. Client is created from flags, if client is nil.
+func run(args []string, stdout, stderr io.Writer, client vault.Client) error {
+	flags := flag.NewFlagSet("vaultctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", os.Getenv("VAULT_ADDR"), "Vault address")
+	role := flags.String("role", os.Getenv("VAULT_ROLE"), "Kubernetes auth role")
+	token := flags.String("token", os.Getenv("VAULT_TOKEN"), "Vault token used instead of Kubernetes login")
+	tokenFile := flags.String("token-file", os.Getenv("VAULT_TOKEN_FILE"), "Vault Agent token file used instead of Kubernetes login")
+	authPath := flags.String("auth-path", os.Getenv("VAULT_AUTH_PATH"), "login path, auth/kubernetes/login by default")
+	jwtPath := flags.String("jwt-path", os.Getenv("VAULT_JWT_PATH"), "service account token path, default is the pod service account token")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+	kvVersion := flags.String("kv", kvAuto, "KV version: auto detects version 2 mounts from sys/mounts, 1 or 2")
+	mount := flags.String("mount", "", "KV version 2 mount path, required with -kv 2")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vaultctl [flags] read|write|list|delete <path> [key=value...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return fmt.Errorf("command and path are required")
+	}
+	command, path := flags.Arg(0), flags.Arg(1)
+
+	if client == nil {
+		if *addr == "" {
+			return fmt.Errorf("vault address is required, set -addr or VAULT_ADDR")
+		}
+		opts := []vault.ConfigFn{vault.Timeout(*timeout)}
+		switch {
+		case *token != "":
+			opts = append(opts, vault.Token(*token))
+		case *tokenFile != "":
+			opts = append(opts, vault.TokenFile(*tokenFile, false))
+		}
+		if *authPath != "" {
+			opts = append(opts, vault.AuthPath(*authPath))
+		}
+		if *jwtPath != "" {
+			opts = append(opts, vault.JwtPath(*jwtPath))
+		}
+		c, err := vault.NewClient(*addr, *role, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to create vault client: %w", err)
+		}
+		client = c
+	}
+
+	k, err := newKV(client, *kvVersion, *mount)
+	if k == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "vaultctl:", err)
+	}
+
+	var result interface{}
+	switch command {
+	case "read":
+		result, err = k.read(path)
+	case "write":
+		data, parseErr := parseData(flags.Args()[2:])
+		if parseErr != nil {
+			return parseErr
+		}
+		result, err = k.write(path, data)
+	case "list":
+		result, err = k.list(path)
+	case "delete":
+		result, err = k.delete(path)
+	default:
+		return fmt.Errorf("unknown command %s", command)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", command, path, err)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// parseData parses key=value pairs, value starting with @ is read from file.
+func parseData(pairs []string) (map[string]interface{}, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("at least one key=value pair is required")
+	}
+	data := map[string]interface{}{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid key=value pair %s", pair)
+		}
+		if file, ok := strings.CutPrefix(value, "@"); ok {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		}
+		data[key] = value
+	}
+	return data, nil
+}
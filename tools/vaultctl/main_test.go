@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/vault"
+)
+
+type mountsClient struct {
+	*vault.MockClient
+	mounts map[string]*api.MountOutput
+	err    error
+}
+
+func (c *mountsClient) ListMounts() (map[string]*api.MountOutput, error) {
+	return c.mounts, c.err
+}
+
+func TestRunKV2(t *testing.T) {
+	mock := vault.NewMockClient(t)
+	client := &mountsClient{MockClient: mock, mounts: map[string]*api.MountOutput{
+		"secret/":    {Type: "kv", Options: map[string]string{"version": "2"}},
+		"kv1/":       {Type: "kv"},
+		"secret/v1/": {Type: "kv", Options: map[string]string{"version": "1"}},
+		"sys/":       {Type: "system"},
+	}}
+
+	mock.WhenRead("secret/data/my-service/db").ThenReturn(&api.Secret{Data: map[string]interface{}{
+		"data":     map[string]interface{}{"password": "s3cret"},
+		"metadata": map[string]interface{}{"version": 1},
+	}})
+	stdout := &bytes.Buffer{}
+	require.NoError(t, run([]string{"read", "secret/my-service/db"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `{"password":"s3cret"}`, stdout.String())
+
+	mock.WhenWrite("secret/data/my-service/db", map[string]interface{}{"data": map[string]interface{}{"password": "new"}}).
+		ThenReturn(&api.Secret{Data: map[string]interface{}{"version": 2}})
+	stdout.Reset()
+	require.NoError(t, run([]string{"write", "secret/my-service/db", "password=new"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `{"version":2}`, stdout.String())
+
+	mock.WhenList("secret/metadata/my-service").ThenReturn(&api.Secret{Data: map[string]interface{}{"keys": []interface{}{"db"}}})
+	stdout.Reset()
+	require.NoError(t, run([]string{"list", "secret/my-service/"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `["db"]`, stdout.String())
+
+	mock.WhenDelete("secret/data/my-service/db").ThenReturn(nil)
+	stdout.Reset()
+	require.NoError(t, run([]string{"delete", "secret/my-service/db"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `{}`, stdout.String())
+
+	mock.WhenRead("secret/v1/my-service").ThenReturn(&api.Secret{Data: map[string]interface{}{"key": "value"}})
+	stdout.Reset()
+	require.NoError(t, run([]string{"read", "secret/v1/my-service"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `{"key":"value"}`, stdout.String())
+
+	mock.WhenRead("kv1/my-service").ThenReturn(&api.Secret{Data: map[string]interface{}{"key": "value"}})
+	stdout.Reset()
+	require.NoError(t, run([]string{"read", "kv1/my-service"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `{"key":"value"}`, stdout.String())
+}
+
+func TestRunMountsNotAllowed(t *testing.T) {
+	mock := vault.NewMockClient(t)
+	client := &mountsClient{MockClient: mock, err: errors.New("permission denied")}
+
+	mock.WhenRead("secret/my-service").ThenReturn(&api.Secret{Data: map[string]interface{}{"key": "value"}})
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	require.NoError(t, run([]string{"read", "secret/my-service"}, stdout, stderr, client))
+	assert.JSONEq(t, `{"key":"value"}`, stdout.String())
+	assert.Contains(t, stderr.String(), "permission denied")
+
+	mock.WhenRead("secret/data/my-service").ThenReturn(&api.Secret{Data: map[string]interface{}{"data": map[string]interface{}{"key": "value"}}})
+	stdout.Reset()
+	require.NoError(t, run([]string{"-kv", "2", "-mount", "secret", "read", "secret/my-service"}, stdout, &bytes.Buffer{}, client))
+	assert.JSONEq(t, `{"key":"value"}`, stdout.String())
+}
+
+func TestRunErrors(t *testing.T) {
+	client := &mountsClient{MockClient: vault.NewMockClient(t)}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "MissingPath", args: []string{"read"}, want: "command and path are required"},
+		{name: "UnknownCommand", args: []string{"get", "secret/a"}, want: "unknown command get"},
+		{name: "MissingData", args: []string{"write", "secret/a"}, want: "at least one key=value pair is required"},
+		{name: "InvalidData", args: []string{"write", "secret/a", "key"}, want: "invalid key=value pair key"},
+		{name: "MissingMount", args: []string{"-kv", "2", "read", "secret/a"}, want: "mount is required"},
+		{name: "InvalidVersion", args: []string{"-kv", "3", "read", "secret/a"}, want: "unknown KV version 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(tt.args, &bytes.Buffer{}, &bytes.Buffer{}, client)
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	t.Setenv("VAULT_ADDR", "")
+	err := run([]string{"read", "secret/a"}, &bytes.Buffer{}, &bytes.Buffer{}, nil)
+	require.ErrorContains(t, err, "vault address is required")
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultCloseTimeout is time given for flushing traces when tracer is closed by tracermod.
+const DefaultCloseTimeout = 5 * time.Second
+
+// Shutdowner is implemented by closer returned by InitGlobalTracer.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// CloseWithContext closes closer returned by InitGlobalTracer, flushing remaining spans until ctx is done.
+// Other closers are closed in background and error is returned when ctx is done before Close returns.
+func CloseWithContext(ctx context.Context, closer io.Closer) error {
+	if s, ok := closer.(Shutdowner); ok {
+		return s.Shutdown(ctx)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- closer.Close() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("tracer was not closed: %w", ctx.Err())
+	}
+}
+
+// CloseWithTimeout closes closer returned by InitGlobalTracer like CloseWithContext, but gives up after timeout,
+// so that a stuck collector can't block termination of the application. Spans not flushed in time are lost.
+func CloseWithTimeout(closer io.Closer, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return CloseWithContext(ctx, closer)
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestCloseWithTimeout(t *testing.T) {
+	t.Run("shutdown with deadline", func(t *testing.T) {
+		var deadline time.Time
+		closer := contextCloser(func(ctx context.Context) error {
+			deadline, _ = ctx.Deadline()
+			return nil
+		})
+		require.NoError(t, CloseWithTimeout(closer, time.Minute))
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+	})
+
+	t.Run("stuck closer", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		closer := closerFunc(func() error {
+			<-release
+			return nil
+		})
+		started := time.Now()
+		err := CloseWithTimeout(closer, 10*time.Millisecond)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(started), time.Second)
+	})
+
+	t.Run("closer error", func(t *testing.T) {
+		closer := closerFunc(func() error { return errors.New("failed") })
+		assert.EqualError(t, CloseWithTimeout(closer, time.Second), "failed")
+	})
+
+	t.Run("global tracer", func(t *testing.T) {
+		closer, err := InitGlobalTracer()
+		require.NoError(t, err)
+		require.NoError(t, CloseWithTimeout(closer, time.Second))
+	})
+}
@@ -16,6 +16,12 @@ type TracingConfiguration struct {
 	JaegerReporterLogSpans bool     `envconfig:"JAEGER_REPORTER_LOG_SPANS"`
 	UseSimpleSpanProcessor bool     `envconfig:"USE_SIMPLE_SPAN_PROCESSOR" default:"false"`
 	OtelPropagators        []string `envconfig:"OTEL_PROPAGATORS" default:"tracecontext,baggage,jaeger"`
+	// SamplingRules are root span sampling rules in format pattern=ratio, e.g. /status=0,/api/reports/*=1.
+	SamplingRules []string `envconfig:"TRACING_SAMPLING_RULES"`
+	SampleErrors  bool     `envconfig:"TRACING_SAMPLE_ERRORS" default:"false"`
+	SpanMetrics   bool     `envconfig:"TRACING_SPAN_METRICS" default:"false"`
+	// Mode is one of off, noop, record or export.
+	Mode string `envconfig:"TRACING_MODE" default:"export"`
 }
 
 // FromEnv ...
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedactedValue replaces values of redacted headers and query params in span attributes.
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHTTPFields are headers and query params, which are always redacted by WrapWithCapture.
+var DefaultRedactedHTTPFields = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"access_token",
+	"token",
+	"password",
+}
+
+// HTTPCapture selects request and response metadata captured into server span attributes by WrapWithCapture.
+// Only listed names are captured, so nothing ends up in traces unless it was explicitly allowed.
+// Header names are case-insensitive, query param names are not. Values of names in Redact and DefaultRedactedHTTPFields
+// are replaced with RedactedValue regardless of case.
+type HTTPCapture struct {
+	RequestHeaders  []string
+	ResponseHeaders []string
+	QueryParams     []string
+	Redact          []string
+}
+
+// WrapWithCapture works as Wrap and captures metadata selected by capture into attributes
+// "http.request.header.<name>", "http.response.header.<name>" and "http.request.query.<name>"
+// with lower case names. Multiple values are joined by ", ". Query string is removed from "http.target",
+// so that params not allowed by capture are not exported.
+func WrapWithCapture(handler http.Handler, capture HTTPCapture) http.Handler {
+	h := &captureHandler{next: handler, capture: capture, redact: map[string]bool{}}
+	for _, names := range [][]string{DefaultRedactedHTTPFields, capture.Redact} {
+		for _, name := range names {
+			h.redact[strings.ToLower(name)] = true
+		}
+	}
+	return otelhttp.NewHandler(h, "", otelhttp.WithSpanNameFormatter(nameFormatter))
+}
+
+type captureHandler struct {
+	next    http.Handler
+	capture HTTPCapture
+	redact  map[string]bool
+}
+
+func (h *captureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	span := trace.SpanFromContext(r.Context())
+	if !span.IsRecording() {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	attrs := []attribute.KeyValue{semconv.HTTPTargetKey.String(r.URL.Path)}
+	attrs = h.appendHeaders(attrs, "http.request.header.", r.Header, h.capture.RequestHeaders)
+	query := r.URL.Query()
+	for _, name := range h.capture.QueryParams {
+		if values, ok := query[name]; ok {
+			attrs = append(attrs, h.attribute("http.request.query.", name, values))
+		}
+	}
+	span.SetAttributes(attrs...)
+
+	h.next.ServeHTTP(w, r)
+	span.SetAttributes(h.appendHeaders(nil, "http.response.header.", w.Header(), h.capture.ResponseHeaders)...)
+}
+
+func (h *captureHandler) appendHeaders(attrs []attribute.KeyValue, prefix string, header http.Header, names []string) []attribute.KeyValue {
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			attrs = append(attrs, h.attribute(prefix, name, values))
+		}
+	}
+	return attrs
+}
+
+func (h *captureHandler) attribute(prefix, name string, values []string) attribute.KeyValue {
+	name = strings.ToLower(name)
+	if h.redact[name] {
+		return attribute.String(prefix+name, RedactedValue)
+	}
+	return attribute.String(prefix+name, strings.Join(values, ", "))
+}
@@ -0,0 +1,53 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/phanitejak/kptgolib/tracing/tracingtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapWithCapture(t *testing.T) {
+	closer, mp := tracingtest.SetUpWithMockProcessor(t)
+	defer closer()
+
+	h := tracing.WrapWithCapture(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cache", "hit")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Internal", "not captured")
+	}), tracing.HTTPCapture{
+		RequestHeaders:  []string{"x-tenant", "Authorization", "X-Missing"},
+		ResponseHeaders: []string{"X-Cache", "Set-Cookie"},
+		QueryParams:     []string{"page", "access_token", "apikey"},
+		Redact:          []string{"APIKEY"},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/items?page=2&access_token=secret&apikey=secret&other=secret", nil)
+	r.Header.Add("X-Tenant", "a")
+	r.Header.Add("X-Tenant", "b")
+	r.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	expected := map[string]string{
+		"http.target":                       "/api/v1/items",
+		"http.request.header.x-tenant":      "a, b",
+		"http.request.header.authorization": tracing.RedactedValue,
+		"http.response.header.x-cache":      "hit",
+		"http.response.header.set-cookie":   tracing.RedactedValue,
+		"http.request.query.page":           "2",
+		"http.request.query.access_token":   tracing.RedactedValue,
+		"http.request.query.apikey":         tracing.RedactedValue,
+	}
+	for key, value := range expected {
+		actual, ok := mp.FindAttribute("GET /api/v1/items", key)
+		assert.True(t, ok, key)
+		assert.Equal(t, value, actual, key)
+	}
+	for _, key := range []string{"http.request.header.x-missing", "http.response.header.x-internal", "http.request.query.other"} {
+		_, ok := mp.FindAttribute("GET /api/v1/items", key)
+		assert.False(t, ok, key)
+	}
+}
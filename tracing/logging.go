@@ -28,13 +28,9 @@ func (l *Logger) For(context context.Context) *Logger {
 	if !ctx.IsValid() {
 		return l
 	}
-	// NNEO-12959: Lost parent_id in refactoring, doesn't seem to be easily available anymore
 	ctxLogger := &Logger{
-		Logger: l.Logger.
-			With("trace_id", ctx.TraceID().String()).
-			With("span_id", ctx.SpanID().String()).
-			With("is_sampled", fmt.Sprintf("%v", ctx.IsSampled())),
-		span: span,
+		Logger: withSpanContext(l.Logger, ctx),
+		span:   span,
 	}
 
 	incLog, ok := ctxLogger.Logger.(depthInc)
@@ -45,6 +41,25 @@ func (l *Logger) For(context context.Context) *Logger {
 	return ctxLogger
 }
 
+// LoggerWithSpan returns given logging v1 logger with trace_id, span_id and is_sampled fields of the span
+// in given context, so that services not using Logger can correlate logs with traces without changing
+// every call site. Logger is returned as is if context has no valid span.
+func LoggerWithSpan(logger logging.Logger, ctx context.Context) logging.Logger {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return logger
+	}
+	return withSpanContext(logger, spanCtx)
+}
+
+func withSpanContext(logger logging.Logger, ctx trace.SpanContext) logging.Logger {
+	// NNEO-12959: Lost parent_id in refactoring, doesn't seem to be easily available anymore
+	return logger.
+		With("trace_id", ctx.TraceID().String()).
+		With("span_id", ctx.SpanID().String()).
+		With("is_sampled", fmt.Sprintf("%v", ctx.IsSampled()))
+}
+
 // Debug logs a message at level Debug.
 func (l *Logger) Debug(args ...interface{}) {
 	l.Logger.Debug(args...)
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/phanitejak/kptgolib/logging"
+	"github.com/phanitejak/kptgolib/logging/testutil"
 	"github.com/phanitejak/kptgolib/tracing"
 	"github.com/phanitejak/kptgolib/tracing/tracingtest"
 	"github.com/stretchr/testify/assert"
@@ -92,6 +93,26 @@ func TestShouldLogIsSampledAsString(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestLoggerWithSpan(t *testing.T) {
+	cleanUp := tracingtest.SetUp(t)
+	defer cleanUp()
+	t.Setenv("LOGGING_FORMAT", "json")
+
+	span, ctx := tracing.StartSpanFromContext(context.Background(), "testSpan")
+	defer span.End()
+
+	logOutput := testutil.PipeStderr(t)
+	logger := logging.NewLogger()
+	assert.Equal(t, logger, tracing.LoggerWithSpan(logger, context.Background()))
+	tracing.LoggerWithSpan(logger, ctx).Info("Test")
+
+	logEntry := testutil.UnmarshalLogMessage(t, logOutput().Bytes())
+	assert.Equal(t, span.SpanContext().TraceID().String(), logEntry["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), logEntry["span_id"])
+	assert.Equal(t, "true", logEntry["is_sampled"])
+	assert.Contains(t, logEntry["logger"], "logging_test.go")
+}
+
 func TestLogFatal(t *testing.T) {
 	if os.Getenv("CRASH_APPLICATION") == "1" {
 		_, ctx := tracing.StartSpanFromContext(context.Background(), "crashingSpan")
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/phanitejak/kptgolib/tracing/configuration"
+)
+
+// Tracing modes selected with TRACING_MODE environment variable.
+const (
+	// ModeOff disables tracing and propagation of trace context.
+	ModeOff = "off"
+	// ModeNoop creates no spans, but propagates trace context of incoming requests and messages.
+	ModeNoop = "noop"
+	// ModeRecord samples all spans and keeps finished spans in memory instead of exporting them,
+	// see RecordedSpans.
+	ModeRecord = "record"
+	// ModeExport exports spans as configured. This is the default.
+	ModeExport = "export"
+)
+
+var recorder = &spanRecorder{}
+
+// RecordedSpans returns spans finished since the global tracer was initialized in record mode or
+// ResetRecordedSpans was called. It returns nil in other modes.
+func RecordedSpans() []tracesdk.ReadOnlySpan {
+	return recorder.spans()
+}
+
+// ResetRecordedSpans drops spans recorded so far.
+func ResetRecordedSpans() {
+	recorder.reset()
+}
+
+func validateMode(cfg *configuration.TracingConfiguration) error {
+	switch cfg.Mode {
+	case ModeOff, ModeNoop, ModeRecord, ModeExport:
+		return nil
+	default:
+		return fmt.Errorf("tracing mode not supported: %s", cfg.Mode)
+	}
+}
+
+// setTracerForMode sets global tracer provider for modes other than export. It returns false for export mode.
+func setTracerForMode(c *conf, cfg *configuration.TracingConfiguration) (closer contextCloser, handled bool, err error) {
+	if err := validateMode(cfg); err != nil {
+		return nil, false, err
+	}
+
+	noopCloser := contextCloser(func(context.Context) error { return nil })
+	switch cfg.Mode {
+	case ModeOff:
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+		return noopCloser, true, nil
+	case ModeNoop:
+		propagators, err := parseOtelPropagators(cfg)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed parsing propagators: %w", err)
+		}
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagators...))
+		return noopCloser, true, nil
+	case ModeRecord:
+		propagators, err := parseOtelPropagators(cfg)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed parsing propagators: %w", err)
+		}
+		withResource, err := createWithResourceOpt(cfg)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed creating tracing reosurce: %w", err)
+		}
+		recorder.start()
+		opts := []tracesdk.TracerProviderOption{
+			withResource,
+			tracesdk.WithSampler(tracesdk.AlwaysSample()),
+			tracesdk.WithSpanProcessor(recorder),
+		}
+		tp := tracesdk.NewTracerProvider(append(opts, getTracerProviderOptsFromConf(c)...)...)
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagators...))
+		return contextCloser(tp.Shutdown), true, nil
+	default:
+		recorder.stop()
+		return nil, false, nil
+	}
+}
+
+// spanRecorder is a span processor keeping finished spans in memory while recording.
+type spanRecorder struct {
+	mu        sync.Mutex
+	recording bool
+	finished  []tracesdk.ReadOnlySpan
+}
+
+func (r *spanRecorder) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recording = true
+	r.finished = []tracesdk.ReadOnlySpan{}
+}
+
+func (r *spanRecorder) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recording = false
+	r.finished = nil
+}
+
+func (r *spanRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recording {
+		r.finished = []tracesdk.ReadOnlySpan{}
+	}
+}
+
+func (r *spanRecorder) spans() []tracesdk.ReadOnlySpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recording {
+		return nil
+	}
+	return append([]tracesdk.ReadOnlySpan{}, r.finished...)
+}
+
+// OnStart does nothing.
+func (r *spanRecorder) OnStart(context.Context, tracesdk.ReadWriteSpan) {}
+
+// OnEnd records finished span.
+func (r *spanRecorder) OnEnd(s tracesdk.ReadOnlySpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recording {
+		r.finished = append(r.finished, s)
+	}
+}
+
+// Shutdown does nothing, recorded spans are kept for assertions after the tracer is closed.
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing as there is no data to flush.
+func (r *spanRecorder) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingModes(t *testing.T) {
+	t.Setenv("JAEGER_SAMPLER_TYPE", "const")
+	t.Setenv("JAEGER_SAMPLER_PARAM", "1")
+
+	t.Run("off", func(t *testing.T) {
+		t.Setenv("TRACING_MODE", ModeOff)
+		closer, err := InitGlobalTracer()
+		require.NoError(t, err)
+		defer closer.Close()
+
+		_, span := otel.Tracer("test").Start(context.Background(), "span")
+		defer span.End()
+		assert.False(t, span.IsRecording())
+		assert.Empty(t, otel.GetTextMapPropagator().Fields())
+		assert.Nil(t, RecordedSpans())
+	})
+
+	t.Run("noop", func(t *testing.T) {
+		t.Setenv("TRACING_MODE", ModeNoop)
+		closer, err := InitGlobalTracer()
+		require.NoError(t, err)
+		defer closer.Close()
+
+		_, span := otel.Tracer("test").Start(context.Background(), "span")
+		defer span.End()
+		assert.False(t, span.IsRecording())
+		assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+	})
+
+	t.Run("record", func(t *testing.T) {
+		t.Setenv("TRACING_MODE", ModeRecord)
+		closer, err := InitGlobalTracer()
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "span", trace.WithSpanKind(trace.SpanKindServer))
+		span.End()
+		require.NoError(t, closer.Close())
+
+		spans := RecordedSpans()
+		require.Len(t, spans, 1, "spans should be kept after close")
+		assert.Equal(t, "span", spans[0].Name())
+	})
+
+	t.Run("export", func(t *testing.T) {
+		t.Setenv("TRACING_MODE", ModeExport)
+		closer, err := InitGlobalTracer()
+		require.NoError(t, err)
+		defer closer.Close()
+
+		_, span := otel.Tracer("test").Start(context.Background(), "span")
+		defer span.End()
+		assert.True(t, span.IsRecording())
+		assert.Nil(t, RecordedSpans())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("TRACING_MODE", "verbose")
+		_, err := InitGlobalTracer()
+		assert.Error(t, err)
+		_, err = ValidateConfig()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,138 @@
+// Package obs bootstraps logging, tracing and metrics management server consistently for a service.
+package obs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/kelseyhightower/envconfig"
+	loggingv2 "github.com/phanitejak/kptgolib/logging/v2"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing"
+)
+
+// Config for observability bootstrap.
+type Config struct {
+	ManagementAddr    string `envconfig:"MANAGEMENT_SERVER_ADDR" default:":9876"`
+	ManagementEnabled bool   `envconfig:"MANAGEMENT_SERVER_ENABLED" default:"true"`
+	// TracingSelfTestTimeout limits how long tracing.SelfTestPath endpoint waits for exporter acknowledgment.
+	TracingSelfTestTimeout time.Duration `envconfig:"TRACING_SELF_TEST_TIMEOUT" default:"5s"`
+}
+
+// Opt for Init.
+type Opt func(c *conf) error
+
+type conf struct {
+	Config
+	healthCheck func(http.ResponseWriter, *http.Request)
+	logger      loggingv2.Logger
+}
+
+// WithConfig overrides configuration read from environment.
+func WithConfig(config Config) Opt {
+	return func(c *conf) error {
+		c.Config = config
+		return nil
+	}
+}
+
+// WithManagementAddr sets listen address of the management server.
+func WithManagementAddr(addr string) Opt {
+	return func(c *conf) error {
+		c.ManagementAddr = addr
+		return nil
+	}
+}
+
+// WithoutManagementServer disables the management server, e.g. when metrics are exposed with httpmod.WithMetrics.
+func WithoutManagementServer() Opt {
+	return func(c *conf) error {
+		c.ManagementEnabled = false
+		return nil
+	}
+}
+
+// WithHealthCheck sets health check function for the management server, see metrics.StartManagementServer.
+func WithHealthCheck(healthCheck func(http.ResponseWriter, *http.Request)) Opt {
+	return func(c *conf) error {
+		c.healthCheck = healthCheck
+		return nil
+	}
+}
+
+// WithLogger sets logger instead of logging.NewLogger.
+func WithLogger(logger loggingv2.Logger) Opt {
+	return func(c *conf) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// Observability holds the components initialized by Init.
+type Observability struct {
+	// Logger is the service logger.
+	Logger loggingv2.Logger
+	// TracingLogger is logger for runner modules and other components still using logging v1.
+	TracingLogger *tracing.Logger
+	// ManagementServer is nil if it was disabled.
+	ManagementServer *metrics.ManagementServer
+
+	tracerCloser io.Closer
+}
+
+// Init creates logger, initializes global tracer with given service name and starts metrics management server,
+// which also serves tracing.SelfTestHandler on tracing.SelfTestPath.
+// Service name is used for tracing when JAEGER_SERVICE_NAME is not set. Returned Observability must be closed
+// when the service stops to flush traces.
+func Init(serviceName string, opts ...Opt) (*Observability, error) {
+	c := &conf{}
+	if err := envconfig.Process("", &c.Config); err != nil {
+		return nil, fmt.Errorf("failed to process observability config: %w", err)
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	o := &Observability{
+		Logger:        c.logger,
+		TracingLogger: tracing.NewDefaultLogger(),
+	}
+	if o.Logger == nil {
+		o.Logger = loggingv2.NewLogger()
+	}
+
+	closer, err := tracing.InitGlobalTracer(tracing.WithV2Logger(o.Logger), tracing.WithServiceName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracer: %w", err)
+	}
+	o.tracerCloser = closer
+
+	if c.ManagementEnabled {
+		o.ManagementServer = metrics.StartManagementServer(c.ManagementAddr, c.healthCheck)
+		o.ManagementServer.Handle(tracing.SelfTestPath, tracing.SelfTestHandler(c.TracingSelfTestTimeout))
+	}
+
+	return o, nil
+}
+
+// Close marks shutdown started, shuts down the management server and flushes traces until ctx is done.
+func (o *Observability) Close(ctx context.Context) error {
+	var errs error
+	metrics.MarkShutdownStarted()
+	if o.ManagementServer != nil {
+		if err := o.ManagementServer.Shutdown(ctx); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to shutdown management server: %w", err))
+		}
+	}
+	if err := tracing.CloseWithContext(ctx, o.tracerCloser); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("failed to close tracer: %w", err))
+	}
+	return errs
+}
@@ -0,0 +1,41 @@
+package obs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/phanitejak/kptgolib/tracing/obs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	o, err := obs.Init("my-service", obs.WithManagementAddr("127.0.0.1:0"))
+	require.NoError(t, err)
+	require.NotNil(t, o.Logger)
+	require.NotNil(t, o.TracingLogger)
+	require.NotNil(t, o.ManagementServer)
+
+	span, _ := tracing.StartSpan("test")
+	assert.True(t, span.SpanContext().IsValid())
+	span.Finish()
+
+	assert.NoError(t, o.Close(context.Background()))
+}
+
+func TestInitWithoutManagementServer(t *testing.T) {
+	t.Setenv("MANAGEMENT_SERVER_ENABLED", "false")
+
+	o, err := obs.Init("my-service")
+	require.NoError(t, err)
+	assert.Nil(t, o.ManagementServer)
+	assert.NoError(t, o.Close(context.Background()))
+}
+
+func TestInitInvalidConfig(t *testing.T) {
+	t.Setenv("OTEL_PROPAGATORS", "invalid")
+
+	_, err := obs.Init("my-service", obs.WithoutManagementServer())
+	assert.Error(t, err)
+}
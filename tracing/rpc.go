@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Helper attributes for tagging RPC spans.
+var (
+	RPCSystem         = semconv.RPCSystemKey
+	RPCService        = semconv.RPCServiceKey
+	RPCMethod         = semconv.RPCMethodKey
+	RPCGRPCStatusCode = semconv.RPCGRPCStatusCodeKey
+)
+
+// MetadataCarrier adapts gRPC metadata to propagation.TextMapCarrier. Metadata keys are lower case,
+// so metadata.MD can be converted to MetadataCarrier as is:
+//
+//	md, _ := metadata.FromIncomingContext(ctx)
+//	span, ctx := tracing.StartRPCServerSpan(ctx, info.FullMethod, tracing.MetadataCarrier(md))
+type MetadataCarrier map[string][]string
+
+// Get returns the first value associated with the given key.
+func (c MetadataCarrier) Get(key string) string {
+	values := c[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set sets the value associated with the given key, replacing existing values.
+func (c MetadataCarrier) Set(key string, value string) {
+	c[strings.ToLower(key)] = []string{value}
+}
+
+// Keys lists the keys stored in this carrier.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// StartRPCServerSpan starts server span for RPC with given full method name, e.g. "/package.Service/Method".
+// If incoming metadata contains tracing headers the span will follow the existing trace.
+// Interceptors are built on top of it, e.g. unary server interceptor:
+//
+//	func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+//		md, _ := metadata.FromIncomingContext(ctx)
+//		span, ctx := tracing.StartRPCServerSpan(ctx, info.FullMethod, tracing.MetadataCarrier(md))
+//		resp, err := handler(ctx, req)
+//		tracing.EndRPCSpan(span, uint32(status.Code(err)), err)
+//		return resp, err
+//	}
+func StartRPCServerSpan(ctx context.Context, fullMethod string, md MetadataCarrier) (Span, context.Context) {
+	if md != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, md)
+	}
+	return startRPCSpan(ctx, fullMethod, trace.SpanKindServer)
+}
+
+// StartRPCClientSpan starts client span for RPC with given full method name and injects span context
+// to outgoing metadata, so that servers will continue the trace:
+//
+//	md, _ := metadata.FromOutgoingContext(ctx)
+//	md = md.Copy()
+//	span, ctx := tracing.StartRPCClientSpan(ctx, method, tracing.MetadataCarrier(md))
+//	err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+//	tracing.EndRPCSpan(span, uint32(status.Code(err)), err)
+func StartRPCClientSpan(ctx context.Context, fullMethod string, md MetadataCarrier) (Span, context.Context) {
+	span, ctx := startRPCSpan(ctx, fullMethod, trace.SpanKindClient)
+	if md != nil {
+		otel.GetTextMapPropagator().Inject(ctx, md)
+	}
+	return span, ctx
+}
+
+// EndRPCSpan records gRPC status code and error of the RPC and ends the span. Span is marked as failed
+// according to DefaultStatusPolicy, e.g. NotFound fails client spans, but not server spans.
+func EndRPCSpan(span Span, statusCode uint32, err error) {
+	DefaultStatusPolicy.EndRPCSpan(span, statusCode, err)
+}
+
+// rpcSpan remembers whether RPC span is a server span for status mapping.
+type rpcSpan struct {
+	Span
+	server bool
+}
+
+func startRPCSpan(ctx context.Context, fullMethod string, kind trace.SpanKind) (Span, context.Context) {
+	service, method := splitFullMethod(fullMethod)
+	span, ctx := StartSpanFromContext(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(semconv.RPCSystemGRPC, RPCService.String(service), RPCMethod.String(method)),
+	)
+	return &rpcSpan{Span: span, server: kind == trace.SpanKindServer}, ctx
+}
+
+// splitFullMethod splits "/package.Service/Method" to service and method names.
+func splitFullMethod(fullMethod string) (string, string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
@@ -0,0 +1,57 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/phanitejak/kptgolib/tracing/tracingtest"
+)
+
+func TestRPCSpansPropagateContextThroughMetadata(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+
+	md := tracing.MetadataCarrier{}
+	clientSpan, _ := tracing.StartRPCClientSpan(context.Background(), "/test.Echo/Say", md)
+	require.NotEmpty(t, md.Get("traceparent"))
+
+	serverSpan, _ := tracing.StartRPCServerSpan(context.Background(), "/test.Echo/Say", md)
+	assert.Equal(t, clientSpan.SpanContext().TraceID(), serverSpan.SpanContext().TraceID())
+
+	tracing.EndRPCSpan(serverSpan, 0, nil)
+	tracing.EndRPCSpan(clientSpan, 0, nil)
+
+	assert.Equal(t, 2, mockPros.GetSpanAmount("test.Echo/Say"))
+	system, _ := mockPros.FindAttribute("test.Echo/Say", string(tracing.RPCSystem))
+	assert.Equal(t, "grpc", system)
+	service, _ := mockPros.FindAttribute("test.Echo/Say", string(tracing.RPCService))
+	assert.Equal(t, "test.Echo", service)
+	method, _ := mockPros.FindAttribute("test.Echo/Say", string(tracing.RPCMethod))
+	assert.Equal(t, "Say", method)
+}
+
+func TestEndRPCSpanRecordsError(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+
+	span, _ := tracing.StartRPCServerSpan(context.Background(), "/test.Echo/Fail", nil)
+	tracing.EndRPCSpan(span, 13, errors.New("internal failure"))
+
+	assert.Equal(t, int64(13), spanAttribute(t, mockPros, "test.Echo/Fail", tracing.RPCGRPCStatusCode).AsInt64())
+	_, ok := mockPros.FindEventAttribute("test.Echo/Fail", "exception.message")
+	require.True(t, ok)
+}
+
+func TestMetadataCarrierKeysAreCaseInsensitive(t *testing.T) {
+	md := tracing.MetadataCarrier{}
+	md.Set("TraceParent", "value")
+
+	assert.Equal(t, "value", md.Get("traceparent"))
+	assert.Equal(t, []string{"traceparent"}, md.Keys())
+	assert.Empty(t, md.Get("missing"))
+}
@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SamplingRule sets sampling ratio of root spans matching Pattern.
+// Pattern matches span name, e.g. "GET /status", or only its path, e.g. "/status", for HTTP server spans
+// created by Wrap. Pattern ending with "*" matches by prefix, e.g. "/api/reports/*".
+type SamplingRule struct {
+	Pattern string
+	Ratio   float64
+}
+
+// WithSamplingRules adds sampling rules, which take precedence over configured sampler for root spans.
+// First matching rule wins and rules from TRACING_SAMPLING_RULES are evaluated before these,
+// so that they can be overridden per deployment. Can be used as and opt for InitGlobalTracer.
+func WithSamplingRules(rules ...SamplingRule) func(*conf) error {
+	return func(c *conf) error {
+		for _, rule := range rules {
+			if err := rule.validate(); err != nil {
+				return err
+			}
+		}
+		c.samplingRules = append(c.samplingRules, rules...)
+		return nil
+	}
+}
+
+// WithErrorSampling exports spans with error status even if their trace was not sampled,
+// same as TRACING_SAMPLE_ERRORS=true. Can be used as and opt for InitGlobalTracer.
+func WithErrorSampling() func(*conf) error {
+	return func(c *conf) error {
+		c.sampleErrors = true
+		return nil
+	}
+}
+
+func (r SamplingRule) validate() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("sampling rule pattern must not be empty")
+	}
+	if r.Ratio < 0 || r.Ratio > 1 {
+		return fmt.Errorf("sampling ratio of %s must be between 0 and 1", r.Pattern)
+	}
+	return nil
+}
+
+func (r SamplingRule) matches(name string) bool {
+	candidates := []string{name}
+	if i := strings.IndexByte(name, ' '); i >= 0 {
+		candidates = append(candidates, name[i+1:])
+	}
+	prefix, isPrefix := strings.CutSuffix(r.Pattern, "*")
+	for _, candidate := range candidates {
+		if candidate == r.Pattern || isPrefix && strings.HasPrefix(candidate, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSamplingRules parses rules in format "pattern=ratio", e.g. "/status=0".
+func parseSamplingRules(values []string) ([]SamplingRule, error) {
+	rules := make([]SamplingRule, 0, len(values))
+	for _, value := range values {
+		i := strings.LastIndexByte(value, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid sampling rule %s: expected format pattern=ratio", value)
+		}
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(value[i+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sampling ratio of rule %s: %w", value, err)
+		}
+		rule := SamplingRule{Pattern: strings.TrimSpace(value[:i]), Ratio: ratio}
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+type ruleSampler struct {
+	rules           []SamplingRule
+	samplers        []tracesdk.Sampler
+	fallback        tracesdk.Sampler
+	recordUnsampled bool
+}
+
+// newRuleSampler returns sampler applying rules to root spans and fallback to root spans not matching any rule.
+// Child spans follow sampling decision of their parent. With recordUnsampled spans which are not sampled
+// are still recorded, so that errorSamplingProcessor can export them if they end with error status
+// and span metrics cover them.
+func newRuleSampler(fallback tracesdk.Sampler, recordUnsampled bool, rules ...SamplingRule) tracesdk.Sampler {
+	s := &ruleSampler{rules: rules, fallback: fallback, recordUnsampled: recordUnsampled}
+	for _, rule := range rules {
+		s.samplers = append(s.samplers, tracesdk.TraceIDRatioBased(rule.Ratio))
+	}
+	return s
+}
+
+func (s *ruleSampler) ShouldSample(p tracesdk.SamplingParameters) tracesdk.SamplingResult {
+	result := s.sample(p)
+	if result.Decision == tracesdk.Drop && s.recordUnsampled {
+		result.Decision = tracesdk.RecordOnly
+	}
+	return result
+}
+
+func (s *ruleSampler) sample(p tracesdk.SamplingParameters) tracesdk.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	if psc.IsValid() {
+		decision := tracesdk.Drop
+		if psc.IsSampled() {
+			decision = tracesdk.RecordAndSample
+		}
+		return tracesdk.SamplingResult{Decision: decision, Tracestate: psc.TraceState()}
+	}
+	for i, rule := range s.rules {
+		if rule.matches(p.Name) {
+			return s.samplers[i].ShouldSample(p)
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s *ruleSampler) Description() string {
+	rules := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, fmt.Sprintf("%s=%g", rule.Pattern, rule.Ratio))
+	}
+	return fmt.Sprintf("RuleSampler{rules:[%s],recordUnsampled:%t,fallback:%s}", strings.Join(rules, ","), s.recordUnsampled, s.fallback.Description())
+}
+
+// errorSamplingProcessor forwards sampled spans and spans ending with error status to next processor.
+// Only the failed span is exported from a trace which was not sampled.
+type errorSamplingProcessor struct {
+	tracesdk.SpanProcessor
+}
+
+func (p errorSamplingProcessor) OnEnd(s tracesdk.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		if s.Status().Code != codes.Error {
+			return
+		}
+		s = sampledSpan{s}
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+// sampledSpan marks span as sampled, so that span processors export it.
+type sampledSpan struct {
+	tracesdk.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/phanitejak/kptgolib/tracing/configuration"
+)
+
+type recordingExporter struct {
+	lock  sync.Mutex
+	names []string
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []tracesdk.ReadOnlySpan) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, s := range spans {
+		e.names = append(e.names, s.Name())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error { return nil }
+
+func TestParseSamplingRules(t *testing.T) {
+	rules, err := parseSamplingRules([]string{"/status=0", " GET /api/reports/* = 1"})
+	require.NoError(t, err)
+	assert.Equal(t, []SamplingRule{{Pattern: "/status", Ratio: 0}, {Pattern: "GET /api/reports/*", Ratio: 1}}, rules)
+
+	for _, invalid := range []string{"/status", "/status=x", "/status=2", "=0.5"} {
+		_, err := parseSamplingRules([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSamplingRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "/status", name: "GET /status", want: true},
+		{pattern: "GET /status", name: "GET /status", want: true},
+		{pattern: "POST /status", name: "GET /status", want: false},
+		{pattern: "/status", name: "GET /status/details", want: false},
+		{pattern: "/api/reports/*", name: "GET /api/reports/123", want: true},
+		{pattern: "/api/reports/*", name: "GET /api/users", want: false},
+		{pattern: "process-message", name: "process-message", want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SamplingRule{Pattern: tt.pattern}.matches(tt.name), "%s ~ %s", tt.pattern, tt.name)
+	}
+}
+
+func TestRuleSampler(t *testing.T) {
+	tests := []struct {
+		name         string
+		sampleErrors bool
+		want         []string
+	}{
+		{name: "RulesOnly", want: []string{"GET /api/reports/1", "GET /orders"}},
+		{name: "SampleErrors", sampleErrors: true, want: []string{"GET /status", "GET /api/reports/1", "GET /orders"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &recordingExporter{}
+			cfg := &configuration.TracingConfiguration{
+				JaegerSamplerType:  legacyProbabilisticSampler,
+				JaegerSamplerParam: "1",
+				SamplingRules:      []string{"/status=0"},
+				SampleErrors:       tt.sampleErrors,
+			}
+			withSampler, err := createWithSamplerOpt(cfg, SamplingRule{Pattern: "/status", Ratio: 1}, SamplingRule{Pattern: "/api/*", Ratio: 1})
+			require.NoError(t, err)
+			var processor tracesdk.SpanProcessor = tracesdk.NewSimpleSpanProcessor(exp)
+			if tt.sampleErrors {
+				processor = errorSamplingProcessor{processor}
+			}
+			tp := tracesdk.NewTracerProvider(withSampler, tracesdk.WithSpanProcessor(processor))
+			tracer := tp.Tracer("test")
+
+			// Environment rule dropping /status takes precedence over the rule given as argument.
+			ctx, span := tracer.Start(context.Background(), "GET /status")
+			assert.False(t, span.SpanContext().IsSampled())
+			_, child := tracer.Start(ctx, "SELECT")
+			assert.False(t, child.SpanContext().IsSampled(), "child should follow parent decision")
+			child.End()
+			span.SetStatus(codes.Error, "failed")
+			span.End()
+
+			_, span = tracer.Start(context.Background(), "GET /api/reports/1")
+			span.End()
+			_, span = tracer.Start(context.Background(), "GET /orders")
+			span.End()
+
+			require.NoError(t, tp.Shutdown(context.Background()))
+			assert.Equal(t, tt.want, exp.names)
+		})
+	}
+}
+
+func TestCreateWithSamplerOptInvalidRule(t *testing.T) {
+	_, err := createWithSamplerOpt(&configuration.TracingConfiguration{SamplingRules: []string{"/status"}})
+	require.ErrorContains(t, err, "expected format pattern=ratio")
+
+	err = WithSamplingRules(SamplingRule{Pattern: "/status", Ratio: -1})(&conf{})
+	require.ErrorContains(t, err, "must be between 0 and 1")
+}
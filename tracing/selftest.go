@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/phanitejak/kptgolib/tracing/configuration"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SelfTestPath is the management server path of SelfTestHandler.
+const SelfTestPath = "/tracing/selftest"
+
+// SelfTestResult describes the outcome of SelfTest.
+type SelfTestResult struct {
+	ServiceName string `json:"serviceName"`
+	Endpoint    string `json:"endpoint"`
+	TraceID     string `json:"traceId,omitempty"`
+	// Sampled is false when configured sampler dropped the test span, e.g. const sampler with parameter 0.
+	Sampled bool `json:"sampled"`
+	// Exported is true when the exporter acknowledged the test span.
+	Exported bool   `json:"exported"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ValidateConfig validates tracing configuration read from environment without initializing a tracer.
+func ValidateConfig() (*configuration.TracingConfiguration, error) {
+	cfg, err := getTracingConfig("")
+	if err != nil {
+		return nil, err
+	}
+	if cfg.JaegerEndpoint != "" {
+		u, err := url.Parse(cfg.JaegerEndpoint)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid JAEGER_ENDPOINT: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return cfg, fmt.Errorf("invalid JAEGER_ENDPOINT %s: scheme must be http or https", cfg.JaegerEndpoint)
+		}
+	}
+	if err := validateMode(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid TRACING_MODE: %w", err)
+	}
+	if _, err := createWithSamplerOpt(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid sampler configuration: %w", err)
+	}
+	if _, err := parseOtelPropagators(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid OTEL_PROPAGATORS: %w", err)
+	}
+	return cfg, nil
+}
+
+// SelfTest validates tracing configuration, emits a test span with global tracer and flushes it
+// waiting for exporter acknowledgment until ctx is done. It can be used to verify collector
+// connectivity of a freshly deployed service. InitGlobalTracer must be called before.
+// Test span is started with a sampled remote parent, so that parent based samplers keep it.
+func SelfTest(ctx context.Context) SelfTestResult {
+	start := time.Now()
+	result := SelfTestResult{}
+	finish := func(err error) SelfTestResult {
+		result.Duration = time.Since(start).String()
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	cfg, err := ValidateConfig()
+	if cfg != nil {
+		result.ServiceName = cfg.ServiceName
+		result.Endpoint = cfg.JaegerEndpoint
+	}
+	if err != nil {
+		return finish(err)
+	}
+
+	flusher, ok := otel.GetTracerProvider().(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return finish(errors.New("global tracer provider cannot be flushed, InitGlobalTracer was not called"))
+	}
+
+	parent, err := sampledRemoteParent()
+	if err != nil {
+		return finish(err)
+	}
+	_, span := otel.Tracer("selftest").Start(trace.ContextWithRemoteSpanContext(ctx, parent), "tracing-self-test")
+	span.SetAttributes(attribute.Key("selftest").Bool(true))
+	result.TraceID = span.SpanContext().TraceID().String()
+	result.Sampled = span.SpanContext().IsSampled()
+	span.End()
+
+	if err := flusher.ForceFlush(ctx); err != nil {
+		return finish(fmt.Errorf("failed to export test span: %w", err))
+	}
+	if !result.Sampled {
+		return finish(errors.New("test span was not sampled"))
+	}
+	if result.Endpoint == "" {
+		return finish(errors.New("JAEGER_ENDPOINT is not set, spans are dropped"))
+	}
+	result.Exported = true
+	return finish(nil)
+}
+
+// SelfTestHandler runs SelfTest with timeout and writes its result as JSON.
+// Response status is 200 when test span was exported and 503 otherwise.
+// Non-positive timeout leaves waiting limited only by the request context.
+func SelfTestHandler(timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		result := SelfTest(ctx)
+
+		w.Header().Set("Content-Type", "application/json")
+		if !result.Exported {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	})
+}
+
+func sampledRemoteParent() (trace.SpanContext, error) {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	if _, err := rand.Read(traceID[:]); err != nil {
+		return trace.SpanContext{}, fmt.Errorf("failed to generate trace id: %w", err)
+	}
+	if _, err := rand.Read(spanID[:]); err != nil {
+		return trace.SpanContext{}, fmt.Errorf("failed to generate span id: %w", err)
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}), nil
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest(t *testing.T) {
+	var received atomic.Int32
+	status := http.StatusAccepted
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer collector.Close()
+
+	t.Setenv("JAEGER_ENDPOINT", collector.URL+"/api/traces")
+	t.Setenv("JAEGER_SERVICE_NAME", "self-test")
+	closer, err := InitGlobalTracer()
+	require.NoError(t, err)
+	defer closer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result := SelfTest(ctx)
+	assert.Empty(t, result.Error)
+	assert.True(t, result.Sampled, "test span should be sampled with default sampler")
+	assert.True(t, result.Exported)
+	assert.Equal(t, "self-test", result.ServiceName)
+	assert.NotEmpty(t, result.TraceID)
+	assert.Equal(t, int32(1), received.Load())
+
+	status = http.StatusInternalServerError
+	w := httptest.NewRecorder()
+	SelfTestHandler(5*time.Second).ServeHTTP(w, httptest.NewRequest(http.MethodGet, SelfTestPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.False(t, result.Exported)
+	assert.Contains(t, result.Error, "failed to export test span")
+}
+
+func TestSelfTestWithoutEndpoint(t *testing.T) {
+	closer, err := InitGlobalTracer()
+	require.NoError(t, err)
+	defer closer.Close()
+
+	result := SelfTest(context.Background())
+	assert.False(t, result.Exported)
+	assert.Equal(t, "JAEGER_ENDPOINT is not set, spans are dropped", result.Error)
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"invalid endpoint scheme", "JAEGER_ENDPOINT", "collector:14268"},
+		{"invalid sampler param", "JAEGER_SAMPLER_PARAM", "2"},
+		{"invalid propagator", "OTEL_PROPAGATORS", "b3"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(tc.key, tc.value)
+			_, err := ValidateConfig()
+			assert.Error(t, err)
+			assert.NotEmpty(t, SelfTest(context.Background()).Error)
+		})
+	}
+
+	_, err := ValidateConfig()
+	assert.NoError(t, err)
+}
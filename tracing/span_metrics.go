@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// SpanMetricsMaxOperations limits the number of distinct span names exposed by span metrics.
+	// Spans with other names are counted in operation "other", e.g. when span names contain request ids.
+	SpanMetricsMaxOperations  = 500
+	spanMetricsOtherOperation = "other"
+)
+
+// WithSpanMetrics converts finished spans into rate, errors and duration metrics by span name, kind and
+// status, same as TRACING_SPAN_METRICS=true. Spans of traces which were not sampled are recorded, so that
+// metrics cover all operations and not only the sampled ones. Can be used as and opt for InitGlobalTracer.
+func WithSpanMetrics() func(*conf) error {
+	return func(c *conf) error {
+		c.spanMetrics = true
+		return nil
+	}
+}
+
+// spanMetricsProcessor observes duration of ended spans in a histogram, which count by status
+// gives the rate and errors of the operation.
+type spanMetricsProcessor struct {
+	duration *metrics.CustomHistogramVec
+
+	mu         sync.Mutex
+	operations map[string]bool
+}
+
+func newSpanMetricsProcessor() (*spanMetricsProcessor, error) {
+	duration, err := metrics.TryRegisterHistogramVec("span_duration_seconds", "tracing",
+		"Duration of finished spans in seconds by operation name, span kind and status.",
+		nil, []string{"operation", "kind", "status"}, metrics.ReuseExisting())
+	if err != nil {
+		return nil, err
+	}
+	return &spanMetricsProcessor{duration: duration, operations: map[string]bool{}}, nil
+}
+
+func (p *spanMetricsProcessor) OnStart(context.Context, tracesdk.ReadWriteSpan) {}
+
+func (p *spanMetricsProcessor) OnEnd(s tracesdk.ReadOnlySpan) {
+	status := "ok"
+	if s.Status().Code == codes.Error {
+		status = "error"
+	}
+	p.duration.GetCustomHistogram(p.operation(s.Name()), s.SpanKind().String(), status).
+		Observe(s.EndTime().Sub(s.StartTime()).Seconds())
+}
+
+// operation returns sanitized span name, or "other" when SpanMetricsMaxOperations is reached.
+func (p *spanMetricsProcessor) operation(name string) string {
+	name = metrics.SanitizeLabelValue(name)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.operations[name] {
+		if len(p.operations) >= SpanMetricsMaxOperations {
+			return spanMetricsOtherOperation
+		}
+		p.operations[name] = true
+	}
+	return name
+}
+
+func (p *spanMetricsProcessor) Shutdown(context.Context) error { return nil }
+
+func (p *spanMetricsProcessor) ForceFlush(context.Context) error { return nil }
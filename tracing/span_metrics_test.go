@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/phanitejak/kptgolib/tracing/configuration"
+)
+
+const spanDurationMetric = "com_metrics_tracing_span_duration_seconds"
+
+func TestSpanMetrics(t *testing.T) {
+	processor, err := newSpanMetricsProcessor()
+	require.NoError(t, err)
+	cfg := &configuration.TracingConfiguration{JaegerSamplerType: legacyConstantSampler, JaegerSamplerParam: "0", SpanMetrics: true}
+	withSampler, err := createWithSamplerOpt(cfg)
+	require.NoError(t, err)
+	tp := tracesdk.NewTracerProvider(withSampler, tracesdk.WithSpanProcessor(processor))
+	tracer := tp.Tracer("test")
+
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "span-metrics-test", trace.WithSpanKind(trace.SpanKindServer))
+		assert.False(t, span.SpanContext().IsSampled())
+		if i == 0 {
+			span.SetStatus(codes.Error, "failed")
+		}
+		span.End()
+	}
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	assert.Equal(t, uint64(2), spanCount(t, "span-metrics-test", "server", "ok"))
+	assert.Equal(t, uint64(1), spanCount(t, "span-metrics-test", "server", "error"))
+
+	again, err := newSpanMetricsProcessor()
+	require.NoError(t, err, "histogram should be reused when processor is created again")
+	assert.Same(t, processor.duration.GetCollector(), again.duration.GetCollector())
+}
+
+func TestSpanMetricsMaxOperations(t *testing.T) {
+	processor, err := newSpanMetricsProcessor()
+	require.NoError(t, err)
+	for i := 0; i < SpanMetricsMaxOperations; i++ {
+		assert.Equal(t, fmt.Sprint(i), processor.operation(fmt.Sprint(i)))
+	}
+	assert.Equal(t, "0", processor.operation("0"))
+	assert.Equal(t, spanMetricsOtherOperation, processor.operation("new"))
+}
+
+func spanCount(t *testing.T, operation, kind, status string) uint64 {
+	gathered, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	families := metrics.MetricFamilies{}
+	for _, family := range gathered {
+		families[family.GetName()] = family
+	}
+	require.Contains(t, families, spanDurationMetric)
+	for _, m := range families[spanDurationMetric].GetMetric() {
+		labels := map[string]string{}
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if labels["operation"] == operation && labels["kind"] == kind && labels["status"] == status {
+			return m.GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
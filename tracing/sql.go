@@ -0,0 +1,314 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Helper attributes for tagging database spans.
+var (
+	DBSystem       = semconv.DBSystemKey
+	DBStatement    = semconv.DBStatementKey
+	DBRowsAffected = attribute.Key("db.rows_affected")
+	DBRowsReturned = attribute.Key("db.rows_returned")
+)
+
+// Names of spans created by traced database driver.
+const (
+	SQLExecSpanName     = "sql.exec"
+	SQLQuerySpanName    = "sql.query"
+	SQLPrepareSpanName  = "sql.prepare"
+	SQLBeginSpanName    = "sql.begin"
+	SQLCommitSpanName   = "sql.commit"
+	SQLRollbackSpanName = "sql.rollback"
+)
+
+const tracedDriverSuffix = "-traced"
+
+var (
+	wrapDBMutex sync.Mutex
+	// sqlLiterals matches quoted string literals and numbers which are not part of identifiers or placeholders.
+	sqlLiterals = regexp.MustCompile(`'(?:[^']|'')*'|(^|[^\w$.])-?\d+(?:\.\d+)?`)
+)
+
+// WrapDB registers traced version of the database driver registered with given name
+// and returns name of the traced driver to be used with sql.Open:
+//
+//	name, err := tracing.WrapDB("postgres")
+//	db, err := sql.Open(name, dsn)
+//
+// Calling WrapDB again for the same driver returns the already registered name.
+func WrapDB(driverName string) (string, error) {
+	wrapDBMutex.Lock()
+	defer wrapDBMutex.Unlock()
+
+	tracedName := driverName + tracedDriverSuffix
+	for _, name := range sql.Drivers() {
+		if name == tracedName {
+			return tracedName, nil
+		}
+	}
+
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get driver %s: %w", driverName, err)
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	sql.Register(tracedName, WrapDriver(d, driverName))
+	return tracedName, nil
+}
+
+// WrapDriver returns driver which creates client spans for statements executed via given driver.
+// Spans contain sanitized statement, number of affected or returned rows and given database system name.
+func WrapDriver(d driver.Driver, system string) driver.Driver {
+	return tracedDriver{Driver: d, system: system}
+}
+
+// SanitizeQuery replaces string and numeric literals in SQL statement with '?',
+// so statements can be added to spans without leaking data.
+func SanitizeQuery(query string) string {
+	return sqlLiterals.ReplaceAllString(query, "${1}?")
+}
+
+type tracedDriver struct {
+	driver.Driver
+	system string
+}
+
+func (d tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, system: d.system}, nil
+}
+
+type tracedConn struct {
+	driver.Conn
+	system string
+}
+
+func (c *tracedConn) startSpan(ctx context.Context, operationName, query string) (Span, context.Context) {
+	span, ctx := StartSpanFromContext(ctx, operationName, trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(DBSystem.String(c.system))
+	if query != "" {
+		span.SetAttributes(DBStatement.String(SanitizeQuery(query)))
+	}
+	return span, ctx
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	span, ctx := c.startSpan(ctx, SQLPrepareSpanName, query)
+	defer func() { finishSQLSpan(span, err) }()
+
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	span, spanCtx := c.startSpan(ctx, SQLBeginSpanName, "")
+	defer func() { finishSQLSpan(span, err) }()
+
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(spanCtx, opts)
+	} else {
+		tx, err = c.Conn.Begin() // nolint: staticcheck
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, conn: c, ctx: ctx}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span, ctx := c.startSpan(ctx, SQLExecSpanName, query)
+	res, err := execer.ExecContext(ctx, query, args)
+	return finishExecSpan(span, res, err)
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span, ctx := c.startSpan(ctx, SQLQuerySpanName, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	return finishQuerySpan(span, rows, err)
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	conn  *tracedConn
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (res driver.Result, err error) {
+	span, ctx := s.conn.startSpan(ctx, SQLExecSpanName, s.query)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(namedValuesToValues(args)) // nolint: staticcheck
+	}
+	return finishExecSpan(span, res, err)
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	span, ctx := s.conn.startSpan(ctx, SQLQuerySpanName, s.query)
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValuesToValues(args)) // nolint: staticcheck
+	}
+	return finishQuerySpan(span, rows, err)
+}
+
+func (s *tracedStmt) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return s.conn.CheckNamedValue(v)
+}
+
+type tracedTx struct {
+	driver.Tx
+	conn *tracedConn
+	ctx  context.Context
+}
+
+func (t *tracedTx) Commit() (err error) {
+	span, _ := t.conn.startSpan(t.ctx, SQLCommitSpanName, "")
+	defer func() { finishSQLSpan(span, err) }()
+	return t.Tx.Commit()
+}
+
+func (t *tracedTx) Rollback() (err error) {
+	span, _ := t.conn.startSpan(t.ctx, SQLRollbackSpanName, "")
+	defer func() { finishSQLSpan(span, err) }()
+	return t.Tx.Rollback()
+}
+
+// tracedRows counts returned rows and finishes the query span when rows are closed.
+type tracedRows struct {
+	driver.Rows
+	span  Span
+	count int64
+	err   error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch err {
+	case nil:
+		r.count++
+	case io.EOF:
+	default:
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.err == nil {
+		r.err = err
+	}
+	r.span.SetAttributes(DBRowsReturned.Int64(r.count))
+	finishSQLSpan(r.span, r.err)
+	return err
+}
+
+func finishExecSpan(span Span, res driver.Result, err error) (driver.Result, error) {
+	if err == nil {
+		if affected, rowsErr := res.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(DBRowsAffected.Int64(affected))
+		}
+	}
+	finishSQLSpan(span, err)
+	return res, err
+}
+
+func finishQuerySpan(span Span, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		finishSQLSpan(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func finishSQLSpan(span Span, err error) {
+	// driver.ErrSkip makes database/sql retry the call via prepared statement, which creates its own span.
+	if err != nil && err != driver.ErrSkip {
+		span.LogFields(Error(err))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,163 @@
+package tracing_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/phanitejak/kptgolib/tracing/tracingtest"
+)
+
+var errFakeQuery = errors.New("fake query failed")
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if query == "FAIL" {
+		return nil, errFakeQuery
+	}
+	return driver.RowsAffected(2), nil
+}
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return &fakeRows{n: 3}, nil }
+
+type fakeRows struct{ n int }
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	r.n--
+	dest[0] = int64(r.n)
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func openTracedDB(t *testing.T) *sql.DB {
+	name, err := tracing.WrapDB("fakedb")
+	require.NoError(t, err)
+	assert.Equal(t, "fakedb-traced", name)
+
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func spanAttribute(t *testing.T, mockPros *tracingtest.MockProcessor, spanName string, key attribute.Key) attribute.Value {
+	attrs, err := mockPros.GetAttributes(spanName)
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+	for _, v := range attrs {
+		value, ok := tracingtest.KeyValueToMap(v)[key]
+		require.True(t, ok, "attribute %s not found", key)
+		return value
+	}
+	return attribute.Value{}
+}
+
+func TestWrapDBExec(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+	db := openTracedDB(t)
+
+	span, ctx := tracing.StartSpanFromContext(context.Background(), "parent")
+	_, err := db.ExecContext(ctx, "UPDATE users SET name = 'secret' WHERE id = 42")
+	require.NoError(t, err)
+	span.End()
+
+	statement, ok := mockPros.FindAttribute(tracing.SQLExecSpanName, string(tracing.DBStatement))
+	require.True(t, ok)
+	assert.Equal(t, "UPDATE users SET name = ? WHERE id = ?", statement)
+
+	system, _ := mockPros.FindAttribute(tracing.SQLExecSpanName, string(tracing.DBSystem))
+	assert.Equal(t, "fakedb", system)
+	assert.Equal(t, int64(2), spanAttribute(t, mockPros, tracing.SQLExecSpanName, tracing.DBRowsAffected).AsInt64())
+}
+
+func TestWrapDBExecError(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+	db := openTracedDB(t)
+
+	_, err := db.Exec("FAIL")
+	require.ErrorIs(t, err, errFakeQuery)
+
+	errorObject, ok := mockPros.FindEventAttributeValue(tracing.SQLExecSpanName, "error.object")
+	require.True(t, ok)
+	assert.Equal(t, errFakeQuery.Error(), errorObject)
+}
+
+func TestWrapDBQueryCountsRows(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+	db := openTracedDB(t)
+
+	rows, err := db.Query("SELECT id FROM users WHERE age > $1", 18)
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Close())
+
+	assert.True(t, mockPros.SpanNameExist(tracing.SQLPrepareSpanName))
+	statement, _ := mockPros.FindAttribute(tracing.SQLQuerySpanName, string(tracing.DBStatement))
+	assert.Equal(t, "SELECT id FROM users WHERE age > $1", statement)
+	assert.Equal(t, int64(3), spanAttribute(t, mockPros, tracing.SQLQuerySpanName, tracing.DBRowsReturned).AsInt64())
+}
+
+func TestWrapDBTransaction(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+	db := openTracedDB(t)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	assert.True(t, mockPros.SpanNameExist(tracing.SQLBeginSpanName))
+	assert.True(t, mockPros.SpanNameExist(tracing.SQLCommitSpanName))
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM t WHERE a = 'x' AND b = 10":        "SELECT * FROM t WHERE a = ? AND b = ?",
+		"SELECT * FROM t1 WHERE a = $1 AND b = -3.5":      "SELECT * FROM t1 WHERE a = $1 AND b = ?",
+		"INSERT INTO t VALUES ('it''s', 7)":               "INSERT INTO t VALUES (?, ?)",
+		"SELECT schema1.col2 FROM schema1.table3 LIMIT 5": "SELECT schema1.col2 FROM schema1.table3 LIMIT ?",
+	}
+	for query, expected := range tests {
+		assert.Equal(t, expected, tracing.SanitizeQuery(query))
+	}
+}
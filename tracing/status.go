@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// gRPC status codes, which mark server spans as failed by default.
+const (
+	grpcUnknown          = 2
+	grpcDeadlineExceeded = 4
+	grpcUnimplemented    = 12
+	grpcInternal         = 13
+	grpcUnavailable      = 14
+	grpcDataLoss         = 15
+)
+
+var grpcCodeNames = []string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound", "AlreadyExists",
+	"PermissionDenied", "ResourceExhausted", "FailedPrecondition", "Aborted", "OutOfRange", "Unimplemented",
+	"Internal", "Unavailable", "DataLoss", "Unauthenticated",
+}
+
+// StatusPolicy maps HTTP status codes and gRPC codes to span status, so that services share the same conventions
+// of failed spans. Zero value follows OpenTelemetry semantic conventions, see DefaultHTTPError and DefaultGRPCError.
+type StatusPolicy struct {
+	// HTTPError reports whether HTTP status code marks span as failed, defaults to DefaultHTTPError.
+	HTTPError func(code int) bool
+	// GRPCError reports whether gRPC code marks span of server or client as failed, defaults to DefaultGRPCError.
+	GRPCError func(code uint32, server bool) bool
+}
+
+// DefaultStatusPolicy is used by SetHTTPStatus, SetGRPCStatus, EndRPCSpan and WrapWithStatus.
+var DefaultStatusPolicy = StatusPolicy{}
+
+// DefaultHTTPError reports 5xx status codes as errors. 4xx are failures of the client, not the service.
+func DefaultHTTPError(code int) bool {
+	return code >= http.StatusInternalServerError
+}
+
+// DefaultGRPCError reports all codes other than OK as errors of clients, but only Unknown, DeadlineExceeded,
+// Unimplemented, Internal, Unavailable and DataLoss as errors of servers.
+func DefaultGRPCError(code uint32, server bool) bool {
+	if !server {
+		return code != 0
+	}
+	switch code {
+	case grpcUnknown, grpcDeadlineExceeded, grpcUnimplemented, grpcInternal, grpcUnavailable, grpcDataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetHTTPStatus sets http.status_code attribute and span status according to DefaultStatusPolicy.
+func SetHTTPStatus(span trace.Span, code int) {
+	DefaultStatusPolicy.SetHTTPStatus(span, code)
+}
+
+// SetGRPCStatus sets rpc.grpc.status_code attribute and span status according to DefaultStatusPolicy.
+func SetGRPCStatus(span trace.Span, code uint32, server bool) {
+	DefaultStatusPolicy.SetGRPCStatus(span, code, server)
+}
+
+// WrapWithStatus works as Wrap, but sets status of server spans according to DefaultStatusPolicy.
+func WrapWithStatus(handler http.Handler) http.Handler {
+	return DefaultStatusPolicy.Wrap(handler)
+}
+
+// SetHTTPStatus sets http.status_code attribute and marks span as failed if the policy reports code as error.
+// 5xx status codes, which are not errors by the policy, set status Ok, so that instrumentation applying default
+// conventions, e.g. otelhttp, doesn't mark the span as failed afterwards.
+func (p StatusPolicy) SetHTTPStatus(span trace.Span, code int) {
+	span.SetAttributes(HTTPStatusCode.Int(code))
+	switch {
+	case p.httpError(code):
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d %s", code, http.StatusText(code)))
+	case DefaultHTTPError(code):
+		span.SetStatus(codes.Ok, "")
+	}
+}
+
+// SetGRPCStatus sets rpc.grpc.status_code attribute and marks span as failed if the policy reports code as error.
+func (p StatusPolicy) SetGRPCStatus(span trace.Span, code uint32, server bool) {
+	span.SetAttributes(RPCGRPCStatusCode.Int64(int64(code)))
+	if p.grpcError(code, server) {
+		span.SetStatus(codes.Error, grpcCodeName(code))
+	}
+}
+
+// EndRPCSpan records gRPC status code and error of the RPC started with StartRPCServerSpan or StartRPCClientSpan,
+// sets span status according to the policy and ends the span.
+func (p StatusPolicy) EndRPCSpan(span Span, statusCode uint32, err error) {
+	server := false
+	if s, ok := span.(*rpcSpan); ok {
+		server = s.server
+	}
+	span.SetAttributes(RPCGRPCStatusCode.Int64(int64(statusCode)))
+	if err != nil {
+		span.RecordError(err)
+	}
+	if p.grpcError(statusCode, server) {
+		description := grpcCodeName(statusCode)
+		if err != nil {
+			description = err.Error()
+		}
+		span.SetStatus(codes.Error, description)
+	}
+	span.End()
+}
+
+// Wrap works as Wrap function, but sets http.status_code attribute and status of server spans according to the policy.
+func (p StatusPolicy) Wrap(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		handler.ServeHTTP(sw, r)
+		p.SetHTTPStatus(trace.SpanFromContext(r.Context()), sw.code)
+	}), "", otelhttp.WithSpanNameFormatter(nameFormatter))
+}
+
+func (p StatusPolicy) httpError(code int) bool {
+	if p.HTTPError != nil {
+		return p.HTTPError(code)
+	}
+	return DefaultHTTPError(code)
+}
+
+func (p StatusPolicy) grpcError(code uint32, server bool) bool {
+	if p.GRPCError != nil {
+		return p.GRPCError(code, server)
+	}
+	return DefaultGRPCError(code, server)
+}
+
+func grpcCodeName(code uint32) string {
+	if int(code) < len(grpcCodeNames) {
+		return grpcCodeNames[code]
+	}
+	return fmt.Sprintf("Code(%d)", code)
+}
+
+// statusWriter records status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,136 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/phanitejak/kptgolib/tracing/tracingtest"
+)
+
+func recordedSpan(t *testing.T, name string) tracesdk.ReadOnlySpan {
+	spans := tracingtest.RecordedSpansByName(t, name)
+	require.Len(t, spans, 1)
+	return spans[0]
+}
+
+func spanAttributeOf(span tracesdk.ReadOnlySpan, key string) (interface{}, bool) {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value.AsInterface(), true
+		}
+	}
+	return nil, false
+}
+
+func TestSetHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy tracing.StatusPolicy
+		code   int
+		want   codes.Code
+	}{
+		{name: "OK", code: http.StatusOK, want: codes.Unset},
+		{name: "NotFound", code: http.StatusNotFound, want: codes.Unset},
+		{name: "InternalServerError", code: http.StatusInternalServerError, want: codes.Error},
+		{
+			name:   "TooManyRequestsByPolicy",
+			policy: tracing.StatusPolicy{HTTPError: func(code int) bool { return code == http.StatusTooManyRequests || code >= 500 }},
+			code:   http.StatusTooManyRequests,
+			want:   codes.Error,
+		},
+		{
+			name:   "ServiceUnavailableNotErrorByPolicy",
+			policy: tracing.StatusPolicy{HTTPError: func(code int) bool { return code >= 500 && code != http.StatusServiceUnavailable }},
+			code:   http.StatusServiceUnavailable,
+			want:   codes.Ok,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tracingtest.SetUpRecording(t)()
+
+			span, _ := tracing.StartSpanFromContext(context.Background(), tt.name)
+			tt.policy.SetHTTPStatus(span, tt.code)
+			span.End()
+
+			recorded := recordedSpan(t, tt.name)
+			assert.Equal(t, tt.want, recorded.Status().Code)
+			code, ok := spanAttributeOf(recorded, string(tracing.HTTPStatusCode))
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.code, code)
+		})
+	}
+}
+
+func TestWrapWithStatus(t *testing.T) {
+	defer tracingtest.SetUpRecording(t)()
+
+	policy := tracing.StatusPolicy{HTTPError: func(code int) bool { return code >= 400 }}
+	handler := policy.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/resource", nil))
+
+	recorded := recordedSpan(t, "PUT /resource")
+	assert.Equal(t, codes.Error, recorded.Status().Code)
+	assert.Equal(t, "HTTP 409 Conflict", recorded.Status().Description)
+}
+
+func TestEndRPCSpanMapsGRPCCodes(t *testing.T) {
+	const notFound, unavailable = 5, 14
+	tests := []struct {
+		name   string
+		server bool
+		code   uint32
+		want   codes.Code
+	}{
+		{name: "ServerNotFound", server: true, code: notFound, want: codes.Unset},
+		{name: "ServerUnavailable", server: true, code: unavailable, want: codes.Error},
+		{name: "ClientNotFound", code: notFound, want: codes.Error},
+		{name: "ClientOK", code: 0, want: codes.Unset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tracingtest.SetUpRecording(t)()
+
+			start := tracing.StartRPCClientSpan
+			if tt.server {
+				start = tracing.StartRPCServerSpan
+			}
+			span, _ := start(context.Background(), "/test.Echo/"+tt.name, nil)
+			var err error
+			if tt.code != 0 {
+				err = errors.New("failure")
+			}
+			tracing.EndRPCSpan(span, tt.code, err)
+
+			recorded := recordedSpan(t, "test.Echo/"+tt.name)
+			assert.Equal(t, tt.want, recorded.Status().Code)
+			code, _ := spanAttributeOf(recorded, string(tracing.RPCGRPCStatusCode))
+			assert.EqualValues(t, tt.code, code)
+		})
+	}
+}
+
+func TestSetGRPCStatus(t *testing.T) {
+	defer tracingtest.SetUpRecording(t)()
+
+	span, _ := tracing.StartSpanFromContext(context.Background(), "grpc")
+	tracing.SetGRPCStatus(span, 13, true)
+	span.End()
+
+	recorded := recordedSpan(t, "grpc")
+	assert.Equal(t, codes.Error, recorded.Status().Code)
+	assert.Equal(t, "Internal", recorded.Status().Description)
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Helper attributes for tagging task spans.
+var (
+	TaskPanicked      = attribute.Key("task.panicked")
+	TaskQueueDuration = attribute.Key("task.queue_duration_ms")
+)
+
+// ErrWorkerPoolClosed is returned when submitting tasks to closed WorkerPool.
+var ErrWorkerPoolClosed = errors.New("worker pool is closed")
+
+// Go runs fn in a new goroutine within a child span of ctx named name, so that the trace continues across
+// the goroutine boundary. Span is finished even if fn panics: the panic is recovered, recorded in the span
+// and returned as error. Returned channel receives the result of fn and is closed afterwards.
+//
+// Context cancellation is propagated to fn as well, use context.WithoutCancel(ctx) for background work
+// which should outlive the request.
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		defer close(result)
+		result <- runTask(ctx, name, fn, attribute.KeyValue{})
+	}()
+	return result
+}
+
+// WorkerPool runs submitted tasks in a fixed number of goroutines. Each task runs within a child span of
+// the context it was submitted with, no matter how long it was queued.
+type WorkerPool struct {
+	tasks  chan poolTask
+	lock   sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type poolTask struct {
+	ctx      context.Context
+	name     string
+	fn       func(ctx context.Context) error
+	queuedAt time.Time
+}
+
+// NewWorkerPool starts workers goroutines running tasks queued in a queue of given size.
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &WorkerPool{tasks: make(chan poolTask, queueSize)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues fn to be run within a child span of ctx named name. It blocks while the queue is full
+// and returns ctx.Err() if ctx is done meanwhile. Errors and panics of fn are recorded in its span.
+func (p *WorkerPool) Submit(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrWorkerPoolClosed
+	}
+
+	select {
+	case p.tasks <- poolTask{ctx: ctx, name: name, fn: fn, queuedAt: time.Now()}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits until the queued tasks are finished.
+func (p *WorkerPool) Close() error {
+	p.lock.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.lock.Unlock()
+
+	p.wg.Wait()
+	return nil
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		queued := TaskQueueDuration.Int64(time.Since(task.queuedAt).Milliseconds())
+		_ = runTask(task.ctx, task.name, task.fn, queued)
+	}
+}
+
+func runTask(ctx context.Context, name string, fn func(ctx context.Context) error, attr attribute.KeyValue) (err error) {
+	span, ctx := StartSpanFromContext(ctx, name)
+	if attr.Valid() {
+		span.SetAttributes(attr)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("task %s panicked: %v", name, p)
+			span.SetAttributes(TaskPanicked.Bool(true))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	return fn(ctx)
+}
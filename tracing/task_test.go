@@ -0,0 +1,105 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/phanitejak/kptgolib/tracing/tracingtest"
+)
+
+func TestGoContinuesTraceInGoroutine(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), "parent")
+	defer parent.Finish()
+
+	var childCtx context.Context
+	err := <-tracing.Go(ctx, "task", func(ctx context.Context) error {
+		childCtx = ctx
+		return nil
+	})
+	require.NoError(t, err)
+
+	child := tracing.SpanFromContext(childCtx).SpanContext()
+	assert.Equal(t, parent.SpanContext().TraceID(), child.TraceID())
+	assert.NotEqual(t, parent.SpanContext().SpanID(), child.SpanID())
+	assert.Equal(t, 1, mockPros.GetSpanAmount("task"))
+}
+
+func TestGoRecordsErrorsAndPanics(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+
+	err := <-tracing.Go(context.Background(), "failing", func(ctx context.Context) error {
+		return errors.New("failure")
+	})
+	require.EqualError(t, err, "failure")
+	_, ok := mockPros.FindEventAttribute("failing", "exception.message")
+	assert.True(t, ok)
+
+	err = <-tracing.Go(context.Background(), "panicking", func(ctx context.Context) error {
+		panic("boom")
+	})
+	require.EqualError(t, err, "task panicking panicked: boom")
+	assert.Equal(t, 1, mockPros.GetSpanAmount("panicking"))
+	assert.True(t, spanAttribute(t, mockPros, "panicking", tracing.TaskPanicked).AsBool())
+}
+
+func TestWorkerPoolPreservesSubmittingContext(t *testing.T) {
+	cleanUp, mockPros := tracingtest.SetUpWithMockProcessor(t)
+	defer cleanUp()
+
+	pool := tracing.NewWorkerPool(2, 10)
+	parent, ctx := tracing.StartSpanFromContext(context.Background(), "request")
+
+	var (
+		mu       sync.Mutex
+		traceIDs []string
+	)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, pool.Submit(ctx, "queued", func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			traceIDs = append(traceIDs, tracing.SpanFromContext(ctx).SpanContext().TraceID().String())
+			return nil
+		}))
+	}
+	require.NoError(t, pool.Submit(ctx, "queued", func(ctx context.Context) error {
+		panic("boom")
+	}))
+	parent.Finish()
+	require.NoError(t, pool.Close())
+
+	require.Len(t, traceIDs, 5)
+	for _, id := range traceIDs {
+		assert.Equal(t, parent.SpanContext().TraceID().String(), id)
+	}
+	assert.Equal(t, 6, mockPros.GetSpanAmount("queued"))
+	_, ok := mockPros.FindAttribute("queued", string(tracing.TaskQueueDuration))
+	assert.True(t, ok)
+
+	assert.ErrorIs(t, pool.Submit(ctx, "late", func(ctx context.Context) error { return nil }), tracing.ErrWorkerPoolClosed)
+}
+
+func TestWorkerPoolSubmitReturnsWhenContextIsDone(t *testing.T) {
+	pool := tracing.NewWorkerPool(1, 0)
+	release := make(chan struct{})
+	require.NoError(t, pool.Submit(context.Background(), "blocking", func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, pool.Submit(ctx, "rejected", func(ctx context.Context) error { return nil }), context.Canceled)
+
+	close(release)
+	require.NoError(t, pool.Close())
+}
@@ -21,9 +21,13 @@ import (
 )
 
 type conf struct {
-	logger   logging.Logger
-	loggerV2 loggingv2.Logger
-	opts     []tracerProviderOpt
+	logger        logging.Logger
+	loggerV2      loggingv2.Logger
+	opts          []tracerProviderOpt
+	serviceName   string
+	samplingRules []SamplingRule
+	sampleErrors  bool
+	spanMetrics   bool
 }
 
 const (
@@ -58,7 +62,20 @@ func InitGlobalTracer(options ...func(*conf) error) (closer io.Closer, err error
 func setTracer(c *conf) (closer io.Closer, err error) {
 	setLogger(c)
 
-	opts, propagators, err := buildTracerProviderOptsAndPropagators()
+	cfg, err := getTracingConfig(c.serviceName)
+	if err != nil {
+		return nil, err
+	}
+	modeCloser, handled, err := setTracerForMode(c, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if handled {
+		return modeCloser, nil
+	}
+	cfg.SampleErrors = cfg.SampleErrors || c.sampleErrors
+	cfg.SpanMetrics = cfg.SpanMetrics || c.spanMetrics
+	opts, propagators, err := tracerProviderOptsAndPropagators(cfg, c.samplingRules...)
 	if err != nil {
 		return nil, err
 	}
@@ -76,28 +93,46 @@ func setTracer(c *conf) (closer io.Closer, err error) {
 
 type contextCloser func(ctx context.Context) error
 
+// Close flushes remaining spans and shuts down the tracer provider, without a deadline.
 func (c contextCloser) Close() error {
 	return c(context.Background())
 }
 
+// Shutdown flushes remaining spans and shuts down the tracer provider until ctx is done.
+func (c contextCloser) Shutdown(ctx context.Context) error {
+	return c(ctx)
+}
+
 func buildTracerProviderOptsAndPropagators() (opts []tracesdk.TracerProviderOption, propagators []propagation.TextMapPropagator, err error) {
-	cfg, err := getTracingConfig()
+	cfg, err := getTracingConfig("")
 	if err != nil {
 		return
 	}
+	return tracerProviderOptsAndPropagators(cfg)
+}
+
+func tracerProviderOptsAndPropagators(cfg *configuration.TracingConfiguration, rules ...SamplingRule) (opts []tracesdk.TracerProviderOption, propagators []propagation.TextMapPropagator, err error) {
 	withExporter, err := createWithBatcherExporterOpt(cfg)
 	if err != nil {
 		return opts, propagators, fmt.Errorf("failed creating tracing exporter: %w", err)
 	}
 	opts = append(opts, withExporter)
 
+	if cfg.SpanMetrics {
+		processor, err := newSpanMetricsProcessor()
+		if err != nil {
+			return opts, propagators, fmt.Errorf("failed creating span metrics: %w", err)
+		}
+		opts = append(opts, tracesdk.WithSpanProcessor(processor))
+	}
+
 	withResource, err := createWithResourceOpt(cfg)
 	if err != nil {
 		return opts, propagators, fmt.Errorf("failed creating tracing reosurce: %w", err)
 	}
 	opts = append(opts, withResource)
 
-	withSampler, err := createWithSamplerOpt(cfg)
+	withSampler, err := createWithSamplerOpt(cfg, rules...)
 	if err != nil {
 		return opts, propagators, fmt.Errorf("failed creating tracing sampler: %w", err)
 	}
@@ -126,7 +161,33 @@ func createWithResourceOpt(cfg *configuration.TracingConfiguration) (tracesdk.Tr
 	return tracesdk.WithResource(r), nil
 }
 
-func createWithSamplerOpt(cfg *configuration.TracingConfiguration) (tracesdk.TracerProviderOption, error) {
+// createWithSamplerOpt creates sampler from configuration. Sampling rules from configuration and given rules
+// are applied to root spans before the configured sampler. Spans which are not sampled are recorded
+// when error sampling or span metrics are enabled.
+func createWithSamplerOpt(cfg *configuration.TracingConfiguration, rules ...SamplingRule) (tracesdk.TracerProviderOption, error) {
+	sampler, err := createSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	envRules, err := parseSamplingRules(cfg.SamplingRules)
+	if err != nil {
+		return nil, err
+	}
+	rules = append(envRules, rules...)
+	recordUnsampled := cfg.SampleErrors || cfg.SpanMetrics
+	if len(rules) == 0 && !recordUnsampled {
+		if sampler == nil {
+			return nil, nil // Use default OpenTelemetry sampler creation.
+		}
+		return tracesdk.WithSampler(sampler), nil
+	}
+	if sampler == nil {
+		sampler = tracesdk.ParentBased(tracesdk.AlwaysSample())
+	}
+	return tracesdk.WithSampler(newRuleSampler(sampler, recordUnsampled, rules...)), nil
+}
+
+func createSampler(cfg *configuration.TracingConfiguration) (tracesdk.Sampler, error) {
 	switch cfg.JaegerSamplerType {
 	case legacyConstantSampler:
 		enabled, err := parseConstantSamplerArg(cfg.JaegerSamplerParam)
@@ -134,32 +195,43 @@ func createWithSamplerOpt(cfg *configuration.TracingConfiguration) (tracesdk.Tra
 			return nil, err
 		}
 		if enabled {
-			return tracesdk.WithSampler(tracesdk.AlwaysSample()), nil
+			return tracesdk.AlwaysSample(), nil
 		}
-		return tracesdk.WithSampler(tracesdk.NeverSample()), nil
+		return tracesdk.NeverSample(), nil
 	case legacyProbabilisticSampler:
 		ratio, err := parseTraceIDRatio(cfg.JaegerSamplerParam, cfg.JaegerSamplerParam != "")
 		if err != nil {
 			return nil, err
 		}
-		return tracesdk.WithSampler(tracesdk.ParentBased(ratio)), nil
+		return tracesdk.ParentBased(ratio), nil
 	default:
-		return nil, nil // Use default OpenTelemetry sampler creation.
+		return nil, nil
 	}
 }
 
 func createWithBatcherExporterOpt(cfg *configuration.TracingConfiguration) (tracesdk.TracerProviderOption, error) {
+	processor, err := createSpanProcessor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SampleErrors {
+		processor = errorSamplingProcessor{processor}
+	}
+	return tracesdk.WithSpanProcessor(processor), nil
+}
+
+func createSpanProcessor(cfg *configuration.TracingConfiguration) (tracesdk.SpanProcessor, error) {
 	if cfg.JaegerEndpoint == "" {
-		return tracesdk.WithBatcher(newNoopExporter()), nil
+		return tracesdk.NewBatchSpanProcessor(newNoopExporter()), nil
 	}
 	exp, err := exporter.New(exporter.WithCollectorEndpoint(exporter.WithEndpoint(cfg.JaegerEndpoint)))
 	if err != nil {
 		return nil, err
 	}
 	if cfg.UseSimpleSpanProcessor {
-		return tracesdk.WithSpanProcessor(tracesdk.NewSimpleSpanProcessor(exp)), nil
+		return tracesdk.NewSimpleSpanProcessor(exp), nil
 	}
-	return tracesdk.WithBatcher(exp), nil
+	return tracesdk.NewBatchSpanProcessor(exp), nil
 }
 
 // parseOtelPropagators parses the propagators for tracing from env variables
@@ -200,6 +272,14 @@ func WithV2Logger(logger loggingv2.Logger) func(*conf) error {
 	}
 }
 
+// WithServiceName sets service name used when JAEGER_SERVICE_NAME is not set. Can be used as and opt for InitGlobalTracer.
+func WithServiceName(serviceName string) func(*conf) error {
+	return func(c *conf) (err error) {
+		c.serviceName = serviceName
+		return
+	}
+}
+
 // WithProcessor is wrapping configuration with SpanProcessor. Used for initializing processor mocks. Can be used as and opt for InitGlobalTracer.
 func WithProcessor(pros SpanProcessor) func(*conf) error {
 	return func(c *conf) (err error) {
@@ -208,12 +288,16 @@ func WithProcessor(pros SpanProcessor) func(*conf) error {
 	}
 }
 
-func getTracingConfig() (*configuration.TracingConfiguration, error) {
+func getTracingConfig(defaultServiceName string) (*configuration.TracingConfiguration, error) {
 	cfg, err := configuration.FromEnv()
 	if err != nil {
 		return nil, err
 	}
 
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+
 	// This is a workaround for a bug https://github.com/jaegertracing/jaeger-client-go/issues/350
 	// Now application won't fail if service name is not specified.
 	if cfg.ServiceName == "" {
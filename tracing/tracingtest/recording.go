@@ -0,0 +1,41 @@
+package tracingtest
+
+import (
+	"testing"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/stretchr/testify/require"
+)
+
+// SetUpRecording inits global tracer in record mode, same as running with TRACING_MODE=record.
+// CleanUp function closes global tracer.
+func SetUpRecording(t testing.TB) (cleanUp func()) {
+	t.Setenv("TRACING_MODE", tracing.ModeRecord)
+	closer, err := tracing.InitGlobalTracer()
+	require.NoError(t, err)
+
+	return func() {
+		_ = closer.Close()
+	}
+}
+
+// RecordedSpans returns spans finished so far by global tracer initialized in record mode.
+// Test fails if global tracer is not in record mode.
+func RecordedSpans(t testing.TB) []tracesdk.ReadOnlySpan {
+	spans := tracing.RecordedSpans()
+	require.NotNil(t, spans, "global tracer is not initialized with TRACING_MODE=record")
+	return spans
+}
+
+// RecordedSpansByName returns recorded spans with given name, see RecordedSpans.
+func RecordedSpansByName(t testing.TB, spanName string) []tracesdk.ReadOnlySpan {
+	var spans []tracesdk.ReadOnlySpan
+	for _, span := range RecordedSpans(t) {
+		if span.Name() == spanName {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
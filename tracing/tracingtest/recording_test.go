@@ -0,0 +1,31 @@
+// nolint
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/phanitejak/kptgolib/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecording(t *testing.T) {
+	cleanUp := SetUpRecording(t)
+	defer cleanUp()
+
+	span, ctx := tracing.StartSpanFromContext(context.Background(), "parent")
+	child, _ := tracing.StartSpanFromContext(ctx, "child")
+	child.Finish()
+	span.Finish()
+
+	spans := RecordedSpans(t)
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name())
+	children := RecordedSpansByName(t, "child")
+	require.Len(t, children, 1)
+	assert.Equal(t, spans[1].SpanContext().SpanID(), children[0].Parent().SpanID())
+
+	tracing.ResetRecordedSpans()
+	assert.Empty(t, RecordedSpans(t))
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/eapache/go-resiliency/breaker"
-	"github.com/hashicorp/go-retryablehttp"
 	"github.com/hashicorp/vault/api"
 	"github.com/phanitejak/kptgolib/logging"
 	"github.com/pkg/errors"
@@ -26,6 +25,12 @@ const (
 
 var log = logging.NewLogger()
 
+// SetLogger sets logger used by the package, e.g. v2 logger adapted with logging/v2.NewV1Logger.
+// Call before creating any clients.
+func SetLogger(l logging.Logger) {
+	log = l
+}
+
 type ConfigFn func(*config) error
 
 type Client interface {
@@ -44,6 +49,7 @@ type client struct {
 	initialized uint32
 	h           *vaultClientHolder
 	breaker     *breaker.Breaker
+	retrier     *retrier
 }
 
 type vaultClientHolder struct {
@@ -55,13 +61,21 @@ type config struct {
 	AuthPath, JwtPath, VaultAddress, Role string
 	Timeout                               time.Duration
 	Token                                 string
-	MaxRetries                            int
+	TokenFile                             *tokenFile
+	Retry                                 RetryPolicy
 	BreakerTimeout                        time.Duration
 	BreakerErrorTH                        int
 	BreakerSuccessTH                      int
+	Hooks                                 RequestHooks
 }
 
 func (c *client) List(path string) (secret *api.Secret, err error) {
+	done := c.config.Hooks.begin(OperationList, path)
+	defer func() {
+		err = newOperationError(OperationList, path, err)
+		done(err)
+	}()
+
 	err = c.connectIfNotInitialized()
 	if err != nil {
 		return nil, err
@@ -73,6 +87,12 @@ func (c *client) List(path string) (secret *api.Secret, err error) {
 }
 
 func (c *client) Read(path string) (secret *api.Secret, err error) {
+	done := c.config.Hooks.begin(OperationRead, path)
+	defer func() {
+		err = newOperationError(OperationRead, path, err)
+		done(err)
+	}()
+
 	err = c.connectIfNotInitialized()
 	if err != nil {
 		return nil, err
@@ -84,6 +104,12 @@ func (c *client) Read(path string) (secret *api.Secret, err error) {
 }
 
 func (c *client) Write(path string, data map[string]interface{}) (secret *api.Secret, err error) {
+	done := c.config.Hooks.begin(OperationWrite, path)
+	defer func() {
+		err = newOperationError(OperationWrite, path, err)
+		done(err)
+	}()
+
 	err = c.connectIfNotInitialized()
 	if err != nil {
 		return nil, err
@@ -95,6 +121,12 @@ func (c *client) Write(path string, data map[string]interface{}) (secret *api.Se
 }
 
 func (c *client) Delete(path string) (secret *api.Secret, err error) {
+	done := c.config.Hooks.begin(OperationDelete, path)
+	defer func() {
+		err = newOperationError(OperationDelete, path, err)
+		done(err)
+	}()
+
 	err = c.connectIfNotInitialized()
 	if err != nil {
 		return nil, err
@@ -105,8 +137,14 @@ func (c *client) Delete(path string) (secret *api.Secret, err error) {
 	})
 }
 
-func (c *client) Mount(path string, input *api.MountInput) error {
-	err := c.connectIfNotInitialized()
+func (c *client) Mount(path string, input *api.MountInput) (err error) {
+	done := c.config.Hooks.begin(OperationMount, path)
+	defer func() {
+		err = newOperationError(OperationMount, path, err)
+		done(err)
+	}()
+
+	err = c.connectIfNotInitialized()
 	if err != nil {
 		return err
 	}
@@ -116,8 +154,14 @@ func (c *client) Mount(path string, input *api.MountInput) error {
 	return err
 }
 
-func (c *client) Unmount(path string) error {
-	err := c.connectIfNotInitialized()
+func (c *client) Unmount(path string) (err error) {
+	done := c.config.Hooks.begin(OperationUnmount, path)
+	defer func() {
+		err = newOperationError(OperationUnmount, path, err)
+		done(err)
+	}()
+
+	err = c.connectIfNotInitialized()
 	if err != nil {
 		return err
 	}
@@ -127,12 +171,17 @@ func (c *client) Unmount(path string) error {
 	return err
 }
 
-func (c *client) ListMounts() (map[string]*api.MountOutput, error) {
-	err := c.connectIfNotInitialized()
+func (c *client) ListMounts() (mountList map[string]*api.MountOutput, err error) {
+	done := c.config.Hooks.begin(OperationListMounts, "")
+	defer func() {
+		err = newOperationError(OperationListMounts, "", err)
+		done(err)
+	}()
+
+	err = c.connectIfNotInitialized()
 	if err != nil {
 		return nil, err
 	}
-	var mountList map[string]*api.MountOutput
 
 	_, err = c.tryOperationWithBreaker(func() (secret *api.Secret, err error) {
 		mountList, err = c.h.get().Sys().ListMounts()
@@ -152,31 +201,27 @@ func (c *client) tryOperationWithBreaker(operation func() (secret *api.Secret, e
 	return secret, err
 }
 
+// tryOperation runs operation with the client retry policy. Attempts rejected with 401 or 403
+// are retried after logging in again, as the token may have expired or been revoked.
 func (c *client) tryOperation(operation func() (secret *api.Secret, err error)) (secret *api.Secret, err error) {
-	secret, err = operation()
-	if err == nil {
-		return
-	}
-	log.Debug("error performing request, retrying")
-
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	secret, err = operation()
-	if err == nil {
-		return
-	}
-	log.Debug("error performing request, reconnecting to vault server")
-
-	if err = c.connectToVaultServerWithBreaker(); err != nil {
-		return
-	}
-
-	return operation()
+	err = c.retrier.do(func() (e error) {
+		secret, e = operation()
+		return e
+	}, func(err error) error {
+		if !isAuthError(err) {
+			return nil
+		}
+		log.Debug("error performing request, reconnecting to vault server")
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return c.connectToVaultServerWithBreaker()
+	})
+	return secret, err
 }
 
 func (c *client) connectIfNotInitialized() (err error) {
 	if atomic.LoadUint32(&c.initialized) == 1 {
+		c.reloadTokenIfChanged()
 		return
 	}
 
@@ -204,15 +249,17 @@ func (c *client) connectToVaultServerWithBreaker() (err error) {
 func (c *client) connectToVaultServer() (err error) {
 	log.Debug("establishing connection to vault")
 
-	jwt, err := readServiceAccountToken(c.config.JwtPath)
-	if err != nil {
-		log.Errorf("error reading json web token under %s", c.config.JwtPath)
-		return
+	var jwt string
+	if c.config.TokenFile == nil {
+		jwt, err = readServiceAccountToken(c.config.JwtPath)
+		if err != nil {
+			log.Errorf("error reading json web token under %s", c.config.JwtPath)
+			return
+		}
 	}
 
 	config := defaultConfig(c.config.VaultAddress)
 	config.Timeout = c.config.Timeout
-	config.MaxRetries = c.config.MaxRetries
 
 	vaultClient, err := api.NewClient(config)
 	if err != nil {
@@ -222,7 +269,14 @@ func (c *client) connectToVaultServer() (err error) {
 	c.h.set(vaultClient)
 
 	token := c.config.Token
-	if c.config.Token == "" {
+	switch {
+	case c.config.TokenFile != nil:
+		token, err = c.config.TokenFile.read()
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	case c.config.Token == "":
 		authResponse, err := c.h.get().Logical().Write(c.config.AuthPath, createAuthData(jwt, c.config.Role))
 		if err != nil {
 			log.Errorf(errors.WithMessagef(err, "error authenticating to vault server. auth path: %s, role: %s", c.config.AuthPath, c.config.Role).Error())
@@ -276,10 +330,13 @@ func Timeout(timeout time.Duration) ConfigFn {
 	}
 }
 
-// MaxRetries in case of 5xx errors from Vault server.
+// MaxRetries in case of retryable errors from Vault server, see RetryPolicy.MaxAttempts.
 func MaxRetries(maxRetries int) ConfigFn {
 	return func(c *config) (err error) {
-		c.MaxRetries = maxRetries
+		if maxRetries < 0 {
+			return errors.New("max retries must not be negative")
+		}
+		c.Retry.MaxAttempts = maxRetries + 1
 		return
 	}
 }
@@ -308,15 +365,54 @@ func BreakerTimeout(bTimeout time.Duration) ConfigFn {
 	}
 }
 
+// NewClient returns client authenticating with Kubernetes service account token or options like TokenFile.
+// With VAULT_DEV_MODE=true in-memory client seeded from VAULT_DEV_SECRETS_FILE is returned instead,
+// so that services can be run locally without a Vault server, see NewInMemoryClientFromFile.
+// Only Hooks option is applied to the in-memory client, other options are ignored.
+//
 //nolint:golint
-func NewClient(vaultAddress, role string, options ...ConfigFn) (c *client, err error) {
+func NewClient(vaultAddress, role string, options ...ConfigFn) (Client, error) {
+	if c, ok, err := devModeClient(); ok {
+		if err != nil {
+			return nil, err
+		}
+		return withDevModeOptions(c, options...)
+	}
+
+	c, err := newClient(vaultAddress, role, options...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// withDevModeOptions wraps in-memory client with hooks set by options, so that audit and metrics hooks
+// are called also in dev mode.
+func withDevModeOptions(c *InMemoryClient, options ...ConfigFn) (Client, error) {
+	if len(options) == 0 {
+		return c, nil
+	}
+	log.Warnf("%s is set, vault client options other than Hooks are ignored", DevModeEnv)
+	var conf config
+	for _, option := range options {
+		if err := option(&conf); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Hooks.OnRequest == nil && conf.Hooks.OnResponse == nil {
+		return c, nil
+	}
+	return WithHooks(c, conf.Hooks), nil
+}
+
+func newClient(vaultAddress, role string, options ...ConfigFn) (c *client, err error) {
 	conf := config{
 		AuthPath:         defaultAuthPath,
 		JwtPath:          defaultServiceAccountTokenPath,
 		VaultAddress:     vaultAddress,
 		Role:             role,
 		Timeout:          defaultTimeout,
-		MaxRetries:       defaultMaxRetries,
+		Retry:            DefaultRetryPolicy(),
 		BreakerErrorTH:   defaultBreakerErrorTH,
 		BreakerSuccessTH: defaultBreakerSuccessTH,
 		BreakerTimeout:   defaultBreakerTimeout,
@@ -335,6 +431,7 @@ func NewClient(vaultAddress, role string, options ...ConfigFn) (c *client, err e
 		config:  &conf,
 		h:       newVaultClientHolder(),
 		breaker: b,
+		retrier: newRetrier(conf.Retry),
 	}
 
 	return
@@ -390,8 +487,7 @@ func defaultConfig(address string) *api.Config {
 	config := api.DefaultConfig()
 	config.Address = address
 	config.Timeout = defaultTimeout
-	config.Backoff = retryablehttp.DefaultBackoff
-	// exponential backoff instead of linear set in vault by default
-	config.MaxRetries = defaultMaxRetries
+	// retries are done by the client according to RetryPolicy
+	config.MaxRetries = 0
 	return config
 }
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DatabaseConnection is connection configuration of database secret engine, see ConfigureConnection.
+type DatabaseConnection struct {
+	// PluginName is the database plugin, e.g. "postgresql-database-plugin".
+	PluginName string `vault:"plugin_name"`
+	// ConnectionURL is the connection string with templated credentials,
+	// e.g. "postgresql://{{username}}:{{password}}@db:5432/app".
+	ConnectionURL string   `vault:"connection_url,omitempty"`
+	Username      string   `vault:"username,omitempty"`
+	Password      string   `vault:"password,omitempty"`
+	AllowedRoles  []string `vault:"allowed_roles,omitempty"`
+	// VerifyConnection disables verifying the connection when set to false, verified by default.
+	VerifyConnection       *bool    `vault:"verify_connection,omitempty"`
+	RootRotationStatements []string `vault:"root_rotation_statements,omitempty"`
+	PasswordPolicy         string   `vault:"password_policy,omitempty"`
+	// Options are additional plugin specific parameters, e.g. "max_open_connections".
+	Options map[string]interface{} `vault:"-"`
+}
+
+// DatabaseRole is a dynamic credentials role of database secret engine, see CreateRole.
+type DatabaseRole struct {
+	// DBName is the name of the connection configured with ConfigureConnection.
+	DBName               string        `vault:"db_name"`
+	CreationStatements   []string      `vault:"creation_statements"`
+	RevocationStatements []string      `vault:"revocation_statements,omitempty"`
+	RollbackStatements   []string      `vault:"rollback_statements,omitempty"`
+	RenewStatements      []string      `vault:"renew_statements,omitempty"`
+	DefaultTTL           time.Duration `vault:"default_ttl,omitempty"`
+	MaxTTL               time.Duration `vault:"max_ttl,omitempty"`
+}
+
+// ConfigureConnection creates or updates connection with given name of database secret engine mounted
+// to given path, e.g. "database".
+//
+//	err := vault.ConfigureConnection(client, "database", "app", vault.DatabaseConnection{
+//		PluginName:    "postgresql-database-plugin",
+//		ConnectionURL: "postgresql://{{username}}:{{password}}@db:5432/app",
+//		Username:      "vault",
+//		Password:      password,
+//		AllowedRoles:  []string{"app-readonly"},
+//	})
+func ConfigureConnection(c Client, mount, name string, conn DatabaseConnection) error {
+	if conn.PluginName == "" {
+		return errors.New("database plugin name is required")
+	}
+	data, err := Encode(conn)
+	if err != nil {
+		return err
+	}
+	for key, value := range conn.Options {
+		if _, ok := data[key]; ok {
+			return errors.Errorf("database connection option %s conflicts with connection field", key)
+		}
+		data[key] = value
+	}
+	return databaseWrite(c, mount, "config", name, data)
+}
+
+// CreateRole creates or updates dynamic credentials role with given name of database secret engine
+// mounted to given path.
+func CreateRole(c Client, mount, name string, role DatabaseRole) error {
+	if role.DBName == "" || len(role.CreationStatements) == 0 {
+		return errors.New("database name and creation statements are required")
+	}
+	data, err := Encode(role)
+	if err != nil {
+		return err
+	}
+	return databaseWrite(c, mount, "roles", name, data)
+}
+
+// RotateRootCredentials rotates credentials of the user configured to connection with given name
+// of database secret engine mounted to given path. After rotation only Vault knows the password.
+func RotateRootCredentials(c Client, mount, name string) error {
+	return databaseWrite(c, mount, "rotate-root", name, nil)
+}
+
+func databaseWrite(c Client, mount, endpoint, name string, data map[string]interface{}) error {
+	if name == "" {
+		return errors.Errorf("name is required for database %s", endpoint)
+	}
+	path := strings.Trim(mount, "/") + "/" + endpoint + "/" + name
+	_, err := c.Write(path, data)
+	return errors.WithMessage(err, path)
+}
@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureDatabaseSecretEngine(t *testing.T) {
+	verify := false
+	mock := NewMockClient(t)
+	mock.WhenWrite("database/config/app", map[string]interface{}{
+		"plugin_name":          "postgresql-database-plugin",
+		"connection_url":       "postgresql://{{username}}:{{password}}@db:5432/app",
+		"username":             "vault",
+		"password":             "p4ss",
+		"allowed_roles":        []string{"app-readonly"},
+		"verify_connection":    &verify,
+		"max_open_connections": 5,
+	}).ThenReturn(nil)
+	mock.WhenWrite("database/roles/app-readonly", map[string]interface{}{
+		"db_name":             "app",
+		"creation_statements": []string{"CREATE ROLE \"{{name}}\" WITH LOGIN PASSWORD '{{password}}' VALID UNTIL '{{expiration}}';"},
+		"default_ttl":         "1h0m0s",
+	}).ThenReturn(nil)
+	mock.WhenWrite("database/rotate-root/app", nil).ThenReturn(nil)
+
+	require.NoError(t, ConfigureConnection(mock, "/database/", "app", DatabaseConnection{
+		PluginName:       "postgresql-database-plugin",
+		ConnectionURL:    "postgresql://{{username}}:{{password}}@db:5432/app",
+		Username:         "vault",
+		Password:         "p4ss",
+		AllowedRoles:     []string{"app-readonly"},
+		VerifyConnection: &verify,
+		Options:          map[string]interface{}{"max_open_connections": 5},
+	}))
+	require.NoError(t, CreateRole(mock, "database", "app-readonly", DatabaseRole{
+		DBName:             "app",
+		CreationStatements: []string{"CREATE ROLE \"{{name}}\" WITH LOGIN PASSWORD '{{password}}' VALID UNTIL '{{expiration}}';"},
+		DefaultTTL:         time.Hour,
+	}))
+	require.NoError(t, RotateRootCredentials(mock, "database", "app"))
+}
+
+func TestDatabaseSecretEngineValidation(t *testing.T) {
+	mock := NewMockClient(t)
+
+	assert.Error(t, ConfigureConnection(mock, "database", "app", DatabaseConnection{}))
+	assert.EqualError(t, ConfigureConnection(mock, "database", "app", DatabaseConnection{
+		PluginName: "postgresql-database-plugin",
+		Options:    map[string]interface{}{"plugin_name": "other"},
+	}), "database connection option plugin_name conflicts with connection field")
+	assert.Error(t, CreateRole(mock, "database", "app-readonly", DatabaseRole{DBName: "app"}))
+	assert.EqualError(t, RotateRootCredentials(mock, "database", ""), "name is required for database rotate-root")
+}
@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrPermissionDenied is matched by errors of operations rejected with 403, e.g. because of missing policy.
+	ErrPermissionDenied = errors.New("vault permission denied")
+	// ErrPathNotFound is matched by errors of operations rejected with 404. Note that Read and List
+	// return nil secret without error when the path doesn't exist.
+	ErrPathNotFound = errors.New("vault path not found")
+	// ErrRateLimited is matched by errors of operations rejected with 429.
+	ErrRateLimited = errors.New("vault rate limit exceeded")
+	// ErrNotSupported is returned when an optional operation, e.g. Health or PutPolicy, is not implemented by the wrapped client.
+	ErrNotSupported = errors.New("operation is not supported by vault client")
+)
+
+// Error is returned by client operations that fail. It matches one of ErrPermissionDenied, ErrSealed,
+// ErrPathNotFound and ErrRateLimited with errors.Is, when the cause is known, and unwraps to the original
+// error, e.g. *api.ResponseError, so that callers don't need to match error messages:
+//
+//	_, err := client.Read("secret/data/db")
+//	if errors.Is(err, vault.ErrPermissionDenied) {
+//		...
+//	}
+//	var vaultErr *vault.Error
+//	if errors.As(err, &vaultErr) {
+//		log.Errorf("%s of %s failed with status %d: %v", vaultErr.Operation, vaultErr.Path, vaultErr.StatusCode, vaultErr.Errors)
+//	}
+type Error struct {
+	// Operation is the name of the failed operation, e.g. OperationRead.
+	Operation string
+	// Path is the path of the operation, empty for operations without a path.
+	Path string
+	// StatusCode is the HTTP status code of the response, zero if no response was received.
+	StatusCode int
+	// Errors are the error messages returned by Vault.
+	Errors []string
+	// Code is one of the typed errors or nil when the cause is not classified.
+	Code error
+	// Err is the original error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("vault %s: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("vault %s %s: %v", e.Operation, e.Path, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the typed error of e.
+func (e *Error) Is(target error) bool {
+	return e.Code != nil && target == e.Code
+}
+
+// newOperationError wraps err of given operation into Error, unless it is nil or already wrapped.
+func newOperationError(operation, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var opErr *Error
+	if errors.As(err, &opErr) {
+		return err
+	}
+
+	opErr = &Error{Operation: operation, Path: path, Err: err}
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) {
+		return opErr
+	}
+	opErr.StatusCode = respErr.StatusCode
+	opErr.Errors = respErr.Errors
+	switch respErr.StatusCode {
+	case http.StatusForbidden:
+		opErr.Code = ErrPermissionDenied
+	case http.StatusNotFound:
+		opErr.Code = ErrPathNotFound
+	case http.StatusTooManyRequests:
+		opErr.Code = ErrRateLimited
+	case http.StatusServiceUnavailable:
+		for _, msg := range respErr.Errors {
+			if strings.Contains(strings.ToLower(msg), "sealed") {
+				opErr.Code = ErrSealed
+			}
+		}
+	}
+	return opErr
+}
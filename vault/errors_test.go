@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		expectedCode error
+	}{
+		{"permission denied", http.StatusForbidden, `{"errors": ["1 error occurred:\n\t* permission denied\n\n"]}`, ErrPermissionDenied},
+		{"path not found", http.StatusNotFound, `{"errors": ["no handler for route"]}`, ErrPathNotFound},
+		{"rate limited", http.StatusTooManyRequests, `{"errors": ["request path \"secret/data/a\": rate limit quota exceeded"]}`, ErrRateLimited},
+		{"sealed", http.StatusServiceUnavailable, `{"errors": ["Vault is sealed"]}`, ErrSealed},
+		{"unclassified", http.StatusBadRequest, `{"errors": ["invalid request"]}`, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "token")
+			writeTokenFile(t, path, "token", time.Now())
+			c, err := NewClient(server.URL, "", TokenFile(path, false), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+			require.NoError(t, err)
+
+			_, err = c.Write("secret/data/a", map[string]interface{}{"key": "value"})
+			require.Error(t, err)
+
+			var opErr *Error
+			require.True(t, errors.As(err, &opErr), err)
+			assert.Equal(t, OperationWrite, opErr.Operation)
+			assert.Equal(t, "secret/data/a", opErr.Path)
+			assert.Equal(t, tc.status, opErr.StatusCode)
+			assert.NotEmpty(t, opErr.Errors)
+			assert.Equal(t, tc.expectedCode, opErr.Code)
+			for _, code := range []error{ErrPermissionDenied, ErrPathNotFound, ErrRateLimited, ErrSealed} {
+				assert.Equal(t, code == tc.expectedCode, errors.Is(err, code), code)
+			}
+
+			var respErr *api.ResponseError
+			require.True(t, errors.As(err, &respErr))
+			assert.Equal(t, tc.status, respErr.StatusCode)
+			assert.Contains(t, err.Error(), "vault write secret/data/a: ")
+		})
+	}
+}
+
+func TestOperationErrorIsNotWrappedTwice(t *testing.T) {
+	err := errors.WithMessage(newOperationError(OperationRead, "a", errors.New("failure")), "outer")
+	assert.Equal(t, err, newOperationError(OperationList, "b", err))
+	assert.NoError(t, newOperationError(OperationRead, "a", nil))
+}
@@ -0,0 +1,91 @@
+package vault
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrSealed is returned by CheckHealth when Vault is sealed.
+	ErrSealed = errors.New("vault is sealed")
+	// ErrNotInitialized is returned by CheckHealth when Vault is not initialized.
+	ErrNotInitialized = errors.New("vault is not initialized")
+)
+
+// HealthChecker is implemented by clients which can report health of Vault server, e.g. the ones returned by
+// NewClient and NewSimpleTokenClient and MockClient. It is kept out of Client, so that existing implementations
+// of Client don't need to implement it.
+type HealthChecker interface {
+	Health() (*api.HealthResponse, error)
+}
+
+// Health returns health and seal status of Vault server. Health endpoint doesn't require
+// authentication, so it can be used before the client has logged in, e.g. while Vault is sealed.
+func (c *client) Health() (health *api.HealthResponse, err error) {
+	done := c.config.Hooks.begin(OperationHealth, "sys/health")
+	defer func() {
+		err = newOperationError(OperationHealth, "sys/health", err)
+		done(err)
+	}()
+
+	vaultClient := c.h.get()
+	if vaultClient == nil {
+		config := defaultConfig(c.config.VaultAddress)
+		config.Timeout = c.config.Timeout
+
+		vaultClient, err = api.NewClient(config)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Could not configure Vault client with settings provided")
+		}
+	}
+
+	err = c.retrier.do(func() (e error) {
+		health, e = vaultClient.Sys().Health()
+		return e
+	}, nil)
+	return health, err
+}
+
+// CheckHealth returns error if Vault server can't be reached, is not initialized or is sealed.
+// ErrNotSupported is returned if c doesn't implement HealthChecker.
+func CheckHealth(c Client) error {
+	checker, ok := c.(HealthChecker)
+	if !ok {
+		return errors.WithMessage(ErrNotSupported, "vault health check failed")
+	}
+	health, err := checker.Health()
+	if err != nil {
+		return errors.WithMessage(err, "vault health check failed")
+	}
+
+	switch {
+	case !health.Initialized:
+		return ErrNotInitialized
+	case health.Sealed:
+		return ErrSealed
+	}
+	return nil
+}
+
+// HealthCheckFunc returns health check function for metrics.StartManagementServer, which responds with
+// 503 Service Unavailable when CheckHealth fails, so the pod is marked unready while Vault is sealed.
+// If next is not nil, it is called when Vault is healthy, otherwise 200 OK is returned.
+func HealthCheckFunc(c Client, next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := CheckHealth(c); err != nil {
+			log.Infof("vault readiness check failed: %s", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprint(w, err)
+			return
+		}
+
+		if next != nil {
+			next(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientHealth(t *testing.T) {
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"initialized":true,"sealed":true,"version":"1.15.0"}`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "role", JwtPath("/non/existing/path"), MaxRetries(0))
+	require.NoError(t, err)
+
+	health, err := c.(HealthChecker).Health()
+	require.NoError(t, err, "health should not require authentication")
+	assert.Equal(t, "/v1/sys/health", requestedPath)
+	assert.True(t, health.Sealed)
+	assert.Equal(t, "1.15.0", health.Version)
+	assert.ErrorIs(t, CheckHealth(c), ErrSealed)
+}
+
+func TestCheckHealthNotSupported(t *testing.T) {
+	c := struct{ Client }{}
+	assert.ErrorIs(t, CheckHealth(c), ErrNotSupported)
+	_, err := WithHooks(c, RequestHooks{}).(HealthChecker).Health()
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestHealthCheckFunc(t *testing.T) {
+	tests := []struct {
+		name           string
+		health         *api.HealthResponse
+		err            error
+		next           func(http.ResponseWriter, *http.Request)
+		expectedStatus int
+	}{
+		{
+			name:           "healthy",
+			health:         &api.HealthResponse{Initialized: true},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "healthy with next",
+			health: &api.HealthResponse{Initialized: true},
+			next: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "sealed",
+			health:         &api.HealthResponse{Initialized: true, Sealed: true},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "not initialized",
+			health:         &api.HealthResponse{},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "unreachable",
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockClient(t)
+			if tt.err != nil {
+				mock.WhenHealth().ThenError(tt.err)
+			} else {
+				mock.WhenHealth().ThenReturn(tt.health)
+			}
+
+			rec := httptest.NewRecorder()
+			HealthCheckFunc(mock, tt.next)(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package vault
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/phanitejak/kptgolib/logging"
+)
+
+// Operation names passed to RequestHooks.
+const (
+	OperationRead       = "read"
+	OperationWrite      = "write"
+	OperationDelete     = "delete"
+	OperationList       = "list"
+	OperationMount      = "mount"
+	OperationUnmount    = "unmount"
+	OperationListMounts = "list-mounts"
+	OperationHealth     = "health"
+	// Operations of sys backend.
+	OperationPutPolicy    = "put-policy"
+	OperationGetPolicy    = "get-policy"
+	OperationDeletePolicy = "delete-policy"
+	OperationListPolicies = "list-policies"
+	OperationEnableAuth   = "enable-auth"
+	OperationDisableAuth  = "disable-auth"
+	OperationListAuth     = "list-auth"
+	OperationListAudit    = "list-audit"
+)
+
+// Operation describes single vault client operation.
+type Operation struct {
+	// Name is one of Operation* constants.
+	Name string
+	// Path is the secret path accessed, after redaction.
+	Path string
+	// Component is the name of the component using the client.
+	Component string
+}
+
+// RequestHooks are called around every vault client operation. All fields are optional.
+type RequestHooks struct {
+	// Component is passed to hooks as Operation.Component.
+	Component string
+	// Redact is applied to the secret path before it is passed to hooks.
+	Redact func(path string) string
+	// OnRequest is called before operation is executed.
+	OnRequest func(op Operation)
+	// OnResponse is called after operation is completed with the operation duration and error.
+	OnResponse func(op Operation, duration time.Duration, err error)
+}
+
+// Hooks sets hooks which are called around every client operation.
+func Hooks(hooks RequestHooks) ConfigFn {
+	return func(c *config) (err error) {
+		c.Hooks = hooks
+		return
+	}
+}
+
+// LogHooks returns hooks logging every completed operation with its path,
+// component and duration. Failed operations are logged on error level.
+func LogHooks(log logging.Logger, component string) RequestHooks {
+	return RequestHooks{
+		Component: component,
+		OnResponse: func(op Operation, duration time.Duration, err error) {
+			l := log.WithFields(map[string]interface{}{
+				"vault_operation": op.Name,
+				"vault_path":      op.Path,
+				"component":       op.Component,
+				"duration_ms":     duration.Milliseconds(),
+			})
+			if err != nil {
+				l.With("error", err.Error()).Error("vault operation failed")
+				return
+			}
+			l.Info("vault operation completed")
+		},
+	}
+}
+
+func (h RequestHooks) begin(name, path string) func(err error) {
+	if h.OnRequest == nil && h.OnResponse == nil {
+		return func(error) {}
+	}
+
+	if h.Redact != nil {
+		path = h.Redact(path)
+	}
+	op := Operation{Name: name, Path: path, Component: h.Component}
+	if h.OnRequest != nil {
+		h.OnRequest(op)
+	}
+
+	start := time.Now()
+	return func(err error) {
+		if h.OnResponse != nil {
+			h.OnResponse(op, time.Since(start), err)
+		}
+	}
+}
+
+// WithHooks wraps given client so that hooks are called around every
+// operation. It can be used with clients which do not support Hooks option,
+// e.g. the one returned by NewSimpleTokenClient.
+func WithHooks(c Client, hooks RequestHooks) Client {
+	return &hookedClient{c: c, hooks: hooks}
+}
+
+type hookedClient struct {
+	c     Client
+	hooks RequestHooks
+}
+
+func (h *hookedClient) Read(path string) (secret *api.Secret, err error) {
+	done := h.hooks.begin(OperationRead, path)
+	defer func() { done(err) }()
+	return h.c.Read(path)
+}
+
+func (h *hookedClient) Write(path string, data map[string]interface{}) (secret *api.Secret, err error) {
+	done := h.hooks.begin(OperationWrite, path)
+	defer func() { done(err) }()
+	return h.c.Write(path, data)
+}
+
+func (h *hookedClient) Delete(path string) (secret *api.Secret, err error) {
+	done := h.hooks.begin(OperationDelete, path)
+	defer func() { done(err) }()
+	return h.c.Delete(path)
+}
+
+func (h *hookedClient) List(path string) (secret *api.Secret, err error) {
+	done := h.hooks.begin(OperationList, path)
+	defer func() { done(err) }()
+	return h.c.List(path)
+}
+
+func (h *hookedClient) Mount(path string, input *api.MountInput) (err error) {
+	done := h.hooks.begin(OperationMount, path)
+	defer func() { done(err) }()
+	return h.c.Mount(path, input)
+}
+
+func (h *hookedClient) Unmount(path string) (err error) {
+	done := h.hooks.begin(OperationUnmount, path)
+	defer func() { done(err) }()
+	return h.c.Unmount(path)
+}
+
+func (h *hookedClient) ListMounts() (mounts map[string]*api.MountOutput, err error) {
+	done := h.hooks.begin(OperationListMounts, "")
+	defer func() { done(err) }()
+	return h.c.ListMounts()
+}
+
+func (h *hookedClient) Health() (health *api.HealthResponse, err error) {
+	done := h.hooks.begin(OperationHealth, "sys/health")
+	defer func() { done(err) }()
+	checker, ok := h.c.(HealthChecker)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return checker.Health()
+}
+
+func (h *hookedClient) PutPolicy(name, rules string) (err error) {
+	done := h.hooks.begin(OperationPutPolicy, "sys/policies/acl/"+name)
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return ErrNotSupported
+	}
+	return sys.PutPolicy(name, rules)
+}
+
+func (h *hookedClient) GetPolicy(name string) (rules string, err error) {
+	done := h.hooks.begin(OperationGetPolicy, "sys/policies/acl/"+name)
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return "", ErrNotSupported
+	}
+	return sys.GetPolicy(name)
+}
+
+func (h *hookedClient) DeletePolicy(name string) (err error) {
+	done := h.hooks.begin(OperationDeletePolicy, "sys/policies/acl/"+name)
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return ErrNotSupported
+	}
+	return sys.DeletePolicy(name)
+}
+
+func (h *hookedClient) ListPolicies() (policies []string, err error) {
+	done := h.hooks.begin(OperationListPolicies, "sys/policies/acl")
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return sys.ListPolicies()
+}
+
+func (h *hookedClient) EnableAuth(path string, options *api.EnableAuthOptions) (err error) {
+	done := h.hooks.begin(OperationEnableAuth, "sys/auth/"+path)
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return ErrNotSupported
+	}
+	return sys.EnableAuth(path, options)
+}
+
+func (h *hookedClient) DisableAuth(path string) (err error) {
+	done := h.hooks.begin(OperationDisableAuth, "sys/auth/"+path)
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return ErrNotSupported
+	}
+	return sys.DisableAuth(path)
+}
+
+func (h *hookedClient) ListAuth() (auths map[string]*api.AuthMount, err error) {
+	done := h.hooks.begin(OperationListAuth, "sys/auth")
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return sys.ListAuth()
+}
+
+func (h *hookedClient) ListAudit() (audits map[string]*api.Audit, err error) {
+	done := h.hooks.begin(OperationListAudit, "sys/audit")
+	defer func() { done(err) }()
+	sys, ok := h.c.(SysClient)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return sys.ListAudit()
+}
@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHooks(t *testing.T) {
+	handler := &mockVaultHandler{bodyToWrite: []byte(`{"data":{"hello":"world"}}`)}
+	mockVaultServer := httptest.NewServer(handler)
+	defer mockVaultServer.Close()
+
+	tokenClient, err := NewSimpleTokenClient(mockVaultServer.URL, "helloToken")
+	require.NoError(t, err)
+
+	var requests, responses []Operation
+	c := WithHooks(tokenClient, RequestHooks{
+		Component: "my-component",
+		Redact: func(path string) string {
+			return path[:strings.LastIndex(path, "/")] + "/***"
+		},
+		OnRequest: func(op Operation) {
+			requests = append(requests, op)
+		},
+		OnResponse: func(op Operation, duration time.Duration, err error) {
+			assert.NoError(t, err)
+			assert.True(t, duration > 0)
+			responses = append(responses, op)
+		},
+	})
+
+	_, err = c.Read("secret/my/password")
+	require.NoError(t, err)
+	_, err = c.Write("secret/my/password", map[string]interface{}{"hello": "world"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/secret/my/password", handler.capturedRequest.URL.Path)
+	expected := []Operation{
+		{Name: OperationRead, Path: "secret/my/***", Component: "my-component"},
+		{Name: OperationWrite, Path: "secret/my/***", Component: "my-component"},
+	}
+	assert.Equal(t, expected, requests)
+	assert.Equal(t, expected, responses)
+}
+
+func TestClientHooksOnError(t *testing.T) {
+	var responses []Operation
+	var errs []error
+	c, err := NewClient("http://localhost:0", "role",
+		JwtPath("/not/existing/token"),
+		Hooks(RequestHooks{
+			OnResponse: func(op Operation, _ time.Duration, err error) {
+				responses = append(responses, op)
+				errs = append(errs, err)
+			},
+		}))
+	require.NoError(t, err)
+
+	_, err = c.Read("secret/path")
+	require.Error(t, err)
+
+	assert.Equal(t, []Operation{{Name: OperationRead, Path: "secret/path"}}, responses)
+	assert.Equal(t, []error{err}, errs)
+}
@@ -0,0 +1,260 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"sigs.k8s.io/yaml"
+)
+
+// Environment variables enabling local development mode of NewClient.
+const (
+	DevModeEnv        = "VAULT_DEV_MODE"
+	DevSecretsFileEnv = "VAULT_DEV_SECRETS_FILE"
+)
+
+// InMemoryClient is a Client keeping secrets, mounts, policies and auth methods in memory, e.g. for running services
+// locally without a Vault server. Secrets are returned as written: reading a path returns the data last written
+// to it and missing paths return nil secret like Vault. For KV version 2 paths the data must contain "data" map,
+// e.g. "secret/data/my-service": {"data": {"password": "..."}}.
+type InMemoryClient struct {
+	lock     sync.RWMutex
+	secrets  map[string]map[string]interface{}
+	mounts   map[string]*api.MountOutput
+	policies map[string]string
+	auths    map[string]*api.AuthMount
+}
+
+// NewInMemoryClient returns client seeded with given secrets by path.
+func NewInMemoryClient(secrets map[string]map[string]interface{}) *InMemoryClient {
+	c := &InMemoryClient{
+		secrets:  map[string]map[string]interface{}{},
+		mounts:   map[string]*api.MountOutput{},
+		policies: map[string]string{},
+		auths:    map[string]*api.AuthMount{},
+	}
+	for path, data := range secrets {
+		c.secrets[cleanPath(path)] = copyData(data)
+	}
+	return c
+}
+
+// NewInMemoryClientFromFile returns client seeded with secrets by path read from YAML or JSON file, e.g.
+//
+//	secret/data/my-service:
+//	  data:
+//	    password: local-password
+//	database/creds/my-role:
+//	  username: local-user
+//	  password: local-password
+func NewInMemoryClientFromFile(path string) (*InMemoryClient, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dev secrets file: %w", err)
+	}
+	secrets := map[string]map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse dev secrets file %s: %w", path, err)
+	}
+	return NewInMemoryClient(secrets), nil
+}
+
+// devModeClient returns in-memory client when VAULT_DEV_MODE is true, seeded from VAULT_DEV_SECRETS_FILE if set.
+func devModeClient() (*InMemoryClient, bool, error) {
+	enabled, _ := strconv.ParseBool(os.Getenv(DevModeEnv))
+	if !enabled {
+		return nil, false, nil
+	}
+	file := os.Getenv(DevSecretsFileEnv)
+	if file == "" {
+		log.Infof("%s is set, using in-memory vault client without secrets", DevModeEnv)
+		return NewInMemoryClient(nil), true, nil
+	}
+	log.Infof("%s is set, using in-memory vault client with secrets from %s", DevModeEnv, file)
+	c, err := NewInMemoryClientFromFile(file)
+	return c, true, err
+}
+
+// Read returns secret written to path or nil if there is none.
+func (c *InMemoryClient) Read(path string) (*api.Secret, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	data, ok := c.secrets[cleanPath(path)]
+	if !ok {
+		return nil, nil
+	}
+	return &api.Secret{Data: copyData(data)}, nil
+}
+
+// Write stores data to path, replacing existing secret.
+func (c *InMemoryClient) Write(path string, data map[string]interface{}) (*api.Secret, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.secrets[cleanPath(path)] = copyData(data)
+	return nil, nil
+}
+
+// Delete removes secret of path.
+func (c *InMemoryClient) Delete(path string) (*api.Secret, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.secrets, cleanPath(path))
+	return nil, nil
+}
+
+// List returns keys under path in "keys" like Vault: nested paths end with "/". Nil is returned if there are none.
+func (c *InMemoryClient) List(path string) (*api.Secret, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	prefix := cleanPath(path) + "/"
+	found := map[string]bool{}
+	for p := range c.secrets {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(p, prefix)
+		if i := strings.Index(key, "/"); i >= 0 {
+			key = key[:i+1]
+		}
+		found[key] = true
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key
+	}
+	return &api.Secret{Data: map[string]interface{}{"keys": values}}, nil
+}
+
+// Mount records secret engine mounted to path.
+func (c *InMemoryClient) Mount(path string, input *api.MountInput) error {
+	if input == nil {
+		return fmt.Errorf("mount input is required for %s", path)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.mounts[cleanPath(path)+"/"] = &api.MountOutput{Type: input.Type, Description: input.Description}
+	return nil
+}
+
+// Unmount removes secret engine mounted to path.
+func (c *InMemoryClient) Unmount(path string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.mounts, cleanPath(path)+"/")
+	return nil
+}
+
+// ListMounts returns mounted secret engines by path.
+func (c *InMemoryClient) ListMounts() (map[string]*api.MountOutput, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	mounts := make(map[string]*api.MountOutput, len(c.mounts))
+	for path, mount := range c.mounts {
+		mounts[path] = mount
+	}
+	return mounts, nil
+}
+
+// Health returns initialized and unsealed status.
+func (c *InMemoryClient) Health() (*api.HealthResponse, error) {
+	return &api.HealthResponse{Initialized: true, Sealed: false, Standby: false, Version: "in-memory"}, nil
+}
+
+// PutPolicy stores policy rules.
+func (c *InMemoryClient) PutPolicy(name, rules string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.policies[name] = rules
+	return nil
+}
+
+// GetPolicy returns policy rules, empty if the policy doesn't exist.
+func (c *InMemoryClient) GetPolicy(name string) (string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.policies[name], nil
+}
+
+// DeletePolicy removes policy.
+func (c *InMemoryClient) DeletePolicy(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.policies, name)
+	return nil
+}
+
+// ListPolicies returns sorted policy names.
+func (c *InMemoryClient) ListPolicies() ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	policies := make([]string, 0, len(c.policies))
+	for name := range c.policies {
+		policies = append(policies, name)
+	}
+	sort.Strings(policies)
+	return policies, nil
+}
+
+// EnableAuth records auth method enabled at path.
+func (c *InMemoryClient) EnableAuth(path string, options *api.EnableAuthOptions) error {
+	if options == nil {
+		return fmt.Errorf("auth options are required for %s", path)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.auths[cleanPath(path)+"/"] = &api.AuthMount{Type: options.Type, Description: options.Description}
+	return nil
+}
+
+// DisableAuth removes auth method enabled at path.
+func (c *InMemoryClient) DisableAuth(path string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.auths, cleanPath(path)+"/")
+	return nil
+}
+
+// ListAuth returns enabled auth methods by path.
+func (c *InMemoryClient) ListAuth() (map[string]*api.AuthMount, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	auths := make(map[string]*api.AuthMount, len(c.auths))
+	for path, auth := range c.auths {
+		auths[path] = auth
+	}
+	return auths, nil
+}
+
+// ListAudit returns no audit devices.
+func (c *InMemoryClient) ListAudit() (map[string]*api.Audit, error) {
+	return map[string]*api.Audit{}, nil
+}
+
+func cleanPath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryClientSecrets(t *testing.T) {
+	c := NewInMemoryClient(map[string]map[string]interface{}{
+		"secret/data/app": {"data": map[string]interface{}{"password": "seeded"}},
+	})
+
+	secret, err := c.Read("/secret/data/app")
+	require.NoError(t, err)
+	require.NotNil(t, secret)
+	assert.Equal(t, map[string]interface{}{"password": "seeded"}, secret.Data["data"])
+
+	_, err = c.Write("secret/data/app/nested", map[string]interface{}{"key": "value"})
+	require.NoError(t, err)
+	_, err = c.Write("secret/data/other", map[string]interface{}{"key": "value"})
+	require.NoError(t, err)
+
+	list, err := c.List("secret/data")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"app", "app/", "other"}, list.Data["keys"])
+
+	_, err = c.Delete("secret/data/app")
+	require.NoError(t, err)
+	secret, err = c.Read("secret/data/app")
+	require.NoError(t, err)
+	assert.Nil(t, secret, "missing secret is nil like in Vault")
+
+	list, err = c.List("missing")
+	require.NoError(t, err)
+	assert.Nil(t, list)
+}
+
+func TestInMemoryClientSys(t *testing.T) {
+	c := NewInMemoryClient(nil)
+
+	require.NoError(t, c.Mount("kv", &api.MountInput{Type: "kv"}))
+	mounts, err := c.ListMounts()
+	require.NoError(t, err)
+	assert.Equal(t, "kv", mounts["kv/"].Type)
+	require.NoError(t, c.Unmount("kv"))
+	mounts, err = c.ListMounts()
+	require.NoError(t, err)
+	assert.Empty(t, mounts)
+
+	require.NoError(t, c.PutPolicy("reader", `path "secret/*" { capabilities = ["read"] }`))
+	policies, err := c.ListPolicies()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reader"}, policies)
+
+	health, err := c.Health()
+	require.NoError(t, err)
+	assert.True(t, health.Initialized)
+	assert.False(t, health.Sealed)
+
+	assert.Error(t, c.Mount("kv", nil))
+	assert.Error(t, c.EnableAuth("kubernetes", nil))
+}
+
+func TestNewClientDevMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secrets.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+secret/data/app:
+  data:
+    password: local
+database/creds/app:
+  username: user
+`), 0o600))
+	t.Setenv(DevModeEnv, "true")
+	t.Setenv(DevSecretsFileEnv, file)
+
+	c, err := NewClient("https://vault.invalid", "role")
+	require.NoError(t, err)
+	require.IsType(t, &InMemoryClient{}, c)
+
+	secret, err := c.Read("secret/data/app")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "local"}, secret.Data["data"])
+	secret, err = c.Read("database/creds/app")
+	require.NoError(t, err)
+	assert.Equal(t, "user", secret.Data["username"])
+}
+
+func TestNewClientDevModeHooks(t *testing.T) {
+	t.Setenv(DevModeEnv, "true")
+	t.Setenv(DevSecretsFileEnv, "")
+
+	var ops []string
+	c, err := NewClient("https://vault.invalid", "role", Timeout(time.Second), Hooks(RequestHooks{
+		OnRequest: func(op Operation) { ops = append(ops, op.Name+" "+op.Path) },
+	}))
+	require.NoError(t, err)
+
+	_, err = c.Read("secret/data/app")
+	require.NoError(t, err)
+	require.NoError(t, c.Mount("kv", &api.MountInput{Type: "kv"}))
+	require.NoError(t, c.(SysClient).PutPolicy("reader", ""))
+	assert.Equal(t, []string{"read secret/data/app", "mount kv", "put-policy sys/policies/acl/reader"}, ops)
+}
+
+func TestNewClientDevModeInvalidFile(t *testing.T) {
+	t.Setenv(DevModeEnv, "true")
+	t.Setenv(DevSecretsFileEnv, filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := NewClient("https://vault.invalid", "role")
+	assert.Error(t, err)
+}
+
+func TestNewClientWithoutDevMode(t *testing.T) {
+	t.Setenv(DevModeEnv, "false")
+
+	c, err := NewClient("https://vault.invalid", "role")
+	require.NoError(t, err)
+	assert.IsType(t, &client{}, c)
+}
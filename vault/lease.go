@@ -0,0 +1,255 @@
+package vault
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/phanitejak/kptgolib/metrics"
+)
+
+const (
+	tokenLookupPath = "auth/token/lookup-self"
+	tokenRenewPath  = "auth/token/renew-self"
+	leaseRenewPath  = "sys/leases/renew"
+)
+
+// Kinds of renewals counted by com_metrics_vault_renewal_errors_total.
+const (
+	RenewalToken = "token"
+	RenewalLease = "lease"
+)
+
+var (
+	leaseManagersLock sync.RWMutex
+	leaseManagers     = map[string]*LeaseManager{}
+
+	_ = metrics.RegisterGaugeVecFunc("token_ttl_seconds", "vault",
+		"Remaining TTL of the vault token of the lease manager, missing for tokens which never expire.",
+		collectLeaseManagers(func(m *LeaseManager, now time.Time) (float64, bool) { return m.tokenTTL(now) }), "manager")
+	_ = metrics.RegisterGaugeVecFunc("managed_leases", "vault",
+		"Number of secret leases managed by the lease manager.",
+		collectLeaseManagers(func(m *LeaseManager, _ time.Time) (float64, bool) { return float64(m.Leases()), true }), "manager")
+	_ = metrics.RegisterGaugeVecFunc("next_renewal_seconds", "vault",
+		"Time until the next renewal of the token or a lease of the lease manager.",
+		collectLeaseManagers(func(m *LeaseManager, now time.Time) (float64, bool) { return m.nextRenewal(now) }), "manager")
+	renewalErrors = metrics.RegisterCounterVec("renewal_errors_total", "vault",
+		"Count of failed renewals of vault token and secret leases.", "manager", "kind")
+)
+
+// LeaseManager renews the token of a client and leases of dynamic secrets, e.g. database credentials,
+// before they expire. Its state is exposed as com_metrics_vault_token_ttl_seconds, com_metrics_vault_managed_leases
+// and com_metrics_vault_next_renewal_seconds gauges and com_metrics_vault_renewal_errors_total counter labeled
+// with manager name, so that expiring credentials can be alerted before they cause outages.
+type LeaseManager struct {
+	c      Client
+	name   string
+	lock   sync.Mutex
+	token  *lease
+	leases map[string]*lease
+	now    func() time.Time
+}
+
+// lease is the renewal state of a token or secret lease.
+type lease struct {
+	path      string
+	renewable bool
+	expires   bool
+	expiresAt time.Time
+	renewAt   time.Time
+}
+
+// NewLeaseManager returns lease manager of given client, which metrics are labeled with name.
+// Name must be unique, a manager replaces metrics of previous manager with the same name.
+func NewLeaseManager(c Client, name string) *LeaseManager {
+	m := &LeaseManager{c: c, name: name, leases: map[string]*lease{}, now: time.Now}
+	leaseManagersLock.Lock()
+	leaseManagers[name] = m
+	leaseManagersLock.Unlock()
+	return m
+}
+
+// Manage adds lease of given secret read from path to be renewed. Secrets without lease are ignored.
+func (m *LeaseManager) Manage(path string, secret *api.Secret) {
+	if secret == nil || secret.LeaseID == "" {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.leases[secret.LeaseID] = newLease(path, secret.Renewable, time.Duration(secret.LeaseDuration)*time.Second, m.now())
+}
+
+// Forget stops renewing lease with given ID, e.g. when the secret is not used anymore.
+func (m *LeaseManager) Forget(leaseID string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.leases, leaseID)
+}
+
+// Leases returns the number of managed leases.
+func (m *LeaseManager) Leases() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.leases)
+}
+
+// Run renews the token and leases, which have reached two thirds of their TTL, every interval until
+// ctx is done. Token state is looked up on start. Failed renewals are logged and attempted again
+// at next interval. Expired leases are dropped, their secrets must be read and managed again.
+func (m *LeaseManager) Run(ctx context.Context, interval time.Duration) error {
+	m.renewDue()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.renewDue()
+		}
+	}
+}
+
+// Close removes metrics of the manager.
+func (m *LeaseManager) Close() error {
+	leaseManagersLock.Lock()
+	defer leaseManagersLock.Unlock()
+	if leaseManagers[m.name] == m {
+		delete(leaseManagers, m.name)
+	}
+	return nil
+}
+
+func (m *LeaseManager) renewDue() {
+	m.renewTokenIfDue()
+
+	m.lock.Lock()
+	due := map[string]string{}
+	now := m.now()
+	for id, l := range m.leases {
+		if l.expires && !now.Before(l.expiresAt) {
+			log.Errorf("vault lease %s of %s expired", id, l.path)
+			delete(m.leases, id)
+			continue
+		}
+		if l.renewable && !now.Before(l.renewAt) {
+			due[id] = l.path
+		}
+	}
+	m.lock.Unlock()
+
+	for id, path := range due {
+		secret, err := m.c.Write(leaseRenewPath, map[string]interface{}{"lease_id": id})
+		if err != nil {
+			renewalErrors.GetCustomCounter(m.name, RenewalLease).Inc()
+			log.Errorf("failed to renew vault lease %s of %s: %s", id, path, err)
+			continue
+		}
+		m.lock.Lock()
+		if _, ok := m.leases[id]; ok && secret != nil {
+			m.leases[id] = newLease(path, secret.Renewable, time.Duration(secret.LeaseDuration)*time.Second, m.now())
+		}
+		m.lock.Unlock()
+	}
+}
+
+func (m *LeaseManager) renewTokenIfDue() {
+	m.lock.Lock()
+	token := m.token
+	m.lock.Unlock()
+	if token != nil && (!token.expires || m.now().Before(token.renewAt)) {
+		return
+	}
+
+	path := tokenLookupPath
+	var secret *api.Secret
+	var err error
+	if token != nil && token.renewable {
+		path = tokenRenewPath
+		secret, err = m.c.Write(path, nil)
+	} else {
+		secret, err = m.c.Read(path)
+	}
+	if err == nil {
+		token, err = tokenLease(secret, m.now())
+	}
+	if err != nil {
+		renewalErrors.GetCustomCounter(m.name, RenewalToken).Inc()
+		log.Errorf("failed to renew vault token using %s: %s", path, err)
+		return
+	}
+
+	m.lock.Lock()
+	m.token = token
+	m.lock.Unlock()
+}
+
+func (m *LeaseManager) tokenTTL(now time.Time) (float64, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.token == nil || !m.token.expires {
+		return 0, false
+	}
+	return math.Max(m.token.expiresAt.Sub(now).Seconds(), 0), true
+}
+
+func (m *LeaseManager) nextRenewal(now time.Time) (float64, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var next time.Time
+	candidates := make([]*lease, 0, len(m.leases)+1)
+	if m.token != nil {
+		candidates = append(candidates, m.token)
+	}
+	for _, l := range m.leases {
+		candidates = append(candidates, l)
+	}
+	for _, l := range candidates {
+		if l.expires && (next.IsZero() || l.renewAt.Before(next)) {
+			next = l.renewAt
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return math.Max(next.Sub(now).Seconds(), 0), true
+}
+
+func tokenLease(secret *api.Secret, now time.Time) (*lease, error) {
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, err
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, err
+	}
+	return newLease(tokenLookupPath, renewable, ttl, now), nil
+}
+
+// newLease returns lease obtained at now, which is renewed after two thirds of its TTL. Zero TTL never expires.
+func newLease(path string, renewable bool, ttl time.Duration, now time.Time) *lease {
+	return &lease{
+		path:      path,
+		renewable: renewable,
+		expires:   ttl > 0,
+		expiresAt: now.Add(ttl),
+		renewAt:   now.Add(ttl * 2 / 3),
+	}
+}
+
+func collectLeaseManagers(value func(m *LeaseManager, now time.Time) (float64, bool)) func() []metrics.LabeledValue {
+	return func() []metrics.LabeledValue {
+		leaseManagersLock.RLock()
+		defer leaseManagersLock.RUnlock()
+		values := make([]metrics.LabeledValue, 0, len(leaseManagers))
+		for name, m := range leaseManagers {
+			if v, ok := value(m, m.now()); ok {
+				values = append(values, metrics.LabeledValue{LabelValues: []string{name}, Value: v})
+			}
+		}
+		return values
+	}
+}
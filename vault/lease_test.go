@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/phanitejak/kptgolib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrapeLeaseMetrics(t *testing.T) metrics.MetricFamilies {
+	server := httptest.NewServer(metrics.GetMetricsHandler())
+	defer server.Close()
+	families, err := metrics.Scrape(server.URL)
+	require.NoError(t, err)
+	return families
+}
+
+func TestLeaseManager(t *testing.T) {
+	c := NewMockClient(t)
+	m := NewLeaseManager(c, "lease-manager-test")
+	defer m.Close()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	labels := map[string]string{"manager": "lease-manager-test"}
+	leaseID := "database/creds/app/1"
+
+	c.WhenRead(tokenLookupPath).ThenReturn(&api.Secret{Data: map[string]interface{}{"ttl": json.Number("300"), "renewable": true}})
+	m.Manage("database/creds/app", &api.Secret{LeaseID: leaseID, LeaseDuration: 90, Renewable: true})
+	m.Manage("secret/data/static", &api.Secret{Data: map[string]interface{}{"key": "value"}})
+	m.renewDue()
+
+	families := scrapeLeaseMetrics(t)
+	value, ok := families.Value("com_metrics_vault_token_ttl_seconds", labels)
+	require.True(t, ok)
+	assert.Equal(t, 300.0, value)
+	value, ok = families.Value("com_metrics_vault_managed_leases", labels)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, value)
+	value, ok = families.Value("com_metrics_vault_next_renewal_seconds", labels)
+	require.True(t, ok)
+	assert.Equal(t, 60.0, value)
+
+	now = now.Add(70 * time.Second)
+	c.WhenWrite(leaseRenewPath, map[string]interface{}{"lease_id": leaseID}).ThenError(errors.New("connection refused"))
+	m.renewDue()
+	value, ok = scrapeLeaseMetrics(t).Value("com_metrics_vault_renewal_errors_total", map[string]string{"manager": "lease-manager-test", "kind": RenewalLease})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, value)
+
+	c.WhenWrite(leaseRenewPath, map[string]interface{}{"lease_id": leaseID}).ThenReturn(&api.Secret{LeaseID: leaseID, LeaseDuration: 90, Renewable: true})
+	m.renewDue()
+	value, _ = scrapeLeaseMetrics(t).Value("com_metrics_vault_next_renewal_seconds", labels)
+	assert.Equal(t, 60.0, value)
+
+	now = now.Add(140 * time.Second)
+	c.WhenWrite(tokenRenewPath, nil).ThenReturn(&api.Secret{Auth: &api.SecretAuth{LeaseDuration: 300, Renewable: true}})
+	m.renewDue()
+	assert.Equal(t, 0, m.Leases())
+	families = scrapeLeaseMetrics(t)
+	value, _ = families.Value("com_metrics_vault_token_ttl_seconds", labels)
+	assert.Equal(t, 300.0, value)
+	value, _ = families.Value("com_metrics_vault_managed_leases", labels)
+	assert.Equal(t, 0.0, value)
+	assert.Empty(t, c.expectedCalls)
+
+	require.NoError(t, m.Close())
+	_, ok = scrapeLeaseMetrics(t).Value("com_metrics_vault_managed_leases", labels)
+	assert.False(t, ok)
+}
+
+func TestLeaseManagerTokenRenewalErrors(t *testing.T) {
+	c := NewMockClient(t)
+	m := NewLeaseManager(c, "lease-manager-token-test")
+	defer m.Close()
+	labels := map[string]string{"manager": "lease-manager-token-test"}
+
+	c.WhenRead(tokenLookupPath).ThenError(errors.New("permission denied"))
+	m.renewDue()
+	value, ok := scrapeLeaseMetrics(t).Value("com_metrics_vault_renewal_errors_total", map[string]string{"manager": "lease-manager-token-test", "kind": RenewalToken})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, value)
+
+	c.WhenRead(tokenLookupPath).ThenReturn(&api.Secret{Data: map[string]interface{}{"ttl": json.Number("0")}})
+	m.renewDue()
+	m.renewDue() // token which never expires is not looked up again
+	_, ok = scrapeLeaseMetrics(t).Value("com_metrics_vault_token_ttl_seconds", labels)
+	assert.False(t, ok)
+}
@@ -0,0 +1,212 @@
+package vault
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+const tagName = "vault"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ReadInto reads secret from given path and maps its data into struct pointed by v, see Decode.
+// Data of KV version 2 secrets is read from the nested "data" field.
+func ReadInto(c Client, path string, v interface{}) error {
+	secret, err := c.Read(path)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Data == nil {
+		return errors.WithMessage(ErrSecretNotFound, path)
+	}
+	return errors.WithMessage(Decode(secretData(secret), v), path)
+}
+
+// WriteFrom maps struct v into secret data, see Encode, and writes it to given path.
+// Data written to KV version 2 mounts must be nested in "data" field, so use Encode
+// and Write for those instead.
+func WriteFrom(c Client, path string, v interface{}) (*api.Secret, error) {
+	data, err := Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.Write(path, data)
+}
+
+// Decode maps secret data into struct pointed by v. Fields are mapped by `vault:"key"` tags and
+// by field names when there's no tag, fields tagged with `vault:"-"` are skipped. Missing keys
+// of fields tagged with `vault:"key,required"` are reported in a single error.
+//
+// String values are converted to numbers, booleans and time.Duration fields, other values
+// are converted via JSON, e.g. objects to nested structs and maps.
+//
+//	var db struct {
+//		User     string        `vault:"username,required"`
+//		Password string        `vault:"password,required"`
+//		Port     int           `vault:"port"`
+//		Timeout  time.Duration `vault:"timeout"`
+//	}
+//	err := vault.ReadInto(client, "secret/db", &db)
+func Decode(data map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("expected non-nil pointer to struct, got %T", v)
+	}
+	rv = rv.Elem()
+
+	var missing []string
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		key, required, ok := fieldKey(field)
+		if !ok {
+			continue
+		}
+
+		value, exists := data[key]
+		if !exists || value == nil {
+			if required {
+				missing = append(missing, key)
+			}
+			continue
+		}
+		if err := setValue(rv.Field(i), value); err != nil {
+			return errors.WithMessagef(err, "failed to map key '%s' into field %s", key, field.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.Errorf("required keys missing from secret: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Encode maps struct v into secret data by the same rules as Decode. Zero values are
+// omitted for fields with `vault:"key,omitempty"` tag. time.Duration fields are written as strings.
+func Encode(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.Errorf("expected struct or pointer to struct, got %T", v)
+	}
+
+	data := make(map[string]interface{}, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		key, _, ok := fieldKey(field)
+		if !ok {
+			continue
+		}
+
+		value := rv.Field(i)
+		if value.IsZero() && hasTagOption(field, "omitempty") {
+			continue
+		}
+		if value.Type() == durationType {
+			data[key] = time.Duration(value.Int()).String()
+			continue
+		}
+		data[key] = value.Interface()
+	}
+	return data, nil
+}
+
+func fieldKey(field reflect.StructField) (key string, required bool, ok bool) {
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+	tag := field.Tag.Get(tagName)
+	if tag == "-" {
+		return "", false, false
+	}
+	key = strings.Split(tag, ",")[0]
+	if key == "" {
+		key = field.Name
+	}
+	return key, hasTagOption(field, "required"), true
+}
+
+func hasTagOption(field reflect.StructField, option string) bool {
+	options := strings.Split(field.Tag.Get(tagName), ",")
+	for _, o := range options[1:] {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func setValue(field reflect.Value, value interface{}) error {
+	s, isString := value.(string)
+	if n, ok := value.(json.Number); ok {
+		s, isString = n.String(), true
+	}
+
+	switch {
+	case field.Type() == durationType && isString:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case field.Kind() == reflect.String && isString:
+		field.SetString(s)
+		return nil
+	case !isString:
+		return setValueFromJSON(field, value)
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes([]byte(s))
+			return nil
+		}
+		return unmarshalString(field, s)
+	default:
+		return unmarshalString(field, s)
+	}
+	return nil
+}
+
+// unmarshalString sets field from JSON encoded string value, e.g. list stored as a string.
+func unmarshalString(field reflect.Value, s string) error {
+	return errors.Wrapf(json.Unmarshal([]byte(s), field.Addr().Interface()), "can't convert string to %s", field.Type())
+}
+
+func setValueFromJSON(field reflect.Value, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(json.Unmarshal(b, field.Addr().Interface()), "can't convert %T to %s", value, field.Type())
+}
@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dbSecret struct {
+	User     string            `vault:"username,required"`
+	Password string            `vault:"password,required"`
+	Port     int               `vault:"port"`
+	TLS      bool              `vault:"tls"`
+	Timeout  time.Duration     `vault:"timeout,omitempty"`
+	Hosts    []string          `vault:"hosts,omitempty"`
+	Options  map[string]string `vault:"options,omitempty"`
+	Ignored  string            `vault:"-"`
+	Name     string
+}
+
+func TestReadInto(t *testing.T) {
+	kv2 := &api.Secret{Data: map[string]interface{}{
+		"data": map[string]interface{}{
+			"username": "admin",
+			"password": "p4ss",
+			"port":     json.Number("5432"),
+			"tls":      "true",
+			"timeout":  "5s",
+			"hosts":    []interface{}{"db1", "db2"},
+			"options":  `{"sslmode":"require"}`,
+			"Name":     "main",
+			"-":        "skipped",
+		},
+		"metadata": map[string]interface{}{"version": 1},
+	}}
+
+	mock := NewMockClient(t)
+	mock.WhenRead("secret/data/db").ThenReturn(kv2)
+	mock.WhenRead("secret/missing").ThenReturn(nil)
+
+	var db dbSecret
+	require.NoError(t, ReadInto(mock, "secret/data/db", &db))
+	assert.Equal(t, dbSecret{
+		User:     "admin",
+		Password: "p4ss",
+		Port:     5432,
+		TLS:      true,
+		Timeout:  5 * time.Second,
+		Hosts:    []string{"db1", "db2"},
+		Options:  map[string]string{"sslmode": "require"},
+		Name:     "main",
+	}, db)
+
+	assert.ErrorIs(t, ReadInto(mock, "secret/missing", &db), ErrSecretNotFound)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var db dbSecret
+	err := Decode(map[string]interface{}{"port": "1"}, &db)
+	assert.EqualError(t, err, "required keys missing from secret: username, password")
+
+	err = Decode(map[string]interface{}{"username": "u", "password": "p", "port": "not a number"}, &db)
+	assert.ErrorContains(t, err, "failed to map key 'port' into field Port")
+
+	assert.Error(t, Decode(map[string]interface{}{}, db))
+}
+
+func TestWriteFrom(t *testing.T) {
+	mock := NewMockClient(t)
+	mock.WhenWrite("secret/db", map[string]interface{}{
+		"username": "admin",
+		"password": "p4ss",
+		"port":     5432,
+		"tls":      false,
+		"Name":     "",
+	}).ThenReturn(nil)
+
+	_, err := WriteFrom(mock, "secret/db", dbSecret{User: "admin", Password: "p4ss", Port: 5432, Ignored: "x"})
+	require.NoError(t, err)
+
+	data, err := Encode(&dbSecret{Timeout: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, "1m0s", data["timeout"])
+}
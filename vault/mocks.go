@@ -22,7 +22,11 @@ type expectedCall struct {
 	result          interface{}
 }
 
-var _ Client = &MockClient{}
+var (
+	_ Client        = &MockClient{}
+	_ HealthChecker = &MockClient{}
+	_ SysClient     = &MockClient{}
+)
 
 // Provides mock implementation for vault client.
 // Will fail test, if any function is called unexpectedly.
@@ -112,6 +116,72 @@ func (m *MockClient) ListMounts() (map[string]*api.MountOutput, error) {
 	panic("not implemented")
 }
 
+func (m *MockClient) Health() (result *api.HealthResponse, err error) {
+	checkResult, err := m.checkCallIsCorrect("health", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkResult == nil {
+		return
+	}
+
+	result = checkResult.(*api.HealthResponse)
+	return
+}
+
+func (m *MockClient) PutPolicy(name, rules string) error {
+	_, err := m.checkCallIsCorrect("put-policy", [2]string{name, rules})
+	return err
+}
+
+func (m *MockClient) GetPolicy(name string) (result string, err error) {
+	checkResult, err := m.checkCallIsCorrect("get-policy", name)
+	if err != nil || checkResult == nil {
+		return "", err
+	}
+	return checkResult.(string), nil
+}
+
+func (m *MockClient) DeletePolicy(name string) error {
+	_, err := m.checkCallIsCorrect("delete-policy", name)
+	return err
+}
+
+func (m *MockClient) ListPolicies() (result []string, err error) {
+	checkResult, err := m.checkCallIsCorrect("list-policies", nil)
+	if err != nil || checkResult == nil {
+		return nil, err
+	}
+	return checkResult.([]string), nil
+}
+
+// EnableAuth is not implemented.
+func (m *MockClient) EnableAuth(string, *api.EnableAuthOptions) error {
+	panic("not implemented")
+}
+
+// DisableAuth is not implemented.
+func (m *MockClient) DisableAuth(string) error {
+	panic("not implemented")
+}
+
+func (m *MockClient) ListAuth() (result map[string]*api.AuthMount, err error) {
+	checkResult, err := m.checkCallIsCorrect("list-auth", nil)
+	if err != nil || checkResult == nil {
+		return nil, err
+	}
+	return checkResult.(map[string]*api.AuthMount), nil
+}
+
+func (m *MockClient) ListAudit() (result map[string]*api.Audit, err error) {
+	checkResult, err := m.checkCallIsCorrect("list-audit", nil)
+	if err != nil || checkResult == nil {
+		return nil, err
+	}
+	return checkResult.(map[string]*api.Audit), nil
+}
+
 func (m *MockClient) WhenList(path string) *expectedCall {
 	c := &expectedCall{operation: "list", expectedParams: path, addExpectedCall: m.addExpectedCall}
 	return c
@@ -137,6 +207,35 @@ func (m *MockClient) WhenDelete(path string) *expectedCall {
 	return c
 }
 
+func (m *MockClient) WhenHealth() *expectedCall {
+	c := &expectedCall{operation: "health", addExpectedCall: m.addExpectedCall}
+	return c
+}
+
+func (m *MockClient) WhenPutPolicy(name, rules string) *expectedCall {
+	return &expectedCall{operation: "put-policy", expectedParams: [2]string{name, rules}, addExpectedCall: m.addExpectedCall}
+}
+
+func (m *MockClient) WhenGetPolicy(name string) *expectedCall {
+	return &expectedCall{operation: "get-policy", expectedParams: name, addExpectedCall: m.addExpectedCall}
+}
+
+func (m *MockClient) WhenDeletePolicy(name string) *expectedCall {
+	return &expectedCall{operation: "delete-policy", expectedParams: name, addExpectedCall: m.addExpectedCall}
+}
+
+func (m *MockClient) WhenListPolicies() *expectedCall {
+	return &expectedCall{operation: "list-policies", addExpectedCall: m.addExpectedCall}
+}
+
+func (m *MockClient) WhenListAuth() *expectedCall {
+	return &expectedCall{operation: "list-auth", addExpectedCall: m.addExpectedCall}
+}
+
+func (m *MockClient) WhenListAudit() *expectedCall {
+	return &expectedCall{operation: "list-audit", addExpectedCall: m.addExpectedCall}
+}
+
 func (ec *expectedCall) ThenReturn(result interface{}) {
 	ec.result = result
 	ec.addExpectedCall(ec)
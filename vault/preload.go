@@ -0,0 +1,326 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// SecretTarget tells where a secret is exported to by Preloader. Value is either a single key of the secret
+// data or Template rendered with the data, e.g. `{{ .username }}:{{ .password }}`. The value is set to
+// environment variable Env, written to file File, or both.
+type SecretTarget struct {
+	Path     string      `json:"path"`
+	Key      string      `json:"key,omitempty"`
+	Template string      `json:"template,omitempty"`
+	Env      string      `json:"env,omitempty"`
+	File     string      `json:"file,omitempty"`
+	FileMode os.FileMode `json:"fileMode,omitempty"`
+
+	tmpl *template.Template
+}
+
+// ReadPreloadManifest reads JSON array of secret targets from given file, e.g.
+//
+//	[
+//		{"path": "secret/data/db", "key": "password", "env": "DB_PASSWORD"},
+//		{"path": "secret/data/tls", "template": "{{ .cert }}\n{{ .chain }}", "file": "/run/secrets/tls.pem"}
+//	]
+func ReadPreloadManifest(path string) ([]SecretTarget, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "error reading preload manifest %s", path)
+	}
+	var targets []SecretTarget
+	if err := json.Unmarshal(b, &targets); err != nil {
+		return nil, errors.WithMessagef(err, "error parsing preload manifest %s", path)
+	}
+	return targets, nil
+}
+
+// PreloadOpt is a functional option type for Preloader.
+type PreloadOpt func(*Preloader) error
+
+// PreloadConcurrency sets how many secrets are read concurrently, 4 by default.
+func PreloadConcurrency(concurrency int) PreloadOpt {
+	return func(p *Preloader) error {
+		if concurrency <= 0 {
+			return errors.Errorf("preload concurrency must be positive, got %d", concurrency)
+		}
+		p.concurrency = concurrency
+		return nil
+	}
+}
+
+// PreloadAttempts sets how many times reading a secret is attempted, waiting delay between attempts,
+// 3 attempts with 1 second delay by default. Those are on top of retries of the client, see RetryPolicy,
+// e.g. for secrets written by another job which may not exist yet when the service starts.
+func PreloadAttempts(attempts int, delay time.Duration) PreloadOpt {
+	return func(p *Preloader) error {
+		if attempts <= 0 || delay < 0 {
+			return errors.Errorf("invalid preload attempts %d with delay %s", attempts, delay)
+		}
+		p.attempts, p.delay = attempts, delay
+		return nil
+	}
+}
+
+// OnSecretChange sets callback called by Run after a rotated secret was exported again,
+// e.g. to reconnect to a database with new credentials.
+func OnSecretChange(onChange func(target SecretTarget)) PreloadOpt {
+	return func(p *Preloader) error {
+		p.onChange = onChange
+		return nil
+	}
+}
+
+// Preloader reads secrets at startup and exports them to environment variables and files, like Vault Agent
+// templating but within the service process. Run can be used to export secrets again when they are rotated.
+type Preloader struct {
+	client      Client
+	targets     []SecretTarget
+	concurrency int
+	attempts    int
+	delay       time.Duration
+	onChange    func(target SecretTarget)
+
+	lock     sync.Mutex
+	exported map[int]string
+}
+
+// NewPreloader returns Preloader exporting given targets with secrets read with client.
+func NewPreloader(c Client, targets []SecretTarget, opts ...PreloadOpt) (*Preloader, error) {
+	p := &Preloader{
+		client:      c,
+		targets:     make([]SecretTarget, len(targets)),
+		concurrency: 4,
+		attempts:    3,
+		delay:       time.Second,
+		onChange:    func(SecretTarget) {},
+		exported:    map[int]string{},
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	for i, t := range targets {
+		if err := t.parse(); err != nil {
+			return nil, errors.WithMessagef(err, "invalid preload target %d", i)
+		}
+		p.targets[i] = t
+	}
+	return p, nil
+}
+
+func (t *SecretTarget) parse() error {
+	switch {
+	case t.Path == "":
+		return errors.New("secret path is required")
+	case t.Env == "" && t.File == "":
+		return errors.Errorf("env or file is required for secret %s", t.Path)
+	case (t.Key == "") == (t.Template == ""):
+		return errors.Errorf("either key or template is required for secret %s", t.Path)
+	}
+	if t.FileMode == 0 {
+		t.FileMode = 0o600
+	}
+	if t.Template != "" {
+		tmpl, err := template.New(t.Path).Option("missingkey=error").Parse(t.Template)
+		if err != nil {
+			return errors.WithMessagef(err, "error parsing template for secret %s", t.Path)
+		}
+		t.tmpl = tmpl
+	}
+	return nil
+}
+
+// Load reads all secrets and exports them. Secrets are read concurrently and the same path is read
+// only once. Errors of all targets are returned together, successfully read secrets are exported anyway.
+func (p *Preloader) Load(ctx context.Context) error {
+	_, err := p.load(ctx)
+	return err
+}
+
+// Run reads and exports secrets in given interval until ctx is done. Targets which value has changed since
+// the previous export are exported again and OnSecretChange callback is called. Errors are logged and
+// previously exported values are kept.
+func (p *Preloader) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			changed, err := p.load(ctx)
+			if err != nil {
+				log.Errorf("failed to reload preloaded secrets: %s", err)
+			}
+			for _, t := range changed {
+				log.Infof("secret %s was rotated and exported again", t.Path)
+				p.onChange(t)
+			}
+		}
+	}
+}
+
+// load reads secrets and exports targets which value has changed, returning changed targets.
+func (p *Preloader) load(ctx context.Context) (changed []SecretTarget, err error) {
+	secrets := p.readAll(ctx)
+
+	var errs error
+	for i, t := range p.targets {
+		secret := secrets[t.Path]
+		if secret.err != nil {
+			errs = multierror.Append(errs, secret.err)
+			continue
+		}
+		value, err := t.render(secret.data)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+
+		p.lock.Lock()
+		previous, exported := p.exported[i]
+		p.lock.Unlock()
+		if exported && previous == value {
+			continue
+		}
+		if err := t.export(value); err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		p.lock.Lock()
+		p.exported[i] = value
+		p.lock.Unlock()
+		if exported {
+			changed = append(changed, t)
+		}
+	}
+	return changed, errs
+}
+
+type preloadedSecret struct {
+	data map[string]interface{}
+	err  error
+}
+
+// readAll reads every distinct path of the targets with limited concurrency.
+func (p *Preloader) readAll(ctx context.Context) map[string]preloadedSecret {
+	var paths []string
+	seen := map[string]bool{}
+	for _, t := range p.targets {
+		if !seen[t.Path] {
+			seen[t.Path] = true
+			paths = append(paths, t.Path)
+		}
+	}
+
+	results := make([]preloadedSecret, len(paths))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.concurrency)
+	for i, path := range paths {
+		i, path := i, path
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			data, err := p.read(ctx, path)
+			results[i] = preloadedSecret{data: data, err: err}
+		}()
+	}
+	wg.Wait()
+
+	secrets := make(map[string]preloadedSecret, len(paths))
+	for i, path := range paths {
+		secrets[path] = results[i]
+	}
+	return secrets
+}
+
+func (p *Preloader) read(ctx context.Context, path string) (data map[string]interface{}, err error) {
+	for attempt := 1; ; attempt++ {
+		secret, readErr := p.client.Read(path)
+		switch {
+		case readErr != nil:
+			err = errors.WithMessage(readErr, path)
+		case secret == nil || secret.Data == nil:
+			err = errors.WithMessage(ErrSecretNotFound, path)
+		default:
+			return secretData(secret), nil
+		}
+		if attempt >= p.attempts {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.WithMessage(ctx.Err(), path)
+		case <-time.After(p.delay):
+		}
+	}
+}
+
+func (t SecretTarget) render(data map[string]interface{}) (string, error) {
+	if t.tmpl == nil {
+		value, ok := data[t.Key]
+		if !ok {
+			return "", errors.Errorf("secret %s has no key %s", t.Path, t.Key)
+		}
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(value), nil
+	}
+
+	var b bytes.Buffer
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", errors.WithMessagef(err, "error rendering template for secret %s", t.Path)
+	}
+	return b.String(), nil
+}
+
+func (t SecretTarget) export(value string) error {
+	if t.Env != "" {
+		if err := os.Setenv(t.Env, value); err != nil {
+			return errors.WithMessagef(err, "error setting %s from secret %s", t.Env, t.Path)
+		}
+	}
+	if t.File != "" {
+		if err := writeFileAtomic(t.File, []byte(value), t.FileMode); err != nil {
+			return errors.WithMessagef(err, "error writing secret %s to %s", t.Path, t.File)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes file via temporary file in the same directory, so that readers never see partial content.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
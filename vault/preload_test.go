@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreloader(t *testing.T) {
+	var lock sync.Mutex
+	secrets := map[string]interface{}{
+		"/v1/secret/data/db": map[string]interface{}{
+			"data":     map[string]interface{}{"username": "app", "password": "first"},
+			"metadata": map[string]interface{}{"version": 1},
+		},
+		"/v1/secret/port": map[string]interface{}{"port": 5432},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		data, ok := secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	writeTokenFile(t, tokenPath, "token", time.Now())
+	c, err := NewClient(server.URL, "", TokenFile(tokenPath, false))
+	require.NoError(t, err)
+
+	t.Setenv("PRELOAD_DB_PASSWORD", "")
+	t.Setenv("PRELOAD_DB_PORT", "")
+	dsnPath := filepath.Join(t.TempDir(), "dsn")
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`[
+		{"path": "secret/data/db", "key": "password", "env": "PRELOAD_DB_PASSWORD"},
+		{"path": "secret/data/db", "template": "{{ .username }}:{{ .password }}", "file": "`+dsnPath+`"},
+		{"path": "secret/port", "key": "port", "env": "PRELOAD_DB_PORT"}
+	]`), 0o600))
+	targets, err := ReadPreloadManifest(manifestPath)
+	require.NoError(t, err)
+
+	changed := make(chan SecretTarget, 10)
+	p, err := NewPreloader(c, targets, PreloadConcurrency(2), OnSecretChange(func(t SecretTarget) { changed <- t }))
+	require.NoError(t, err)
+	require.NoError(t, p.Load(context.Background()))
+
+	assert.Equal(t, "first", os.Getenv("PRELOAD_DB_PASSWORD"))
+	assert.Equal(t, "5432", os.Getenv("PRELOAD_DB_PORT"))
+	dsn, err := os.ReadFile(dsnPath)
+	require.NoError(t, err)
+	assert.Equal(t, "app:first", string(dsn))
+	info, err := os.Stat(dsnPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	lock.Lock()
+	secrets["/v1/secret/data/db"].(map[string]interface{})["data"] = map[string]interface{}{"username": "app", "password": "second"}
+	lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx, 10*time.Millisecond) }()
+	assert.Equal(t, targets[0].Env, (<-changed).Env)
+	assert.Equal(t, targets[1].File, (<-changed).File)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, changed, "unchanged secrets should not be exported again")
+
+	assert.Equal(t, "second", os.Getenv("PRELOAD_DB_PASSWORD"))
+	dsn, err = os.ReadFile(dsnPath)
+	require.NoError(t, err)
+	assert.Equal(t, "app:second", string(dsn))
+}
+
+func TestPreloaderErrors(t *testing.T) {
+	for name, target := range map[string]SecretTarget{
+		"no path":          {Key: "key", Env: "ENV"},
+		"no destination":   {Path: "secret/a", Key: "key"},
+		"no key":           {Path: "secret/a", Env: "ENV"},
+		"key and template": {Path: "secret/a", Key: "key", Template: "{{ .key }}", Env: "ENV"},
+		"invalid template": {Path: "secret/a", Template: "{{ .key", Env: "ENV"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewPreloader(NewMockClient(t), []SecretTarget{target})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("missing secret", func(t *testing.T) {
+		mock := NewMockClient(t)
+		mock.WhenRead("secret/a").ThenReturn(nil)
+		mock.WhenRead("secret/a").ThenReturn(nil)
+		p, err := NewPreloader(mock, []SecretTarget{{Path: "secret/a", Key: "key", Env: "PRELOAD_MISSING"}},
+			PreloadAttempts(2, time.Millisecond))
+		require.NoError(t, err)
+		assert.ErrorIs(t, p.Load(context.Background()), ErrSecretNotFound)
+	})
+}
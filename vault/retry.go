@@ -0,0 +1,173 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRetryBaseDelay       = 100 * time.Millisecond
+	defaultRetryMaxDelay        = 5 * time.Second
+	defaultRetryBudgetPerMinute = 60
+)
+
+// ErrRetryBudgetExhausted is returned with the last operation error when it was not retried
+// because RetryPolicy.BudgetPerMinute was used up.
+var ErrRetryBudgetExhausted = errors.New("vault retry budget exhausted")
+
+// RetryPolicy controls retries of all client operations. Failed attempts are retried after
+// exponential backoff with decorrelated jitter: every delay is random between BaseDelay and three
+// times the previous delay, capped at MaxDelay. Requests are not retried by the underlying HTTP client.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per operation including the first one.
+	MaxAttempts int
+	// BaseDelay is the minimum delay between attempts.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between attempts.
+	MaxDelay time.Duration
+	// BudgetPerMinute limits retries made by the client within a minute, so that an unavailable
+	// Vault server is not flooded with retries of concurrent operations. Zero disables the limit.
+	BudgetPerMinute int
+	// Retryable reports whether error of an attempt is worth retrying, defaults to IsRetryable.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns policy used by NewClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     defaultMaxRetries + 1,
+		BaseDelay:       defaultRetryBaseDelay,
+		MaxDelay:        defaultRetryMaxDelay,
+		BudgetPerMinute: defaultRetryBudgetPerMinute,
+		Retryable:       IsRetryable,
+	}
+}
+
+// WithRetryPolicy sets retry policy of the client. Zero fields are set to defaults,
+// except BudgetPerMinute which disables the budget.
+func WithRetryPolicy(policy RetryPolicy) ConfigFn {
+	return func(c *config) (err error) {
+		if policy.MaxAttempts < 0 || policy.BaseDelay < 0 || policy.MaxDelay < 0 || policy.BudgetPerMinute < 0 {
+			return errors.New("retry policy values must not be negative")
+		}
+		defaults := DefaultRetryPolicy()
+		if policy.MaxAttempts == 0 {
+			policy.MaxAttempts = defaults.MaxAttempts
+		}
+		if policy.BaseDelay == 0 {
+			policy.BaseDelay = defaults.BaseDelay
+		}
+		if policy.MaxDelay == 0 {
+			policy.MaxDelay = defaults.MaxDelay
+		}
+		if policy.Retryable == nil {
+			policy.Retryable = defaults.Retryable
+		}
+		c.Retry = policy
+		return
+	}
+}
+
+// IsRetryable reports whether err is transient: a network error, 412 returned by performance standbys
+// before replication catches up, 429, 5xx other than 501, or 401 and 403 which are retried after logging in again.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) {
+		return true
+	}
+	switch code := respErr.StatusCode; {
+	case code == http.StatusPreconditionFailed, code == http.StatusTooManyRequests:
+		return true
+	case isAuthError(err):
+		return true
+	case code == http.StatusNotImplemented:
+		return false
+	default:
+		return code >= http.StatusInternalServerError
+	}
+}
+
+func isAuthError(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) &&
+		(respErr.StatusCode == http.StatusUnauthorized || respErr.StatusCode == http.StatusForbidden)
+}
+
+// retrier executes operations according to RetryPolicy and tracks retry budget shared by all operations.
+type retrier struct {
+	policy RetryPolicy
+	sleep  func(time.Duration)
+
+	lock        sync.Mutex
+	windowStart time.Time
+	retries     int
+}
+
+func newRetrier(policy RetryPolicy) *retrier {
+	return &retrier{policy: policy, sleep: time.Sleep}
+}
+
+// do runs operation until it succeeds, returns non-retryable error or attempts are used up.
+// beforeRetry is called, if not nil, before retrying an attempt failed with err.
+func (r *retrier) do(operation func() error, beforeRetry func(err error) error) (err error) {
+	delay := r.policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err = operation()
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.policy.Retryable(err) {
+			return err
+		}
+		if !r.takeBudget() {
+			return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+		}
+
+		delay = r.nextDelay(delay)
+		log.Debugf("error performing request, retrying in %s: %s", delay, err)
+		r.sleep(delay)
+
+		if beforeRetry != nil {
+			if retryErr := beforeRetry(err); retryErr != nil {
+				return retryErr
+			}
+		}
+	}
+}
+
+func (r *retrier) nextDelay(previous time.Duration) time.Duration {
+	upper := 3 * previous
+	if upper <= r.policy.BaseDelay {
+		return r.policy.BaseDelay
+	}
+	//nolint:gosec
+	delay := r.policy.BaseDelay + time.Duration(rand.Int63n(int64(upper-r.policy.BaseDelay)))
+	if delay > r.policy.MaxDelay {
+		return r.policy.MaxDelay
+	}
+	return delay
+}
+
+func (r *retrier) takeBudget() bool {
+	if r.policy.BudgetPerMinute == 0 {
+		return true
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if now := time.Now(); now.Sub(r.windowStart) >= time.Minute {
+		r.windowStart = now
+		r.retries = 0
+	}
+	if r.retries >= r.policy.BudgetPerMinute {
+		return false
+	}
+	r.retries++
+	return true
+}
@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           RetryPolicy
+		statuses         []int
+		expectedRequests int32
+		expectedErr      error
+	}{
+		{"retry until success", RetryPolicy{}, []int{503, 500, 200}, 3, nil},
+		{"retry throttled", RetryPolicy{}, []int{429, 412, 200}, 3, nil},
+		{"no retry on client error", RetryPolicy{}, []int{400, 200}, 1, nil},
+		{"max attempts", RetryPolicy{MaxAttempts: 2}, []int{503, 503, 200}, 2, nil},
+		{"budget exhausted", RetryPolicy{BudgetPerMinute: 1}, []int{503, 503, 200}, 2, ErrRetryBudgetExhausted},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := requests.Add(1)
+				w.WriteHeader(tc.statuses[n-1])
+				_, _ = w.Write([]byte(`{"data": {"key": "value"}}`))
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "token")
+			writeTokenFile(t, path, "token", time.Now())
+			c, err := newClient(server.URL, "", TokenFile(path, false), WithRetryPolicy(tc.policy))
+			require.NoError(t, err)
+			var delays []time.Duration
+			c.retrier.sleep = func(d time.Duration) { delays = append(delays, d) }
+
+			secret, err := c.Read("secret/a")
+			assert.Equal(t, tc.expectedRequests, requests.Load())
+			assert.Len(t, delays, int(tc.expectedRequests)-1)
+			for _, d := range delays {
+				assert.True(t, d >= defaultRetryBaseDelay && d <= defaultRetryMaxDelay, d)
+			}
+			if tc.statuses[tc.expectedRequests-1] == http.StatusOK {
+				require.NoError(t, err)
+				assert.Equal(t, "value", secret.Data["key"])
+				return
+			}
+			require.Error(t, err)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr), err)
+			}
+			var vaultErr *Error
+			require.True(t, errors.As(err, &vaultErr), err)
+			assert.Equal(t, tc.statuses[tc.expectedRequests-1], vaultErr.StatusCode, "last error should be kept")
+		})
+	}
+}
+
+func TestClientRetryReloginOnForbidden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	writeTokenFile(t, path, "expired", time.Now().Add(-time.Minute))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "renewed" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"key": "value"}}`))
+	}))
+	defer server.Close()
+
+	c, err := newClient(server.URL, "", TokenFile(path, false))
+	require.NoError(t, err)
+	c.retrier.sleep = func(time.Duration) {}
+
+	_, err = c.Read("secret/a")
+	require.Error(t, err)
+
+	writeTokenFile(t, path, "renewed", time.Now())
+	secret, err := c.Read("secret/a")
+	require.NoError(t, err, "token should be read again before retrying forbidden request")
+	assert.Equal(t, "value", secret.Data["key"])
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(errors.New("connection refused")))
+	assert.True(t, IsRetryable(&api.ResponseError{StatusCode: http.StatusBadGateway}))
+	assert.True(t, IsRetryable(&api.ResponseError{StatusCode: http.StatusForbidden}))
+	assert.False(t, IsRetryable(&api.ResponseError{StatusCode: http.StatusNotImplemented}))
+	assert.False(t, IsRetryable(&api.ResponseError{StatusCode: http.StatusNotFound}))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestWithRetryPolicyInvalid(t *testing.T) {
+	_, err := NewClient("http://127.0.0.1:0", "", WithRetryPolicy(RetryPolicy{MaxAttempts: -1}))
+	assert.Error(t, err)
+	_, err = NewClient("http://127.0.0.1:0", "", MaxRetries(-1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrSecretClosed is returned by SealedSecret.Plaintext after the secret has been closed.
+	ErrSecretClosed = errors.New("sealed secret is closed")
+	// ErrSecretNotFound is returned by ReadSealed when there is no secret in given path.
+	ErrSecretNotFound = errors.New("secret not found")
+)
+
+// SealedSecret keeps secret value encrypted in memory with AES-GCM and a random key of its own,
+// so that the plaintext doesn't end up in heap dumps and core files while the value is cached.
+// It doesn't protect against an attacker who can read the memory of a running process.
+// Plaintext is only available through Plaintext and both key and ciphertext are zeroed on Close.
+type SealedSecret struct {
+	lock       sync.RWMutex
+	key        []byte
+	nonce      []byte
+	ciphertext []byte
+}
+
+// Seal encrypts given plaintext into SealedSecret. Caller should zero the plaintext after sealing.
+func Seal(plaintext []byte) (*SealedSecret, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.WithMessage(err, "failed to generate key for sealed secret")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.WithMessage(err, "failed to generate nonce for sealed secret")
+	}
+
+	return &SealedSecret{
+		key:        key,
+		nonce:      nonce,
+		ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	}, nil
+}
+
+// Plaintext decrypts the secret and returns a new copy of the plaintext on every call.
+// Caller should zero the returned slice as soon as the value is no longer needed.
+func (s *SealedSecret) Plaintext() ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.key == nil {
+		return nil, ErrSecretClosed
+	}
+
+	aead, err := newAEAD(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, s.nonce, s.ciphertext, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decrypt sealed secret")
+	}
+	return plaintext, nil
+}
+
+// String never returns the secret value, so that it can't be logged by accident.
+func (s *SealedSecret) String() string {
+	return "[SEALED]"
+}
+
+// Close zeroes the key and the ciphertext. Secret can't be read after it has been closed.
+func (s *SealedSecret) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	Zero(s.key)
+	Zero(s.ciphertext)
+	s.key, s.nonce, s.ciphertext = nil, nil, nil
+	return nil
+}
+
+// SealedSecrets are the sealed values of a secret data, keyed by the data key.
+type SealedSecrets map[string]*SealedSecret
+
+// Get returns plaintext of given key, see SealedSecret.Plaintext.
+func (s SealedSecrets) Get(key string) ([]byte, error) {
+	secret, ok := s[key]
+	if !ok {
+		return nil, errors.Errorf("key '%s' not found in sealed secrets", key)
+	}
+	return secret.Plaintext()
+}
+
+// Close closes all sealed values.
+func (s SealedSecrets) Close() error {
+	for _, secret := range s {
+		_ = secret.Close()
+	}
+	return nil
+}
+
+// ReadSealed reads secret from given path and seals every value of its data. String values are
+// sealed as is, other values as JSON. Values are removed from the data of the read secret, so that
+// the plaintext is not left behind in the response. Data of KV version 2 secrets is sealed
+// from the nested "data" field.
+func ReadSealed(c Client, path string) (SealedSecrets, error) {
+	secret, err := c.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.WithMessage(ErrSecretNotFound, path)
+	}
+
+	return SealData(secretData(secret))
+}
+
+// secretData returns data of given secret, which is nested in the "data" field for KV version 2 secrets.
+func secretData(secret *api.Secret) map[string]interface{} {
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		return nested
+	}
+	return data
+}
+
+// SealData seals every value of given data and removes the values from the map.
+func SealData(data map[string]interface{}) (SealedSecrets, error) {
+	sealed := make(SealedSecrets, len(data))
+	for key, value := range data {
+		var plaintext []byte
+		switch v := value.(type) {
+		case string:
+			plaintext = []byte(v)
+		case []byte:
+			plaintext = v
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				_ = sealed.Close()
+				return nil, errors.WithMessagef(err, "failed to marshal value of key '%s'", key)
+			}
+			plaintext = b
+		}
+
+		secret, err := Seal(plaintext)
+		Zero(plaintext)
+		if err != nil {
+			_ = sealed.Close()
+			return nil, err
+		}
+		sealed[key] = secret
+		delete(data, key)
+	}
+	return sealed, nil
+}
+
+// Zero overwrites given slice with zeroes.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create cipher for sealed secret")
+	}
+	return cipher.NewGCM(block)
+}
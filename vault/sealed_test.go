@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealedSecret(t *testing.T) {
+	secret, err := Seal([]byte("s3cr3t"))
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(secret.ciphertext, []byte("s3cr3t")))
+	assert.Equal(t, "[SEALED]", secret.String())
+
+	plaintext, err := secret.Plaintext()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", string(plaintext))
+
+	key, ciphertext := secret.key, secret.ciphertext
+	require.NoError(t, secret.Close())
+	assert.Equal(t, make([]byte, len(key)), key)
+	assert.Equal(t, make([]byte, len(ciphertext)), ciphertext)
+
+	_, err = secret.Plaintext()
+	assert.ErrorIs(t, err, ErrSecretClosed)
+}
+
+func TestReadSealed(t *testing.T) {
+	kv1 := &api.Secret{Data: map[string]interface{}{"password": "p4ss", "port": 5432}}
+	kv2 := &api.Secret{Data: map[string]interface{}{
+		"data":     map[string]interface{}{"password": "p4ss"},
+		"metadata": map[string]interface{}{"version": 1},
+	}}
+
+	mock := NewMockClient(t)
+	mock.WhenRead("secret/db").ThenReturn(kv1)
+	mock.WhenRead("secret/data/db").ThenReturn(kv2)
+	mock.WhenRead("secret/missing").ThenReturn(nil)
+
+	sealed, err := ReadSealed(mock, "secret/db")
+	require.NoError(t, err)
+	defer sealed.Close()
+	assert.Empty(t, kv1.Data, "plaintext values should be removed from secret")
+
+	password, err := sealed.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "p4ss", string(password))
+	port, err := sealed.Get("port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", string(port))
+	_, err = sealed.Get("user")
+	assert.Error(t, err)
+
+	sealed, err = ReadSealed(mock, "secret/data/db")
+	require.NoError(t, err)
+	password, err = sealed.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "p4ss", string(password))
+	require.NoError(t, sealed.Close())
+	_, err = sealed.Get("password")
+	assert.ErrorIs(t, err, ErrSecretClosed)
+
+	_, err = ReadSealed(mock, "secret/missing")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
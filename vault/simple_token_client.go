@@ -50,3 +50,39 @@ func (c *simpleTokenClient) Unmount(path string) error {
 func (c *simpleTokenClient) ListMounts() (map[string]*api.MountOutput, error) {
 	return c.vaultClient.Sys().ListMounts()
 }
+
+func (c *simpleTokenClient) Health() (*api.HealthResponse, error) {
+	return c.vaultClient.Sys().Health()
+}
+
+func (c *simpleTokenClient) PutPolicy(name, rules string) error {
+	return c.vaultClient.Sys().PutPolicy(name, rules)
+}
+
+func (c *simpleTokenClient) GetPolicy(name string) (string, error) {
+	return c.vaultClient.Sys().GetPolicy(name)
+}
+
+func (c *simpleTokenClient) DeletePolicy(name string) error {
+	return c.vaultClient.Sys().DeletePolicy(name)
+}
+
+func (c *simpleTokenClient) ListPolicies() ([]string, error) {
+	return c.vaultClient.Sys().ListPolicies()
+}
+
+func (c *simpleTokenClient) EnableAuth(path string, options *api.EnableAuthOptions) error {
+	return c.vaultClient.Sys().EnableAuthWithOptions(path, options)
+}
+
+func (c *simpleTokenClient) DisableAuth(path string) error {
+	return c.vaultClient.Sys().DisableAuth(path)
+}
+
+func (c *simpleTokenClient) ListAuth() (map[string]*api.AuthMount, error) {
+	return c.vaultClient.Sys().ListAuth()
+}
+
+func (c *simpleTokenClient) ListAudit() (map[string]*api.Audit, error) {
+	return c.vaultClient.Sys().ListAudit()
+}
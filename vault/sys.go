@@ -0,0 +1,103 @@
+package vault
+
+import (
+	"github.com/hashicorp/vault/api"
+)
+
+// SysClient manages policies, auth methods and audit devices of Vault. It is implemented by the clients returned
+// by NewClient and NewSimpleTokenClient, InMemoryClient and MockClient, and kept out of Client, so that existing
+// implementations of Client don't need to implement it.
+type SysClient interface {
+	PutPolicy(name, rules string) error
+	GetPolicy(name string) (string, error)
+	DeletePolicy(name string) error
+	ListPolicies() ([]string, error)
+	EnableAuth(path string, options *api.EnableAuthOptions) error
+	DisableAuth(path string) error
+	ListAuth() (map[string]*api.AuthMount, error)
+	ListAudit() (map[string]*api.Audit, error)
+}
+
+// PutPolicy creates or updates ACL policy with given name and HCL rules.
+func (c *client) PutPolicy(name, rules string) error {
+	return c.sysOperation(OperationPutPolicy, "sys/policies/acl/"+name, func(sys *api.Sys) error {
+		return sys.PutPolicy(name, rules)
+	})
+}
+
+// GetPolicy returns HCL rules of ACL policy with given name, empty string is returned if policy doesn't exist.
+func (c *client) GetPolicy(name string) (rules string, err error) {
+	err = c.sysOperation(OperationGetPolicy, "sys/policies/acl/"+name, func(sys *api.Sys) (e error) {
+		rules, e = sys.GetPolicy(name)
+		return e
+	})
+	return rules, err
+}
+
+// DeletePolicy deletes ACL policy with given name.
+func (c *client) DeletePolicy(name string) error {
+	return c.sysOperation(OperationDeletePolicy, "sys/policies/acl/"+name, func(sys *api.Sys) error {
+		return sys.DeletePolicy(name)
+	})
+}
+
+// ListPolicies returns names of ACL policies.
+func (c *client) ListPolicies() (policies []string, err error) {
+	err = c.sysOperation(OperationListPolicies, "sys/policies/acl", func(sys *api.Sys) (e error) {
+		policies, e = sys.ListPolicies()
+		return e
+	})
+	return policies, err
+}
+
+// EnableAuth mounts auth method to given path.
+func (c *client) EnableAuth(path string, options *api.EnableAuthOptions) error {
+	return c.sysOperation(OperationEnableAuth, "sys/auth/"+path, func(sys *api.Sys) error {
+		return sys.EnableAuthWithOptions(path, options)
+	})
+}
+
+// DisableAuth unmounts auth method from given path.
+func (c *client) DisableAuth(path string) error {
+	return c.sysOperation(OperationDisableAuth, "sys/auth/"+path, func(sys *api.Sys) error {
+		return sys.DisableAuth(path)
+	})
+}
+
+// ListAuth returns mounted auth methods by path.
+func (c *client) ListAuth() (auths map[string]*api.AuthMount, err error) {
+	err = c.sysOperation(OperationListAuth, "sys/auth", func(sys *api.Sys) (e error) {
+		auths, e = sys.ListAuth()
+		return e
+	})
+	return auths, err
+}
+
+// ListAudit returns enabled audit devices by path.
+func (c *client) ListAudit() (audits map[string]*api.Audit, err error) {
+	err = c.sysOperation(OperationListAudit, "sys/audit", func(sys *api.Sys) (e error) {
+		audits, e = sys.ListAudit()
+		return e
+	})
+	return audits, err
+}
+
+// sysOperation runs operation against sys backend with the same hooks, retries and circuit breaker
+// as secret operations.
+func (c *client) sysOperation(name, path string, operation func(sys *api.Sys) error) (err error) {
+	done := c.config.Hooks.begin(name, path)
+	defer func() {
+		err = newOperationError(name, path, err)
+		done(err)
+	}()
+
+	err = c.connectIfNotInitialized()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tryOperationWithBreaker(func() (secret *api.Secret, err error) {
+		return nil, operation(c.h.get().Sys())
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSysOperations(t *testing.T) {
+	var requests []string
+	policies := map[string]string{}
+	mockVaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		switch {
+		case r.URL.Path == "/v1/sys/policies/acl/reader" && r.Method == http.MethodPut:
+			body := struct{ Policy string }{}
+			b, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(b, &body))
+			policies["reader"] = body.Policy
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/sys/policies/acl/reader" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"policy": policies["reader"]}})
+		case r.URL.Path == "/v1/sys/policies/acl/reader" && r.Method == http.MethodDelete:
+			delete(policies, "reader")
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/sys/auth":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"kubernetes/": map[string]interface{}{"type": "kubernetes"},
+			}})
+		case r.URL.Path == "/v1/sys/audit":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"file/": map[string]interface{}{"type": "file", "path": "file/"},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockVaultServer.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	writeTokenFile(t, path, "token", time.Now())
+
+	var ops []string
+	c, err := NewClient(mockVaultServer.URL, "", TokenFile(path, false), MaxRetries(0), Hooks(RequestHooks{
+		OnRequest: func(op Operation) { ops = append(ops, op.Name+" "+op.Path) },
+	}))
+	require.NoError(t, err)
+	sys := c.(SysClient)
+
+	require.NoError(t, sys.PutPolicy("reader", `path "secret/*" { capabilities = ["read"] }`))
+	rules, err := sys.GetPolicy("reader")
+	require.NoError(t, err)
+	assert.Equal(t, `path "secret/*" { capabilities = ["read"] }`, rules)
+	require.NoError(t, sys.DeletePolicy("reader"))
+
+	auths, err := sys.ListAuth()
+	require.NoError(t, err)
+	require.Contains(t, auths, "kubernetes/")
+	assert.Equal(t, "kubernetes", auths["kubernetes/"].Type)
+
+	audits, err := sys.ListAudit()
+	require.NoError(t, err)
+	require.Contains(t, audits, "file/")
+	assert.Equal(t, "file", audits["file/"].Type)
+
+	assert.Equal(t, []string{
+		"PUT /v1/sys/policies/acl/reader",
+		"GET /v1/sys/policies/acl/reader",
+		"DELETE /v1/sys/policies/acl/reader",
+		"GET /v1/sys/auth",
+		"GET /v1/sys/audit",
+	}, requests)
+	assert.Equal(t, []string{
+		"put-policy sys/policies/acl/reader",
+		"get-policy sys/policies/acl/reader",
+		"delete-policy sys/policies/acl/reader",
+		"list-auth sys/auth",
+		"list-audit sys/audit",
+	}, ops)
+}
+
+func TestMockClientPolicies(t *testing.T) {
+	tt := &testing.T{}
+	mockVaultClient := NewMockClient(tt)
+
+	mockVaultClient.WhenPutPolicy("reader", "rules").ThenReturn(nil)
+	mockVaultClient.WhenGetPolicy("reader").ThenReturn("rules")
+	mockVaultClient.WhenListAuth().ThenReturn(map[string]*api.AuthMount{"kubernetes/": {Type: "kubernetes"}})
+
+	assert.NoError(t, mockVaultClient.PutPolicy("reader", "rules"))
+	rules, err := mockVaultClient.GetPolicy("reader")
+	assert.NoError(t, err)
+	assert.Equal(t, "rules", rules)
+	auths, err := mockVaultClient.ListAuth()
+	assert.NoError(t, err)
+	assert.Len(t, auths, 1)
+	assert.False(t, tt.Failed())
+}
+
+func TestHookedClientSysNotSupported(t *testing.T) {
+	sys := WithHooks(struct{ Client }{}, RequestHooks{}).(SysClient)
+	assert.ErrorIs(t, sys.PutPolicy("reader", "rules"), ErrNotSupported)
+	_, err := sys.ListAudit()
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,91 @@
+package vault
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TokenFile reads Vault token from given file, as written by Vault Agent auto-auth sink, instead of
+// logging in with Kubernetes service account token. If watch is true, the file is checked for changes
+// before every operation and the token is reloaded when the file has been modified, so that tokens
+// renewed by the agent are picked up.
+func TokenFile(path string, watch bool) ConfigFn {
+	return func(c *config) (err error) {
+		c.TokenFile = &tokenFile{path: path, watch: watch}
+		return
+	}
+}
+
+type tokenFile struct {
+	lock    sync.Mutex
+	path    string
+	watch   bool
+	modTime time.Time
+	size    int64
+}
+
+// read reads the token from the file.
+func (f *tokenFile) read() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", errors.WithMessagef(err, "error reading vault token file %s", f.path)
+	}
+	return f.readLocked(info)
+}
+
+// readIfChanged reads the token from the file if the file has been modified since it was last read.
+func (f *tokenFile) readIfChanged() (token string, changed bool, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", false, errors.WithMessagef(err, "error reading vault token file %s", f.path)
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return "", false, nil
+	}
+
+	token, err = f.readLocked(info)
+	return token, err == nil, err
+}
+
+func (f *tokenFile) readLocked(info os.FileInfo) (string, error) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", errors.WithMessagef(err, "error reading vault token file %s", f.path)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", errors.Errorf("vault token file %s is empty", f.path)
+	}
+
+	f.modTime, f.size = info.ModTime(), info.Size()
+	return token, nil
+}
+
+// reloadTokenIfChanged sets token from the watched token file to the vault client if the file has changed.
+// Errors are only logged, so that the previous token is used until the file is readable again.
+func (c *client) reloadTokenIfChanged() {
+	if c.config.TokenFile == nil || !c.config.TokenFile.watch {
+		return
+	}
+
+	token, changed, err := c.config.TokenFile.readIfChanged()
+	if err != nil {
+		log.Errorf("failed to reload vault token: %s", err)
+		return
+	}
+	if changed {
+		log.Debugf("reloaded vault token from %s", c.config.TokenFile.path)
+		c.h.get().SetToken(token)
+	}
+}
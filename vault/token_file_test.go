@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTokenFile(t *testing.T, path, token string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, []byte(token+"\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestTokenFile(t *testing.T) {
+	for _, watch := range []bool{true, false} {
+		watch := watch
+		t.Run(map[bool]string{true: "Watch", false: "NoWatch"}[watch], func(t *testing.T) {
+			handler := &mockVaultHandler{}
+			mockVaultServer := httptest.NewServer(handler)
+			defer mockVaultServer.Close()
+
+			path := filepath.Join(t.TempDir(), "token")
+			modTime := time.Now().Add(-time.Minute)
+			writeTokenFile(t, path, "first-token", modTime)
+
+			c, err := NewClient(mockVaultServer.URL, "role", TokenFile(path, watch), JwtPath("/non/existing/path"), MaxRetries(0))
+			require.NoError(t, err)
+
+			_, err = c.Read("secret/a")
+			require.NoError(t, err)
+			assert.Equal(t, "first-token", handler.capturedRequest.Header.Get("X-Vault-Token"))
+			assert.Equal(t, "/v1/secret/a", handler.capturedRequest.URL.Path, "login should be skipped")
+
+			writeTokenFile(t, path, "second-token", modTime.Add(time.Second))
+			_, err = c.Read("secret/a")
+			require.NoError(t, err)
+			expected := map[bool]string{true: "second-token", false: "first-token"}[watch]
+			assert.Equal(t, expected, handler.capturedRequest.Header.Get("X-Vault-Token"))
+
+			require.NoError(t, os.Remove(path))
+			_, err = c.Read("secret/a")
+			require.NoError(t, err, "previous token should be used when file can't be read")
+			assert.Equal(t, expected, handler.capturedRequest.Header.Get("X-Vault-Token"))
+		})
+	}
+}
+
+func TestTokenFileMissing(t *testing.T) {
+	c, err := NewClient("http://127.0.0.1:0", "role", TokenFile(filepath.Join(t.TempDir(), "token"), true), MaxRetries(0))
+	require.NoError(t, err)
+
+	_, err = c.Read("secret/a")
+	assert.Error(t, err)
+}